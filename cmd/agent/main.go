@@ -2,32 +2,66 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
-	"time"
 
 	dockerclient "github.com/docker/docker/client"
+	"github.com/open-beagle/beagle-wind-game/internal/config"
 	"github.com/open-beagle/beagle-wind-game/internal/grpc"
+	"github.com/open-beagle/beagle-wind-game/internal/metrics"
+	"github.com/open-beagle/beagle-wind-game/internal/models"
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
 var (
-	serverAddr = flag.String("server", "localhost:50051", "gRPC server address")
-	nodeID     = flag.String("id", "", "node ID")
+	configPath = flag.String("config", "config/agent.yaml", "agent config file")
+	serverAddr = flag.String("server", "", "comma separated gRPC server addresses, overrides server.addr and server.addrs")
+	nodeID     = flag.String("id", "", "node ID, overrides node.id")
+	statusAddr = flag.String("status-addr", "", "local status endpoint address, empty to disable, overrides status_addr")
+	tlsCA      = flag.String("tls-ca", "", "CA certificate used to verify the server")
+	tlsCert    = flag.String("tls-cert", "", "client certificate, CN or SAN must equal the node ID")
+	tlsKey     = flag.String("tls-key", "", "client private key")
+	tlsServer  = flag.String("tls-server-name", "", "expected server name in the server certificate")
+	joinToken  = flag.String("join-token", "", "join token used to obtain a node credential on first registration")
+	credFile   = flag.String("credential-file", "", "file storing the node credential issued by the server, overrides credential_file")
+	outboxFile = flag.String("outbox-file", "", "file buffering status and metrics reports while the server is unreachable, overrides outbox_file")
+	logFile    = flag.String("log-file", "", "log file, overrides log.file")
+	logLevel   = flag.String("log-level", "", "log level (DEBUG, INFO, WARN, ERROR, FATAL), overrides log.level")
+	logBoth    = flag.Bool("log-both", false, "log to both console and file, overrides log.both")
 )
 
 func main() {
 	flag.Parse()
 
-	// 1. 初始化日志
+	// 1. 加载配置，优先级：命令行参数 > 环境变量 > 配置文件 > 默认值
+	cfg, err := config.LoadAgentFileConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	applyFlags(cfg)
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "配置校验失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 2. 初始化日志
+	utils.InitLogger(cfg.Log.File, logLevels[cfg.Log.Level], cfg.Log.Both)
 	logger := utils.New("Agent")
-	logger.Info("启动 Agent...")
+	logger.Info("启动 Agent, 节点ID: %s, 服务端: %s", cfg.Node.ID, strings.Join(cfg.Server.Endpoints(), ", "))
 
-	// 2. 参数验证
-	if *nodeID == "" {
-		logger.Fatal("节点ID不能为空")
+	// 流水线引擎和诊断信息通过环境变量读取 Docker 地址和数据根目录
+	if cfg.Docker.Host != "" {
+		os.Setenv("DOCKER_HOST", cfg.Docker.Host)
+	}
+	if cfg.Root != "" {
+		os.Setenv("BEAGLE_WIND_ROOT", cfg.Root)
 	}
 
 	// 3. 创建 Docker 客户端
@@ -39,11 +73,22 @@ func main() {
 	// 4. 创建基础 Agent
 	baseAgent, err := grpc.NewAgent(
 		context.Background(),
-		*nodeID,
-		*serverAddr,
+		cfg.Node.ID,
+		cfg.Server.Endpoints(),
 		dockerClient,
 		&grpc.AgentOptions{
-			HeartbeatPeriod: 5 * time.Second,
+			HeartbeatPeriod:    cfg.Intervals.Heartbeat,
+			MetricsInterval:    cfg.Intervals.Metrics,
+			ContainersInterval: cfg.Intervals.Containers,
+			TLS: grpc.TLSConfig{
+				CAFile:     cfg.Server.TLS.CAFile,
+				CertFile:   cfg.Server.TLS.CertFile,
+				KeyFile:    cfg.Server.TLS.KeyFile,
+				ServerName: cfg.Server.TLS.ServerName,
+			},
+			JoinToken:      cfg.Server.JoinToken,
+			CredentialFile: cfg.CredentialFile,
+			OutboxFile:     cfg.OutboxFile,
 		},
 	)
 	if err != nil {
@@ -51,7 +96,13 @@ func main() {
 	}
 
 	// 5. 创建业务 Agent
-	gameNodeAgent, err := grpc.NewGameNodeAgent(baseAgent)
+	gameNodeAgent, err := grpc.NewGameNodeAgent(baseAgent, &grpc.GameNodeOptions{
+		Alias:    cfg.Node.Alias,
+		Model:    cfg.Node.Model,
+		Type:     models.GameNodeType(cfg.Node.Type),
+		Location: cfg.Node.Location,
+		Labels:   cfg.Node.Labels,
+	})
 	if err != nil {
 		logger.Fatal("创建 GameNode Agent 失败: %v", err)
 	}
@@ -61,16 +112,11 @@ func main() {
 		logger.Fatal("创建 GamePipeline Agent 失败: %v", err)
 	}
 
-	// 6. 注册节点
-	if err := gameNodeAgent.Register(context.Background()); err != nil {
-		logger.Fatal("注册节点失败: %v", err)
-	}
-
-	// 7. 启动 Agent
+	// 6. 启动 Agent，连接建立后自动注册节点，断线后自动重连
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if err := baseAgent.Start(ctx); err != nil {
+	if err := gameNodeAgent.Start(ctx); err != nil {
 		logger.Fatal("启动 Agent 失败: %v", err)
 	}
 
@@ -79,6 +125,11 @@ func main() {
 		logger.Fatal("启动 Pipeline Agent 失败: %v", err)
 	}
 
+	// 7. 启动本地状态接口
+	if cfg.StatusAddr != "" {
+		go serveStatus(cfg.StatusAddr, baseAgent, gamePipelineAgent, logger)
+	}
+
 	// 8. 等待信号
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -88,3 +139,59 @@ func main() {
 	baseAgent.Stop(ctx)
 	logger.Info("Agent 已停止")
 }
+
+// logLevels 配置中的日志级别
+var logLevels = map[string]utils.LogLevel{
+	"DEBUG": utils.DEBUG,
+	"INFO":  utils.INFO,
+	"WARN":  utils.WARN,
+	"ERROR": utils.ERROR,
+	"FATAL": utils.FATAL,
+}
+
+// applyFlags 使用命令行中显式指定的参数覆盖配置
+func applyFlags(cfg *config.AgentFileConfig) {
+	overrides := map[string]*string{
+		"server":          &cfg.Server.Addr,
+		"id":              &cfg.Node.ID,
+		"status-addr":     &cfg.StatusAddr,
+		"tls-ca":          &cfg.Server.TLS.CAFile,
+		"tls-cert":        &cfg.Server.TLS.CertFile,
+		"tls-key":         &cfg.Server.TLS.KeyFile,
+		"tls-server-name": &cfg.Server.TLS.ServerName,
+		"join-token":      &cfg.Server.JoinToken,
+		"credential-file": &cfg.CredentialFile,
+		"outbox-file":     &cfg.OutboxFile,
+		"log-file":        &cfg.Log.File,
+		"log-level":       &cfg.Log.Level,
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if field, ok := overrides[f.Name]; ok {
+			*field = f.Value.String()
+		}
+		if f.Name == "server" {
+			cfg.Server.Addrs = nil
+		}
+		if f.Name == "log-both" {
+			cfg.Log.Both = *logBoth
+		}
+	})
+}
+
+// serveStatus 提供本地状态接口，返回连接状态和正在执行的 Pipeline，/metrics 提供 RPC 指标
+func serveStatus(addr string, agent *grpc.Agent, pipelineAgent *grpc.GamePipelineAgent, logger utils.Logger) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"connection": agent.ConnectionStatus(),
+			"pipelines":  pipelineAgent.GetSourceIDs(),
+		})
+	})
+	mux.Handle("/metrics", metrics.Handler())
+
+	logger.Info("本地状态接口监听: %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Error("本地状态接口启动失败: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"flag"
 	"fmt"
 	"os"
@@ -12,7 +13,10 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/open-beagle/beagle-wind-game/internal/api"
+	"github.com/open-beagle/beagle-wind-game/internal/config"
 	"github.com/open-beagle/beagle-wind-game/internal/grpc"
+	"github.com/open-beagle/beagle-wind-game/internal/metrics"
+	"github.com/open-beagle/beagle-wind-game/internal/models"
 	"github.com/open-beagle/beagle-wind-game/internal/service"
 	"github.com/open-beagle/beagle-wind-game/internal/store"
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
@@ -74,6 +78,10 @@ func main() {
 	logLevel := flag.String("log-level", "INFO", "日志级别: DEBUG, INFO, WARN, ERROR, FATAL (用于gRPC服务)")
 	logFile := flag.String("log-file", "", "日志文件路径, 为空则只输出到控制台 (用于gRPC服务)")
 	logBoth := flag.Bool("log-both", false, "是否同时输出到文件和控制台 (用于gRPC服务)")
+	configPath := flag.String("config", "config/server.yaml", "服务端配置文件路径")
+	tlsCert := flag.String("tls-cert", "", "gRPC 服务端证书文件")
+	tlsKey := flag.String("tls-key", "", "gRPC 服务端私钥文件")
+	tlsClientCA := flag.String("tls-client-ca", "", "校验节点客户端证书的 CA 文件")
 	showVersion := flag.Bool("version", false, "显示版本信息")
 	flag.Parse()
 
@@ -99,6 +107,12 @@ func main() {
 	utils.InitLogger(*logFile, level, *logBoth)
 	logger := utils.New("gRPC")
 
+	// 加载服务端配置
+	serverConfig, err := config.LoadServerFileConfig(*configPath)
+	if err != nil {
+		logger.Fatal("加载配置失败: %v", err)
+	}
+
 	// 创建错误通道
 	errCh := make(chan error, 1)
 
@@ -108,6 +122,11 @@ func main() {
 	if err != nil {
 		logger.Fatal("初始化存储失败: %v", err)
 	}
+	joinTokenStore := store.NewYAMLJoinTokenStore(context.Background(), "data/jointokens.yaml")
+	diagnosticsStore := store.NewYAMLDiagnosticsStore(context.Background(), "data/diagnostics.yaml")
+	portStore := store.NewYAMLPortReservationStore(context.Background(), "data/ports.yaml")
+	accessStore := store.NewYAMLAccessGrantStore(context.Background(), "data/access.yaml")
+	usageStore := store.NewYAMLUsageStore(context.Background(), "data/usage.yaml")
 	logger.Info("存储初始化完成")
 
 	// 创建服务实例
@@ -117,30 +136,216 @@ func main() {
 	platformService := service.NewGamePlatformService(gamePlatformStore)
 	cardService := service.NewGameCardService(gameCardStore)
 	instanceService := service.NewGameInstanceService(gameInstanceStore)
+	joinTokenService := service.NewJoinTokenService(joinTokenStore)
+	diagnosticsService := service.NewDiagnosticsService(diagnosticsStore, "data/diagnostics")
+	// 节点使用加入令牌或经管理员审批后才能接入
+	nodeService.SetJoinTokenService(joinTokenService)
 
 	// 创建 gRPC 服务器
 	logger.Info("创建 gRPC 服务器...")
-	grpcServer := grpc.NewGRPCServer(
+	grpcServer, err := grpc.NewGRPCServer(
 		nodeService,
 		pipelineService,
 		logger,
 		&grpc.ServerConfig{
-			MaxConnections:  100,
-			HeartbeatPeriod: time.Second * 30,
+			MaxConnections:     100,
+			HeartbeatPeriod:    time.Second * 30,
+			OfflineGracePeriod: serverConfig.Node.OfflineGracePeriod,
+			TLS: grpc.TLSConfig{
+				CAFile:   *tlsClientCA,
+				CertFile: *tlsCert,
+				KeyFile:  *tlsKey,
+			},
 		},
 	)
+	if err != nil {
+		logger.Fatal("创建 gRPC 服务器失败: %v", err)
+	}
+
+	// 流水线通过节点的 PipelineStream 会话下发
+	pipelineService.SetTemplateSource(serverConfig.Pipeline.TemplateDir, serverConfig.Envs)
+	pipelineService.SetNodeService(nodeService)
+	pipelineService.SetDispatcher(grpcServer.GetPipelineServer())
+
+	// 步骤日志按步骤保存为独立文件，不写入流水线数据
+	logCfg := serverConfig.Pipeline.Logs
+	pipelineService.SetLogStore(store.NewStepLogStore(logCfg.Dir, logCfg.MaxFileSize, logCfg.MaxFiles))
+
+	// 节点连接信息由 gRPC 连接注册表提供，节点离线后其上的流水线和实例标记为失联
+	nodeService.SetConnectionProvider(grpcServer.GetConnections())
+	if nodes, err := nodeService.ListAll(context.Background()); err == nil {
+		for _, node := range nodes {
+			if node.Status.Online {
+				grpcServer.GetConnections().Expect(node.ID)
+			}
+		}
+	}
+	grpcServer.GetConnections().OnOffline(func(ctx context.Context, nodeID string) {
+		if _, err := pipelineService.MarkNodeLost(ctx, nodeID); err != nil {
+			logger.Error("标记节点 %s 的流水线失联失败: %v", nodeID, err)
+		}
+		if _, err := instanceService.MarkNodeLost(ctx, nodeID); err != nil {
+			logger.Error("标记节点 %s 的实例失联失败: %v", nodeID, err)
+		}
+	})
+
+	// 维护状态变更后通知节点，禁用节点上未结束的流水线被取消
+	nodeService.OnStateChange(func(ctx context.Context, nodeID string, from, to models.GameNodeStaticState) {
+		if to == models.GameNodeStaticStateDisabled {
+			if _, err := pipelineService.CancelNodePipelines(ctx, nodeID, "节点已禁用"); err != nil {
+				logger.Error("取消节点 %s 的流水线失败: %v", nodeID, err)
+			}
+		}
+		if err := grpcServer.GetPipelineServer().SendNodeState(ctx, nodeID, to); err != nil {
+			logger.Warn("通知节点 %s 维护状态变更失败: %v", nodeID, err)
+		}
+		// 恢复正常状态后下发维护期间排队的流水线
+		if to == models.GameNodeStaticStateNormal && grpcServer.GetPipelineServer().IsConnected(nodeID) {
+			if err := pipelineService.DispatchQueued(ctx, nodeID); err != nil {
+				logger.Warn("下发节点 %s 排队的流水线失败: %v", nodeID, err)
+			}
+		}
+	})
+
+	// 诊断命令通过节点的 PipelineStream 会话下发，诊断包由节点上传
+	diagnosticsService.SetNodeService(nodeService)
+	diagnosticsService.SetRequester(grpcServer.GetPipelineServer())
+	grpcServer.GetPipelineServer().SetDiagnosticsService(diagnosticsService)
+
+	// 容器清单核对，孤立容器通过节点的 PipelineStream 会话删除
+	inventoryService := service.NewInventoryService(nodeService, instanceService, pipelineService, service.InventoryOptions{
+		Interval:    serverConfig.Inventory.Interval,
+		Grace:       serverConfig.Inventory.Grace,
+		AutoCleanup: serverConfig.Inventory.AutoCleanup,
+	})
+	inventoryService.SetRemover(grpcServer.GetPipelineServer())
+	// 节点重新连接后上报容器清单，失联实例由此恢复为运行中或标记为失败
+	nodeService.OnContainersUpdate(inventoryService.HandleContainers)
+
+	// 未指定节点的实例由调度器按资源要求选择节点
+	scheduler, err := service.NewInstanceScheduler(nodeService, cardService, platformService, instanceService, models.SchedulingPolicy(serverConfig.Scheduler.Policy))
+	if err != nil {
+		logger.Fatal("创建实例调度器失败: %v", err)
+	}
+	instanceService.SetScheduler(scheduler)
+
+	// 实例通过 start-platform / stop-platform 流水线启动和停止，流水线状态变更时更新实例状态
+	instanceService.SetLifecycle(pipelineService, platformService, cardService)
+	pipelineService.OnStateChange(instanceService.HandlePipelineState)
+
+	// 实例启动时在节点上分配主机端口，核对容器清单时同时核对端口预留
+	portAllocator, err := service.NewPortAllocator(portStore, serverConfig.Ports.Start, serverConfig.Ports.End)
+	if err != nil {
+		logger.Fatal("创建端口分配器失败: %v", err)
+	}
+	instanceService.SetPortAllocator(portAllocator)
+	inventoryService.SetPortAllocator(portAllocator)
+
+	// 配置了 TURN 共享密钥时每次启动实例签发新的临时凭证
+	if serverConfig.TURN.Secret != "" {
+		turnIssuer, err := service.NewTURNCredentialIssuer(serverConfig.TURN.Secret, serverConfig.TURN.TTL)
+		if err != nil {
+			logger.Fatal("创建 TURN 凭证签发器失败: %v", err)
+		}
+		instanceService.SetTURNCredentials(turnIssuer)
+	}
+
+	// 启用存档同步时启动前恢复用户的最新存档，实例停止或崩溃后上传存档
+	if serverConfig.Saves.Enabled {
+		s3Options := service.S3Options{
+			Endpoint:  serverConfig.Saves.Endpoint,
+			Region:    serverConfig.Saves.Region,
+			Bucket:    serverConfig.Saves.Bucket,
+			AccessKey: serverConfig.Saves.AccessKey,
+			SecretKey: serverConfig.Saves.SecretKey,
+		}
+		saveStorage, err := service.NewS3Storage(s3Options)
+		if err != nil {
+			logger.Fatal("创建存档存储失败: %v", err)
+		}
+		saveService := service.NewSaveService(saveStorage, instanceService, pipelineService, cardService, service.SaveOptions{
+			Prefix: serverConfig.Saves.Prefix,
+			S3:     s3Options,
+		})
+		instanceService.SetSaveService(saveService)
+		instanceService.OnStateChange(saveService.HandleInstanceState)
+		pipelineService.OnStateChange(saveService.HandlePipelineState)
+	}
+
+	// 根据节点上报的活动采样自动停止空闲实例，停止时按正常流程上传存档
+	var idleService *service.IdleService
+	if serverConfig.Idle.Enabled {
+		idleService = service.NewIdleService(nodeService, instanceService, service.IdleOptions{
+			Interval:              serverConfig.Idle.Interval,
+			Timeout:               serverConfig.Idle.Timeout,
+			Warning:               serverConfig.Idle.Warning,
+			CPUThreshold:          serverConfig.Idle.CPUThreshold,
+			GPUThreshold:          serverConfig.Idle.GPUThreshold,
+			IdleWithoutConnection: serverConfig.Idle.IdleWithoutConnection,
+		})
+	}
+
+	// 实例进入运行中时开始会话，停止或失败时结束会话，会话记录用于用量统计
+	usageService := service.NewUsageService(usageStore, nodeService)
+	instanceService.OnStateChange(usageService.HandleInstanceState)
+
+	// 实例和节点的访问链接使用签名的访问令牌，服务端校验后跳转到节点上的访问地址
+	accessSecret := []byte(serverConfig.Access.Secret)
+	if len(accessSecret) == 0 {
+		accessSecret = make([]byte, 32)
+		if _, err := rand.Read(accessSecret); err != nil {
+			logger.Fatal("生成访问令牌签名密钥失败: %v", err)
+		}
+		logger.Warn("未配置访问令牌签名密钥，使用随机密钥，重启后之前签发的访问链接失效")
+	}
+	accessService, err := service.NewAccessService(accessStore, nodeService, instanceService, service.AccessOptions{
+		Secret:    accessSecret,
+		TTL:       serverConfig.Access.TTL,
+		BaseURL:   serverConfig.Access.BaseURL,
+		IssuerKey: serverConfig.Access.IssuerKey,
+	})
+	if err != nil {
+		logger.Fatal("创建访问令牌服务失败: %v", err)
+	}
+	if serverConfig.Access.IssuerKey == "" {
+		logger.Warn("未配置访问链接签发密钥，不签发访问链接")
+	}
+	nodeService.SetAccessService(accessService)
+	platformService.SetAccessService(accessService)
 
 	// 设置 HTTP 路由
 	router := gin.Default()
+	// 签发访问链接的请求需携带签发密钥，访问用户由持有密钥的调用方指定
+	router.Use(api.RequireAccessIssuer(accessService))
 
 	// 注册路由处理器
 	gamenodeHandler := api.NewGameNodeHandler(nodeService)
 	gamenodeHandler.RegisterRoutes(router)
+	pipelineHandler := api.NewGamePipelineHandler(pipelineService)
+	pipelineHandler.RegisterRoutes(router)
+	joinTokenHandler := api.NewJoinTokenHandler(joinTokenService)
+	joinTokenHandler.RegisterRoutes(router)
+	diagnosticsHandler := api.NewDiagnosticsHandler(diagnosticsService)
+	diagnosticsHandler.RegisterRoutes(router)
+	inventoryHandler := api.NewInventoryHandler(inventoryService)
+	inventoryHandler.RegisterRoutes(router)
+	instanceHandler := api.NewGameInstanceHandler(instanceService)
+	instanceHandler.RegisterRoutes(router)
+	accessHandler := api.NewAccessHandler(accessService)
+	accessHandler.RegisterRoutes(router)
+	usageHandler := api.NewUsageHandler(usageService)
+	usageHandler.RegisterRoutes(router)
+	// 实例桌面通过 /play/{id}/ 代理访问，实例停止后断开代理连接
+	playHandler := api.NewPlayHandler(accessService)
+	playHandler.RegisterRoutes(router)
+	instanceService.OnStateChange(playHandler.HandleInstanceState)
+
+	// Prometheus 指标
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	// TODO: 其他服务的路由处理器将在实现后添加
 	_ = platformService // 避免未使用变量警告
 	_ = cardService     // 避免未使用变量警告
-	_ = instanceService // 避免未使用变量警告
 
 	// 创建上下文
 	ctx, cancel := context.WithCancel(context.Background())
@@ -154,6 +359,14 @@ func main() {
 		}
 	}()
 
+	// 定期核对容器清单
+	go inventoryService.Run(ctx)
+
+	// 定期检测空闲实例
+	if idleService != nil {
+		go idleService.Run(ctx)
+	}
+
 	// 启动 gRPC 服务器
 	go func() {
 		logger.Info("gRPC服务器开始监听 %s", *grpcAddr)
@@ -183,7 +396,7 @@ func main() {
 
 	// 关闭存储层，确保数据保存
 	logger.Info("正在关闭所有存储...")
-	closeStores(gamenodeStore, gameCardStore, gameInstanceStore, gamePlatformStore, GamePipelineStore)
+	closeStores(gamenodeStore, gameCardStore, gameInstanceStore, gamePlatformStore, GamePipelineStore, joinTokenStore, diagnosticsStore, portStore, accessStore, usageStore)
 
 	// 等待一段时间让服务器完成关闭
 	time.Sleep(5 * time.Second)
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+)
+
+// AccessUserHeader 标识访问用户的请求头，也可以使用 user_id 查询参数
+const AccessUserHeader = "X-User-ID"
+
+// AccessIssuerHeader 签发访问令牌时携带签发密钥的请求头
+const AccessIssuerHeader = "X-Access-Issuer-Key"
+
+// accessIssueRoutes 签发访问令牌的路由，由调用方指定访问用户
+var accessIssueRoutes = map[string]bool{
+	"/api/v1/instances/:id/access":         true,
+	"/api/v1/instances/:id/access/refresh": true,
+	"/api/v1/nodes/:id/access":             true,
+	"/api/v1/nodes/:id/access/refresh":     true,
+	"/api/v1/platforms/:id/access":         true,
+	"/api/v1/platforms/:id/access/refresh": true,
+}
+
+// RequireAccessIssuer 签发访问令牌的请求需携带签发密钥，其他请求不受影响
+// 访问用户取自请求头或查询参数，只有持有签发密钥的可信调用方才能为用户签发令牌
+func RequireAccessIssuer(svc *service.AccessService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !accessIssueRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+		if err := svc.AuthorizeIssuer(c.GetHeader(AccessIssuerHeader)); err != nil {
+			accessError(c, "签发访问令牌失败", err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AccessHandler 处理实例访问链接和访问令牌校验相关的 HTTP 请求
+type AccessHandler struct {
+	svc *service.AccessService
+}
+
+// NewAccessHandler 创建新的 AccessHandler
+func NewAccessHandler(svc *service.AccessService) *AccessHandler {
+	return &AccessHandler{
+		svc: svc,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *AccessHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/api/v1/instances/:id/access", h.GetInstanceAccess)
+	r.POST("/api/v1/instances/:id/access/refresh", h.RefreshInstanceAccess)
+	r.GET("/access/:token", h.Redirect)
+}
+
+// GetInstanceAccess 获取实例访问链接
+// @Summary 获取实例访问链接
+// @Description 为用户签发运行中实例的访问链接，链接带有签名的访问令牌，过期前有效
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Param id path string true "实例ID"
+// @Param X-User-ID header string true "访问用户"
+// @Param X-Access-Issuer-Key header string true "签发密钥"
+// @Success 200 {object} service.AccessResult "访问链接"
+// @Failure 400 {object} map[string]interface{} "缺少访问用户"
+// @Failure 401 {object} map[string]interface{} "签发密钥错误"
+// @Failure 404 {object} map[string]interface{} "实例不存在"
+// @Failure 409 {object} map[string]interface{} "实例未运行"
+// @Router /api/v1/instances/{id}/access [get]
+func (h *AccessHandler) GetInstanceAccess(c *gin.Context) {
+	result, err := h.svc.IssueInstance(c.Request.Context(), c.Param("id"), accessUser(c))
+	if err != nil {
+		accessError(c, "获取实例访问链接失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// RefreshInstanceAccess 刷新实例访问链接
+// @Summary 刷新实例访问链接
+// @Description 吊销用户之前获取的实例访问链接并签发新的链接
+// @Tags 访问令牌
+// @Accept json
+// @Produce json
+// @Param id path string true "实例ID"
+// @Param X-User-ID header string true "访问用户"
+// @Param X-Access-Issuer-Key header string true "签发密钥"
+// @Success 200 {object} service.AccessResult "访问链接"
+// @Failure 400 {object} map[string]interface{} "缺少访问用户"
+// @Failure 401 {object} map[string]interface{} "签发密钥错误"
+// @Failure 404 {object} map[string]interface{} "实例不存在"
+// @Failure 409 {object} map[string]interface{} "实例未运行"
+// @Router /api/v1/instances/{id}/access/refresh [post]
+func (h *AccessHandler) RefreshInstanceAccess(c *gin.Context) {
+	result, err := h.svc.RefreshInstance(c.Request.Context(), c.Param("id"), accessUser(c))
+	if err != nil {
+		accessError(c, "刷新实例访问链接失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// Redirect 校验访问令牌并跳转到访问地址，实例跳转到 /play/{id}/ 代理，节点跳转到节点地址
+// @Summary 使用访问链接
+// @Description 校验访问令牌的签名、有效期和吊销状态，通过后实例跳转到服务端的 /play/{id}/ 代理，节点跳转到节点地址
+// @Tags 访问令牌
+// @Param token path string true "访问令牌"
+// @Success 302 "跳转到访问地址"
+// @Failure 401 {object} map[string]interface{} "令牌无效、过期或已吊销"
+// @Failure 409 {object} map[string]interface{} "访问目标不可用"
+// @Router /access/{token} [get]
+func (h *AccessHandler) Redirect(c *gin.Context) {
+	target, err := h.svc.Verify(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		accessError(c, "访问链接无效", err)
+		return
+	}
+
+	// 实例通过服务端的代理访问，节点直接跳转到节点地址
+	if target.Claims.Scope == models.AccessScopeInstance {
+		c.Redirect(http.StatusFound, playPrefix(target.Claims.Target)+"/?"+playTokenQuery+"="+url.QueryEscape(c.Param("token")))
+		return
+	}
+	c.Redirect(http.StatusFound, target.URL.String())
+}
+
+// accessUser 获取请求的访问用户
+func accessUser(c *gin.Context) string {
+	if user := strings.TrimSpace(c.GetHeader(AccessUserHeader)); user != "" {
+		return user
+	}
+	return strings.TrimSpace(c.Query("user_id"))
+}
+
+// accessStatus 访问令牌错误对应的 HTTP 状态码
+func accessStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrMissingAccessUser):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidAccessToken), errors.Is(err, service.ErrAccessTokenExpired), errors.Is(err, service.ErrAccessTokenRevoked),
+		errors.Is(err, service.ErrAccessIssuerUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrAccessUnavailable):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrAccessDisabled):
+		return http.StatusServiceUnavailable
+	case strings.Contains(err.Error(), "不存在"):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// accessError 返回访问令牌错误
+func accessError(c *gin.Context, message string, err error) {
+	code := accessStatus(err)
+	c.JSON(code, gin.H{
+		"code":    code,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAccessIssuer(t *testing.T) {
+	access, _ := newTestAccess(t, "10.0.0.5", 30001, "issuer-key")
+	router := gin.New()
+	router.Use(RequireAccessIssuer(access))
+	NewAccessHandler(access).RegisterRoutes(router)
+
+	request := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/instances/inst-1/access", nil)
+		req.Header.Set(AccessUserHeader, "alice")
+		if key != "" {
+			req.Header.Set(AccessIssuerHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	// 没有签发密钥的调用方不能为任意用户签发令牌
+	assert.Equal(t, http.StatusUnauthorized, request(""))
+	assert.Equal(t, http.StatusUnauthorized, request("wrong"))
+	assert.Equal(t, http.StatusOK, request("issuer-key"))
+
+	// 使用访问链接不需要签发密钥
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/access/invalid", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 未配置签发密钥时不签发令牌
+	disabled, _ := newTestAccess(t, "10.0.0.5", 30001, "")
+	router = gin.New()
+	router.Use(RequireAccessIssuer(disabled))
+	NewAccessHandler(disabled).RegisterRoutes(router)
+	assert.Equal(t, http.StatusServiceUnavailable, request("issuer-key"))
+}
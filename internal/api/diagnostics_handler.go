@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+)
+
+// DiagnosticsHandler 处理节点诊断包相关的 HTTP 请求
+type DiagnosticsHandler struct {
+	svc *service.DiagnosticsService
+}
+
+// NewDiagnosticsHandler 创建新的 DiagnosticsHandler
+func NewDiagnosticsHandler(svc *service.DiagnosticsService) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		svc: svc,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *DiagnosticsHandler) RegisterRoutes(r *gin.Engine) {
+	r.POST("/api/v1/nodes/:id/diagnostics", h.Request)
+
+	diagnostics := r.Group("/api/v1/diagnostics")
+	{
+		diagnostics.GET("", h.List)
+		diagnostics.GET("/:id", h.Get)
+		diagnostics.GET("/:id/download", h.Download)
+	}
+}
+
+// Request 请求节点收集诊断包
+// @Summary 请求节点收集诊断包
+// @Description 通过节点的 PipelineStream 会话下发诊断命令，节点收集 Agent 配置、最近日志、容器信息、系统信息和磁盘占用后打包上传
+// @Tags 节点诊断
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Success 202 {object} models.DiagnosticsBundle "等待上传的诊断包"
+// @Failure 400 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "节点未连接"
+// @Router /api/v1/nodes/{id}/diagnostics [post]
+func (h *DiagnosticsHandler) Request(c *gin.Context) {
+	bundle, err := h.svc.Request(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNodeNotConnected):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    http.StatusConflict,
+				"message": "节点未连接",
+				"error":   err.Error(),
+			})
+		case errors.Is(err, service.ErrDiagnosticsDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    http.StatusServiceUnavailable,
+				"message": "诊断信息收集未启用",
+				"error":   err.Error(),
+			})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "请求诊断包失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    bundle,
+	})
+}
+
+// List 获取诊断包列表
+// @Summary 获取诊断包列表
+// @Description 获取诊断包列表，按请求时间倒序排列
+// @Tags 节点诊断
+// @Accept json
+// @Produce json
+// @Param node_id query string false "节点ID"
+// @Success 200 {array} models.DiagnosticsBundle "诊断包列表"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/diagnostics [get]
+func (h *DiagnosticsHandler) List(c *gin.Context) {
+	bundles, err := h.svc.List(c.Request.Context(), c.Query("node_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": "获取诊断包列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    bundles,
+	})
+}
+
+// Get 获取诊断包状态
+// @Summary 获取诊断包状态
+// @Description 获取诊断包的收集状态，state 为 completed 时可以下载
+// @Tags 节点诊断
+// @Accept json
+// @Produce json
+// @Param id path string true "诊断包ID"
+// @Success 200 {object} models.DiagnosticsBundle "诊断包"
+// @Failure 404 {object} map[string]interface{} "诊断包不存在"
+// @Router /api/v1/diagnostics/{id} [get]
+func (h *DiagnosticsHandler) Get(c *gin.Context) {
+	bundle, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    http.StatusNotFound,
+			"message": "诊断包不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    bundle,
+	})
+}
+
+// Download 下载诊断包
+// @Summary 下载诊断包
+// @Description 下载 tar.gz 格式的诊断包
+// @Tags 节点诊断
+// @Produce application/gzip
+// @Param id path string true "诊断包ID"
+// @Success 200 {file} file "诊断包"
+// @Failure 404 {object} map[string]interface{} "诊断包不存在"
+// @Failure 409 {object} map[string]interface{} "诊断包尚未就绪"
+// @Router /api/v1/diagnostics/{id}/download [get]
+func (h *DiagnosticsHandler) Download(c *gin.Context) {
+	f, bundle, err := h.svc.Open(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDiagnosticsNotReady):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    http.StatusConflict,
+				"message": "诊断包尚未就绪",
+				"error":   err.Error(),
+			})
+		case errors.Is(err, service.ErrDiagnosticsNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    http.StatusNotFound,
+				"message": "诊断包不存在",
+				"error":   err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    http.StatusInternalServerError,
+				"message": "下载诊断包失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+	defer f.Close()
+
+	filename := fmt.Sprintf("diagnostics-%s-%s.tar.gz", bundle.NodeID, bundle.CreatedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.DataFromReader(http.StatusOK, bundle.Size, "application/gzip", f, nil)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +22,22 @@ func NewGameInstanceHandler(service *service.GameInstanceService) *GameInstanceH
 	}
 }
 
+// RegisterRoutes 注册路由
+func (h *GameInstanceHandler) RegisterRoutes(r *gin.Engine) {
+	instances := r.Group("/api/v1/instances")
+	{
+		instances.GET("", h.List)
+		instances.GET("/:id", h.Get)
+		instances.POST("", h.Create)
+		instances.POST("/schedule", h.Schedule)
+		instances.POST("/:id/update", h.Update)
+		instances.POST("/:id/delete", h.Delete)
+		// 实例操作
+		instances.POST("/:id/start", h.Start)
+		instances.POST("/:id/stop", h.Stop)
+	}
+}
+
 // List 获取实例列表
 // @Summary 获取实例列表
 // @Description 获取游戏实例列表，支持分页和筛选
@@ -30,7 +47,7 @@ func NewGameInstanceHandler(service *service.GameInstanceService) *GameInstanceH
 // @Param page query int false "页码"
 // @Param size query int false "每页数量"
 // @Param keyword query string false "搜索关键词"
-// @Param status query string false "实例状态(running/stopped/paused/error)"
+// @Param status query string false "实例状态(pending/scheduling/preparing/starting/running/stopping/stopped/failed/lost)"
 // @Param node_id query string false "节点ID"
 // @Param card_id query string false "游戏卡片ID"
 // @Param platform_id query string false "平台ID"
@@ -92,12 +109,14 @@ func (h *GameInstanceHandler) Get(c *gin.Context) {
 
 // Create 创建实例
 // @Summary 创建实例
-// @Description 创建新的游戏实例
+// @Description 创建新的游戏实例，未指定 node_id 时由调度器按资源要求选择节点
 // @Tags 游戏实例
 // @Accept json
 // @Produce json
 // @Param body body service.CreateInstanceParams true "创建实例参数"
 // @Success 201 {object} gin.H "包含新创建的实例ID"
+// @Failure 400 {object} gin.H "请求参数错误或未启用调度"
+// @Failure 409 {object} gin.H "没有满足资源要求的节点，rejections 说明各节点被排除的原因"
 // @Router /api/v1/instances [post]
 func (h *GameInstanceHandler) Create(c *gin.Context) {
 	var params service.CreateInstanceParams
@@ -108,13 +127,57 @@ func (h *GameInstanceHandler) Create(c *gin.Context) {
 
 	id, err := h.service.Create(c, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		scheduleError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
 
+// Schedule 预览实例调度结果
+// @Summary 预览实例调度结果
+// @Description 按游戏卡片和平台的资源要求计算实例将被调度到的节点，不创建实例也不预留资源
+// @Tags 游戏实例
+// @Accept json
+// @Produce json
+// @Param body body service.ScheduleParams true "调度参数"
+// @Success 200 {object} models.SchedulingDecision "调度结果"
+// @Failure 400 {object} gin.H "请求参数错误或未启用调度"
+// @Failure 409 {object} gin.H "没有满足资源要求的节点，rejections 说明各节点被排除的原因"
+// @Router /api/v1/instances/schedule [post]
+func (h *GameInstanceHandler) Schedule(c *gin.Context) {
+	var params service.ScheduleParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decision, err := h.service.Schedule(c, params)
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+// scheduleError 将调度错误转换为 HTTP 响应
+func scheduleError(c *gin.Context, err error) {
+	var schedErr *service.SchedulingError
+	switch {
+	case errors.As(err, &schedErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":        err.Error(),
+			"requirements": schedErr.Requirements,
+			"rejections":   schedErr.Rejections,
+		})
+	case errors.Is(err, service.ErrSchedulerDisabled), errors.Is(err, service.ErrInvalidPolicy), errors.Is(err, service.ErrMissingPlacementID), errors.Is(err, service.ErrInvalidUserID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // Update 更新实例
 // @Summary 更新实例
 // @Description 更新游戏实例信息
@@ -153,7 +216,6 @@ func (h *GameInstanceHandler) Update(c *gin.Context) {
 	// 将参数转换为 GameInstance
 	instance = models.GameInstance{
 		ID:          id,
-		Status:      params.Status,
 		Resources:   params.Resources,
 		Performance: params.Performance,
 		SaveData:    params.SaveData,
@@ -172,12 +234,13 @@ func (h *GameInstanceHandler) Update(c *gin.Context) {
 
 // Delete 删除实例
 // @Summary 删除实例
-// @Description 删除指定的游戏实例
+// @Description 删除指定的游戏实例，未结束的实例需先停止
 // @Tags 游戏实例
 // @Accept json
 // @Produce json
 // @Param id path string true "实例ID"
 // @Success 204 "无内容"
+// @Failure 409 {object} gin.H "实例未结束"
 // @Router /api/v1/instances/{id} [delete]
 func (h *GameInstanceHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
@@ -200,6 +263,10 @@ func (h *GameInstanceHandler) Delete(c *gin.Context) {
 
 	err = h.service.Delete(c, id)
 	if err != nil {
+		if errors.Is(err, service.ErrInstanceStateTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -209,12 +276,12 @@ func (h *GameInstanceHandler) Delete(c *gin.Context) {
 
 // Start 启动实例
 // @Summary 启动实例
-// @Description 启动指定的游戏实例
+// @Description 提交启动流水线启动指定的游戏实例，实例状态随流水线执行更新
 // @Tags 游戏实例
 // @Accept json
 // @Produce json
 // @Param id path string true "实例ID"
-// @Success 204 "无内容"
+// @Success 202 {object} models.GameInstance "实例详情"
 // @Router /api/v1/instances/{id}/start [post]
 func (h *GameInstanceHandler) Start(c *gin.Context) {
 	id := c.Param("id")
@@ -235,23 +302,23 @@ func (h *GameInstanceHandler) Start(c *gin.Context) {
 		return
 	}
 
-	err = h.service.Start(c, id)
+	instance, err = h.service.Start(c, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		lifecycleError(c, err)
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusAccepted, instance)
 }
 
 // Stop 停止实例
 // @Summary 停止实例
-// @Description 停止指定的游戏实例
+// @Description 提交停止流水线停止指定的游戏实例，实例状态随流水线执行更新
 // @Tags 游戏实例
 // @Accept json
 // @Produce json
 // @Param id path string true "实例ID"
-// @Success 204 "无内容"
+// @Success 202 {object} models.GameInstance "实例详情"
 // @Router /api/v1/instances/{id}/stop [post]
 func (h *GameInstanceHandler) Stop(c *gin.Context) {
 	id := c.Param("id")
@@ -272,11 +339,23 @@ func (h *GameInstanceHandler) Stop(c *gin.Context) {
 		return
 	}
 
-	err = h.service.Stop(c, id)
+	instance, err = h.service.Stop(c, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		lifecycleError(c, err)
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusAccepted, instance)
+}
+
+// lifecycleError 将实例启动和停止的错误转换为 HTTP 响应
+func lifecycleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInstanceAlreadyRunning), errors.Is(err, service.ErrInstanceNotRunning), errors.Is(err, service.ErrInstanceStateTransition), errors.Is(err, service.ErrSaveUploading):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLifecycleDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		scheduleError(c, err)
+	}
 }
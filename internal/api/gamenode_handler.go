@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -28,8 +31,14 @@ func (h *GameNodeHandler) RegisterRoutes(r *gin.Engine) {
 	{
 		nodes.GET("", h.ListNodes)
 		nodes.GET("/:id", h.GetNode)
+		nodes.GET("/:id/connection", h.GetNodeConnection)
+		nodes.GET("/:id/access", h.GetNodeAccess)
+		nodes.POST("/:id/access/refresh", h.RefreshNodeAccess)
 		nodes.POST("/:id/update", h.UpdateNode)
 		nodes.POST("/:id/delete", h.DeleteNode)
+		nodes.POST("/:id/state", h.UpdateNodeState)
+		nodes.POST("/:id/approve", h.ApproveNode)
+		nodes.POST("/:id/reject", h.RejectNode)
 	}
 }
 
@@ -45,6 +54,7 @@ func (h *GameNodeHandler) RegisterRoutes(r *gin.Engine) {
 // @Param status query string false "节点状态(online/offline/maintenance)"
 // @Param type query string false "节点类型"
 // @Param region query string false "区域"
+// @Param approval query string false "审批状态(pending/approved/rejected)"
 // @Param sort_by query string false "排序字段(created_at/updated_at/status)"
 // @Param sort_order query string false "排序方向(asc/desc)"
 // @Success 200 {object} service.GameNodeListResult "节点列表"
@@ -175,6 +185,102 @@ func (h *GameNodeHandler) GetNode(c *gin.Context) {
 	})
 }
 
+// GetNodeConnection 获取节点连接信息
+// @Summary 获取节点连接信息
+// @Description 获取节点的在线状态、来源地址、最近心跳时间和 PipelineStream 会话信息
+// @Tags 游戏节点
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Success 200 {object} models.NodeConnection "连接信息"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/nodes/{id}/connection [get]
+func (h *GameNodeHandler) GetNodeConnection(c *gin.Context) {
+	nodeID := c.Param("id")
+	conn, err := h.svc.GetConnection(c.Request.Context(), nodeID)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "节点不存在") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    http.StatusNotFound,
+				"message": "节点不存在",
+				"error":   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": "获取节点连接信息失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    conn,
+	})
+}
+
+// GetNodeAccess 获取节点访问链接
+// @Summary 获取节点访问链接
+// @Description 为用户签发节点的访问链接，链接带有签名的访问令牌，过期前有效
+// @Tags 游戏节点
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Param X-User-ID header string true "访问用户"
+// @Param X-Access-Issuer-Key header string true "签发密钥"
+// @Success 200 {object} service.NodeAccessResult "访问链接"
+// @Failure 400 {object} map[string]interface{} "缺少访问用户"
+// @Failure 401 {object} map[string]interface{} "签发密钥错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "无法确定节点地址"
+// @Router /api/v1/nodes/{id}/access [get]
+func (h *GameNodeHandler) GetNodeAccess(c *gin.Context) {
+	result, err := h.svc.GetAccess(c.Request.Context(), c.Param("id"), accessUser(c))
+	if err != nil {
+		accessError(c, "获取节点访问链接失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// RefreshNodeAccess 刷新节点访问链接
+// @Summary 刷新节点访问链接
+// @Description 吊销用户之前获取的节点访问链接并签发新的链接
+// @Tags 游戏节点
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Param X-User-ID header string true "访问用户"
+// @Param X-Access-Issuer-Key header string true "签发密钥"
+// @Success 200 {object} service.NodeAccessResult "访问链接"
+// @Failure 400 {object} map[string]interface{} "缺少访问用户"
+// @Failure 401 {object} map[string]interface{} "签发密钥错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "无法确定节点地址"
+// @Router /api/v1/nodes/{id}/access/refresh [post]
+func (h *GameNodeHandler) RefreshNodeAccess(c *gin.Context) {
+	result, err := h.svc.RefreshAccess(c.Request.Context(), c.Param("id"), accessUser(c))
+	if err != nil {
+		accessError(c, "刷新节点访问链接失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
 // UpdateNode 更新节点信息
 // @Summary 更新节点信息
 // @Description 更新游戏节点的基本信息
@@ -186,6 +292,7 @@ func (h *GameNodeHandler) GetNode(c *gin.Context) {
 // @Success 200 {object} models.GameNode "更新后的节点信息"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
 // @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "不允许的状态转换"
 // @Failure 500 {object} map[string]interface{} "服务器内部错误"
 // @Router /api/v1/nodes/{id}/update [post]
 func (h *GameNodeHandler) UpdateNode(c *gin.Context) {
@@ -241,16 +348,6 @@ func (h *GameNodeHandler) UpdateNode(c *gin.Context) {
 	node.Type = models.GameNodeType(nodeUpdate.Type)
 	node.Location = nodeUpdate.Location
 	node.Labels = nodeUpdate.Labels
-	if nodeUpdate.MaintenanceMode {
-		node.Status.State = "maintenance"
-	} else if node.Status.State == "maintenance" {
-		// 如果从维护模式恢复，根据在线状态决定新状态
-		if node.Status.Online {
-			node.Status.State = "online"
-		} else {
-			node.Status.State = "offline"
-		}
-	}
 
 	// 调用服务层更新数据
 	err = h.svc.Update(c, node)
@@ -263,6 +360,21 @@ func (h *GameNodeHandler) UpdateNode(c *gin.Context) {
 		return
 	}
 
+	// 维护模式通过维护状态变更，从维护模式恢复时转换为正常状态
+	target := node.State.Effective()
+	if nodeUpdate.MaintenanceMode {
+		target = models.GameNodeStaticStateMaintenance
+	} else if target == models.GameNodeStaticStateMaintenance {
+		target = models.GameNodeStaticStateNormal
+	}
+	if target != node.State.Effective() {
+		node, err = h.svc.UpdateState(c.Request.Context(), nodeID, target)
+		if err != nil {
+			h.stateError(c, err)
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
@@ -335,3 +447,139 @@ func (h *GameNodeHandler) DeleteNode(c *gin.Context) {
 		"message": "节点删除成功",
 	})
 }
+
+// UpdateNodeState 变更节点维护状态
+// @Summary 变更节点维护状态
+// @Description 将节点设置为 normal、maintenance 或 disabled 状态。维护状态的节点不接收新的流水线，禁用节点上未结束的流水线会被取消，禁用状态不允许直接转换为维护状态
+// @Tags 游戏节点
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Param body body struct{State string `json:"state" binding:"required,oneof=normal maintenance disabled"`} true "目标状态"
+// @Success 200 {object} models.GameNode "更新后的节点信息"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 409 {object} map[string]interface{} "不允许的状态转换"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/nodes/{id}/state [post]
+func (h *GameNodeHandler) UpdateNodeState(c *gin.Context) {
+	nodeID := c.Param("id")
+
+	var req struct {
+		State string `json:"state" binding:"required,oneof=normal maintenance disabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	node, err := h.svc.UpdateState(c.Request.Context(), nodeID, models.GameNodeStaticState(req.State))
+	if err != nil {
+		h.stateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    node,
+	})
+}
+
+// stateError 返回维护状态变更失败的响应
+func (h *GameNodeHandler) stateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNodeStateTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    http.StatusConflict,
+			"message": "不允许的状态转换",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidNodeState):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "无效的节点维护状态",
+			"error":   err.Error(),
+		})
+	case strings.HasPrefix(err.Error(), "节点不存在"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    http.StatusNotFound,
+			"message": "节点不存在",
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": "变更节点维护状态失败",
+			"error":   err.Error(),
+		})
+	}
+}
+
+// ApproveNode 批准节点加入
+// @Summary 批准节点加入
+// @Description 批准等待审批的节点，节点在下一次注册时获得凭证
+// @Tags 游戏节点
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Success 200 {object} map[string]interface{} "操作结果"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/nodes/{id}/approve [post]
+func (h *GameNodeHandler) ApproveNode(c *gin.Context) {
+	h.setApproval(c, h.svc.Approve, "批准节点失败")
+}
+
+// RejectNode 拒绝节点加入
+// @Summary 拒绝节点加入
+// @Description 拒绝节点加入并作废节点已有的凭证
+// @Tags 游戏节点
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Success 200 {object} map[string]interface{} "操作结果"
+// @Failure 404 {object} map[string]interface{} "节点不存在"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/nodes/{id}/reject [post]
+func (h *GameNodeHandler) RejectNode(c *gin.Context) {
+	h.setApproval(c, h.svc.Reject, "拒绝节点失败")
+}
+
+// setApproval 调用审批操作并返回结果
+func (h *GameNodeHandler) setApproval(c *gin.Context, action func(ctx context.Context, id string) error, failMessage string) {
+	nodeID := c.Param("id")
+	if nodeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "节点ID不能为空",
+		})
+		return
+	}
+
+	if err := action(c.Request.Context(), nodeID); err != nil {
+		if strings.HasPrefix(err.Error(), "节点不存在") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    http.StatusNotFound,
+				"message": "节点不存在",
+				"error":   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": failMessage,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -25,9 +26,11 @@ func (h *GamePipelineHandler) RegisterRoutes(r *gin.Engine) {
 	pipelines := r.Group("/api/v1/pipelines")
 	{
 		pipelines.GET("", h.List)
+		pipelines.POST("", h.Submit)
 		pipelines.GET("/:id", h.Get)
 		pipelines.POST("/:id/cancel", h.Cancel)
 		pipelines.POST("/:id/delete", h.Delete)
+		pipelines.GET("/:id/steps/:step/logs", h.StepLogs)
 	}
 }
 
@@ -59,6 +62,74 @@ func (h *GamePipelineHandler) List(c *gin.Context) {
 	c.JSON(200, pipelines)
 }
 
+// Submit 提交流水线
+// @Summary 提交流水线
+// @Description 根据模板和参数创建流水线，并下发到指定节点或按标签选择的节点
+// @Tags 游戏节点流水线
+// @Accept json
+// @Produce json
+// @Param body body service.SubmitPipelineParams true "提交参数"
+// @Success 201 {object} models.GamePipeline "创建的流水线"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "节点不存在或没有符合条件的节点"
+// @Failure 409 {object} map[string]interface{} "节点未连接或处于维护、禁用状态"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/pipelines [post]
+func (h *GamePipelineHandler) Submit(c *gin.Context) {
+	var params service.SubmitPipelineParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	pipeline, err := h.svc.Submit(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNodeNotConnected):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    http.StatusConflict,
+				"message": "节点未连接，可使用 queue=true 排队等待节点上线",
+				"error":   err.Error(),
+			})
+		case errors.Is(err, service.ErrNodeUnavailable):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    http.StatusConflict,
+				"message": "节点处于维护或禁用状态",
+				"error":   err.Error(),
+			})
+		case errors.Is(err, service.ErrNoMatchingNode):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    http.StatusNotFound,
+				"message": "没有符合条件的节点",
+				"error":   err.Error(),
+			})
+		case errors.Is(err, service.ErrDispatchDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    http.StatusServiceUnavailable,
+				"message": "流水线下发未启用",
+				"error":   err.Error(),
+			})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "提交流水线失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    pipeline,
+	})
+}
+
 // Get 获取流水线详情
 // @Summary 获取流水线详情
 // @Description 根据流水线ID获取游戏节点流水线详情
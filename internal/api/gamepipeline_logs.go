@@ -0,0 +1,234 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+)
+
+const (
+	// maxLogResponseBytes 单次响应最多返回的日志字节数，更多内容通过 Range 分段读取
+	maxLogResponseBytes = 4 << 20
+	// logFollowInterval 跟踪日志时的轮询间隔
+	logFollowInterval = 500 * time.Millisecond
+	// logFollowGrace 步骤结束但日志未标记结束时继续等待的时间
+	logFollowGrace = 5 * time.Second
+)
+
+// StepLogs 获取步骤日志
+// @Summary 获取步骤日志
+// @Description 获取流水线步骤的日志，支持按行读取末尾、Range 字节范围读取和持续跟踪。响应头 X-Log-Start/X-Log-End 为返回内容的起止偏移
+// @Tags 游戏节点流水线
+// @Produce plain
+// @Param id path string true "流水线ID"
+// @Param step path string true "步骤ID"
+// @Param tail query int false "只返回最后 N 行"
+// @Param follow query bool false "持续输出新日志直到步骤结束"
+// @Param Range header string false "字节范围，如 bytes=0-1023、bytes=1024-、bytes=-512"
+// @Success 200 {string} string "日志内容"
+// @Success 206 {string} string "指定范围的日志内容"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "流水线或步骤不存在"
+// @Failure 416 {object} map[string]interface{} "日志范围不可用"
+// @Router /api/v1/pipelines/{id}/steps/{step}/logs [get]
+func (h *GamePipelineHandler) StepLogs(c *gin.Context) {
+	ctx := c.Request.Context()
+	pipelineID, stepID := c.Param("id"), c.Param("step")
+
+	follow, err := strconv.ParseBool(c.DefaultQuery("follow", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "follow 参数格式错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	info, finished, err := h.svc.StatStepLog(ctx, pipelineID, stepID)
+	if err != nil {
+		stepLogError(c, err)
+		return
+	}
+
+	// 确定首次返回的日志范围
+	status := http.StatusOK
+	var data []byte
+	var start int64
+	switch {
+	case c.Query("tail") != "":
+		lines, err := strconv.Atoi(c.Query("tail"))
+		if err != nil || lines <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "tail 参数必须为正整数",
+			})
+			return
+		}
+		data, start, err = h.svc.TailStepLog(ctx, pipelineID, stepID, lines)
+		if err != nil {
+			stepLogError(c, err)
+			return
+		}
+	case c.GetHeader("Range") != "":
+		var end int64
+		start, end, err = parseLogRange(c.GetHeader("Range"), info)
+		if err != nil {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", info.End))
+			stepLogError(c, err)
+			return
+		}
+		data, err = h.svc.ReadStepLog(ctx, pipelineID, stepID, start, end)
+		if err != nil {
+			stepLogError(c, err)
+			return
+		}
+		status = http.StatusPartialContent
+		if len(data) > 0 {
+			c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, info.End))
+		}
+	default:
+		start = info.Start
+		data, err = h.svc.ReadStepLog(ctx, pipelineID, stepID, start, min(info.End, start+maxLogResponseBytes))
+		if err != nil {
+			stepLogError(c, err)
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Log-Start", strconv.FormatInt(start, 10))
+	c.Header("X-Log-Complete", strconv.FormatBool(info.Complete))
+	if !follow {
+		c.Header("X-Log-End", strconv.FormatInt(start+int64(len(data)), 10))
+		c.Data(status, "text/plain; charset=utf-8", data)
+		return
+	}
+
+	// 持续跟踪：先输出首次读取的内容，再轮询新写入的日志
+	c.Status(status)
+	c.Writer.Write(data)
+	c.Writer.Flush()
+	pos := start + int64(len(data))
+	idleSince := time.Now()
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		if info.Complete && pos >= info.End {
+			return
+		}
+		if finished && time.Since(idleSince) > logFollowGrace {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, finished, err = h.svc.StatStepLog(ctx, pipelineID, stepID)
+		if err != nil {
+			return
+		}
+		if pos < info.Start {
+			// 未读取的部分已被轮转清理
+			pos = info.Start
+		}
+		if pos >= info.End {
+			continue
+		}
+
+		chunk, err := h.svc.ReadStepLog(ctx, pipelineID, stepID, pos, min(info.End, pos+maxLogResponseBytes))
+		if err != nil {
+			continue
+		}
+		if _, err := c.Writer.Write(chunk); err != nil {
+			return
+		}
+		c.Writer.Flush()
+		pos += int64(len(chunk))
+		idleSince = time.Now()
+	}
+}
+
+// parseLogRange 解析 Range 请求头，返回 [start, end) 范围
+func parseLogRange(header string, info store.StepLogInfo) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, fmt.Errorf("不支持的 Range: %s", header)
+	}
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("不支持的 Range: %s", header)
+	}
+
+	var start, end int64
+	switch {
+	case first == "":
+		// 后缀范围：最后 N 字节
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("不支持的 Range: %s", header)
+		}
+		start, end = max(info.End-n, info.Start), info.End
+	default:
+		var err error
+		start, err = strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return 0, 0, fmt.Errorf("不支持的 Range: %s", header)
+		}
+		end = info.End
+		if last != "" {
+			l, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || l < start {
+				return 0, 0, fmt.Errorf("不支持的 Range: %s", header)
+			}
+			end = min(l+1, info.End)
+		}
+	}
+
+	if start < info.Start || start >= info.End {
+		return 0, 0, store.ErrLogRangeNotSatisfiable
+	}
+	return start, min(end, start+maxLogResponseBytes), nil
+}
+
+// stepLogError 将步骤日志相关错误转换为 HTTP 响应
+func stepLogError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPipelineNotFound), errors.Is(err, service.ErrStepNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    http.StatusNotFound,
+			"message": "流水线或步骤不存在",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, store.ErrLogRangeNotSatisfiable):
+		c.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{
+			"code":    http.StatusRequestedRangeNotSatisfiable,
+			"message": "日志范围不可用",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrLogStoreDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    http.StatusServiceUnavailable,
+			"message": "步骤日志存储未启用",
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "获取步骤日志失败",
+			"error":   err.Error(),
+		})
+	}
+}
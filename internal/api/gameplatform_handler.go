@@ -88,12 +88,14 @@ func (h *GamePlatformHandler) Get(c *gin.Context) {
 
 // GetAccess 获取平台远程访问链接
 // @Summary 获取平台远程访问链接
-// @Description 获取平台远程访问的WebRTC链接
+// @Description 获取平台最近启动的运行中实例的访问链接，链接带有签名的访问令牌
 // @Tags 游戏平台
 // @Accept json
 // @Produce json
 // @Param id path string true "平台ID"
-// @Success 200 {object} service.PlatformAccessResult "访问链接"
+// @Param X-User-ID header string true "访问用户"
+// @Param X-Access-Issuer-Key header string true "签发密钥"
+// @Success 200 {object} service.GamePlatformAccessResult "访问链接"
 // @Router /api/v1/platforms/{id}/access [get]
 func (h *GamePlatformHandler) GetAccess(c *gin.Context) {
 	id := c.Param("id")
@@ -114,9 +116,9 @@ func (h *GamePlatformHandler) GetAccess(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.GetAccess(c, id)
+	result, err := h.service.GetAccess(c, id, accessUser(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(accessStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -125,12 +127,14 @@ func (h *GamePlatformHandler) GetAccess(c *gin.Context) {
 
 // RefreshPlatformAccess 刷新平台远程访问链接
 // @Summary 刷新平台远程访问链接
-// @Description 刷新平台远程访问的WebRTC链接
+// @Description 刷新平台远程访问链接，用户之前获取的链接失效
 // @Tags 游戏平台
 // @Accept json
 // @Produce json
 // @Param id path string true "平台ID"
-// @Success 200 {object} service.PlatformAccessResult "访问链接"
+// @Param X-User-ID header string true "访问用户"
+// @Param X-Access-Issuer-Key header string true "签发密钥"
+// @Success 200 {object} service.GamePlatformAccessResult "访问链接"
 // @Router /api/v1/platforms/{id}/access/refresh [post]
 func (h *GamePlatformHandler) RefreshAccess(c *gin.Context) {
 	id := c.Param("id")
@@ -151,9 +155,9 @@ func (h *GamePlatformHandler) RefreshAccess(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.RefreshAccess(c, id)
+	result, err := h.service.RefreshAccess(c, id, accessUser(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(accessStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+)
+
+// InventoryHandler 处理容器清单核对相关的 HTTP 请求
+type InventoryHandler struct {
+	svc *service.InventoryService
+}
+
+// NewInventoryHandler 创建新的 InventoryHandler
+func NewInventoryHandler(svc *service.InventoryService) *InventoryHandler {
+	return &InventoryHandler{
+		svc: svc,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *InventoryHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/api/v1/nodes/:id/inventory", h.Get)
+
+	inventory := r.Group("/api/v1/inventory")
+	{
+		inventory.GET("", h.List)
+		inventory.POST("/reconcile", h.Reconcile)
+	}
+}
+
+// List 获取容器清单核对结果
+// @Summary 获取容器清单核对结果
+// @Description 获取所有节点最近一次的容器清单核对结果，包括运行中却没有容器的实例和没有对应实例的容器
+// @Tags 容器清单
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.InventoryReport "核对结果"
+// @Router /api/v1/inventory [get]
+func (h *InventoryHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.svc.Reports(),
+	})
+}
+
+// Get 获取节点的容器清单核对结果
+// @Summary 获取节点的容器清单核对结果
+// @Description 获取节点最近一次的容器清单核对结果
+// @Tags 容器清单
+// @Accept json
+// @Produce json
+// @Param id path string true "节点ID"
+// @Success 200 {object} models.InventoryReport "核对结果"
+// @Failure 404 {object} map[string]interface{} "节点尚未核对"
+// @Router /api/v1/nodes/{id}/inventory [get]
+func (h *InventoryHandler) Get(c *gin.Context) {
+	report, ok := h.svc.Report(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    http.StatusNotFound,
+			"message": "节点尚未核对，节点需在线并已上报容器清单",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    report,
+	})
+}
+
+// Reconcile 立即核对容器清单
+// @Summary 立即核对容器清单
+// @Description 立即核对所有在线节点的容器清单，开启自动清理时同时清理不一致项
+// @Tags 容器清单
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.InventoryReport "本次核对结果"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/inventory/reconcile [post]
+func (h *InventoryHandler) Reconcile(c *gin.Context) {
+	reports, err := h.svc.Reconcile(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": "核对容器清单失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    reports,
+	})
+}
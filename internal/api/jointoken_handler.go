@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+)
+
+// JoinTokenHandler 处理节点加入令牌相关的 HTTP 请求
+type JoinTokenHandler struct {
+	svc *service.JoinTokenService
+}
+
+// NewJoinTokenHandler 创建新的 JoinTokenHandler
+func NewJoinTokenHandler(svc *service.JoinTokenService) *JoinTokenHandler {
+	return &JoinTokenHandler{
+		svc: svc,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *JoinTokenHandler) RegisterRoutes(r *gin.Engine) {
+	tokens := r.Group("/api/v1/jointokens")
+	{
+		tokens.GET("", h.List)
+		tokens.POST("", h.Create)
+		tokens.POST("/:id/revoke", h.Revoke)
+	}
+}
+
+// List 获取加入令牌列表
+// @Summary 获取加入令牌列表
+// @Description 获取所有节点加入令牌，不包含令牌明文
+// @Tags 节点加入令牌
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.JoinToken "令牌列表"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/jointokens [get]
+func (h *JoinTokenHandler) List(c *gin.Context) {
+	tokens, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": "获取加入令牌列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    tokens,
+	})
+}
+
+// Create 创建加入令牌
+// @Summary 创建加入令牌
+// @Description 创建有有效期的节点加入令牌，令牌明文只在本次响应中返回
+// @Tags 节点加入令牌
+// @Accept json
+// @Produce json
+// @Param body body service.CreateJoinTokenParams true "令牌参数"
+// @Success 201 {object} service.CreateJoinTokenResult "令牌明文和令牌信息"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /api/v1/jointokens [post]
+func (h *JoinTokenHandler) Create(c *gin.Context) {
+	var params service.CreateJoinTokenParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	result, err := h.svc.Create(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "创建加入令牌失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// Revoke 吊销加入令牌
+// @Summary 吊销加入令牌
+// @Description 吊销加入令牌，已使用该令牌加入的节点不受影响
+// @Tags 节点加入令牌
+// @Accept json
+// @Produce json
+// @Param id path string true "令牌ID"
+// @Success 200 {object} map[string]interface{} "操作结果"
+// @Failure 404 {object} map[string]interface{} "令牌不存在"
+// @Router /api/v1/jointokens/{id}/revoke [post]
+func (h *JoinTokenHandler) Revoke(c *gin.Context) {
+	if err := h.svc.Revoke(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    http.StatusNotFound,
+			"message": "吊销加入令牌失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// playTokenQuery 携带访问令牌的查询参数，首次访问后改用 Cookie
+	playTokenQuery = "token"
+	// playTokenCookie 保存访问令牌的 Cookie，作用路径限定为实例的代理路径
+	playTokenCookie = "beagle_play_token"
+)
+
+// PlayHandler 将 /play/{id}/ 下的 HTTP 和 WebSocket 请求代理到实例在节点上的 Selkies 服务
+// 每个请求都校验实例访问令牌，实例离开运行中状态时断开该实例的所有代理连接
+type PlayHandler struct {
+	access *service.AccessService
+	logger utils.Logger
+
+	// sessions 每个实例进行中的代理请求，用于实例停止时断开连接
+	mu       sync.Mutex
+	sessions map[string]map[*playSession]struct{}
+}
+
+// playSession 一个进行中的代理请求
+type playSession struct {
+	cancel context.CancelFunc
+}
+
+// NewPlayHandler 创建新的 PlayHandler
+func NewPlayHandler(access *service.AccessService) *PlayHandler {
+	return &PlayHandler{
+		access:   access,
+		logger:   utils.New("PlayHandler"),
+		sessions: make(map[string]map[*playSession]struct{}),
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *PlayHandler) RegisterRoutes(r *gin.Engine) {
+	r.Any("/play/:id", h.Redirect)
+	r.Any("/play/:id/*path", h.Proxy)
+}
+
+// HandleInstanceState 实例离开运行中状态时断开该实例的代理连接
+func (h *PlayHandler) HandleInstanceState(ctx context.Context, instance models.GameInstance, from models.GameInstanceState) {
+	if instance.Status != models.GameInstanceStateRunning {
+		h.CloseInstance(instance.ID)
+	}
+}
+
+// CloseInstance 断开实例的所有代理连接，返回断开的数量
+func (h *PlayHandler) CloseInstance(instanceID string) int {
+	h.mu.Lock()
+	sessions := h.sessions[instanceID]
+	delete(h.sessions, instanceID)
+	h.mu.Unlock()
+
+	for session := range sessions {
+		session.cancel()
+	}
+	if len(sessions) > 0 {
+		h.logger.Info("实例 %s 已停止，断开 %d 个代理连接", instanceID, len(sessions))
+	}
+	return len(sessions)
+}
+
+// Redirect 将 /play/{id} 重定向到 /play/{id}/，保证 Selkies 页面的相对路径正确
+// @Summary 访问实例桌面
+// @Description 重定向到 /play/{id}/
+// @Tags 访问令牌
+// @Param id path string true "实例ID"
+// @Success 302 "重定向"
+// @Router /play/{id} [get]
+func (h *PlayHandler) Redirect(c *gin.Context) {
+	location := playPrefix(c.Param("id")) + "/"
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// Proxy 校验访问令牌并代理请求到实例的 Selkies 服务
+// @Summary 访问实例桌面
+// @Description 代理实例 Selkies 服务的 HTTP 和 WebSocket 请求；令牌通过 token 查询参数、Cookie 或 Authorization: Bearer 请求头携带，
+// @Description 使用查询参数时保存到 Cookie 并重定向到不带令牌的地址
+// @Tags 访问令牌
+// @Param id path string true "实例ID"
+// @Param path path string true "Selkies 服务上的路径"
+// @Param token query string false "实例访问令牌"
+// @Failure 401 {object} map[string]interface{} "令牌无效、过期或已吊销"
+// @Failure 409 {object} map[string]interface{} "实例未运行"
+// @Failure 502 {object} map[string]interface{} "无法连接实例"
+// @Router /play/{id}/{path} [get]
+func (h *PlayHandler) Proxy(c *gin.Context) {
+	instanceID := c.Param("id")
+	prefix := playPrefix(instanceID)
+
+	// 先记录请求再校验令牌：校验通过时实例仍在运行，之后实例停止时 CloseInstance 能取消该请求，
+	// 避免在校验和记录之间停止的实例留下代理连接
+	ctx, session := h.track(c.Request.Context(), instanceID)
+	defer h.untrack(instanceID, session)
+
+	token, fromQuery := playToken(c)
+	target, err := h.access.Verify(ctx, token)
+	if err == nil && (target.Claims.Scope != models.AccessScopeInstance || target.Claims.Target != instanceID) {
+		err = service.ErrInvalidAccessToken
+	}
+	if err != nil {
+		accessError(c, "访问实例失败", err)
+		return
+	}
+
+	// 令牌写入 Cookie 后去掉地址中的令牌，后续的资源和 WebSocket 请求通过 Cookie 校验
+	if fromQuery {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(playTokenCookie, token, int(time.Until(time.Unix(target.Claims.ExpiresAt, 0)).Seconds()),
+			prefix+"/", "", c.Request.TLS != nil, true)
+		if c.Request.Method == http.MethodGet && !isWebSocket(c.Request) {
+			query := c.Request.URL.Query()
+			query.Del(playTokenQuery)
+			location := prefix + c.Param("path")
+			if encoded := query.Encode(); encoded != "" {
+				location += "?" + encoded
+			}
+			c.Redirect(http.StatusFound, location)
+			return
+		}
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Path = c.Param("path")
+			pr.Out.URL.RawPath = ""
+			query := pr.Out.URL.Query()
+			query.Del(playTokenQuery)
+			pr.Out.URL.RawQuery = query.Encode()
+			pr.SetURL(target.URL)
+			pr.SetXForwarded()
+			pr.Out.Header.Set("X-Forwarded-Prefix", prefix)
+			// 访问令牌只用于服务端校验，不转发给实例
+			pr.Out.Header.Del("Authorization")
+			stripCookie(pr.Out, playTokenCookie)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			rewriteLocation(resp, target.URL, prefix)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			h.logger.Warn("代理实例 %s 的请求失败: %v", instanceID, err)
+			c.JSON(http.StatusBadGateway, gin.H{
+				"code":    http.StatusBadGateway,
+				"message": "无法连接实例",
+				"error":   err.Error(),
+			})
+		},
+	}
+	proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+// track 记录进行中的代理请求，返回的上下文在实例停止时取消
+func (h *PlayHandler) track(parent context.Context, instanceID string) (context.Context, *playSession) {
+	ctx, cancel := context.WithCancel(parent)
+	session := &playSession{cancel: cancel}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sessions[instanceID] == nil {
+		h.sessions[instanceID] = make(map[*playSession]struct{})
+	}
+	h.sessions[instanceID][session] = struct{}{}
+	return ctx, session
+}
+
+// untrack 代理请求结束后移除记录
+func (h *PlayHandler) untrack(instanceID string, session *playSession) {
+	session.cancel()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions[instanceID], session)
+	if len(h.sessions[instanceID]) == 0 {
+		delete(h.sessions, instanceID)
+	}
+}
+
+// playPrefix 实例的代理路径前缀
+func playPrefix(instanceID string) string {
+	return "/play/" + url.PathEscape(instanceID)
+}
+
+// playToken 获取请求携带的访问令牌，依次使用查询参数、Cookie 和 Authorization 请求头
+func playToken(c *gin.Context) (string, bool) {
+	if token := c.Query(playTokenQuery); token != "" {
+		return token, true
+	}
+	if token, err := c.Cookie(playTokenCookie); err == nil && token != "" {
+		return token, false
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer "), false
+	}
+	return "", false
+}
+
+// isWebSocket 判断是否为 WebSocket 升级请求
+func isWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// stripCookie 从转发的请求中移除指定的 Cookie
+func stripCookie(r *http.Request, name string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != name {
+			r.AddCookie(cookie)
+		}
+	}
+}
+
+// rewriteLocation 将实例返回的重定向地址改写到代理路径下
+func rewriteLocation(resp *http.Response, target *url.URL, prefix string) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return
+	}
+	if u.Host != "" && u.Host != target.Host {
+		return
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		// 相对路径相对于当前地址解析，无需改写
+		if u.Host == "" {
+			return
+		}
+		u.Path = "/" + u.Path
+	}
+	u.Scheme = ""
+	u.Host = ""
+	u.Path = prefix + u.Path
+	u.RawPath = ""
+	resp.Header.Set("Location", u.String())
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// newTestAccess 创建访问令牌服务，实例 inst-1 运行在 host:port 上
+func newTestAccess(t *testing.T, host string, port int, issuerKey string) (*service.AccessService, store.GameInstanceStore) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	dir := t.TempDir()
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(dir, "nodes.yaml"))
+	require.NoError(t, err)
+	// 存储延迟落盘，预先创建数据文件
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "instances.yaml"), []byte("[]\n"), 0644))
+	instanceStore, err := store.NewGameInstanceStore(ctx, filepath.Join(dir, "instances.yaml"), utils.New("AccessTest"))
+	require.NoError(t, err)
+
+	nodes := service.NewGameNodeService(nodeStore)
+	instances := service.NewGameInstanceService(instanceStore)
+	access, err := service.NewAccessService(store.NewYAMLAccessGrantStore(ctx, filepath.Join(dir, "access.yaml")), nodes, instances, service.AccessOptions{
+		Secret:    []byte("secret"),
+		TTL:       time.Hour,
+		IssuerKey: issuerKey,
+	})
+	require.NoError(t, err)
+	require.NoError(t, nodes.Create(ctx, models.GameNode{ID: "node-1", Labels: map[string]string{service.NodeAddressLabel: host}}))
+	require.NoError(t, instanceStore.Add(ctx, models.GameInstance{ID: "inst-1", NodeID: "node-1", Status: models.GameInstanceStateRunning, Port: port}))
+	return access, instanceStore
+}
+
+// newPlayTest 创建代理到 backend 的 PlayHandler，返回 inst-1 的访问令牌
+func newPlayTest(t *testing.T, backend *httptest.Server) (*gin.Engine, *PlayHandler, store.GameInstanceStore, string) {
+	backendURL, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+	host, portText, err := net.SplitHostPort(backendURL.Host)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portText)
+	require.NoError(t, err)
+	access, instanceStore := newTestAccess(t, host, port, "")
+	result, err := access.IssueInstance(context.Background(), "inst-1", "alice")
+	require.NoError(t, err)
+
+	handler := NewPlayHandler(access)
+	router := gin.New()
+	handler.RegisterRoutes(router)
+	return router, handler, instanceStore, result.Token
+}
+
+func TestPlayProxyTokenCookie(t *testing.T) {
+	var received *http.Request
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	router, _, instanceStore, token := newPlayTest(t, backend)
+
+	// 使用查询参数携带令牌时保存到 Cookie，并重定向到不带令牌的地址
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/play/inst-1/index.html?token="+token+"&lang=zh", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/play/inst-1/index.html?lang=zh", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, playTokenCookie, cookies[0].Name)
+	assert.Equal(t, token, cookies[0].Value)
+	assert.Equal(t, "/play/inst-1/", cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Nil(t, received)
+
+	// 后续请求通过 Cookie 校验，令牌 Cookie 不转发给实例
+	req := httptest.NewRequest(http.MethodGet, "/play/inst-1/index.html?lang=zh", nil)
+	req.AddCookie(cookies[0])
+	req.AddCookie(&http.Cookie{Name: "selkies", Value: "1"})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, received)
+	assert.Equal(t, "/index.html", received.URL.Path)
+	assert.Equal(t, "lang=zh", received.URL.RawQuery)
+	assert.Equal(t, "selkies=1", received.Header.Get("Cookie"))
+	assert.Equal(t, "/play/inst-1", received.Header.Get("X-Forwarded-Prefix"))
+
+	// 没有令牌或令牌不属于该实例时拒绝访问
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/play/inst-1/index.html", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/play/inst-2/index.html?token="+token, nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 实例停止后 Cookie 中的令牌不可用
+	instance, err := instanceStore.Get(context.Background(), "inst-1")
+	require.NoError(t, err)
+	instance.Status = models.GameInstanceStateStopped
+	require.NoError(t, instanceStore.Update(context.Background(), instance))
+	req = httptest.NewRequest(http.MethodGet, "/play/inst-1/index.html", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestPlayProxyCloseInstance(t *testing.T) {
+	started := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer backend.Close()
+	router, handler, _, token := newPlayTest(t, backend)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodGet, "/play/inst-1/websockets", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-started
+
+	// 实例离开运行中状态时断开进行中的代理请求
+	handler.HandleInstanceState(context.Background(), models.GameInstance{ID: "inst-1", Status: models.GameInstanceStateStopping}, models.GameInstanceStateRunning)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("实例停止后代理请求未断开")
+	}
+	assert.Equal(t, 0, handler.CloseInstance("inst-1"))
+}
+
+func TestRewriteLocation(t *testing.T) {
+	target := &url.URL{Scheme: "http", Host: "10.0.0.5:30001", Path: "/"}
+	for _, tc := range []struct {
+		name     string
+		location string
+		want     string
+	}{
+		{"绝对路径", "/login?next=%2F", "/play/inst-1/login?next=%2F"},
+		{"实例地址", "http://10.0.0.5:30001/app/", "/play/inst-1/app/"},
+		{"相对路径", "app/index.html", "app/index.html"},
+		{"其他主机", "https://auth.example.com/login", "https://auth.example.com/login"},
+		{"无重定向", "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tc.location != "" {
+				resp.Header.Set("Location", tc.location)
+			}
+			rewriteLocation(resp, target, "/play/inst-1")
+			assert.Equal(t, tc.want, resp.Header.Get("Location"))
+		})
+	}
+}
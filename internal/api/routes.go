@@ -42,8 +42,12 @@ func SetupRouter(gameplatformService *service.GamePlatformService, gamenodeServi
 		{
 			nodes.GET("", gamenodeHandler.ListNodes)
 			nodes.GET("/:id", gamenodeHandler.GetNode)
+			nodes.GET("/:id/connection", gamenodeHandler.GetNodeConnection)
 			nodes.POST("/:id/update", gamenodeHandler.UpdateNode)
 			nodes.POST("/:id/delete", gamenodeHandler.DeleteNode)
+			nodes.POST("/:id/state", gamenodeHandler.UpdateNodeState)
+			nodes.POST("/:id/approve", gamenodeHandler.ApproveNode)
+			nodes.POST("/:id/reject", gamenodeHandler.RejectNode)
 		}
 
 		// 游戏平台管理
@@ -72,6 +76,7 @@ func SetupRouter(gameplatformService *service.GamePlatformService, gamenodeServi
 			instances.GET("", gameinstanceHandler.List)
 			instances.GET("/:id", gameinstanceHandler.Get)
 			instances.POST("", gameinstanceHandler.Create)
+			instances.POST("/schedule", gameinstanceHandler.Schedule)
 			instances.POST("/:id/update", gameinstanceHandler.Update)
 			instances.POST("/:id/delete", gameinstanceHandler.Delete)
 			// 实例操作
@@ -83,9 +88,11 @@ func SetupRouter(gameplatformService *service.GamePlatformService, gamenodeServi
 		pipelines := v1.Group("/pipelines")
 		{
 			pipelines.GET("", GamePipelineHandler.List)
+			pipelines.POST("", GamePipelineHandler.Submit)
 			pipelines.GET("/:id", GamePipelineHandler.Get)
 			pipelines.POST("/:id/cancel", GamePipelineHandler.Cancel)
 			pipelines.POST("/:id/delete", GamePipelineHandler.Delete)
+			pipelines.GET("/:id/steps/:step/logs", GamePipelineHandler.StepLogs)
 		}
 	}
 
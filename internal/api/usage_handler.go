@@ -0,0 +1,194 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+)
+
+// UsageHandler 处理实例用量统计相关的 HTTP 请求
+type UsageHandler struct {
+	svc *service.UsageService
+}
+
+// NewUsageHandler 创建新的 UsageHandler
+func NewUsageHandler(svc *service.UsageService) *UsageHandler {
+	return &UsageHandler{
+		svc: svc,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *UsageHandler) RegisterRoutes(r *gin.Engine) {
+	usage := r.Group("/api/v1/usage")
+	{
+		usage.GET("/sessions", h.ListSessions)
+		usage.GET("/summary", h.Summary)
+	}
+}
+
+// ListSessions 获取实例会话记录
+// @Summary 获取实例会话记录
+// @Description 获取与时间范围重叠的实例会话记录，包括用户、卡片、平台、节点、GPU 型号、开始和结束时间、时长和停止原因，format=csv 时导出 CSV
+// @Tags 用量统计
+// @Accept json
+// @Produce json,text/csv
+// @Param from query string false "开始时间(RFC 3339 或 2006-01-02)"
+// @Param to query string false "结束时间(RFC 3339 或 2006-01-02)，不包含"
+// @Param tz query string false "日期使用的时区，例如 Asia/Shanghai，默认为服务端时区"
+// @Param user_id query string false "用户ID"
+// @Param card_id query string false "卡片ID"
+// @Param node_id query string false "节点ID"
+// @Param format query string false "输出格式(json/csv)"
+// @Success 200 {array} models.UsageSession "会话记录"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/usage/sessions [get]
+func (h *UsageHandler) ListSessions(c *gin.Context) {
+	query, _, ok := parseUsageQuery(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.svc.List(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": "获取会话记录失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		setCSVHeaders(c, "usage-sessions.csv")
+		if err := service.WriteUsageSessionsCSV(c.Writer, sessions); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sessions,
+	})
+}
+
+// Summary 汇总实例使用时长
+// @Summary 汇总实例使用时长
+// @Description 按用户、卡片、节点或自然日汇总时间范围内的会话数和使用时长（秒），跨越时间范围或跨天的会话只计算范围内的部分，format=csv 时导出 CSV
+// @Tags 用量统计
+// @Accept json
+// @Produce json,text/csv
+// @Param group_by query string true "汇总维度(user/card/node/day)"
+// @Param from query string false "开始时间(RFC 3339 或 2006-01-02)"
+// @Param to query string false "结束时间(RFC 3339 或 2006-01-02)，不包含"
+// @Param tz query string false "划分自然日使用的时区，例如 Asia/Shanghai，默认为服务端时区"
+// @Param user_id query string false "用户ID"
+// @Param card_id query string false "卡片ID"
+// @Param node_id query string false "节点ID"
+// @Param format query string false "输出格式(json/csv)"
+// @Success 200 {array} models.UsageSummary "汇总结果"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /api/v1/usage/summary [get]
+func (h *UsageHandler) Summary(c *gin.Context) {
+	query, loc, ok := parseUsageQuery(c)
+	if !ok {
+		return
+	}
+	groupBy := models.UsageGroupBy(c.Query("group_by"))
+	if !groupBy.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    http.StatusBadRequest,
+			"message": "汇总维度必须是 user、card、node 或 day",
+		})
+		return
+	}
+
+	summaries, err := h.svc.Summarize(c.Request.Context(), query, groupBy, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": "汇总使用时长失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		setCSVHeaders(c, fmt.Sprintf("usage-by-%s.csv", groupBy))
+		if err := service.WriteUsageSummaryCSV(c.Writer, groupBy, summaries); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    summaries,
+	})
+}
+
+// parseUsageQuery 解析用量查询参数，参数错误时写入 400 响应并返回 false
+func parseUsageQuery(c *gin.Context) (models.UsageQuery, *time.Location, bool) {
+	loc := time.Local
+	if tz := c.Query("tz"); tz != "" {
+		parsed, err := time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "时区格式错误",
+				"error":   err.Error(),
+			})
+			return models.UsageQuery{}, nil, false
+		}
+		loc = parsed
+	}
+
+	query := models.UsageQuery{
+		UserID: c.Query("user_id"),
+		CardID: c.Query("card_id"),
+		NodeID: c.Query("node_id"),
+	}
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseUsageTime(value, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": fmt.Sprintf("%s 时间格式错误，应为 RFC 3339 或 2006-01-02", param.name),
+				"error":   err.Error(),
+			})
+			return models.UsageQuery{}, nil, false
+		}
+		*param.target = parsed
+	}
+	return query, loc, true
+}
+
+// parseUsageTime 解析 RFC 3339 时间或日期，日期为 loc 中当天的零点
+func parseUsageTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+// setCSVHeaders 设置 CSV 下载的响应头
+func setCSVHeaders(c *gin.Context, filename string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AgentFileConfig config/agent.yaml 中的 Agent 配置
+// 加载顺序为默认值、配置文件、环境变量，命令行参数由调用方在加载后覆盖
+type AgentFileConfig struct {
+	// Node 节点身份和注册信息
+	Node AgentNodeConfig `yaml:"node"`
+	// Server 服务端连接配置
+	Server AgentServerConfig `yaml:"server"`
+	// Intervals 心跳和指标上报周期
+	Intervals AgentIntervalConfig `yaml:"intervals"`
+	// Docker Docker 连接配置
+	Docker AgentDockerConfig `yaml:"docker"`
+	// Root 节点数据根目录，即 BEAGLE_WIND_ROOT
+	Root string `yaml:"root"`
+	// Log 日志配置
+	Log AgentLogConfig `yaml:"log"`
+	// StatusAddr 本地状态接口监听地址，为空时不启动
+	StatusAddr string `yaml:"status_addr"`
+	// CredentialFile 服务端签发的节点凭证的保存路径
+	CredentialFile string `yaml:"credential_file"`
+	// OutboxFile 连接中断期间积压的上报消息的保存路径
+	OutboxFile string `yaml:"outbox_file"`
+}
+
+// AgentNodeConfig 节点身份和注册信息
+type AgentNodeConfig struct {
+	// ID 节点ID，必须配置
+	ID string `yaml:"id"`
+	// Alias 节点别名，为空时使用节点ID
+	Alias string `yaml:"alias"`
+	// Model 节点型号
+	Model string `yaml:"model"`
+	// Type 节点类型，可选 physical、virtual、container，为空时自动检测
+	Type string `yaml:"type"`
+	// Location 节点位置
+	Location string `yaml:"location"`
+	// Labels 节点标签
+	Labels map[string]string `yaml:"labels"`
+}
+
+// AgentServerConfig 服务端连接配置
+type AgentServerConfig struct {
+	// Addr gRPC 服务地址，可以是逗号分隔的多个地址，也可以是解析出多个地址的域名
+	Addr string `yaml:"addr"`
+	// Addrs 多个 gRPC 服务地址，与 Addr 合并，Agent 连接其中一个健康的服务端，不可用时切换到其他服务端
+	Addrs []string `yaml:"addrs"`
+	// JoinToken 加入令牌，节点尚无凭证时随注册请求提交
+	JoinToken string `yaml:"join_token"`
+	// TLS 双向 TLS 配置
+	TLS AgentTLSConfig `yaml:"tls"`
+}
+
+// AgentTLSConfig 双向 TLS 配置，均为空时使用明文连接
+type AgentTLSConfig struct {
+	// CAFile 用于校验服务端证书的 CA
+	CAFile string `yaml:"ca_file"`
+	// CertFile 节点证书，CN 或 SAN 必须等于节点ID
+	CertFile string `yaml:"cert_file"`
+	// KeyFile 节点证书私钥
+	KeyFile string `yaml:"key_file"`
+	// ServerName 覆盖校验服务端证书时使用的名称
+	ServerName string `yaml:"server_name"`
+}
+
+// AgentIntervalConfig 心跳和上报周期
+type AgentIntervalConfig struct {
+	// Heartbeat 心跳周期
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// Metrics 指标上报周期
+	Metrics time.Duration `yaml:"metrics"`
+	// Containers 容器清单上报周期，托管容器变化时另外立即上报
+	Containers time.Duration `yaml:"containers"`
+}
+
+// AgentDockerConfig Docker 连接配置
+type AgentDockerConfig struct {
+	// Host Docker 服务地址，为空时使用 DOCKER_HOST 或默认的 unix socket
+	Host string `yaml:"host"`
+}
+
+// AgentLogConfig 日志配置
+type AgentLogConfig struct {
+	// Level 日志级别，可选 DEBUG、INFO、WARN、ERROR、FATAL
+	Level string `yaml:"level"`
+	// File 日志文件，为空时只输出到控制台
+	File string `yaml:"file"`
+	// Both 配置日志文件时是否同时输出到控制台
+	Both bool `yaml:"both"`
+}
+
+// defaultAgentServerAddr 未配置服务端地址时使用的默认地址
+const defaultAgentServerAddr = "localhost:50051"
+
+// agentNodeTypes 允许配置的节点类型
+var agentNodeTypes = []string{"physical", "virtual", "container"}
+
+// agentLogLevels 允许配置的日志级别
+var agentLogLevels = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// LoadAgentFileConfig 加载 Agent 配置文件并应用环境变量，文件不存在时使用默认配置
+// 返回的配置未经校验，调用方应用命令行参数后需调用 Validate
+func LoadAgentFileConfig(path string) (*AgentFileConfig, error) {
+	cfg := &AgentFileConfig{
+		Node: AgentNodeConfig{
+			Model:    "Beagle-Wind-2024",
+			Location: "default",
+		},
+		Intervals: AgentIntervalConfig{
+			Heartbeat:  5 * time.Second,
+			Metrics:    30 * time.Second,
+			Containers: 60 * time.Second,
+		},
+		Log: AgentLogConfig{
+			Level: "INFO",
+		},
+		StatusAddr:     "127.0.0.1:9091",
+		CredentialFile: "data/agent/credential",
+		OutboxFile:     "data/agent/outbox.json",
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+		if err == nil {
+			if err := yaml.Unmarshal(data, cfg); err != nil {
+				return nil, fmt.Errorf("解析配置文件失败: %w", err)
+			}
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if cfg.Server.Addr == "" && len(cfg.Server.Addrs) == 0 {
+		cfg.Server.Addr = defaultAgentServerAddr
+	}
+	if cfg.Node.Labels == nil {
+		cfg.Node.Labels = make(map[string]string)
+	}
+	return cfg, nil
+}
+
+// applyEnv 使用环境变量覆盖配置
+func (c *AgentFileConfig) applyEnv() error {
+	overrides := map[string]*string{
+		"BEAGLE_WIND_NODE_ID":         &c.Node.ID,
+		"BEAGLE_WIND_NODE_ALIAS":      &c.Node.Alias,
+		"BEAGLE_WIND_NODE_MODEL":      &c.Node.Model,
+		"BEAGLE_WIND_NODE_TYPE":       &c.Node.Type,
+		"BEAGLE_WIND_NODE_LOCATION":   &c.Node.Location,
+		"BEAGLE_WIND_SERVER":          &c.Server.Addr,
+		"BEAGLE_WIND_JOIN_TOKEN":      &c.Server.JoinToken,
+		"BEAGLE_WIND_TLS_CA":          &c.Server.TLS.CAFile,
+		"BEAGLE_WIND_TLS_CERT":        &c.Server.TLS.CertFile,
+		"BEAGLE_WIND_TLS_KEY":         &c.Server.TLS.KeyFile,
+		"BEAGLE_WIND_TLS_SERVER_NAME": &c.Server.TLS.ServerName,
+		"DOCKER_HOST":                 &c.Docker.Host,
+		"BEAGLE_WIND_ROOT":            &c.Root,
+		"BEAGLE_WIND_LOG_LEVEL":       &c.Log.Level,
+		"BEAGLE_WIND_LOG_FILE":        &c.Log.File,
+	}
+	for key, field := range overrides {
+		if value := os.Getenv(key); value != "" {
+			*field = value
+		}
+	}
+	// 环境变量中的服务端地址替换配置文件中的全部地址
+	if os.Getenv("BEAGLE_WIND_SERVER") != "" {
+		c.Server.Addrs = nil
+	}
+
+	durations := map[string]*time.Duration{
+		"BEAGLE_WIND_HEARTBEAT_PERIOD":    &c.Intervals.Heartbeat,
+		"BEAGLE_WIND_METRICS_INTERVAL":    &c.Intervals.Metrics,
+		"BEAGLE_WIND_CONTAINERS_INTERVAL": &c.Intervals.Containers,
+	}
+	for key, field := range durations {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("环境变量 %s 格式错误: %w", key, err)
+		}
+		*field = d
+	}
+
+	// 环境变量中的标签格式为 key=value,key=value，与配置文件中的标签合并
+	if value := os.Getenv("BEAGLE_WIND_NODE_LABELS"); value != "" {
+		labels, err := ParseLabels(value)
+		if err != nil {
+			return fmt.Errorf("环境变量 BEAGLE_WIND_NODE_LABELS 格式错误: %w", err)
+		}
+		if c.Node.Labels == nil {
+			c.Node.Labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			c.Node.Labels[k] = v
+		}
+	}
+	return nil
+}
+
+// Validate 校验 Agent 配置
+func (c *AgentFileConfig) Validate() error {
+	if c.Node.ID == "" {
+		return fmt.Errorf("节点ID不能为空")
+	}
+	if c.Node.Type != "" && !slices.Contains(agentNodeTypes, c.Node.Type) {
+		return fmt.Errorf("不支持的节点类型: %s", c.Node.Type)
+	}
+	for k := range c.Node.Labels {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("节点标签名不能为空")
+		}
+	}
+	if len(c.Server.Endpoints()) == 0 {
+		return fmt.Errorf("服务端地址不能为空")
+	}
+	tls := c.Server.TLS
+	if (tls.CAFile != "" || tls.CertFile != "" || tls.KeyFile != "") &&
+		(tls.CAFile == "" || tls.CertFile == "" || tls.KeyFile == "") {
+		return fmt.Errorf("双向 TLS 需要同时配置 CA、证书和私钥")
+	}
+	if c.Intervals.Heartbeat <= 0 {
+		return fmt.Errorf("心跳周期必须大于 0")
+	}
+	if c.Intervals.Metrics <= 0 {
+		return fmt.Errorf("指标上报周期必须大于 0")
+	}
+	if c.Intervals.Containers <= 0 {
+		return fmt.Errorf("容器清单上报周期必须大于 0")
+	}
+	if !slices.Contains(agentLogLevels, c.Log.Level) {
+		return fmt.Errorf("不支持的日志级别: %s", c.Log.Level)
+	}
+	if c.Root != "" {
+		info, err := os.Stat(c.Root)
+		if err != nil {
+			return fmt.Errorf("数据根目录不可用: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("数据根目录不是目录: %s", c.Root)
+		}
+	}
+	return nil
+}
+
+// Endpoints 合并 Addr 和 Addrs 中的服务端地址，去除空地址和重复地址
+func (c AgentServerConfig) Endpoints() []string {
+	var endpoints []string
+	for _, addr := range append(strings.Split(c.Addr, ","), c.Addrs...) {
+		addr = strings.TrimSpace(addr)
+		if addr != "" && !slices.Contains(endpoints, addr) {
+			endpoints = append(endpoints, addr)
+		}
+	}
+	return endpoints
+}
+
+// ParseLabels 解析 key=value,key=value 格式的标签
+func ParseLabels(value string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("标签 %q 应为 key=value 格式", pair)
+		}
+		labels[k] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAgentFileConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+node:
+  id: node-1
+  alias: 一号机
+  location: shanghai
+  labels:
+    zone: a
+intervals:
+  metrics: 1m
+`), 0644))
+
+	// 环境变量覆盖配置文件，标签与配置文件合并
+	t.Setenv("BEAGLE_WIND_NODE_LOCATION", "beijing")
+	t.Setenv("BEAGLE_WIND_NODE_LABELS", "gpu=rtx4090, zone=b")
+	t.Setenv("BEAGLE_WIND_HEARTBEAT_PERIOD", "10s")
+
+	cfg, err := LoadAgentFileConfig(path)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	assert.Equal(t, "node-1", cfg.Node.ID)
+	assert.Equal(t, "一号机", cfg.Node.Alias)
+	assert.Equal(t, "beijing", cfg.Node.Location)
+	assert.Equal(t, "Beagle-Wind-2024", cfg.Node.Model)
+	assert.Equal(t, map[string]string{"zone": "b", "gpu": "rtx4090"}, cfg.Node.Labels)
+	assert.Equal(t, 10*time.Second, cfg.Intervals.Heartbeat)
+	assert.Equal(t, time.Minute, cfg.Intervals.Metrics)
+	assert.Equal(t, "localhost:50051", cfg.Server.Addr)
+
+	cfg.Node.Type = "cloud"
+	assert.Error(t, cfg.Validate())
+	cfg.Node.Type = ""
+	cfg.Server.TLS.CertFile = "node.crt"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestAgentServerEndpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+node:
+  id: node-1
+server:
+  addrs:
+    - server-1:50051
+    - server-2:50051
+`), 0644))
+
+	// 只配置 addrs 时不使用默认地址
+	cfg, err := LoadAgentFileConfig(path)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"server-1:50051", "server-2:50051"}, cfg.Server.Endpoints())
+
+	// 环境变量替换配置文件中的全部地址，支持逗号分隔
+	t.Setenv("BEAGLE_WIND_SERVER", "server-3:50051, server-4:50051,server-3:50051")
+	cfg, err = LoadAgentFileConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"server-3:50051", "server-4:50051"}, cfg.Server.Endpoints())
+}
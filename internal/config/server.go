@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ServerFileConfig config/server.yaml 中的服务端配置
+type ServerFileConfig struct {
+	// Envs 渲染流水线模板时 ${{ envs.X }} 使用的变量
+	Envs map[string]string `yaml:"envs"`
+	// Pipeline 流水线相关配置
+	Pipeline PipelineConfig `yaml:"pipeline"`
+	// Node 节点连接配置
+	Node NodeConfig `yaml:"node"`
+	// Inventory 容器清单核对配置
+	Inventory InventoryConfig `yaml:"inventory"`
+	// Scheduler 实例调度配置
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	// Ports 实例端口分配配置
+	Ports PortsConfig `yaml:"ports"`
+	// Access 实例和节点访问链接配置
+	Access AccessConfig `yaml:"access"`
+	// TURN 实例 TURN 凭证配置
+	TURN TURNConfig `yaml:"turn"`
+	// Saves 游戏存档同步配置
+	Saves SavesConfig `yaml:"saves"`
+	// Idle 空闲实例自动停止配置
+	Idle IdleConfig `yaml:"idle"`
+}
+
+// IdleConfig 空闲实例自动停止配置，活动采样由节点随容器清单上报
+type IdleConfig struct {
+	// Enabled 是否自动停止空闲实例
+	Enabled bool `yaml:"enabled"`
+	// Interval 检测周期
+	Interval time.Duration `yaml:"interval"`
+	// Timeout 实例持续空闲超过该时间后自动停止
+	Timeout time.Duration `yaml:"timeout"`
+	// Warning 自动停止前多久在实例上记录空闲警告
+	Warning time.Duration `yaml:"warning"`
+	// CPUThreshold 容器 CPU 使用率达到该值时视为活动，单核满载为 100
+	CPUThreshold float64 `yaml:"cpu_threshold"`
+	// GPUThreshold 容器 GPU 使用率达到该值时视为活动
+	GPUThreshold float64 `yaml:"gpu_threshold"`
+	// IdleWithoutConnection 没有串流连接时直接视为空闲
+	IdleWithoutConnection bool `yaml:"idle_without_connection"`
+}
+
+// SavesConfig 游戏存档同步配置，存档保存在 S3 兼容存储中
+// 连接配置为空时使用 envs 中的 S3_URL、S3_ACCESS_KEY、S3_SECRET_KEY 和 S3_BUCKET
+type SavesConfig struct {
+	// Enabled 是否同步存档
+	Enabled bool `yaml:"enabled"`
+	// Endpoint 对象存储地址，例如 http://minio:9000
+	Endpoint string `yaml:"endpoint"`
+	// Region 签名使用的区域
+	Region string `yaml:"region"`
+	// Bucket 存储桶
+	Bucket string `yaml:"bucket"`
+	// AccessKey 访问密钥ID
+	AccessKey string `yaml:"access_key"`
+	// SecretKey 访问密钥
+	SecretKey string `yaml:"secret_key"`
+	// Prefix 存档在存储桶中的路径前缀
+	Prefix string `yaml:"prefix"`
+}
+
+// TURNConfig TURN 凭证配置，按 coturn REST API 的共享密钥方案为每个实例签发临时凭证
+type TURNConfig struct {
+	// Secret 与 coturn static-auth-secret 一致的共享密钥，为空时使用 envs 中的静态凭证
+	Secret string `yaml:"secret"`
+	// TTL 凭证有效期，需覆盖实例的最长运行时间
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// AccessConfig 访问链接配置
+type AccessConfig struct {
+	// Secret 访问令牌的签名密钥，为空时每次启动随机生成，重启后之前签发的链接失效
+	Secret string `yaml:"secret"`
+	// TTL 访问令牌有效期
+	TTL time.Duration `yaml:"ttl"`
+	// BaseURL 访问链接使用的服务端地址，为空时返回相对路径
+	BaseURL string `yaml:"base_url"`
+	// IssuerKey 签发访问链接的调用方需在 X-Access-Issuer-Key 请求头中携带的密钥，为空时不签发访问链接
+	IssuerKey string `yaml:"issuer_key"`
+}
+
+// PortsConfig 实例端口分配配置，每个节点独立分配 [Start, End] 范围内的端口
+type PortsConfig struct {
+	// Start 端口范围的起始端口
+	Start int `yaml:"start"`
+	// End 端口范围的结束端口（包含）
+	End int `yaml:"end"`
+}
+
+// SchedulerConfig 实例调度配置
+type SchedulerConfig struct {
+	// Policy 默认调度策略：least_loaded、bin_packing 或 spread
+	Policy string `yaml:"policy"`
+}
+
+// InventoryConfig 容器清单核对配置
+type InventoryConfig struct {
+	// Interval 核对周期
+	Interval time.Duration `yaml:"interval"`
+	// Grace 实例启动或容器创建后经过该时间才参与核对
+	Grace time.Duration `yaml:"grace"`
+	// AutoCleanup 是否自动清理不一致项：缺少容器的实例标记为 failed，孤立容器通知节点删除
+	AutoCleanup bool `yaml:"auto_cleanup"`
+}
+
+// NodeConfig 节点连接配置
+type NodeConfig struct {
+	// OfflineGracePeriod 节点超过该时间未发送任何消息时标记为离线，其上的流水线和实例标记为失联
+	OfflineGracePeriod time.Duration `yaml:"offline_grace_period"`
+}
+
+// PipelineConfig 流水线配置
+type PipelineConfig struct {
+	// TemplateDir 流水线模板目录
+	TemplateDir string `yaml:"template_dir"`
+	// Logs 步骤日志存储配置
+	Logs StepLogConfig `yaml:"logs"`
+}
+
+// StepLogConfig 步骤日志存储配置
+type StepLogConfig struct {
+	// Dir 日志目录，每个步骤一个日志文件
+	Dir string `yaml:"dir"`
+	// MaxFileSize 单个日志文件的最大字节数，超过后轮转
+	MaxFileSize int64 `yaml:"max_file_size"`
+	// MaxFiles 每个步骤保留的日志文件数（包括当前文件）
+	MaxFiles int `yaml:"max_files"`
+}
+
+// LoadServerFileConfig 加载服务端配置文件，文件不存在时使用默认配置
+func LoadServerFileConfig(path string) (*ServerFileConfig, error) {
+	cfg := &ServerFileConfig{
+		Envs: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %w", err)
+		}
+	}
+
+	if cfg.Envs == nil {
+		cfg.Envs = make(map[string]string)
+	}
+	if cfg.Pipeline.TemplateDir == "" {
+		cfg.Pipeline.TemplateDir = "config/pipeline"
+	}
+	if cfg.Pipeline.Logs.Dir == "" {
+		cfg.Pipeline.Logs.Dir = "data/logs"
+	}
+	if cfg.Pipeline.Logs.MaxFileSize <= 0 {
+		cfg.Pipeline.Logs.MaxFileSize = 10 << 20
+	}
+	if cfg.Pipeline.Logs.MaxFiles <= 0 {
+		cfg.Pipeline.Logs.MaxFiles = 3
+	}
+	if cfg.Node.OfflineGracePeriod <= 0 {
+		cfg.Node.OfflineGracePeriod = 60 * time.Second
+	}
+	if cfg.Inventory.Interval <= 0 {
+		cfg.Inventory.Interval = 60 * time.Second
+	}
+	if cfg.Inventory.Grace <= 0 {
+		cfg.Inventory.Grace = 5 * time.Minute
+	}
+	if cfg.Scheduler.Policy == "" {
+		cfg.Scheduler.Policy = "least_loaded"
+	}
+	if cfg.Ports.Start <= 0 {
+		cfg.Ports.Start = 30000
+	}
+	if cfg.Ports.End <= 0 {
+		cfg.Ports.End = 30999
+	}
+	if cfg.Access.TTL <= 0 {
+		cfg.Access.TTL = 24 * time.Hour
+	}
+	if cfg.TURN.TTL <= 0 {
+		cfg.TURN.TTL = 24 * time.Hour
+	}
+	if cfg.Saves.Endpoint == "" {
+		cfg.Saves.Endpoint = cfg.Envs["S3_URL"]
+	}
+	if cfg.Saves.Bucket == "" {
+		cfg.Saves.Bucket = cfg.Envs["S3_BUCKET"]
+	}
+	if cfg.Saves.AccessKey == "" {
+		cfg.Saves.AccessKey = cfg.Envs["S3_ACCESS_KEY"]
+	}
+	if cfg.Saves.SecretKey == "" {
+		cfg.Saves.SecretKey = cfg.Envs["S3_SECRET_KEY"]
+	}
+	if cfg.Saves.Region == "" {
+		cfg.Saves.Region = "us-east-1"
+	}
+	if cfg.Saves.Prefix == "" {
+		cfg.Saves.Prefix = "saves"
+	}
+	if cfg.Idle.Interval <= 0 {
+		cfg.Idle.Interval = 60 * time.Second
+	}
+	if cfg.Idle.Timeout <= 0 {
+		cfg.Idle.Timeout = 30 * time.Minute
+	}
+	if cfg.Idle.Warning <= 0 {
+		cfg.Idle.Warning = 5 * time.Minute
+	}
+	if cfg.Idle.CPUThreshold <= 0 {
+		cfg.Idle.CPUThreshold = 10
+	}
+	if cfg.Idle.GPUThreshold <= 0 {
+		cfg.Idle.GPUThreshold = 10
+	}
+	if cfg.Ports.Start > cfg.Ports.End || cfg.Ports.End > 65535 {
+		return nil, fmt.Errorf("无效的端口范围: %d-%d", cfg.Ports.Start, cfg.Ports.End)
+	}
+
+	return cfg, nil
+}
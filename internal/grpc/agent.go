@@ -3,23 +3,72 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	dockerclient "github.com/docker/docker/client"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/open-beagle/beagle-wind-game/internal/models"
 	"github.com/open-beagle/beagle-wind-game/internal/proto"
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
+const (
+	// 单次等待连接就绪的超时时间
+	connectTimeout = 10 * time.Second
+)
+
 // AgentOptions 配置选项
 type AgentOptions struct {
 	HeartbeatPeriod time.Duration
+	MetricsInterval time.Duration
+	// ContainersInterval 容器清单上报周期
+	ContainersInterval time.Duration
+	// TLS 双向 TLS 配置，未配置时使用明文连接
+	TLS TLSConfig
+	// JoinToken 加入令牌，节点尚无凭证时随注册请求提交
+	JoinToken string
+	// CredentialFile 节点凭证的保存路径，服务端签发的凭证写入该文件供重启后使用
+	CredentialFile string
+	// OutboxFile 连接中断期间积压的上报消息的保存路径，为空时只保存在内存中
+	OutboxFile string
+}
+
+// ConnectionState Agent 与服务端的连接状态
+type ConnectionState string
+
+const (
+	ConnectionStateConnecting   ConnectionState = "connecting"   // 正在建立连接
+	ConnectionStateConnected    ConnectionState = "connected"    // 已连接并完成注册
+	ConnectionStateDisconnected ConnectionState = "disconnected" // 连接断开，等待重连
+)
+
+// ConnectionStatus 连接状态快照
+// ServerAddr 为当前连接的服务端，Servers 为配置的全部服务端
+type ConnectionStatus struct {
+	NodeID     string          `json:"node_id"`
+	ServerAddr string          `json:"server_addr"`
+	Servers    []string        `json:"servers"`
+	State      ConnectionState `json:"state"`
+	Since      time.Time       `json:"since"`
+	LastError  string          `json:"last_error,omitempty"`
+	Reconnects int             `json:"reconnects"`
+
+	// NodeState 服务端下发的节点维护状态
+	NodeState models.GameNodeStaticState `json:"node_state"`
 }
 
+// ConnectedHandler 连接建立（包括重连）后执行的处理函数，返回错误时整体重试
+type ConnectedHandler func(ctx context.Context) error
+
+// NodeStateHandler 节点维护状态变更后执行的处理函数
+type NodeStateHandler func(state models.GameNodeStaticState)
+
 // Agent 表示基础 Agent
 type Agent struct {
 	mu     sync.RWMutex
@@ -30,6 +79,9 @@ type Agent struct {
 	id         string
 	serverAddr string
 
+	// 服务端地址列表，serverAddr 为当前连接的服务端
+	servers *serverList
+
 	// Docker 客户端
 	dockerClient *dockerclient.Client
 
@@ -39,6 +91,34 @@ type Agent struct {
 	stopChan  chan struct{}
 	isRunning bool
 
+	// 连接状态
+	connState         ConnectionState
+	connSince         time.Time
+	lastError         string
+	reconnects        int
+	lost              chan struct{}
+	connectedHandlers []ConnectedHandler
+
+	// 节点凭证，由服务端在注册时签发
+	credential string
+
+	// 节点维护状态，由服务端在注册、心跳和 PipelineStream 中下发
+	nodeState     models.GameNodeStaticState
+	stateHandlers []NodeStateHandler
+
+	// 诊断信息收集函数，由 AddDiagnostics 注册
+	diagnostics []diagnosticsEntry
+
+	// 上报消息队列，reportMu 保证直接发送和重放按顺序进行
+	outbox        *Outbox
+	reportSenders map[OutboxKind]ReportSender
+	reportMu      sync.Mutex
+	replaying     bool
+	// replayAttempt 重放遇到暂时性错误的连续次数，用于计算退避时间
+	replayAttempt int
+	// runCtx Agent 主循环的上下文，直接发送失败后的延迟重放使用该上下文
+	runCtx context.Context
+
 	// 服务客户端
 	gameNodeClient proto.GameNodeGRPCServiceClient
 	pipelineClient proto.GamePipelineGRPCServiceClient
@@ -48,20 +128,50 @@ type Agent struct {
 func NewAgent(
 	ctx context.Context,
 	id string,
-	serverAddr string,
+	servers []string,
 	dockerClient *dockerclient.Client,
 	opts *AgentOptions,
 ) (*Agent, error) {
+	serverList, err := newServerList(servers)
+	if err != nil {
+		return nil, err
+	}
+
 	agent := &Agent{
 		id:           id,
-		serverAddr:   serverAddr,
+		servers:      serverList,
 		dockerClient: dockerClient,
 		opts:         opts,
 		logger:       utils.New("Agent"),
 		stopChan:     make(chan struct{}),
 		isRunning:    false,
+		connState:    ConnectionStateConnecting,
+		connSince:    time.Now(),
+		lost:         make(chan struct{}, 1),
+
+		reportSenders: make(map[OutboxKind]ReportSender),
+	}
+	if agent.opts == nil {
+		agent.opts = &AgentOptions{}
 	}
 
+	// 加载本地保存的节点凭证
+	credential, err := loadCredential(agent.opts.CredentialFile)
+	if err != nil {
+		return nil, err
+	}
+	agent.credential = credential
+
+	// 加载连接中断期间积压的上报消息
+	outbox, err := NewOutbox(agent.opts.OutboxFile, agent.logger)
+	if err != nil {
+		return nil, err
+	}
+	agent.outbox = outbox
+
+	// 注册基础诊断信息，业务 Agent 可继续注册各自的诊断信息
+	agent.registerBuiltinDiagnostics()
+
 	// 建立连接
 	if err := agent.connect(ctx); err != nil {
 		return nil, fmt.Errorf("connect failed: %v", err)
@@ -81,13 +191,36 @@ func (a *Agent) initClients() {
 
 // connect 建立 gRPC 连接
 func (a *Agent) connect(ctx context.Context) error {
-	a.logger.Debug("开始连接到服务器: %s", a.serverAddr)
+	a.logger.Debug("开始连接到服务器: %s", strings.Join(a.servers.Servers(), ", "))
+
+	creds := insecure.NewCredentials()
+	if a.opts.TLS.Enabled() {
+		tlsCreds, err := NewClientCredentials(a.opts.TLS, a.logger)
+		if err != nil {
+			return fmt.Errorf("创建 TLS 凭证失败: %w", err)
+		}
+		creds = tlsCreds
+	} else {
+		a.logger.Warn("未配置 TLS，使用明文连接服务器")
+	}
 
-	// 使用 grpc.NewClient 创建连接
-	conn, err := grpc.NewClient(
-		a.serverAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	// 使用 grpc.NewClient 创建连接，底层连接断开后按退避策略自动重建
+	// 配置多个服务端时由 pick_first 选择一个可连接的服务端
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(a.observeUnaryInterceptor, a.credentialUnaryInterceptor),
+		grpc.WithChainStreamInterceptor(a.observeStreamInterceptor, a.credentialStreamInterceptor),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  ReconnectRetryConfig.InitialDelay,
+				Multiplier: ReconnectRetryConfig.BackoffFactor,
+				Jitter:     ReconnectRetryConfig.JitterFactor,
+				MaxDelay:   ReconnectRetryConfig.MaxDelay,
+			},
+			MinConnectTimeout: connectTimeout,
+		}),
+	}, a.servers.dialOptions()...)
+	conn, err := grpc.NewClient(a.servers.target(), dialOpts...)
 	if err != nil {
 		a.logger.Error("连接服务器失败: %v", err)
 		return fmt.Errorf("连接服务器失败: %v", err)
@@ -120,6 +253,7 @@ func (a *Agent) Start(ctx context.Context) error {
 	}
 
 	a.isRunning = true
+	a.runCtx = ctx
 
 	// 启动主循环
 	go a.run(ctx)
@@ -143,37 +277,280 @@ func (a *Agent) Stop(ctx context.Context) {
 	a.isRunning = false
 }
 
-// run 运行 Agent 的主循环
-func (a *Agent) run(ctx context.Context) {
-	heartbeatPeriod := 5 * time.Second
-	if a.opts != nil && a.opts.HeartbeatPeriod > 0 {
-		heartbeatPeriod = a.opts.HeartbeatPeriod
+// OnConnected 注册连接建立后执行的处理函数
+// 首次连接和每次重连后按注册顺序执行，用于注册节点、同步状态等
+func (a *Agent) OnConnected(handler ConnectedHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.connectedHandlers = append(a.connectedHandlers, handler)
+}
+
+// MarkDisconnected 报告连接已断开，触发重连
+// 各业务 Agent 在 RPC 或流返回连接类错误时调用
+func (a *Agent) MarkDisconnected(err error) {
+	a.mu.Lock()
+	if a.connState != ConnectionStateConnected {
+		a.mu.Unlock()
+		return
+	}
+	a.setConnStateLocked(ConnectionStateDisconnected, err)
+	a.mu.Unlock()
+
+	a.logger.Warn("与服务器的连接已断开: %v", err)
+	select {
+	case a.lost <- struct{}{}:
+	default:
+	}
+}
+
+// ConnectionStatus 获取连接状态快照
+func (a *Agent) ConnectionStatus() ConnectionStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return ConnectionStatus{
+		NodeID:     a.id,
+		ServerAddr: a.serverAddr,
+		Servers:    a.servers.Servers(),
+		State:      a.connState,
+		Since:      a.connSince,
+		LastError:  a.lastError,
+		Reconnects: a.reconnects,
+		NodeState:  a.nodeState.Effective(),
+	}
+}
+
+// IsConnected 是否已连接并完成连接后处理
+func (a *Agent) IsConnected() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.connState == ConnectionStateConnected
+}
+
+// setConnStateLocked 更新连接状态，调用方持有 a.mu
+func (a *Agent) setConnStateLocked(state ConnectionState, err error) {
+	if a.connState != state {
+		a.connState = state
+		a.connSince = time.Now()
+	}
+	if err != nil {
+		a.lastError = err.Error()
 	}
+}
+
+// setConnState 更新连接状态
+func (a *Agent) setConnState(state ConnectionState, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.setConnStateLocked(state, err)
+}
 
-	ticker := time.NewTicker(heartbeatPeriod)
-	defer ticker.Stop()
+// run 维护与服务端的连接：建立连接后执行连接处理函数，连接断开后按退避策略重连
+func (a *Agent) run(ctx context.Context) {
+	go a.watchConnection(ctx)
 
+	resume := false
 	for {
+		server, err := a.establish(ctx, resume)
+		if err != nil {
+			// 只有 ctx 结束时才会返回错误
+			return
+		}
+		resume = true
+
+		// 监听当前服务端的健康状态，连接断开后结束
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		go a.watchServer(watchCtx, server)
+
+		lost := false
 		select {
 		case <-ctx.Done():
-			return
 		case <-a.stopChan:
+		case <-a.lost:
+			lost = true
+		}
+		stopWatch()
+		if !lost {
 			return
-		case <-ticker.C:
-			// 发送心跳
-			if err := a.sendHeartbeat(ctx); err != nil {
-				a.logger.Error("Failed to send heartbeat: %v", err)
+		}
+	}
+}
+
+// establish 等待连接就绪并执行连接处理函数，失败时按 ReconnectRetryConfig 退避重试
+// 连接到不健康的服务端时将其排除，切换到其他服务端，返回最终连接的服务端
+func (a *Agent) establish(ctx context.Context, resume bool) (string, error) {
+	a.setConnState(ConnectionStateConnecting, nil)
+	if resume {
+		a.logger.Info("开始重新连接服务器: %s", strings.Join(a.servers.Servers(), ", "))
+	}
+
+	attempt := 0
+	server := ""
+	err := Retry(ctx, func() error {
+		attempt++
+		if err := a.waitReady(ctx); err != nil {
+			// 排除不健康的服务端后其余服务端都无法连接，恢复完整的地址列表
+			a.servers.restore()
+			a.setConnState(ConnectionStateDisconnected, err)
+			a.logger.Warn("第 %d 次连接服务器失败: %v", attempt, err)
+			return WrapError(err, true)
+		}
+		var err error
+		server, err = a.checkServer(ctx)
+		if err != nil {
+			if a.servers.exclude(server) {
+				a.logger.Warn("服务端 %s 不可用，切换到其他服务端", server)
 			}
+			a.setConnState(ConnectionStateDisconnected, err)
+			a.logger.Warn("第 %d 次连接服务器失败: %v", attempt, err)
+			return WrapError(err, true)
+		}
+		if err := a.runConnectedHandlers(ctx); err != nil {
+			a.setConnState(ConnectionStateConnecting, err)
+			a.logger.Warn("第 %d 次连接后处理失败: %v", attempt, err)
+			return WrapError(err, true)
+		}
+		return nil
+	}, ReconnectRetryConfig)
+	if err != nil {
+		return "", err
+	}
+	a.servers.restore()
+
+	a.mu.Lock()
+	a.setConnStateLocked(ConnectionStateConnected, nil)
+	a.lastError = ""
+	if resume {
+		a.reconnects++
+	}
+	prev := a.serverAddr
+	a.serverAddr = server
+	a.mu.Unlock()
+
+	if resume {
+		a.logger.Info("已重新连接服务器: %s", server)
+	}
+	if prev != "" && prev != server {
+		a.logger.Warn("已从服务端 %s 切换到 %s", prev, server)
+	}
+
+	// 重放连接中断期间积压的上报消息
+	a.startReplay(ctx)
+	return server, nil
+}
+
+// waitReady 等待 gRPC 连接进入 Ready 状态，最多等待 connectTimeout
+func (a *Agent) waitReady(ctx context.Context) error {
+	waitCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	for {
+		state := a.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Shutdown:
+			return fmt.Errorf("连接已关闭")
+		case connectivity.Idle:
+			a.conn.Connect()
+		}
+		if !a.conn.WaitForStateChange(waitCtx, state) {
+			return fmt.Errorf("等待连接就绪超时, 当前状态: %s", state)
 		}
 	}
 }
 
-// sendHeartbeat 发送心跳消息
-func (a *Agent) sendHeartbeat(ctx context.Context) error {
-	// 基础 Agent 不实现具体的心跳逻辑
+// runConnectedHandlers 按注册顺序执行连接处理函数
+func (a *Agent) runConnectedHandlers(ctx context.Context) error {
+	a.mu.RLock()
+	handlers := append([]ConnectedHandler{}, a.connectedHandlers...)
+	a.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// watchConnection 监听 gRPC 连接状态，连接从 Ready 变为其他状态时触发重连
+func (a *Agent) watchConnection(ctx context.Context) {
+	prev := a.conn.GetState()
+	for {
+		if !a.conn.WaitForStateChange(ctx, prev) {
+			return
+		}
+		state := a.conn.GetState()
+		if state == connectivity.Shutdown {
+			return
+		}
+		if prev == connectivity.Ready && state != connectivity.Ready {
+			a.MarkDisconnected(fmt.Errorf("连接状态变为 %s", state))
+		}
+		prev = state
+	}
+}
+
+// Credential 获取当前节点凭证
+func (a *Agent) Credential() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.credential
+}
+
+// NodeState 获取节点维护状态
+func (a *Agent) NodeState() models.GameNodeStaticState {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.nodeState.Effective()
+}
+
+// OnNodeStateChange 注册节点维护状态变更后执行的处理函数
+func (a *Agent) OnNodeStateChange(handler NodeStateHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stateHandlers = append(a.stateHandlers, handler)
+}
+
+// SetNodeState 更新节点维护状态，状态变化时按注册顺序执行处理函数
+func (a *Agent) SetNodeState(state models.GameNodeStaticState) {
+	if !state.IsValid() {
+		a.logger.Warn("忽略无效的节点维护状态: %s", state)
+		return
+	}
+
+	a.mu.Lock()
+	prev := a.nodeState.Effective()
+	a.nodeState = state
+	handlers := append([]NodeStateHandler{}, a.stateHandlers...)
+	a.mu.Unlock()
+
+	if prev == state {
+		return
+	}
+	a.logger.Info("节点维护状态变更: %s -> %s", prev, state)
+	for _, handler := range handlers {
+		handler(state)
+	}
+}
+
+// JoinToken 获取配置的加入令牌
+func (a *Agent) JoinToken() string {
+	return a.opts.JoinToken
+}
+
+// SetCredential 更新节点凭证并写入凭证文件
+func (a *Agent) SetCredential(credential string) error {
+	a.mu.Lock()
+	a.credential = credential
+	a.mu.Unlock()
+
+	if credential == "" {
+		return nil
+	}
+	return saveCredential(a.opts.CredentialFile, credential)
+}
+
 // GetGameNodeClient 获取 GameNode 服务客户端
 func (a *Agent) GetGameNodeClient() proto.GameNodeGRPCServiceClient {
 	return a.gameNodeClient
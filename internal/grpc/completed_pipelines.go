@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// completedPipelinesFile 已结束 Pipeline 记录的文件名，与 outbox 保存在同一目录
+	completedPipelinesFile = "completed-pipelines.json"
+	// completedMaxEntries 最多保留的已结束 Pipeline 记录数量，超出时丢弃最早结束的记录
+	completedMaxEntries = 1000
+	// completedRetention 已结束 Pipeline 记录的保留时间，需覆盖节点离线和 outbox 积压的时间
+	completedRetention = 7 * 24 * time.Hour
+)
+
+// CompletedPipeline 节点上已结束的 Pipeline
+type CompletedPipeline struct {
+	ID         string    `json:"id"`          // Pipeline ID
+	Status     []byte    `json:"status"`      // protobuf 编码的结束状态
+	FinishedAt time.Time `json:"finished_at"` // 结束时间
+}
+
+// CompletedPipelines 保存在磁盘上的已结束 Pipeline 记录
+// 服务端未收到确认或结束状态时会在重连后重新下发 Pipeline，
+// Agent 重启后仍能据此识别已执行过的 Pipeline，只确认并重新上报结束状态，不再执行
+type CompletedPipelines struct {
+	path    string
+	logger  utils.Logger
+	mu      sync.Mutex
+	entries map[string]*CompletedPipeline
+}
+
+// completedPipelinesPath 已结束 Pipeline 记录的文件路径，outbox 只保存在内存中时同样只保存在内存中
+func completedPipelinesPath(outboxFile string) string {
+	if outboxFile == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(outboxFile), completedPipelinesFile)
+}
+
+// NewCompletedPipelines 创建已结束 Pipeline 记录并加载文件中保存的记录，path 为空时只保存在内存中
+func NewCompletedPipelines(path string, logger utils.Logger) (*CompletedPipelines, error) {
+	c := &CompletedPipelines{
+		path:    path,
+		logger:  logger,
+		entries: make(map[string]*CompletedPipeline),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取已结束 Pipeline 记录失败: %w", err)
+	}
+	var entries []*CompletedPipeline
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("解析已结束 Pipeline 记录失败: %w", err)
+	}
+	for _, entry := range entries {
+		c.entries[entry.ID] = entry
+	}
+	return c, nil
+}
+
+// Get 获取已结束的 Pipeline
+func (c *CompletedPipelines) Get(id string) (*CompletedPipeline, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[id]
+	return entry, ok
+}
+
+// Add 记录已结束的 Pipeline 并保存，同时清理超过保留时间和数量限制的记录
+func (c *CompletedPipelines) Add(id string, status []byte, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[id] = &CompletedPipeline{ID: id, Status: status, FinishedAt: now}
+	entries := c.sortedLocked()
+	for i, entry := range entries {
+		if now.Sub(entry.FinishedAt) > completedRetention || len(entries)-i > completedMaxEntries {
+			delete(c.entries, entry.ID)
+		}
+	}
+	return c.saveLocked()
+}
+
+// sortedLocked 按结束时间排序的记录，调用方需持有锁
+func (c *CompletedPipelines) sortedLocked() []*CompletedPipeline {
+	entries := make([]*CompletedPipeline, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FinishedAt.Before(entries[j].FinishedAt)
+	})
+	return entries
+}
+
+// saveLocked 将记录写入文件，调用方需持有锁
+func (c *CompletedPipelines) saveLocked() error {
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(c.sortedLocked())
+	if err != nil {
+		return fmt.Errorf("序列化已结束 Pipeline 记录失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("创建已结束 Pipeline 记录目录失败: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存已结束 Pipeline 记录失败: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("保存已结束 Pipeline 记录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestCompletedPipelinesAcknowledgeRedispatch(t *testing.T) {
+	dir := t.TempDir()
+	logger := utils.New("CompletedTest")
+	path := completedPipelinesPath(filepath.Join(dir, "outbox.json"))
+	now := time.Now()
+
+	completed, err := NewCompletedPipelines(path, logger)
+	require.NoError(t, err)
+	status, err := protobuf.Marshal(&proto.PipelineStatus{State: proto.PipelineState_PIPELINE_STATE_COMPLETED, CurrentStep: 2, TotalSteps: 2})
+	require.NoError(t, err)
+	require.NoError(t, completed.Add("expired", status, now.Add(-completedRetention-time.Hour)))
+	require.NoError(t, completed.Add("start-platform-1", status, now))
+
+	// Agent 重启后仍能识别已结束的 Pipeline，超过保留时间的记录被清理
+	completed, err = NewCompletedPipelines(path, logger)
+	require.NoError(t, err)
+	_, ok := completed.Get("expired")
+	assert.False(t, ok)
+
+	outbox, err := NewOutbox("", logger)
+	require.NoError(t, err)
+	agent := &Agent{
+		id:            "node-1",
+		logger:        logger,
+		connState:     ConnectionStateConnected,
+		lost:          make(chan struct{}, 1),
+		outbox:        outbox,
+		reportSenders: make(map[OutboxKind]ReportSender),
+	}
+	var reported []*proto.UpdatePipelineStatusRequest
+	agent.HandleReport(OutboxKindPipelineStatus, func(ctx context.Context, payload []byte) error {
+		req := &proto.UpdatePipelineStatusRequest{}
+		require.NoError(t, protobuf.Unmarshal(payload, req))
+		reported = append(reported, req)
+		return nil
+	})
+	pipelineAgent := &GamePipelineAgent{
+		agent:     agent,
+		logger:    logger,
+		sources:   make(map[string]*models.GamePipeline),
+		sequences: make(map[string]int64),
+		completed: completed,
+	}
+
+	// 重复下发的已结束 Pipeline 只确认并重新上报结束状态，不再执行
+	ack := pipelineAgent.acceptPipeline(context.Background(), &proto.GamePipeline{Id: "start-platform-1"})
+	assert.True(t, ack.Accepted)
+	require.Len(t, reported, 1)
+	assert.Equal(t, "start-platform-1", reported[0].PipelineId)
+	assert.Equal(t, proto.PipelineState_PIPELINE_STATE_COMPLETED, reported[0].Status.State)
+	assert.EqualValues(t, 2, reported[0].Status.CurrentStep)
+	assert.Positive(t, reported[0].Sequence)
+	assert.Zero(t, pipelineAgent.GetSourceCount())
+	assert.Empty(t, pipelineAgent.sequences)
+}
@@ -0,0 +1,281 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// defaultOfflineGracePeriod 默认的离线宽限期
+	defaultOfflineGracePeriod = 60 * time.Second
+	// offlineHandlerTimeout 执行离线处理函数的最长时间
+	offlineHandlerTimeout = 30 * time.Second
+)
+
+// NodeOfflineHandler 节点被标记为离线后执行的处理函数
+type NodeOfflineHandler func(ctx context.Context, nodeID string)
+
+// AgentConnection 一个节点的连接记录，Heartbeat 请求和 PipelineStream 会话共用
+type AgentConnection struct {
+	nodeID        string
+	remoteAddr    string
+	online        bool
+	connectedAt   time.Time
+	lastHeartbeat time.Time
+	lastSeen      time.Time
+	offlineAt     time.Time
+	session       *NodeSession
+}
+
+// ConnectionRegistry 节点连接注册表
+// 记录节点的心跳和 PipelineStream 会话，定期关闭心跳超时的会话，
+// 超过离线宽限期未收到任何消息的节点被标记为离线
+type ConnectionRegistry struct {
+	nodeService GameNodeServiceInterface
+	gracePeriod time.Duration
+	logger      utils.Logger
+
+	mu       sync.RWMutex
+	conns    map[string]*AgentConnection
+	handlers []NodeOfflineHandler
+}
+
+// NewConnectionRegistry 创建连接注册表，gracePeriod 不大于 0 时使用默认值
+func NewConnectionRegistry(nodeService GameNodeServiceInterface, gracePeriod time.Duration, logger utils.Logger) *ConnectionRegistry {
+	if gracePeriod <= 0 {
+		gracePeriod = defaultOfflineGracePeriod
+	}
+	return &ConnectionRegistry{
+		nodeService: nodeService,
+		gracePeriod: gracePeriod,
+		logger:      logger,
+		conns:       make(map[string]*AgentConnection),
+	}
+}
+
+// OnOffline 注册节点离线后执行的处理函数，按注册顺序执行
+func (r *ConnectionRegistry) OnOffline(handler NodeOfflineHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, handler)
+}
+
+// Touch 记录收到节点请求
+func (r *ConnectionRegistry) Touch(ctx context.Context, nodeID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.touchLocked(ctx, nodeID, time.Now())
+}
+
+// Heartbeat 记录节点的 Heartbeat 请求
+func (r *ConnectionRegistry) Heartbeat(ctx context.Context, nodeID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	conn := r.touchLocked(ctx, nodeID, now)
+	conn.lastHeartbeat = now
+}
+
+// touchLocked 更新节点的最近消息时间，离线或首次出现的节点重新上线，调用方需持有锁
+func (r *ConnectionRegistry) touchLocked(ctx context.Context, nodeID string, now time.Time) *AgentConnection {
+	conn, ok := r.conns[nodeID]
+	if !ok {
+		conn = &AgentConnection{nodeID: nodeID}
+		r.conns[nodeID] = conn
+	}
+	if !conn.online {
+		conn.online = true
+		conn.connectedAt = now
+		conn.offlineAt = time.Time{}
+	}
+	conn.lastSeen = now
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		conn.remoteAddr = p.Addr.String()
+	}
+	return conn
+}
+
+// Expect 登记服务端启动前在线的节点，节点在离线宽限期内未重新连接时被标记为离线
+func (r *ConnectionRegistry) Expect(nodeID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.conns[nodeID]; ok {
+		return
+	}
+	now := time.Now()
+	r.conns[nodeID] = &AgentConnection{
+		nodeID:      nodeID,
+		online:      true,
+		connectedAt: now,
+		lastSeen:    now,
+	}
+}
+
+// AttachSession 为节点创建 PipelineStream 会话，同一节点重新连接时关闭旧会话
+func (r *ConnectionRegistry) AttachSession(ctx context.Context, nodeID string) *NodeSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conn := r.touchLocked(ctx, nodeID, time.Now())
+	if conn.session != nil {
+		r.logger.Warn("节点 %s 重新建立会话，关闭旧会话", nodeID)
+		conn.session.Close(status.Error(codes.Aborted, "节点已建立新会话"))
+	}
+	conn.session = NewNodeSession(nodeID)
+	return conn.session
+}
+
+// DetachSession 移除节点会话，会话已被新会话替换时不做处理
+func (r *ConnectionRegistry) DetachSession(session *NodeSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conn, ok := r.conns[session.ID]
+	if !ok || conn.session != session {
+		return
+	}
+	// 会话中最后收到的消息同样计入节点的最近消息时间
+	if seen := session.LastSeen(); seen.After(conn.lastSeen) {
+		conn.lastSeen = seen
+	}
+	conn.session = nil
+}
+
+// Session 获取节点当前的 PipelineStream 会话
+func (r *ConnectionRegistry) Session(nodeID string) (*NodeSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conn, ok := r.conns[nodeID]
+	if !ok || conn.session == nil {
+		return nil, fmt.Errorf("%w: %s", service.ErrNodeNotConnected, nodeID)
+	}
+	return conn.session, nil
+}
+
+// Sessions 获取所有 PipelineStream 会话
+func (r *ConnectionRegistry) Sessions() []*NodeSession {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sessions := make([]*NodeSession, 0, len(r.conns))
+	for _, conn := range r.conns {
+		if conn.session != nil {
+			sessions = append(sessions, conn.session)
+		}
+	}
+	return sessions
+}
+
+// NodeConnection 获取节点的连接信息，实现 service.NodeConnectionProvider
+func (r *ConnectionRegistry) NodeConnection(nodeID string) (models.NodeConnection, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conn, ok := r.conns[nodeID]
+	if !ok {
+		return models.NodeConnection{}, false
+	}
+	return conn.snapshot(), true
+}
+
+// snapshot 连接信息快照，调用方需持有锁
+func (c *AgentConnection) snapshot() models.NodeConnection {
+	info := models.NodeConnection{
+		NodeID:     c.nodeID,
+		Online:     c.online,
+		RemoteAddr: c.remoteAddr,
+		LastSeen:   c.lastSeenAt(),
+	}
+	if c.online {
+		info.ConnectedAt = timePtr(c.connectedAt)
+	} else {
+		info.OfflineAt = timePtr(c.offlineAt)
+	}
+	if !c.lastHeartbeat.IsZero() {
+		info.LastHeartbeat = timePtr(c.lastHeartbeat)
+	}
+	if c.session != nil {
+		info.StreamConnected = true
+		info.StreamSince = timePtr(c.session.Since())
+		info.Pipelines = c.session.GetSources()
+	}
+	return info
+}
+
+// lastSeenAt 最近一次收到节点消息的时间，包括会话中的消息
+func (c *AgentConnection) lastSeenAt() time.Time {
+	if c.session != nil {
+		if seen := c.session.LastSeen(); seen.After(c.lastSeen) {
+			return seen
+		}
+	}
+	return c.lastSeen
+}
+
+// Run 定期检查连接，直到 ctx 取消
+func (r *ConnectionRegistry) Run(ctx context.Context) {
+	ticker := time.NewTicker(sessionSweepInterval)
+	defer ticker.Stop()
+
+	r.logger.Info("节点连接检查启动，离线宽限期: %v", r.gracePeriod)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.Sweep(ctx, now)
+		}
+	}
+}
+
+// Sweep 关闭超过 heartbeatTimeout 未收到消息的会话，并将超过离线宽限期的节点标记为离线
+func (r *ConnectionRegistry) Sweep(ctx context.Context, now time.Time) {
+	var expired []*NodeSession
+	var offline []string
+
+	r.mu.Lock()
+	for id, conn := range r.conns {
+		if conn.session != nil && now.Sub(conn.session.LastSeen()) > heartbeatTimeout {
+			expired = append(expired, conn.session)
+		}
+		if conn.online && now.Sub(conn.lastSeenAt()) > r.gracePeriod {
+			conn.online = false
+			conn.offlineAt = now
+			offline = append(offline, id)
+		}
+	}
+	handlers := append([]NodeOfflineHandler{}, r.handlers...)
+	r.mu.Unlock()
+
+	for _, session := range expired {
+		r.logger.Warn("节点 %s 心跳超时，关闭会话", session.ID)
+		session.Close(status.Error(codes.DeadlineExceeded, "心跳超时"))
+	}
+
+	for _, nodeID := range offline {
+		r.logger.Warn("节点 %s 超过 %v 未发送消息，标记为离线", nodeID, r.gracePeriod)
+		handlerCtx, cancel := context.WithTimeout(ctx, offlineHandlerTimeout)
+		if r.nodeService != nil {
+			if err := r.nodeService.UpdateStatusOnlineStatus(handlerCtx, nodeID, false); err != nil {
+				r.logger.Error("更新节点 %s 离线状态失败: %v", nodeID, err)
+			}
+		}
+		for _, handler := range handlers {
+			handler(handlerCtx, nodeID)
+		}
+		cancel()
+	}
+}
+
+// timePtr 返回时间的指针
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
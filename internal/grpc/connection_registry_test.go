@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestConnectionRegistrySweep(t *testing.T) {
+	ctx := context.Background()
+	registry := NewConnectionRegistry(nil, time.Minute, utils.New("RegistryTest"))
+
+	var offline []string
+	registry.OnOffline(func(ctx context.Context, nodeID string) {
+		offline = append(offline, nodeID)
+	})
+
+	registry.Heartbeat(ctx, "node-1")
+	session := registry.AttachSession(ctx, "node-2")
+	registry.Expect("node-3")
+
+	conn, ok := registry.NodeConnection("node-2")
+	require.True(t, ok)
+	assert.True(t, conn.Online)
+	assert.True(t, conn.StreamConnected)
+
+	// 会话心跳超时后被关闭，节点在离线宽限期内仍然在线
+	now := time.Now()
+	registry.Sweep(ctx, now.Add(heartbeatTimeout+time.Second))
+	select {
+	case <-session.Done():
+	default:
+		t.Fatal("心跳超时的会话应被关闭")
+	}
+	assert.Empty(t, offline)
+
+	// 超过离线宽限期后标记为离线，处理函数只执行一次
+	registry.Sweep(ctx, now.Add(time.Minute+time.Second))
+	assert.ElementsMatch(t, []string{"node-1", "node-2", "node-3"}, offline)
+	registry.Sweep(ctx, now.Add(2*time.Minute))
+	assert.Len(t, offline, 3)
+
+	conn, ok = registry.NodeConnection("node-1")
+	require.True(t, ok)
+	assert.False(t, conn.Online)
+	assert.NotNil(t, conn.OfflineAt)
+
+	// 重新发送消息后恢复在线
+	registry.Heartbeat(ctx, "node-1")
+	conn, _ = registry.NodeConnection("node-1")
+	assert.True(t, conn.Online)
+	assert.NotNil(t, conn.LastHeartbeat)
+}
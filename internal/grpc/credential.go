@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+)
+
+// nodeCredentialMetadataKey 节点凭证在请求 metadata 中的键
+const nodeCredentialMetadataKey = "x-node-credential"
+
+// nodeCredentialFromContext 获取请求 metadata 中的节点凭证
+func nodeCredentialFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(nodeCredentialMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// nodeAuthenticator 校验节点凭证，节点必须已获批准且凭证一致
+type nodeAuthenticator struct {
+	nodeService GameNodeServiceInterface
+}
+
+// authenticate 校验请求携带的凭证是否属于声明的节点
+func (a *nodeAuthenticator) authenticate(ctx context.Context, nodeID string) error {
+	err := a.nodeService.VerifyCredential(ctx, nodeID, nodeCredentialFromContext(ctx))
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, service.ErrNodeNotApproved):
+		return status.Errorf(codes.PermissionDenied, "节点 %s 未获批准", nodeID)
+	case errors.Is(err, service.ErrInvalidCredential):
+		return status.Errorf(codes.Unauthenticated, "节点 %s 凭证无效", nodeID)
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// unaryInterceptor 校验除注册外的一元请求的节点凭证
+func (a *nodeAuthenticator) unaryInterceptor(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (interface{}, error) {
+	// 注册请求在 Register 中通过凭证或加入令牌完成准入
+	if _, ok := req.(*proto.RegisterRequest); ok {
+		return handler(ctx, req)
+	}
+	// 健康检查不属于任何节点，Agent 据此选择可用的服务端
+	if strings.HasPrefix(info.FullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+		return handler(ctx, req)
+	}
+	nodeID, ok := requestNodeID(req)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "无法识别请求所属节点: %s", info.FullMethod)
+	}
+	if err := a.authenticate(ctx, nodeID); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// loadCredential 读取本地保存的节点凭证，文件不存在时返回空字符串
+func loadCredential(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取节点凭证失败: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveCredential 保存节点凭证，文件仅当前用户可读写
+func saveCredential(path string, credential string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建凭证目录失败: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(credential), 0600); err != nil {
+		return fmt.Errorf("写入节点凭证失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("写入节点凭证失败: %w", err)
+	}
+	return nil
+}
+
+// withCredential 在请求 metadata 中附加节点凭证
+func (a *Agent) withCredential(ctx context.Context) context.Context {
+	if credential := a.Credential(); credential != "" {
+		return metadata.AppendToOutgoingContext(ctx, nodeCredentialMetadataKey, credential)
+	}
+	return ctx
+}
+
+// credentialUnaryInterceptor 为一元请求附加节点凭证
+func (a *Agent) credentialUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *ggrpc.ClientConn, invoker ggrpc.UnaryInvoker, opts ...ggrpc.CallOption) error {
+	return invoker(a.withCredential(ctx), method, req, reply, cc, opts...)
+}
+
+// credentialStreamInterceptor 为流式请求附加节点凭证
+func (a *Agent) credentialStreamInterceptor(ctx context.Context, desc *ggrpc.StreamDesc, cc *ggrpc.ClientConn, method string, streamer ggrpc.Streamer, opts ...ggrpc.CallOption) (ggrpc.ClientStream, error) {
+	return streamer(a.withCredential(ctx), desc, cc, method, opts...)
+}
@@ -0,0 +1,277 @@
+package grpc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// diagnosticsCollectTimeout 收集一次诊断信息的最长时间
+	diagnosticsCollectTimeout = 2 * time.Minute
+	// diagnosticsChunkSize 上传诊断包时单个分块的最大字节数
+	diagnosticsChunkSize = 256 << 10
+	// maxInspectedContainers 诊断包中最多包含的容器详情数量
+	maxInspectedContainers = 50
+	// diskUsageDepth 统计 BEAGLE_WIND_ROOT 磁盘占用的目录深度
+	diskUsageDepth = 2
+)
+
+// sensitiveEnvKeys 环境变量名包含这些关键字时，诊断包中隐藏其取值
+var sensitiveEnvKeys = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL"}
+
+// DiagnosticsCollector 收集一项诊断信息，返回写入诊断包的文件内容
+type DiagnosticsCollector func(ctx context.Context) ([]byte, error)
+
+// diagnosticsEntry 诊断包中的一个文件
+type diagnosticsEntry struct {
+	name    string
+	collect DiagnosticsCollector
+}
+
+// AddDiagnostics 注册诊断信息收集函数，name 为诊断包中的文件路径
+// 收集失败时诊断包中写入 <name>.error 记录失败原因，不影响其他文件
+func (a *Agent) AddDiagnostics(name string, collect DiagnosticsCollector) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.diagnostics = append(a.diagnostics, diagnosticsEntry{name: name, collect: collect})
+}
+
+// registerBuiltinDiagnostics 注册基础 Agent 提供的诊断信息
+func (a *Agent) registerBuiltinDiagnostics() {
+	a.AddDiagnostics("agent.json", a.collectAgentInfo)
+	a.AddDiagnostics("logs/agent.log", func(ctx context.Context) ([]byte, error) {
+		return utils.RecentLogs(), nil
+	})
+	a.AddDiagnostics("containers.json", a.collectContainers)
+	a.AddDiagnostics("disk.txt", collectDiskUsage)
+}
+
+// RunDiagnostics 收集诊断信息并上传到服务端
+func (a *Agent) RunDiagnostics(ctx context.Context, requestID string) {
+	a.logger.Info("开始收集诊断信息: %s", requestID)
+
+	collectCtx, cancel := context.WithTimeout(ctx, diagnosticsCollectTimeout)
+	bundle, err := a.CollectDiagnostics(collectCtx)
+	cancel()
+
+	if err := a.uploadDiagnostics(ctx, requestID, bundle, err); err != nil {
+		a.logger.Error("上传诊断包 %s 失败: %v", requestID, err)
+		return
+	}
+	a.logger.Info("诊断包 %s 上传完成, 大小: %d 字节", requestID, len(bundle))
+}
+
+// CollectDiagnostics 依次执行已注册的收集函数，返回 tar.gz 格式的诊断包
+func (a *Agent) CollectDiagnostics(ctx context.Context) ([]byte, error) {
+	a.mu.RLock()
+	entries := append([]diagnosticsEntry{}, a.diagnostics...)
+	a.mu.RUnlock()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	for _, entry := range entries {
+		name := entry.name
+		data, err := entry.collect(ctx)
+		if err != nil {
+			a.logger.Warn("收集诊断信息 %s 失败: %v", entry.name, err)
+			name, data = entry.name+".error", []byte(err.Error()+"\n")
+		}
+		if err := writeTarFile(tw, name, data, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("生成诊断包失败: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("生成诊断包失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// uploadDiagnostics 通过 UploadDiagnostics 分块上传诊断包，collectErr 非空时只上报失败原因
+func (a *Agent) uploadDiagnostics(ctx context.Context, requestID string, bundle []byte, collectErr error) error {
+	stream, err := a.GetPipelineClient().UploadDiagnostics(ctx)
+	if err != nil {
+		return err
+	}
+
+	if collectErr != nil {
+		if err := stream.Send(&proto.DiagnosticsChunk{
+			NodeId:    a.id,
+			RequestId: requestID,
+			Error:     collectErr.Error(),
+		}); err != nil && err != io.EOF {
+			return err
+		}
+		_, err := stream.CloseAndRecv()
+		return err
+	}
+
+	for offset := 0; ; {
+		end := min(offset+diagnosticsChunkSize, len(bundle))
+		chunk := &proto.DiagnosticsChunk{
+			NodeId:    a.id,
+			RequestId: requestID,
+			Data:      bundle[offset:end],
+			Eof:       end == len(bundle),
+		}
+		if err := stream.Send(chunk); err != nil {
+			if err == io.EOF {
+				// 流已被服务端结束，真实错误通过 CloseAndRecv 获取
+				_, err = stream.CloseAndRecv()
+			}
+			return err
+		}
+		if chunk.Eof {
+			break
+		}
+		offset = end
+	}
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// collectAgentInfo Agent 配置和连接状态，不包含加入令牌和节点凭证
+func (a *Agent) collectAgentInfo(ctx context.Context) ([]byte, error) {
+	info := map[string]interface{}{
+		"node_id":          a.id,
+		"servers":          a.servers.Servers(),
+		"heartbeat_period": a.opts.HeartbeatPeriod.String(),
+		"metrics_interval": a.opts.MetricsInterval.String(),
+		"tls": map[string]interface{}{
+			"enabled":     a.opts.TLS.Enabled(),
+			"ca_file":     a.opts.TLS.CAFile,
+			"cert_file":   a.opts.TLS.CertFile,
+			"server_name": a.opts.TLS.ServerName,
+		},
+		"join_token_configured": a.opts.JoinToken != "",
+		"credential_file":       a.opts.CredentialFile,
+		"has_credential":        a.Credential() != "",
+		"outbox_file":           a.opts.OutboxFile,
+		"outbox_pending":        a.outbox.Len(),
+		"connection":            a.ConnectionStatus(),
+		"go_version":            runtime.Version(),
+		"collected_at":          time.Now(),
+	}
+	return json.MarshalIndent(info, "", "  ")
+}
+
+// collectContainers 节点上所有容器的列表和详情，环境变量中的敏感取值被隐藏
+func (a *Agent) collectContainers(ctx context.Context) ([]byte, error) {
+	if a.dockerClient == nil {
+		return nil, fmt.Errorf("Docker 客户端未初始化")
+	}
+
+	containers, err := a.dockerClient.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("获取容器列表失败: %w", err)
+	}
+
+	inspects := make([]interface{}, 0, min(len(containers), maxInspectedContainers))
+	for i, c := range containers {
+		if i >= maxInspectedContainers {
+			break
+		}
+		detail, err := a.dockerClient.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			inspects = append(inspects, map[string]string{"id": c.ID, "error": err.Error()})
+			continue
+		}
+		if detail.Config != nil {
+			detail.Config.Env = redactEnv(detail.Config.Env)
+		}
+		inspects = append(inspects, detail)
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"containers": containers,
+		"inspect":    inspects,
+	}, "", "  ")
+}
+
+// collectDiskUsage BEAGLE_WIND_ROOT 所在文件系统的容量和目录占用
+func collectDiskUsage(ctx context.Context) ([]byte, error) {
+	root := os.Getenv("BEAGLE_WIND_ROOT")
+	if root == "" {
+		return nil, fmt.Errorf("未设置 BEAGLE_WIND_ROOT 环境变量")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# BEAGLE_WIND_ROOT=%s\n\n", root)
+
+	out, err := exec.CommandContext(ctx, "df", "-B1", root).CombinedOutput()
+	fmt.Fprintf(&buf, "$ df -B1 %s\n%s", root, out)
+	if err != nil {
+		fmt.Fprintf(&buf, "错误: %v\n", err)
+	}
+
+	// du 遇到无权限的目录时返回非零，已输出的结果仍然有效
+	depth := fmt.Sprintf("--max-depth=%d", diskUsageDepth)
+	out, err = exec.CommandContext(ctx, "du", "-x", "-B1", depth, root).CombinedOutput()
+	fmt.Fprintf(&buf, "\n$ du -x -B1 %s %s\n%s", depth, root, out)
+	if err != nil {
+		fmt.Fprintf(&buf, "错误: %v\n", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// redactEnv 隐藏敏感环境变量的取值
+func redactEnv(env []string) []string {
+	result := make([]string, 0, len(env))
+	for _, kv := range env {
+		key, _, ok := strings.Cut(kv, "=")
+		if ok && isSensitiveEnv(key) {
+			kv = key + "=******"
+		}
+		result = append(result, kv)
+	}
+	return result
+}
+
+// isSensitiveEnv 环境变量名是否包含敏感关键字
+func isSensitiveEnv(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, word := range sensitiveEnvKeys {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTarFile 向诊断包写入一个文件
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("写入诊断包失败: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("写入诊断包失败: %w", err)
+	}
+	return nil
+}
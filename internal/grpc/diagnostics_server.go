@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+)
+
+// DiagnosticsServiceInterface 诊断包服务接口
+type DiagnosticsServiceInterface interface {
+	// 开始接收节点上传的诊断包
+	BeginUpload(ctx context.Context, nodeID, requestID string) (*service.DiagnosticsUpload, error)
+	// 记录节点收集诊断信息失败
+	Fail(ctx context.Context, nodeID, requestID, reason string) error
+}
+
+// SetDiagnosticsService 设置诊断包服务，未设置时拒绝诊断包上传
+func (s *GamePipelineServer) SetDiagnosticsService(diagnostics DiagnosticsServiceInterface) {
+	s.diagnostics = diagnostics
+}
+
+// RequestDiagnostics 发送诊断命令到指定节点，实现 service.DiagnosticsRequester
+func (s *GamePipelineServer) RequestDiagnostics(ctx context.Context, nodeID string, requestID string) error {
+	node, err := s.getSession(nodeID)
+	if err != nil {
+		return err
+	}
+
+	resp := &proto.PipelineStreamResponse{
+		Response: &proto.PipelineStreamResponse_Diagnostics{
+			Diagnostics: &proto.DiagnosticsCommand{
+				RequestId: requestID,
+			},
+		},
+	}
+	if err := node.Enqueue(ctx, resp); err != nil {
+		if errors.Is(err, errSessionClosed) {
+			return fmt.Errorf("%w: %s", service.ErrNodeNotConnected, nodeID)
+		}
+		return fmt.Errorf("发送诊断命令失败: %w", err)
+	}
+	return nil
+}
+
+// UploadDiagnostics 接收节点上传的诊断包，一个流只传输一个诊断包
+// 流在收到 eof 之前结束时诊断请求标记为失败
+func (s *GamePipelineServer) UploadDiagnostics(stream proto.GamePipelineGRPCService_UploadDiagnosticsServer) error {
+	ctx := stream.Context()
+	if s.diagnostics == nil {
+		return status.Error(codes.Unimplemented, service.ErrDiagnosticsDisabled.Error())
+	}
+
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return stream.SendAndClose(&proto.UploadDiagnosticsResponse{})
+	}
+	if err != nil {
+		return err
+	}
+	if first.NodeId == "" || first.RequestId == "" {
+		return status.Error(codes.InvalidArgument, "节点ID和诊断请求ID不能为空")
+	}
+	if err := authorizeNode(ctx, first.NodeId); err != nil {
+		return err
+	}
+	if s.auth != nil {
+		if err := s.auth.authenticate(ctx, first.NodeId); err != nil {
+			return err
+		}
+	}
+
+	// 节点收集诊断信息失败
+	if first.Error != "" {
+		if err := s.diagnostics.Fail(ctx, first.NodeId, first.RequestId, first.Error); err != nil {
+			return diagnosticsError(err)
+		}
+		return stream.SendAndClose(&proto.UploadDiagnosticsResponse{})
+	}
+
+	upload, err := s.diagnostics.BeginUpload(ctx, first.NodeId, first.RequestId)
+	if err != nil {
+		s.logger.Error("开始接收诊断包失败: %s, 错误: %v", first.RequestId, err)
+		return diagnosticsError(err)
+	}
+
+	for chunk := first; ; {
+		if chunk.NodeId != first.NodeId || chunk.RequestId != first.RequestId {
+			upload.Abort(ctx, "同一个流上传了多个诊断包")
+			return status.Error(codes.InvalidArgument, "同一个流只能上传一个诊断包")
+		}
+		if err := upload.Write(chunk.Data); err != nil {
+			upload.Abort(ctx, err.Error())
+			return diagnosticsError(err)
+		}
+		if chunk.Eof {
+			size, err := upload.Commit(ctx)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			return stream.SendAndClose(&proto.UploadDiagnosticsResponse{Size: size})
+		}
+
+		chunk, err = stream.Recv()
+		if err == io.EOF {
+			upload.Abort(ctx, "上传在完成前结束")
+			return status.Error(codes.InvalidArgument, "诊断包未完整上传")
+		}
+		if err != nil {
+			upload.Abort(context.Background(), fmt.Sprintf("上传中断: %v", err))
+			return err
+		}
+	}
+}
+
+// diagnosticsError 将诊断包服务错误转换为 gRPC 错误
+func diagnosticsError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrDiagnosticsNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrDiagnosticsNotOwned):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrDiagnosticsTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+}
@@ -2,10 +2,14 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // RetryConfig 重试配置
@@ -17,6 +21,15 @@ type RetryConfig struct {
 	JitterFactor  float64
 }
 
+// ReconnectRetryConfig 断线重连使用的重试配置，不限制重试次数
+var ReconnectRetryConfig = RetryConfig{
+	MaxRetries:    -1,
+	InitialDelay:  time.Second,
+	MaxDelay:      time.Minute,
+	BackoffFactor: 2.0,
+	JitterFactor:  0.2,
+}
+
 // DefaultRetryConfig 默认重试配置
 var DefaultRetryConfig = RetryConfig{
 	MaxRetries:    5,
@@ -60,10 +73,10 @@ func NewRetryNowError(err error) *RetryableError {
 	}
 }
 
-// Retry 重试函数
+// Retry 重试函数，MaxRetries 小于 0 时一直重试直到成功或 ctx 结束
 func Retry(ctx context.Context, fn func() error, config RetryConfig) error {
 	var lastErr error
-	for i := 0; i <= config.MaxRetries; i++ {
+	for i := 0; config.MaxRetries < 0 || i <= config.MaxRetries; i++ {
 		// 执行函数
 		err := fn()
 		if err == nil {
@@ -83,8 +96,11 @@ func Retry(ctx context.Context, fn func() error, config RetryConfig) error {
 			return fmt.Errorf("达到最大重试次数: %v", lastErr)
 		}
 
-		// 计算延迟时间
+		// 计算延迟时间，立即重试的错误不等待
 		delay := calculateDelay(i, config)
+		if IsRetryNowError(err) {
+			delay = 0
+		}
 
 		// 等待一段时间
 		select {
@@ -100,14 +116,35 @@ func Retry(ctx context.Context, fn func() error, config RetryConfig) error {
 
 // IsRetryableError 检查错误是否可重试
 func IsRetryableError(err error) bool {
-	retryErr, ok := err.(*RetryableError)
-	return ok && retryErr.Retry
+	var retryErr *RetryableError
+	return errors.As(err, &retryErr) && retryErr.Retry
 }
 
 // IsRetryNowError 检查错误是否需要立即重试
 func IsRetryNowError(err error) bool {
-	retryErr, ok := err.(*RetryableError)
-	return ok && retryErr.RetryNow
+	var retryErr *RetryableError
+	return errors.As(err, &retryErr) && retryErr.RetryNow
+}
+
+// IsConnectionError 检查 gRPC 错误是否表示与服务端的连接不可用
+func IsConnectionError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRejectedReportError 检查上报消息是否被服务端永久拒绝，例如流水线已删除
+// 认证失败、限流、服务端内部错误和取消等错误可能在稍后恢复，不视为拒绝
+func IsRejectedReportError(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition:
+		return true
+	default:
+		return false
+	}
 }
 
 // WrapError 包装错误
@@ -126,16 +163,17 @@ func WrapRetryNowError(err error) error {
 	return NewRetryNowError(err)
 }
 
+// calculateDelay 计算第 attempt 次重试前的等待时间
+// 按 BackoffFactor 指数增长并限制在 MaxDelay 以内，再叠加 ±JitterFactor 的随机抖动
 func calculateDelay(attempt int, config RetryConfig) time.Duration {
-	delay := config.InitialDelay
-	for i := 0; i < attempt; i++ {
-		delay = time.Duration(float64(delay) * config.BackoffFactor)
-		if delay > config.MaxDelay {
-			delay = config.MaxDelay
-		}
-
-		jitter := time.Duration(float64(delay) * config.JitterFactor)
-		delay += time.Duration(math.Floor(float64(jitter) * (math.Floor(rand.Float64()*2) - 1)))
+	delay := float64(config.InitialDelay)
+	for i := 0; i < attempt && delay < float64(config.MaxDelay); i++ {
+		delay *= config.BackoffFactor
 	}
-	return delay
+	if config.MaxDelay > 0 && delay > float64(config.MaxDelay) {
+		delay = float64(config.MaxDelay)
+	}
+
+	jitter := delay * config.JitterFactor * (rand.Float64()*2 - 1)
+	return time.Duration(math.Max(0, delay+jitter))
 }
@@ -0,0 +1,204 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// 配置多个服务端地址时使用的解析器 scheme
+	serverResolverScheme = "beagle-servers"
+	// 服务端健康检查的超时时间
+	healthCheckTimeout = 5 * time.Second
+)
+
+// serverServiceConfig 每次只连接一个服务端，地址列表打乱顺序使节点分散到各个服务端
+const serverServiceConfig = `{"loadBalancingConfig":[{"pick_first":{"shuffleAddressList":true}}]}`
+
+// serverList Agent 的服务端地址列表
+// 配置多个地址时通过手动解析器提供给 gRPC，由 pick_first 选择一个可连接的服务端，
+// 当前服务端不健康时将其从地址列表中排除，使连接切换到其他服务端。
+// 只配置一个地址时直接作为 gRPC 目标，域名解析出的多个地址同样由 pick_first 选择
+type serverList struct {
+	mu       sync.Mutex
+	servers  []string
+	resolver *manual.Resolver
+	built    bool
+	excluded string
+
+	// dialed 记录连接的远端地址对应的配置地址
+	dialed map[string]string
+}
+
+// newServerList 创建服务端地址列表，忽略空地址和重复地址
+func newServerList(servers []string) (*serverList, error) {
+	l := &serverList{dialed: make(map[string]string)}
+	for _, server := range servers {
+		server = strings.TrimSpace(server)
+		if server != "" && !slices.Contains(l.servers, server) {
+			l.servers = append(l.servers, server)
+		}
+	}
+	if len(l.servers) == 0 {
+		return nil, fmt.Errorf("未配置服务端地址")
+	}
+
+	if len(l.servers) > 1 {
+		l.resolver = manual.NewBuilderWithScheme(serverResolverScheme)
+		l.resolver.BuildCallback = func(resolver.Target, resolver.ClientConn, resolver.BuildOptions) {
+			l.mu.Lock()
+			l.built = true
+			l.mu.Unlock()
+		}
+		l.resolver.InitialState(resolver.State{Addresses: l.addresses("")})
+	}
+	return l, nil
+}
+
+// Servers 获取配置的服务端地址
+func (l *serverList) Servers() []string {
+	return append([]string{}, l.servers...)
+}
+
+// target gRPC 连接目标
+func (l *serverList) target() string {
+	if l.resolver == nil {
+		return l.servers[0]
+	}
+	return serverResolverScheme + ":///servers"
+}
+
+// dialOptions 建立连接时附加的选项
+func (l *serverList) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(serverServiceConfig),
+		grpc.WithContextDialer(l.dial),
+	}
+	if l.resolver != nil {
+		opts = append(opts, grpc.WithResolvers(l.resolver))
+	}
+	return opts
+}
+
+// dial 建立 TCP 连接并记录远端地址对应的配置地址，用于报告当前连接的服务端
+func (l *serverList) dial(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	l.dialed[conn.RemoteAddr().String()] = addr
+	l.mu.Unlock()
+	return conn, nil
+}
+
+// resolve 获取远端地址对应的配置地址
+func (l *serverList) resolve(remote string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if addr, ok := l.dialed[remote]; ok {
+		return addr
+	}
+	return remote
+}
+
+// addresses 构造解析器地址列表，跳过 excluded
+func (l *serverList) addresses(excluded string) []resolver.Address {
+	addrs := make([]resolver.Address, 0, len(l.servers))
+	for _, server := range l.servers {
+		if server != excluded {
+			addrs = append(addrs, resolver.Address{Addr: server})
+		}
+	}
+	return addrs
+}
+
+// exclude 将不健康的服务端从地址列表中排除，返回是否有其他服务端可以切换
+func (l *serverList) exclude(server string) bool {
+	l.mu.Lock()
+	if l.resolver == nil || !l.built || !slices.Contains(l.servers, server) {
+		l.mu.Unlock()
+		return false
+	}
+	l.excluded = server
+	l.mu.Unlock()
+
+	// 不持有 l.mu 更新解析器，避免与 dial 互相等待
+	l.resolver.UpdateState(resolver.State{Addresses: l.addresses(server)})
+	return true
+}
+
+// restore 恢复完整的地址列表
+// pick_first 在当前连接的地址仍在列表中时保持连接，恢复后不会切回被排除的服务端
+func (l *serverList) restore() {
+	l.mu.Lock()
+	if l.excluded == "" {
+		l.mu.Unlock()
+		return
+	}
+	l.excluded = ""
+	l.mu.Unlock()
+
+	l.resolver.UpdateState(resolver.State{Addresses: l.addresses("")})
+}
+
+// checkServer 检查当前连接的服务端是否健康，返回服务端地址
+// 未提供健康检查服务的服务端视为健康
+func (a *Agent) checkServer(ctx context.Context) (string, error) {
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var p peer.Peer
+	resp, err := healthpb.NewHealthClient(a.conn).Check(checkCtx, &healthpb.HealthCheckRequest{}, grpc.Peer(&p))
+	server := ""
+	if p.Addr != nil {
+		server = a.servers.resolve(p.Addr.String())
+	}
+	if status.Code(err) == codes.Unimplemented {
+		return server, nil
+	}
+	if err != nil {
+		return server, fmt.Errorf("服务端健康检查失败: %w", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return server, fmt.Errorf("服务端 %s 不可用: %s", server, resp.Status)
+	}
+	return server, nil
+}
+
+// watchServer 监听当前服务端的健康状态，服务端停止服务时切换到其他服务端
+// 流因连接断开而结束时由 watchConnection 处理
+func (a *Agent) watchServer(ctx context.Context, server string) {
+	stream, err := healthpb.NewHealthClient(a.conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return
+		}
+		if resp.Status == healthpb.HealthCheckResponse_SERVING {
+			continue
+		}
+		if a.servers.exclude(server) {
+			a.logger.Warn("服务端 %s 停止服务，切换到其他服务端", server)
+		}
+		a.MarkDisconnected(fmt.Errorf("服务端 %s 不可用: %s", server, resp.Status))
+		return
+	}
+}
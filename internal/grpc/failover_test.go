@@ -0,0 +1,56 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startHealthServer 启动只提供健康检查服务的 gRPC 服务器
+func startHealthServer(t *testing.T) (string, *health.Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := ggrpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), healthServer
+}
+
+func TestAgentFailover(t *testing.T) {
+	addr1, health1 := startHealthServer(t)
+	addr2, health2 := startHealthServer(t)
+	healthServers := map[string]*health.Server{addr1: health1, addr2: health2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agent, err := NewAgent(ctx, "node-1", []string{addr1, addr2, addr1}, nil, &AgentOptions{})
+	require.NoError(t, err)
+	require.NoError(t, agent.Start(ctx))
+	defer agent.Stop(ctx)
+
+	var first string
+	require.Eventually(t, func() bool {
+		status := agent.ConnectionStatus()
+		first = status.ServerAddr
+		return status.State == ConnectionStateConnected
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.Contains(t, []string{addr1, addr2}, first)
+	assert.Equal(t, []string{addr1, addr2}, agent.ConnectionStatus().Servers)
+
+	// 当前服务端停止服务后切换到另一个服务端
+	healthServers[first].Shutdown()
+	require.Eventually(t, func() bool {
+		status := agent.ConnectionStatus()
+		return status.State == ConnectionStateConnected && status.ServerAddr != first
+	}, 15*time.Second, 50*time.Millisecond)
+	assert.Equal(t, 1, agent.ConnectionStatus().Reconnects)
+}
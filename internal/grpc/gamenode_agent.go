@@ -2,12 +2,17 @@ package grpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+
 	"github.com/open-beagle/beagle-wind-game/internal/models"
 	pb "github.com/open-beagle/beagle-wind-game/internal/proto"
 	"github.com/open-beagle/beagle-wind-game/internal/sysinfo"
@@ -17,55 +22,76 @@ const (
 	defaultRPCTimeout = 10 * time.Second
 )
 
-// GameNodeOptions 配置选项
+// GameNodeOptions 节点注册信息
 type GameNodeOptions struct {
-	HeartbeatPeriod time.Duration
-	MetricsInterval time.Duration
+	// Alias 节点别名，为空时使用节点ID
+	Alias string
+	// Model 节点型号
+	Model string
+	// Type 节点类型，为空时自动检测
+	Type models.GameNodeType
+	// Location 节点位置
+	Location string
+	// Labels 节点标签
+	Labels map[string]string
 }
 
 // GameNodeAgent gRPC客户端实现
 type GameNodeAgent struct {
 	*Agent // 嵌入基础 Agent
 
-	// 节点状态
-	state models.GameNodeStaticState
-
 	// 状态管理
 	mu     sync.RWMutex
 	status *models.GameNodeStatus
 
 	pipelines map[string]*models.GamePipeline
 
+	// 最近一次分配的指标上报序号
+	metricsSeq int64
+
 	// 系统信息采集器
 	hardwareCollector *sysinfo.HardwareCollector
 	systemCollector   *sysinfo.SystemCollector
 	metricsCollector  *sysinfo.MetricsCollector
 
-	// 节点基本信息
-	nodeInfo struct {
-		name      string
-		namespace string
-		nodeType  models.GameNodeType
-		ip        string
-		labels    map[string]string
-	}
+	// 节点注册信息
+	nodeInfo GameNodeOptions
 }
 
 // NewGameNodeAgent 创建新的 GameNode Agent
-func NewGameNodeAgent(base *Agent) (*GameNodeAgent, error) {
+func NewGameNodeAgent(base *Agent, opts *GameNodeOptions) (*GameNodeAgent, error) {
 	agent := &GameNodeAgent{
 		Agent:     base,
 		pipelines: make(map[string]*models.GamePipeline),
 	}
+	if opts != nil {
+		agent.nodeInfo = *opts
+	}
+	if agent.nodeInfo.Alias == "" {
+		agent.nodeInfo.Alias = base.id
+	}
 
 	// 初始化系统信息采集器
 	agent.hardwareCollector = sysinfo.NewHardwareCollector(nil)
 	agent.systemCollector = sysinfo.NewSystemCollector(nil)
 	agent.metricsCollector = sysinfo.NewMetricsCollector(nil)
 
-	// 检测节点类型
-	agent.nodeInfo.nodeType = agent.DetectNodeType()
-	agent.GetLogger().Info("检测到节点类型: %s", agent.nodeInfo.nodeType)
+	// 未配置节点类型时自动检测
+	if agent.nodeInfo.Type == "" {
+		agent.nodeInfo.Type = agent.DetectNodeType()
+		agent.GetLogger().Info("检测到节点类型: %s", agent.nodeInfo.Type)
+	}
+
+	// 首次连接和每次重连后重新注册节点
+	base.OnConnected(agent.Register)
+
+	// 指标上报经过 outbox，连接中断期间的指标降采样后在重连后补发
+	base.HandleReport(OutboxKindMetrics, agent.sendMetrics)
+
+	// 诊断包包含硬件、系统和监控指标的实时采集结果
+	base.AddDiagnostics("sysinfo/hardware.json", agent.collectHardwareDiagnostics)
+	base.AddDiagnostics("sysinfo/system.json", agent.collectSystemDiagnostics)
+	base.AddDiagnostics("sysinfo/metrics.json", agent.collectMetricsDiagnostics)
 
 	return agent, nil
 }
@@ -76,10 +102,10 @@ func (a *GameNodeAgent) Register(ctx context.Context) error {
 
 	// 1. 参数验证
 	if a.id == "" {
-		a.GetLogger().Fatal("节点ID不能为空")
+		return fmt.Errorf("节点ID不能为空")
 	}
-	if a.nodeInfo.nodeType == "" {
-		a.GetLogger().Fatal("节点类型不能为空")
+	if a.nodeInfo.Type == "" {
+		return fmt.Errorf("节点类型不能为空")
 	}
 
 	// 2. 收集硬件和系统信息（带超时控制）
@@ -87,22 +113,22 @@ func (a *GameNodeAgent) Register(ctx context.Context) error {
 	defer cancel()
 
 	if err := a.collectHardwareAndSystemInfo(collectCtx); err != nil {
-		a.GetLogger().Fatal("收集硬件和系统信息失败: %v", err)
+		return fmt.Errorf("收集硬件和系统信息失败: %w", err)
 	}
 
 	// 3. 获取标准化的硬件信息
 	hardware, err := a.hardwareCollector.GetSimplifiedHardwareInfo()
 	if err != nil {
-		a.GetLogger().Fatal("获取标准化硬件信息失败: %v", err)
+		return fmt.Errorf("获取标准化硬件信息失败: %w", err)
 	}
 
 	// 4. 准备注册请求
 	req := &pb.RegisterRequest{
 		Id:       a.id,
-		Alias:    a.id,
-		Model:    "Beagle-Wind-2024",
-		Type:     string(a.nodeInfo.nodeType),
-		Location: "default",
+		Alias:    a.nodeInfo.Alias,
+		Model:    a.nodeInfo.Model,
+		Type:     string(a.nodeInfo.Type),
+		Location: a.nodeInfo.Location,
 		Hardware: hardware,
 		System: map[string]string{
 			"os_distribution":         a.status.System.OSDistribution,
@@ -112,7 +138,8 @@ func (a *GameNodeAgent) Register(ctx context.Context) error {
 			"gpu_driver_version":      a.status.System.GPUDriverVersion,
 			"gpu_compute_api_version": a.status.System.GPUComputeAPIVersion,
 		},
-		Labels: a.nodeInfo.labels,
+		Labels:    a.nodeInfo.Labels,
+		JoinToken: a.JoinToken(),
 	}
 
 	// 5. 验证必要的系统信息
@@ -133,22 +160,39 @@ func (a *GameNodeAgent) Register(ctx context.Context) error {
 	client := a.Agent.GetGameNodeClient()
 	resp, err := client.Register(rpcCtx, req)
 	if err != nil {
-		a.GetLogger().Fatal("注册请求失败: %v", err)
+		// 本地凭证已失效（如节点被拒绝后重新批准），丢弃后下次注册重新申请
+		if status.Code(err) == codes.Unauthenticated && a.Credential() != "" {
+			a.GetLogger().Warn("节点凭证已失效，丢弃本地凭证")
+			a.SetCredential("")
+		}
+		return fmt.Errorf("注册请求失败: %w", err)
 	}
 
 	if !resp.Success {
-		a.GetLogger().Fatal("注册失败: %s", resp.Message)
+		if resp.Approval == string(models.NodeApprovalPending) {
+			a.GetLogger().Warn("节点 %s 等待管理员审批，稍后重试注册", a.id)
+		}
+		return fmt.Errorf("注册失败: %s", resp.Message)
 	}
 
-	// 7. 存储节点维护状态
-	a.state = convertFromProtoStaticState(resp.State)
+	// 7. 保存服务端签发的凭证
+	if resp.Credential != "" {
+		if err := a.SetCredential(resp.Credential); err != nil {
+			return fmt.Errorf("保存节点凭证失败: %w", err)
+		}
+		a.GetLogger().Info("已获得节点凭证")
+	}
+
+	// 8. 同步节点维护状态
+	a.SetNodeState(convertFromProtoStaticState(resp.State))
+	a.GetLogger().Info("节点注册成功: %s", a.id)
 
 	return nil
 }
 
 // GetState 获取节点维护状态
 func (a *GameNodeAgent) GetState() models.GameNodeStaticState {
-	return a.state
+	return a.NodeState()
 }
 
 // SendHeartbeat 发送心跳
@@ -160,26 +204,38 @@ func (a *GameNodeAgent) SendHeartbeat(ctx context.Context) error {
 		Timestamp: time.Now().Unix(),
 	}
 
-	// 发送心跳请求，最多重试3次
-	var lastErr error
-	for i := 0; i < 3; i++ {
-		client := a.Agent.GetGameNodeClient()
-		_, err := client.Heartbeat(ctx, req)
-		if err == nil {
-			return nil
+	// 发送心跳请求，连接断开时由基础 Agent 负责重连
+	rpcCtx, cancel := context.WithTimeout(ctx, defaultRPCTimeout)
+	defer cancel()
+
+	client := a.Agent.GetGameNodeClient()
+	resp, err := client.Heartbeat(rpcCtx, req)
+	if err != nil {
+		if IsConnectionError(err) {
+			a.MarkDisconnected(err)
 		}
-		lastErr = err
-		time.Sleep(time.Second * time.Duration(i+1)) // 指数退避
+		return fmt.Errorf("心跳发送失败: %w", err)
 	}
 
-	a.GetLogger().Error("心跳发送失败: %v", lastErr)
-	return fmt.Errorf("心跳发送失败: %v", lastErr)
+	// 同步节点维护状态，PipelineStream 未连接时状态变更通过心跳送达
+	a.SetNodeState(convertFromProtoStaticState(resp.State))
+	return nil
 }
 
-// ReportMetrics 发送指标报告
+// ReportMetrics 发送指标报告，连接中断时降采样写入 outbox 等待重连后发送
 func (a *GameNodeAgent) ReportMetrics(ctx context.Context) error {
-	// 获取硬件信息
+	// 禁用状态的节点只保持心跳
+	if a.NodeState() == models.GameNodeStaticStateDisabled {
+		return nil
+	}
+
+	a.mu.RLock()
+	if a.status == nil {
+		a.mu.RUnlock()
+		return fmt.Errorf("节点尚未完成硬件信息采集")
+	}
 	hardwareInfo := a.status.Hardware
+	a.mu.RUnlock()
 
 	// 使用 MetricsCollector 收集指标
 	metricsInfo, err := a.metricsCollector.GetMetricsInfo(&hardwareInfo)
@@ -193,38 +249,98 @@ func (a *GameNodeAgent) ReportMetrics(ctx context.Context) error {
 	a.mu.Unlock()
 
 	// 准备请求
+	now := time.Now()
 	req := &pb.MetricsRequest{
 		Id:        a.id,
-		Timestamp: time.Now().Unix(),
+		Timestamp: now.Unix(),
 		Metrics:   convertToProtoMetricsInfo(metricsInfo),
+		Sequence:  a.nextMetricsSequence(now),
 	}
 
-	// 发送请求
-	client := a.Agent.GetGameNodeClient()
-	_, err = client.ReportMetrics(ctx, req)
-	if err != nil {
-		return fmt.Errorf("上报指标失败: %v", err)
+	if err := a.Report(ctx, OutboxKindMetrics, req); err != nil {
+		return fmt.Errorf("上报指标失败: %w", err)
 	}
-
 	return nil
 }
 
+// nextMetricsSequence 分配指标上报序号
+// 序号以纳秒时间戳为基准，Agent 重启后仍然大于之前的序号
+func (a *GameNodeAgent) nextMetricsSequence(now time.Time) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	seq := now.UnixNano()
+	if seq <= a.metricsSeq {
+		seq = a.metricsSeq + 1
+	}
+	a.metricsSeq = seq
+	return seq
+}
+
+// sendMetrics 发送 outbox 中的指标
+func (a *GameNodeAgent) sendMetrics(ctx context.Context, payload []byte) error {
+	req := &pb.MetricsRequest{}
+	if err := protobuf.Unmarshal(payload, req); err != nil {
+		return fmt.Errorf("解析指标失败: %w", err)
+	}
+	_, err := a.GetGameNodeClient().ReportMetrics(ctx, req)
+	return err
+}
+
 // Start 启动代理
 func (a *GameNodeAgent) Start(ctx context.Context) error {
-	// 使用基础 Agent 的 Start 方法
+	// 使用基础 Agent 的 Start 方法，连接建立后由 OnConnected 处理函数完成注册
 	if err := a.Agent.Start(ctx); err != nil {
 		return err
 	}
 
-	// 注册节点
-	if err := a.Register(ctx); err != nil {
-		return fmt.Errorf("register failed: %v", err)
-	}
+	// 启动心跳和指标上报
+	go a.runHeartbeat(ctx)
+	go a.runMetrics(ctx)
 
-	a.GetLogger().Info("GameNodeAgent 已启动并完成注册")
+	a.GetLogger().Info("GameNodeAgent 已启动")
 	return nil
 }
 
+// runHeartbeat 定期发送心跳，未连接时跳过
+func (a *GameNodeAgent) runHeartbeat(ctx context.Context) {
+	period := 5 * time.Second
+	if a.opts != nil && a.opts.HeartbeatPeriod > 0 {
+		period = a.opts.HeartbeatPeriod
+	}
+	a.runPeriodic(ctx, period, "心跳", a.SendHeartbeat, false)
+}
+
+// runMetrics 定期上报指标，未连接时指标写入 outbox
+func (a *GameNodeAgent) runMetrics(ctx context.Context) {
+	period := 30 * time.Second
+	if a.opts != nil && a.opts.MetricsInterval > 0 {
+		period = a.opts.MetricsInterval
+	}
+	a.runPeriodic(ctx, period, "指标上报", a.ReportMetrics, true)
+}
+
+// runPeriodic 周期性执行 fn，whileDisconnected 为 false 时连接中断期间跳过
+func (a *GameNodeAgent) runPeriodic(ctx context.Context, period time.Duration, name string, fn func(context.Context) error, whileDisconnected bool) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopChan:
+			return
+		case <-ticker.C:
+			if !whileDisconnected && !a.IsConnected() {
+				continue
+			}
+			if err := fn(ctx); err != nil {
+				a.GetLogger().Error("%s失败: %v", name, err)
+			}
+		}
+	}
+}
+
 // Stop 停止代理
 func (a *GameNodeAgent) Stop(ctx context.Context) {
 	a.Agent.Stop(ctx)
@@ -286,16 +402,12 @@ func (a *GameNodeAgent) collectHardwareAndSystemInfo(ctx context.Context) error
 		return fmt.Errorf("收集系统信息失败: %v", err)
 	}
 
+	// 更新状态
+	a.mu.Lock()
 	// 确保状态对象已初始化
 	if a.status == nil {
-		a.status = &models.GameNodeStatus{
-			Hardware: hardwareInfo,
-			System:   systemInfo,
-		}
+		a.status = &models.GameNodeStatus{}
 	}
-
-	// 更新状态
-	a.mu.Lock()
 	a.status.Hardware = hardwareInfo
 	a.status.System = systemInfo
 	a.status.UpdatedAt = time.Now()
@@ -490,3 +602,34 @@ func convertToProtoNetworkDevices(devices []models.NetworkDevice) []*pb.NetworkD
 	}
 	return result
 }
+
+// collectHardwareDiagnostics 采集硬件信息用于诊断包
+func (a *GameNodeAgent) collectHardwareDiagnostics(ctx context.Context) ([]byte, error) {
+	hardwareInfo, err := a.hardwareCollector.GetHardwareInfo()
+	if err != nil {
+		return nil, fmt.Errorf("获取硬件信息失败: %w", err)
+	}
+	return json.MarshalIndent(hardwareInfo, "", "  ")
+}
+
+// collectSystemDiagnostics 采集系统信息用于诊断包
+func (a *GameNodeAgent) collectSystemDiagnostics(ctx context.Context) ([]byte, error) {
+	systemInfo, err := a.systemCollector.GetSystemInfo()
+	if err != nil {
+		return nil, fmt.Errorf("获取系统信息失败: %w", err)
+	}
+	return json.MarshalIndent(systemInfo, "", "  ")
+}
+
+// collectMetricsDiagnostics 采集监控指标用于诊断包
+func (a *GameNodeAgent) collectMetricsDiagnostics(ctx context.Context) ([]byte, error) {
+	hardwareInfo, err := a.hardwareCollector.GetHardwareInfo()
+	if err != nil {
+		return nil, fmt.Errorf("获取硬件信息失败: %w", err)
+	}
+	metricsInfo, err := a.metricsCollector.GetMetricsInfo(&hardwareInfo)
+	if err != nil {
+		return nil, fmt.Errorf("获取监控指标失败: %w", err)
+	}
+	return json.MarshalIndent(metricsInfo, "", "  ")
+}
@@ -2,29 +2,21 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net"
 	"strings"
-	"sync"
 	"time"
 
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/open-beagle/beagle-wind-game/internal/models"
 	pb "github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
-// GameNodeServerOptions 包含服务器配置选项
-type GameNodeServerOptions struct {
-	ListenAddr  string
-	TLSCertFile string
-	TLSKeyFile  string
-}
-
 // GameNodeServer 游戏节点服务器
 type GameNodeServer struct {
 	pb.UnimplementedGameNodeGRPCServiceServer
@@ -39,13 +31,8 @@ type GameNodeServer struct {
 	// 配置
 	config *ServerConfig
 
-	// 连接管理
-	mu          sync.RWMutex
-	connections map[string]*AgentConnection
-
-	// 服务器
-	server *grpc.Server
-	done   chan struct{}
+	// 节点连接注册表，记录心跳
+	connections *ConnectionRegistry
 }
 
 // GameNodeServiceInterface 节点服务接口
@@ -58,10 +45,18 @@ type GameNodeServiceInterface interface {
 	Get(ctx context.Context, id string) (models.GameNode, error)
 	// 更新节点在线状态
 	UpdateStatusOnlineStatus(ctx context.Context, id string, online bool) error
-	// 更新节点指标
-	UpdateStatusMetrics(ctx context.Context, id string, metrics models.MetricsInfo) error
+	// 应用节点上报的指标，序号过期时忽略
+	ApplyMetricsReport(ctx context.Context, id string, sequence int64, metrics models.MetricsInfo) (bool, error)
 	// 更新节点硬件和系统信息
 	UpdateHardwareAndSystem(ctx context.Context, id string, hardware models.HardwareInfo, system models.SystemInfo) error
+	// 更新节点容器清单
+	UpdateStatusContainers(ctx context.Context, id string, containers []models.ContainerInfo) error
+	// 节点注册准入检查，签发新凭证时返回凭证明文
+	Admit(ctx context.Context, node *models.GameNode, joinToken, credential string) (string, error)
+	// 校验节点凭证
+	VerifyCredential(ctx context.Context, id string, credential string) error
+	// 变更节点维护状态
+	UpdateState(ctx context.Context, id string, state models.GameNodeStaticState) (models.GameNode, error)
 }
 
 // GamePipelineServiceInterface Pipeline服务接口
@@ -78,24 +73,33 @@ type GamePipelineServiceInterface interface {
 	UpdateStepStatus(ctx context.Context, pipelineID string, stepID string, status *models.StepStatus) error
 	// 取消Pipeline
 	Cancel(ctx context.Context, id string) error
-}
-
-// AgentConnection Agent连接
-type AgentConnection struct {
-	nodeID     string
-	lastActive time.Time
+	// 应用节点上报的Pipeline状态
+	ApplyStatusReport(ctx context.Context, id string, nodeID string, sequence int64, status *models.PipelineStatus) (bool, error)
+	// 应用节点上报的Step状态
+	ApplyStepReport(ctx context.Context, id string, nodeID string, stepID string, sequence int64, status *models.StepStatus) (bool, error)
+	// 下发节点上排队的Pipeline
+	DispatchQueued(ctx context.Context, nodeID string) error
+	// 记录节点对下发Pipeline的确认
+	AckDispatch(ctx context.Context, id string, nodeID string, accepted bool, message string) error
+	// 追加节点上传的Step日志
+	AppendStepLog(ctx context.Context, nodeID, pipelineID, stepID string, offset int64, data []byte, eof bool) (int64, error)
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	MaxConnections  int
 	HeartbeatPeriod time.Duration
+	// OfflineGracePeriod 节点超过该时间未发送任何消息时标记为离线，为 0 时使用默认值
+	OfflineGracePeriod time.Duration
+	// TLS 双向 TLS 配置，未配置时使用明文连接
+	TLS TLSConfig
 }
 
 // NewGameNodeServer 创建新的游戏节点服务器
 func NewGameNodeServer(
 	nodeService GameNodeServiceInterface,
 	pipelineService GamePipelineServiceInterface,
+	connections *ConnectionRegistry,
 	logger utils.Logger,
 	config *ServerConfig,
 ) *GameNodeServer {
@@ -103,10 +107,8 @@ func NewGameNodeServer(
 		nodeService:     nodeService,
 		pipelineService: pipelineService,
 		logger:          logger,
-		connections:     make(map[string]*AgentConnection),
+		connections:     connections,
 		config:          config,
-		server:          grpc.NewServer(),
-		done:            make(chan struct{}),
 	}
 }
 
@@ -156,65 +158,116 @@ func (s *GameNodeServer) Register(ctx context.Context, req *pb.RegisterRequest)
 		}, nil
 	}
 
-	// 5. 构建或更新节点信息
-	node := models.GameNode{
-		ID:       req.Id,
-		Alias:    req.Alias,
-		Model:    req.Model,
-		Type:     models.GameNodeType(req.Type),
-		Location: req.Location,
-		Labels:   req.Labels,
-		State:    nodeState,
-		Hardware: req.Hardware,
-		System:   req.System,
-		Status: models.GameNodeStatus{
-			State:      models.GameNodeStateOnline,
-			Online:     true,
-			LastOnline: time.Now(),
-			UpdatedAt:  time.Now(),
-			Hardware:   models.HardwareInfo{},
-			System:     models.SystemInfo{},
-			Metrics:    models.MetricsInfo{},
-		},
-	}
-
-	// 6. 创建或更新节点
-	if existingNode.ID == "" {
-		// 新节点
-		node.CreatedAt = time.Now()
-		node.UpdatedAt = time.Now()
-		if err := s.nodeService.Create(ctx, node); err != nil {
-			s.logger.Error("创建节点失败: %v", err)
-			return &pb.RegisterResponse{
-				Success: false,
-				Message: "创建节点失败",
-			}, nil
+	// 5. 构建节点信息，已有节点沿用存储中的信息，注册元数据以 Agent 配置为准
+	node := existingNode
+	if node.ID == "" {
+		node = models.GameNode{
+			ID:       req.Id,
+			Alias:    req.Alias,
+			Model:    req.Model,
+			Type:     models.GameNodeType(req.Type),
+			Location: req.Location,
+			Labels:   req.Labels,
+			State:    nodeState,
+			Hardware: req.Hardware,
+			System:   req.System,
 		}
-		s.logger.Info("节点注册成功-新节点: %s", node.ID)
 	} else {
-		// 更新已有节点：只更新状态信息
-		existingNode.Status.State = models.GameNodeStateOnline
-		existingNode.Status.Online = true
-		existingNode.Status.LastOnline = time.Now()
+		applyRegisterMetadata(&node, req)
+	}
 
-		if err := s.nodeService.Update(ctx, existingNode); err != nil {
-			s.logger.Error("更新节点状态失败: %v", err)
+	// 6. 准入检查：凭证、加入令牌或管理员审批
+	credential, err := s.nodeService.Admit(ctx, &node, req.JoinToken, nodeCredentialFromContext(ctx))
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrNodePendingApproval):
+		// 等待审批的节点也需要保存，以便管理员在 REST API 中看到
+		if saveErr := s.saveRegisteredNode(ctx, node, existingNode.ID == ""); saveErr != nil {
 			return &pb.RegisterResponse{
 				Success: false,
-				Message: "更新节点状态失败",
+				Message: saveErr.Error(),
 			}, nil
 		}
-		s.logger.Info("节点注册成功-已知节点: %s", existingNode.ID)
+		s.logger.Info("节点 %s 等待管理员审批", node.ID)
+		return &pb.RegisterResponse{
+			Success:  false,
+			Message:  err.Error(),
+			State:    convertToProtoStaticState(nodeState),
+			Approval: string(models.NodeApprovalPending),
+		}, nil
+	case errors.Is(err, service.ErrNodeRejected):
+		s.logger.Warn("拒绝已被拒绝的节点注册: %s", node.ID)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrInvalidJoinToken), errors.Is(err, service.ErrInvalidCredential):
+		s.logger.Warn("节点 %s 注册认证失败: %v", node.ID, err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	default:
+		s.logger.Error("节点 %s 准入检查失败: %v", node.ID, err)
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	// 7. 返回注册响应
+	// 7. 保存节点并标记在线
+	if err := s.saveRegisteredNode(ctx, node, existingNode.ID == ""); err != nil {
+		return &pb.RegisterResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+	if err := s.nodeService.UpdateStatusOnlineStatus(ctx, node.ID, true); err != nil {
+		s.logger.Error("更新节点在线状态失败: %v", err)
+		return &pb.RegisterResponse{
+			Success: false,
+			Message: "更新节点状态失败",
+		}, nil
+	}
+	s.connections.Touch(ctx, node.ID)
+	s.logger.Info("节点注册成功: %s", node.ID)
+
+	// 8. 返回注册响应
 	return &pb.RegisterResponse{
-		Success: true,
-		Message: "",
-		State:   convertToProtoStaticState(nodeState),
+		Success:    true,
+		Message:    "",
+		State:      convertToProtoStaticState(nodeState),
+		Credential: credential,
+		Approval:   string(node.Approval),
 	}, nil
 }
 
+// applyRegisterMetadata 使用注册请求中的别名、型号、类型、位置和标签更新已有节点，请求中为空的字段保持不变
+func applyRegisterMetadata(node *models.GameNode, req *pb.RegisterRequest) {
+	if req.Alias != "" {
+		node.Alias = req.Alias
+	}
+	if req.Model != "" {
+		node.Model = req.Model
+	}
+	if req.Type != "" {
+		node.Type = models.GameNodeType(req.Type)
+	}
+	if req.Location != "" {
+		node.Location = req.Location
+	}
+	if req.Labels != nil {
+		node.Labels = req.Labels
+	}
+}
+
+// saveRegisteredNode 保存注册的节点，包括审批状态和凭证摘要
+func (s *GameNodeServer) saveRegisteredNode(ctx context.Context, node models.GameNode, isNew bool) error {
+	if isNew {
+		if err := s.nodeService.Create(ctx, node); err != nil {
+			s.logger.Error("创建节点失败: %v", err)
+			return fmt.Errorf("创建节点失败")
+		}
+		return nil
+	}
+	if err := s.nodeService.Update(ctx, node); err != nil {
+		s.logger.Error("更新节点失败: %v", err)
+		return fmt.Errorf("更新节点失败")
+	}
+	return nil
+}
+
 // convertToProtoStaticState 将 models.GameNodeStaticState 转换为 proto.GameNodeStaticState
 func convertToProtoStaticState(state models.GameNodeStaticState) pb.GameNodeStaticState {
 	switch state {
@@ -231,6 +284,8 @@ func convertToProtoStaticState(state models.GameNodeStaticState) pb.GameNodeStat
 
 // Heartbeat 心跳检测
 func (s *GameNodeServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	s.connections.Heartbeat(ctx, req.Id)
+
 	// 更新节点在线状态
 	err := s.nodeService.UpdateStatusOnlineStatus(ctx, req.Id, true)
 	if err != nil {
@@ -240,9 +295,17 @@ func (s *GameNodeServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest
 
 	s.logger.Debug("收到节点心跳: %s", req.Id)
 
+	// 心跳响应携带节点维护状态，节点据此同步状态
+	node, err := s.nodeService.Get(ctx, req.Id)
+	if err != nil {
+		s.logger.Error("获取节点失败: %v", err)
+		return nil, status.Error(codes.NotFound, "节点不存在")
+	}
+
 	return &pb.HeartbeatResponse{
 		Status:  "success",
 		Message: "心跳接收成功",
+		State:   convertToProtoStaticState(node.State.Effective()),
 	}, nil
 }
 
@@ -316,14 +379,16 @@ func (s *GameNodeServer) ReportMetrics(ctx context.Context, req *pb.MetricsReque
 		Connections:     req.Metrics.Network.Connections,
 	}
 
-	// 更新节点状态
-	err = s.nodeService.UpdateStatusMetrics(ctx, req.Id, node.Status.Metrics)
+	// 更新节点状态，重放的过期指标被忽略
+	applied, err := s.nodeService.ApplyMetricsReport(ctx, req.Id, req.Sequence, node.Status.Metrics)
 	if err != nil {
 		s.logger.Error("更新节点状态失败", "node_id", req.Id, "error", err)
 		return nil, status.Error(codes.Internal, "更新节点状态失败")
 	}
 
-	s.logger.Debug("节点指标更新成功: %s", req.Id)
+	if applied {
+		s.logger.Debug("节点指标更新成功: %s", req.Id)
+	}
 
 	return &pb.MetricsResponse{
 		Success: true,
@@ -433,34 +498,87 @@ func (s *GameNodeServer) ReportResource(ctx context.Context, req *pb.ResourceReq
 	}, nil
 }
 
-// UpdateNodeState 处理节点状态变更请求
-func (s *GameNodeServer) UpdateNodeState(ctx context.Context, req *pb.StateChangeRequest) (*pb.StateChangeResponse, error) {
-	// 获取节点
-	node, err := s.nodeService.Get(ctx, req.NodeId)
-	if err != nil {
-		s.logger.Error("获取节点失败: %v", err)
-		return nil, status.Error(codes.NotFound, "节点不存在")
+// ReportContainers 处理容器清单上报请求
+func (s *GameNodeServer) ReportContainers(ctx context.Context, req *pb.ContainersRequest) (*pb.ContainersResponse, error) {
+	if req.NodeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "节点ID不能为空")
+	}
+
+	containers := make([]models.ContainerInfo, 0, len(req.Containers))
+	for _, c := range req.Containers {
+		info := models.ContainerInfo{
+			ID:         c.Id,
+			Name:       c.Name,
+			Image:      c.Image,
+			State:      c.State,
+			PipelineID: c.PipelineId,
+			InstanceID: c.InstanceId,
+		}
+		if c.CreatedAt != nil {
+			info.CreatedAt = c.CreatedAt.AsTime()
+		}
+		if c.StartedAt != nil {
+			info.StartedAt = timePtr(c.StartedAt.AsTime())
+		}
+		for _, p := range c.Ports {
+			info.Ports = append(info.Ports, models.ContainerPort{
+				PrivatePort: uint16(p.PrivatePort),
+				PublicPort:  uint16(p.PublicPort),
+				Protocol:    p.Protocol,
+				IP:          p.Ip,
+			})
+		}
+		if a := c.Activity; a != nil {
+			info.Activity = &models.ContainerActivity{
+				Connections: int(a.Connections),
+				CPUPercent:  a.CpuPercent,
+				GPUPercent:  a.GpuPercent,
+				InputIdle:   time.Duration(a.InputIdleSeconds) * time.Second,
+			}
+			if a.SampledAt != nil {
+				info.Activity.SampledAt = a.SampledAt.AsTime()
+			}
+		}
+		containers = append(containers, info)
+	}
+
+	if err := s.nodeService.UpdateStatusContainers(ctx, req.NodeId, containers); err != nil {
+		s.logger.Error("更新节点容器清单失败: %v", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to update containers: %v", err))
 	}
 
-	// 将 proto 的 GameNodeStaticState 转换为 models.GameNodeState
-	var targetState models.GameNodeState
+	s.logger.Debug("节点容器清单更新成功: %s, 容器数: %d", req.NodeId, len(containers))
+
+	return &pb.ContainersResponse{
+		Success: true,
+		Message: "Containers updated successfully",
+	}, nil
+}
+
+// UpdateNodeState 处理节点状态变更请求
+func (s *GameNodeServer) UpdateNodeState(ctx context.Context, req *pb.StateChangeRequest) (*pb.StateChangeResponse, error) {
+	var targetState models.GameNodeStaticState
 	switch req.TargetState {
 	case pb.GameNodeStaticState_NODE_STATE_NORMAL:
-		targetState = models.GameNodeStateOnline
+		targetState = models.GameNodeStaticStateNormal
 	case pb.GameNodeStaticState_NODE_STATE_MAINTENANCE:
-		targetState = models.GameNodeStateMaintenance
+		targetState = models.GameNodeStaticStateMaintenance
 	case pb.GameNodeStaticState_NODE_STATE_DISABLED:
-		targetState = models.GameNodeStateOffline
+		targetState = models.GameNodeStaticStateDisabled
 	default:
 		s.logger.Error("无效的目标状态: %v", req.TargetState)
 		return nil, status.Error(codes.InvalidArgument, "无效的目标状态")
 	}
 
-	// 更新节点状态
-	node.Status.State = targetState
-	err = s.nodeService.Update(ctx, node)
-	if err != nil {
+	// 通过节点服务的状态机变更维护状态
+	if _, err := s.nodeService.UpdateState(ctx, req.NodeId, targetState); err != nil {
 		s.logger.Error("更新节点状态失败: %v", err)
+		if errors.Is(err, service.ErrNodeStateTransition) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		if strings.HasPrefix(err.Error(), "节点不存在") {
+			return nil, status.Error(codes.NotFound, "节点不存在")
+		}
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to update node state: %v", err))
 	}
 
@@ -472,95 +590,3 @@ func (s *GameNodeServer) UpdateNodeState(ctx context.Context, req *pb.StateChang
 		ConfirmTime:  timestamppb.Now(),
 	}, nil
 }
-
-// Stop 停止服务器
-func (s *GameNodeServer) Stop() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	close(s.done)
-	s.server.GracefulStop()
-
-	return nil
-}
-
-// Start 启动服务器
-func (s *GameNodeServer) Start(ctx context.Context, opts *GameNodeServerOptions) error {
-	// 创建 gRPC 服务器
-	pb.RegisterGameNodeGRPCServiceServer(s.server, s)
-
-	// 创建监听器
-	listener, err := net.Listen("tcp", opts.ListenAddr)
-	if err != nil {
-		s.logger.Error("监听地址失败: %v", err)
-		return fmt.Errorf("failed to listen: %v", err)
-	}
-
-	s.logger.Info("服务器开始监听地址: %s", opts.ListenAddr)
-
-	// 启动心跳检查
-	go s.startHeartbeatCheck(ctx)
-
-	// 启动服务器
-	go func() {
-		if err := s.server.Serve(listener); err != nil {
-			s.logger.Error("gRPC服务器运行失败: %v", err)
-		}
-	}()
-
-	// 等待上下文取消
-	<-ctx.Done()
-	s.logger.Info("收到停止信号，正在关闭服务器")
-
-	// 优雅关闭
-	s.server.GracefulStop()
-	return nil
-}
-
-// startHeartbeatCheck 启动心跳检查
-func (s *GameNodeServer) startHeartbeatCheck(ctx context.Context) {
-	ticker := time.NewTicker(s.config.HeartbeatPeriod)
-	defer ticker.Stop()
-
-	s.logger.Info("心跳检查服务启动，周期: %v", s.config.HeartbeatPeriod)
-
-	for {
-		select {
-		case <-ctx.Done():
-			s.logger.Info("心跳检查服务停止")
-			return
-		case <-ticker.C:
-			s.checkHeartbeats(ctx)
-		}
-	}
-}
-
-// checkHeartbeats 检查心跳
-func (s *GameNodeServer) checkHeartbeats(ctx context.Context) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	now := time.Now()
-	for nodeID := range s.connections {
-		// 获取节点状态
-		node, err := s.nodeService.Get(ctx, nodeID)
-		if err != nil {
-			s.logger.Error("获取节点状态失败: %v", err)
-			continue
-		}
-
-		// 检查最后在线时间
-		if now.Sub(node.Status.LastOnline) > s.config.HeartbeatPeriod*2 {
-			// 更新节点状态为离线
-			if err := s.nodeService.UpdateStatusOnlineStatus(ctx, nodeID, false); err != nil {
-				s.logger.Error("更新节点状态失败: %v", err)
-				continue
-			}
-
-			s.logger.Warn("节点心跳超时，标记为离线: %s", nodeID)
-
-			// 从连接池中移除
-			delete(s.connections, nodeID)
-		}
-	}
-}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
+	protobuf "google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/open-beagle/beagle-wind-game/internal/models"
@@ -15,13 +17,26 @@ import (
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
+const (
+	// streamHeartbeatInterval PipelineStream 心跳间隔，需小于服务端的会话超时时间
+	streamHeartbeatInterval = 10 * time.Second
+	// defaultContainersInterval 默认的容器清单上报周期
+	defaultContainersInterval = 60 * time.Second
+	// containersReportDelay 托管容器变化后延迟上报的时间，合并短时间内的多个事件
+	containersReportDelay = 2 * time.Second
+)
+
 // GamePipelineAgent 表示 Game Pipeline Agent
 type GamePipelineAgent struct {
-	agent   *Agent
-	logger  utils.Logger
-	sources map[string]*models.GamePipeline
-	mu      sync.RWMutex
-	engine  *pl.Engine
+	agent     *Agent
+	logger    utils.Logger
+	sources   map[string]*models.GamePipeline
+	sequences map[string]int64 // 每个 Pipeline 最近一次分配的上报序号
+	mu        sync.RWMutex
+	engine    *pl.Engine
+	ctx       context.Context // Agent 运行上下文，用于步骤日志上传
+	// completed 已结束的 Pipeline，服务端重复下发时不再执行
+	completed *CompletedPipelines
 }
 
 // NewGamePipelineAgent 创建一个新的 Pipeline Agent
@@ -32,12 +47,39 @@ func NewGamePipelineAgent(agent *Agent) (*GamePipelineAgent, error) {
 		return nil, fmt.Errorf("创建 Pipeline 执行引擎失败: %w", err)
 	}
 
-	return &GamePipelineAgent{
-		agent:   agent,
-		logger:  agent.GetLogger(),
-		sources: make(map[string]*models.GamePipeline),
-		engine:  engine,
-	}, nil
+	// 加载已结束的 Pipeline 记录，与 outbox 保存在同一目录
+	completed, err := NewCompletedPipelines(completedPipelinesPath(agent.opts.OutboxFile), agent.GetLogger())
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineAgent := &GamePipelineAgent{
+		agent:     agent,
+		logger:    agent.GetLogger(),
+		sources:   make(map[string]*models.GamePipeline),
+		sequences: make(map[string]int64),
+		engine:    engine,
+		ctx:       context.Background(),
+		completed: completed,
+	}
+
+	// 注册事件处理器，所有 Pipeline 共用
+	engine.RegisterHandler(pipelineAgent.handleEngineEvent)
+
+	// 容器步骤的日志上传到服务端
+	engine.SetLogSink(pipelineAgent.openStepLog)
+
+	// 状态上报经过 outbox，连接中断期间的状态在重连后按顺序补发
+	agent.HandleReport(OutboxKindPipelineStatus, pipelineAgent.sendPipelineStatus)
+	agent.HandleReport(OutboxKindStepStatus, pipelineAgent.sendStepStatus)
+
+	// 首次连接和每次重连后上报容器清单，需在节点注册之后执行
+	agent.OnConnected(pipelineAgent.ReportContainers)
+
+	// 节点被禁用时终止正在执行的 Pipeline
+	agent.OnNodeStateChange(pipelineAgent.handleNodeState)
+
+	return pipelineAgent, nil
 }
 
 // GetSourceCount 获取当前 Pipeline 数量
@@ -47,9 +89,129 @@ func (a *GamePipelineAgent) GetSourceCount() int {
 	return len(a.sources)
 }
 
+// GetSourceIDs 获取正在执行的 Pipeline ID 列表
+func (a *GamePipelineAgent) GetSourceIDs() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	ids := make([]string, 0, len(a.sources))
+	for id := range a.sources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ReportContainers 上报引擎创建的容器清单
+func (a *GamePipelineAgent) ReportContainers(ctx context.Context) error {
+	containers, err := a.engine.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+	a.engine.SampleActivity(ctx, containers)
+
+	req := &proto.ContainersRequest{
+		NodeId:     a.agent.id,
+		Timestamp:  time.Now().Unix(),
+		Containers: make([]*proto.ContainerInfo, 0, len(containers)),
+	}
+	for _, c := range containers {
+		info := &proto.ContainerInfo{
+			Id:         c.ID,
+			Name:       c.Name,
+			Image:      c.Image,
+			State:      c.State,
+			PipelineId: c.PipelineID,
+			InstanceId: c.InstanceID,
+			CreatedAt:  timestamppb.New(c.CreatedAt),
+		}
+		if c.StartedAt != nil {
+			info.StartedAt = timestamppb.New(*c.StartedAt)
+		}
+		for _, p := range c.Ports {
+			info.Ports = append(info.Ports, &proto.ContainerPort{
+				PrivatePort: uint32(p.PrivatePort),
+				PublicPort:  uint32(p.PublicPort),
+				Protocol:    p.Protocol,
+				Ip:          p.IP,
+			})
+		}
+		if c.Activity != nil {
+			info.Activity = &proto.ContainerActivity{
+				SampledAt:        timestamppb.New(c.Activity.SampledAt),
+				Connections:      int32(c.Activity.Connections),
+				CpuPercent:       c.Activity.CPUPercent,
+				GpuPercent:       c.Activity.GPUPercent,
+				InputIdleSeconds: int64(c.Activity.InputIdle / time.Second),
+			}
+			if c.Activity.InputIdle < 0 {
+				info.Activity.InputIdleSeconds = -1
+			}
+		}
+		req.Containers = append(req.Containers, info)
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, defaultRPCTimeout)
+	defer cancel()
+	if _, err := a.agent.GetGameNodeClient().ReportContainers(rpcCtx, req); err != nil {
+		return fmt.Errorf("上报容器清单失败: %w", err)
+	}
+	a.logger.Debug("已上报容器清单, 容器数: %d", len(containers))
+	return nil
+}
+
+// runContainersReport 定期上报容器清单，托管容器变化时延迟 containersReportDelay 后上报，未连接时跳过
+func (a *GamePipelineAgent) runContainersReport(ctx context.Context) {
+	period := defaultContainersInterval
+	if a.agent.opts != nil && a.agent.opts.ContainersInterval > 0 {
+		period = a.agent.opts.ContainersInterval
+	}
+
+	changed := make(chan struct{}, 1)
+	go a.engine.WatchContainers(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	delay := time.NewTimer(containersReportDelay)
+	delay.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			delay.Reset(containersReportDelay)
+			continue
+		case <-ticker.C:
+		case <-delay.C:
+		}
+		if !a.agent.IsConnected() {
+			continue
+		}
+		if err := a.ReportContainers(ctx); err != nil {
+			a.logger.Warn("%v", err)
+		}
+	}
+}
+
+// openStepLog 打开步骤日志上传器，步骤ID与状态上报一致使用步骤名称
+func (a *GamePipelineAgent) openStepLog(pipeline *models.GamePipeline, step *models.PipelineStep) io.WriteCloser {
+	a.mu.RLock()
+	ctx := a.ctx
+	a.mu.RUnlock()
+	return newStepLogUploader(ctx, a.agent, pipeline.ID, step.Name)
+}
+
 // Start 启动 Pipeline Agent
 func (a *GamePipelineAgent) Start(ctx context.Context) error {
 	a.logger.Info("启动 Pipeline Agent...")
+	a.mu.Lock()
+	a.ctx = ctx
+	a.mu.Unlock()
 
 	// 启动执行引擎
 	if err := a.engine.Start(ctx); err != nil {
@@ -59,6 +221,12 @@ func (a *GamePipelineAgent) Start(ctx context.Context) error {
 	// 启动 Pipeline 流
 	go a.runPipelineStream(ctx)
 
+	// 定期和托管容器变化时上报容器清单
+	go a.runContainersReport(ctx)
+
+	// 监听输入设备，上报容器清单时附带最近一次输入的时间
+	go a.engine.WatchInput(ctx)
+
 	return nil
 }
 
@@ -67,43 +235,84 @@ func (a *GamePipelineAgent) Stop(ctx context.Context) {
 	a.logger.Info("停止 Pipeline Agent...")
 }
 
-// runPipelineStream 运行 Pipeline 流
+// runPipelineStream 运行 Pipeline 流，流断开后按退避策略重建
+// 新建的流首先发送携带正在执行的 Pipeline 列表的心跳，服务端据此恢复会话
 func (a *GamePipelineAgent) runPipelineStream(ctx context.Context) {
+	attempt := 0
 	for {
+		started := time.Now()
+		err := a.handlePipelineStream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 流持续过一段时间说明连接曾经正常，重新开始退避计数
+		if time.Since(started) > streamHeartbeatInterval {
+			attempt = 0
+		}
+		if err != nil {
+			a.logger.Error("Pipeline 流处理失败: %v", err)
+			if IsConnectionError(err) {
+				a.agent.MarkDisconnected(err)
+			}
+		}
+
+		delay := calculateDelay(attempt, ReconnectRetryConfig)
+		attempt++
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			if err := a.handlePipelineStream(ctx); err != nil {
-				a.logger.Error("Pipeline 流处理失败: %v", err)
-				time.Sleep(5 * time.Second) // 重试延迟
-				continue
-			}
+		case <-time.After(delay):
 		}
 	}
 }
 
 // handlePipelineStream 处理 Pipeline 流
+// 接收循环处理服务端下发的消息，心跳由独立协程定期发送，任意一方出错时结束本次流
 func (a *GamePipelineAgent) handlePipelineStream(ctx context.Context) error {
 	client := a.agent.GetPipelineClient()
 
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// 创建流
-	stream, err := client.PipelineStream(ctx)
+	stream, err := client.PipelineStream(streamCtx)
 	if err != nil {
 		return err
 	}
 
-	// 发送初始心跳
-	req := &proto.PipelineStreamRequest{
-		Heartbeat: &proto.Heartbeat{
-			NodeId:    a.agent.id,
-			Timestamp: timestamppb.Now(),
-		},
+	// gRPC 流不允许并发 Send，所有发送都经过 send
+	var sendMu sync.Mutex
+	send := func(req *proto.PipelineStreamRequest) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(req)
 	}
-	if err := stream.Send(req); err != nil {
+
+	// 发送初始心跳，服务端据此注册会话
+	if err := send(a.heartbeatRequest()); err != nil {
 		return err
 	}
 
+	// 定期发送心跳
+	heartbeatErr := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(streamHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-streamCtx.Done():
+				return
+			case <-ticker.C:
+				if err := send(a.heartbeatRequest()); err != nil {
+					heartbeatErr <- err
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
 	// 处理响应
 	for {
 		resp, err := stream.Recv()
@@ -111,6 +320,11 @@ func (a *GamePipelineAgent) handlePipelineStream(ctx context.Context) error {
 			return nil
 		}
 		if err != nil {
+			select {
+			case hbErr := <-heartbeatErr:
+				return fmt.Errorf("发送心跳失败: %w", hbErr)
+			default:
+			}
 			return err
 		}
 
@@ -120,17 +334,111 @@ func (a *GamePipelineAgent) handlePipelineStream(ctx context.Context) error {
 			// 处理心跳确认
 			a.logger.Debug("收到心跳确认")
 		case resp.GetPipeline() != nil:
-			// 处理 Pipeline 任务
-			if err := a.handlePipeline(ctx, resp.GetPipeline()); err != nil {
-				a.logger.Error("处理 Pipeline 任务失败: %v", err)
+			// 处理 Pipeline 任务并确认
+			ack := a.acceptPipeline(ctx, resp.GetPipeline())
+			if err := send(&proto.PipelineStreamRequest{Ack: ack}); err != nil {
+				return err
 			}
 		case resp.GetCancel() != nil:
 			// 处理取消命令
-			a.logger.Info("收到取消命令: %s", resp.GetCancel().Reason)
+			a.handleCancel(resp.GetCancel())
+		case resp.GetNodeState() != nil:
+			// 服务端变更了节点维护状态
+			a.agent.SetNodeState(models.GameNodeStaticState(resp.GetNodeState().State))
+		case resp.GetRemoveContainer() != nil:
+			// 删除服务端核对出的孤立容器，容器变化事件会触发重新上报清单
+			go a.handleRemoveContainer(ctx, resp.GetRemoveContainer())
+		case resp.GetDiagnostics() != nil:
+			// 收集和上传诊断包耗时较长，不阻塞流的接收
+			go a.agent.RunDiagnostics(ctx, resp.GetDiagnostics().RequestId)
 		}
 	}
 }
 
+// heartbeatRequest 构造携带正在运行的 Pipeline 列表的心跳
+func (a *GamePipelineAgent) heartbeatRequest() *proto.PipelineStreamRequest {
+	a.mu.RLock()
+	ids := make([]string, 0, len(a.sources))
+	for id := range a.sources {
+		ids = append(ids, id)
+	}
+	a.mu.RUnlock()
+
+	return &proto.PipelineStreamRequest{
+		Heartbeat: &proto.Heartbeat{
+			NodeId:      a.agent.id,
+			Timestamp:   timestamppb.Now(),
+			PipelineIds: ids,
+		},
+	}
+}
+
+// handleCancel 处理服务端的取消命令
+func (a *GamePipelineAgent) handleCancel(cmd *proto.CancelCommand) {
+	a.logger.Info("收到取消命令: %s, 原因: %s", cmd.PipelineId, cmd.Reason)
+	if cmd.PipelineId == "" {
+		return
+	}
+	if err := a.engine.CancelPipeline(cmd.PipelineId, cmd.Reason); err != nil {
+		a.logger.Warn("取消 Pipeline %s 失败: %v", cmd.PipelineId, err)
+	}
+}
+
+// handleRemoveContainer 处理服务端的删除容器命令
+func (a *GamePipelineAgent) handleRemoveContainer(ctx context.Context, cmd *proto.RemoveContainerCommand) {
+	a.logger.Info("收到删除容器命令: %s, 原因: %s", cmd.ContainerId, cmd.Reason)
+	if err := a.engine.RemoveContainer(ctx, cmd.ContainerId); err != nil {
+		a.logger.Warn("删除容器 %s 失败: %v", cmd.ContainerId, err)
+	}
+}
+
+// handleNodeState 处理节点维护状态变更，禁用时取消所有正在执行的 Pipeline
+func (a *GamePipelineAgent) handleNodeState(state models.GameNodeStaticState) {
+	if state != models.GameNodeStaticStateDisabled {
+		return
+	}
+	for _, id := range a.GetSourceIDs() {
+		a.logger.Info("节点已禁用，取消 Pipeline %s", id)
+		if err := a.engine.CancelPipeline(id, "节点已禁用"); err != nil {
+			a.logger.Warn("取消 Pipeline %s 失败: %v", id, err)
+		}
+	}
+}
+
+// acceptPipeline 接收服务端下发的 Pipeline 并生成确认
+// 服务端重连后可能重复下发尚未确认的 Pipeline，已在执行的直接确认，已结束的确认并重新上报结束状态；
+// 节点处于维护或禁用状态时拒绝新的 Pipeline
+func (a *GamePipelineAgent) acceptPipeline(ctx context.Context, pipeline *proto.GamePipeline) *proto.PipelineAck {
+	ack := &proto.PipelineAck{PipelineId: pipeline.Id, Accepted: true}
+
+	a.mu.RLock()
+	_, exists := a.sources[pipeline.Id]
+	a.mu.RUnlock()
+	if exists {
+		a.logger.Info("Pipeline %s 已在执行，忽略重复下发", pipeline.Id)
+		return ack
+	}
+	if completed, ok := a.completed.Get(pipeline.Id); ok {
+		a.logger.Info("Pipeline %s 已于 %s 结束，不再执行", pipeline.Id, completed.FinishedAt.Format(time.RFC3339))
+		a.reportCompleted(ctx, completed)
+		return ack
+	}
+
+	if state := a.agent.NodeState(); !state.AcceptsPipelines() {
+		a.logger.Warn("节点处于 %s 状态，拒绝 Pipeline %s", state, pipeline.Id)
+		ack.Accepted = false
+		ack.Message = fmt.Sprintf("节点处于 %s 状态，不接收新的 Pipeline", state)
+		return ack
+	}
+
+	if err := a.handlePipeline(ctx, pipeline); err != nil {
+		a.logger.Error("处理 Pipeline 任务失败: %v", err)
+		ack.Accepted = false
+		ack.Message = err.Error()
+	}
+	return ack
+}
+
 // convertProtoToModelPipeline 将 proto.GamePipeline 转换为 models.GamePipeline
 func (a *GamePipelineAgent) convertProtoToModelPipeline(pipeline *proto.GamePipeline) *models.GamePipeline {
 	// 初始化步骤状态
@@ -139,6 +447,7 @@ func (a *GamePipelineAgent) convertProtoToModelPipeline(pipeline *proto.GamePipe
 	modelPipeline := &models.GamePipeline{
 		ID:          pipeline.Id,
 		Model:       models.PipelineModel(pipeline.Model),
+		InstanceID:  pipeline.InstanceId,
 		Name:        pipeline.Name,
 		Description: pipeline.Description,
 		Envs:        pipeline.Envs,
@@ -154,31 +463,52 @@ func (a *GamePipelineAgent) convertProtoToModelPipeline(pipeline *proto.GamePipe
 		},
 	}
 
+	// 转换托管资源声明
+	for _, v := range pipeline.Volumes {
+		modelPipeline.Volumes = append(modelPipeline.Volumes, models.PipelineVolume{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			DriverOpts: v.DriverOpts,
+			Lifecycle:  models.ResourceLifecycle(v.Lifecycle),
+		})
+	}
+	for _, n := range pipeline.Networks {
+		modelPipeline.Networks = append(modelPipeline.Networks, models.PipelineNetwork{
+			Name:      n.Name,
+			Driver:    n.Driver,
+			Internal:  n.Internal,
+			Lifecycle: models.ResourceLifecycle(n.Lifecycle),
+		})
+	}
+
 	// 转换步骤
 	for i, step := range pipeline.Steps {
+		container := step.GetContainer()
 		modelPipeline.Steps[i] = models.PipelineStep{
 			Name: step.Name,
 			Type: step.Type,
 			Container: models.ContainerConfig{
-				Image:       step.Container.Image,
-				Hostname:    step.Container.Hostname,
-				Privileged:  step.Container.Privileged,
-				SecurityOpt: step.Container.SecurityOpt,
-				CapAdd:      step.Container.CapAdd,
-				Tmpfs:       step.Container.Tmpfs,
-				Devices:     step.Container.Devices,
-				Volumes:     step.Container.Volumes,
-				Ports:       step.Container.Ports,
-				Environment: step.Container.Environment,
-				Commands:    step.Container.Commands,
+				Image:       container.GetImage(),
+				Hostname:    container.GetHostname(),
+				Privileged:  container.GetPrivileged(),
+				SecurityOpt: container.GetSecurityOpt(),
+				CapAdd:      container.GetCapAdd(),
+				Tmpfs:       container.GetTmpfs(),
+				Devices:     container.GetDevices(),
+				Volumes:     container.GetVolumes(),
+				Ports:       container.GetPorts(),
+				Environment: container.GetEnvironment(),
+				Commands:    container.GetCommands(),
+				Networks:    container.GetNetworks(),
 			},
 		}
 
 		// 转换设备配置
-		for j, device := range step.Container.Deploy.Resources.Reservations.Devices {
-			modelPipeline.Steps[i].Container.Deploy.Resources.Reservations.Devices[j] = models.DeviceConfig{
+		for _, device := range container.GetDeploy().GetResources().GetReservations().GetDevices() {
+			reservations := &modelPipeline.Steps[i].Container.Deploy.Resources.Reservations
+			reservations.Devices = append(reservations.Devices, models.DeviceConfig{
 				Capabilities: device.Capabilities,
-			}
+			})
 		}
 
 		// 初始化步骤状态
@@ -204,128 +534,166 @@ func (a *GamePipelineAgent) handlePipeline(ctx context.Context, pipeline *proto.
 	a.sources[modelPipeline.ID] = modelPipeline
 	a.mu.Unlock()
 
-	// 获取 Pipeline 服务客户端
-	client := a.agent.GetPipelineClient()
-
-	// 更新 Pipeline 状态
-	now := timestamppb.Now()
-	status := &proto.PipelineStatus{
-		NodeId:      a.agent.id,
-		State:       proto.PipelineState_PIPELINE_STATE_RUNNING,
-		CurrentStep: 0,
-		TotalSteps:  int32(len(pipeline.Steps)),
-		StartTime:   now,
-		UpdatedAt:   now,
+	// 执行 Pipeline，状态由 handleEngineEvent 上报
+	if err := a.engine.Execute(ctx, modelPipeline); err != nil {
+		a.logger.Error("执行 Pipeline 失败: %v", err)
+		a.removeSource(modelPipeline.ID)
+
+		now := timestamppb.Now()
+		status := &proto.PipelineStatus{
+			NodeId:       a.agent.id,
+			State:        proto.PipelineState_PIPELINE_STATE_FAILED,
+			TotalSteps:   int32(len(pipeline.Steps)),
+			ErrorMessage: fmt.Sprintf("执行 Pipeline 失败: %v", err),
+			EndTime:      now,
+			UpdatedAt:    now,
+		}
+		if reportErr := a.UpdatePipelineStatus(ctx, pipeline.Id, status); reportErr != nil {
+			a.logger.Error("更新 Pipeline 状态失败: %v", reportErr)
+		}
+		return err
 	}
 
-	// 发送状态更新
-	_, err := client.UpdatePipelineStatus(ctx, &proto.UpdatePipelineStatusRequest{
-		PipelineId: pipeline.Id,
-		Status:     status,
-	})
-	if err != nil {
-		return err
+	return nil
+}
+
+// handleEngineEvent 将执行引擎事件上报给服务端
+// 引擎按顺序投递事件，上报序号在此处分配，保证服务端按发生顺序应用
+func (a *GamePipelineAgent) handleEngineEvent(event pl.Event) {
+	if event.Pipeline == nil {
+		return
 	}
+	pipelineID := event.Pipeline.ID
 
-	// 注册事件处理器
-	a.engine.RegisterHandler(func(event pl.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+
+	switch event.Type {
+	case pl.StepStarted, pl.StepCompleted, pl.StepFailed:
+		stepStatus := &proto.StepStatus{
+			Id:        event.Step.Name,
+			Name:      event.Step.Name,
+			UpdatedAt: timestamppb.Now(),
+		}
 		switch event.Type {
 		case pl.StepStarted:
-			// 更新步骤状态为运行中
-			stepStatus := &proto.StepStatus{
-				Id:        event.Step.Name,
-				Name:      event.Step.Name,
-				State:     proto.StepState_STEP_STATE_RUNNING,
-				StartTime: timestamppb.Now(),
-				UpdatedAt: timestamppb.Now(),
-			}
-			if err := a.UpdateStepStatus(ctx, pipeline.Id, event.Step.Name, stepStatus); err != nil {
-				a.logger.Error("更新步骤状态失败: %v", err)
-			}
+			stepStatus.State = proto.StepState_STEP_STATE_RUNNING
+			stepStatus.StartTime = timestamppb.Now()
 		case pl.StepCompleted:
-			// 更新步骤状态为完成
-			stepStatus := &proto.StepStatus{
-				Id:        event.Step.Name,
-				Name:      event.Step.Name,
-				State:     proto.StepState_STEP_STATE_COMPLETED,
-				EndTime:   timestamppb.Now(),
-				UpdatedAt: timestamppb.Now(),
-			}
-			if err := a.UpdateStepStatus(ctx, pipeline.Id, event.Step.Name, stepStatus); err != nil {
-				a.logger.Error("更新步骤状态失败: %v", err)
-			}
+			stepStatus.State = proto.StepState_STEP_STATE_COMPLETED
+			stepStatus.EndTime = timestamppb.Now()
 		case pl.StepFailed:
-			// 更新步骤状态为失败
-			stepStatus := &proto.StepStatus{
-				Id:        event.Step.Name,
-				Name:      event.Step.Name,
-				State:     proto.StepState_STEP_STATE_FAILED,
-				Error:     event.Message,
-				EndTime:   timestamppb.Now(),
-				UpdatedAt: timestamppb.Now(),
-			}
-			if err := a.UpdateStepStatus(ctx, pipeline.Id, event.Step.Name, stepStatus); err != nil {
-				a.logger.Error("更新步骤状态失败: %v", err)
-			}
-		case pl.PipelineCompleted:
-			// 更新 Pipeline 状态为完成
-			status.State = proto.PipelineState_PIPELINE_STATE_COMPLETED
-			status.EndTime = timestamppb.Now()
-			status.UpdatedAt = timestamppb.Now()
-			if _, err := client.UpdatePipelineStatus(ctx, &proto.UpdatePipelineStatusRequest{
-				PipelineId: pipeline.Id,
-				Status:     status,
-			}); err != nil {
-				a.logger.Error("更新 Pipeline 状态失败: %v", err)
-			}
-		case pl.PipelineFailed:
-			// 更新 Pipeline 状态为失败
+			stepStatus.State = proto.StepState_STEP_STATE_FAILED
+			stepStatus.Error = event.Message
+			stepStatus.EndTime = timestamppb.Now()
+		}
+		if err := a.UpdateStepStatus(ctx, pipelineID, event.Step.Name, stepStatus); err != nil {
+			a.logger.Error("更新步骤状态失败: %v", err)
+		}
+
+	case pl.PipelineStarted, pl.PipelineCompleted, pl.PipelineFailed:
+		status := convertModelToProtoPipelineStatus(event.Pipeline.Status)
+		status.NodeId = a.agent.id
+		status.UpdatedAt = timestamppb.Now()
+		if event.Type == pl.PipelineFailed {
 			status.State = proto.PipelineState_PIPELINE_STATE_FAILED
 			status.ErrorMessage = event.Message
-			status.EndTime = timestamppb.Now()
-			status.UpdatedAt = timestamppb.Now()
-			if _, err := client.UpdatePipelineStatus(ctx, &proto.UpdatePipelineStatusRequest{
-				PipelineId: pipeline.Id,
-				Status:     status,
-			}); err != nil {
-				a.logger.Error("更新 Pipeline 状态失败: %v", err)
-			}
 		}
-	})
+		// 先记录结束状态再移除，重复下发时总能识别为执行中或已结束
+		if event.Type != pl.PipelineStarted {
+			a.recordCompleted(pipelineID, status)
+		}
+		if err := a.UpdatePipelineStatus(ctx, pipelineID, status); err != nil {
+			a.logger.Error("更新 Pipeline 状态失败: %v", err)
+		}
+		if event.Type != pl.PipelineStarted {
+			a.removeSource(pipelineID)
+		}
+	}
+}
 
-	// 执行 Pipeline
-	if err := a.engine.Execute(ctx, modelPipeline); err != nil {
-		a.logger.Error("执行 Pipeline 失败: %v", err)
-		status.State = proto.PipelineState_PIPELINE_STATE_FAILED
-		status.ErrorMessage = fmt.Sprintf("执行 Pipeline 失败: %v", err)
-		status.UpdatedAt = timestamppb.Now()
-		_, _ = client.UpdatePipelineStatus(ctx, &proto.UpdatePipelineStatusRequest{
-			PipelineId: pipeline.Id,
-			Status:     status,
-		})
-		return err
+// recordCompleted 记录已结束的 Pipeline 及其结束状态
+func (a *GamePipelineAgent) recordCompleted(pipelineID string, status *proto.PipelineStatus) {
+	payload, err := protobuf.Marshal(status)
+	if err == nil {
+		err = a.completed.Add(pipelineID, payload, time.Now())
+	}
+	if err != nil {
+		a.logger.Error("记录已结束的 Pipeline %s 失败: %v", pipelineID, err)
 	}
+}
 
-	return nil
+// reportCompleted 重新上报已结束 Pipeline 的结束状态，服务端按上报序号忽略已应用的状态
+func (a *GamePipelineAgent) reportCompleted(ctx context.Context, completed *CompletedPipeline) {
+	status := &proto.PipelineStatus{}
+	if err := protobuf.Unmarshal(completed.Status, status); err != nil {
+		a.logger.Error("解析 Pipeline %s 的结束状态失败: %v", completed.ID, err)
+		return
+	}
+	status.UpdatedAt = timestamppb.Now()
+	if err := a.UpdatePipelineStatus(ctx, completed.ID, status); err != nil {
+		a.logger.Error("更新 Pipeline 状态失败: %v", err)
+	}
+	a.removeSource(completed.ID)
+}
+
+// removeSource 移除已结束的 Pipeline
+func (a *GamePipelineAgent) removeSource(pipelineID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sources, pipelineID)
+	delete(a.sequences, pipelineID)
+}
+
+// nextSequence 分配 Pipeline 状态上报序号
+// 序号以纳秒时间戳为基准，Agent 重启后仍然大于之前的序号
+func (a *GamePipelineAgent) nextSequence(pipelineID string) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	seq := time.Now().UnixNano()
+	if last := a.sequences[pipelineID]; seq <= last {
+		seq = last + 1
+	}
+	a.sequences[pipelineID] = seq
+	return seq
 }
 
-// UpdatePipelineStatus 更新 Pipeline 状态
+// UpdatePipelineStatus 更新 Pipeline 状态，连接中断时写入 outbox 等待重连后发送
 func (a *GamePipelineAgent) UpdatePipelineStatus(ctx context.Context, pipelineId string, status *proto.PipelineStatus) error {
-	client := a.agent.GetPipelineClient()
-	_, err := client.UpdatePipelineStatus(ctx, &proto.UpdatePipelineStatusRequest{
+	return a.agent.Report(ctx, OutboxKindPipelineStatus, &proto.UpdatePipelineStatusRequest{
 		PipelineId: pipelineId,
 		Status:     status,
+		Sequence:   a.nextSequence(pipelineId),
 	})
-	return err
 }
 
-// UpdateStepStatus 更新步骤状态
+// UpdateStepStatus 更新步骤状态，连接中断时写入 outbox 等待重连后发送
 func (a *GamePipelineAgent) UpdateStepStatus(ctx context.Context, pipelineId string, stepId string, status *proto.StepStatus) error {
-	client := a.agent.GetPipelineClient()
-	_, err := client.UpdateStepStatus(ctx, &proto.UpdateStepStatusRequest{
+	return a.agent.Report(ctx, OutboxKindStepStatus, &proto.UpdateStepStatusRequest{
 		PipelineId: pipelineId,
 		StepId:     stepId,
 		Status:     status,
+		Sequence:   a.nextSequence(pipelineId),
+		NodeId:     a.agent.id,
 	})
+}
+
+// sendPipelineStatus 发送 outbox 中的流水线状态
+func (a *GamePipelineAgent) sendPipelineStatus(ctx context.Context, payload []byte) error {
+	req := &proto.UpdatePipelineStatusRequest{}
+	if err := protobuf.Unmarshal(payload, req); err != nil {
+		return fmt.Errorf("解析流水线状态失败: %w", err)
+	}
+	_, err := a.agent.GetPipelineClient().UpdatePipelineStatus(ctx, req)
+	return err
+}
+
+// sendStepStatus 发送 outbox 中的步骤状态
+func (a *GamePipelineAgent) sendStepStatus(ctx context.Context, payload []byte) error {
+	req := &proto.UpdateStepStatusRequest{}
+	if err := protobuf.Unmarshal(payload, req); err != nil {
+		return fmt.Errorf("解析步骤状态失败: %w", err)
+	}
+	_, err := a.agent.GetPipelineClient().UpdateStepStatus(ctx, req)
 	return err
 }
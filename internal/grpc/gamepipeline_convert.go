@@ -0,0 +1,204 @@
+package grpc
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+)
+
+// pipelineStateToProto 将模型中的流水线状态转换为 proto 枚举
+func pipelineStateToProto(state models.PipelineState) proto.PipelineState {
+	switch state {
+	case models.PipelineStatePending:
+		return proto.PipelineState_PIPELINE_STATE_PENDING
+	case models.PipelineStateRunning:
+		return proto.PipelineState_PIPELINE_STATE_RUNNING
+	case models.PipelineStateCompleted:
+		return proto.PipelineState_PIPELINE_STATE_COMPLETED
+	case models.PipelineStateFailed:
+		return proto.PipelineState_PIPELINE_STATE_FAILED
+	case models.PipelineStateCanceled:
+		return proto.PipelineState_PIPELINE_STATE_CANCELED
+	default:
+		return proto.PipelineState_PIPELINE_STATE_NOT_STARTED
+	}
+}
+
+// pipelineStateFromProto 将 proto 枚举转换为模型中的流水线状态
+func pipelineStateFromProto(state proto.PipelineState) models.PipelineState {
+	switch state {
+	case proto.PipelineState_PIPELINE_STATE_PENDING:
+		return models.PipelineStatePending
+	case proto.PipelineState_PIPELINE_STATE_RUNNING:
+		return models.PipelineStateRunning
+	case proto.PipelineState_PIPELINE_STATE_COMPLETED:
+		return models.PipelineStateCompleted
+	case proto.PipelineState_PIPELINE_STATE_FAILED:
+		return models.PipelineStateFailed
+	case proto.PipelineState_PIPELINE_STATE_CANCELED:
+		return models.PipelineStateCanceled
+	default:
+		return models.PipelineStateNotStarted
+	}
+}
+
+// stepStateToProto 将模型中的步骤状态转换为 proto 枚举
+func stepStateToProto(state models.StepState) proto.StepState {
+	switch state {
+	case models.StepStateRunning:
+		return proto.StepState_STEP_STATE_RUNNING
+	case models.StepStateCompleted:
+		return proto.StepState_STEP_STATE_COMPLETED
+	case models.StepStateFailed:
+		return proto.StepState_STEP_STATE_FAILED
+	case models.StepStateSkipped:
+		return proto.StepState_STEP_STATE_SKIPPED
+	default:
+		return proto.StepState_STEP_STATE_PENDING
+	}
+}
+
+// stepStateFromProto 将 proto 枚举转换为模型中的步骤状态
+func stepStateFromProto(state proto.StepState) models.StepState {
+	switch state {
+	case proto.StepState_STEP_STATE_RUNNING:
+		return models.StepStateRunning
+	case proto.StepState_STEP_STATE_COMPLETED:
+		return models.StepStateCompleted
+	case proto.StepState_STEP_STATE_FAILED:
+		return models.StepStateFailed
+	case proto.StepState_STEP_STATE_SKIPPED:
+		return models.StepStateSkipped
+	default:
+		return models.StepStatePending
+	}
+}
+
+// timeToProto 将可空时间转换为 proto 时间戳
+func timeToProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// timeFromProto 将 proto 时间戳转换为可空时间
+func timeFromProto(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// convertProtoToModelPipelineStatus 将上报的 proto 流水线状态转换为模型
+func convertProtoToModelPipelineStatus(status *proto.PipelineStatus) *models.PipelineStatus {
+	return &models.PipelineStatus{
+		NodeID:       status.GetNodeId(),
+		State:        pipelineStateFromProto(status.GetState()),
+		CurrentStep:  status.GetCurrentStep(),
+		TotalSteps:   status.GetTotalSteps(),
+		ErrorMessage: status.GetErrorMessage(),
+		StartTime:    timeFromProto(status.GetStartTime()),
+		EndTime:      timeFromProto(status.GetEndTime()),
+		UpdatedAt:    timeFromProto(status.GetUpdatedAt()),
+	}
+}
+
+// convertModelToProtoPipelineStatus 将模型中的流水线状态转换为 proto
+func convertModelToProtoPipelineStatus(status *models.PipelineStatus) *proto.PipelineStatus {
+	return &proto.PipelineStatus{
+		NodeId:       status.NodeID,
+		State:        pipelineStateToProto(status.State),
+		CurrentStep:  status.CurrentStep,
+		TotalSteps:   status.TotalSteps,
+		ErrorMessage: status.ErrorMessage,
+		StartTime:    timeToProto(status.StartTime),
+		EndTime:      timeToProto(status.EndTime),
+		UpdatedAt:    timeToProto(status.UpdatedAt),
+	}
+}
+
+// convertProtoToModelStepStatus 将上报的 proto 步骤状态转换为模型
+func convertProtoToModelStepStatus(status *proto.StepStatus) *models.StepStatus {
+	return &models.StepStatus{
+		ID:        status.GetId(),
+		Name:      status.GetName(),
+		State:     stepStateFromProto(status.GetState()),
+		Error:     status.GetError(),
+		Output:    status.GetOutput(),
+		Progress:  status.GetProgress(),
+		StartTime: timeFromProto(status.GetStartTime()),
+		EndTime:   timeFromProto(status.GetEndTime()),
+		UpdatedAt: timeFromProto(status.GetUpdatedAt()),
+	}
+}
+
+// convertModelToProtoPipeline 将模型中的流水线转换为下发给节点的 proto
+func convertModelToProtoPipeline(pipeline *models.GamePipeline) *proto.GamePipeline {
+	result := &proto.GamePipeline{
+		Id:          pipeline.ID,
+		Model:       proto.PipelineModel(pipeline.Model),
+		Name:        pipeline.Name,
+		Description: pipeline.Description,
+		Envs:        pipeline.Envs,
+		Args:        pipeline.Args,
+		InstanceId:  pipeline.InstanceID,
+	}
+	if pipeline.Status != nil {
+		result.Status = convertModelToProtoPipelineStatus(pipeline.Status)
+	}
+
+	for _, v := range pipeline.Volumes {
+		result.Volumes = append(result.Volumes, &proto.PipelineVolume{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			DriverOpts: v.DriverOpts,
+			Lifecycle:  string(v.Lifecycle),
+		})
+	}
+	for _, n := range pipeline.Networks {
+		result.Networks = append(result.Networks, &proto.PipelineNetwork{
+			Name:      n.Name,
+			Driver:    n.Driver,
+			Internal:  n.Internal,
+			Lifecycle: string(n.Lifecycle),
+		})
+	}
+
+	for _, step := range pipeline.Steps {
+		c := step.Container
+		devices := make([]*proto.DeviceConfig, 0, len(c.Deploy.Resources.Reservations.Devices))
+		for _, d := range c.Deploy.Resources.Reservations.Devices {
+			devices = append(devices, &proto.DeviceConfig{Capabilities: d.Capabilities})
+		}
+		result.Steps = append(result.Steps, &proto.PipelineStep{
+			Name: step.Name,
+			Type: step.Type,
+			Container: &proto.ContainerConfig{
+				Image:      c.Image,
+				Hostname:   c.Hostname,
+				Privileged: c.Privileged,
+				Deploy: &proto.DeployConfig{
+					Resources: &proto.ResourcesConfig{
+						Reservations: &proto.ReservationsConfig{Devices: devices},
+					},
+				},
+				SecurityOpt: c.SecurityOpt,
+				CapAdd:      c.CapAdd,
+				Tmpfs:       c.Tmpfs,
+				Devices:     c.Devices,
+				Volumes:     c.Volumes,
+				Ports:       c.Ports,
+				Environment: c.Environment,
+				Commands:    c.Commands,
+				Networks:    c.Networks,
+			},
+		})
+	}
+
+	return result
+}
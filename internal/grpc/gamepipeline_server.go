@@ -2,30 +2,49 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/open-beagle/beagle-wind-game/internal/models"
 	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/service"
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
 const (
-	// 心跳超时时间
+	// 心跳超时时间，超过该时间未收到节点消息的会话将被关闭
 	heartbeatTimeout = 30 * time.Second
 	// 每个节点最大 Pipeline 数量
 	maxPipelinesPerNode = 2
+	// 会话发送队列长度
+	sessionQueueSize = 16
+	// 消息进入发送队列的最长等待时间
+	sessionEnqueueTimeout = 5 * time.Second
+	// 连接检查周期
+	sessionSweepInterval = 10 * time.Second
 )
 
-// NodeSession 表示一个节点的会话
+// errSessionClosed 会话已关闭
+var errSessionClosed = errors.New("节点会话已关闭")
+
+// NodeSession 表示一个节点的 PipelineStream 会话
+// 接收和发送由独立的协程处理，发往节点的消息先进入有界队列
 type NodeSession struct {
 	ID       string
-	Pipeline chan *proto.GamePipeline
-	Cancel   chan string
-	Timeout  chan struct{}
 	Sources  []string // 正在运行的 Pipeline IDs，由 Agent 主动报告
+	outbound chan *proto.PipelineStreamResponse
+	since    time.Time
+	lastSeen time.Time
+	done     chan struct{}
+	once     sync.Once
+	err      error
 	mu       sync.RWMutex
 }
 
@@ -33,10 +52,11 @@ type NodeSession struct {
 func NewNodeSession(id string) *NodeSession {
 	return &NodeSession{
 		ID:       id,
-		Pipeline: make(chan *proto.GamePipeline, 1),
-		Cancel:   make(chan string, 1),
-		Timeout:  make(chan struct{}, 1),
 		Sources:  make([]string, 0),
+		outbound: make(chan *proto.PipelineStreamResponse, sessionQueueSize),
+		since:    time.Now(),
+		lastSeen: time.Now(),
+		done:     make(chan struct{}),
 	}
 }
 
@@ -73,153 +93,245 @@ func (s *NodeSession) GetSourceCount() int {
 	return len(s.Sources)
 }
 
-// GamePipelineServer 表示 Pipeline 服务器
-type GamePipelineServer struct {
-	proto.UnimplementedGamePipelineGRPCServiceServer
+// Touch 记录收到节点消息的时间
+func (s *NodeSession) Touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeen = time.Now()
+}
 
-	mu     sync.RWMutex
-	nodes  map[string]*NodeSession
-	logger utils.Logger
-	stop   chan struct{}
+// Since 获取会话建立的时间
+func (s *NodeSession) Since() time.Time {
+	return s.since
 }
 
-// NewGamePipelineServer 创建一个新的 Pipeline 服务器
-func NewGamePipelineServer(logger utils.Logger) *GamePipelineServer {
-	server := &GamePipelineServer{
-		nodes:  make(map[string]*NodeSession),
-		logger: logger,
-		stop:   make(chan struct{}),
+// LastSeen 获取最近一次收到节点消息的时间
+func (s *NodeSession) LastSeen() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastSeen
+}
+
+// Enqueue 将消息放入发送队列，队列已满时最多等待 sessionEnqueueTimeout
+func (s *NodeSession) Enqueue(ctx context.Context, resp *proto.PipelineStreamResponse) error {
+	timer := time.NewTimer(sessionEnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.outbound <- resp:
+		return nil
+	case <-s.done:
+		return fmt.Errorf("%w: %s", errSessionClosed, s.ID)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("节点 %s 发送队列已满", s.ID)
 	}
+}
+
+// Close 关闭会话，只有第一次调用记录的原因生效
+func (s *NodeSession) Close(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
 
-	// 启动事件分发器
-	go server.eventDispatcher()
+// Done 会话关闭时返回的通道
+func (s *NodeSession) Done() <-chan struct{} {
+	return s.done
+}
 
-	return server
+// Err 获取会话关闭的原因
+func (s *NodeSession) Err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
 }
 
-// eventDispatcher 事件分发器
-func (s *GamePipelineServer) eventDispatcher() {
-	ticker := time.NewTicker(heartbeatTimeout)
-	defer ticker.Stop()
+// GamePipelineServer 表示 Pipeline 服务器
+type GamePipelineServer struct {
+	proto.UnimplementedGamePipelineGRPCServiceServer
 
-	for {
-		select {
-		case <-s.stop:
-			return
-		case <-ticker.C:
-			s.mu.RLock()
-			for _, node := range s.nodes {
-				select {
-				case node.Timeout <- struct{}{}:
-				default:
-				}
-			}
-			s.mu.RUnlock()
-		}
-	}
+	// connections 节点连接注册表，保存 PipelineStream 会话
+	connections     *ConnectionRegistry
+	pipelineService GamePipelineServiceInterface
+	logger          utils.Logger
+	// auth 节点凭证校验，为空时不校验
+	auth *nodeAuthenticator
+	// diagnostics 诊断包服务，由 SetDiagnosticsService 注入
+	diagnostics DiagnosticsServiceInterface
 }
 
-// handleSendError 处理发送错误
-func (s *GamePipelineServer) handleSendError(node *NodeSession, err error, msg string) error {
-	s.logger.Error("%s: %v", msg, err)
-	s.removeNode(node.ID)
-	return fmt.Errorf("%s: %w", msg, err)
+// NewGamePipelineServer 创建一个新的 Pipeline 服务器，会话超时由连接注册表检查
+func NewGamePipelineServer(pipelineService GamePipelineServiceInterface, connections *ConnectionRegistry, logger utils.Logger) *GamePipelineServer {
+	return &GamePipelineServer{
+		connections:     connections,
+		pipelineService: pipelineService,
+		logger:          logger,
+	}
 }
 
 // PipelineStream 处理 Pipeline 流式请求
+// 节点的第一条消息必须是心跳，用于注册会话；之后接收和发送由独立协程处理，
+// 任意一方结束或会话被关闭时整个流结束
 func (s *GamePipelineServer) PipelineStream(stream proto.GamePipelineGRPCService_PipelineStreamServer) error {
-	var node *NodeSession
+	ctx := stream.Context()
+
+	// 1. 注册会话
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	heartbeat := first.GetHeartbeat()
+	if heartbeat == nil || heartbeat.NodeId == "" {
+		return status.Error(codes.InvalidArgument, "第一条消息必须是包含节点ID的心跳")
+	}
+	if err := authorizeNode(ctx, heartbeat.NodeId); err != nil {
+		return err
+	}
+	if s.auth != nil {
+		if err := s.auth.authenticate(ctx, heartbeat.NodeId); err != nil {
+			return err
+		}
+	}
+	node := s.connections.AttachSession(ctx, heartbeat.NodeId)
+	defer s.connections.DetachSession(node)
+	node.UpdateSources(heartbeat.PipelineIds)
+	s.logger.Info("节点 %s 已建立 Pipeline 会话", node.ID)
+
+	// 2. 发送协程
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- s.sendLoop(stream, node)
+	}()
+
+	// 3. 接收协程
+	recvErr := make(chan error, 1)
+	go func() {
+		recvErr <- s.recvLoop(stream, node)
+	}()
+
+	if err := node.Enqueue(ctx, heartbeatAckResponse()); err != nil {
+		node.Close(err)
+	}
+	go s.dispatchQueued(node.ID)
+
+	// 4. 等待任意一方结束
+	sendStopped := false
+	select {
+	case err = <-recvErr:
+		if err == io.EOF {
+			err = nil
+		}
+	case err = <-sendErr:
+		sendStopped = true
+	case <-node.Done():
+		err = node.Err()
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	node.Close(err)
 
-	// 处理客户端请求
+	// 处理函数返回后不能再调用 stream.Send，等待发送协程退出
+	if !sendStopped {
+		<-sendErr
+	}
+	s.logger.Info("节点 %s 的 Pipeline 会话已结束: %v", node.ID, err)
+	return err
+}
+
+// recvLoop 接收节点消息，直到流结束
+func (s *GamePipelineServer) recvLoop(stream proto.GamePipelineGRPCService_PipelineStreamServer, node *NodeSession) error {
+	ctx := stream.Context()
 	for {
 		req, err := stream.Recv()
 		if err != nil {
-			if node != nil {
-				s.removeNode(node.ID)
-			}
 			return err
 		}
-
-		// 获取或创建节点
-		nodeID := req.GetHeartbeat().NodeId
-		node = s.getOrCreateNode(nodeID)
-
-		// 处理心跳消息中的 Pipeline 状态
-		if heartbeat := req.GetHeartbeat(); heartbeat != nil {
-			// 更新节点的 Pipeline 状态
-			node.UpdateSources(heartbeat.PipelineIds)
+		node.Touch()
+
+		switch {
+		case req.GetAck() != nil:
+			s.handleAck(ctx, node.ID, req.GetAck())
+		case req.GetHeartbeat() != nil:
+			node.UpdateSources(req.GetHeartbeat().PipelineIds)
+			if err := node.Enqueue(ctx, heartbeatAckResponse()); err != nil {
+				s.logger.Warn("节点 %s 心跳确认入队失败: %v", node.ID, err)
+			}
+		default:
+			s.logger.Warn("收到节点 %s 的无效 Pipeline 流消息", node.ID)
 		}
+	}
+}
 
-		// 等待事件
+// sendLoop 从发送队列取出消息发送给节点，直到会话关闭
+func (s *GamePipelineServer) sendLoop(stream proto.GamePipelineGRPCService_PipelineStreamServer, node *NodeSession) error {
+	for {
 		select {
-		case reason := <-node.Cancel:
-			// 处理取消命令
-			resp := &proto.PipelineStreamResponse{
-				Response: &proto.PipelineStreamResponse_Cancel{
-					Cancel: &proto.CancelCommand{
-						Reason: reason,
-					},
-				},
-			}
-			if err := stream.Send(resp); err != nil {
-				return s.handleSendError(node, err, "发送取消命令失败")
-			}
-
-		case pipeline := <-node.Pipeline:
-			// 处理 Pipeline 任务
-			resp := &proto.PipelineStreamResponse{
-				Response: &proto.PipelineStreamResponse_Pipeline{
-					Pipeline: pipeline,
-				},
-			}
+		case resp := <-node.outbound:
 			if err := stream.Send(resp); err != nil {
-				return s.handleSendError(node, err, "发送 Pipeline 任务失败")
-			}
-
-		case <-node.Timeout:
-			// 发送心跳确认
-			ack := &proto.PipelineStreamResponse{
-				Response: &proto.PipelineStreamResponse_HeartbeatAck{
-					HeartbeatAck: &proto.HeartbeatAck{
-						Success: true,
-					},
-				},
-			}
-			if err := stream.Send(ack); err != nil {
-				return s.handleSendError(node, err, "发送心跳确认失败")
+				s.logger.Error("发送消息到节点 %s 失败: %v", node.ID, err)
+				return err
 			}
+		case <-node.Done():
+			return nil
+		case <-stream.Context().Done():
+			return stream.Context().Err()
 		}
 	}
 }
 
-// getOrCreateNode 获取或创建节点
-func (s *GamePipelineServer) getOrCreateNode(id string) *NodeSession {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// heartbeatAckResponse 构造心跳确认消息
+func heartbeatAckResponse() *proto.PipelineStreamResponse {
+	return &proto.PipelineStreamResponse{
+		Response: &proto.PipelineStreamResponse_HeartbeatAck{
+			HeartbeatAck: &proto.HeartbeatAck{
+				Success: true,
+			},
+		},
+	}
+}
 
-	node, ok := s.nodes[id]
-	if !ok {
-		node = NewNodeSession(id)
-		s.nodes[id] = node
+// getSession 获取节点当前的会话
+func (s *GamePipelineServer) getSession(nodeID string) (*NodeSession, error) {
+	return s.connections.Session(nodeID)
+}
+
+// handleAck 处理节点对下发流水线的确认
+func (s *GamePipelineServer) handleAck(ctx context.Context, nodeID string, ack *proto.PipelineAck) {
+	if err := s.pipelineService.AckDispatch(ctx, ack.PipelineId, nodeID, ack.Accepted, ack.Message); err != nil {
+		s.logger.Error("记录流水线 %s 确认失败: %v", ack.PipelineId, err)
 	}
-	return node
 }
 
-// removeNode 移除节点
-func (s *GamePipelineServer) removeNode(id string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.nodes, id)
+// dispatchQueued 下发节点上排队的流水线
+func (s *GamePipelineServer) dispatchQueued(nodeID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), heartbeatTimeout)
+	defer cancel()
+	if err := s.pipelineService.DispatchQueued(ctx, nodeID); err != nil {
+		s.logger.Warn("下发节点 %s 排队的流水线失败: %v", nodeID, err)
+	}
+}
+
+// IsConnected 节点是否已建立 PipelineStream 会话
+func (s *GamePipelineServer) IsConnected(nodeID string) bool {
+	_, err := s.connections.Session(nodeID)
+	return err == nil
+}
+
+// Dispatch 下发流水线到节点，实现 service.PipelineDispatcher
+func (s *GamePipelineServer) Dispatch(ctx context.Context, nodeID string, pipeline *models.GamePipeline) error {
+	return s.SendPipeline(ctx, nodeID, convertModelToProtoPipeline(pipeline))
 }
 
 // SendPipeline 发送 Pipeline 任务到指定节点
 func (s *GamePipelineServer) SendPipeline(ctx context.Context, nodeID string, pipeline *proto.GamePipeline) error {
-	s.mu.RLock()
-	node, ok := s.nodes[nodeID]
-	s.mu.RUnlock()
-
-	if !ok {
-		return nil
+	node, err := s.getSession(nodeID)
+	if err != nil {
+		return err
 	}
 
 	// 检查节点是否达到最大 Pipeline 数量
@@ -227,50 +339,204 @@ func (s *GamePipelineServer) SendPipeline(ctx context.Context, nodeID string, pi
 		return fmt.Errorf("节点 %s 已达到最大 Pipeline 数量限制 (%d)", nodeID, maxPipelinesPerNode)
 	}
 
-	// 发送 Pipeline 任务
-	node.Pipeline <- pipeline
+	resp := &proto.PipelineStreamResponse{
+		Response: &proto.PipelineStreamResponse_Pipeline{
+			Pipeline: pipeline,
+		},
+	}
+	if err := node.Enqueue(ctx, resp); err != nil {
+		if errors.Is(err, errSessionClosed) {
+			return fmt.Errorf("%w: %s", service.ErrNodeNotConnected, nodeID)
+		}
+		return fmt.Errorf("发送 Pipeline 任务失败: %w", err)
+	}
 	return nil
 }
 
 // SendCancel 发送取消命令到指定节点
-func (s *GamePipelineServer) SendCancel(ctx context.Context, nodeID string, reason string) error {
-	s.mu.RLock()
-	node, ok := s.nodes[nodeID]
-	s.mu.RUnlock()
+func (s *GamePipelineServer) SendCancel(ctx context.Context, nodeID string, pipelineID string, reason string) error {
+	node, err := s.getSession(nodeID)
+	if err != nil {
+		return err
+	}
 
-	if !ok {
-		return nil
+	resp := &proto.PipelineStreamResponse{
+		Response: &proto.PipelineStreamResponse_Cancel{
+			Cancel: &proto.CancelCommand{
+				PipelineId: pipelineID,
+				Reason:     reason,
+			},
+		},
+	}
+	if err := node.Enqueue(ctx, resp); err != nil {
+		if errors.Is(err, errSessionClosed) {
+			return fmt.Errorf("%w: %s", service.ErrNodeNotConnected, nodeID)
+		}
+		return fmt.Errorf("发送取消命令失败: %w", err)
+	}
+	return nil
+}
+
+// Cancel 取消节点上的流水线，实现 service.PipelineDispatcher
+func (s *GamePipelineServer) Cancel(ctx context.Context, nodeID string, pipelineID string, reason string) error {
+	return s.SendCancel(ctx, nodeID, pipelineID, reason)
+}
+
+// SendNodeState 通知节点维护状态已变更，节点未连接时在下一次注册或心跳时同步
+func (s *GamePipelineServer) SendNodeState(ctx context.Context, nodeID string, state models.GameNodeStaticState) error {
+	node, err := s.getSession(nodeID)
+	if err != nil {
+		return err
 	}
 
-	// 发送取消命令
-	node.Cancel <- reason
+	resp := &proto.PipelineStreamResponse{
+		Response: &proto.PipelineStreamResponse_NodeState{
+			NodeState: &proto.NodeStateCommand{
+				State: string(state),
+			},
+		},
+	}
+	if err := node.Enqueue(ctx, resp); err != nil {
+		if errors.Is(err, errSessionClosed) {
+			return fmt.Errorf("%w: %s", service.ErrNodeNotConnected, nodeID)
+		}
+		return fmt.Errorf("发送节点状态失败: %w", err)
+	}
+	return nil
+}
+
+// RemoveContainer 通知节点删除托管容器，实现 service.ContainerRemover
+func (s *GamePipelineServer) RemoveContainer(ctx context.Context, nodeID string, containerID string, reason string) error {
+	node, err := s.getSession(nodeID)
+	if err != nil {
+		return err
+	}
+
+	resp := &proto.PipelineStreamResponse{
+		Response: &proto.PipelineStreamResponse_RemoveContainer{
+			RemoveContainer: &proto.RemoveContainerCommand{
+				ContainerId: containerID,
+				Reason:      reason,
+			},
+		},
+	}
+	if err := node.Enqueue(ctx, resp); err != nil {
+		if errors.Is(err, errSessionClosed) {
+			return fmt.Errorf("%w: %s", service.ErrNodeNotConnected, nodeID)
+		}
+		return fmt.Errorf("发送删除容器命令失败: %w", err)
+	}
 	return nil
 }
 
 // UpdatePipelineStatus 更新 Pipeline 状态
 func (s *GamePipelineServer) UpdatePipelineStatus(ctx context.Context, req *proto.UpdatePipelineStatusRequest) (*proto.UpdatePipelineStatusResponse, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if req.PipelineId == "" || req.Status == nil {
+		return nil, status.Error(codes.InvalidArgument, "流水线ID和状态不能为空")
+	}
+	if req.Status.NodeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "节点ID不能为空")
+	}
+
+	report := convertProtoToModelPipelineStatus(req.Status)
+	applied, err := s.pipelineService.ApplyStatusReport(ctx, req.PipelineId, req.Status.NodeId, req.Sequence, report)
+	if err != nil {
+		s.logger.Error("更新 Pipeline 状态失败: %s, 错误: %v", req.PipelineId, err)
+		return nil, reportError(err)
+	}
+	if applied {
+		s.logger.Debug("Pipeline %s 状态已更新为 %s (序号 %d)", req.PipelineId, report.State, req.Sequence)
+	}
 
-	// TODO: 实现状态更新逻辑
 	return &proto.UpdatePipelineStatusResponse{Success: true}, nil
 }
 
 // UpdateStepStatus 更新步骤状态
 func (s *GamePipelineServer) UpdateStepStatus(ctx context.Context, req *proto.UpdateStepStatusRequest) (*proto.UpdateStepStatusResponse, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if req.PipelineId == "" || req.StepId == "" || req.Status == nil {
+		return nil, status.Error(codes.InvalidArgument, "流水线ID、步骤ID和状态不能为空")
+	}
+	if req.NodeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "节点ID不能为空")
+	}
+
+	report := convertProtoToModelStepStatus(req.Status)
+	applied, err := s.pipelineService.ApplyStepReport(ctx, req.PipelineId, req.NodeId, req.StepId, req.Sequence, report)
+	if err != nil {
+		s.logger.Error("更新步骤状态失败: %s/%s, 错误: %v", req.PipelineId, req.StepId, err)
+		return nil, reportError(err)
+	}
+	if applied {
+		s.logger.Debug("Pipeline %s 步骤 %s 状态已更新为 %s (序号 %d)", req.PipelineId, req.StepId, report.State, req.Sequence)
+	}
 
-	// TODO: 实现步骤状态更新逻辑
 	return &proto.UpdateStepStatusResponse{Success: true}, nil
 }
 
+// StreamStepLogs 接收节点上传的步骤日志，一个流只传输一个步骤的日志
+func (s *GamePipelineServer) StreamStepLogs(stream proto.GamePipelineGRPCService_StreamStepLogsServer) error {
+	ctx := stream.Context()
+
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return stream.SendAndClose(&proto.StreamStepLogsResponse{})
+	}
+	if err != nil {
+		return err
+	}
+	if first.NodeId == "" || first.PipelineId == "" || first.StepId == "" {
+		return status.Error(codes.InvalidArgument, "节点ID、流水线ID和步骤ID不能为空")
+	}
+	if err := authorizeNode(ctx, first.NodeId); err != nil {
+		return err
+	}
+	if s.auth != nil {
+		if err := s.auth.authenticate(ctx, first.NodeId); err != nil {
+			return err
+		}
+	}
+
+	var written int64
+	for chunk := first; ; {
+		if chunk.NodeId != first.NodeId || chunk.PipelineId != first.PipelineId || chunk.StepId != first.StepId {
+			return status.Error(codes.InvalidArgument, "同一个日志流只能上传一个步骤的日志")
+		}
+		written, err = s.pipelineService.AppendStepLog(ctx, chunk.NodeId, chunk.PipelineId, chunk.StepId, chunk.Offset, chunk.Data, chunk.Eof)
+		if err != nil {
+			s.logger.Error("保存步骤日志失败: %s/%s, 错误: %v", chunk.PipelineId, chunk.StepId, err)
+			return reportError(err)
+		}
+
+		chunk, err = stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&proto.StreamStepLogsResponse{Written: written})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// reportError 将状态上报的服务层错误转换为 gRPC 错误
+// 只有流水线或步骤不存在和不允许的状态转换是永久拒绝，其他错误（例如保存失败）返回 Internal，节点保留上报稍后重试
+func reportError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrPipelineNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrPipelineNotOwned):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrStepNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrStepStateTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrLogStoreDisabled):
+		return status.Error(codes.Unimplemented, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
 // Register 注册 Pipeline 服务到 gRPC 服务器
 func (s *GamePipelineServer) Register(server *grpc.Server) {
 	proto.RegisterGamePipelineGRPCServiceServer(server, s)
 }
-
-// Stop 停止服务器
-func (s *GamePipelineServer) Stop() {
-	close(s.stop)
-}
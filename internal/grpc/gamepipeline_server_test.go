@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/service"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestNodeSessionEnqueue(t *testing.T) {
+	ctx := context.Background()
+	logger := utils.New("PipelineServerTest")
+	registry := NewConnectionRegistry(nil, time.Minute, logger)
+	server := NewGamePipelineServer(nil, registry, logger)
+	session := registry.AttachSession(ctx, "node-1")
+
+	// 发送协程未取出消息时，队列满之前的消息直接入队
+	for i := 0; i < sessionQueueSize; i++ {
+		require.NoError(t, server.SendCancel(ctx, "node-1", "pipeline-1", "test"))
+	}
+
+	// 队列已满时等待调用方的超时
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err := server.SendCancel(timeoutCtx, "node-1", "pipeline-1", "test")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 队列一直没有空位时最多等待 sessionEnqueueTimeout
+	start := time.Now()
+	err = server.SendCancel(ctx, "node-1", "pipeline-1", "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "发送队列已满")
+	assert.GreaterOrEqual(t, time.Since(start), sessionEnqueueTimeout)
+
+	// 等待期间队列腾出空位后消息入队
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		<-session.outbound
+	}()
+	require.NoError(t, server.SendCancel(ctx, "node-1", "pipeline-1", "test"))
+	assert.Len(t, session.outbound, sessionQueueSize)
+
+	// 等待期间会话关闭时按节点未连接处理
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		session.Close(nil)
+	}()
+	err = server.SendCancel(ctx, "node-1", "pipeline-1", "test")
+	assert.ErrorIs(t, err, service.ErrNodeNotConnected)
+}
@@ -0,0 +1,230 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"runtime/debug"
+	"time"
+
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/open-beagle/beagle-wind-game/internal/metrics"
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// requestIDMetadataKey 请求ID在 metadata 中的键
+const requestIDMetadataKey = "x-request-id"
+
+var (
+	rpcRequests = metrics.NewCounterVec(
+		"beagle_grpc_requests_total",
+		"gRPC 请求数",
+		"side", "method", "code",
+	)
+	rpcDuration = metrics.NewHistogramVec(
+		"beagle_grpc_request_duration_seconds",
+		"gRPC 请求耗时，流式请求为整个流的持续时间",
+		nil,
+		"side", "method",
+	)
+)
+
+// requestIDKey 请求ID在 context 中的键
+type requestIDKey struct{}
+
+// WithRequestID 在 context 中保存请求ID，Agent 发起的请求会在 metadata 中携带该ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 获取 context 中的请求ID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID 生成请求ID
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// incomingRequestID 获取客户端传入的请求ID，没有时生成新的ID
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return newRequestID()
+}
+
+// rpcObserver 记录 RPC 日志和指标，并将 handler 中的 panic 转换为 codes.Internal
+type rpcObserver struct {
+	logger utils.Logger
+}
+
+// unaryServerInterceptor 服务端一元请求拦截器，应位于拦截器链的最外层
+func (o *rpcObserver) unaryServerInterceptor(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (resp interface{}, err error) {
+	requestID := incomingRequestID(ctx)
+	ctx = WithRequestID(ctx, requestID)
+	ggrpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+	nodeID, _ := requestNodeID(req)
+
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = o.recovered(requestID, info.FullMethod, r)
+		}
+		o.observe("server", info.FullMethod, requestID, nodeID, start, err)
+	}()
+	return handler(ctx, req)
+}
+
+// streamServerInterceptor 服务端流式请求拦截器，节点ID取自流中的第一条消息
+func (o *rpcObserver) streamServerInterceptor(srv interface{}, ss ggrpc.ServerStream, info *ggrpc.StreamServerInfo, handler ggrpc.StreamHandler) (err error) {
+	requestID := incomingRequestID(ss.Context())
+	ss.SetHeader(metadata.Pairs(requestIDMetadataKey, requestID))
+	stream := &observedServerStream{
+		ServerStream: ss,
+		ctx:          WithRequestID(ss.Context(), requestID),
+	}
+
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = o.recovered(requestID, info.FullMethod, r)
+		}
+		o.observe("server", info.FullMethod, requestID, stream.nodeID, start, err)
+	}()
+	return handler(srv, stream)
+}
+
+// recovered 记录 panic 并返回 codes.Internal
+func (o *rpcObserver) recovered(requestID, method string, r interface{}) error {
+	o.logger.WithRequestID(requestID).Error("处理 %s 时发生 panic: %v\n%s", method, r, debug.Stack())
+	return status.Errorf(codes.Internal, "服务端内部错误, 请求ID: %s", requestID)
+}
+
+// observe 记录请求日志和指标，成功的请求只输出调试日志
+func (o *rpcObserver) observe(side, method, requestID, nodeID string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+	rpcRequests.WithLabelValues(side, method, code.String()).Inc()
+	rpcDuration.WithLabelValues(side, method).Observe(duration.Seconds())
+
+	logger := o.logger.WithRequestID(requestID)
+	switch {
+	case err == nil:
+		logger.Debug("%s %s 节点: %s 耗时: %v 结果: %s", side, method, nodeID, duration, code)
+	case code == codes.Internal || code == codes.Unknown:
+		logger.Error("%s %s 节点: %s 耗时: %v 结果: %s 错误: %v", side, method, nodeID, duration, code, err)
+	default:
+		logger.Warn("%s %s 节点: %s 耗时: %v 结果: %s 错误: %v", side, method, nodeID, duration, code, err)
+	}
+}
+
+// observedServerStream 携带请求ID的服务端流，记录第一条消息中的节点ID
+type observedServerStream struct {
+	ggrpc.ServerStream
+	ctx    context.Context
+	nodeID string
+}
+
+func (s *observedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *observedServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil && s.nodeID == "" {
+		s.nodeID = streamMessageNodeID(m)
+	}
+	return err
+}
+
+// streamMessageNodeID 获取流式消息中声明的节点ID
+func streamMessageNodeID(m interface{}) string {
+	switch r := m.(type) {
+	case *proto.PipelineStreamRequest:
+		return r.GetHeartbeat().GetNodeId()
+	case *proto.StepLogChunk:
+		return r.GetNodeId()
+	case *proto.DiagnosticsChunk:
+		return r.GetNodeId()
+	default:
+		nodeID, _ := requestNodeID(m)
+		return nodeID
+	}
+}
+
+// outgoingRequestID 使用 context 中的请求ID，没有时生成新的ID，并附加到请求 metadata
+func outgoingRequestID(ctx context.Context) (context.Context, string) {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	return metadata.AppendToOutgoingContext(ctx, requestIDMetadataKey, requestID), requestID
+}
+
+// observeUnaryInterceptor Agent 一元请求拦截器，附加请求ID并记录日志和指标
+func (a *Agent) observeUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *ggrpc.ClientConn, invoker ggrpc.UnaryInvoker, opts ...ggrpc.CallOption) error {
+	ctx, requestID := outgoingRequestID(ctx)
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	a.rpcObserver().observe("client", method, requestID, a.id, start, err)
+	return err
+}
+
+// observeStreamInterceptor Agent 流式请求拦截器，附加请求ID并在流结束时记录日志和指标
+func (a *Agent) observeStreamInterceptor(ctx context.Context, desc *ggrpc.StreamDesc, cc *ggrpc.ClientConn, method string, streamer ggrpc.Streamer, opts ...ggrpc.CallOption) (ggrpc.ClientStream, error) {
+	ctx, requestID := outgoingRequestID(ctx)
+	start := time.Now()
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		a.rpcObserver().observe("client", method, requestID, a.id, start, err)
+		return nil, err
+	}
+	return &observedClientStream{
+		ClientStream:  cs,
+		serverStreams: desc.ServerStreams,
+		done: func(err error) {
+			a.rpcObserver().observe("client", method, requestID, a.id, start, err)
+		},
+	}, nil
+}
+
+// rpcObserver Agent 使用的 RPC 观测器
+func (a *Agent) rpcObserver() *rpcObserver {
+	return &rpcObserver{logger: a.logger}
+}
+
+// observedClientStream 在流结束时回调一次
+// 服务端流以 io.EOF 或错误结束，客户端流在收到唯一的响应后结束
+type observedClientStream struct {
+	ggrpc.ClientStream
+	serverStreams bool
+	done          func(err error)
+	finished      bool
+}
+
+func (s *observedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if s.finished || (err == nil && s.serverStreams) {
+		return err
+	}
+	s.finished = true
+	if err == io.EOF {
+		s.done(nil)
+	} else {
+		s.done(err)
+	}
+	return err
+}
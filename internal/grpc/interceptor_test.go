@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	observer := &rpcObserver{logger: utils.New("InterceptorTest")}
+	info := &ggrpc.UnaryServerInfo{FullMethod: "/test.Service/Heartbeat"}
+	req := &proto.HeartbeatRequest{Id: "node-1"}
+
+	// 客户端传入的请求ID传递给 handler
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDMetadataKey, "req-1"))
+	var got string
+	_, err := observer.unaryServerInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = RequestIDFromContext(ctx)
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "req-1", got)
+
+	// handler 中的 panic 转换为 codes.Internal
+	before := rpcRequests.WithLabelValues("server", info.FullMethod, codes.Internal.String()).Value()
+	_, err = observer.unaryServerInterceptor(context.Background(), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, before+1, rpcRequests.WithLabelValues("server", info.FullMethod, codes.Internal.String()).Value())
+}
@@ -0,0 +1,342 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// outboxMaxEntries outbox 最多保留的消息数量，超出时丢弃最早的消息
+	outboxMaxEntries = 10000
+	// outboxMaxMetrics outbox 最多保留的指标数量
+	outboxMaxMetrics = 288
+	// metricsDownsampleInterval 连接中断期间相邻两条指标的最小间隔，间隔内的指标被丢弃
+	metricsDownsampleInterval = 5 * time.Minute
+	// outboxSaveEvery 重放时每发送多少条消息保存一次 outbox
+	// 重放后未及时保存的消息在重启后会再次发送，由服务端按上报序号去重
+	outboxSaveEvery = 50
+)
+
+// OutboxKind 上报消息类型
+type OutboxKind string
+
+const (
+	OutboxKindPipelineStatus OutboxKind = "pipeline_status" // 流水线状态
+	OutboxKindStepStatus     OutboxKind = "step_status"     // 步骤状态
+	OutboxKindMetrics        OutboxKind = "metrics"         // 节点指标
+)
+
+// OutboxEntry 等待发送的上报消息
+type OutboxEntry struct {
+	ID        int64      `json:"id"`         // 入队序号，单调递增
+	Kind      OutboxKind `json:"kind"`       // 消息类型
+	Payload   []byte     `json:"payload"`    // protobuf 编码的请求
+	CreatedAt time.Time  `json:"created_at"` // 入队时间
+}
+
+// ReportSender 发送一种类型的上报消息
+type ReportSender func(ctx context.Context, payload []byte) error
+
+// Outbox 保存在磁盘上的上报消息队列
+// 连接中断期间的上报按顺序写入文件，Agent 重启后仍然保留，重连后按入队顺序重放
+type Outbox struct {
+	path    string
+	logger  utils.Logger
+	mu      sync.Mutex
+	entries []*OutboxEntry
+	nextID  int64
+	dirty   int
+}
+
+// NewOutbox 创建 outbox 并加载文件中保存的消息，path 为空时只保存在内存中
+func NewOutbox(path string, logger utils.Logger) (*Outbox, error) {
+	o := &Outbox{
+		path:   path,
+		logger: logger,
+		nextID: 1,
+	}
+	if path == "" {
+		return o, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 outbox 失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &o.entries); err != nil {
+		return nil, fmt.Errorf("解析 outbox 失败: %w", err)
+	}
+	for _, entry := range o.entries {
+		if entry.ID >= o.nextID {
+			o.nextID = entry.ID + 1
+		}
+	}
+	if len(o.entries) > 0 {
+		logger.Info("已加载 %d 条待重放的上报消息", len(o.entries))
+	}
+	return o, nil
+}
+
+// Len 等待发送的消息数量
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// Add 消息入队并保存
+// 指标按 metricsDownsampleInterval 降采样，超出数量限制时丢弃最早的同类消息
+func (o *Outbox) Add(kind OutboxKind, payload []byte, now time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if kind == OutboxKindMetrics {
+		metrics := 0
+		var last *OutboxEntry
+		for _, entry := range o.entries {
+			if entry.Kind == OutboxKindMetrics {
+				metrics++
+				last = entry
+			}
+		}
+		if last != nil && now.Sub(last.CreatedAt) < metricsDownsampleInterval {
+			return nil
+		}
+		if metrics >= outboxMaxMetrics {
+			o.removeFirstLocked(OutboxKindMetrics)
+		}
+	}
+	if len(o.entries) >= outboxMaxEntries {
+		o.logger.Warn("outbox 已满，丢弃最早的 %s 消息", o.entries[0].Kind)
+		o.entries = o.entries[1:]
+	}
+
+	o.entries = append(o.entries, &OutboxEntry{
+		ID:        o.nextID,
+		Kind:      kind,
+		Payload:   payload,
+		CreatedAt: now,
+	})
+	o.nextID++
+	return o.saveLocked()
+}
+
+// Peek 获取最早的消息，队列为空时返回 nil
+func (o *Outbox) Peek() *OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.entries) == 0 {
+		return nil
+	}
+	return o.entries[0]
+}
+
+// Remove 移除已发送的消息，每 outboxSaveEvery 条或队列清空时保存
+func (o *Outbox) Remove(id int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, entry := range o.entries {
+		if entry.ID == id {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			o.dirty++
+			break
+		}
+	}
+	if o.dirty >= outboxSaveEvery || len(o.entries) == 0 {
+		return o.saveLocked()
+	}
+	return nil
+}
+
+// removeFirstLocked 移除最早的指定类型消息，调用方需持有锁
+func (o *Outbox) removeFirstLocked(kind OutboxKind) {
+	for i, entry := range o.entries {
+		if entry.Kind == kind {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// saveLocked 将队列写入文件，调用方需持有锁
+func (o *Outbox) saveLocked() error {
+	o.dirty = 0
+	if o.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(o.entries)
+	if err != nil {
+		return fmt.Errorf("序列化 outbox 失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0700); err != nil {
+		return fmt.Errorf("创建 outbox 目录失败: %w", err)
+	}
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存 outbox 失败: %w", err)
+	}
+	if err := os.Rename(tmp, o.path); err != nil {
+		return fmt.Errorf("保存 outbox 失败: %w", err)
+	}
+	return nil
+}
+
+// HandleReport 注册一种上报消息的发送函数
+func (a *Agent) HandleReport(kind OutboxKind, sender ReportSender) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reportSenders[kind] = sender
+}
+
+// Report 上报消息，同一 Agent 的上报按提交顺序到达服务端
+// 连接正常且没有积压时直接发送；连接中断或存在积压时写入 outbox，重连后按顺序重放
+func (a *Agent) Report(ctx context.Context, kind OutboxKind, msg protobuf.Message) error {
+	payload, err := protobuf.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化上报消息失败: %w", err)
+	}
+
+	a.reportMu.Lock()
+	defer a.reportMu.Unlock()
+
+	if a.outbox.Len() == 0 && a.IsConnected() {
+		err := a.sendReport(ctx, kind, payload)
+		if err == nil || IsRejectedReportError(err) {
+			return err
+		}
+		if IsConnectionError(err) {
+			a.MarkDisconnected(err)
+		} else {
+			// 暂时性错误：写入 outbox 后按退避策略重放
+			a.logger.Warn("发送 %s 消息失败，稍后重试: %v", kind, err)
+			defer a.scheduleReplay(a.replayContext())
+		}
+	}
+
+	if err := a.outbox.Add(kind, payload, time.Now()); err != nil {
+		return err
+	}
+	a.logger.Debug("%s 消息已写入 outbox，等待重连后发送", kind)
+	return nil
+}
+
+// startReplay 启动 outbox 重放，已在重放或没有积压时不做处理
+func (a *Agent) startReplay(ctx context.Context) {
+	a.reportMu.Lock()
+	defer a.reportMu.Unlock()
+	if a.replaying || a.outbox.Len() == 0 {
+		return
+	}
+	a.replaying = true
+	go a.replayOutbox(ctx)
+}
+
+// scheduleReplay 按退避策略稍后重放积压的消息，调用方需持有 reportMu
+// 等待期间视为正在重放，重连不会提前触发重放
+func (a *Agent) scheduleReplay(ctx context.Context) {
+	if a.replaying || ctx.Err() != nil {
+		return
+	}
+	delay := calculateDelay(a.replayAttempt, ReconnectRetryConfig)
+	a.replayAttempt++
+	a.replaying = true
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		a.reportMu.Lock()
+		a.replaying = false
+		a.reportMu.Unlock()
+		// 未连接时等待重连后触发重放
+		if ctx.Err() == nil && a.IsConnected() {
+			a.startReplay(ctx)
+		}
+	}()
+}
+
+// replayContext 延迟重放使用的上下文，Agent 未启动时使用 Background
+func (a *Agent) replayContext() context.Context {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.runCtx != nil {
+		return a.runCtx
+	}
+	return context.Background()
+}
+
+// replayOutbox 按入队顺序发送积压的上报消息
+// 连接错误时停止重放等待下次重连；认证失败、限流等暂时性错误保留消息并按退避策略稍后重放；
+// 只有服务端明确拒绝的消息（参数错误、流水线已删除、前置条件不满足）被丢弃
+func (a *Agent) replayOutbox(ctx context.Context) {
+	a.logger.Info("开始重放 %d 条积压的上报消息", a.outbox.Len())
+	sent := 0
+	for {
+		a.reportMu.Lock()
+		entry := a.outbox.Peek()
+		if entry == nil {
+			a.replaying = false
+			a.reportMu.Unlock()
+			a.logger.Info("积压的上报消息已重放完成, 共 %d 条", sent)
+			return
+		}
+
+		err := a.sendReport(ctx, entry.Kind, entry.Payload)
+		if err != nil && IsConnectionError(err) {
+			a.replaying = false
+			a.reportMu.Unlock()
+			a.logger.Warn("重放上报消息时连接中断: %v", err)
+			a.MarkDisconnected(err)
+			return
+		}
+		if err != nil && !IsRejectedReportError(err) {
+			a.replaying = false
+			if ctx.Err() == nil {
+				a.logger.Warn("重放 %s 消息 #%d 失败，稍后重试: %v", entry.Kind, entry.ID, err)
+				a.scheduleReplay(ctx)
+			}
+			a.reportMu.Unlock()
+			return
+		}
+		if err != nil {
+			a.logger.Warn("服务端拒绝 %s 消息 #%d, 已丢弃: %v", entry.Kind, entry.ID, err)
+		} else {
+			sent++
+			a.replayAttempt = 0
+		}
+		if err := a.outbox.Remove(entry.ID); err != nil {
+			a.logger.Error("更新 outbox 失败: %v", err)
+		}
+		a.reportMu.Unlock()
+	}
+}
+
+// sendReport 使用注册的发送函数发送消息
+func (a *Agent) sendReport(ctx context.Context, kind OutboxKind, payload []byte) error {
+	a.mu.RLock()
+	sender, ok := a.reportSenders[kind]
+	a.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("未注册 %s 消息的发送函数", kind)
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, defaultRPCTimeout)
+	defer cancel()
+	return sender(rpcCtx, payload)
+}
@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestOutboxPersistAndDownsample(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	logger := utils.New("OutboxTest")
+	now := time.Now()
+
+	outbox, err := NewOutbox(path, logger)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(OutboxKindMetrics, []byte("m1"), now))
+	// 降采样间隔内的指标被丢弃，状态不受影响
+	require.NoError(t, outbox.Add(OutboxKindMetrics, []byte("m2"), now.Add(time.Minute)))
+	require.NoError(t, outbox.Add(OutboxKindStepStatus, []byte("s1"), now.Add(time.Minute)))
+	require.NoError(t, outbox.Add(OutboxKindMetrics, []byte("m3"), now.Add(metricsDownsampleInterval)))
+
+	// 重新加载后顺序和入队序号保持不变
+	reloaded, err := NewOutbox(path, logger)
+	require.NoError(t, err)
+	require.Equal(t, 3, reloaded.Len())
+	var payloads []string
+	for entry := reloaded.Peek(); entry != nil; entry = reloaded.Peek() {
+		payloads = append(payloads, string(entry.Payload))
+		require.NoError(t, reloaded.Remove(entry.ID))
+	}
+	assert.Equal(t, []string{"m1", "s1", "m3"}, payloads)
+
+	require.NoError(t, reloaded.Add(OutboxKindStepStatus, []byte("s2"), now))
+	assert.EqualValues(t, 4, reloaded.Peek().ID)
+}
+
+func TestAgentReportReplaysInOrder(t *testing.T) {
+	logger := utils.New("OutboxTest")
+	outbox, err := NewOutbox("", logger)
+	require.NoError(t, err)
+	agent := &Agent{
+		logger:        logger,
+		connState:     ConnectionStateDisconnected,
+		lost:          make(chan struct{}, 1),
+		outbox:        outbox,
+		reportSenders: make(map[OutboxKind]ReportSender),
+	}
+
+	var sent []int64
+	agent.HandleReport(OutboxKindStepStatus, func(ctx context.Context, payload []byte) error {
+		req := &proto.UpdateStepStatusRequest{}
+		require.NoError(t, protobuf.Unmarshal(payload, req))
+		if req.StepId == "rejected" {
+			return status.Error(codes.NotFound, "流水线不存在")
+		}
+		sent = append(sent, req.Sequence)
+		return nil
+	})
+
+	// 连接中断期间的上报写入 outbox
+	ctx := context.Background()
+	require.NoError(t, agent.Report(ctx, OutboxKindStepStatus, &proto.UpdateStepStatusRequest{StepId: "a", Sequence: 1}))
+	require.NoError(t, agent.Report(ctx, OutboxKindStepStatus, &proto.UpdateStepStatusRequest{StepId: "rejected", Sequence: 2}))
+	require.NoError(t, agent.Report(ctx, OutboxKindStepStatus, &proto.UpdateStepStatusRequest{StepId: "b", Sequence: 3}))
+	assert.Empty(t, sent)
+	assert.Equal(t, 3, outbox.Len())
+
+	// 重连后按顺序重放，服务端拒绝的消息被丢弃
+	agent.setConnState(ConnectionStateConnected, nil)
+	agent.replaying = true
+	agent.replayOutbox(ctx)
+	assert.Equal(t, []int64{1, 3}, sent)
+	assert.Equal(t, 0, outbox.Len())
+
+	// 没有积压时直接发送
+	require.NoError(t, agent.Report(ctx, OutboxKindStepStatus, &proto.UpdateStepStatusRequest{StepId: "c", Sequence: 4}))
+	assert.Equal(t, []int64{1, 3, 4}, sent)
+}
+
+func TestAgentReplayKeepsTemporaryFailures(t *testing.T) {
+	logger := utils.New("OutboxTest")
+	outbox, err := NewOutbox("", logger)
+	require.NoError(t, err)
+	agent := &Agent{
+		logger:        logger,
+		connState:     ConnectionStateConnected,
+		lost:          make(chan struct{}, 1),
+		outbox:        outbox,
+		reportSenders: make(map[OutboxKind]ReportSender),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agent.runCtx = ctx
+
+	// 凭证轮换期间服务端返回认证失败，随后恢复
+	failure := status.Error(codes.Unauthenticated, "凭证无效")
+	var sent []int64
+	agent.HandleReport(OutboxKindStepStatus, func(ctx context.Context, payload []byte) error {
+		if failure != nil {
+			return failure
+		}
+		req := &proto.UpdateStepStatusRequest{}
+		require.NoError(t, protobuf.Unmarshal(payload, req))
+		sent = append(sent, req.Sequence)
+		return nil
+	})
+
+	// 直接发送失败的消息写入 outbox，后续消息排在其后
+	require.NoError(t, agent.Report(ctx, OutboxKindStepStatus, &proto.UpdateStepStatusRequest{Sequence: 1}))
+	require.NoError(t, agent.Report(ctx, OutboxKindStepStatus, &proto.UpdateStepStatusRequest{Sequence: 2}))
+	assert.Equal(t, 2, outbox.Len())
+
+	// 重放遇到暂时性错误时保留消息
+	for _, code := range []codes.Code{codes.ResourceExhausted, codes.Internal, codes.PermissionDenied} {
+		failure = status.Error(code, "暂时失败")
+		agent.reportMu.Lock()
+		agent.replaying = true
+		agent.reportMu.Unlock()
+		agent.replayOutbox(ctx)
+		assert.Equal(t, 2, outbox.Len(), code.String())
+	}
+	assert.Empty(t, sent)
+
+	failure = nil
+	agent.reportMu.Lock()
+	agent.replaying = true
+	agent.reportMu.Unlock()
+	agent.replayOutbox(ctx)
+	assert.Equal(t, []int64{1, 2}, sent)
+	assert.Equal(t, 0, outbox.Len())
+	assert.Equal(t, 0, agent.replayAttempt)
+}
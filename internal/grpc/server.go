@@ -5,13 +5,19 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/open-beagle/beagle-wind-game/internal/proto"
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
+// serverDrainTimeout 停止服务后等待 Agent 切换到其他服务端的最长时间，超时后强制关闭连接
+const serverDrainTimeout = 10 * time.Second
+
 // GRPCServer 统一的 gRPC 服务器管理类
 type GRPCServer struct {
 	// 服务器配置
@@ -24,9 +30,15 @@ type GRPCServer struct {
 	nodeServer     *GameNodeServer
 	pipelineServer *GamePipelineServer
 
+	// 节点连接注册表，由两个子服务器共用
+	connections *ConnectionRegistry
+
 	// 服务器实例
 	server *ggrpc.Server
 
+	// 健康检查服务，停止时设置为 NOT_SERVING，通知 Agent 切换到其他服务端
+	health *health.Server
+
 	// 同步原语
 	mu   sync.RWMutex
 	done chan struct{}
@@ -38,33 +50,59 @@ func NewGRPCServer(
 	pipelineService GamePipelineServiceInterface,
 	logger utils.Logger,
 	config *ServerConfig,
-) *GRPCServer {
-	// 创建 gRPC 服务器实例
-	server := ggrpc.NewServer()
+) (*GRPCServer, error) {
+	// 创建 gRPC 服务器实例，节点ID与客户端证书的一致性、节点凭证由拦截器校验
+	// 最外层拦截器记录请求日志和指标，并将 panic 转换为 codes.Internal
+	auth := &nodeAuthenticator{nodeService: nodeService}
+	observer := &rpcObserver{logger: logger}
+	serverOpts := []ggrpc.ServerOption{
+		ggrpc.ChainUnaryInterceptor(observer.unaryServerInterceptor, nodeIdentityUnaryInterceptor, auth.unaryInterceptor),
+		ggrpc.ChainStreamInterceptor(observer.streamServerInterceptor),
+	}
+	if config.TLS.Enabled() {
+		creds, err := NewServerCredentials(config.TLS, logger)
+		if err != nil {
+			return nil, fmt.Errorf("创建 TLS 凭证失败: %w", err)
+		}
+		serverOpts = append(serverOpts, ggrpc.Creds(creds))
+		logger.Info("gRPC 服务器已启用双向 TLS")
+	} else {
+		logger.Warn("gRPC 服务器未配置 TLS，使用明文连接")
+	}
+	server := ggrpc.NewServer(serverOpts...)
+
+	// 节点的心跳和 PipelineStream 会话记录在同一个连接注册表中
+	connections := NewConnectionRegistry(nodeService, config.OfflineGracePeriod, logger)
 
 	// 创建节点服务器
 	nodeServer := NewGameNodeServer(
 		nodeService,
 		pipelineService,
+		connections,
 		logger,
 		config,
 	)
 
 	// 创建 Pipeline 服务器
-	pipelineServer := NewGamePipelineServer(logger)
+	pipelineServer := NewGamePipelineServer(pipelineService, connections, logger)
+	pipelineServer.auth = auth
 
 	// 注册服务
 	proto.RegisterGameNodeGRPCServiceServer(server, nodeServer)
 	proto.RegisterGamePipelineGRPCServiceServer(server, pipelineServer)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
 
 	return &GRPCServer{
 		config:         config,
 		logger:         logger,
 		nodeServer:     nodeServer,
 		pipelineServer: pipelineServer,
+		connections:    connections,
 		server:         server,
+		health:         healthServer,
 		done:           make(chan struct{}),
-	}
+	}, nil
 }
 
 // Start 启动 gRPC 服务器
@@ -77,6 +115,9 @@ func (s *GRPCServer) Start(ctx context.Context, listenAddr string) error {
 
 	s.logger.Info("gRPC 服务器开始监听地址: %s", listenAddr)
 
+	// 启动连接检查，关闭心跳超时的会话并标记离线节点
+	go s.connections.Run(ctx)
+
 	// 启动服务器
 	go func() {
 		if err := s.server.Serve(listener); err != nil {
@@ -89,7 +130,7 @@ func (s *GRPCServer) Start(ctx context.Context, listenAddr string) error {
 	s.logger.Info("收到停止信号，正在关闭服务器")
 
 	// 优雅关闭
-	s.server.GracefulStop()
+	s.drain()
 	return nil
 }
 
@@ -98,28 +139,43 @@ func (s *GRPCServer) Stop() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 关闭子服务器
-	if s.nodeServer != nil {
-		s.nodeServer.Stop()
-	}
-	if s.pipelineServer != nil {
-		s.pipelineServer.Stop()
-	}
-
 	// 关闭主服务器
 	if s.server != nil {
-		s.server.GracefulStop()
+		s.drain()
 	}
 
 	close(s.done)
 	return nil
 }
 
+// drain 将健康状态设置为 NOT_SERVING 后优雅关闭
+// Agent 收到状态变化后切换到其他服务端并关闭 PipelineStream，超过 serverDrainTimeout 仍未结束的连接被强制关闭
+func (s *GRPCServer) drain() {
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(serverDrainTimeout):
+		s.logger.Warn("等待连接关闭超时，强制关闭服务器")
+		s.server.Stop()
+	}
+}
+
 // GetNodeServer 获取节点服务器实例
 func (s *GRPCServer) GetNodeServer() *GameNodeServer {
 	return s.nodeServer
 }
 
+// GetConnections 获取节点连接注册表
+func (s *GRPCServer) GetConnections() *ConnectionRegistry {
+	return s.connections
+}
+
 // GetPipelineServer 获取 Pipeline 服务器实例
 func (s *GRPCServer) GetPipelineServer() *GamePipelineServer {
 	return s.pipelineServer
@@ -0,0 +1,189 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// stepLogChunkSize 单个日志块的最大字节数
+	stepLogChunkSize = 32 << 10
+	// stepLogQueueSize 等待上传的日志写入数
+	stepLogQueueSize = 64
+	// stepLogSendAttempts 单个日志块的最大发送次数
+	stepLogSendAttempts = 3
+)
+
+// stepLogUploader 通过 StreamStepLogs 上传单个步骤的日志
+// 连接中断时重新建立日志流并从当前偏移继续上传，服务端按偏移去重；
+// 多次重试失败的日志块被丢弃，服务端会记录缺失的字节数
+type stepLogUploader struct {
+	ctx        context.Context
+	client     proto.GamePipelineGRPCServiceClient
+	logger     utils.Logger
+	nodeID     string
+	pipelineID string
+	stepID     string
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan []byte
+	done   chan struct{}
+
+	// 以下字段只在上传协程中访问
+	stream  proto.GamePipelineGRPCService_StreamStepLogsClient
+	offset  int64
+	dropped int64
+	failed  bool
+}
+
+// newStepLogUploader 创建步骤日志上传器并启动上传协程
+func newStepLogUploader(ctx context.Context, agent *Agent, pipelineID, stepID string) *stepLogUploader {
+	u := &stepLogUploader{
+		ctx:        ctx,
+		client:     agent.GetPipelineClient(),
+		logger:     agent.GetLogger(),
+		nodeID:     agent.id,
+		pipelineID: pipelineID,
+		stepID:     stepID,
+		queue:      make(chan []byte, stepLogQueueSize),
+		done:       make(chan struct{}),
+	}
+	go u.run()
+	return u
+}
+
+// Write 实现 io.Writer，数据被复制后放入上传队列
+func (u *stepLogUploader) Write(p []byte) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed {
+		return 0, io.ErrClosedPipe
+	}
+	buf := make([]byte, len(p))
+	copy(buf, p)
+	u.queue <- buf
+	return len(p), nil
+}
+
+// Close 标记步骤日志结束，等待剩余日志上传完成
+func (u *stepLogUploader) Close() error {
+	u.mu.Lock()
+	if u.closed {
+		u.mu.Unlock()
+		return nil
+	}
+	u.closed = true
+	close(u.queue)
+	u.mu.Unlock()
+
+	<-u.done
+	return nil
+}
+
+// run 上传协程，合并队列中的小块后发送
+func (u *stepLogUploader) run() {
+	defer close(u.done)
+
+	for data := range u.queue {
+		// 合并已在队列中的写入，减少消息数量
+	merge:
+		for len(data) < stepLogChunkSize {
+			select {
+			case more, ok := <-u.queue:
+				if !ok {
+					break merge
+				}
+				data = append(data, more...)
+			default:
+				break merge
+			}
+		}
+		for len(data) > 0 {
+			n := min(len(data), stepLogChunkSize)
+			u.send(data[:n], false)
+			data = data[n:]
+		}
+	}
+
+	u.send(nil, true)
+	if u.stream != nil {
+		if _, err := u.stream.CloseAndRecv(); err != nil {
+			u.logger.Warn("步骤 %s/%s 日志上传结束时出错: %v", u.pipelineID, u.stepID, err)
+		}
+	}
+	if u.dropped > 0 {
+		u.logger.Warn("步骤 %s/%s 有 %d 字节日志未能上传", u.pipelineID, u.stepID, u.dropped)
+	}
+}
+
+// send 发送一个日志块，连接错误时重建日志流并重试
+func (u *stepLogUploader) send(data []byte, eof bool) {
+	chunk := &proto.StepLogChunk{
+		NodeId:     u.nodeID,
+		PipelineId: u.pipelineID,
+		StepId:     u.stepID,
+		Offset:     u.offset,
+		Data:       data,
+		Eof:        eof,
+	}
+	u.offset += int64(len(data))
+
+	if u.failed {
+		u.dropped += int64(len(data))
+		return
+	}
+
+	for attempt := 1; attempt <= stepLogSendAttempts; attempt++ {
+		err := u.trySend(chunk)
+		if err == nil {
+			return
+		}
+		if !IsConnectionError(err) {
+			// 服务端拒绝（如流水线不属于本节点），后续日志不再上传
+			u.logger.Error("步骤 %s/%s 日志被服务端拒绝: %v", u.pipelineID, u.stepID, err)
+			u.failed = true
+			break
+		}
+		u.logger.Warn("第 %d 次上传步骤 %s/%s 日志失败: %v", attempt, u.pipelineID, u.stepID, err)
+
+		select {
+		case <-u.ctx.Done():
+			u.failed = true
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+		if u.failed {
+			break
+		}
+	}
+	u.dropped += int64(len(data))
+}
+
+// trySend 在当前日志流上发送，日志流不存在或已断开时重新建立
+func (u *stepLogUploader) trySend(chunk *proto.StepLogChunk) error {
+	if u.stream == nil {
+		stream, err := u.client.StreamStepLogs(u.ctx)
+		if err != nil {
+			return err
+		}
+		u.stream = stream
+	}
+
+	err := u.stream.Send(chunk)
+	if err == io.EOF {
+		// 流已被服务端结束，真实错误通过 CloseAndRecv 获取
+		_, err = u.stream.CloseAndRecv()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil {
+		u.stream = nil
+	}
+	return err
+}
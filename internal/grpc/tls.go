@@ -0,0 +1,248 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/open-beagle/beagle-wind-game/internal/proto"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// TLSConfig 双向 TLS 配置
+// 服务端：CertFile/KeyFile 为服务端证书，CAFile 用于校验节点的客户端证书
+// Agent：CertFile/KeyFile 为节点证书，CAFile 用于校验服务端证书
+type TLSConfig struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// ServerName Agent 校验服务端证书时使用的名称，为空时使用连接地址中的主机名
+	ServerName string
+}
+
+// Enabled 是否启用 TLS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != ""
+}
+
+// validate 双向 TLS 要求证书、私钥和 CA 同时配置
+func (c TLSConfig) validate() error {
+	if c.CertFile == "" || c.KeyFile == "" || c.CAFile == "" {
+		return fmt.Errorf("双向 TLS 需要同时配置 CA、证书和私钥")
+	}
+	return nil
+}
+
+// certReloader 证书加载器，文件修改后在下一次握手时重新加载，无需重启进程
+type certReloader struct {
+	cfg    TLSConfig
+	logger utils.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// newCertReloader 创建证书加载器并立即加载一次
+func newCertReloader(cfg TLSConfig, logger utils.Logger) (*certReloader, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	r := &certReloader{cfg: cfg, logger: logger}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// latestModTime 获取证书文件中最新的修改时间
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reload 加载证书、私钥和 CA
+func (r *certReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return fmt.Errorf("读取证书文件失败: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("加载证书失败: %w", err)
+	}
+
+	caData, err := os.ReadFile(r.cfg.CAFile)
+	if err != nil {
+		return fmt.Errorf("读取 CA 证书失败: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return fmt.Errorf("解析 CA 证书失败: %s", r.cfg.CAFile)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.pool = pool
+	r.modTime = modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// current 获取当前证书，文件有更新时先重新加载；加载失败时继续使用旧证书
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	if modTime, err := r.latestModTime(); err == nil {
+		r.mu.RLock()
+		changed := modTime.After(r.modTime)
+		r.mu.RUnlock()
+		if changed {
+			if err := r.reload(); err != nil {
+				r.logger.Error("重新加载证书失败，继续使用旧证书: %v", err)
+			} else {
+				r.logger.Info("证书已重新加载: %s", r.cfg.CertFile)
+			}
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+// NewServerCredentials 创建服务端双向 TLS 凭证，要求节点提供由 CA 签发的客户端证书
+func NewServerCredentials(cfg TLSConfig, logger utils.Logger) (credentials.TransportCredentials, error) {
+	r, err := newCertReloader(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	}), nil
+}
+
+// NewClientCredentials 创建 Agent 双向 TLS 凭证
+// 节点证书在每次握手时按需重新加载，CA 证书在创建连接时加载
+func NewClientCredentials(cfg TLSConfig, logger utils.Logger) (credentials.TransportCredentials, error) {
+	r, err := newCertReloader(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	_, pool := r.current()
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    pool,
+		ServerName: cfg.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+	}), nil
+}
+
+// certMatchesNode 证书的 CN 或 DNS SAN 是否与节点ID一致
+func certMatchesNode(cert *x509.Certificate, nodeID string) bool {
+	if nodeID == "" {
+		return false
+	}
+	if cert.Subject.CommonName == nodeID {
+		return true
+	}
+	for _, name := range cert.DNSNames {
+		if name == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeNode 校验请求中的节点ID与客户端证书一致
+// 未启用 TLS 的连接不做校验
+func authorizeNode(ctx context.Context, nodeID string) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+
+	certs := tlsInfo.State.PeerCertificates
+	if len(tlsInfo.State.VerifiedChains) > 0 && len(tlsInfo.State.VerifiedChains[0]) > 0 {
+		certs = tlsInfo.State.VerifiedChains[0]
+	}
+	if len(certs) == 0 {
+		return status.Error(codes.Unauthenticated, "缺少客户端证书")
+	}
+	if !certMatchesNode(certs[0], nodeID) {
+		return status.Errorf(codes.PermissionDenied, "客户端证书 %s 与节点ID %s 不匹配", certs[0].Subject.CommonName, nodeID)
+	}
+	return nil
+}
+
+// requestNodeID 获取请求中声明的节点ID
+func requestNodeID(req interface{}) (string, bool) {
+	switch r := req.(type) {
+	case *proto.RegisterRequest:
+		return r.GetId(), true
+	case *proto.HeartbeatRequest:
+		return r.GetId(), true
+	case *proto.MetricsRequest:
+		return r.GetId(), true
+	case *proto.ResourceRequest:
+		return r.GetNodeId(), true
+	case *proto.ContainersRequest:
+		return r.GetNodeId(), true
+	case *proto.StateChangeRequest:
+		return r.GetNodeId(), true
+	case *proto.UpdatePipelineStatusRequest:
+		return r.GetStatus().GetNodeId(), true
+	case *proto.UpdateStepStatusRequest:
+		return r.GetNodeId(), true
+	default:
+		return "", false
+	}
+}
+
+// nodeIdentityUnaryInterceptor 校验一元请求中的节点ID与客户端证书一致
+func nodeIdentityUnaryInterceptor(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (interface{}, error) {
+	if nodeID, ok := requestNodeID(req); ok {
+		if err := authorizeNode(ctx, nodeID); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// writeTestCert 生成由 ca 签发（ca 为空时自签名）的证书并写入 dir
+func writeTestCert(t *testing.T, dir, name, cn string, dnsNames []string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	parent, parentKey := tmpl, key
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		parent, parentKey = ca, caKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func TestCertMatchesNode(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "node-1"},
+		DNSNames: []string{"node-1.example.com", "node-1-alias"},
+	}
+
+	assert.True(t, certMatchesNode(cert, "node-1"))
+	assert.True(t, certMatchesNode(cert, "node-1-alias"))
+	assert.False(t, certMatchesNode(cert, "node-2"))
+	assert.False(t, certMatchesNode(cert, ""))
+}
+
+func TestCertReloaderReload(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeTestCert(t, dir, "ca", "test-ca", nil, nil, nil)
+	writeTestCert(t, dir, "node", "node-1", nil, ca, caKey)
+
+	r, err := newCertReloader(TLSConfig{
+		CAFile:   filepath.Join(dir, "ca.crt"),
+		CertFile: filepath.Join(dir, "node.crt"),
+		KeyFile:  filepath.Join(dir, "node.key"),
+	}, utils.New("TLSTest"))
+	require.NoError(t, err)
+
+	cert, _ := r.current()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "node-1", leaf.Subject.CommonName)
+
+	// 替换证书文件后下一次获取应返回新证书
+	writeTestCert(t, dir, "node", "node-2", nil, ca, caKey)
+	future := time.Now().Add(time.Minute)
+	for _, name := range []string{"node.crt", "node.key"} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), future, future))
+	}
+
+	cert, _ = r.current()
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "node-2", leaf.Subject.CommonName)
+}
+
+func TestTLSConfigValidate(t *testing.T) {
+	assert.False(t, TLSConfig{}.Enabled())
+	assert.True(t, TLSConfig{CertFile: "a"}.Enabled())
+	assert.Error(t, TLSConfig{CertFile: "a", KeyFile: "b"}.validate())
+	assert.NoError(t, TLSConfig{CertFile: "a", KeyFile: "b", CAFile: "c"}.validate())
+}
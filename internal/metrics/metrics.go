@@ -0,0 +1,327 @@
+// Package metrics 提供计数器和直方图，以 Prometheus 文本格式输出
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets 默认的延迟直方图分桶，单位为秒
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default 默认的指标注册表
+var Default = NewRegistry()
+
+// collector 可输出到注册表的指标
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.Mutex
+	names      map[string]bool
+	collectors []collector
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register 注册指标，同名指标重复注册时 panic
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic(fmt.Sprintf("指标 %s 重复注册", name))
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo 以 Prometheus 文本格式输出所有指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector{}, r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler 提供 /metrics 接口
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// Handler 提供默认注册表的 /metrics 接口
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// NewCounterVec 在默认注册表中创建带标签的计数器
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// NewHistogramVec 在默认注册表中创建带标签的直方图，buckets 为空时使用 DefaultBuckets
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*Counter
+}
+
+// Counter 单个计数器
+type Counter struct {
+	values []string
+
+	mu    sync.Mutex
+	value float64
+}
+
+// NewCounterVec 创建带标签的计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		series: make(map[string]*Counter),
+	}
+	r.register(name, c)
+	return c
+}
+
+// WithLabelValues 获取标签取值对应的计数器，取值数量必须与标签数量一致
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := seriesKey(v.name, v.labels, values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.series[key]
+	if !ok {
+		c = &Counter{values: values}
+		v.series[key] = c
+	}
+	return c
+}
+
+// Inc 计数加一
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add 计数增加 delta，delta 不能为负数
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.value += delta
+	c.mu.Unlock()
+}
+
+// Value 当前计数
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", v.name, v.help, v.name)
+	for _, c := range v.sortedSeries() {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, c.values, "", ""), formatFloat(c.Value()))
+	}
+}
+
+// sortedSeries 按标签取值排序的计数器，保证输出稳定
+func (v *CounterVec) sortedSeries() []*Counter {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]*Counter, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, v.series[k])
+	}
+	return result
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*Histogram
+}
+
+// Histogram 单个直方图
+type Histogram struct {
+	values  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec 创建带标签的直方图，buckets 为空时使用 DefaultBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*Histogram),
+	}
+	r.register(name, h)
+	return h
+}
+
+// WithLabelValues 获取标签取值对应的直方图，取值数量必须与标签数量一致
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := seriesKey(v.name, v.labels, values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	h, ok := v.series[key]
+	if !ok {
+		h = &Histogram{
+			values:  values,
+			buckets: v.buckets,
+			counts:  make([]uint64, len(v.buckets)),
+		}
+		v.series[key] = h
+	}
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, upper := range h.buckets {
+		if value <= upper {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", v.name, v.help, v.name)
+
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	series := make([]*Histogram, 0, len(keys))
+	for _, k := range keys {
+		series = append(series, v.series[k])
+	}
+	v.mu.Unlock()
+
+	for _, h := range series {
+		h.mu.Lock()
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, h.values, "le", formatFloat(upper)), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, h.values, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, formatLabels(v.labels, h.values, "", ""), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, formatLabels(v.labels, h.values, "", ""), h.count)
+		h.mu.Unlock()
+	}
+}
+
+// seriesKey 标签取值组成的序列键，取值数量与标签数量不一致时 panic
+func seriesKey(name string, labels, values []string) string {
+	if len(labels) != len(values) {
+		panic(fmt.Sprintf("指标 %s 需要 %d 个标签取值, 实际 %d 个", name, len(labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels 输出 {k="v",...}，extraKey 非空时追加一个标签（如直方图的 le）
+func formatLabels(labels, values []string, extraKey, extraValue string) string {
+	if len(labels) == 0 && extraKey == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", label, values[i])
+	}
+	if extraKey != "" {
+		if len(labels) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", extraKey, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatFloat 按 Prometheus 文本格式输出浮点数
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "请求数", "method", "code")
+	latency := r.NewHistogramVec("test_duration_seconds", "请求耗时", []float64{0.1, 1}, "method")
+
+	requests.WithLabelValues("/a", "OK").Inc()
+	requests.WithLabelValues("/a", "OK").Add(2)
+	requests.WithLabelValues("/b", "Internal").Inc()
+	latency.WithLabelValues("/a").Observe(0.05)
+	latency.WithLabelValues("/a").Observe(0.5)
+	latency.WithLabelValues("/a").Observe(3)
+
+	var buf bytes.Buffer
+	_, err := r.WriteTo(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `# HELP test_requests_total 请求数
+# TYPE test_requests_total counter
+test_requests_total{method="/a",code="OK"} 3
+test_requests_total{method="/b",code="Internal"} 1
+# HELP test_duration_seconds 请求耗时
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{method="/a",le="0.1"} 1
+test_duration_seconds_bucket{method="/a",le="1"} 2
+test_duration_seconds_bucket{method="/a",le="+Inf"} 3
+test_duration_seconds_sum{method="/a"} 3.55
+test_duration_seconds_count{method="/a"} 3
+`, buf.String())
+
+	assert.Panics(t, func() { r.NewCounterVec("test_requests_total", "重复") })
+	assert.Panics(t, func() { requests.WithLabelValues("/a") })
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AccessScope 访问令牌的作用范围
+type AccessScope string
+
+const (
+	AccessScopeInstance AccessScope = "instance" // 访问游戏实例的桌面
+	AccessScopeNode     AccessScope = "node"     // 访问节点
+)
+
+// AccessClaims 访问令牌携带的声明，由服务端使用 HMAC-SHA256 签名
+type AccessClaims struct {
+	Scope      AccessScope `json:"scope"`          // 作用范围
+	Target     string      `json:"target"`         // 实例ID或节点ID
+	UserID     string      `json:"user"`           // 令牌所属用户
+	NodeID     string      `json:"node"`           // 签发时实例所在的节点
+	Port       int         `json:"port,omitempty"` // 签发时实例在节点上的端口
+	Generation int         `json:"gen"`            // 签发时的令牌代数，刷新后旧代数的令牌失效
+	IssuedAt   int64       `json:"iat"`            // 签发时间（Unix 秒）
+	ExpiresAt  int64       `json:"exp"`            // 过期时间（Unix 秒）
+}
+
+// AccessGrant 用户在某个作用范围上的令牌代数，只保存刷新过的范围
+type AccessGrant struct {
+	Scope      AccessScope `json:"scope" yaml:"scope"`
+	Target     string      `json:"target" yaml:"target"`
+	UserID     string      `json:"user_id" yaml:"user_id"`
+	Generation int         `json:"generation" yaml:"generation"`
+	UpdatedAt  time.Time   `json:"updated_at" yaml:"updated_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// NodeConnection 节点与服务端的连接信息，只保存在内存中
+type NodeConnection struct {
+	NodeID          string     `json:"node_id"`                  // 节点ID
+	Online          bool       `json:"online"`                   // 是否在线，超过离线宽限期未收到消息时为 false
+	RemoteAddr      string     `json:"remote_addr,omitempty"`    // 最近一次请求的来源地址
+	ConnectedAt     *time.Time `json:"connected_at,omitempty"`   // 本次上线时间
+	LastHeartbeat   *time.Time `json:"last_heartbeat,omitempty"` // 最近一次 Heartbeat 请求时间
+	LastSeen        time.Time  `json:"last_seen"`                // 最近一次收到节点消息的时间
+	OfflineAt       *time.Time `json:"offline_at,omitempty"`     // 标记离线的时间
+	StreamConnected bool       `json:"stream_connected"`         // 是否已建立 PipelineStream 会话
+	StreamSince     *time.Time `json:"stream_since,omitempty"`   // 会话建立时间
+	Pipelines       []string   `json:"pipelines,omitempty"`      // 节点报告的正在执行的流水线
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// DiagnosticsState 诊断包收集状态
+type DiagnosticsState string
+
+const (
+	DiagnosticsStatePending   DiagnosticsState = "pending"   // 已下发命令，等待节点上传
+	DiagnosticsStateCompleted DiagnosticsState = "completed" // 上传完成，可以下载
+	DiagnosticsStateFailed    DiagnosticsState = "failed"    // 收集或上传失败
+)
+
+// DiagnosticsBundle 节点诊断包
+// 诊断包为 tar.gz 文件，包含 Agent 配置、最近日志、容器信息、系统信息和磁盘占用
+type DiagnosticsBundle struct {
+	ID          string           `json:"id" yaml:"id"`                                         // 诊断请求ID
+	NodeID      string           `json:"node_id" yaml:"node_id"`                               // 节点ID
+	State       DiagnosticsState `json:"state" yaml:"state"`                                   // 收集状态
+	Error       string           `json:"error,omitempty" yaml:"error,omitempty"`               // 失败原因
+	Size        int64            `json:"size" yaml:"size"`                                     // 诊断包大小（字节）
+	CreatedAt   time.Time        `json:"created_at" yaml:"created_at"`                         // 请求时间
+	CompletedAt *time.Time       `json:"completed_at,omitempty" yaml:"completed_at,omitempty"` // 完成或失败时间
+}
@@ -23,6 +23,13 @@ type GameCard struct {
 	Permissions string    `json:"permissions" yaml:"permissions"`   // 权限控制
 	CreatedAt   time.Time `json:"created_at" yaml:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" yaml:"updated_at"`
+
+	// Requirements 运行游戏需要的节点资源，覆盖平台声明的资源要求
+	Requirements *ResourceRequirements `json:"requirements,omitempty" yaml:"requirements,omitempty"`
+
+	// SavePaths 游戏存档所在的目录或文件，相对于游戏容器的 /home/ubuntu，
+	// 启动前恢复用户的最新存档，停止或崩溃后上传到对象存储
+	SavePaths []string `json:"save_paths,omitempty" yaml:"save_paths,omitempty"`
 }
 
 // TableName 返回表名
@@ -2,22 +2,112 @@ package models
 
 import "time"
 
+// GameInstanceState 游戏实例状态
+type GameInstanceState string
+
+const (
+	GameInstanceStatePending    GameInstanceState = "pending"    // 已创建，尚未启动
+	GameInstanceStateScheduling GameInstanceState = "scheduling" // 正在选择运行节点
+	GameInstanceStatePreparing  GameInstanceState = "preparing"  // 启动流水线已提交，等待节点执行
+	GameInstanceStateStarting   GameInstanceState = "starting"   // 节点正在执行启动流水线
+	GameInstanceStateRunning    GameInstanceState = "running"    // 游戏容器运行中
+	GameInstanceStateStopping   GameInstanceState = "stopping"   // 停止流水线执行中
+	GameInstanceStateStopped    GameInstanceState = "stopped"    // 已停止
+	GameInstanceStateFailed     GameInstanceState = "failed"     // 启动、运行或停止失败
+	GameInstanceStateLost       GameInstanceState = "lost"       // 运行节点失联，节点恢复后按流水线上报的状态或容器清单继续
+)
+
+// gameInstanceTransitions 实例状态允许的转换
+// 流水线状态上报可能合并，准备中的实例允许直接转换为运行中
+// 失联的实例可以重新启动，节点恢复后由流水线上报或容器清单确定状态
+var gameInstanceTransitions = map[GameInstanceState][]GameInstanceState{
+	GameInstanceStatePending:    {GameInstanceStateScheduling, GameInstanceStatePreparing, GameInstanceStateFailed},
+	GameInstanceStateScheduling: {GameInstanceStatePreparing, GameInstanceStateFailed},
+	GameInstanceStatePreparing:  {GameInstanceStateStarting, GameInstanceStateRunning, GameInstanceStateStopping, GameInstanceStateFailed, GameInstanceStateLost},
+	GameInstanceStateStarting:   {GameInstanceStateRunning, GameInstanceStateStopping, GameInstanceStateFailed, GameInstanceStateLost},
+	GameInstanceStateRunning:    {GameInstanceStateStopping, GameInstanceStateFailed, GameInstanceStateLost},
+	GameInstanceStateStopping:   {GameInstanceStateStopped, GameInstanceStateFailed},
+	GameInstanceStateStopped:    {GameInstanceStateScheduling, GameInstanceStatePreparing},
+	GameInstanceStateFailed:     {GameInstanceStateScheduling, GameInstanceStatePreparing, GameInstanceStateStopping},
+	GameInstanceStateLost:       {GameInstanceStatePreparing, GameInstanceStateStarting, GameInstanceStateRunning, GameInstanceStateStopping, GameInstanceStateFailed},
+}
+
+// IsValid 判断是否为有效的实例状态
+func (s GameInstanceState) IsValid() bool {
+	_, ok := gameInstanceTransitions[s]
+	return ok
+}
+
+// CanTransitionTo 判断是否允许转换到目标状态
+func (s GameInstanceState) CanTransitionTo(target GameInstanceState) bool {
+	for _, next := range gameInstanceTransitions[s] {
+		if next == target {
+			return true
+		}
+	}
+	return false
+}
+
+// ReservesResources 处于该状态的实例是否占用节点资源，已停止和失败的实例不占用
+func (s GameInstanceState) ReservesResources() bool {
+	return s.IsValid() && s != GameInstanceStateStopped && s != GameInstanceStateFailed
+}
+
+// InstanceStopReason 实例停止的原因
+type InstanceStopReason string
+
+const (
+	StopReasonUser   InstanceStopReason = "user"   // 用户或管理员停止
+	StopReasonIdle   InstanceStopReason = "idle"   // 空闲超时自动停止
+	StopReasonFailed InstanceStopReason = "failed" // 启动或运行失败
+)
+
+// IdleState 运行中实例的空闲状态，空闲时间超过警告阈值时记录
+type IdleState struct {
+	// Since 最近一次检测到活动的时间
+	Since time.Time `json:"since" yaml:"since"`
+	// WarnedAt 首次发出空闲警告的时间
+	WarnedAt time.Time `json:"warned_at" yaml:"warned_at"`
+	// ShutdownAt 保持空闲时自动停止的时间
+	ShutdownAt time.Time `json:"shutdown_at" yaml:"shutdown_at"`
+}
+
 // GameInstance 游戏实例模型
 type GameInstance struct {
-	ID          string    `json:"id" yaml:"id"`
-	NodeID      string    `json:"node_id" yaml:"node_id"`         // 关联的游戏机ID
-	PlatformID  string    `json:"platform_id" yaml:"platform_id"` // 关联的平台ID
-	CardID      string    `json:"card_id" yaml:"card_id"`         // 关联的游戏卡片ID
-	Status      string    `json:"status" yaml:"status"`           // 运行状态
-	Resources   string    `json:"resources" yaml:"resources"`     // 资源占用
-	Performance string    `json:"performance" yaml:"performance"` // 性能指标
-	SaveData    string    `json:"save_data" yaml:"save_data"`     // 存档数据
-	Config      string    `json:"config" yaml:"config"`           // 实例配置
-	Backup      string    `json:"backup" yaml:"backup"`           // 备份数据
-	CreatedAt   time.Time `json:"created_at" yaml:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" yaml:"updated_at"`
-	StartedAt   time.Time `json:"started_at" yaml:"started_at"` // 启动时间
-	StoppedAt   time.Time `json:"stopped_at" yaml:"stopped_at"` // 停止时间
+	ID          string            `json:"id" yaml:"id"`
+	NodeID      string            `json:"node_id" yaml:"node_id"`         // 关联的游戏机ID
+	PlatformID  string            `json:"platform_id" yaml:"platform_id"` // 关联的平台ID
+	CardID      string            `json:"card_id" yaml:"card_id"`         // 关联的游戏卡片ID
+	Status      GameInstanceState `json:"status" yaml:"status"`           // 运行状态
+	Resources   string            `json:"resources" yaml:"resources"`     // 资源占用
+	Performance string            `json:"performance" yaml:"performance"` // 性能指标
+	SaveData    string            `json:"save_data" yaml:"save_data"`     // 存档数据
+	Config      string            `json:"config" yaml:"config"`           // 实例配置
+	Backup      string            `json:"backup" yaml:"backup"`           // 备份数据
+	CreatedAt   time.Time         `json:"created_at" yaml:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at" yaml:"updated_at"`
+	StartedAt   time.Time         `json:"started_at" yaml:"started_at"` // 启动时间
+	StoppedAt   time.Time         `json:"stopped_at" yaml:"stopped_at"` // 停止时间
+
+	// Reservation 调度时在节点上预留的资源，实例结束后不再计入节点的已预留资源
+	Reservation *ResourceReservation `json:"reservation,omitempty" yaml:"reservation,omitempty"`
+
+	// Port 游戏容器的 8080 端口映射到节点上的端口
+	Port int `json:"port,omitempty" yaml:"port,omitempty"`
+	// PipelineID 最近一次启动或停止实例的流水线
+	PipelineID string `json:"pipeline_id,omitempty" yaml:"pipeline_id,omitempty"`
+	// Message 最近一次状态变更的说明，例如失败原因
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+
+	// UserID 使用实例的用户，存档按用户和卡片保存，为空时不同步存档
+	UserID string `json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	// Save 最近一次启动的存档同步状态
+	Save *SaveState `json:"save,omitempty" yaml:"save,omitempty"`
+
+	// StopReason 最近一次停止的原因，再次启动时清除
+	StopReason InstanceStopReason `json:"stop_reason,omitempty" yaml:"stop_reason,omitempty"`
+	// Idle 空闲警告，实例恢复活动或停止后清除
+	Idle *IdleState `json:"idle,omitempty" yaml:"idle,omitempty"`
 }
 
 // TableName 返回表名
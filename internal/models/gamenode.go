@@ -34,6 +34,45 @@ const (
 	GameNodeStaticStateDisabled    GameNodeStaticState = "disabled"    // 禁用状态
 )
 
+// IsValid 判断是否为有效的维护状态
+func (s GameNodeStaticState) IsValid() bool {
+	switch s {
+	case GameNodeStaticStateNormal, GameNodeStaticStateMaintenance, GameNodeStaticStateDisabled:
+		return true
+	}
+	return false
+}
+
+// Effective 返回实际生效的维护状态，为空视为正常状态
+func (s GameNodeStaticState) Effective() GameNodeStaticState {
+	if s == "" {
+		return GameNodeStaticStateNormal
+	}
+	return s
+}
+
+// AcceptsPipelines 节点是否接收新的流水线，只有正常状态的节点接收
+func (s GameNodeStaticState) AcceptsPipelines() bool {
+	return s.Effective() == GameNodeStaticStateNormal
+}
+
+// CanTransitionTo 判断是否允许转换到目标状态，禁用状态不允许直接转换为维护状态
+func (s GameNodeStaticState) CanTransitionTo(target GameNodeStaticState) bool {
+	if !target.IsValid() {
+		return false
+	}
+	return !(s.Effective() == GameNodeStaticStateDisabled && target == GameNodeStaticStateMaintenance)
+}
+
+// NodeApproval 节点准入审批状态
+type NodeApproval string
+
+const (
+	NodeApprovalPending  NodeApproval = "pending"  // 等待管理员审批
+	NodeApprovalApproved NodeApproval = "approved" // 已批准
+	NodeApprovalRejected NodeApproval = "rejected" // 已拒绝
+)
+
 // CPUDevice CPU设备信息
 type CPUDevice struct {
 	Model        string  `json:"model" yaml:"model"`               // CPU型号
@@ -152,29 +191,75 @@ type MetricsInfo struct {
 	Network  NetworkMetrics   `json:"network" yaml:"network"`   // 网络指标
 }
 
+// ContainerInfo 节点上由流水线创建的容器
+type ContainerInfo struct {
+	ID         string    `json:"id" yaml:"id"`                   // 容器ID
+	Name       string    `json:"name" yaml:"name"`               // 容器名称
+	Image      string    `json:"image" yaml:"image"`             // 镜像
+	State      string    `json:"state" yaml:"state"`             // 容器状态(running/exited等)
+	PipelineID string    `json:"pipeline_id" yaml:"pipeline_id"` // 所属流水线
+	InstanceID string    `json:"instance_id" yaml:"instance_id"` // 所属游戏实例
+	CreatedAt  time.Time `json:"created_at" yaml:"created_at"`   // 创建时间
+
+	// StartedAt 最近一次启动时间，未启动过时为空
+	StartedAt *time.Time `json:"started_at,omitempty" yaml:"started_at,omitempty"`
+	// Ports 端口映射
+	Ports []ContainerPort `json:"ports,omitempty" yaml:"ports,omitempty"`
+	// Activity 运行中实例容器的活动采样，用于空闲检测
+	Activity *ContainerActivity `json:"activity,omitempty" yaml:"activity,omitempty"`
+}
+
+// ContainerActivity 容器活动采样，无法采集的指标为 -1
+type ContainerActivity struct {
+	SampledAt   time.Time `json:"sampled_at" yaml:"sampled_at"`
+	Connections int       `json:"connections" yaml:"connections"` // 已发布端口上的 TCP 连接数，即 Selkies 连接数
+	CPUPercent  float64   `json:"cpu_percent" yaml:"cpu_percent"` // CPU 使用率，单核满载为 100
+	GPUPercent  float64   `json:"gpu_percent" yaml:"gpu_percent"` // 容器进程的 GPU 使用率
+
+	// InputIdle 节点输入设备距最近一次输入事件的时间，输入设备由节点上的实例共享
+	InputIdle time.Duration `json:"input_idle" yaml:"input_idle"`
+}
+
+// ContainerPort 容器端口映射
+type ContainerPort struct {
+	PrivatePort uint16 `json:"private_port" yaml:"private_port"`                   // 容器端口
+	PublicPort  uint16 `json:"public_port,omitempty" yaml:"public_port,omitempty"` // 主机端口，未映射时为 0
+	Protocol    string `json:"protocol" yaml:"protocol"`                           // tcp/udp
+	IP          string `json:"ip,omitempty" yaml:"ip,omitempty"`                   // 主机监听地址
+}
+
 // GameNodeStatus 节点状态信息
 type GameNodeStatus struct {
-	State      GameNodeState `json:"state" yaml:"state"`             // 节点状态
-	Online     bool          `json:"online" yaml:"online"`           // 是否在线
-	LastOnline time.Time     `json:"last_online" yaml:"last_online"` // 最后在线时间
-	UpdatedAt  time.Time     `json:"updated_at" yaml:"updated_at"`   // 状态更新时间
-	Hardware   HardwareInfo  `json:"hardware" yaml:"hardware"`       // 硬件配置
-	System     SystemInfo    `json:"system" yaml:"system"`           // 系统配置
-	Metrics    MetricsInfo   `json:"metrics" yaml:"metrics"`         // 监控指标
+	State      GameNodeState   `json:"state" yaml:"state"`             // 节点状态
+	Online     bool            `json:"online" yaml:"online"`           // 是否在线
+	LastOnline time.Time       `json:"last_online" yaml:"last_online"` // 最后在线时间
+	UpdatedAt  time.Time       `json:"updated_at" yaml:"updated_at"`   // 状态更新时间
+	Hardware   HardwareInfo    `json:"hardware" yaml:"hardware"`       // 硬件配置
+	System     SystemInfo      `json:"system" yaml:"system"`           // 系统配置
+	Metrics    MetricsInfo     `json:"metrics" yaml:"metrics"`         // 监控指标
+	Containers []ContainerInfo `json:"containers" yaml:"containers"`   // 容器清单，由 Agent 上报
+
+	// MetricsSequence 最近一次应用的指标上报序号，用于丢弃重放时重复或过期的指标
+	MetricsSequence int64 `json:"metrics_sequence,omitempty" yaml:"metrics_sequence,omitempty"`
+
+	// ContainersUpdatedAt 最近一次收到容器清单的时间
+	ContainersUpdatedAt time.Time `json:"containers_updated_at,omitempty" yaml:"containers_updated_at,omitempty"`
 }
 
 // GameNode 游戏节点
 type GameNode struct {
-	ID        string              `json:"id" yaml:"id"`                 // 节点ID
-	Alias     string              `json:"alias" yaml:"alias"`           // 节点别名
-	Model     string              `json:"model" yaml:"model"`           // 节点型号
-	Type      GameNodeType        `json:"type" yaml:"type"`             // 节点类型
-	Location  string              `json:"location" yaml:"location"`     // 节点位置
-	Labels    map[string]string   `json:"labels" yaml:"labels"`         // 标签
-	State     GameNodeStaticState `json:"state" yaml:"state"`           // 节点维护状态
-	Hardware  map[string]string   `json:"hardware" yaml:"hardware"`     // 硬件配置(简化版)
-	System    map[string]string   `json:"system" yaml:"system"`         // 系统配置(简化版)
-	Status    GameNodeStatus      `json:"status" yaml:"status"`         // 节点状态信息
-	CreatedAt time.Time           `json:"created_at" yaml:"created_at"` // 创建时间
-	UpdatedAt time.Time           `json:"updated_at" yaml:"updated_at"` // 更新时间
+	ID             string              `json:"id" yaml:"id"`                       // 节点ID
+	Alias          string              `json:"alias" yaml:"alias"`                 // 节点别名
+	Model          string              `json:"model" yaml:"model"`                 // 节点型号
+	Type           GameNodeType        `json:"type" yaml:"type"`                   // 节点类型
+	Location       string              `json:"location" yaml:"location"`           // 节点位置
+	Labels         map[string]string   `json:"labels" yaml:"labels"`               // 标签
+	State          GameNodeStaticState `json:"state" yaml:"state"`                 // 节点维护状态
+	Approval       NodeApproval        `json:"approval" yaml:"approval"`           // 准入审批状态，为空视为等待审批
+	CredentialHash string              `json:"-" yaml:"credential_hash,omitempty"` // 节点凭证的 SHA-256 摘要
+	Hardware       map[string]string   `json:"hardware" yaml:"hardware"`           // 硬件配置(简化版)
+	System         map[string]string   `json:"system" yaml:"system"`               // 系统配置(简化版)
+	Status         GameNodeStatus      `json:"status" yaml:"status"`               // 节点状态信息
+	CreatedAt      time.Time           `json:"created_at" yaml:"created_at"`       // 创建时间
+	UpdatedAt      time.Time           `json:"updated_at" yaml:"updated_at"`       // 更新时间
 }
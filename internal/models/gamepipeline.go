@@ -24,8 +24,14 @@ const (
 	PipelineStateCompleted  PipelineState = "completed"   // 已完成
 	PipelineStateFailed     PipelineState = "failed"      // 失败
 	PipelineStateCanceled   PipelineState = "canceled"    // 取消
+	PipelineStateLost       PipelineState = "lost"        // 执行节点失联，节点恢复上报后继续更新
 )
 
+// IsTerminal 判断流水线是否已处于终态
+func (s PipelineState) IsTerminal() bool {
+	return s == PipelineStateCompleted || s == PipelineStateFailed || s == PipelineStateCanceled
+}
+
 // StepState 表示步骤状态
 type StepState string
 
@@ -66,6 +72,7 @@ type ContainerConfig struct {
 	Ports       []string          `json:"ports,omitempty" yaml:"ports,omitempty"`
 	Environment map[string]string `json:"environment,omitempty" yaml:"environment,omitempty"`
 	Commands    []string          `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Networks    []string          `json:"networks,omitempty" yaml:"networks,omitempty"`
 }
 
 // DeployConfig 部署配置
@@ -88,6 +95,46 @@ type DeviceConfig struct {
 	Capabilities []string `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
 }
 
+// ResourceLifecycle 托管资源的生命周期策略
+type ResourceLifecycle string
+
+const (
+	ResourceLifecycleRetain ResourceLifecycle = "retain" // 实例销毁时保留
+	ResourceLifecycleRemove ResourceLifecycle = "remove" // 实例销毁时删除
+)
+
+// PipelineVolume 流水线声明的托管卷
+type PipelineVolume struct {
+	Name       string            `json:"name" yaml:"name"`                                   // 卷名称，步骤中以该名称引用
+	Driver     string            `json:"driver,omitempty" yaml:"driver,omitempty"`           // 卷驱动
+	DriverOpts map[string]string `json:"driver_opts,omitempty" yaml:"driver_opts,omitempty"` // 驱动参数
+	Lifecycle  ResourceLifecycle `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`     // 生命周期策略，默认保留
+}
+
+// PipelineNetwork 流水线声明的托管网络
+type PipelineNetwork struct {
+	Name      string            `json:"name" yaml:"name"`                               // 网络名称，步骤中以该名称引用
+	Driver    string            `json:"driver,omitempty" yaml:"driver,omitempty"`       // 网络驱动
+	Internal  bool              `json:"internal,omitempty" yaml:"internal,omitempty"`   // 是否为内部网络
+	Lifecycle ResourceLifecycle `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"` // 生命周期策略，默认删除
+}
+
+// VolumeLifecycle 返回卷的生命周期策略，未设置时默认保留
+func (v PipelineVolume) VolumeLifecycle() ResourceLifecycle {
+	if v.Lifecycle == "" {
+		return ResourceLifecycleRetain
+	}
+	return v.Lifecycle
+}
+
+// NetworkLifecycle 返回网络的生命周期策略，未设置时默认删除
+func (n PipelineNetwork) NetworkLifecycle() ResourceLifecycle {
+	if n.Lifecycle == "" {
+		return ResourceLifecycleRemove
+	}
+	return n.Lifecycle
+}
+
 // PipelineStep 流水线步骤
 type PipelineStep struct {
 	Name      string          `json:"name" yaml:"name"`
@@ -106,6 +153,33 @@ type PipelineStatus struct {
 	Steps        []StepStatus  `json:"steps,omitempty" yaml:"steps,omitempty"`           // 步骤状态列表
 	ErrorMessage string        `json:"error,omitempty" yaml:"error,omitempty"`           // 错误信息
 	UpdatedAt    *time.Time    `json:"updated_at,omitempty" yaml:"updated_at,omitempty"` // 更新时间
+	Sequence     int64         `json:"sequence,omitempty" yaml:"sequence,omitempty"`     // 最近一次应用的节点上报序号
+}
+
+// PipelineDispatchState 流水线下发状态
+type PipelineDispatchState string
+
+const (
+	PipelineDispatchQueued   PipelineDispatchState = "queued"   // 节点未连接，等待下发
+	PipelineDispatchSent     PipelineDispatchState = "sent"     // 已发送，等待节点确认
+	PipelineDispatchAcked    PipelineDispatchState = "acked"    // 节点已确认接收
+	PipelineDispatchRejected PipelineDispatchState = "rejected" // 节点拒绝执行
+)
+
+// PipelineDispatch 流水线下发信息
+type PipelineDispatch struct {
+	State    PipelineDispatchState `json:"state" yaml:"state"`                           // 下发状态
+	Template string                `json:"template,omitempty" yaml:"template,omitempty"` // 模板名称
+	Args     map[string]string     `json:"args,omitempty" yaml:"args,omitempty"`         // 模板参数，不包含敏感参数
+	// SecretArgs 敏感参数名称，参数值只保存在 Secrets 中
+	SecretArgs []string `json:"secret_args,omitempty" yaml:"secret_args,omitempty"`
+	// Secrets 敏感参数值，只保存在服务端内存中，不写入数据文件和 API 响应
+	Secrets  map[string]string `json:"-" yaml:"-"`
+	Attempts int               `json:"attempts,omitempty" yaml:"attempts,omitempty"`   // 发送次数
+	Message  string            `json:"message,omitempty" yaml:"message,omitempty"`     // 节点返回的信息
+	QueuedAt *time.Time        `json:"queued_at,omitempty" yaml:"queued_at,omitempty"` // 进入队列时间
+	SentAt   *time.Time        `json:"sent_at,omitempty" yaml:"sent_at,omitempty"`     // 最近一次发送时间
+	AckedAt  *time.Time        `json:"acked_at,omitempty" yaml:"acked_at,omitempty"`   // 节点确认时间
 }
 
 // GamePipeline 表示一个游戏节点流水线模板
@@ -113,6 +187,9 @@ type GamePipeline struct {
 	ID    string        `json:"id" yaml:"id"`       // 实例ID
 	Model PipelineModel `json:"model" yaml:"model"` // 实例模板
 
+	// InstanceID 所属游戏实例，托管资源按实例归属，为空时按流水线归属
+	InstanceID string `json:"instance_id,omitempty" yaml:"instance_id,omitempty"`
+
 	// 静态信息（模板定义）
 	Name        string         `json:"name" yaml:"name"`
 	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
@@ -120,8 +197,13 @@ type GamePipeline struct {
 	Args        []string       `json:"args,omitempty" yaml:"args,omitempty"`
 	Steps       []PipelineStep `json:"steps,omitempty" yaml:"steps,omitempty"`
 
+	// 托管资源声明
+	Volumes  []PipelineVolume  `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	Networks []PipelineNetwork `json:"networks,omitempty" yaml:"networks,omitempty"`
+
 	// 动态信息（执行状态）
-	Status *PipelineStatus `json:"status,omitempty" yaml:"status,omitempty"`
+	Status   *PipelineStatus   `json:"status,omitempty" yaml:"status,omitempty"`
+	Dispatch *PipelineDispatch `json:"dispatch,omitempty" yaml:"dispatch,omitempty"`
 }
 
 // NewGamePipelineFromYAML 从YAML创建新的游戏节点流水线模板
@@ -149,6 +231,14 @@ func NewGamePipelineFromYAML(data []byte) (*GamePipeline, error) {
 	return &pipeline, nil
 }
 
+// ResourceScope 返回托管资源的归属范围
+func (p *GamePipeline) ResourceScope() string {
+	if p.InstanceID != "" {
+		return p.InstanceID
+	}
+	return p.ID
+}
+
 // ToYAML 将流水线转换为YAML
 func (p *GamePipeline) ToYAML() ([]byte, error) {
 	return yaml.Marshal(p)
@@ -17,6 +17,9 @@ type GamePlatform struct {
 	Installer []GamePlatformInstaller `json:"installer" yaml:"installer"` // 安装步骤
 	CreatedAt time.Time               `json:"created_at" yaml:"created_at"`
 	UpdatedAt time.Time               `json:"updated_at" yaml:"updated_at"`
+
+	// Requirements 平台运行需要的节点资源，游戏卡片可以覆盖
+	Requirements *ResourceRequirements `json:"requirements,omitempty" yaml:"requirements,omitempty"`
 }
 
 // GamePlatformFile 平台文件
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// InventoryIssueType 容器清单与实例记录不一致的类型
+type InventoryIssueType string
+
+const (
+	InventoryIssueMissingContainer InventoryIssueType = "missing_container" // 运行中的实例在节点上没有运行中的容器
+	InventoryIssueOrphanContainer  InventoryIssueType = "orphan_container"  // 托管容器没有对应的实例或未结束的流水线
+	InventoryIssuePortConflict     InventoryIssueType = "port_conflict"     // 预留给实例的端口被其他容器占用
+)
+
+// InventoryIssue 一条容器清单不一致记录
+type InventoryIssue struct {
+	Type        InventoryIssueType `json:"type"`                   // 不一致类型
+	NodeID      string             `json:"node_id"`                // 节点ID
+	InstanceID  string             `json:"instance_id,omitempty"`  // 实例ID
+	ContainerID string             `json:"container_id,omitempty"` // 容器ID
+	Container   string             `json:"container,omitempty"`    // 容器名称
+	PipelineID  string             `json:"pipeline_id,omitempty"`  // 创建容器的流水线
+	Message     string             `json:"message"`                // 说明
+	CleanedUp   bool               `json:"cleaned_up"`             // 是否已自动清理
+}
+
+// InventoryReport 一个节点的容器清单核对结果，只保存在内存中
+type InventoryReport struct {
+	NodeID     string           `json:"node_id"`     // 节点ID
+	CheckedAt  time.Time        `json:"checked_at"`  // 核对时间
+	ReportedAt time.Time        `json:"reported_at"` // 核对使用的容器清单的上报时间
+	Containers int              `json:"containers"`  // 容器数量
+	Instances  int              `json:"instances"`   // 节点上运行中的实例数量
+	Issues     []InventoryIssue `json:"issues"`      // 不一致记录
+}
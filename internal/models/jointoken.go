@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// JoinToken 节点加入令牌
+// 令牌格式为 "<ID>.<Secret>"，只保存 Secret 的摘要，明文只在创建时返回一次
+type JoinToken struct {
+	ID          string    `json:"id" yaml:"id"`                   // 令牌ID
+	SecretHash  string    `json:"-" yaml:"secret_hash"`           // Secret 的 SHA-256 摘要
+	Description string    `json:"description" yaml:"description"` // 描述
+	ExpiresAt   time.Time `json:"expires_at" yaml:"expires_at"`   // 过期时间
+	MaxUses     int       `json:"max_uses" yaml:"max_uses"`       // 最大使用次数，0 表示不限
+	Uses        int       `json:"uses" yaml:"uses"`               // 已使用次数
+	UsedBy      []string  `json:"used_by" yaml:"used_by"`         // 使用过该令牌的节点ID
+	Revoked     bool      `json:"revoked" yaml:"revoked"`         // 是否已吊销
+	CreatedAt   time.Time `json:"created_at" yaml:"created_at"`   // 创建时间
+}
+
+// Usable 令牌在指定时间是否可用
+func (t *JoinToken) Usable(now time.Time) bool {
+	if t.Revoked || now.After(t.ExpiresAt) {
+		return false
+	}
+	return t.MaxUses == 0 || t.Uses < t.MaxUses
+}
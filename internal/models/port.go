@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PortReservation 实例在节点上预留的主机端口
+type PortReservation struct {
+	NodeID     string    `json:"node_id" yaml:"node_id"`         // 节点ID
+	Port       int       `json:"port" yaml:"port"`               // 主机端口
+	InstanceID string    `json:"instance_id" yaml:"instance_id"` // 使用端口的实例
+	CreatedAt  time.Time `json:"created_at" yaml:"created_at"`   // 预留时间
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SaveSyncStatus 存档同步状态
+type SaveSyncStatus string
+
+const (
+	SaveSyncPrepared  SaveSyncStatus = "prepared"  // 启动前已确定要恢复的存档
+	SaveSyncUploading SaveSyncStatus = "uploading" // 上传流水线执行中
+	SaveSyncSynced    SaveSyncStatus = "synced"    // 存档已上传为用户的最新存档
+	SaveSyncUnchanged SaveSyncStatus = "unchanged" // 存档与启动时恢复的存档相同，未更新
+	SaveSyncEmpty     SaveSyncStatus = "empty"     // 存档目录中没有文件
+	SaveSyncConflict  SaveSyncStatus = "conflict"  // 游戏期间最新存档被其他实例更新，本次存档另行保存
+	SaveSyncFailed    SaveSyncStatus = "failed"    // 上传或提交存档失败
+)
+
+// SaveState 实例的存档同步状态
+type SaveState struct {
+	Status SaveSyncStatus `json:"status" yaml:"status"`
+	// Paths 本次启动同步的存档路径，取自卡片的 save_paths
+	Paths []string `json:"paths" yaml:"paths"`
+	// Object 用户最新存档在对象存储中的位置
+	Object string `json:"object" yaml:"object"`
+
+	// Restore 启动流水线是否恢复 Object，用户没有存档或上一次存档未上传时不恢复
+	Restore bool `json:"restore" yaml:"restore"`
+
+	// BaseChecksum 启动时恢复的存档的 SHA-256，为空表示用户没有存档
+	BaseChecksum string `json:"base_checksum,omitempty" yaml:"base_checksum,omitempty"`
+	// BaseETag 启动时恢复的存档的 ETag，存档缺少校验和时用于检测冲突
+	BaseETag string `json:"base_etag,omitempty" yaml:"base_etag,omitempty"`
+	// PreparedAt 启动前确定恢复存档的时间
+	PreparedAt time.Time `json:"prepared_at" yaml:"prepared_at"`
+
+	// UploadPipelineID 上传存档的流水线
+	UploadPipelineID string `json:"upload_pipeline_id,omitempty" yaml:"upload_pipeline_id,omitempty"`
+	// Upload 上传流水线写入的暂存对象
+	Upload string `json:"upload,omitempty" yaml:"upload,omitempty"`
+	// Checksum 最近一次上传的存档的 SHA-256
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	// UploadedAt 最近一次上传完成的时间
+	UploadedAt time.Time `json:"uploaded_at,omitempty" yaml:"uploaded_at,omitempty"`
+	// Conflict 发生冲突时本次存档的保存位置
+	Conflict string `json:"conflict,omitempty" yaml:"conflict,omitempty"`
+	// Message 同步失败或冲突的说明
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// saveNamePattern 存档对象路径和流水线脚本中允许使用的名称
+var saveNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidateSaveName 校验用于存档对象路径的用户ID或卡片ID
+func ValidateSaveName(name string) error {
+	if !saveNamePattern.MatchString(name) || name == "." || name == ".." {
+		return fmt.Errorf("无效的名称 %q: 只能包含字母、数字、点、下划线和连字符", name)
+	}
+	return nil
+}
+
+// ValidateSavePaths 校验卡片的存档路径，路径必须是 /home/ubuntu 下的相对路径
+func ValidateSavePaths(paths []string) error {
+	for _, p := range paths {
+		if p == "" || strings.HasPrefix(p, "/") || path.Clean(p) != p {
+			return fmt.Errorf("无效的存档路径 %q: 必须是 /home/ubuntu 下的相对路径", p)
+		}
+		for _, segment := range strings.Split(p, "/") {
+			if err := ValidateSaveName(segment); err != nil {
+				return fmt.Errorf("无效的存档路径 %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package models
+
+// SchedulingPolicy 实例调度策略
+type SchedulingPolicy string
+
+const (
+	SchedulingPolicyLeastLoaded SchedulingPolicy = "least_loaded" // 选择放置后剩余资源比例最高的节点
+	SchedulingPolicyBinPacking  SchedulingPolicy = "bin_packing"  // 选择放置后剩余资源比例最低的节点，尽量填满已使用的节点
+	SchedulingPolicySpread      SchedulingPolicy = "spread"       // 选择运行实例最少的节点
+)
+
+// IsValid 判断是否为支持的调度策略
+func (p SchedulingPolicy) IsValid() bool {
+	switch p {
+	case SchedulingPolicyLeastLoaded, SchedulingPolicyBinPacking, SchedulingPolicySpread:
+		return true
+	}
+	return false
+}
+
+// ResourceRequirements 运行实例需要的节点资源，由游戏平台和游戏卡片声明
+type ResourceRequirements struct {
+	GPUVendor string            `json:"gpu_vendor,omitempty" yaml:"gpu_vendor,omitempty"` // GPU厂商(nvidia/amd/intel)
+	GPUMemory int64             `json:"gpu_memory,omitempty" yaml:"gpu_memory,omitempty"` // 单块GPU的显存(MB)
+	CPUCores  float64           `json:"cpu_cores,omitempty" yaml:"cpu_cores,omitempty"`   // CPU线程数
+	Memory    int64             `json:"memory,omitempty" yaml:"memory,omitempty"`         // 内存(MB)
+	Labels    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`         // 节点标签
+	Location  string            `json:"location,omitempty" yaml:"location,omitempty"`     // 节点位置
+}
+
+// Merge 使用 override 中非零的字段覆盖当前要求，标签合并
+func (r ResourceRequirements) Merge(override *ResourceRequirements) ResourceRequirements {
+	if override == nil {
+		return r
+	}
+	if override.GPUVendor != "" {
+		r.GPUVendor = override.GPUVendor
+	}
+	if override.GPUMemory > 0 {
+		r.GPUMemory = override.GPUMemory
+	}
+	if override.CPUCores > 0 {
+		r.CPUCores = override.CPUCores
+	}
+	if override.Memory > 0 {
+		r.Memory = override.Memory
+	}
+	if override.Location != "" {
+		r.Location = override.Location
+	}
+	if len(override.Labels) > 0 {
+		labels := make(map[string]string, len(r.Labels)+len(override.Labels))
+		for k, v := range r.Labels {
+			labels[k] = v
+		}
+		for k, v := range override.Labels {
+			labels[k] = v
+		}
+		r.Labels = labels
+	}
+	return r
+}
+
+// Reservation 实例按资源要求在节点上预留的资源
+func (r ResourceRequirements) Reservation() ResourceReservation {
+	return ResourceReservation{
+		CPUCores:  r.CPUCores,
+		Memory:    r.Memory,
+		GPUMemory: r.GPUMemory,
+	}
+}
+
+// ResourceReservation 实例在节点上预留的资源
+type ResourceReservation struct {
+	CPUCores  float64 `json:"cpu_cores" yaml:"cpu_cores"`   // CPU线程数
+	Memory    int64   `json:"memory" yaml:"memory"`         // 内存(MB)
+	GPUMemory int64   `json:"gpu_memory" yaml:"gpu_memory"` // 显存(MB)
+}
+
+// Add 累加预留资源
+func (r ResourceReservation) Add(other ResourceReservation) ResourceReservation {
+	return ResourceReservation{
+		CPUCores:  r.CPUCores + other.CPUCores,
+		Memory:    r.Memory + other.Memory,
+		GPUMemory: r.GPUMemory + other.GPUMemory,
+	}
+}
+
+// NodeScore 满足资源要求的节点及其评分
+type NodeScore struct {
+	NodeID        string  `json:"node_id"`
+	Score         float64 `json:"score"`           // 按调度策略计算的评分，越高越优先
+	FreeCPUCores  float64 `json:"free_cpu_cores"`  // 放置前可用的CPU线程数
+	FreeMemory    int64   `json:"free_memory"`     // 放置前可用的内存(MB)
+	FreeGPUMemory int64   `json:"free_gpu_memory"` // 放置前可用的显存(MB)
+	Instances     int     `json:"instances"`       // 节点上运行中的实例数
+}
+
+// NodeRejection 节点不满足资源要求的原因
+type NodeRejection struct {
+	NodeID string `json:"node_id"`
+	Reason string `json:"reason"`
+}
+
+// SchedulingDecision 实例调度结果
+type SchedulingDecision struct {
+	NodeID       string               `json:"node_id"`      // 选中的节点
+	Policy       SchedulingPolicy     `json:"policy"`       // 使用的调度策略
+	Requirements ResourceRequirements `json:"requirements"` // 合并后的资源要求
+	Candidates   []NodeScore          `json:"candidates"`   // 满足要求的节点，按评分从高到低排列
+	Rejections   []NodeRejection      `json:"rejections"`   // 不满足要求的节点及原因
+}
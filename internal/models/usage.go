@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// UsageSession 实例的一次运行会话，实例进入运行中时开始，停止或失败时结束
+type UsageSession struct {
+	ID         string `json:"id" yaml:"id"`
+	InstanceID string `json:"instance_id" yaml:"instance_id"`
+	UserID     string `json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	CardID     string `json:"card_id" yaml:"card_id"`
+	PlatformID string `json:"platform_id" yaml:"platform_id"`
+	NodeID     string `json:"node_id" yaml:"node_id"`
+	// GPUModel 会话开始时运行节点的 GPU 型号，多种型号以逗号分隔
+	GPUModel string `json:"gpu_model,omitempty" yaml:"gpu_model,omitempty"`
+
+	StartedAt time.Time `json:"started_at" yaml:"started_at"`
+	// StoppedAt 会话结束时间，会话进行中时为空
+	StoppedAt *time.Time `json:"stopped_at,omitempty" yaml:"stopped_at,omitempty"`
+	// Duration 会话时长（秒），进行中的会话计算到查询时间
+	Duration int64 `json:"duration" yaml:"duration"`
+	// StopReason 会话结束的原因
+	StopReason InstanceStopReason `json:"stop_reason,omitempty" yaml:"stop_reason,omitempty"`
+}
+
+// UsageGroupBy 用量汇总维度
+type UsageGroupBy string
+
+const (
+	UsageGroupByUser UsageGroupBy = "user" // 按用户汇总
+	UsageGroupByCard UsageGroupBy = "card" // 按游戏卡片汇总
+	UsageGroupByNode UsageGroupBy = "node" // 按节点汇总
+	UsageGroupByDay  UsageGroupBy = "day"  // 按自然日汇总，跨天的会话按天拆分
+)
+
+// IsValid 判断是否为有效的汇总维度
+func (g UsageGroupBy) IsValid() bool {
+	switch g {
+	case UsageGroupByUser, UsageGroupByCard, UsageGroupByNode, UsageGroupByDay:
+		return true
+	}
+	return false
+}
+
+// UsageQuery 用量查询条件，时间范围为 [From, To)，为空时不限制
+type UsageQuery struct {
+	From   time.Time
+	To     time.Time
+	UserID string
+	CardID string
+	NodeID string
+}
+
+// UsageSummary 按维度汇总的用量
+type UsageSummary struct {
+	// Key 维度的取值，例如用户ID或日期 2006-01-02
+	Key string `json:"key" yaml:"key"`
+	// Sessions 与该维度有时间重叠的会话数
+	Sessions int `json:"sessions" yaml:"sessions"`
+	// Duration 查询范围内的使用时长（秒）
+	Duration int64 `json:"duration" yaml:"duration"`
+}
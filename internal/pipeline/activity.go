@@ -0,0 +1,338 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// inputScanInterval 重新扫描输入设备的周期，发现新接入的设备
+	inputScanInterval = 30 * time.Second
+	// inputEventSize 64 位系统上 input_event 结构的大小
+	inputEventSize = 24
+	// tcpStateEstablished /proc/net/tcp 中 ESTABLISHED 状态的编码
+	tcpStateEstablished = "01"
+	// activitySampleConcurrency 同时采集活动指标的容器数量
+	activitySampleConcurrency = 8
+)
+
+// InputMonitor 监听节点输入设备的事件，记录最近一次输入时间
+// 输入设备由节点上的实例共享，无法区分输入属于哪个实例，只在节点上运行单个实例时使用
+type InputMonitor struct {
+	dir    string
+	logger utils.Logger
+
+	mu      sync.Mutex
+	devices map[string]bool
+	last    time.Time
+}
+
+// NewInputMonitor 创建输入设备监听器，dir 通常为 /dev/input
+func NewInputMonitor(dir string) *InputMonitor {
+	return &InputMonitor{
+		dir:     dir,
+		logger:  utils.New("InputMonitor"),
+		devices: make(map[string]bool),
+		last:    time.Now(),
+	}
+}
+
+// Run 定期扫描输入设备并监听新设备的事件，直到 ctx 取消
+func (m *InputMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(inputScanInterval)
+	defer ticker.Stop()
+	for {
+		m.scan(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Idle 距最近一次输入事件的时间，没有可监听的输入设备时返回 -1
+func (m *InputMonitor) Idle(now time.Time) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.devices) == 0 {
+		return -1
+	}
+	if now.Before(m.last) {
+		return 0
+	}
+	return now.Sub(m.last)
+}
+
+// scan 打开尚未监听的输入设备
+func (m *InputMonitor) scan(ctx context.Context) {
+	paths, err := filepath.Glob(filepath.Join(m.dir, "event*"))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		m.mu.Lock()
+		watching := m.devices[path]
+		m.mu.Unlock()
+		if watching {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			m.logger.Debug("无法监听输入设备 %s: %v", path, err)
+			continue
+		}
+		m.mu.Lock()
+		m.devices[path] = true
+		m.mu.Unlock()
+		m.logger.Debug("监听输入设备: %s", path)
+		go m.watch(ctx, path, f)
+	}
+}
+
+// watch 读取输入设备的事件，设备移除或 ctx 取消后停止
+func (m *InputMonitor) watch(ctx context.Context, path string, f *os.File) {
+	defer func() {
+		f.Close()
+		m.mu.Lock()
+		delete(m.devices, path)
+		m.mu.Unlock()
+	}()
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	buf := make([]byte, inputEventSize*64)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			m.mu.Lock()
+			m.last = time.Now()
+			m.mu.Unlock()
+		}
+	}
+}
+
+// SampleActivity 为运行中的实例容器采集活动指标，写入容器的 Activity
+// 采集失败的指标记为 -1，不影响其他指标；各容器的指标并行采集
+// 输入事件无法区分属于哪个实例，节点上运行多个实例时不上报输入空闲时间
+func (m *ContainerManager) SampleActivity(ctx context.Context, containers []models.ContainerInfo, input *InputMonitor) {
+	now := time.Now()
+	instances := make(map[string]bool)
+	for _, c := range containers {
+		if c.InstanceID != "" && c.State == "running" {
+			instances[c.InstanceID] = true
+		}
+	}
+	inputIdle := time.Duration(-1)
+	if input != nil && len(instances) == 1 {
+		inputIdle = input.Idle(now)
+	}
+	gpu, gpuErr := gpuUsageByContainer(ctx)
+	if gpuErr != nil {
+		m.logger.Debug("无法采集 GPU 使用率: %v", gpuErr)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, activitySampleConcurrency)
+	for i := range containers {
+		c := &containers[i]
+		if c.InstanceID == "" || c.State != "running" {
+			continue
+		}
+		activity := &models.ContainerActivity{
+			SampledAt:   now,
+			Connections: -1,
+			CPUPercent:  -1,
+			GPUPercent:  -1,
+			InputIdle:   inputIdle,
+		}
+		if gpuErr == nil {
+			activity.GPUPercent = gpu[c.ID]
+		}
+		c.Activity = activity
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			m.sampleContainer(ctx, c, activity)
+		}()
+	}
+	wg.Wait()
+}
+
+// sampleContainer 采集单个容器的 CPU 使用率和串流连接数
+func (m *ContainerManager) sampleContainer(ctx context.Context, c *models.ContainerInfo, activity *models.ContainerActivity) {
+	if cpu, err := m.cpuPercent(ctx, c.ID); err == nil {
+		activity.CPUPercent = cpu
+	} else {
+		m.logger.Debug("无法采集容器 %s 的 CPU 使用率: %v", c.ID, err)
+	}
+	if detail, err := m.cli.ContainerInspect(ctx, c.ID); err == nil && detail.State != nil && detail.State.Pid > 0 {
+		if count, err := establishedConnections(detail.State.Pid, publishedPorts(c.Ports)); err == nil {
+			activity.Connections = count
+		} else {
+			m.logger.Debug("无法采集容器 %s 的连接数: %v", c.ID, err)
+		}
+	}
+}
+
+// cpuPercent 采集容器的 CPU 使用率，计算方式与 docker stats 相同
+func (m *ContainerManager) cpuPercent(ctx context.Context, containerID string) (float64, error) {
+	resp, err := m.cli.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var stats container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return 0, fmt.Errorf("解析容器统计信息失败: %w", err)
+	}
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0, nil
+	}
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * cpus * 100, nil
+}
+
+// publishedPorts 映射到节点上的容器 TCP 端口
+func publishedPorts(ports []models.ContainerPort) map[uint16]bool {
+	result := make(map[uint16]bool)
+	for _, p := range ports {
+		if p.PublicPort != 0 && (p.Protocol == "" || p.Protocol == "tcp") {
+			result[p.PrivatePort] = true
+		}
+	}
+	return result
+}
+
+// establishedConnections 统计容器网络命名空间中本地端口属于 ports 的已建立 TCP 连接数
+func establishedConnections(pid int, ports map[uint16]bool) (int, error) {
+	count := 0
+	found := false
+	for _, name := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "net", name))
+		if err != nil {
+			continue
+		}
+		found = true
+		n, err := countEstablished(f, ports)
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+	if !found {
+		return 0, fmt.Errorf("无法读取进程 %d 的网络连接", pid)
+	}
+	return count, nil
+}
+
+// countEstablished 解析 /proc/net/tcp 格式的内容，统计本地端口属于 ports 的已建立连接
+func countEstablished(f *os.File, ports map[uint16]bool) (int, error) {
+	count := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // 跳过表头
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] != tcpStateEstablished {
+			continue
+		}
+		i := strings.LastIndex(fields[1], ":")
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.ParseUint(fields[1][i+1:], 16, 16)
+		if err != nil {
+			continue
+		}
+		if ports[uint16(port)] {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// gpuUsageByContainer 使用 nvidia-smi pmon 采集进程的 GPU 使用率，按进程所属的容器汇总
+func gpuUsageByContainer(ctx context.Context) (map[string]float64, error) {
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "pmon", "-c", "1", "-s", "u").Output()
+	if err != nil {
+		return nil, err
+	}
+	usage := make(map[string]float64)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		sm, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			continue
+		}
+		if id := processContainer(pid); id != "" {
+			usage[id] += sm
+		}
+	}
+	return usage, nil
+}
+
+// processContainer 根据进程的 cgroup 获取进程所属的 Docker 容器ID
+func processContainer(pid int) string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// cgroup v1: /docker/<id>，cgroup v2: /system.slice/docker-<id>.scope
+		for _, segment := range strings.Split(line, "/") {
+			segment = strings.TrimSuffix(strings.TrimPrefix(segment, "docker-"), ".scope")
+			if len(segment) == 64 && isHex(segment) {
+				return segment
+			}
+		}
+	}
+	return ""
+}
+
+// isHex 判断字符串是否只包含小写十六进制字符
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
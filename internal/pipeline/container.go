@@ -7,20 +7,32 @@ import (
 	"io"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
 
 	"github.com/open-beagle/beagle-wind-game/internal/models"
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
+// logDrainTimeout 容器结束后等待日志转发完成的最长时间
+const logDrainTimeout = 5 * time.Second
+
 // ContainerManager 容器管理器
 type ContainerManager struct {
 	cli    *client.Client
 	logger utils.Logger
+
+	// startedMu 保护 started
+	startedMu sync.Mutex
+	// started 托管容器启动时间缓存，列表中没有启动时间，缓存后只在容器状态变化时重新查询
+	started map[string]containerStarted
 }
 
 // NewContainerManager 创建新的容器管理器
@@ -59,8 +71,9 @@ func NewContainerManager() (*ContainerManager, error) {
 	logger.Info("Docker 服务器版本: %s, API 版本: %s", version.Version, version.APIVersion)
 
 	return &ContainerManager{
-		logger: logger,
-		cli:    cli,
+		logger:  logger,
+		cli:     cli,
+		started: make(map[string]containerStarted),
 	}, nil
 }
 
@@ -75,154 +88,244 @@ func generateContainerName() string {
 }
 
 // RunContainer 运行容器
-func (m *ContainerManager) RunContainer(ctx context.Context, step *models.PipelineStep) error {
+// 容器的标准输出和标准错误写入 logOut
+func (m *ContainerManager) RunContainer(ctx context.Context, pipeline *models.GamePipeline, step *models.PipelineStep, logOut io.Writer) error {
 	m.logger.Debug("准备运行容器步骤: %s, 镜像: %s", step.Name, step.Container.Image)
 
+	containerID, err := m.createContainer(ctx, pipeline, step)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Debug("开始启动容器: %s", containerID)
+	// 启动容器
+	err = m.cli.ContainerStart(ctx, containerID, container.StartOptions{})
+	if err != nil {
+		m.logger.Error("启动容器失败: %v", err)
+		// 尝试清理容器
+		if removeErr := m.RemoveContainer(ctx, containerID); removeErr != nil {
+			m.logger.Error("清理失败的容器失败: %v", removeErr)
+		}
+		return fmt.Errorf("启动容器失败: %w", err)
+	}
+	m.logger.Debug("容器启动成功: %s", containerID)
+
+	m.logger.Debug("开始获取容器日志流: %s", containerID)
+	// 获取容器日志流
+	logs, err := m.cli.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Timestamps: true,
+	})
+	if err != nil {
+		m.logger.Error("获取容器日志失败: %v", err)
+		// 尝试清理容器
+		if removeErr := m.RemoveContainer(ctx, containerID); removeErr != nil {
+			m.logger.Error("清理失败的容器失败: %v", removeErr)
+		}
+		return fmt.Errorf("获取容器日志失败: %w", err)
+	}
+	defer logs.Close()
+	m.logger.Debug("容器日志流获取成功: %s", containerID)
+
+	// 转发容器日志，容器停止后日志流结束
+	logDone := make(chan struct{})
+	go func() {
+		defer close(logDone)
+		if _, err := stdcopy.StdCopy(logOut, logOut, logs); err != nil && ctx.Err() == nil {
+			m.logger.Warn("读取容器 %s 日志失败: %v", containerID, err)
+		}
+	}()
+
+	// 等待容器完成
+	m.logger.Debug("开始等待容器完成: %s", containerID)
+	statusCh, errCh := m.cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		m.logger.Error("等待容器完成失败: %v", err)
+		return fmt.Errorf("等待容器完成失败: %w", err)
+	case status := <-statusCh:
+		m.logger.Debug("容器状态更新: %+v", status)
+	}
+
+	// 等待剩余日志转发完成，避免日志流未正常结束时阻塞
+	select {
+	case <-logDone:
+	case <-time.After(logDrainTimeout):
+		m.logger.Warn("等待容器 %s 日志转发超时", containerID)
+	}
+
+	// 检查容器退出状态
+	m.logger.Debug("开始检查容器退出状态: %s", containerID)
+	inspect, err := m.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		m.logger.Error("检查容器状态失败: %v", err)
+		return fmt.Errorf("检查容器状态失败: %w", err)
+	}
+	m.logger.Debug("容器退出状态: %+v", inspect.State)
+
+	// 删除容器
+	if err := m.RemoveContainer(ctx, containerID); err != nil {
+		// 不返回错误，容器已经执行结束
+		m.logger.Error("删除容器失败: %v", err)
+	} else {
+		m.logger.Debug("容器删除成功: %s", containerID)
+	}
+
+	if inspect.State.ExitCode != 0 {
+		return fmt.Errorf("容器执行失败，退出码: %d", inspect.State.ExitCode)
+	}
+	m.logger.Debug("容器执行完成: %s", containerID)
+	return nil
+}
+
+// RunService 启动服务容器
+// 服务容器启动后保持运行，不等待退出也不删除，由 teardown 步骤随实例一起删除
+func (m *ContainerManager) RunService(ctx context.Context, pipeline *models.GamePipeline, step *models.PipelineStep, logOut io.Writer) error {
+	m.logger.Debug("准备启动服务步骤: %s, 镜像: %s", step.Name, step.Container.Image)
+
+	containerID, err := m.createContainer(ctx, pipeline, step)
+	if err != nil {
+		return err
+	}
+	if err := m.cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+		if removeErr := m.RemoveContainer(ctx, containerID); removeErr != nil {
+			m.logger.Error("清理失败的容器失败: %v", removeErr)
+		}
+		return fmt.Errorf("启动容器失败: %w", err)
+	}
+
+	// 启动后立即退出的服务视为启动失败
+	inspect, err := m.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("检查容器状态失败: %w", err)
+	}
+	if !inspect.State.Running && inspect.State.ExitCode != 0 {
+		if removeErr := m.RemoveContainer(ctx, containerID); removeErr != nil {
+			m.logger.Error("清理失败的容器失败: %v", removeErr)
+		}
+		return fmt.Errorf("服务容器启动后退出，退出码: %d", inspect.State.ExitCode)
+	}
+
+	fmt.Fprintf(logOut, "服务容器已启动: %s\n", containerID)
+	m.logger.Info("服务容器已启动: %s", containerID)
+	return nil
+}
+
+// createContainer 按步骤配置创建容器并接入网络，返回容器ID
+func (m *ContainerManager) createContainer(ctx context.Context, pipeline *models.GamePipeline, step *models.PipelineStep) (string, error) {
 	// 确保镜像存在
 	if err := m.ensureImageExists(ctx, step.Container.Image); err != nil {
-		return fmt.Errorf("准备镜像失败: %w", err)
+		return "", fmt.Errorf("准备镜像失败: %w", err)
+	}
+
+	exposedPorts, portBindings, err := nat.ParsePortSpecs(step.Container.Ports)
+	if err != nil {
+		return "", fmt.Errorf("解析端口映射失败: %w", err)
 	}
 
-	// 准备容器配置
+	// 准备容器配置，未指定命令时使用镜像的默认命令
 	config := &container.Config{
 		Image:        step.Container.Image,
-		Cmd:          []string{"sh", "-c", joinCommands(step.Container.Commands)},
 		Env:          convertMapToSlice(step.Container.Environment),
 		Hostname:     step.Container.Hostname,
+		Labels:       managedLabels(pipeline),
+		ExposedPorts: exposedPorts,
 		AttachStdout: true,
 		AttachStderr: true,
 	}
+	if len(step.Container.Commands) > 0 {
+		config.Cmd = []string{"sh", "-c", joinCommands(step.Container.Commands)}
+	}
 
 	// 准备主机配置
 	hostConfig := &container.HostConfig{
-		Privileged:  step.Container.Privileged,
-		SecurityOpt: step.Container.SecurityOpt,
-		CapAdd:      step.Container.CapAdd,
-		Tmpfs:       make(map[string]string),
-		Binds:       step.Container.Volumes,
+		Privileged:   step.Container.Privileged,
+		SecurityOpt:  step.Container.SecurityOpt,
+		CapAdd:       step.Container.CapAdd,
+		Tmpfs:        make(map[string]string),
+		Binds:        resolveBinds(pipeline, step.Container.Volumes),
+		PortBindings: portBindings,
 	}
+	hostConfig.Devices = resolveDevices(step.Container.Devices)
+	hostConfig.DeviceRequests = resolveDeviceRequests(step.Container.Deploy.Resources.Reservations.Devices)
 
 	// 设置 Tmpfs
 	for _, tmpfs := range step.Container.Tmpfs {
 		hostConfig.Tmpfs[tmpfs] = ""
 	}
 
+	// 准备网络配置，创建时接入第一个网络，其余网络在启动前接入
+	networks := resolveNetworks(pipeline, step.Container.Networks)
+	var networkingConfig *network.NetworkingConfig
+	if len(networks) > 0 {
+		hostConfig.NetworkMode = container.NetworkMode(networks[0])
+		networkingConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				networks[0]: {},
+			},
+		}
+	}
+
 	// 生成容器名称
 	containerName := generateContainerName()
 	m.logger.Debug("生成容器名称: %s", containerName)
 
 	// 创建容器
 	m.logger.Debug("开始创建容器...")
-	resp, err := m.cli.ContainerCreate(ctx, config, hostConfig, nil, nil, containerName)
+	resp, err := m.cli.ContainerCreate(ctx, config, hostConfig, networkingConfig, nil, containerName)
 	if err != nil {
 		m.logger.Error("创建容器失败: %v", err)
-		return fmt.Errorf("创建容器失败: %w", err)
+		return "", fmt.Errorf("创建容器失败: %w", err)
 	}
 	m.logger.Debug("容器创建成功，ID: %s", resp.ID)
 
-	m.logger.Debug("开始启动容器: %s", resp.ID)
-	// 启动容器
-	err = m.cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
-	if err != nil {
-		m.logger.Error("启动容器失败: %v", err)
-		// 尝试清理容器
-		if removeErr := m.RemoveContainer(ctx, resp.ID); removeErr != nil {
-			m.logger.Error("清理失败的容器失败: %v", removeErr)
+	for i := 1; i < len(networks); i++ {
+		if err := m.cli.NetworkConnect(ctx, networks[i], resp.ID, nil); err != nil {
+			if removeErr := m.RemoveContainer(ctx, resp.ID); removeErr != nil {
+				m.logger.Error("清理失败的容器失败: %v", removeErr)
+			}
+			return "", fmt.Errorf("容器接入网络 %s 失败: %w", networks[i], err)
 		}
-		return fmt.Errorf("启动容器失败: %w", err)
 	}
-	m.logger.Debug("容器启动成功: %s", resp.ID)
+	return resp.ID, nil
+}
 
-	m.logger.Debug("开始获取容器日志流: %s", resp.ID)
-	// 获取容器日志流
-	logs, err := m.cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Follow:     true,
-		Timestamps: true,
-	})
-	if err != nil {
-		m.logger.Error("获取容器日志失败: %v", err)
-		// 尝试清理容器
-		if removeErr := m.RemoveContainer(ctx, resp.ID); removeErr != nil {
-			m.logger.Error("清理失败的容器失败: %v", removeErr)
+// resolveDevices 将 "主机路径[:容器路径[:权限]]" 格式的设备映射转换为 Docker 设备配置
+func resolveDevices(devices []string) []container.DeviceMapping {
+	mappings := make([]container.DeviceMapping, 0, len(devices))
+	for _, device := range devices {
+		parts := strings.SplitN(device, ":", 3)
+		mapping := container.DeviceMapping{
+			PathOnHost:        parts[0],
+			PathInContainer:   parts[0],
+			CgroupPermissions: "rwm",
 		}
-		return fmt.Errorf("获取容器日志失败: %w", err)
-	}
-	defer logs.Close()
-	m.logger.Debug("容器日志流获取成功: %s", resp.ID)
-
-	// 创建一个通道用于等待容器完成
-	done := make(chan error, 1)
-	go func() {
-		m.logger.Debug("开始等待容器完成: %s", resp.ID)
-		// 等待容器完成
-		statusCh, errCh := m.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
-		select {
-		case err := <-errCh:
-			m.logger.Error("等待容器完成时发生错误: %v", err)
-			done <- err
-		case status := <-statusCh:
-			m.logger.Debug("容器状态更新: %+v", status)
-			done <- nil
+		if len(parts) > 1 && parts[1] != "" {
+			mapping.PathInContainer = parts[1]
 		}
-	}()
-
-	// 创建一个缓冲区用于读取日志
-	buf := make([]byte, 1024)
-	for {
-		select {
-		case err := <-done:
-			if err != nil {
-				m.logger.Error("等待容器完成失败: %v", err)
-				return fmt.Errorf("等待容器完成失败: %w", err)
-			}
-			// 检查容器退出状态
-			m.logger.Debug("开始检查容器退出状态: %s", resp.ID)
-			inspect, err := m.cli.ContainerInspect(ctx, resp.ID)
-			if err != nil {
-				m.logger.Error("检查容器状态失败: %v", err)
-				return fmt.Errorf("检查容器状态失败: %w", err)
-			}
-			m.logger.Debug("容器退出状态: %+v", inspect.State)
-			if inspect.State.ExitCode != 0 {
-				// 删除容器
-				m.logger.Debug("容器执行失败，开始删除容器: %s", resp.ID)
-				if err := m.RemoveContainer(ctx, resp.ID); err != nil {
-					m.logger.Error("删除容器失败: %v", err)
-					// 不返回错误，因为容器已经执行失败
-				} else {
-					m.logger.Debug("容器删除成功: %s", resp.ID)
-				}
-				return fmt.Errorf("容器执行失败，退出码: %d", inspect.State.ExitCode)
-			}
-			m.logger.Debug("容器执行完成: %s", resp.ID)
-
-			// 删除容器
-			m.logger.Debug("开始删除容器: %s", resp.ID)
-			if err := m.RemoveContainer(ctx, resp.ID); err != nil {
-				m.logger.Error("删除容器失败: %v", err)
-				// 不返回错误，因为容器已经成功执行完成
-			} else {
-				m.logger.Debug("容器删除成功: %s", resp.ID)
-			}
+		if len(parts) > 2 && parts[2] != "" {
+			mapping.CgroupPermissions = parts[2]
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings
+}
 
-			return nil
-		default:
-			// 读取日志
-			n, err := logs.Read(buf)
-			if err != nil {
-				if err == io.EOF {
-					continue
-				}
-				return fmt.Errorf("读取容器日志失败: %w", err)
-			}
-			if n > 0 {
-				// 发送日志事件
-				m.logger.Debug("[%s] %s", step.Name, string(buf[:n]))
-			}
+// resolveDeviceRequests 将资源预留中的设备转换为 Docker 设备请求，例如 capabilities: [gpu] 请求所有 GPU
+func resolveDeviceRequests(devices []models.DeviceConfig) []container.DeviceRequest {
+	var requests []container.DeviceRequest
+	for _, device := range devices {
+		if len(device.Capabilities) == 0 {
+			continue
 		}
+		requests = append(requests, container.DeviceRequest{
+			Count:        -1,
+			Capabilities: [][]string{device.Capabilities},
+		})
 	}
+	return requests
 }
 
 // StopContainer 停止容器
@@ -3,6 +3,7 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
@@ -19,6 +20,8 @@ type Engine struct {
 	containerMgr *ContainerManager
 	eventQueue   chan Event
 	done         chan struct{}
+	logSink      StepLogSink
+	input        *InputMonitor
 }
 
 // NewEngine 创建新的执行引擎
@@ -45,6 +48,7 @@ func NewEngine() (*Engine, error) {
 		containerMgr: containerMgr,
 		eventQueue:   make(chan Event, 1000), // 缓冲通道，避免阻塞
 		done:         make(chan struct{}),
+		input:        NewInputMonitor("/dev/input"),
 	}
 
 	// 启动事件处理循环
@@ -161,6 +165,64 @@ func (e *Engine) CancelPipeline(pipelineID string, reason string) error {
 	return nil
 }
 
+// TeardownInstance 销毁实例的容器，并按生命周期策略清理托管卷和网络
+func (e *Engine) TeardownInstance(ctx context.Context, instanceID string) error {
+	e.logger.Info("销毁实例 %s 的托管资源", instanceID)
+	return e.containerMgr.TeardownInstance(ctx, instanceID)
+}
+
+// ListContainers 列出引擎创建的容器
+func (e *Engine) ListContainers(ctx context.Context) ([]models.ContainerInfo, error) {
+	return e.containerMgr.ListManagedContainers(ctx)
+}
+
+// WatchContainers 监听引擎创建的容器的变化，直到 ctx 取消
+func (e *Engine) WatchContainers(ctx context.Context, onChange func()) {
+	e.containerMgr.WatchManagedContainers(ctx, onChange)
+}
+
+// WatchInput 监听节点输入设备的事件，直到 ctx 取消
+func (e *Engine) WatchInput(ctx context.Context) {
+	e.input.Run(ctx)
+}
+
+// SampleActivity 为运行中的实例容器采集活动指标
+func (e *Engine) SampleActivity(ctx context.Context, containers []models.ContainerInfo) {
+	e.containerMgr.SampleActivity(ctx, containers, e.input)
+}
+
+// RemoveContainer 删除引擎创建的容器，不删除非托管容器
+func (e *Engine) RemoveContainer(ctx context.Context, containerID string) error {
+	e.logger.Info("删除托管容器: %s", containerID)
+	return e.containerMgr.RemoveManagedContainer(ctx, containerID)
+}
+
+// SetLogSink 设置步骤日志输出，未设置时容器日志只写入调试日志
+func (e *Engine) SetLogSink(sink StepLogSink) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.logSink = sink
+}
+
+// openStepLog 打开步骤日志输出
+func (e *Engine) openStepLog(pipeline *models.GamePipeline, step *models.PipelineStep) io.WriteCloser {
+	e.mu.RLock()
+	sink := e.logSink
+	e.mu.RUnlock()
+	if sink == nil {
+		return nopWriteCloser{io.Discard}
+	}
+	return sink(pipeline, step)
+}
+
+// nopWriteCloser 关闭时不做任何操作的 WriteCloser
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close 实现 io.Closer
+func (nopWriteCloser) Close() error { return nil }
+
 // RegisterHandler 注册事件处理器
 func (e *Engine) RegisterHandler(handler EventHandler) {
 	e.mu.Lock()
@@ -178,16 +240,9 @@ func (e *Engine) eventLoop() {
 			copy(handlers, e.handlers)
 			e.mu.RUnlock()
 
-			// 异步处理每个事件处理器
+			// 按顺序调用事件处理器，保证同一 Pipeline 的事件按发生顺序处理
 			for _, handler := range handlers {
-				go func(h EventHandler, evt Event) {
-					defer func() {
-						if r := recover(); r != nil {
-							e.logger.Error("事件处理器发生panic: %v", r)
-						}
-					}()
-					h(evt)
-				}(handler, event)
+				e.dispatchEvent(handler, event)
 			}
 		case <-e.done:
 			e.logger.Info("事件处理循环已停止")
@@ -196,6 +251,16 @@ func (e *Engine) eventLoop() {
 	}
 }
 
+// dispatchEvent 调用单个事件处理器，隔离处理器中的 panic
+func (e *Engine) dispatchEvent(h EventHandler, evt Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.logger.Error("事件处理器发生panic: %v", r)
+		}
+	}()
+	h(evt)
+}
+
 // emitEvent 发送事件
 func (e *Engine) emitEvent(event Event) {
 	select {
@@ -219,6 +284,23 @@ func (e *Engine) executePipeline(ctx context.Context, pipeline *models.GamePipel
 		e.logger.Info("Pipeline %s 已从运行列表中移除", pipeline.ID)
 	}()
 
+	// 创建流水线声明的托管卷和网络
+	if err := e.containerMgr.EnsureResources(ctx, pipeline); err != nil {
+		e.logger.Error("Pipeline %s 准备托管资源失败: %v", pipeline.ID, err)
+		now := time.Now()
+		pipeline.Status.State = models.PipelineStateFailed
+		pipeline.Status.ErrorMessage = err.Error()
+		pipeline.Status.EndTime = &now
+
+		e.emitEvent(Event{
+			Type:      PipelineFailed,
+			Pipeline:  pipeline,
+			Message:   err.Error(),
+			Timestamp: now.Unix(),
+		})
+		return
+	}
+
 	for i := range pipeline.Steps {
 		step := &pipeline.Steps[i]
 		e.logger.Info("准备执行步骤 %d/%d: %s", i+1, len(pipeline.Steps), step.Name)
@@ -308,7 +390,19 @@ func (e *Engine) executeStep(ctx context.Context, pipeline *models.GamePipeline,
 	case "container":
 		// 执行容器步骤
 		e.logger.Debug("准备执行容器步骤: %s, 镜像: %s", step.Name, step.Container.Image)
-		return e.containerMgr.RunContainer(ctx, step)
+		logOut := e.openStepLog(pipeline, step)
+		defer logOut.Close()
+		return e.containerMgr.RunContainer(ctx, pipeline, step, logOut)
+	case "service":
+		// 启动服务容器，容器保持运行直到实例销毁
+		e.logger.Debug("准备执行服务步骤: %s, 镜像: %s", step.Name, step.Container.Image)
+		logOut := e.openStepLog(pipeline, step)
+		defer logOut.Close()
+		return e.containerMgr.RunService(ctx, pipeline, step, logOut)
+	case "teardown":
+		// 销毁实例的容器和托管资源
+		e.logger.Debug("准备销毁实例资源: %s", pipeline.ResourceScope())
+		return e.containerMgr.TeardownInstance(ctx, pipeline.ResourceScope())
 	default:
 		e.logger.Error("不支持的步骤类型: %s", step.Type)
 		return fmt.Errorf("不支持的步骤类型: %s", step.Type)
@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"context"
+	"io"
 
 	"github.com/open-beagle/beagle-wind-game/internal/models"
 )
@@ -57,3 +58,6 @@ type Event struct {
 
 // EventHandler 定义事件处理函数类型
 type EventHandler func(event Event)
+
+// StepLogSink 为步骤打开日志输出，返回的 Writer 在步骤结束后关闭
+type StepLogSink func(pipeline *models.GamePipeline, step *models.PipelineStep) io.WriteCloser
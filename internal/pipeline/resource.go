@@ -0,0 +1,344 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+)
+
+// 托管资源标签
+const (
+	LabelManaged   = "beagle-wind.managed"   // 由引擎创建并管理
+	LabelPipeline  = "beagle-wind.pipeline"  // 创建资源的流水线ID
+	LabelInstance  = "beagle-wind.instance"  // 资源归属的实例ID
+	LabelResource  = "beagle-wind.resource"  // 流水线中声明的资源名称
+	LabelLifecycle = "beagle-wind.lifecycle" // 实例销毁时的生命周期策略
+)
+
+// containerEventsRetryDelay 容器事件流中断后重新订阅的等待时间
+const containerEventsRetryDelay = 5 * time.Second
+
+// managedResourceName 生成托管资源在 Docker 中的名称
+func managedResourceName(scope, name string) string {
+	return fmt.Sprintf("bwg-%s-%s", scope, name)
+}
+
+// managedLabels 生成托管对象的公共标签
+func managedLabels(pipeline *models.GamePipeline) map[string]string {
+	return map[string]string{
+		LabelManaged:  "true",
+		LabelPipeline: pipeline.ID,
+		LabelInstance: pipeline.ResourceScope(),
+	}
+}
+
+// EnsureResources 创建流水线声明的卷和网络，已存在的托管资源直接复用
+func (m *ContainerManager) EnsureResources(ctx context.Context, pipeline *models.GamePipeline) error {
+	scope := pipeline.ResourceScope()
+
+	for _, v := range pipeline.Volumes {
+		name := managedResourceName(scope, v.Name)
+		existing, err := m.cli.VolumeInspect(ctx, name)
+		if err == nil {
+			if existing.Labels[LabelManaged] != "true" {
+				return fmt.Errorf("卷 %s 已存在且不是托管资源", name)
+			}
+			m.logger.Debug("复用已存在的托管卷: %s", name)
+			continue
+		}
+		if !client.IsErrNotFound(err) {
+			return fmt.Errorf("检查卷 %s 失败: %w", name, err)
+		}
+
+		labels := managedLabels(pipeline)
+		labels[LabelResource] = v.Name
+		labels[LabelLifecycle] = string(v.VolumeLifecycle())
+		if _, err := m.cli.VolumeCreate(ctx, volume.CreateOptions{
+			Name:       name,
+			Driver:     v.Driver,
+			DriverOpts: v.DriverOpts,
+			Labels:     labels,
+		}); err != nil {
+			return fmt.Errorf("创建卷 %s 失败: %w", name, err)
+		}
+		m.logger.Info("已创建托管卷: %s", name)
+	}
+
+	for _, n := range pipeline.Networks {
+		name := managedResourceName(scope, n.Name)
+		existing, err := m.cli.NetworkInspect(ctx, name, network.InspectOptions{})
+		if err == nil {
+			if existing.Labels[LabelManaged] != "true" {
+				return fmt.Errorf("网络 %s 已存在且不是托管资源", name)
+			}
+			m.logger.Debug("复用已存在的托管网络: %s", name)
+			continue
+		}
+		if !client.IsErrNotFound(err) {
+			return fmt.Errorf("检查网络 %s 失败: %w", name, err)
+		}
+
+		labels := managedLabels(pipeline)
+		labels[LabelResource] = n.Name
+		labels[LabelLifecycle] = string(n.NetworkLifecycle())
+		if _, err := m.cli.NetworkCreate(ctx, name, network.CreateOptions{
+			Driver:   n.Driver,
+			Internal: n.Internal,
+			Labels:   labels,
+		}); err != nil {
+			return fmt.Errorf("创建网络 %s 失败: %w", name, err)
+		}
+		m.logger.Info("已创建托管网络: %s", name)
+	}
+
+	return nil
+}
+
+// resolveBinds 将步骤挂载中引用的托管卷名称替换为实际的卷名称
+func resolveBinds(pipeline *models.GamePipeline, binds []string) []string {
+	if len(binds) == 0 {
+		return binds
+	}
+	declared := make(map[string]bool, len(pipeline.Volumes))
+	for _, v := range pipeline.Volumes {
+		declared[v.Name] = true
+	}
+
+	result := make([]string, len(binds))
+	for i, bind := range binds {
+		parts := strings.SplitN(bind, ":", 2)
+		if len(parts) == 2 && declared[parts[0]] {
+			result[i] = managedResourceName(pipeline.ResourceScope(), parts[0]) + ":" + parts[1]
+			continue
+		}
+		result[i] = bind
+	}
+	return result
+}
+
+// resolveNetworks 将步骤引用的托管网络名称替换为实际的网络名称
+func resolveNetworks(pipeline *models.GamePipeline, names []string) []string {
+	declared := make(map[string]bool, len(pipeline.Networks))
+	for _, n := range pipeline.Networks {
+		declared[n.Name] = true
+	}
+
+	result := make([]string, len(names))
+	for i, name := range names {
+		if declared[name] {
+			result[i] = managedResourceName(pipeline.ResourceScope(), name)
+		} else {
+			result[i] = name
+		}
+	}
+	return result
+}
+
+// TeardownInstance 删除实例的托管容器，并按生命周期策略清理卷和网络
+func (m *ContainerManager) TeardownInstance(ctx context.Context, instanceID string) error {
+	args := filters.NewArgs(
+		filters.Arg("label", LabelManaged+"=true"),
+		filters.Arg("label", LabelInstance+"="+instanceID),
+	)
+
+	containers, err := m.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return fmt.Errorf("列出实例容器失败: %w", err)
+	}
+	for _, c := range containers {
+		if err := m.RemoveContainer(ctx, c.ID); err != nil {
+			return err
+		}
+		m.logger.Info("已删除实例 %s 的容器: %s", instanceID, c.ID)
+	}
+
+	networks, err := m.cli.NetworkList(ctx, network.ListOptions{Filters: args})
+	if err != nil {
+		return fmt.Errorf("列出实例网络失败: %w", err)
+	}
+	for _, n := range networks {
+		if models.ResourceLifecycle(n.Labels[LabelLifecycle]) == models.ResourceLifecycleRetain {
+			m.logger.Debug("按策略保留网络: %s", n.Name)
+			continue
+		}
+		if err := m.cli.NetworkRemove(ctx, n.ID); err != nil {
+			return fmt.Errorf("删除网络 %s 失败: %w", n.Name, err)
+		}
+		m.logger.Info("已删除实例 %s 的网络: %s", instanceID, n.Name)
+	}
+
+	volumes, err := m.cli.VolumeList(ctx, volume.ListOptions{Filters: args})
+	if err != nil {
+		return fmt.Errorf("列出实例卷失败: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		if models.ResourceLifecycle(v.Labels[LabelLifecycle]) != models.ResourceLifecycleRemove {
+			m.logger.Debug("按策略保留卷: %s", v.Name)
+			continue
+		}
+		if err := m.cli.VolumeRemove(ctx, v.Name, false); err != nil {
+			return fmt.Errorf("删除卷 %s 失败: %w", v.Name, err)
+		}
+		m.logger.Info("已删除实例 %s 的卷: %s", instanceID, v.Name)
+	}
+
+	return nil
+}
+
+// ListManagedContainers 列出引擎创建的所有容器
+func (m *ContainerManager) ListManagedContainers(ctx context.Context) ([]models.ContainerInfo, error) {
+	args := filters.NewArgs(filters.Arg("label", LabelManaged+"=true"))
+	containers, err := m.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("列出托管容器失败: %w", err)
+	}
+
+	result := make([]models.ContainerInfo, 0, len(containers))
+	for _, c := range containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		info := models.ContainerInfo{
+			ID:         c.ID,
+			Name:       name,
+			Image:      c.Image,
+			State:      c.State,
+			PipelineID: c.Labels[LabelPipeline],
+			InstanceID: c.Labels[LabelInstance],
+			CreatedAt:  time.Unix(c.Created, 0),
+			Ports:      make([]models.ContainerPort, 0, len(c.Ports)),
+		}
+		for _, p := range c.Ports {
+			info.Ports = append(info.Ports, models.ContainerPort{
+				PrivatePort: p.PrivatePort,
+				PublicPort:  p.PublicPort,
+				Protocol:    p.Type,
+				IP:          p.IP,
+			})
+		}
+		info.StartedAt = m.containerStartedAt(ctx, c.ID, c.State)
+		result = append(result, info)
+	}
+	m.pruneStarted(containers)
+	return result, nil
+}
+
+// containerStarted 缓存的容器启动时间及查询时的容器状态
+type containerStarted struct {
+	state string
+	at    *time.Time
+}
+
+// containerStartedAt 获取容器最近一次启动时间
+// 列表中没有启动时间，只在容器首次出现或状态变化时查询一次，避免每次上报都逐个查询容器
+func (m *ContainerManager) containerStartedAt(ctx context.Context, id, state string) *time.Time {
+	m.startedMu.Lock()
+	cached, ok := m.started[id]
+	m.startedMu.Unlock()
+	if ok && cached.state == state {
+		return cached.at
+	}
+
+	detail, err := m.cli.ContainerInspect(ctx, id)
+	if err != nil || detail.State == nil {
+		return nil
+	}
+	entry := containerStarted{state: state}
+	if started, err := time.Parse(time.RFC3339Nano, detail.State.StartedAt); err == nil && !started.IsZero() {
+		entry.at = &started
+	}
+	m.startedMu.Lock()
+	m.started[id] = entry
+	m.startedMu.Unlock()
+	return entry.at
+}
+
+// forgetStarted 删除容器的启动时间缓存，下次列出时重新查询
+func (m *ContainerManager) forgetStarted(id string) {
+	m.startedMu.Lock()
+	defer m.startedMu.Unlock()
+	delete(m.started, id)
+}
+
+// pruneStarted 删除已不存在的容器的启动时间缓存
+func (m *ContainerManager) pruneStarted(containers []container.Summary) {
+	present := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		present[c.ID] = true
+	}
+	m.startedMu.Lock()
+	defer m.startedMu.Unlock()
+	for id := range m.started {
+		if !present[id] {
+			delete(m.started, id)
+		}
+	}
+}
+
+// RemoveManagedContainer 删除托管容器，容器不是由引擎创建时返回错误
+func (m *ContainerManager) RemoveManagedContainer(ctx context.Context, containerID string) error {
+	detail, err := m.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("检查容器 %s 失败: %w", containerID, err)
+	}
+	if detail.Config == nil || detail.Config.Labels[LabelManaged] != "true" {
+		return fmt.Errorf("容器 %s 不是托管容器", containerID)
+	}
+	return m.RemoveContainer(ctx, containerID)
+}
+
+// WatchManagedContainers 监听托管容器的创建、启动、停止和删除事件，每个事件调用一次 onChange
+// 事件流中断时等待后重新订阅，直到 ctx 取消
+func (m *ContainerManager) WatchManagedContainers(ctx context.Context, onChange func()) {
+	args := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("label", LabelManaged+"=true"),
+	)
+	for _, action := range []events.Action{events.ActionCreate, events.ActionStart, events.ActionDie, events.ActionDestroy} {
+		args.Add("event", string(action))
+	}
+
+	for {
+		messages, errs := m.cli.Events(ctx, events.ListOptions{Filters: args})
+	recv:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-messages:
+				m.logger.Debug("托管容器事件: %s %s", msg.Action, msg.Actor.ID)
+				// 容器重启后启动时间变化，即使两次列出之间状态相同也需要重新查询
+				if msg.Action == events.ActionStart || msg.Action == events.ActionDestroy {
+					m.forgetStarted(msg.Actor.ID)
+				}
+				onChange()
+			case err := <-errs:
+				if ctx.Err() != nil {
+					return
+				}
+				m.logger.Warn("容器事件流中断: %v", err)
+				break recv
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(containerEventsRetryDelay):
+		}
+	}
+}
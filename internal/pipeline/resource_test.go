@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestResolveResources(t *testing.T) {
+	pipeline := &models.GamePipeline{
+		ID:         "start-platform-1",
+		InstanceID: "inst-1",
+		Volumes:    []models.PipelineVolume{{Name: "system"}},
+		Networks:   []models.PipelineNetwork{{Name: "game"}},
+	}
+
+	// 只替换流水线声明的卷和网络，主机路径和外部资源保持不变
+	assert.Equal(t, []string{
+		"bwg-inst-1-system:/home/ubuntu",
+		"/dev/dri:/dev/dri",
+		"data:/data",
+	}, resolveBinds(pipeline, []string{"system:/home/ubuntu", "/dev/dri:/dev/dri", "data:/data"}))
+	assert.Equal(t, []string{"bwg-inst-1-game", "host"}, resolveNetworks(pipeline, []string{"game", "host"}))
+	assert.Nil(t, resolveBinds(pipeline, nil))
+
+	// 不属于实例的流水线以流水线ID为作用域
+	pipeline.InstanceID = ""
+	assert.Equal(t, []string{"bwg-start-platform-1-system:/home/ubuntu"}, resolveBinds(pipeline, []string{"system:/home/ubuntu"}))
+	assert.Equal(t, []string{"bwg-start-platform-1-game"}, resolveNetworks(pipeline, []string{"game"}))
+}
+
+func TestTeardownInstance(t *testing.T) {
+	var mu sync.Mutex
+	var removed, queries []string
+	record := func(list *[]string, value string) {
+		mu.Lock()
+		defer mu.Unlock()
+		*list = append(*list, value)
+	}
+	writeJSON := func(w http.ResponseWriter, r *http.Request, v any) {
+		record(&queries, r.URL.Query().Get("filters"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	// 模拟 Docker API，返回实例的托管容器、网络和卷
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1.47/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, []map[string]any{{"Id": "container-1"}})
+	})
+	mux.HandleFunc("GET /v1.47/networks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, []map[string]any{
+			{"Id": "network-1", "Name": "bwg-inst-1-game", "Labels": map[string]string{}},
+			{"Id": "network-2", "Name": "bwg-inst-1-shared", "Labels": map[string]string{LabelLifecycle: "retain"}},
+		})
+	})
+	mux.HandleFunc("GET /v1.47/volumes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, map[string]any{"Volumes": []map[string]any{
+			{"Name": "bwg-inst-1-system", "Labels": map[string]string{LabelLifecycle: "retain"}},
+			{"Name": "bwg-inst-1-cache", "Labels": map[string]string{LabelLifecycle: "remove"}},
+			{"Name": "bwg-inst-1-legacy", "Labels": map[string]string{}},
+		}})
+	})
+	mux.HandleFunc("DELETE /v1.47/{kind}/{id}", func(w http.ResponseWriter, r *http.Request) {
+		record(&removed, r.PathValue("kind")+"/"+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cli, err := client.NewClientWithOpts(
+		client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")),
+		client.WithHTTPClient(server.Client()),
+		client.WithVersion("1.47"),
+	)
+	require.NoError(t, err)
+	manager := &ContainerManager{cli: cli, logger: utils.New("ResourceTest"), started: make(map[string]containerStarted)}
+
+	// 删除容器，网络默认删除，卷只删除声明为 remove 的，其余按策略保留
+	require.NoError(t, manager.TeardownInstance(context.Background(), "inst-1"))
+	assert.Equal(t, []string{
+		"containers/container-1",
+		"networks/network-1",
+		"volumes/bwg-inst-1-cache",
+	}, removed)
+
+	// 只列出属于该实例的托管资源
+	require.Len(t, queries, 3)
+	for _, query := range queries {
+		assert.Contains(t, query, LabelManaged+"=true")
+		assert.Contains(t, query, LabelInstance+"=inst-1")
+	}
+}
@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+)
+
+// templateExpr 匹配模板中的 ${{ args.X }} 与 ${{ envs.X }} 表达式
+var templateExpr = regexp.MustCompile(`\$\{\{\s*(args|envs)\.([A-Za-z0-9_]+)\s*\}\}`)
+
+// secretExpr 匹配敏感参数的 ${{ secrets.X }} 引用，渲染模板时敏感参数渲染为该引用，下发时再替换为参数值
+var secretExpr = regexp.MustCompile(`\$\{\{\s*secrets\.([A-Za-z0-9_]+)\s*\}\}`)
+
+// templateHeader 模板中声明的参数和环境变量
+type templateHeader struct {
+	Envs []string `yaml:"envs"`
+	Args []string `yaml:"args"`
+}
+
+// LoadTemplate 读取流水线模板目录中的模板文件
+func LoadTemplate(dir, name string) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("无效的模板名称: %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("流水线模板不存在: %s", name)
+		}
+		return nil, fmt.Errorf("读取流水线模板失败: %w", err)
+	}
+	return data, nil
+}
+
+// RenderTemplate 使用参数和环境变量渲染流水线模板
+// 替换只发生在 YAML 标量内部，变量值中的特殊字符不会破坏模板结构；
+// 模板声明的参数必须全部提供，未配置的环境变量渲染为空字符串
+func RenderTemplate(data []byte, args, envs map[string]string) (*models.GamePipeline, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析流水线模板失败: %w", err)
+	}
+
+	var header templateHeader
+	if err := root.Decode(&header); err != nil {
+		return nil, fmt.Errorf("解析流水线模板失败: %w", err)
+	}
+
+	var missing []string
+	for _, name := range header.Args {
+		if _, ok := args[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("缺少流水线参数: %s", strings.Join(missing, ", "))
+	}
+
+	if err := renderNode(&root, args, envs); err != nil {
+		return nil, err
+	}
+
+	var pipeline models.GamePipeline
+	if err := root.Decode(&pipeline); err != nil {
+		return nil, fmt.Errorf("解析渲染后的流水线失败: %w", err)
+	}
+
+	// 未指定类型的步骤按容器步骤执行
+	for i := range pipeline.Steps {
+		if pipeline.Steps[i].Type == "" {
+			pipeline.Steps[i].Type = "container"
+		}
+	}
+
+	return &pipeline, nil
+}
+
+// renderNode 递归替换节点中的模板表达式
+func renderNode(node *yaml.Node, args, envs map[string]string) error {
+	if node.Kind == yaml.ScalarNode {
+		var renderErr error
+		node.Value = templateExpr.ReplaceAllStringFunc(node.Value, func(expr string) string {
+			m := templateExpr.FindStringSubmatch(expr)
+			if m[1] == "envs" {
+				return envs[m[2]]
+			}
+			value, ok := args[m[2]]
+			if !ok && renderErr == nil {
+				renderErr = fmt.Errorf("模板引用了未声明的参数: %s", m[2])
+			}
+			return value
+		})
+		return renderErr
+	}
+	for _, child := range node.Content {
+		if err := renderNode(child, args, envs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SecretRef 敏感参数的引用表达式，作为参数值渲染模板后流水线中只保留引用
+func SecretRef(name string) string {
+	return "${{ secrets." + name + " }}"
+}
+
+// RenderSecrets 将流水线中敏感参数的引用替换为参数值，返回替换后的副本，不修改原流水线
+func RenderSecrets(pipeline *models.GamePipeline, secrets map[string]string) (*models.GamePipeline, error) {
+	data, err := yaml.Marshal(pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("序列化流水线失败: %w", err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析流水线失败: %w", err)
+	}
+	if err := renderSecretNode(&root, secrets); err != nil {
+		return nil, err
+	}
+
+	var rendered models.GamePipeline
+	if err := root.Decode(&rendered); err != nil {
+		return nil, fmt.Errorf("解析替换后的流水线失败: %w", err)
+	}
+	return &rendered, nil
+}
+
+// renderSecretNode 递归替换节点中敏感参数的引用
+func renderSecretNode(node *yaml.Node, secrets map[string]string) error {
+	if node.Kind == yaml.ScalarNode {
+		var renderErr error
+		node.Value = secretExpr.ReplaceAllStringFunc(node.Value, func(expr string) string {
+			name := secretExpr.FindStringSubmatch(expr)[1]
+			value, ok := secrets[name]
+			if !ok && renderErr == nil {
+				renderErr = fmt.Errorf("缺少敏感参数: %s", name)
+			}
+			return value
+		})
+		return renderErr
+	}
+	for _, child := range node.Content {
+		if err := renderSecretNode(child, secrets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTemplate = `name: Test
+envs:
+  - S3_URL
+args:
+  - INSTANCE
+  - PORT
+steps:
+  - name: fetch
+    container:
+      image: alpine
+      commands:
+        - echo ${{ envs.S3_URL }} ${{ args.INSTANCE }}
+  - name: game
+    type: service
+    container:
+      image: game
+      ports:
+        - "${{ args.PORT }}:8080"
+      environment:
+        PASSWD: ${{ envs.PASSWD }}
+`
+
+func TestRenderTemplate(t *testing.T) {
+	pipeline, err := RenderTemplate([]byte(testTemplate),
+		map[string]string{"INSTANCE": "i-1", "PORT": "30001"},
+		map[string]string{"S3_URL": "http://minio:9000 #tag"},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "container", pipeline.Steps[0].Type, "未指定类型的步骤默认为容器步骤")
+	assert.Equal(t, "service", pipeline.Steps[1].Type)
+	assert.Equal(t, []string{"echo http://minio:9000 #tag i-1"}, pipeline.Steps[0].Container.Commands)
+	assert.Equal(t, []string{"30001:8080"}, pipeline.Steps[1].Container.Ports)
+	assert.Equal(t, "", pipeline.Steps[1].Container.Environment["PASSWD"], "未配置的环境变量渲染为空")
+}
+
+func TestRenderTemplateMissingArgs(t *testing.T) {
+	_, err := RenderTemplate([]byte(testTemplate), map[string]string{"INSTANCE": "i-1"}, nil)
+	assert.ErrorContains(t, err, "PORT")
+}
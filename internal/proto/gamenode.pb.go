@@ -83,6 +83,7 @@ type RegisterRequest struct {
 	Hardware      map[string]string      `protobuf:"bytes,6,rep,name=hardware,proto3" json:"hardware,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	System        map[string]string      `protobuf:"bytes,7,rep,name=system,proto3" json:"system,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	Labels        map[string]string      `protobuf:"bytes,8,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	JoinToken     string                 `protobuf:"bytes,9,opt,name=join_token,json=joinToken,proto3" json:"join_token,omitempty"` // 加入令牌，首次注册或凭证丢失时使用
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -173,11 +174,20 @@ func (x *RegisterRequest) GetLabels() map[string]string {
 	return nil
 }
 
+func (x *RegisterRequest) GetJoinToken() string {
+	if x != nil {
+		return x.JoinToken
+	}
+	return ""
+}
+
 type RegisterResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	State         GameNodeStaticState    `protobuf:"varint,3,opt,name=state,proto3,enum=gamenode.GameNodeStaticState" json:"state,omitempty"` // 节点维护状态
+	Credential    string                 `protobuf:"bytes,4,opt,name=credential,proto3" json:"credential,omitempty"`                          // 新签发的节点凭证，只返回一次
+	Approval      string                 `protobuf:"bytes,5,opt,name=approval,proto3" json:"approval,omitempty"`                              // 节点准入审批状态：pending/approved/rejected
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -233,6 +243,20 @@ func (x *RegisterResponse) GetState() GameNodeStaticState {
 	return GameNodeStaticState_NODE_STATE_NORMAL
 }
 
+func (x *RegisterResponse) GetCredential() string {
+	if x != nil {
+		return x.Credential
+	}
+	return ""
+}
+
+func (x *RegisterResponse) GetApproval() string {
+	if x != nil {
+		return x.Approval
+	}
+	return ""
+}
+
 // 心跳
 type HeartbeatRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -298,6 +322,7 @@ type HeartbeatResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	State         GameNodeStaticState    `protobuf:"varint,3,opt,name=state,proto3,enum=gamenode.GameNodeStaticState" json:"state,omitempty"` // 节点维护状态
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -346,12 +371,20 @@ func (x *HeartbeatResponse) GetMessage() string {
 	return ""
 }
 
+func (x *HeartbeatResponse) GetState() GameNodeStaticState {
+	if x != nil {
+		return x.State
+	}
+	return GameNodeStaticState_NODE_STATE_NORMAL
+}
+
 // 节点指标报告
 type MetricsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Metrics       *MetricsInfo           `protobuf:"bytes,3,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	Sequence      int64                  `protobuf:"varint,4,opt,name=sequence,proto3" json:"sequence,omitempty"` // 上报序号，单调递增，用于丢弃重放时重复或过期的指标
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -407,6 +440,13 @@ func (x *MetricsRequest) GetMetrics() *MetricsInfo {
 	return nil
 }
 
+func (x *MetricsRequest) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
 type MetricsInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Cpus          []*CPUMetrics          `protobuf:"bytes,1,rep,name=cpus,proto3" json:"cpus,omitempty"`
@@ -1028,6 +1068,380 @@ func (x *ResourceResponse) GetMessage() string {
 	return ""
 }
 
+// 容器清单上报，Agent 启动、重连、托管容器变化时和定期全量上报
+type ContainersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Containers    []*ContainerInfo       `protobuf:"bytes,3,rep,name=containers,proto3" json:"containers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainersRequest) Reset() {
+	*x = ContainersRequest{}
+	mi := &file_internal_proto_gamenode_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainersRequest) ProtoMessage() {}
+
+func (x *ContainersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamenode_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainersRequest.ProtoReflect.Descriptor instead.
+func (*ContainersRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ContainersRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ContainersRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ContainersRequest) GetContainers() []*ContainerInfo {
+	if x != nil {
+		return x.Containers
+	}
+	return nil
+}
+
+type ContainerInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Image         string                 `protobuf:"bytes,3,opt,name=image,proto3" json:"image,omitempty"`
+	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	PipelineId    string                 `protobuf:"bytes,5,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
+	InstanceId    string                 `protobuf:"bytes,6,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"` // 最近一次启动时间，未启动过时为空
+	Ports         []*ContainerPort       `protobuf:"bytes,9,rep,name=ports,proto3" json:"ports,omitempty"`                          // 端口映射
+	Activity      *ContainerActivity     `protobuf:"bytes,10,opt,name=activity,proto3" json:"activity,omitempty"`                   // 运行中实例容器的活动采样，用于空闲检测
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainerInfo) Reset() {
+	*x = ContainerInfo{}
+	mi := &file_internal_proto_gamenode_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerInfo) ProtoMessage() {}
+
+func (x *ContainerInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamenode_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerInfo.ProtoReflect.Descriptor instead.
+func (*ContainerInfo) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ContainerInfo) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ContainerInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ContainerInfo) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *ContainerInfo) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *ContainerInfo) GetPipelineId() string {
+	if x != nil {
+		return x.PipelineId
+	}
+	return ""
+}
+
+func (x *ContainerInfo) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *ContainerInfo) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *ContainerInfo) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *ContainerInfo) GetPorts() []*ContainerPort {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+func (x *ContainerInfo) GetActivity() *ContainerActivity {
+	if x != nil {
+		return x.Activity
+	}
+	return nil
+}
+
+// 容器活动采样，无法采集的指标为 -1
+type ContainerActivity struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	SampledAt        *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=sampled_at,json=sampledAt,proto3" json:"sampled_at,omitempty"`
+	Connections      int32                  `protobuf:"varint,2,opt,name=connections,proto3" json:"connections,omitempty"`                                     // 已发布端口上的 TCP 连接数，即 Selkies 连接数
+	CpuPercent       float64                `protobuf:"fixed64,3,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`                    // CPU 使用率，单核满载为 100
+	GpuPercent       float64                `protobuf:"fixed64,4,opt,name=gpu_percent,json=gpuPercent,proto3" json:"gpu_percent,omitempty"`                    // 容器进程的 GPU 使用率
+	InputIdleSeconds int64                  `protobuf:"varint,5,opt,name=input_idle_seconds,json=inputIdleSeconds,proto3" json:"input_idle_seconds,omitempty"` // 节点输入设备距最近一次输入事件的秒数
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ContainerActivity) Reset() {
+	*x = ContainerActivity{}
+	mi := &file_internal_proto_gamenode_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerActivity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerActivity) ProtoMessage() {}
+
+func (x *ContainerActivity) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamenode_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerActivity.ProtoReflect.Descriptor instead.
+func (*ContainerActivity) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ContainerActivity) GetSampledAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SampledAt
+	}
+	return nil
+}
+
+func (x *ContainerActivity) GetConnections() int32 {
+	if x != nil {
+		return x.Connections
+	}
+	return 0
+}
+
+func (x *ContainerActivity) GetCpuPercent() float64 {
+	if x != nil {
+		return x.CpuPercent
+	}
+	return 0
+}
+
+func (x *ContainerActivity) GetGpuPercent() float64 {
+	if x != nil {
+		return x.GpuPercent
+	}
+	return 0
+}
+
+func (x *ContainerActivity) GetInputIdleSeconds() int64 {
+	if x != nil {
+		return x.InputIdleSeconds
+	}
+	return 0
+}
+
+type ContainerPort struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrivatePort   uint32                 `protobuf:"varint,1,opt,name=private_port,json=privatePort,proto3" json:"private_port,omitempty"` // 容器端口
+	PublicPort    uint32                 `protobuf:"varint,2,opt,name=public_port,json=publicPort,proto3" json:"public_port,omitempty"`    // 主机端口，未映射时为 0
+	Protocol      string                 `protobuf:"bytes,3,opt,name=protocol,proto3" json:"protocol,omitempty"`                           // tcp/udp
+	Ip            string                 `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`                                       // 主机监听地址
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainerPort) Reset() {
+	*x = ContainerPort{}
+	mi := &file_internal_proto_gamenode_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerPort) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerPort) ProtoMessage() {}
+
+func (x *ContainerPort) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamenode_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerPort.ProtoReflect.Descriptor instead.
+func (*ContainerPort) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ContainerPort) GetPrivatePort() uint32 {
+	if x != nil {
+		return x.PrivatePort
+	}
+	return 0
+}
+
+func (x *ContainerPort) GetPublicPort() uint32 {
+	if x != nil {
+		return x.PublicPort
+	}
+	return 0
+}
+
+func (x *ContainerPort) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *ContainerPort) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+type ContainersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainersResponse) Reset() {
+	*x = ContainersResponse{}
+	mi := &file_internal_proto_gamenode_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainersResponse) ProtoMessage() {}
+
+func (x *ContainersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamenode_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainersResponse.ProtoReflect.Descriptor instead.
+func (*ContainersResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ContainersResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ContainersResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 // 状态变更请求
 type StateChangeRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1040,7 +1454,7 @@ type StateChangeRequest struct {
 
 func (x *StateChangeRequest) Reset() {
 	*x = StateChangeRequest{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[14]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1052,7 +1466,7 @@ func (x *StateChangeRequest) String() string {
 func (*StateChangeRequest) ProtoMessage() {}
 
 func (x *StateChangeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[14]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1065,7 +1479,7 @@ func (x *StateChangeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StateChangeRequest.ProtoReflect.Descriptor instead.
 func (*StateChangeRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{14}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *StateChangeRequest) GetNodeId() string {
@@ -1100,7 +1514,7 @@ type StateChangeResponse struct {
 
 func (x *StateChangeResponse) Reset() {
 	*x = StateChangeResponse{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[15]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1112,7 +1526,7 @@ func (x *StateChangeResponse) String() string {
 func (*StateChangeResponse) ProtoMessage() {}
 
 func (x *StateChangeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[15]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1125,7 +1539,7 @@ func (x *StateChangeResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StateChangeResponse.ProtoReflect.Descriptor instead.
 func (*StateChangeResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{15}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *StateChangeResponse) GetSuccess() bool {
@@ -1163,7 +1577,7 @@ type HardwareInfo struct {
 
 func (x *HardwareInfo) Reset() {
 	*x = HardwareInfo{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[16]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1175,7 +1589,7 @@ func (x *HardwareInfo) String() string {
 func (*HardwareInfo) ProtoMessage() {}
 
 func (x *HardwareInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[16]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1188,7 +1602,7 @@ func (x *HardwareInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HardwareInfo.ProtoReflect.Descriptor instead.
 func (*HardwareInfo) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{16}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *HardwareInfo) GetCpus() []*CPUHardware {
@@ -1240,7 +1654,7 @@ type CPUHardware struct {
 
 func (x *CPUHardware) Reset() {
 	*x = CPUHardware{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[17]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1252,7 +1666,7 @@ func (x *CPUHardware) String() string {
 func (*CPUHardware) ProtoMessage() {}
 
 func (x *CPUHardware) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[17]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1265,7 +1679,7 @@ func (x *CPUHardware) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CPUHardware.ProtoReflect.Descriptor instead.
 func (*CPUHardware) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{17}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *CPUHardware) GetModel() string {
@@ -1321,7 +1735,7 @@ type MemoryHardware struct {
 
 func (x *MemoryHardware) Reset() {
 	*x = MemoryHardware{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[18]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1333,7 +1747,7 @@ func (x *MemoryHardware) String() string {
 func (*MemoryHardware) ProtoMessage() {}
 
 func (x *MemoryHardware) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[18]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1346,7 +1760,7 @@ func (x *MemoryHardware) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemoryHardware.ProtoReflect.Descriptor instead.
 func (*MemoryHardware) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{18}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *MemoryHardware) GetSize() int64 {
@@ -1384,7 +1798,7 @@ type GPUHardware struct {
 
 func (x *GPUHardware) Reset() {
 	*x = GPUHardware{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[19]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1396,7 +1810,7 @@ func (x *GPUHardware) String() string {
 func (*GPUHardware) ProtoMessage() {}
 
 func (x *GPUHardware) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[19]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1409,7 +1823,7 @@ func (x *GPUHardware) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GPUHardware.ProtoReflect.Descriptor instead.
 func (*GPUHardware) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{19}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *GPUHardware) GetModel() string {
@@ -1466,7 +1880,7 @@ type StorageDevice struct {
 
 func (x *StorageDevice) Reset() {
 	*x = StorageDevice{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[20]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1478,7 +1892,7 @@ func (x *StorageDevice) String() string {
 func (*StorageDevice) ProtoMessage() {}
 
 func (x *StorageDevice) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[20]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1491,7 +1905,7 @@ func (x *StorageDevice) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StorageDevice.ProtoReflect.Descriptor instead.
 func (*StorageDevice) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{20}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *StorageDevice) GetType() string {
@@ -1534,7 +1948,7 @@ type NetworkDevice struct {
 
 func (x *NetworkDevice) Reset() {
 	*x = NetworkDevice{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[21]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1546,7 +1960,7 @@ func (x *NetworkDevice) String() string {
 func (*NetworkDevice) ProtoMessage() {}
 
 func (x *NetworkDevice) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[21]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1559,7 +1973,7 @@ func (x *NetworkDevice) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NetworkDevice.ProtoReflect.Descriptor instead.
 func (*NetworkDevice) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{21}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *NetworkDevice) GetName() string {
@@ -1608,7 +2022,7 @@ type SystemInfo struct {
 
 func (x *SystemInfo) Reset() {
 	*x = SystemInfo{}
-	mi := &file_internal_proto_gamenode_proto_msgTypes[22]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1620,7 +2034,7 @@ func (x *SystemInfo) String() string {
 func (*SystemInfo) ProtoMessage() {}
 
 func (x *SystemInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamenode_proto_msgTypes[22]
+	mi := &file_internal_proto_gamenode_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1633,7 +2047,7 @@ func (x *SystemInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SystemInfo.ProtoReflect.Descriptor instead.
 func (*SystemInfo) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{22}
+	return file_internal_proto_gamenode_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *SystemInfo) GetOsDistribution() string {
@@ -1703,7 +2117,7 @@ var File_internal_proto_gamenode_proto protoreflect.FileDescriptor
 
 const file_internal_proto_gamenode_proto_rawDesc = "" +
 	"\n" +
-	"\x1dinternal/proto/gamenode.proto\x12\bgamenode\x1a\x1fgoogle/protobuf/timestamp.proto\"\xf3\x03\n" +
+	"\x1dinternal/proto/gamenode.proto\x12\bgamenode\x1a\x1fgoogle/protobuf/timestamp.proto\"\x92\x04\n" +
 	"\x0fRegisterRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
 	"\x05alias\x18\x02 \x01(\tR\x05alias\x12\x14\n" +
@@ -1712,7 +2126,9 @@ const file_internal_proto_gamenode_proto_rawDesc = "" +
 	"\blocation\x18\x05 \x01(\tR\blocation\x12C\n" +
 	"\bhardware\x18\x06 \x03(\v2'.gamenode.RegisterRequest.HardwareEntryR\bhardware\x12=\n" +
 	"\x06system\x18\a \x03(\v2%.gamenode.RegisterRequest.SystemEntryR\x06system\x12=\n" +
-	"\x06labels\x18\b \x03(\v2%.gamenode.RegisterRequest.LabelsEntryR\x06labels\x1a;\n" +
+	"\x06labels\x18\b \x03(\v2%.gamenode.RegisterRequest.LabelsEntryR\x06labels\x12\x1d\n" +
+	"\n" +
+	"join_token\x18\t \x01(\tR\tjoinToken\x1a;\n" +
 	"\rHardwareEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
@@ -1721,23 +2137,29 @@ const file_internal_proto_gamenode_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
 	"\vLabelsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"{\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb7\x01\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x123\n" +
-	"\x05state\x18\x03 \x01(\x0e2\x1d.gamenode.GameNodeStaticStateR\x05state\"_\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x1d.gamenode.GameNodeStaticStateR\x05state\x12\x1e\n" +
+	"\n" +
+	"credential\x18\x04 \x01(\tR\n" +
+	"credential\x12\x1a\n" +
+	"\bapproval\x18\x05 \x01(\tR\bapproval\"_\n" +
 	"\x10HeartbeatRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x02 \x01(\tR\tsessionId\x12\x1c\n" +
-	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"E\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"z\n" +
 	"\x11HeartbeatResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"o\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x123\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x1d.gamenode.GameNodeStaticStateR\x05state\"\x8b\x01\n" +
 	"\x0eMetricsRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1c\n" +
 	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12/\n" +
-	"\ametrics\x18\x03 \x01(\v2\x15.gamenode.MetricsInfoR\ametrics\"\xfc\x01\n" +
+	"\ametrics\x18\x03 \x01(\v2\x15.gamenode.MetricsInfoR\ametrics\x12\x1a\n" +
+	"\bsequence\x18\x04 \x01(\x03R\bsequence\"\xfc\x01\n" +
 	"\vMetricsInfo\x12(\n" +
 	"\x04cpus\x18\x01 \x03(\v2\x14.gamenode.CPUMetricsR\x04cpus\x12/\n" +
 	"\x06memory\x18\x02 \x01(\v2\x17.gamenode.MemoryMetricsR\x06memory\x12(\n" +
@@ -1787,6 +2209,46 @@ const file_internal_proto_gamenode_proto_rawDesc = "" +
 	"\x06system\x18\x04 \x01(\v2\x14.gamenode.SystemInfoR\x06system\"F\n" +
 	"\x10ResourceResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x83\x01\n" +
+	"\x11ContainersRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x127\n" +
+	"\n" +
+	"containers\x18\x03 \x03(\v2\x17.gamenode.ContainerInfoR\n" +
+	"containers\"\xff\x02\n" +
+	"\rContainerInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05image\x18\x03 \x01(\tR\x05image\x12\x14\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12\x1f\n" +
+	"\vpipeline_id\x18\x05 \x01(\tR\n" +
+	"pipelineId\x12\x1f\n" +
+	"\vinstance_id\x18\x06 \x01(\tR\n" +
+	"instanceId\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"started_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12-\n" +
+	"\x05ports\x18\t \x03(\v2\x17.gamenode.ContainerPortR\x05ports\x127\n" +
+	"\bactivity\x18\n" +
+	" \x01(\v2\x1b.gamenode.ContainerActivityR\bactivity\"\xe0\x01\n" +
+	"\x11ContainerActivity\x129\n" +
+	"\n" +
+	"sampled_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\tsampledAt\x12 \n" +
+	"\vconnections\x18\x02 \x01(\x05R\vconnections\x12\x1f\n" +
+	"\vcpu_percent\x18\x03 \x01(\x01R\n" +
+	"cpuPercent\x12\x1f\n" +
+	"\vgpu_percent\x18\x04 \x01(\x01R\n" +
+	"gpuPercent\x12,\n" +
+	"\x12input_idle_seconds\x18\x05 \x01(\x03R\x10inputIdleSeconds\"\x7f\n" +
+	"\rContainerPort\x12!\n" +
+	"\fprivate_port\x18\x01 \x01(\rR\vprivatePort\x12\x1f\n" +
+	"\vpublic_port\x18\x02 \x01(\rR\n" +
+	"publicPort\x12\x1a\n" +
+	"\bprotocol\x18\x03 \x01(\tR\bprotocol\x12\x0e\n" +
+	"\x02ip\x18\x04 \x01(\tR\x02ip\"H\n" +
+	"\x12ContainersResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\"\xac\x01\n" +
 	"\x12StateChangeRequest\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12@\n" +
@@ -1848,13 +2310,14 @@ const file_internal_proto_gamenode_proto_rawDesc = "" +
 	"\x13GameNodeStaticState\x12\x15\n" +
 	"\x11NODE_STATE_NORMAL\x10\x00\x12\x1a\n" +
 	"\x16NODE_STATE_MAINTENANCE\x10\x01\x12\x17\n" +
-	"\x13NODE_STATE_DISABLED\x10\x022\xfd\x02\n" +
+	"\x13NODE_STATE_DISABLED\x10\x022\xcc\x03\n" +
 	"\x13GameNodeGRPCService\x12A\n" +
 	"\bRegister\x12\x19.gamenode.RegisterRequest\x1a\x1a.gamenode.RegisterResponse\x12D\n" +
 	"\tHeartbeat\x12\x1a.gamenode.HeartbeatRequest\x1a\x1b.gamenode.HeartbeatResponse\x12D\n" +
 	"\rReportMetrics\x12\x18.gamenode.MetricsRequest\x1a\x19.gamenode.MetricsResponse\x12G\n" +
 	"\x0eReportResource\x12\x19.gamenode.ResourceRequest\x1a\x1a.gamenode.ResourceResponse\x12N\n" +
-	"\x0fUpdateNodeState\x12\x1c.gamenode.StateChangeRequest\x1a\x1d.gamenode.StateChangeResponseB8Z6github.com/open-beagle/beagle-wind-game/internal/protob\x06proto3"
+	"\x0fUpdateNodeState\x12\x1c.gamenode.StateChangeRequest\x1a\x1d.gamenode.StateChangeResponse\x12M\n" +
+	"\x10ReportContainers\x12\x1b.gamenode.ContainersRequest\x1a\x1c.gamenode.ContainersResponseB8Z6github.com/open-beagle/beagle-wind-game/internal/protob\x06proto3"
 
 var (
 	file_internal_proto_gamenode_proto_rawDescOnce sync.Once
@@ -1869,7 +2332,7 @@ func file_internal_proto_gamenode_proto_rawDescGZIP() []byte {
 }
 
 var file_internal_proto_gamenode_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_internal_proto_gamenode_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_internal_proto_gamenode_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
 var file_internal_proto_gamenode_proto_goTypes = []any{
 	(GameNodeStaticState)(0),      // 0: gamenode.GameNodeStaticState
 	(*RegisterRequest)(nil),       // 1: gamenode.RegisterRequest
@@ -1886,56 +2349,70 @@ var file_internal_proto_gamenode_proto_goTypes = []any{
 	(*MetricsResponse)(nil),       // 12: gamenode.MetricsResponse
 	(*ResourceRequest)(nil),       // 13: gamenode.ResourceRequest
 	(*ResourceResponse)(nil),      // 14: gamenode.ResourceResponse
-	(*StateChangeRequest)(nil),    // 15: gamenode.StateChangeRequest
-	(*StateChangeResponse)(nil),   // 16: gamenode.StateChangeResponse
-	(*HardwareInfo)(nil),          // 17: gamenode.HardwareInfo
-	(*CPUHardware)(nil),           // 18: gamenode.CPUHardware
-	(*MemoryHardware)(nil),        // 19: gamenode.MemoryHardware
-	(*GPUHardware)(nil),           // 20: gamenode.GPUHardware
-	(*StorageDevice)(nil),         // 21: gamenode.StorageDevice
-	(*NetworkDevice)(nil),         // 22: gamenode.NetworkDevice
-	(*SystemInfo)(nil),            // 23: gamenode.SystemInfo
-	nil,                           // 24: gamenode.RegisterRequest.HardwareEntry
-	nil,                           // 25: gamenode.RegisterRequest.SystemEntry
-	nil,                           // 26: gamenode.RegisterRequest.LabelsEntry
-	(*timestamppb.Timestamp)(nil), // 27: google.protobuf.Timestamp
+	(*ContainersRequest)(nil),     // 15: gamenode.ContainersRequest
+	(*ContainerInfo)(nil),         // 16: gamenode.ContainerInfo
+	(*ContainerActivity)(nil),     // 17: gamenode.ContainerActivity
+	(*ContainerPort)(nil),         // 18: gamenode.ContainerPort
+	(*ContainersResponse)(nil),    // 19: gamenode.ContainersResponse
+	(*StateChangeRequest)(nil),    // 20: gamenode.StateChangeRequest
+	(*StateChangeResponse)(nil),   // 21: gamenode.StateChangeResponse
+	(*HardwareInfo)(nil),          // 22: gamenode.HardwareInfo
+	(*CPUHardware)(nil),           // 23: gamenode.CPUHardware
+	(*MemoryHardware)(nil),        // 24: gamenode.MemoryHardware
+	(*GPUHardware)(nil),           // 25: gamenode.GPUHardware
+	(*StorageDevice)(nil),         // 26: gamenode.StorageDevice
+	(*NetworkDevice)(nil),         // 27: gamenode.NetworkDevice
+	(*SystemInfo)(nil),            // 28: gamenode.SystemInfo
+	nil,                           // 29: gamenode.RegisterRequest.HardwareEntry
+	nil,                           // 30: gamenode.RegisterRequest.SystemEntry
+	nil,                           // 31: gamenode.RegisterRequest.LabelsEntry
+	(*timestamppb.Timestamp)(nil), // 32: google.protobuf.Timestamp
 }
 var file_internal_proto_gamenode_proto_depIdxs = []int32{
-	24, // 0: gamenode.RegisterRequest.hardware:type_name -> gamenode.RegisterRequest.HardwareEntry
-	25, // 1: gamenode.RegisterRequest.system:type_name -> gamenode.RegisterRequest.SystemEntry
-	26, // 2: gamenode.RegisterRequest.labels:type_name -> gamenode.RegisterRequest.LabelsEntry
+	29, // 0: gamenode.RegisterRequest.hardware:type_name -> gamenode.RegisterRequest.HardwareEntry
+	30, // 1: gamenode.RegisterRequest.system:type_name -> gamenode.RegisterRequest.SystemEntry
+	31, // 2: gamenode.RegisterRequest.labels:type_name -> gamenode.RegisterRequest.LabelsEntry
 	0,  // 3: gamenode.RegisterResponse.state:type_name -> gamenode.GameNodeStaticState
-	6,  // 4: gamenode.MetricsRequest.metrics:type_name -> gamenode.MetricsInfo
-	7,  // 5: gamenode.MetricsInfo.cpus:type_name -> gamenode.CPUMetrics
-	8,  // 6: gamenode.MetricsInfo.memory:type_name -> gamenode.MemoryMetrics
-	9,  // 7: gamenode.MetricsInfo.gpus:type_name -> gamenode.GPUMetrics
-	10, // 8: gamenode.MetricsInfo.storages:type_name -> gamenode.StorageMetrics
-	11, // 9: gamenode.MetricsInfo.network:type_name -> gamenode.NetworkMetrics
-	17, // 10: gamenode.ResourceRequest.hardware:type_name -> gamenode.HardwareInfo
-	23, // 11: gamenode.ResourceRequest.system:type_name -> gamenode.SystemInfo
-	0,  // 12: gamenode.StateChangeRequest.target_state:type_name -> gamenode.GameNodeStaticState
-	27, // 13: gamenode.StateChangeRequest.change_time:type_name -> google.protobuf.Timestamp
-	27, // 14: gamenode.StateChangeResponse.confirm_time:type_name -> google.protobuf.Timestamp
-	18, // 15: gamenode.HardwareInfo.cpus:type_name -> gamenode.CPUHardware
-	19, // 16: gamenode.HardwareInfo.memories:type_name -> gamenode.MemoryHardware
-	20, // 17: gamenode.HardwareInfo.gpus:type_name -> gamenode.GPUHardware
-	21, // 18: gamenode.HardwareInfo.storages:type_name -> gamenode.StorageDevice
-	22, // 19: gamenode.HardwareInfo.networks:type_name -> gamenode.NetworkDevice
-	1,  // 20: gamenode.GameNodeGRPCService.Register:input_type -> gamenode.RegisterRequest
-	3,  // 21: gamenode.GameNodeGRPCService.Heartbeat:input_type -> gamenode.HeartbeatRequest
-	5,  // 22: gamenode.GameNodeGRPCService.ReportMetrics:input_type -> gamenode.MetricsRequest
-	13, // 23: gamenode.GameNodeGRPCService.ReportResource:input_type -> gamenode.ResourceRequest
-	15, // 24: gamenode.GameNodeGRPCService.UpdateNodeState:input_type -> gamenode.StateChangeRequest
-	2,  // 25: gamenode.GameNodeGRPCService.Register:output_type -> gamenode.RegisterResponse
-	4,  // 26: gamenode.GameNodeGRPCService.Heartbeat:output_type -> gamenode.HeartbeatResponse
-	12, // 27: gamenode.GameNodeGRPCService.ReportMetrics:output_type -> gamenode.MetricsResponse
-	14, // 28: gamenode.GameNodeGRPCService.ReportResource:output_type -> gamenode.ResourceResponse
-	16, // 29: gamenode.GameNodeGRPCService.UpdateNodeState:output_type -> gamenode.StateChangeResponse
-	25, // [25:30] is the sub-list for method output_type
-	20, // [20:25] is the sub-list for method input_type
-	20, // [20:20] is the sub-list for extension type_name
-	20, // [20:20] is the sub-list for extension extendee
-	0,  // [0:20] is the sub-list for field type_name
+	0,  // 4: gamenode.HeartbeatResponse.state:type_name -> gamenode.GameNodeStaticState
+	6,  // 5: gamenode.MetricsRequest.metrics:type_name -> gamenode.MetricsInfo
+	7,  // 6: gamenode.MetricsInfo.cpus:type_name -> gamenode.CPUMetrics
+	8,  // 7: gamenode.MetricsInfo.memory:type_name -> gamenode.MemoryMetrics
+	9,  // 8: gamenode.MetricsInfo.gpus:type_name -> gamenode.GPUMetrics
+	10, // 9: gamenode.MetricsInfo.storages:type_name -> gamenode.StorageMetrics
+	11, // 10: gamenode.MetricsInfo.network:type_name -> gamenode.NetworkMetrics
+	22, // 11: gamenode.ResourceRequest.hardware:type_name -> gamenode.HardwareInfo
+	28, // 12: gamenode.ResourceRequest.system:type_name -> gamenode.SystemInfo
+	16, // 13: gamenode.ContainersRequest.containers:type_name -> gamenode.ContainerInfo
+	32, // 14: gamenode.ContainerInfo.created_at:type_name -> google.protobuf.Timestamp
+	32, // 15: gamenode.ContainerInfo.started_at:type_name -> google.protobuf.Timestamp
+	18, // 16: gamenode.ContainerInfo.ports:type_name -> gamenode.ContainerPort
+	17, // 17: gamenode.ContainerInfo.activity:type_name -> gamenode.ContainerActivity
+	32, // 18: gamenode.ContainerActivity.sampled_at:type_name -> google.protobuf.Timestamp
+	0,  // 19: gamenode.StateChangeRequest.target_state:type_name -> gamenode.GameNodeStaticState
+	32, // 20: gamenode.StateChangeRequest.change_time:type_name -> google.protobuf.Timestamp
+	32, // 21: gamenode.StateChangeResponse.confirm_time:type_name -> google.protobuf.Timestamp
+	23, // 22: gamenode.HardwareInfo.cpus:type_name -> gamenode.CPUHardware
+	24, // 23: gamenode.HardwareInfo.memories:type_name -> gamenode.MemoryHardware
+	25, // 24: gamenode.HardwareInfo.gpus:type_name -> gamenode.GPUHardware
+	26, // 25: gamenode.HardwareInfo.storages:type_name -> gamenode.StorageDevice
+	27, // 26: gamenode.HardwareInfo.networks:type_name -> gamenode.NetworkDevice
+	1,  // 27: gamenode.GameNodeGRPCService.Register:input_type -> gamenode.RegisterRequest
+	3,  // 28: gamenode.GameNodeGRPCService.Heartbeat:input_type -> gamenode.HeartbeatRequest
+	5,  // 29: gamenode.GameNodeGRPCService.ReportMetrics:input_type -> gamenode.MetricsRequest
+	13, // 30: gamenode.GameNodeGRPCService.ReportResource:input_type -> gamenode.ResourceRequest
+	20, // 31: gamenode.GameNodeGRPCService.UpdateNodeState:input_type -> gamenode.StateChangeRequest
+	15, // 32: gamenode.GameNodeGRPCService.ReportContainers:input_type -> gamenode.ContainersRequest
+	2,  // 33: gamenode.GameNodeGRPCService.Register:output_type -> gamenode.RegisterResponse
+	4,  // 34: gamenode.GameNodeGRPCService.Heartbeat:output_type -> gamenode.HeartbeatResponse
+	12, // 35: gamenode.GameNodeGRPCService.ReportMetrics:output_type -> gamenode.MetricsResponse
+	14, // 36: gamenode.GameNodeGRPCService.ReportResource:output_type -> gamenode.ResourceResponse
+	21, // 37: gamenode.GameNodeGRPCService.UpdateNodeState:output_type -> gamenode.StateChangeResponse
+	19, // 38: gamenode.GameNodeGRPCService.ReportContainers:output_type -> gamenode.ContainersResponse
+	33, // [33:39] is the sub-list for method output_type
+	27, // [27:33] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
 }
 
 func init() { file_internal_proto_gamenode_proto_init() }
@@ -1949,7 +2426,7 @@ func file_internal_proto_gamenode_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_proto_gamenode_proto_rawDesc), len(file_internal_proto_gamenode_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   26,
+			NumMessages:   31,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,11 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	GameNodeGRPCService_Register_FullMethodName        = "/gamenode.GameNodeGRPCService/Register"
-	GameNodeGRPCService_Heartbeat_FullMethodName       = "/gamenode.GameNodeGRPCService/Heartbeat"
-	GameNodeGRPCService_ReportMetrics_FullMethodName   = "/gamenode.GameNodeGRPCService/ReportMetrics"
-	GameNodeGRPCService_ReportResource_FullMethodName  = "/gamenode.GameNodeGRPCService/ReportResource"
-	GameNodeGRPCService_UpdateNodeState_FullMethodName = "/gamenode.GameNodeGRPCService/UpdateNodeState"
+	GameNodeGRPCService_Register_FullMethodName         = "/gamenode.GameNodeGRPCService/Register"
+	GameNodeGRPCService_Heartbeat_FullMethodName        = "/gamenode.GameNodeGRPCService/Heartbeat"
+	GameNodeGRPCService_ReportMetrics_FullMethodName    = "/gamenode.GameNodeGRPCService/ReportMetrics"
+	GameNodeGRPCService_ReportResource_FullMethodName   = "/gamenode.GameNodeGRPCService/ReportResource"
+	GameNodeGRPCService_UpdateNodeState_FullMethodName  = "/gamenode.GameNodeGRPCService/UpdateNodeState"
+	GameNodeGRPCService_ReportContainers_FullMethodName = "/gamenode.GameNodeGRPCService/ReportContainers"
 )
 
 // GameNodeGRPCServiceClient is the client API for GameNodeGRPCService service.
@@ -38,6 +39,7 @@ type GameNodeGRPCServiceClient interface {
 	ReportMetrics(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error)
 	ReportResource(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*ResourceResponse, error)
 	UpdateNodeState(ctx context.Context, in *StateChangeRequest, opts ...grpc.CallOption) (*StateChangeResponse, error)
+	ReportContainers(ctx context.Context, in *ContainersRequest, opts ...grpc.CallOption) (*ContainersResponse, error)
 }
 
 type gameNodeGRPCServiceClient struct {
@@ -98,6 +100,16 @@ func (c *gameNodeGRPCServiceClient) UpdateNodeState(ctx context.Context, in *Sta
 	return out, nil
 }
 
+func (c *gameNodeGRPCServiceClient) ReportContainers(ctx context.Context, in *ContainersRequest, opts ...grpc.CallOption) (*ContainersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ContainersResponse)
+	err := c.cc.Invoke(ctx, GameNodeGRPCService_ReportContainers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GameNodeGRPCServiceServer is the server API for GameNodeGRPCService service.
 // All implementations must embed UnimplementedGameNodeGRPCServiceServer
 // for forward compatibility.
@@ -110,6 +122,7 @@ type GameNodeGRPCServiceServer interface {
 	ReportMetrics(context.Context, *MetricsRequest) (*MetricsResponse, error)
 	ReportResource(context.Context, *ResourceRequest) (*ResourceResponse, error)
 	UpdateNodeState(context.Context, *StateChangeRequest) (*StateChangeResponse, error)
+	ReportContainers(context.Context, *ContainersRequest) (*ContainersResponse, error)
 	mustEmbedUnimplementedGameNodeGRPCServiceServer()
 }
 
@@ -135,6 +148,9 @@ func (UnimplementedGameNodeGRPCServiceServer) ReportResource(context.Context, *R
 func (UnimplementedGameNodeGRPCServiceServer) UpdateNodeState(context.Context, *StateChangeRequest) (*StateChangeResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateNodeState not implemented")
 }
+func (UnimplementedGameNodeGRPCServiceServer) ReportContainers(context.Context, *ContainersRequest) (*ContainersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportContainers not implemented")
+}
 func (UnimplementedGameNodeGRPCServiceServer) mustEmbedUnimplementedGameNodeGRPCServiceServer() {}
 func (UnimplementedGameNodeGRPCServiceServer) testEmbeddedByValue()                             {}
 
@@ -246,6 +262,24 @@ func _GameNodeGRPCService_UpdateNodeState_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GameNodeGRPCService_ReportContainers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContainersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GameNodeGRPCServiceServer).ReportContainers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GameNodeGRPCService_ReportContainers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GameNodeGRPCServiceServer).ReportContainers(ctx, req.(*ContainersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GameNodeGRPCService_ServiceDesc is the grpc.ServiceDesc for GameNodeGRPCService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -273,6 +307,10 @@ var GameNodeGRPCService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateNodeState",
 			Handler:    _GameNodeGRPCService_UpdateNodeState_Handler,
 		},
+		{
+			MethodName: "ReportContainers",
+			Handler:    _GameNodeGRPCService_ReportContainers_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/proto/gamenode.proto",
@@ -316,6 +316,7 @@ type ContainerConfig struct {
 	Ports         []string               `protobuf:"bytes,10,rep,name=ports,proto3" json:"ports,omitempty"`
 	Environment   map[string]string      `protobuf:"bytes,11,rep,name=environment,proto3" json:"environment,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	Commands      []string               `protobuf:"bytes,12,rep,name=commands,proto3" json:"commands,omitempty"`
+	Networks      []string               `protobuf:"bytes,13,rep,name=networks,proto3" json:"networks,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -434,6 +435,13 @@ func (x *ContainerConfig) GetCommands() []string {
 	return nil
 }
 
+func (x *ContainerConfig) GetNetworks() []string {
+	if x != nil {
+		return x.Networks
+	}
+	return nil
+}
+
 // DeployConfig 部署配置
 type DeployConfig struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -614,6 +622,144 @@ func (x *DeviceConfig) GetCapabilities() []string {
 	return nil
 }
 
+// PipelineVolume 流水线声明的托管卷
+type PipelineVolume struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Driver        string                 `protobuf:"bytes,2,opt,name=driver,proto3" json:"driver,omitempty"`
+	DriverOpts    map[string]string      `protobuf:"bytes,3,rep,name=driver_opts,json=driverOpts,proto3" json:"driver_opts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Lifecycle     string                 `protobuf:"bytes,4,opt,name=lifecycle,proto3" json:"lifecycle,omitempty"` // 生命周期策略（retain/remove）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineVolume) Reset() {
+	*x = PipelineVolume{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineVolume) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineVolume) ProtoMessage() {}
+
+func (x *PipelineVolume) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PipelineVolume.ProtoReflect.Descriptor instead.
+func (*PipelineVolume) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PipelineVolume) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PipelineVolume) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+func (x *PipelineVolume) GetDriverOpts() map[string]string {
+	if x != nil {
+		return x.DriverOpts
+	}
+	return nil
+}
+
+func (x *PipelineVolume) GetLifecycle() string {
+	if x != nil {
+		return x.Lifecycle
+	}
+	return ""
+}
+
+// PipelineNetwork 流水线声明的托管网络
+type PipelineNetwork struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Driver        string                 `protobuf:"bytes,2,opt,name=driver,proto3" json:"driver,omitempty"`
+	Internal      bool                   `protobuf:"varint,3,opt,name=internal,proto3" json:"internal,omitempty"`
+	Lifecycle     string                 `protobuf:"bytes,4,opt,name=lifecycle,proto3" json:"lifecycle,omitempty"` // 生命周期策略（retain/remove）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineNetwork) Reset() {
+	*x = PipelineNetwork{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineNetwork) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineNetwork) ProtoMessage() {}
+
+func (x *PipelineNetwork) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PipelineNetwork.ProtoReflect.Descriptor instead.
+func (*PipelineNetwork) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PipelineNetwork) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PipelineNetwork) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+func (x *PipelineNetwork) GetInternal() bool {
+	if x != nil {
+		return x.Internal
+	}
+	return false
+}
+
+func (x *PipelineNetwork) GetLifecycle() string {
+	if x != nil {
+		return x.Lifecycle
+	}
+	return ""
+}
+
 // PipelineStep 流水线步骤
 type PipelineStep struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -626,7 +772,7 @@ type PipelineStep struct {
 
 func (x *PipelineStep) Reset() {
 	*x = PipelineStep{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[6]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -638,7 +784,7 @@ func (x *PipelineStep) String() string {
 func (*PipelineStep) ProtoMessage() {}
 
 func (x *PipelineStep) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[6]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -651,7 +797,7 @@ func (x *PipelineStep) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PipelineStep.ProtoReflect.Descriptor instead.
 func (*PipelineStep) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{6}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *PipelineStep) GetName() string {
@@ -692,7 +838,7 @@ type PipelineStatus struct {
 
 func (x *PipelineStatus) Reset() {
 	*x = PipelineStatus{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[7]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -704,7 +850,7 @@ func (x *PipelineStatus) String() string {
 func (*PipelineStatus) ProtoMessage() {}
 
 func (x *PipelineStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[7]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -717,7 +863,7 @@ func (x *PipelineStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PipelineStatus.ProtoReflect.Descriptor instead.
 func (*PipelineStatus) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{7}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *PipelineStatus) GetNodeId() string {
@@ -788,14 +934,18 @@ type GamePipeline struct {
 	Args        []string        `protobuf:"bytes,6,rep,name=args,proto3" json:"args,omitempty"`
 	Steps       []*PipelineStep `protobuf:"bytes,7,rep,name=steps,proto3" json:"steps,omitempty"`
 	// 动态信息（执行状态）
-	Status        *PipelineStatus `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	Status *PipelineStatus `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	// 托管资源声明
+	Volumes       []*PipelineVolume  `protobuf:"bytes,9,rep,name=volumes,proto3" json:"volumes,omitempty"`
+	Networks      []*PipelineNetwork `protobuf:"bytes,10,rep,name=networks,proto3" json:"networks,omitempty"`
+	InstanceId    string             `protobuf:"bytes,11,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"` // 所属游戏实例
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GamePipeline) Reset() {
 	*x = GamePipeline{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[8]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -807,7 +957,7 @@ func (x *GamePipeline) String() string {
 func (*GamePipeline) ProtoMessage() {}
 
 func (x *GamePipeline) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[8]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -820,7 +970,7 @@ func (x *GamePipeline) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GamePipeline.ProtoReflect.Descriptor instead.
 func (*GamePipeline) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{8}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *GamePipeline) GetId() string {
@@ -879,6 +1029,27 @@ func (x *GamePipeline) GetStatus() *PipelineStatus {
 	return nil
 }
 
+func (x *GamePipeline) GetVolumes() []*PipelineVolume {
+	if x != nil {
+		return x.Volumes
+	}
+	return nil
+}
+
+func (x *GamePipeline) GetNetworks() []*PipelineNetwork {
+	if x != nil {
+		return x.Networks
+	}
+	return nil
+}
+
+func (x *GamePipeline) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
 // CreatePipelineRequest 创建流水线请求
 type CreatePipelineRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -889,7 +1060,7 @@ type CreatePipelineRequest struct {
 
 func (x *CreatePipelineRequest) Reset() {
 	*x = CreatePipelineRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[9]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -901,7 +1072,7 @@ func (x *CreatePipelineRequest) String() string {
 func (*CreatePipelineRequest) ProtoMessage() {}
 
 func (x *CreatePipelineRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[9]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -914,7 +1085,7 @@ func (x *CreatePipelineRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreatePipelineRequest.ProtoReflect.Descriptor instead.
 func (*CreatePipelineRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{9}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *CreatePipelineRequest) GetPipeline() *GamePipeline {
@@ -934,7 +1105,7 @@ type CreatePipelineResponse struct {
 
 func (x *CreatePipelineResponse) Reset() {
 	*x = CreatePipelineResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[10]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -946,7 +1117,7 @@ func (x *CreatePipelineResponse) String() string {
 func (*CreatePipelineResponse) ProtoMessage() {}
 
 func (x *CreatePipelineResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[10]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -959,7 +1130,7 @@ func (x *CreatePipelineResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreatePipelineResponse.ProtoReflect.Descriptor instead.
 func (*CreatePipelineResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{10}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *CreatePipelineResponse) GetId() string {
@@ -979,7 +1150,7 @@ type GetPipelineRequest struct {
 
 func (x *GetPipelineRequest) Reset() {
 	*x = GetPipelineRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[11]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -991,7 +1162,7 @@ func (x *GetPipelineRequest) String() string {
 func (*GetPipelineRequest) ProtoMessage() {}
 
 func (x *GetPipelineRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[11]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1004,7 +1175,7 @@ func (x *GetPipelineRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetPipelineRequest.ProtoReflect.Descriptor instead.
 func (*GetPipelineRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{11}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *GetPipelineRequest) GetId() string {
@@ -1024,7 +1195,7 @@ type GetPipelineResponse struct {
 
 func (x *GetPipelineResponse) Reset() {
 	*x = GetPipelineResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[12]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1036,7 +1207,7 @@ func (x *GetPipelineResponse) String() string {
 func (*GetPipelineResponse) ProtoMessage() {}
 
 func (x *GetPipelineResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[12]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1049,7 +1220,7 @@ func (x *GetPipelineResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetPipelineResponse.ProtoReflect.Descriptor instead.
 func (*GetPipelineResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{12}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *GetPipelineResponse) GetPipeline() *GamePipeline {
@@ -1076,7 +1247,7 @@ type ListPipelinesRequest struct {
 
 func (x *ListPipelinesRequest) Reset() {
 	*x = ListPipelinesRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[13]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1088,7 +1259,7 @@ func (x *ListPipelinesRequest) String() string {
 func (*ListPipelinesRequest) ProtoMessage() {}
 
 func (x *ListPipelinesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[13]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1101,7 +1272,7 @@ func (x *ListPipelinesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPipelinesRequest.ProtoReflect.Descriptor instead.
 func (*ListPipelinesRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{13}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *ListPipelinesRequest) GetPage() int32 {
@@ -1171,7 +1342,7 @@ type ListPipelinesResponse struct {
 
 func (x *ListPipelinesResponse) Reset() {
 	*x = ListPipelinesResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[14]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1183,7 +1354,7 @@ func (x *ListPipelinesResponse) String() string {
 func (*ListPipelinesResponse) ProtoMessage() {}
 
 func (x *ListPipelinesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[14]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1196,7 +1367,7 @@ func (x *ListPipelinesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPipelinesResponse.ProtoReflect.Descriptor instead.
 func (*ListPipelinesResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{14}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *ListPipelinesResponse) GetPipelines() []*GamePipeline {
@@ -1223,7 +1394,7 @@ type UpdatePipelineRequest struct {
 
 func (x *UpdatePipelineRequest) Reset() {
 	*x = UpdatePipelineRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[15]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1235,7 +1406,7 @@ func (x *UpdatePipelineRequest) String() string {
 func (*UpdatePipelineRequest) ProtoMessage() {}
 
 func (x *UpdatePipelineRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[15]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1248,7 +1419,7 @@ func (x *UpdatePipelineRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdatePipelineRequest.ProtoReflect.Descriptor instead.
 func (*UpdatePipelineRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{15}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *UpdatePipelineRequest) GetPipeline() *GamePipeline {
@@ -1268,7 +1439,7 @@ type UpdatePipelineResponse struct {
 
 func (x *UpdatePipelineResponse) Reset() {
 	*x = UpdatePipelineResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[16]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1280,7 +1451,7 @@ func (x *UpdatePipelineResponse) String() string {
 func (*UpdatePipelineResponse) ProtoMessage() {}
 
 func (x *UpdatePipelineResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[16]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1293,7 +1464,7 @@ func (x *UpdatePipelineResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdatePipelineResponse.ProtoReflect.Descriptor instead.
 func (*UpdatePipelineResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{16}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *UpdatePipelineResponse) GetSuccess() bool {
@@ -1314,7 +1485,7 @@ type DeletePipelineRequest struct {
 
 func (x *DeletePipelineRequest) Reset() {
 	*x = DeletePipelineRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[17]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1326,7 +1497,7 @@ func (x *DeletePipelineRequest) String() string {
 func (*DeletePipelineRequest) ProtoMessage() {}
 
 func (x *DeletePipelineRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[17]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1339,7 +1510,7 @@ func (x *DeletePipelineRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeletePipelineRequest.ProtoReflect.Descriptor instead.
 func (*DeletePipelineRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{17}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *DeletePipelineRequest) GetId() string {
@@ -1366,7 +1537,7 @@ type DeletePipelineResponse struct {
 
 func (x *DeletePipelineResponse) Reset() {
 	*x = DeletePipelineResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[18]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1378,7 +1549,7 @@ func (x *DeletePipelineResponse) String() string {
 func (*DeletePipelineResponse) ProtoMessage() {}
 
 func (x *DeletePipelineResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[18]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1391,7 +1562,7 @@ func (x *DeletePipelineResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeletePipelineResponse.ProtoReflect.Descriptor instead.
 func (*DeletePipelineResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{18}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *DeletePipelineResponse) GetSuccess() bool {
@@ -1411,7 +1582,7 @@ type ExecutePipelineRequest struct {
 
 func (x *ExecutePipelineRequest) Reset() {
 	*x = ExecutePipelineRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[19]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1423,7 +1594,7 @@ func (x *ExecutePipelineRequest) String() string {
 func (*ExecutePipelineRequest) ProtoMessage() {}
 
 func (x *ExecutePipelineRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[19]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1436,7 +1607,7 @@ func (x *ExecutePipelineRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecutePipelineRequest.ProtoReflect.Descriptor instead.
 func (*ExecutePipelineRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{19}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *ExecutePipelineRequest) GetId() string {
@@ -1456,7 +1627,7 @@ type ExecutePipelineResponse struct {
 
 func (x *ExecutePipelineResponse) Reset() {
 	*x = ExecutePipelineResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[20]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1468,7 +1639,7 @@ func (x *ExecutePipelineResponse) String() string {
 func (*ExecutePipelineResponse) ProtoMessage() {}
 
 func (x *ExecutePipelineResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[20]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1481,7 +1652,7 @@ func (x *ExecutePipelineResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecutePipelineResponse.ProtoReflect.Descriptor instead.
 func (*ExecutePipelineResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{20}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *ExecutePipelineResponse) GetSuccess() bool {
@@ -1495,14 +1666,16 @@ func (x *ExecutePipelineResponse) GetSuccess() bool {
 type PipelineStreamRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// 心跳消息
-	Heartbeat     *Heartbeat `protobuf:"bytes,1,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`
+	Heartbeat *Heartbeat `protobuf:"bytes,1,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`
+	// 流水线接收确认
+	Ack           *PipelineAck `protobuf:"bytes,2,opt,name=ack,proto3" json:"ack,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PipelineStreamRequest) Reset() {
 	*x = PipelineStreamRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[21]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1514,7 +1687,7 @@ func (x *PipelineStreamRequest) String() string {
 func (*PipelineStreamRequest) ProtoMessage() {}
 
 func (x *PipelineStreamRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[21]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1527,7 +1700,7 @@ func (x *PipelineStreamRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PipelineStreamRequest.ProtoReflect.Descriptor instead.
 func (*PipelineStreamRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{21}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *PipelineStreamRequest) GetHeartbeat() *Heartbeat {
@@ -1537,6 +1710,13 @@ func (x *PipelineStreamRequest) GetHeartbeat() *Heartbeat {
 	return nil
 }
 
+func (x *PipelineStreamRequest) GetAck() *PipelineAck {
+	if x != nil {
+		return x.Ack
+	}
+	return nil
+}
+
 // PipelineStreamResponse 流水线流式响应
 type PipelineStreamResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -1545,6 +1725,9 @@ type PipelineStreamResponse struct {
 	//	*PipelineStreamResponse_HeartbeatAck
 	//	*PipelineStreamResponse_Pipeline
 	//	*PipelineStreamResponse_Cancel
+	//	*PipelineStreamResponse_Diagnostics
+	//	*PipelineStreamResponse_NodeState
+	//	*PipelineStreamResponse_RemoveContainer
 	Response      isPipelineStreamResponse_Response `protobuf_oneof:"response"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1552,7 +1735,7 @@ type PipelineStreamResponse struct {
 
 func (x *PipelineStreamResponse) Reset() {
 	*x = PipelineStreamResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[22]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1564,7 +1747,7 @@ func (x *PipelineStreamResponse) String() string {
 func (*PipelineStreamResponse) ProtoMessage() {}
 
 func (x *PipelineStreamResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[22]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1577,7 +1760,7 @@ func (x *PipelineStreamResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PipelineStreamResponse.ProtoReflect.Descriptor instead.
 func (*PipelineStreamResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{22}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *PipelineStreamResponse) GetResponse() isPipelineStreamResponse_Response {
@@ -1614,6 +1797,33 @@ func (x *PipelineStreamResponse) GetCancel() *CancelCommand {
 	return nil
 }
 
+func (x *PipelineStreamResponse) GetDiagnostics() *DiagnosticsCommand {
+	if x != nil {
+		if x, ok := x.Response.(*PipelineStreamResponse_Diagnostics); ok {
+			return x.Diagnostics
+		}
+	}
+	return nil
+}
+
+func (x *PipelineStreamResponse) GetNodeState() *NodeStateCommand {
+	if x != nil {
+		if x, ok := x.Response.(*PipelineStreamResponse_NodeState); ok {
+			return x.NodeState
+		}
+	}
+	return nil
+}
+
+func (x *PipelineStreamResponse) GetRemoveContainer() *RemoveContainerCommand {
+	if x != nil {
+		if x, ok := x.Response.(*PipelineStreamResponse_RemoveContainer); ok {
+			return x.RemoveContainer
+		}
+	}
+	return nil
+}
+
 type isPipelineStreamResponse_Response interface {
 	isPipelineStreamResponse_Response()
 }
@@ -1633,12 +1843,33 @@ type PipelineStreamResponse_Cancel struct {
 	Cancel *CancelCommand `protobuf:"bytes,3,opt,name=cancel,proto3,oneof"`
 }
 
+type PipelineStreamResponse_Diagnostics struct {
+	// 诊断信息收集命令
+	Diagnostics *DiagnosticsCommand `protobuf:"bytes,4,opt,name=diagnostics,proto3,oneof"`
+}
+
+type PipelineStreamResponse_NodeState struct {
+	// 节点维护状态变更
+	NodeState *NodeStateCommand `protobuf:"bytes,5,opt,name=node_state,json=nodeState,proto3,oneof"`
+}
+
+type PipelineStreamResponse_RemoveContainer struct {
+	// 删除容器命令
+	RemoveContainer *RemoveContainerCommand `protobuf:"bytes,6,opt,name=remove_container,json=removeContainer,proto3,oneof"`
+}
+
 func (*PipelineStreamResponse_HeartbeatAck) isPipelineStreamResponse_Response() {}
 
 func (*PipelineStreamResponse_Pipeline) isPipelineStreamResponse_Response() {}
 
 func (*PipelineStreamResponse_Cancel) isPipelineStreamResponse_Response() {}
 
+func (*PipelineStreamResponse_Diagnostics) isPipelineStreamResponse_Response() {}
+
+func (*PipelineStreamResponse_NodeState) isPipelineStreamResponse_Response() {}
+
+func (*PipelineStreamResponse_RemoveContainer) isPipelineStreamResponse_Response() {}
+
 // Heartbeat 心跳消息
 type Heartbeat struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1651,7 +1882,7 @@ type Heartbeat struct {
 
 func (x *Heartbeat) Reset() {
 	*x = Heartbeat{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[23]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1663,7 +1894,7 @@ func (x *Heartbeat) String() string {
 func (*Heartbeat) ProtoMessage() {}
 
 func (x *Heartbeat) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[23]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1676,7 +1907,7 @@ func (x *Heartbeat) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
 func (*Heartbeat) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{23}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *Heartbeat) GetNodeId() string {
@@ -1700,6 +1931,67 @@ func (x *Heartbeat) GetPipelineIds() []string {
 	return nil
 }
 
+// PipelineAck 节点对下发流水线的确认
+type PipelineAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PipelineId    string                 `protobuf:"bytes,1,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
+	Accepted      bool                   `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"` // 是否接受执行
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`    // 拒绝原因
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineAck) Reset() {
+	*x = PipelineAck{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineAck) ProtoMessage() {}
+
+func (x *PipelineAck) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PipelineAck.ProtoReflect.Descriptor instead.
+func (*PipelineAck) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *PipelineAck) GetPipelineId() string {
+	if x != nil {
+		return x.PipelineId
+	}
+	return ""
+}
+
+func (x *PipelineAck) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+func (x *PipelineAck) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 // HeartbeatAck 心跳确认
 type HeartbeatAck struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1710,7 +2002,7 @@ type HeartbeatAck struct {
 
 func (x *HeartbeatAck) Reset() {
 	*x = HeartbeatAck{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[24]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1722,7 +2014,7 @@ func (x *HeartbeatAck) String() string {
 func (*HeartbeatAck) ProtoMessage() {}
 
 func (x *HeartbeatAck) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[24]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1735,7 +2027,7 @@ func (x *HeartbeatAck) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HeartbeatAck.ProtoReflect.Descriptor instead.
 func (*HeartbeatAck) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{24}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *HeartbeatAck) GetSuccess() bool {
@@ -1749,13 +2041,14 @@ func (x *HeartbeatAck) GetSuccess() bool {
 type CancelCommand struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	PipelineId    string                 `protobuf:"bytes,2,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"` // 要取消的流水线
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CancelCommand) Reset() {
 	*x = CancelCommand{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[25]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1767,7 +2060,7 @@ func (x *CancelCommand) String() string {
 func (*CancelCommand) ProtoMessage() {}
 
 func (x *CancelCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[25]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1780,7 +2073,7 @@ func (x *CancelCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelCommand.ProtoReflect.Descriptor instead.
 func (*CancelCommand) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{25}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{28}
 }
 
 func (x *CancelCommand) GetReason() string {
@@ -1790,18 +2083,169 @@ func (x *CancelCommand) GetReason() string {
 	return ""
 }
 
+func (x *CancelCommand) GetPipelineId() string {
+	if x != nil {
+		return x.PipelineId
+	}
+	return ""
+}
+
+// NodeStateCommand 节点维护状态变更命令
+type NodeStateCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"` // 节点维护状态：normal/maintenance/disabled
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeStateCommand) Reset() {
+	*x = NodeStateCommand{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeStateCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeStateCommand) ProtoMessage() {}
+
+func (x *NodeStateCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeStateCommand.ProtoReflect.Descriptor instead.
+func (*NodeStateCommand) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *NodeStateCommand) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+// RemoveContainerCommand 删除托管容器命令，用于清理没有对应实例的容器
+type RemoveContainerCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContainerId   string                 `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveContainerCommand) Reset() {
+	*x = RemoveContainerCommand{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveContainerCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveContainerCommand) ProtoMessage() {}
+
+func (x *RemoveContainerCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveContainerCommand.ProtoReflect.Descriptor instead.
+func (*RemoveContainerCommand) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *RemoveContainerCommand) GetContainerId() string {
+	if x != nil {
+		return x.ContainerId
+	}
+	return ""
+}
+
+func (x *RemoveContainerCommand) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// DiagnosticsCommand 诊断信息收集命令，节点收集诊断包后通过 UploadDiagnostics 上传
+type DiagnosticsCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // 诊断请求ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiagnosticsCommand) Reset() {
+	*x = DiagnosticsCommand{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiagnosticsCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiagnosticsCommand) ProtoMessage() {}
+
+func (x *DiagnosticsCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiagnosticsCommand.ProtoReflect.Descriptor instead.
+func (*DiagnosticsCommand) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *DiagnosticsCommand) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
 // UpdatePipelineStatusRequest 更新流水线状态请求
 type UpdatePipelineStatusRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PipelineId    string                 `protobuf:"bytes,1,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
 	Status        *PipelineStatus        `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Sequence      int64                  `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"` // 上报序号，同一流水线内单调递增，用于丢弃重复或过期的上报
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UpdatePipelineStatusRequest) Reset() {
 	*x = UpdatePipelineStatusRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[26]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1813,7 +2257,7 @@ func (x *UpdatePipelineStatusRequest) String() string {
 func (*UpdatePipelineStatusRequest) ProtoMessage() {}
 
 func (x *UpdatePipelineStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[26]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1826,7 +2270,7 @@ func (x *UpdatePipelineStatusRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdatePipelineStatusRequest.ProtoReflect.Descriptor instead.
 func (*UpdatePipelineStatusRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{26}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *UpdatePipelineStatusRequest) GetPipelineId() string {
@@ -1843,6 +2287,13 @@ func (x *UpdatePipelineStatusRequest) GetStatus() *PipelineStatus {
 	return nil
 }
 
+func (x *UpdatePipelineStatusRequest) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
 // UpdatePipelineStatusResponse 更新流水线状态响应
 type UpdatePipelineStatusResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1853,7 +2304,7 @@ type UpdatePipelineStatusResponse struct {
 
 func (x *UpdatePipelineStatusResponse) Reset() {
 	*x = UpdatePipelineStatusResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[27]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1865,7 +2316,7 @@ func (x *UpdatePipelineStatusResponse) String() string {
 func (*UpdatePipelineStatusResponse) ProtoMessage() {}
 
 func (x *UpdatePipelineStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[27]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1878,7 +2329,7 @@ func (x *UpdatePipelineStatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdatePipelineStatusResponse.ProtoReflect.Descriptor instead.
 func (*UpdatePipelineStatusResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{27}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{33}
 }
 
 func (x *UpdatePipelineStatusResponse) GetSuccess() bool {
@@ -1894,13 +2345,15 @@ type UpdateStepStatusRequest struct {
 	PipelineId    string                 `protobuf:"bytes,1,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
 	StepId        string                 `protobuf:"bytes,2,opt,name=step_id,json=stepId,proto3" json:"step_id,omitempty"`
 	Status        *StepStatus            `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Sequence      int64                  `protobuf:"varint,4,opt,name=sequence,proto3" json:"sequence,omitempty"`          // 上报序号，与流水线状态共用同一序列
+	NodeId        string                 `protobuf:"bytes,5,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"` // 上报节点 ID
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UpdateStepStatusRequest) Reset() {
 	*x = UpdateStepStatusRequest{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[28]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1912,7 +2365,7 @@ func (x *UpdateStepStatusRequest) String() string {
 func (*UpdateStepStatusRequest) ProtoMessage() {}
 
 func (x *UpdateStepStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[28]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1925,7 +2378,7 @@ func (x *UpdateStepStatusRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateStepStatusRequest.ProtoReflect.Descriptor instead.
 func (*UpdateStepStatusRequest) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{28}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{34}
 }
 
 func (x *UpdateStepStatusRequest) GetPipelineId() string {
@@ -1949,6 +2402,20 @@ func (x *UpdateStepStatusRequest) GetStatus() *StepStatus {
 	return nil
 }
 
+func (x *UpdateStepStatusRequest) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *UpdateStepStatusRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
 // UpdateStepStatusResponse 更新步骤状态响应
 type UpdateStepStatusResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1959,7 +2426,7 @@ type UpdateStepStatusResponse struct {
 
 func (x *UpdateStepStatusResponse) Reset() {
 	*x = UpdateStepStatusResponse{}
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[29]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1971,7 +2438,7 @@ func (x *UpdateStepStatusResponse) String() string {
 func (*UpdateStepStatusResponse) ProtoMessage() {}
 
 func (x *UpdateStepStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_proto_gamepipeline_proto_msgTypes[29]
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1984,7 +2451,7 @@ func (x *UpdateStepStatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateStepStatusResponse.ProtoReflect.Descriptor instead.
 func (*UpdateStepStatusResponse) Descriptor() ([]byte, []int) {
-	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{29}
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{35}
 }
 
 func (x *UpdateStepStatusResponse) GetSuccess() bool {
@@ -1994,6 +2461,258 @@ func (x *UpdateStepStatusResponse) GetSuccess() bool {
 	return false
 }
 
+// StepLogChunk 步骤日志分块，同一个流只传输一个步骤的日志
+type StepLogChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"` // 上报节点 ID
+	PipelineId    string                 `protobuf:"bytes,2,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
+	StepId        string                 `protobuf:"bytes,3,opt,name=step_id,json=stepId,proto3" json:"step_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"` // 本块在步骤日志中的起始偏移，用于重传去重
+	Data          []byte                 `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	Eof           bool                   `protobuf:"varint,6,opt,name=eof,proto3" json:"eof,omitempty"` // 步骤日志已结束
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StepLogChunk) Reset() {
+	*x = StepLogChunk{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StepLogChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepLogChunk) ProtoMessage() {}
+
+func (x *StepLogChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepLogChunk.ProtoReflect.Descriptor instead.
+func (*StepLogChunk) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *StepLogChunk) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *StepLogChunk) GetPipelineId() string {
+	if x != nil {
+		return x.PipelineId
+	}
+	return ""
+}
+
+func (x *StepLogChunk) GetStepId() string {
+	if x != nil {
+		return x.StepId
+	}
+	return ""
+}
+
+func (x *StepLogChunk) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *StepLogChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *StepLogChunk) GetEof() bool {
+	if x != nil {
+		return x.Eof
+	}
+	return false
+}
+
+// StreamStepLogsResponse 步骤日志上传结果
+type StreamStepLogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Written       int64                  `protobuf:"varint,1,opt,name=written,proto3" json:"written,omitempty"` // 服务端已保存的日志字节数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamStepLogsResponse) Reset() {
+	*x = StreamStepLogsResponse{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamStepLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamStepLogsResponse) ProtoMessage() {}
+
+func (x *StreamStepLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamStepLogsResponse.ProtoReflect.Descriptor instead.
+func (*StreamStepLogsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *StreamStepLogsResponse) GetWritten() int64 {
+	if x != nil {
+		return x.Written
+	}
+	return 0
+}
+
+// DiagnosticsChunk 诊断包分块，同一个流只传输一个诊断包
+type DiagnosticsChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`          // 上报节点 ID
+	RequestId     string                 `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // 诊断请求ID
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`                            // tar.gz 数据
+	Eof           bool                   `protobuf:"varint,4,opt,name=eof,proto3" json:"eof,omitempty"`                             // 诊断包已传输完成
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`                          // 节点收集诊断信息失败的原因，非空时不再传输数据
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiagnosticsChunk) Reset() {
+	*x = DiagnosticsChunk{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiagnosticsChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiagnosticsChunk) ProtoMessage() {}
+
+func (x *DiagnosticsChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiagnosticsChunk.ProtoReflect.Descriptor instead.
+func (*DiagnosticsChunk) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *DiagnosticsChunk) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *DiagnosticsChunk) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *DiagnosticsChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *DiagnosticsChunk) GetEof() bool {
+	if x != nil {
+		return x.Eof
+	}
+	return false
+}
+
+func (x *DiagnosticsChunk) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// UploadDiagnosticsResponse 诊断包上传结果
+type UploadDiagnosticsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Size          int64                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"` // 服务端已保存的字节数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadDiagnosticsResponse) Reset() {
+	*x = UploadDiagnosticsResponse{}
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadDiagnosticsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadDiagnosticsResponse) ProtoMessage() {}
+
+func (x *UploadDiagnosticsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_gamepipeline_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadDiagnosticsResponse.ProtoReflect.Descriptor instead.
+func (*UploadDiagnosticsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_gamepipeline_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *UploadDiagnosticsResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
 var File_internal_proto_gamepipeline_proto protoreflect.FileDescriptor
 
 const file_internal_proto_gamepipeline_proto_rawDesc = "" +
@@ -2013,7 +2732,7 @@ const file_internal_proto_gamepipeline_proto_rawDesc = "" +
 	"\bend_time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x129\n" +
 	"\n" +
 	"updated_at\x18\n" +
-	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xd9\x03\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xf5\x03\n" +
 	"\x0fContainerConfig\x12\x14\n" +
 	"\x05image\x18\x01 \x01(\tR\x05image\x12\x1a\n" +
 	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x1e\n" +
@@ -2029,7 +2748,8 @@ const file_internal_proto_gamepipeline_proto_rawDesc = "" +
 	"\x05ports\x18\n" +
 	" \x03(\tR\x05ports\x12L\n" +
 	"\venvironment\x18\v \x03(\v2*.pipeline.ContainerConfig.EnvironmentEntryR\venvironment\x12\x1a\n" +
-	"\bcommands\x18\f \x03(\tR\bcommands\x1a>\n" +
+	"\bcommands\x18\f \x03(\tR\bcommands\x12\x1a\n" +
+	"\bnetworks\x18\r \x03(\tR\bnetworks\x1a>\n" +
 	"\x10EnvironmentEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"G\n" +
@@ -2040,7 +2760,21 @@ const file_internal_proto_gamepipeline_proto_rawDesc = "" +
 	"\x12ReservationsConfig\x120\n" +
 	"\adevices\x18\x01 \x03(\v2\x16.pipeline.DeviceConfigR\adevices\"2\n" +
 	"\fDeviceConfig\x12\"\n" +
-	"\fcapabilities\x18\x01 \x03(\tR\fcapabilities\"o\n" +
+	"\fcapabilities\x18\x01 \x03(\tR\fcapabilities\"\xe4\x01\n" +
+	"\x0ePipelineVolume\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06driver\x18\x02 \x01(\tR\x06driver\x12I\n" +
+	"\vdriver_opts\x18\x03 \x03(\v2(.pipeline.PipelineVolume.DriverOptsEntryR\n" +
+	"driverOpts\x12\x1c\n" +
+	"\tlifecycle\x18\x04 \x01(\tR\tlifecycle\x1a=\n" +
+	"\x0fDriverOptsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"w\n" +
+	"\x0fPipelineNetwork\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06driver\x18\x02 \x01(\tR\x06driver\x12\x1a\n" +
+	"\binternal\x18\x03 \x01(\bR\binternal\x12\x1c\n" +
+	"\tlifecycle\x18\x04 \x01(\tR\tlifecycle\"o\n" +
 	"\fPipelineStep\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x127\n" +
@@ -2056,7 +2790,7 @@ const file_internal_proto_gamepipeline_proto_rawDesc = "" +
 	"start_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x125\n" +
 	"\bend_time\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x129\n" +
 	"\n" +
-	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x8b\x02\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x97\x03\n" +
 	"\fGamePipeline\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12-\n" +
 	"\x05model\x18\x02 \x01(\x0e2\x17.pipeline.PipelineModelR\x05model\x12\x12\n" +
@@ -2065,7 +2799,12 @@ const file_internal_proto_gamepipeline_proto_rawDesc = "" +
 	"\x04envs\x18\x05 \x03(\tR\x04envs\x12\x12\n" +
 	"\x04args\x18\x06 \x03(\tR\x04args\x12,\n" +
 	"\x05steps\x18\a \x03(\v2\x16.pipeline.PipelineStepR\x05steps\x120\n" +
-	"\x06status\x18\b \x01(\v2\x18.pipeline.PipelineStatusR\x06status\"K\n" +
+	"\x06status\x18\b \x01(\v2\x18.pipeline.PipelineStatusR\x06status\x122\n" +
+	"\avolumes\x18\t \x03(\v2\x18.pipeline.PipelineVolumeR\avolumes\x125\n" +
+	"\bnetworks\x18\n" +
+	" \x03(\v2\x19.pipeline.PipelineNetworkR\bnetworks\x12\x1f\n" +
+	"\vinstance_id\x18\v \x01(\tR\n" +
+	"instanceId\"K\n" +
 	"\x15CreatePipelineRequest\x122\n" +
 	"\bpipeline\x18\x01 \x01(\v2\x16.pipeline.GamePipelineR\bpipeline\"(\n" +
 	"\x16CreatePipelineResponse\x12\x0e\n" +
@@ -2100,36 +2839,78 @@ const file_internal_proto_gamepipeline_proto_rawDesc = "" +
 	"\x16ExecutePipelineRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"3\n" +
 	"\x17ExecutePipelineResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"J\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"s\n" +
 	"\x15PipelineStreamRequest\x121\n" +
-	"\theartbeat\x18\x01 \x01(\v2\x13.pipeline.HeartbeatR\theartbeat\"\xcc\x01\n" +
+	"\theartbeat\x18\x01 \x01(\v2\x13.pipeline.HeartbeatR\theartbeat\x12'\n" +
+	"\x03ack\x18\x02 \x01(\v2\x15.pipeline.PipelineAckR\x03ack\"\x9a\x03\n" +
 	"\x16PipelineStreamResponse\x12=\n" +
 	"\rheartbeat_ack\x18\x01 \x01(\v2\x16.pipeline.HeartbeatAckH\x00R\fheartbeatAck\x124\n" +
 	"\bpipeline\x18\x02 \x01(\v2\x16.pipeline.GamePipelineH\x00R\bpipeline\x121\n" +
-	"\x06cancel\x18\x03 \x01(\v2\x17.pipeline.CancelCommandH\x00R\x06cancelB\n" +
+	"\x06cancel\x18\x03 \x01(\v2\x17.pipeline.CancelCommandH\x00R\x06cancel\x12@\n" +
+	"\vdiagnostics\x18\x04 \x01(\v2\x1c.pipeline.DiagnosticsCommandH\x00R\vdiagnostics\x12;\n" +
+	"\n" +
+	"node_state\x18\x05 \x01(\v2\x1a.pipeline.NodeStateCommandH\x00R\tnodeState\x12M\n" +
+	"\x10remove_container\x18\x06 \x01(\v2 .pipeline.RemoveContainerCommandH\x00R\x0fremoveContainerB\n" +
 	"\n" +
 	"\bresponse\"\x81\x01\n" +
 	"\tHeartbeat\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x128\n" +
 	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12!\n" +
-	"\fpipeline_ids\x18\x03 \x03(\tR\vpipelineIds\"(\n" +
+	"\fpipeline_ids\x18\x03 \x03(\tR\vpipelineIds\"d\n" +
+	"\vPipelineAck\x12\x1f\n" +
+	"\vpipeline_id\x18\x01 \x01(\tR\n" +
+	"pipelineId\x12\x1a\n" +
+	"\baccepted\x18\x02 \x01(\bR\baccepted\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"(\n" +
 	"\fHeartbeatAck\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"'\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"H\n" +
 	"\rCancelCommand\x12\x16\n" +
-	"\x06reason\x18\x01 \x01(\tR\x06reason\"p\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12\x1f\n" +
+	"\vpipeline_id\x18\x02 \x01(\tR\n" +
+	"pipelineId\"(\n" +
+	"\x10NodeStateCommand\x12\x14\n" +
+	"\x05state\x18\x01 \x01(\tR\x05state\"S\n" +
+	"\x16RemoveContainerCommand\x12!\n" +
+	"\fcontainer_id\x18\x01 \x01(\tR\vcontainerId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"3\n" +
+	"\x12DiagnosticsCommand\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"\x8c\x01\n" +
 	"\x1bUpdatePipelineStatusRequest\x12\x1f\n" +
 	"\vpipeline_id\x18\x01 \x01(\tR\n" +
 	"pipelineId\x120\n" +
-	"\x06status\x18\x02 \x01(\v2\x18.pipeline.PipelineStatusR\x06status\"8\n" +
+	"\x06status\x18\x02 \x01(\v2\x18.pipeline.PipelineStatusR\x06status\x12\x1a\n" +
+	"\bsequence\x18\x03 \x01(\x03R\bsequence\"8\n" +
 	"\x1cUpdatePipelineStatusResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"\x81\x01\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"\xb6\x01\n" +
 	"\x17UpdateStepStatusRequest\x12\x1f\n" +
 	"\vpipeline_id\x18\x01 \x01(\tR\n" +
 	"pipelineId\x12\x17\n" +
 	"\astep_id\x18\x02 \x01(\tR\x06stepId\x12,\n" +
-	"\x06status\x18\x03 \x01(\v2\x14.pipeline.StepStatusR\x06status\"4\n" +
+	"\x06status\x18\x03 \x01(\v2\x14.pipeline.StepStatusR\x06status\x12\x1a\n" +
+	"\bsequence\x18\x04 \x01(\x03R\bsequence\x12\x17\n" +
+	"\anode_id\x18\x05 \x01(\tR\x06nodeId\"4\n" +
 	"\x18UpdateStepStatusResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess*N\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"\x9f\x01\n" +
+	"\fStepLogChunk\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1f\n" +
+	"\vpipeline_id\x18\x02 \x01(\tR\n" +
+	"pipelineId\x12\x17\n" +
+	"\astep_id\x18\x03 \x01(\tR\x06stepId\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\fR\x04data\x12\x10\n" +
+	"\x03eof\x18\x06 \x01(\bR\x03eof\"2\n" +
+	"\x16StreamStepLogsResponse\x12\x18\n" +
+	"\awritten\x18\x01 \x01(\x03R\awritten\"\x86\x01\n" +
+	"\x10DiagnosticsChunk\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x10\n" +
+	"\x03eof\x18\x04 \x01(\bR\x03eof\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"/\n" +
+	"\x19UploadDiagnosticsResponse\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size*N\n" +
 	"\rPipelineModel\x12\x1a\n" +
 	"\x16PIPELINE_MODEL_UNKNOWN\x10\x00\x12!\n" +
 	"\x1dPIPELINE_MODEL_START_PLATFORM\x10\x01*\xbd\x01\n" +
@@ -2145,11 +2926,13 @@ const file_internal_proto_gamepipeline_proto_rawDesc = "" +
 	"\x12STEP_STATE_RUNNING\x10\x01\x12\x18\n" +
 	"\x14STEP_STATE_COMPLETED\x10\x02\x12\x15\n" +
 	"\x11STEP_STATE_FAILED\x10\x03\x12\x16\n" +
-	"\x12STEP_STATE_SKIPPED\x10\x042\xb4\x02\n" +
+	"\x12STEP_STATE_SKIPPED\x10\x042\xda\x03\n" +
 	"\x17GamePipelineGRPCService\x12W\n" +
 	"\x0ePipelineStream\x12\x1f.pipeline.PipelineStreamRequest\x1a .pipeline.PipelineStreamResponse(\x010\x01\x12e\n" +
 	"\x14UpdatePipelineStatus\x12%.pipeline.UpdatePipelineStatusRequest\x1a&.pipeline.UpdatePipelineStatusResponse\x12Y\n" +
-	"\x10UpdateStepStatus\x12!.pipeline.UpdateStepStatusRequest\x1a\".pipeline.UpdateStepStatusResponseB8Z6github.com/open-beagle/beagle-wind-game/internal/protob\x06proto3"
+	"\x10UpdateStepStatus\x12!.pipeline.UpdateStepStatusRequest\x1a\".pipeline.UpdateStepStatusResponse\x12L\n" +
+	"\x0eStreamStepLogs\x12\x16.pipeline.StepLogChunk\x1a .pipeline.StreamStepLogsResponse(\x01\x12V\n" +
+	"\x11UploadDiagnostics\x12\x1a.pipeline.DiagnosticsChunk\x1a#.pipeline.UploadDiagnosticsResponse(\x01B8Z6github.com/open-beagle/beagle-wind-game/internal/protob\x06proto3"
 
 var (
 	file_internal_proto_gamepipeline_proto_rawDescOnce sync.Once
@@ -2164,7 +2947,7 @@ func file_internal_proto_gamepipeline_proto_rawDescGZIP() []byte {
 }
 
 var file_internal_proto_gamepipeline_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
-var file_internal_proto_gamepipeline_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
+var file_internal_proto_gamepipeline_proto_msgTypes = make([]protoimpl.MessageInfo, 42)
 var file_internal_proto_gamepipeline_proto_goTypes = []any{
 	(PipelineModel)(0),                   // 0: pipeline.PipelineModel
 	(PipelineState)(0),                   // 1: pipeline.PipelineState
@@ -2175,76 +2958,98 @@ var file_internal_proto_gamepipeline_proto_goTypes = []any{
 	(*ResourcesConfig)(nil),              // 6: pipeline.ResourcesConfig
 	(*ReservationsConfig)(nil),           // 7: pipeline.ReservationsConfig
 	(*DeviceConfig)(nil),                 // 8: pipeline.DeviceConfig
-	(*PipelineStep)(nil),                 // 9: pipeline.PipelineStep
-	(*PipelineStatus)(nil),               // 10: pipeline.PipelineStatus
-	(*GamePipeline)(nil),                 // 11: pipeline.GamePipeline
-	(*CreatePipelineRequest)(nil),        // 12: pipeline.CreatePipelineRequest
-	(*CreatePipelineResponse)(nil),       // 13: pipeline.CreatePipelineResponse
-	(*GetPipelineRequest)(nil),           // 14: pipeline.GetPipelineRequest
-	(*GetPipelineResponse)(nil),          // 15: pipeline.GetPipelineResponse
-	(*ListPipelinesRequest)(nil),         // 16: pipeline.ListPipelinesRequest
-	(*ListPipelinesResponse)(nil),        // 17: pipeline.ListPipelinesResponse
-	(*UpdatePipelineRequest)(nil),        // 18: pipeline.UpdatePipelineRequest
-	(*UpdatePipelineResponse)(nil),       // 19: pipeline.UpdatePipelineResponse
-	(*DeletePipelineRequest)(nil),        // 20: pipeline.DeletePipelineRequest
-	(*DeletePipelineResponse)(nil),       // 21: pipeline.DeletePipelineResponse
-	(*ExecutePipelineRequest)(nil),       // 22: pipeline.ExecutePipelineRequest
-	(*ExecutePipelineResponse)(nil),      // 23: pipeline.ExecutePipelineResponse
-	(*PipelineStreamRequest)(nil),        // 24: pipeline.PipelineStreamRequest
-	(*PipelineStreamResponse)(nil),       // 25: pipeline.PipelineStreamResponse
-	(*Heartbeat)(nil),                    // 26: pipeline.Heartbeat
-	(*HeartbeatAck)(nil),                 // 27: pipeline.HeartbeatAck
-	(*CancelCommand)(nil),                // 28: pipeline.CancelCommand
-	(*UpdatePipelineStatusRequest)(nil),  // 29: pipeline.UpdatePipelineStatusRequest
-	(*UpdatePipelineStatusResponse)(nil), // 30: pipeline.UpdatePipelineStatusResponse
-	(*UpdateStepStatusRequest)(nil),      // 31: pipeline.UpdateStepStatusRequest
-	(*UpdateStepStatusResponse)(nil),     // 32: pipeline.UpdateStepStatusResponse
-	nil,                                  // 33: pipeline.ContainerConfig.EnvironmentEntry
-	(*timestamppb.Timestamp)(nil),        // 34: google.protobuf.Timestamp
+	(*PipelineVolume)(nil),               // 9: pipeline.PipelineVolume
+	(*PipelineNetwork)(nil),              // 10: pipeline.PipelineNetwork
+	(*PipelineStep)(nil),                 // 11: pipeline.PipelineStep
+	(*PipelineStatus)(nil),               // 12: pipeline.PipelineStatus
+	(*GamePipeline)(nil),                 // 13: pipeline.GamePipeline
+	(*CreatePipelineRequest)(nil),        // 14: pipeline.CreatePipelineRequest
+	(*CreatePipelineResponse)(nil),       // 15: pipeline.CreatePipelineResponse
+	(*GetPipelineRequest)(nil),           // 16: pipeline.GetPipelineRequest
+	(*GetPipelineResponse)(nil),          // 17: pipeline.GetPipelineResponse
+	(*ListPipelinesRequest)(nil),         // 18: pipeline.ListPipelinesRequest
+	(*ListPipelinesResponse)(nil),        // 19: pipeline.ListPipelinesResponse
+	(*UpdatePipelineRequest)(nil),        // 20: pipeline.UpdatePipelineRequest
+	(*UpdatePipelineResponse)(nil),       // 21: pipeline.UpdatePipelineResponse
+	(*DeletePipelineRequest)(nil),        // 22: pipeline.DeletePipelineRequest
+	(*DeletePipelineResponse)(nil),       // 23: pipeline.DeletePipelineResponse
+	(*ExecutePipelineRequest)(nil),       // 24: pipeline.ExecutePipelineRequest
+	(*ExecutePipelineResponse)(nil),      // 25: pipeline.ExecutePipelineResponse
+	(*PipelineStreamRequest)(nil),        // 26: pipeline.PipelineStreamRequest
+	(*PipelineStreamResponse)(nil),       // 27: pipeline.PipelineStreamResponse
+	(*Heartbeat)(nil),                    // 28: pipeline.Heartbeat
+	(*PipelineAck)(nil),                  // 29: pipeline.PipelineAck
+	(*HeartbeatAck)(nil),                 // 30: pipeline.HeartbeatAck
+	(*CancelCommand)(nil),                // 31: pipeline.CancelCommand
+	(*NodeStateCommand)(nil),             // 32: pipeline.NodeStateCommand
+	(*RemoveContainerCommand)(nil),       // 33: pipeline.RemoveContainerCommand
+	(*DiagnosticsCommand)(nil),           // 34: pipeline.DiagnosticsCommand
+	(*UpdatePipelineStatusRequest)(nil),  // 35: pipeline.UpdatePipelineStatusRequest
+	(*UpdatePipelineStatusResponse)(nil), // 36: pipeline.UpdatePipelineStatusResponse
+	(*UpdateStepStatusRequest)(nil),      // 37: pipeline.UpdateStepStatusRequest
+	(*UpdateStepStatusResponse)(nil),     // 38: pipeline.UpdateStepStatusResponse
+	(*StepLogChunk)(nil),                 // 39: pipeline.StepLogChunk
+	(*StreamStepLogsResponse)(nil),       // 40: pipeline.StreamStepLogsResponse
+	(*DiagnosticsChunk)(nil),             // 41: pipeline.DiagnosticsChunk
+	(*UploadDiagnosticsResponse)(nil),    // 42: pipeline.UploadDiagnosticsResponse
+	nil,                                  // 43: pipeline.ContainerConfig.EnvironmentEntry
+	nil,                                  // 44: pipeline.PipelineVolume.DriverOptsEntry
+	(*timestamppb.Timestamp)(nil),        // 45: google.protobuf.Timestamp
 }
 var file_internal_proto_gamepipeline_proto_depIdxs = []int32{
 	2,  // 0: pipeline.StepStatus.state:type_name -> pipeline.StepState
-	34, // 1: pipeline.StepStatus.start_time:type_name -> google.protobuf.Timestamp
-	34, // 2: pipeline.StepStatus.end_time:type_name -> google.protobuf.Timestamp
-	34, // 3: pipeline.StepStatus.updated_at:type_name -> google.protobuf.Timestamp
+	45, // 1: pipeline.StepStatus.start_time:type_name -> google.protobuf.Timestamp
+	45, // 2: pipeline.StepStatus.end_time:type_name -> google.protobuf.Timestamp
+	45, // 3: pipeline.StepStatus.updated_at:type_name -> google.protobuf.Timestamp
 	5,  // 4: pipeline.ContainerConfig.deploy:type_name -> pipeline.DeployConfig
-	33, // 5: pipeline.ContainerConfig.environment:type_name -> pipeline.ContainerConfig.EnvironmentEntry
+	43, // 5: pipeline.ContainerConfig.environment:type_name -> pipeline.ContainerConfig.EnvironmentEntry
 	6,  // 6: pipeline.DeployConfig.resources:type_name -> pipeline.ResourcesConfig
 	7,  // 7: pipeline.ResourcesConfig.reservations:type_name -> pipeline.ReservationsConfig
 	8,  // 8: pipeline.ReservationsConfig.devices:type_name -> pipeline.DeviceConfig
-	4,  // 9: pipeline.PipelineStep.container:type_name -> pipeline.ContainerConfig
-	1,  // 10: pipeline.PipelineStatus.state:type_name -> pipeline.PipelineState
-	34, // 11: pipeline.PipelineStatus.start_time:type_name -> google.protobuf.Timestamp
-	34, // 12: pipeline.PipelineStatus.end_time:type_name -> google.protobuf.Timestamp
-	34, // 13: pipeline.PipelineStatus.updated_at:type_name -> google.protobuf.Timestamp
-	0,  // 14: pipeline.GamePipeline.model:type_name -> pipeline.PipelineModel
-	9,  // 15: pipeline.GamePipeline.steps:type_name -> pipeline.PipelineStep
-	10, // 16: pipeline.GamePipeline.status:type_name -> pipeline.PipelineStatus
-	11, // 17: pipeline.CreatePipelineRequest.pipeline:type_name -> pipeline.GamePipeline
-	11, // 18: pipeline.GetPipelineResponse.pipeline:type_name -> pipeline.GamePipeline
-	1,  // 19: pipeline.ListPipelinesRequest.status:type_name -> pipeline.PipelineState
-	34, // 20: pipeline.ListPipelinesRequest.start_time:type_name -> google.protobuf.Timestamp
-	34, // 21: pipeline.ListPipelinesRequest.end_time:type_name -> google.protobuf.Timestamp
-	11, // 22: pipeline.ListPipelinesResponse.pipelines:type_name -> pipeline.GamePipeline
-	11, // 23: pipeline.UpdatePipelineRequest.pipeline:type_name -> pipeline.GamePipeline
-	26, // 24: pipeline.PipelineStreamRequest.heartbeat:type_name -> pipeline.Heartbeat
-	27, // 25: pipeline.PipelineStreamResponse.heartbeat_ack:type_name -> pipeline.HeartbeatAck
-	11, // 26: pipeline.PipelineStreamResponse.pipeline:type_name -> pipeline.GamePipeline
-	28, // 27: pipeline.PipelineStreamResponse.cancel:type_name -> pipeline.CancelCommand
-	34, // 28: pipeline.Heartbeat.timestamp:type_name -> google.protobuf.Timestamp
-	10, // 29: pipeline.UpdatePipelineStatusRequest.status:type_name -> pipeline.PipelineStatus
-	3,  // 30: pipeline.UpdateStepStatusRequest.status:type_name -> pipeline.StepStatus
-	24, // 31: pipeline.GamePipelineGRPCService.PipelineStream:input_type -> pipeline.PipelineStreamRequest
-	29, // 32: pipeline.GamePipelineGRPCService.UpdatePipelineStatus:input_type -> pipeline.UpdatePipelineStatusRequest
-	31, // 33: pipeline.GamePipelineGRPCService.UpdateStepStatus:input_type -> pipeline.UpdateStepStatusRequest
-	25, // 34: pipeline.GamePipelineGRPCService.PipelineStream:output_type -> pipeline.PipelineStreamResponse
-	30, // 35: pipeline.GamePipelineGRPCService.UpdatePipelineStatus:output_type -> pipeline.UpdatePipelineStatusResponse
-	32, // 36: pipeline.GamePipelineGRPCService.UpdateStepStatus:output_type -> pipeline.UpdateStepStatusResponse
-	34, // [34:37] is the sub-list for method output_type
-	31, // [31:34] is the sub-list for method input_type
-	31, // [31:31] is the sub-list for extension type_name
-	31, // [31:31] is the sub-list for extension extendee
-	0,  // [0:31] is the sub-list for field type_name
+	44, // 9: pipeline.PipelineVolume.driver_opts:type_name -> pipeline.PipelineVolume.DriverOptsEntry
+	4,  // 10: pipeline.PipelineStep.container:type_name -> pipeline.ContainerConfig
+	1,  // 11: pipeline.PipelineStatus.state:type_name -> pipeline.PipelineState
+	45, // 12: pipeline.PipelineStatus.start_time:type_name -> google.protobuf.Timestamp
+	45, // 13: pipeline.PipelineStatus.end_time:type_name -> google.protobuf.Timestamp
+	45, // 14: pipeline.PipelineStatus.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 15: pipeline.GamePipeline.model:type_name -> pipeline.PipelineModel
+	11, // 16: pipeline.GamePipeline.steps:type_name -> pipeline.PipelineStep
+	12, // 17: pipeline.GamePipeline.status:type_name -> pipeline.PipelineStatus
+	9,  // 18: pipeline.GamePipeline.volumes:type_name -> pipeline.PipelineVolume
+	10, // 19: pipeline.GamePipeline.networks:type_name -> pipeline.PipelineNetwork
+	13, // 20: pipeline.CreatePipelineRequest.pipeline:type_name -> pipeline.GamePipeline
+	13, // 21: pipeline.GetPipelineResponse.pipeline:type_name -> pipeline.GamePipeline
+	1,  // 22: pipeline.ListPipelinesRequest.status:type_name -> pipeline.PipelineState
+	45, // 23: pipeline.ListPipelinesRequest.start_time:type_name -> google.protobuf.Timestamp
+	45, // 24: pipeline.ListPipelinesRequest.end_time:type_name -> google.protobuf.Timestamp
+	13, // 25: pipeline.ListPipelinesResponse.pipelines:type_name -> pipeline.GamePipeline
+	13, // 26: pipeline.UpdatePipelineRequest.pipeline:type_name -> pipeline.GamePipeline
+	28, // 27: pipeline.PipelineStreamRequest.heartbeat:type_name -> pipeline.Heartbeat
+	29, // 28: pipeline.PipelineStreamRequest.ack:type_name -> pipeline.PipelineAck
+	30, // 29: pipeline.PipelineStreamResponse.heartbeat_ack:type_name -> pipeline.HeartbeatAck
+	13, // 30: pipeline.PipelineStreamResponse.pipeline:type_name -> pipeline.GamePipeline
+	31, // 31: pipeline.PipelineStreamResponse.cancel:type_name -> pipeline.CancelCommand
+	34, // 32: pipeline.PipelineStreamResponse.diagnostics:type_name -> pipeline.DiagnosticsCommand
+	32, // 33: pipeline.PipelineStreamResponse.node_state:type_name -> pipeline.NodeStateCommand
+	33, // 34: pipeline.PipelineStreamResponse.remove_container:type_name -> pipeline.RemoveContainerCommand
+	45, // 35: pipeline.Heartbeat.timestamp:type_name -> google.protobuf.Timestamp
+	12, // 36: pipeline.UpdatePipelineStatusRequest.status:type_name -> pipeline.PipelineStatus
+	3,  // 37: pipeline.UpdateStepStatusRequest.status:type_name -> pipeline.StepStatus
+	26, // 38: pipeline.GamePipelineGRPCService.PipelineStream:input_type -> pipeline.PipelineStreamRequest
+	35, // 39: pipeline.GamePipelineGRPCService.UpdatePipelineStatus:input_type -> pipeline.UpdatePipelineStatusRequest
+	37, // 40: pipeline.GamePipelineGRPCService.UpdateStepStatus:input_type -> pipeline.UpdateStepStatusRequest
+	39, // 41: pipeline.GamePipelineGRPCService.StreamStepLogs:input_type -> pipeline.StepLogChunk
+	41, // 42: pipeline.GamePipelineGRPCService.UploadDiagnostics:input_type -> pipeline.DiagnosticsChunk
+	27, // 43: pipeline.GamePipelineGRPCService.PipelineStream:output_type -> pipeline.PipelineStreamResponse
+	36, // 44: pipeline.GamePipelineGRPCService.UpdatePipelineStatus:output_type -> pipeline.UpdatePipelineStatusResponse
+	38, // 45: pipeline.GamePipelineGRPCService.UpdateStepStatus:output_type -> pipeline.UpdateStepStatusResponse
+	40, // 46: pipeline.GamePipelineGRPCService.StreamStepLogs:output_type -> pipeline.StreamStepLogsResponse
+	42, // 47: pipeline.GamePipelineGRPCService.UploadDiagnostics:output_type -> pipeline.UploadDiagnosticsResponse
+	43, // [43:48] is the sub-list for method output_type
+	38, // [38:43] is the sub-list for method input_type
+	38, // [38:38] is the sub-list for extension type_name
+	38, // [38:38] is the sub-list for extension extendee
+	0,  // [0:38] is the sub-list for field type_name
 }
 
 func init() { file_internal_proto_gamepipeline_proto_init() }
@@ -2252,10 +3057,13 @@ func file_internal_proto_gamepipeline_proto_init() {
 	if File_internal_proto_gamepipeline_proto != nil {
 		return
 	}
-	file_internal_proto_gamepipeline_proto_msgTypes[22].OneofWrappers = []any{
+	file_internal_proto_gamepipeline_proto_msgTypes[24].OneofWrappers = []any{
 		(*PipelineStreamResponse_HeartbeatAck)(nil),
 		(*PipelineStreamResponse_Pipeline)(nil),
 		(*PipelineStreamResponse_Cancel)(nil),
+		(*PipelineStreamResponse_Diagnostics)(nil),
+		(*PipelineStreamResponse_NodeState)(nil),
+		(*PipelineStreamResponse_RemoveContainer)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -2263,7 +3071,7 @@ func file_internal_proto_gamepipeline_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_proto_gamepipeline_proto_rawDesc), len(file_internal_proto_gamepipeline_proto_rawDesc)),
 			NumEnums:      3,
-			NumMessages:   31,
+			NumMessages:   42,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -22,6 +22,8 @@ const (
 	GamePipelineGRPCService_PipelineStream_FullMethodName       = "/pipeline.GamePipelineGRPCService/PipelineStream"
 	GamePipelineGRPCService_UpdatePipelineStatus_FullMethodName = "/pipeline.GamePipelineGRPCService/UpdatePipelineStatus"
 	GamePipelineGRPCService_UpdateStepStatus_FullMethodName     = "/pipeline.GamePipelineGRPCService/UpdateStepStatus"
+	GamePipelineGRPCService_StreamStepLogs_FullMethodName       = "/pipeline.GamePipelineGRPCService/StreamStepLogs"
+	GamePipelineGRPCService_UploadDiagnostics_FullMethodName    = "/pipeline.GamePipelineGRPCService/UploadDiagnostics"
 )
 
 // GamePipelineGRPCServiceClient is the client API for GamePipelineGRPCService service.
@@ -34,11 +36,16 @@ type GamePipelineGRPCServiceClient interface {
 	// 1. Agent 通过此服务保持与 Server 的连接
 	// 2. Server 通过此服务下发 Pipeline 任务
 	// 3. Server 通过此服务发送取消命令
+	// 4. Server 通过此服务发送诊断信息收集命令
 	PipelineStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PipelineStreamRequest, PipelineStreamResponse], error)
 	// 更新流水线状态
 	UpdatePipelineStatus(ctx context.Context, in *UpdatePipelineStatusRequest, opts ...grpc.CallOption) (*UpdatePipelineStatusResponse, error)
 	// 更新步骤状态
 	UpdateStepStatus(ctx context.Context, in *UpdateStepStatusRequest, opts ...grpc.CallOption) (*UpdateStepStatusResponse, error)
+	// 上传步骤日志
+	StreamStepLogs(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[StepLogChunk, StreamStepLogsResponse], error)
+	// 上传诊断包
+	UploadDiagnostics(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[DiagnosticsChunk, UploadDiagnosticsResponse], error)
 }
 
 type gamePipelineGRPCServiceClient struct {
@@ -82,6 +89,32 @@ func (c *gamePipelineGRPCServiceClient) UpdateStepStatus(ctx context.Context, in
 	return out, nil
 }
 
+func (c *gamePipelineGRPCServiceClient) StreamStepLogs(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[StepLogChunk, StreamStepLogsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GamePipelineGRPCService_ServiceDesc.Streams[1], GamePipelineGRPCService_StreamStepLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StepLogChunk, StreamStepLogsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GamePipelineGRPCService_StreamStepLogsClient = grpc.ClientStreamingClient[StepLogChunk, StreamStepLogsResponse]
+
+func (c *gamePipelineGRPCServiceClient) UploadDiagnostics(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[DiagnosticsChunk, UploadDiagnosticsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GamePipelineGRPCService_ServiceDesc.Streams[2], GamePipelineGRPCService_UploadDiagnostics_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DiagnosticsChunk, UploadDiagnosticsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GamePipelineGRPCService_UploadDiagnosticsClient = grpc.ClientStreamingClient[DiagnosticsChunk, UploadDiagnosticsResponse]
+
 // GamePipelineGRPCServiceServer is the server API for GamePipelineGRPCService service.
 // All implementations must embed UnimplementedGamePipelineGRPCServiceServer
 // for forward compatibility.
@@ -92,11 +125,16 @@ type GamePipelineGRPCServiceServer interface {
 	// 1. Agent 通过此服务保持与 Server 的连接
 	// 2. Server 通过此服务下发 Pipeline 任务
 	// 3. Server 通过此服务发送取消命令
+	// 4. Server 通过此服务发送诊断信息收集命令
 	PipelineStream(grpc.BidiStreamingServer[PipelineStreamRequest, PipelineStreamResponse]) error
 	// 更新流水线状态
 	UpdatePipelineStatus(context.Context, *UpdatePipelineStatusRequest) (*UpdatePipelineStatusResponse, error)
 	// 更新步骤状态
 	UpdateStepStatus(context.Context, *UpdateStepStatusRequest) (*UpdateStepStatusResponse, error)
+	// 上传步骤日志
+	StreamStepLogs(grpc.ClientStreamingServer[StepLogChunk, StreamStepLogsResponse]) error
+	// 上传诊断包
+	UploadDiagnostics(grpc.ClientStreamingServer[DiagnosticsChunk, UploadDiagnosticsResponse]) error
 	mustEmbedUnimplementedGamePipelineGRPCServiceServer()
 }
 
@@ -116,6 +154,12 @@ func (UnimplementedGamePipelineGRPCServiceServer) UpdatePipelineStatus(context.C
 func (UnimplementedGamePipelineGRPCServiceServer) UpdateStepStatus(context.Context, *UpdateStepStatusRequest) (*UpdateStepStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateStepStatus not implemented")
 }
+func (UnimplementedGamePipelineGRPCServiceServer) StreamStepLogs(grpc.ClientStreamingServer[StepLogChunk, StreamStepLogsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamStepLogs not implemented")
+}
+func (UnimplementedGamePipelineGRPCServiceServer) UploadDiagnostics(grpc.ClientStreamingServer[DiagnosticsChunk, UploadDiagnosticsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadDiagnostics not implemented")
+}
 func (UnimplementedGamePipelineGRPCServiceServer) mustEmbedUnimplementedGamePipelineGRPCServiceServer() {
 }
 func (UnimplementedGamePipelineGRPCServiceServer) testEmbeddedByValue() {}
@@ -181,6 +225,20 @@ func _GamePipelineGRPCService_UpdateStepStatus_Handler(srv interface{}, ctx cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GamePipelineGRPCService_StreamStepLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GamePipelineGRPCServiceServer).StreamStepLogs(&grpc.GenericServerStream[StepLogChunk, StreamStepLogsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GamePipelineGRPCService_StreamStepLogsServer = grpc.ClientStreamingServer[StepLogChunk, StreamStepLogsResponse]
+
+func _GamePipelineGRPCService_UploadDiagnostics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GamePipelineGRPCServiceServer).UploadDiagnostics(&grpc.GenericServerStream[DiagnosticsChunk, UploadDiagnosticsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GamePipelineGRPCService_UploadDiagnosticsServer = grpc.ClientStreamingServer[DiagnosticsChunk, UploadDiagnosticsResponse]
+
 // GamePipelineGRPCService_ServiceDesc is the grpc.ServiceDesc for GamePipelineGRPCService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -204,6 +262,16 @@ var GamePipelineGRPCService_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "StreamStepLogs",
+			Handler:       _GamePipelineGRPCService_StreamStepLogs_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "UploadDiagnostics",
+			Handler:       _GamePipelineGRPCService_UploadDiagnostics_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "internal/proto/gamepipeline.proto",
 }
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// 访问令牌相关错误
+var (
+	ErrAccessDisabled     = errors.New("未启用访问令牌")
+	ErrMissingAccessUser  = errors.New("缺少访问用户")
+	ErrInvalidAccessToken = errors.New("无效的访问令牌")
+	ErrAccessTokenExpired = errors.New("访问令牌已过期")
+	ErrAccessTokenRevoked = errors.New("访问令牌已被吊销")
+	// ErrAccessIssuerUnauthorized 签发请求没有携带正确的签发密钥
+	ErrAccessIssuerUnauthorized = errors.New("无权签发访问令牌")
+	// ErrAccessUnavailable 访问目标当前不可访问，例如实例未运行或已转移到其他节点
+	ErrAccessUnavailable = errors.New("访问目标不可用")
+)
+
+// AccessOptions 访问令牌配置
+type AccessOptions struct {
+	// Secret 签名密钥
+	Secret []byte
+	// TTL 令牌有效期
+	TTL time.Duration
+	// BaseURL 访问链接使用的服务端地址，例如 https://game.example.com，为空时返回相对路径
+	BaseURL string
+	// IssuerKey 签发访问令牌的调用方需携带的密钥，为空时不签发访问令牌
+	// 持有密钥的调用方（例如已认证用户的门户后端）负责确认访问用户的身份
+	IssuerKey string
+}
+
+// AccessResult 访问链接
+type AccessResult struct {
+	Link      string    `json:"link"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AccessTarget 校验通过的访问令牌及其对应的节点地址
+type AccessTarget struct {
+	Claims models.AccessClaims
+	URL    *url.URL
+}
+
+// AccessService 签发和校验实例、节点的访问令牌
+// 令牌使用 HMAC-SHA256 签名，限定作用范围和用户并带有过期时间；
+// 刷新时递增用户在该范围上的令牌代数，之前签发的令牌随之失效
+type AccessService struct {
+	store     store.AccessGrantStore
+	nodes     *GameNodeService
+	instances *GameInstanceService
+	opts      AccessOptions
+	logger    utils.Logger
+
+	// mu 保证令牌代数的读取和递增是原子的
+	mu sync.Mutex
+}
+
+// NewAccessService 创建访问令牌服务
+func NewAccessService(store store.AccessGrantStore, nodes *GameNodeService, instances *GameInstanceService, opts AccessOptions) (*AccessService, error) {
+	if len(opts.Secret) == 0 {
+		return nil, fmt.Errorf("访问令牌签名密钥不能为空")
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+	return &AccessService{
+		store:     store,
+		nodes:     nodes,
+		instances: instances,
+		opts:      opts,
+		logger:    utils.New("AccessService"),
+	}, nil
+}
+
+// AuthorizeIssuer 校验签发访问令牌的调用方携带的密钥
+// 访问用户由调用方指定，服务端不认证用户，只允许持有签发密钥的可信调用方签发令牌
+func (s *AccessService) AuthorizeIssuer(key string) error {
+	if s.opts.IssuerKey == "" {
+		return fmt.Errorf("%w: 未配置签发密钥", ErrAccessDisabled)
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(s.opts.IssuerKey)) != 1 {
+		return ErrAccessIssuerUnauthorized
+	}
+	return nil
+}
+
+// IssueInstance 为用户签发实例的访问链接，实例需处于运行中
+func (s *AccessService) IssueInstance(ctx context.Context, instanceID, userID string) (AccessResult, error) {
+	return s.issue(ctx, models.AccessScopeInstance, instanceID, userID, false)
+}
+
+// RefreshInstance 吊销用户之前获取的实例访问链接并签发新的链接
+func (s *AccessService) RefreshInstance(ctx context.Context, instanceID, userID string) (AccessResult, error) {
+	return s.issue(ctx, models.AccessScopeInstance, instanceID, userID, true)
+}
+
+// IssueNode 为用户签发节点的访问链接
+func (s *AccessService) IssueNode(ctx context.Context, nodeID, userID string) (AccessResult, error) {
+	return s.issue(ctx, models.AccessScopeNode, nodeID, userID, false)
+}
+
+// RefreshNode 吊销用户之前获取的节点访问链接并签发新的链接
+func (s *AccessService) RefreshNode(ctx context.Context, nodeID, userID string) (AccessResult, error) {
+	return s.issue(ctx, models.AccessScopeNode, nodeID, userID, true)
+}
+
+// Verify 校验访问令牌并返回访问目标
+// 令牌签名、有效期和代数均需有效；实例令牌还要求实例仍在签发时的节点和端口上运行
+func (s *AccessService) Verify(ctx context.Context, token string) (AccessTarget, error) {
+	claims, err := s.parse(token)
+	if err != nil {
+		return AccessTarget{}, err
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return AccessTarget{}, ErrAccessTokenExpired
+	}
+
+	grant, err := s.store.Get(ctx, claims.Scope, claims.Target, claims.UserID)
+	if err != nil {
+		return AccessTarget{}, fmt.Errorf("获取令牌代数失败: %w", err)
+	}
+	if claims.Generation != grant.Generation {
+		return AccessTarget{}, ErrAccessTokenRevoked
+	}
+
+	nodeID, port, target, err := s.resolve(ctx, claims.Scope, claims.Target)
+	if err != nil {
+		return AccessTarget{}, err
+	}
+	if nodeID != claims.NodeID || port != claims.Port {
+		return AccessTarget{}, fmt.Errorf("%w: %s 已转移到其他节点或端口", ErrAccessUnavailable, claims.Target)
+	}
+	return AccessTarget{Claims: claims, URL: target}, nil
+}
+
+// latestRunningInstance 获取平台最近启动的运行中实例
+func (s *AccessService) latestRunningInstance(ctx context.Context, platformID string) (string, error) {
+	instances, err := s.instances.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	var latest *models.GameInstance
+	for i := range instances {
+		instance := &instances[i]
+		if instance.PlatformID != platformID || instance.Status != models.GameInstanceStateRunning {
+			continue
+		}
+		if latest == nil || instance.StartedAt.After(latest.StartedAt) {
+			latest = instance
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("%w: 平台 %s 没有运行中的实例", ErrAccessUnavailable, platformID)
+	}
+	return latest.ID, nil
+}
+
+// issue 签发访问令牌，refresh 为 true 时先递增令牌代数
+func (s *AccessService) issue(ctx context.Context, scope models.AccessScope, target, userID string, refresh bool) (AccessResult, error) {
+	if userID == "" {
+		return AccessResult{}, ErrMissingAccessUser
+	}
+	nodeID, port, _, err := s.resolve(ctx, scope, target)
+	if err != nil {
+		return AccessResult{}, err
+	}
+
+	s.mu.Lock()
+	grant, err := s.store.Get(ctx, scope, target, userID)
+	if err == nil && refresh {
+		grant.Generation++
+		grant.UpdatedAt = time.Now()
+		err = s.store.Put(ctx, grant)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return AccessResult{}, fmt.Errorf("更新令牌代数失败: %w", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.opts.TTL)
+	token, err := s.sign(models.AccessClaims{
+		Scope:      scope,
+		Target:     target,
+		UserID:     userID,
+		NodeID:     nodeID,
+		Port:       port,
+		Generation: grant.Generation,
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return AccessResult{}, err
+	}
+
+	if refresh {
+		s.logger.Info("刷新访问令牌: scope=%s, target=%s, user=%s, generation=%d", scope, target, userID, grant.Generation)
+	} else {
+		s.logger.Debug("签发访问令牌: scope=%s, target=%s, user=%s", scope, target, userID)
+	}
+	return AccessResult{
+		Link:      s.opts.BaseURL + "/access/" + token,
+		Token:     token,
+		ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+	}, nil
+}
+
+// resolve 获取访问目标当前所在的节点、端口和访问地址
+func (s *AccessService) resolve(ctx context.Context, scope models.AccessScope, target string) (string, int, *url.URL, error) {
+	switch scope {
+	case models.AccessScopeInstance:
+		instance, err := s.instances.Get(ctx, target)
+		if err != nil {
+			return "", 0, nil, err
+		}
+		if instance.Status != models.GameInstanceStateRunning {
+			return "", 0, nil, fmt.Errorf("%w: 实例 %s 未在运行中(%s)", ErrAccessUnavailable, target, instance.Status)
+		}
+		if instance.NodeID == "" || instance.Port <= 0 {
+			return "", 0, nil, fmt.Errorf("%w: 实例 %s 没有分配节点端口", ErrAccessUnavailable, target)
+		}
+		address, err := s.nodes.Address(ctx, instance.NodeID)
+		if err != nil {
+			return "", 0, nil, err
+		}
+		host := net.JoinHostPort(address, strconv.Itoa(instance.Port))
+		return instance.NodeID, instance.Port, &url.URL{Scheme: "http", Host: host, Path: "/"}, nil
+	case models.AccessScopeNode:
+		address, err := s.nodes.Address(ctx, target)
+		if err != nil {
+			return "", 0, nil, err
+		}
+		host := address
+		if strings.Contains(address, ":") {
+			host = "[" + address + "]"
+		}
+		return target, 0, &url.URL{Scheme: "http", Host: host, Path: "/"}, nil
+	default:
+		return "", 0, nil, fmt.Errorf("%w: 未知的作用范围 %s", ErrInvalidAccessToken, scope)
+	}
+}
+
+// sign 序列化并签名声明，令牌格式为 base64url(声明).base64url(签名)
+func (s *AccessService) sign(claims models.AccessClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("序列化访问令牌失败: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded)), nil
+}
+
+// parse 校验令牌签名并解析声明
+func (s *AccessService) parse(token string) (models.AccessClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return models.AccessClaims{}, ErrInvalidAccessToken
+	}
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, s.mac(encoded)) {
+		return models.AccessClaims{}, ErrInvalidAccessToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return models.AccessClaims{}, ErrInvalidAccessToken
+	}
+	var claims models.AccessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return models.AccessClaims{}, ErrInvalidAccessToken
+	}
+	return claims, nil
+}
+
+// mac 计算令牌载荷的签名
+func (s *AccessService) mac(encoded string) []byte {
+	h := hmac.New(sha256.New, s.opts.Secret)
+	h.Write([]byte(encoded))
+	return h.Sum(nil)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestAccessService(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(dir, "nodes.yaml"))
+	require.NoError(t, err)
+	// 存储延迟落盘，预先创建数据文件
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "instances.yaml"), []byte("[]\n"), 0644))
+	instanceStore, err := store.NewGameInstanceStore(ctx, filepath.Join(dir, "instances.yaml"), utils.New("AccessTest"))
+	require.NoError(t, err)
+
+	nodes := NewGameNodeService(nodeStore)
+	instances := NewGameInstanceService(instanceStore)
+	access, err := NewAccessService(store.NewYAMLAccessGrantStore(ctx, filepath.Join(dir, "access.yaml")), nodes, instances, AccessOptions{
+		Secret:  []byte("secret"),
+		TTL:     time.Hour,
+		BaseURL: "https://game.example.com/",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, nodes.Create(ctx, models.GameNode{ID: "node-1", Labels: map[string]string{NodeAddressLabel: "10.0.0.5"}}))
+	require.NoError(t, instanceStore.Add(ctx, models.GameInstance{ID: "inst-1", NodeID: "node-1", Status: models.GameInstanceStateRunning, Port: 30001}))
+	require.NoError(t, instanceStore.Add(ctx, models.GameInstance{ID: "inst-2", NodeID: "node-1", Status: models.GameInstanceStatePreparing, Port: 30002}))
+
+	_, err = access.IssueInstance(ctx, "inst-1", "")
+	assert.ErrorIs(t, err, ErrMissingAccessUser)
+	_, err = access.IssueInstance(ctx, "inst-2", "alice")
+	assert.ErrorIs(t, err, ErrAccessUnavailable)
+
+	// 链接指向服务端，校验通过后得到节点地址和实例端口
+	result, err := access.IssueInstance(ctx, "inst-1", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "https://game.example.com/access/"+result.Token, result.Link)
+	target, err := access.Verify(ctx, result.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "http://10.0.0.5:30001/", target.URL.String())
+	assert.Equal(t, "alice", target.Claims.UserID)
+
+	_, err = access.Verify(ctx, result.Token+"x")
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+	expired, err := access.sign(models.AccessClaims{Scope: models.AccessScopeInstance, Target: "inst-1", UserID: "alice", NodeID: "node-1", Port: 30001, ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	require.NoError(t, err)
+	_, err = access.Verify(ctx, expired)
+	assert.ErrorIs(t, err, ErrAccessTokenExpired)
+
+	// 刷新后同一用户之前的链接失效，其他用户的链接不受影响
+	other, err := access.IssueInstance(ctx, "inst-1", "bob")
+	require.NoError(t, err)
+	refreshed, err := access.RefreshInstance(ctx, "inst-1", "alice")
+	require.NoError(t, err)
+	_, err = access.Verify(ctx, result.Token)
+	assert.ErrorIs(t, err, ErrAccessTokenRevoked)
+	_, err = access.Verify(ctx, refreshed.Token)
+	assert.NoError(t, err)
+	_, err = access.Verify(ctx, other.Token)
+	assert.NoError(t, err)
+
+	// 实例重启到其他端口后之前的链接不可用
+	instance, err := instanceStore.Get(ctx, "inst-1")
+	require.NoError(t, err)
+	instance.Port = 30005
+	require.NoError(t, instanceStore.Update(ctx, instance))
+	_, err = access.Verify(ctx, refreshed.Token)
+	assert.ErrorIs(t, err, ErrAccessUnavailable)
+
+	// 节点链接
+	nodes.SetAccessService(access)
+	nodeAccess, err := nodes.GetAccess(ctx, "node-1", "admin")
+	require.NoError(t, err)
+	target, err = access.Verify(ctx, nodeAccess.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "http://10.0.0.5/", target.URL.String())
+}
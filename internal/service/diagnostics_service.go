@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// maxDiagnosticsSize 单个诊断包的最大字节数
+	maxDiagnosticsSize = 256 << 20
+	// diagnosticsTimeout 下发命令后等待节点上传的最长时间
+	diagnosticsTimeout = 10 * time.Minute
+	// maxDiagnosticsPerNode 每个节点保留的诊断包数量，更早的诊断包被删除
+	maxDiagnosticsPerNode = 5
+)
+
+// 诊断包相关错误
+var (
+	ErrDiagnosticsNotFound   = errors.New("诊断包不存在")
+	ErrDiagnosticsNotOwned   = errors.New("诊断请求不属于该节点")
+	ErrDiagnosticsNotPending = errors.New("诊断请求不在等待上传状态")
+	ErrDiagnosticsNotReady   = errors.New("诊断包尚未就绪")
+	ErrDiagnosticsTooLarge   = errors.New("诊断包超过大小限制")
+	ErrDiagnosticsDisabled   = errors.New("诊断信息收集未启用")
+)
+
+// DiagnosticsRequester 诊断命令下发接口，由 gRPC Pipeline 服务端实现
+type DiagnosticsRequester interface {
+	// RequestDiagnostics 通过节点会话下发诊断命令，节点未连接时返回 ErrNodeNotConnected
+	RequestDiagnostics(ctx context.Context, nodeID string, requestID string) error
+}
+
+// DiagnosticsService 节点诊断包服务
+// 服务端通过 PipelineStream 下发诊断命令，节点收集诊断信息并打包为 tar.gz 上传，
+// 诊断包保存在 <dir>/<诊断请求ID>.tar.gz
+type DiagnosticsService struct {
+	store     store.DiagnosticsStore
+	dir       string
+	requester DiagnosticsRequester
+	nodes     *GameNodeService
+	logger    utils.Logger
+
+	// mu 保证诊断包状态的检查和更新是原子的
+	mu        sync.Mutex
+	uploading map[string]bool
+}
+
+// NewDiagnosticsService 创建节点诊断包服务
+func NewDiagnosticsService(store store.DiagnosticsStore, dir string) *DiagnosticsService {
+	return &DiagnosticsService{
+		store:     store,
+		dir:       dir,
+		logger:    utils.New("DiagnosticsService"),
+		uploading: make(map[string]bool),
+	}
+}
+
+// SetRequester 设置诊断命令下发器
+func (s *DiagnosticsService) SetRequester(requester DiagnosticsRequester) {
+	s.requester = requester
+}
+
+// SetNodeService 设置节点服务，用于校验目标节点
+func (s *DiagnosticsService) SetNodeService(nodes *GameNodeService) {
+	s.nodes = nodes
+}
+
+// Request 向节点下发诊断命令，返回等待上传的诊断包记录
+func (s *DiagnosticsService) Request(ctx context.Context, nodeID string) (*models.DiagnosticsBundle, error) {
+	if s.requester == nil {
+		return nil, ErrDiagnosticsDisabled
+	}
+	if s.nodes != nil {
+		if _, err := s.nodes.Get(ctx, nodeID); err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := generateSecret(8)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &models.DiagnosticsBundle{
+		ID:        id,
+		NodeID:    nodeID,
+		State:     models.DiagnosticsStatePending,
+		CreatedAt: time.Now(),
+	}
+	if err := s.store.Add(ctx, bundle); err != nil {
+		return nil, fmt.Errorf("存储层错误: %w", err)
+	}
+
+	if err := s.requester.RequestDiagnostics(ctx, nodeID, id); err != nil {
+		s.finish(ctx, id, models.DiagnosticsStateFailed, 0, err.Error())
+		return nil, fmt.Errorf("下发诊断命令失败: %w", err)
+	}
+	s.logger.Info("已向节点 %s 下发诊断命令: %s", nodeID, id)
+
+	s.prune(ctx, nodeID)
+	return bundle, nil
+}
+
+// Get 获取诊断包记录
+func (s *DiagnosticsService) Get(ctx context.Context, id string) (*models.DiagnosticsBundle, error) {
+	bundle, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDiagnosticsNotFound, id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expireLocked(ctx, bundle), nil
+}
+
+// List 获取诊断包记录，nodeID 非空时只返回该节点的记录，按请求时间倒序排列
+func (s *DiagnosticsService) List(ctx context.Context, nodeID string) ([]*models.DiagnosticsBundle, error) {
+	bundles, err := s.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("存储层错误: %w", err)
+	}
+
+	s.mu.Lock()
+	result := make([]*models.DiagnosticsBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		if nodeID != "" && bundle.NodeID != nodeID {
+			continue
+		}
+		result = append(result, s.expireLocked(ctx, bundle))
+	}
+	s.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// Open 打开已完成的诊断包文件，调用方负责关闭
+func (s *DiagnosticsService) Open(ctx context.Context, id string) (*os.File, *models.DiagnosticsBundle, error) {
+	bundle, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bundle.State != models.DiagnosticsStateCompleted {
+		return nil, nil, fmt.Errorf("%w: %s (%s)", ErrDiagnosticsNotReady, id, bundle.State)
+	}
+
+	f, err := os.Open(s.bundlePath(id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开诊断包失败: %w", err)
+	}
+	return f, bundle, nil
+}
+
+// DiagnosticsUpload 正在上传的诊断包
+type DiagnosticsUpload struct {
+	svc  *DiagnosticsService
+	id   string
+	file *os.File
+	size int64
+}
+
+// BeginUpload 开始接收节点上传的诊断包，诊断请求必须属于该节点且在等待上传
+func (s *DiagnosticsService) BeginUpload(ctx context.Context, nodeID, requestID string) (*DiagnosticsUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkPendingLocked(ctx, nodeID, requestID); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建诊断包目录失败: %w", err)
+	}
+	f, err := os.Create(s.bundlePath(requestID) + ".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("创建诊断包文件失败: %w", err)
+	}
+	s.uploading[requestID] = true
+
+	return &DiagnosticsUpload{svc: s, id: requestID, file: f}, nil
+}
+
+// Write 写入诊断包数据
+func (u *DiagnosticsUpload) Write(data []byte) error {
+	if u.size+int64(len(data)) > maxDiagnosticsSize {
+		return fmt.Errorf("%w: %d 字节", ErrDiagnosticsTooLarge, maxDiagnosticsSize)
+	}
+	n, err := u.file.Write(data)
+	u.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("写入诊断包失败: %w", err)
+	}
+	return nil
+}
+
+// Commit 完成上传，诊断包可供下载
+func (u *DiagnosticsUpload) Commit(ctx context.Context) (int64, error) {
+	defer u.release()
+
+	if err := u.file.Close(); err != nil {
+		u.svc.finish(ctx, u.id, models.DiagnosticsStateFailed, 0, err.Error())
+		return 0, fmt.Errorf("保存诊断包失败: %w", err)
+	}
+	path := u.svc.bundlePath(u.id)
+	if err := os.Rename(path+".tmp", path); err != nil {
+		u.svc.finish(ctx, u.id, models.DiagnosticsStateFailed, 0, err.Error())
+		return 0, fmt.Errorf("保存诊断包失败: %w", err)
+	}
+
+	u.svc.finish(ctx, u.id, models.DiagnosticsStateCompleted, u.size, "")
+	u.svc.logger.Info("诊断包 %s 上传完成, 大小: %d 字节", u.id, u.size)
+	return u.size, nil
+}
+
+// Abort 放弃上传并标记诊断请求失败
+func (u *DiagnosticsUpload) Abort(ctx context.Context, reason string) {
+	defer u.release()
+
+	u.file.Close()
+	os.Remove(u.svc.bundlePath(u.id) + ".tmp")
+	u.svc.finish(ctx, u.id, models.DiagnosticsStateFailed, 0, reason)
+	u.svc.logger.Warn("诊断包 %s 上传失败: %s", u.id, reason)
+}
+
+// release 释放上传标记
+func (u *DiagnosticsUpload) release() {
+	u.svc.mu.Lock()
+	delete(u.svc.uploading, u.id)
+	u.svc.mu.Unlock()
+}
+
+// Fail 记录节点收集诊断信息失败
+func (s *DiagnosticsService) Fail(ctx context.Context, nodeID, requestID, reason string) error {
+	s.mu.Lock()
+	err := s.checkPendingLocked(ctx, nodeID, requestID)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.finish(ctx, requestID, models.DiagnosticsStateFailed, 0, reason)
+	s.logger.Warn("节点 %s 收集诊断信息失败: %s, 原因: %s", nodeID, requestID, reason)
+	return nil
+}
+
+// checkPendingLocked 校验诊断请求属于节点且在等待上传，调用方需持有 s.mu
+func (s *DiagnosticsService) checkPendingLocked(ctx context.Context, nodeID, requestID string) error {
+	bundle, err := s.store.Get(ctx, requestID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDiagnosticsNotFound, requestID)
+	}
+	if bundle.NodeID != nodeID {
+		return fmt.Errorf("%w: %s", ErrDiagnosticsNotOwned, requestID)
+	}
+	if s.expireLocked(ctx, bundle).State != models.DiagnosticsStatePending || s.uploading[requestID] {
+		return fmt.Errorf("%w: %s", ErrDiagnosticsNotPending, requestID)
+	}
+	return nil
+}
+
+// finish 更新诊断包的最终状态
+func (s *DiagnosticsService) finish(ctx context.Context, id string, state models.DiagnosticsState, size int64, reason string) {
+	bundle, err := s.store.Get(ctx, id)
+	if err != nil {
+		s.logger.Error("获取诊断包记录失败: %v", err)
+		return
+	}
+	now := time.Now()
+	bundle.State = state
+	bundle.Size = size
+	bundle.Error = reason
+	bundle.CompletedAt = &now
+	if err := s.store.Update(ctx, bundle); err != nil {
+		s.logger.Error("更新诊断包记录失败: %v", err)
+	}
+}
+
+// expireLocked 等待上传超时的诊断请求标记为失败，调用方需持有 s.mu
+func (s *DiagnosticsService) expireLocked(ctx context.Context, bundle *models.DiagnosticsBundle) *models.DiagnosticsBundle {
+	if bundle.State != models.DiagnosticsStatePending || time.Since(bundle.CreatedAt) < diagnosticsTimeout {
+		return bundle
+	}
+	if s.uploading[bundle.ID] {
+		return bundle
+	}
+
+	now := time.Now()
+	bundle.State = models.DiagnosticsStateFailed
+	bundle.Error = "等待节点上传超时"
+	bundle.CompletedAt = &now
+	if err := s.store.Update(ctx, bundle); err != nil {
+		s.logger.Error("更新诊断包记录失败: %v", err)
+	}
+	return bundle
+}
+
+// prune 删除节点超出保留数量的诊断包
+func (s *DiagnosticsService) prune(ctx context.Context, nodeID string) {
+	bundles, err := s.List(ctx, nodeID)
+	if err != nil || len(bundles) <= maxDiagnosticsPerNode {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, bundle := range bundles[maxDiagnosticsPerNode:] {
+		if s.uploading[bundle.ID] {
+			continue
+		}
+		if err := os.Remove(s.bundlePath(bundle.ID)); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("删除诊断包文件失败: %v", err)
+			continue
+		}
+		if err := s.store.Delete(ctx, bundle.ID); err != nil {
+			s.logger.Warn("删除诊断包记录失败: %v", err)
+		}
+	}
+}
+
+// bundlePath 诊断包文件路径
+func (s *DiagnosticsService) bundlePath(id string) string {
+	return filepath.Join(s.dir, id+".tar.gz")
+}
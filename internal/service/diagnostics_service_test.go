@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+)
+
+// fakeDiagnosticsRequester 记录下发的诊断请求
+type fakeDiagnosticsRequester struct {
+	requests []string
+}
+
+func (r *fakeDiagnosticsRequester) RequestDiagnostics(ctx context.Context, nodeID string, requestID string) error {
+	r.requests = append(r.requests, requestID)
+	return nil
+}
+
+func TestDiagnosticsUpload(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	diagnosticsStore := store.NewYAMLDiagnosticsStore(ctx, filepath.Join(dir, "diagnostics.yaml"))
+	t.Cleanup(diagnosticsStore.Close)
+
+	requester := &fakeDiagnosticsRequester{}
+	svc := NewDiagnosticsService(diagnosticsStore, filepath.Join(dir, "bundles"))
+	svc.SetRequester(requester)
+
+	bundle, err := svc.Request(ctx, "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{bundle.ID}, requester.requests)
+
+	// 其他节点不能上传该诊断包
+	_, err = svc.BeginUpload(ctx, "node-2", bundle.ID)
+	assert.ErrorIs(t, err, ErrDiagnosticsNotOwned)
+
+	// 上传完成前不能下载
+	_, _, err = svc.Open(ctx, bundle.ID)
+	assert.ErrorIs(t, err, ErrDiagnosticsNotReady)
+
+	upload, err := svc.BeginUpload(ctx, "node-1", bundle.ID)
+	require.NoError(t, err)
+	// 同一诊断包不能同时上传
+	_, err = svc.BeginUpload(ctx, "node-1", bundle.ID)
+	assert.ErrorIs(t, err, ErrDiagnosticsNotPending)
+
+	require.NoError(t, upload.Write([]byte("hello ")))
+	require.NoError(t, upload.Write([]byte("world")))
+	size, err := upload.Commit(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 11, size)
+
+	f, got, err := svc.Open(ctx, bundle.ID)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, models.DiagnosticsStateCompleted, got.State)
+
+	// 已完成的诊断包不能再次上传
+	_, err = svc.BeginUpload(ctx, "node-1", bundle.ID)
+	assert.ErrorIs(t, err, ErrDiagnosticsNotPending)
+}
@@ -135,6 +135,9 @@ func (s *GameCardService) Get(ctx context.Context, id string) (*models.GameCard,
 // Create 创建游戏卡
 func (s *GameCardService) Create(ctx context.Context, card models.GameCard) (string, error) {
 	s.logger.Debug("创建游戏卡: id=%s, name=%s", card.ID, card.Name)
+	if err := models.ValidateSavePaths(card.SavePaths); err != nil {
+		return "", err
+	}
 
 	// 检查游戏卡是否已存在
 	existingCard, err := s.cardStore.Get(ctx, card.ID)
@@ -176,6 +179,9 @@ func (s *GameCardService) Create(ctx context.Context, card models.GameCard) (str
 // Update 更新游戏卡
 func (s *GameCardService) Update(ctx context.Context, id string, card models.GameCard) error {
 	s.logger.Debug("更新游戏卡: id=%s, name=%s", id, card.Name)
+	if err := models.ValidateSavePaths(card.SavePaths); err != nil {
+		return err
+	}
 
 	// 检查游戏卡是否存在
 	existingCard, err := s.cardStore.Get(ctx, id)
@@ -0,0 +1,628 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+)
+
+// 实例生命周期相关错误
+var (
+	ErrLifecycleDisabled       = errors.New("实例生命周期流水线未启用")
+	ErrInstanceStateTransition = errors.New("不允许的实例状态转换")
+)
+
+const (
+	// startPlatformTemplate 启动实例的流水线模板
+	startPlatformTemplate = "start-platform"
+	// stopPlatformTemplate 停止实例的流水线模板
+	stopPlatformTemplate = "stop-platform"
+	// defaultInstancePort 未指定端口时游戏容器映射到节点上的端口
+	defaultInstancePort = 8080
+	// maxHostnameLength 容器主机名的最大长度
+	maxHostnameLength = 63
+)
+
+// InstanceStateChangeHandler 实例状态变更后执行的处理函数
+// 处理函数在持有 lifecycleMu 时同步执行，不能调用实例服务的生命周期方法
+type InstanceStateChangeHandler func(ctx context.Context, instance models.GameInstance, from models.GameInstanceState)
+
+// SetLifecycle 设置实例生命周期依赖，实例的启动和停止通过流水线在节点上执行
+// 平台和卡片服务用于生成启动流水线的参数
+func (s *GameInstanceService) SetLifecycle(pipelines *GamePipelineService, platforms *GamePlatformService, cards *GameCardService) {
+	s.pipelines = pipelines
+	s.platforms = platforms
+	s.cards = cards
+}
+
+// SetPortAllocator 设置端口分配器，启动实例时在节点上分配端口，实例停止或失败时释放
+// 未设置时实例使用创建时指定的端口或默认端口
+func (s *GameInstanceService) SetPortAllocator(ports *PortAllocator) {
+	s.ports = ports
+}
+
+// SetTURNCredentials 设置 TURN 凭证签发器，每次启动实例时签发新的临时凭证
+// 未设置时使用 BEAGLE_WIND_TURN_USERNAME / BEAGLE_WIND_TURN_PASSWORD 环境变量中的静态凭证
+func (s *GameInstanceService) SetTURNCredentials(turn *TURNCredentialIssuer) {
+	s.turn = turn
+}
+
+// SetSaveService 设置存档同步服务，启动实例前确定要恢复的用户存档
+// 未设置时启动流水线的存档参数为空，不恢复存档
+func (s *GameInstanceService) SetSaveService(saves *SaveService) {
+	s.saves = saves
+}
+
+// OnStateChange 注册实例状态变更处理函数
+func (s *GameInstanceService) OnStateChange(handler InstanceStateChangeHandler) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.stateHandlers = append(s.stateHandlers, handler)
+}
+
+// notifyStateChange 执行实例状态变更处理函数
+func (s *GameInstanceService) notifyStateChange(ctx context.Context, instance models.GameInstance, from models.GameInstanceState) {
+	s.handlersMu.Lock()
+	handlers := append([]InstanceStateChangeHandler{}, s.stateHandlers...)
+	s.handlersMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(ctx, instance, from)
+	}
+}
+
+// Start 启动游戏实例
+// 未分配节点的实例先由调度器选择节点，然后提交启动流水线，实例状态随流水线状态更新
+func (s *GameInstanceService) Start(ctx context.Context, id string) (models.GameInstance, error) {
+	s.logger.Debug("启动游戏实例: %s", id)
+	if s.pipelines == nil {
+		return models.GameInstance{}, ErrLifecycleDisabled
+	}
+
+	s.lifecycleMu.Lock()
+	instance, err := s.Get(ctx, id)
+	if err != nil {
+		s.lifecycleMu.Unlock()
+		return models.GameInstance{}, err
+	}
+	if instance.Status == models.GameInstanceStateRunning {
+		s.lifecycleMu.Unlock()
+		return instance, ErrInstanceAlreadyRunning
+	}
+	target := models.GameInstanceStatePreparing
+	if instance.NodeID == "" {
+		target = models.GameInstanceStateScheduling
+	}
+	instance.StopReason = ""
+	instance.Idle = nil
+	if err := s.transition(ctx, &instance, target, ""); err != nil {
+		s.lifecycleMu.Unlock()
+		return instance, err
+	}
+	s.lifecycleMu.Unlock()
+
+	if target == models.GameInstanceStateScheduling {
+		if err := s.scheduleInstance(ctx, &instance); err != nil {
+			s.failLifecycle(ctx, id, target, err)
+			return instance, err
+		}
+	}
+
+	if err := s.assignPort(ctx, &instance); err != nil {
+		s.failLifecycle(ctx, id, models.GameInstanceStatePreparing, err)
+		return instance, err
+	}
+	if err := s.prepareSave(ctx, &instance); err != nil {
+		s.failLifecycle(ctx, id, models.GameInstanceStatePreparing, err)
+		return instance, err
+	}
+	args, err := s.startArgs(ctx, instance)
+	if err != nil {
+		s.failLifecycle(ctx, id, models.GameInstanceStatePreparing, err)
+		return instance, err
+	}
+	// 准备期间实例可能已被停止或删除，此时不再提交启动流水线
+	if err := s.checkState(ctx, id, models.GameInstanceStatePreparing); err != nil {
+		return instance, err
+	}
+	pipeline, err := s.pipelines.Submit(ctx, SubmitPipelineParams{
+		Template:   startPlatformTemplate,
+		Args:       args,
+		NodeID:     instance.NodeID,
+		InstanceID: instance.ID,
+		Queue:      true,
+	})
+	if err != nil {
+		s.failLifecycle(ctx, id, models.GameInstanceStatePreparing, err)
+		return instance, fmt.Errorf("提交启动流水线失败: %w", err)
+	}
+
+	s.logger.Info("已提交实例 %s 的启动流水线 %s", id, pipeline.ID)
+	return s.recordPipeline(ctx, id, startPlatformTemplate, pipeline.ID, models.GameInstanceStatePreparing)
+}
+
+// Stop 停止游戏实例
+// 取消尚未结束的启动流水线，然后提交停止流水线删除实例的容器
+func (s *GameInstanceService) Stop(ctx context.Context, id string) (models.GameInstance, error) {
+	return s.StopWithReason(ctx, id, models.StopReasonUser)
+}
+
+// StopWithReason 停止游戏实例并记录停止原因，空闲停止只作用于运行中的实例
+func (s *GameInstanceService) StopWithReason(ctx context.Context, id string, reason models.InstanceStopReason) (models.GameInstance, error) {
+	s.logger.Debug("停止游戏实例: %s, 原因: %s", id, reason)
+	if s.pipelines == nil {
+		return models.GameInstance{}, ErrLifecycleDisabled
+	}
+
+	s.lifecycleMu.Lock()
+	instance, err := s.Get(ctx, id)
+	if err != nil {
+		s.lifecycleMu.Unlock()
+		return models.GameInstance{}, err
+	}
+	switch instance.Status {
+	case models.GameInstanceStatePending, models.GameInstanceStateStopped:
+		s.lifecycleMu.Unlock()
+		return instance, ErrInstanceNotRunning
+	}
+	message := ""
+	if reason == models.StopReasonIdle {
+		if instance.Status != models.GameInstanceStateRunning {
+			s.lifecycleMu.Unlock()
+			return instance, ErrInstanceNotRunning
+		}
+		message = "空闲超时自动停止"
+	}
+	previous := instance.PipelineID
+	instance.StopReason = reason
+	instance.Idle = nil
+	if err := s.transition(ctx, &instance, models.GameInstanceStateStopping, message); err != nil {
+		s.lifecycleMu.Unlock()
+		return instance, err
+	}
+	s.lifecycleMu.Unlock()
+
+	// 启动流水线的取消事件在停止中状态下被忽略
+	// 尚未记录的启动流水线由 Start 在记录时发现实例已离开准备中状态后取消
+	if previous != "" {
+		s.cancelPipeline(ctx, id, previous)
+	}
+
+	pipeline, err := s.pipelines.Submit(ctx, SubmitPipelineParams{
+		Template:   stopPlatformTemplate,
+		Args:       map[string]string{"INSTANCE": instance.ID},
+		NodeID:     instance.NodeID,
+		InstanceID: instance.ID,
+		Queue:      true,
+	})
+	if err != nil {
+		s.failLifecycle(ctx, id, models.GameInstanceStateStopping, err)
+		return instance, fmt.Errorf("提交停止流水线失败: %w", err)
+	}
+
+	s.logger.Info("已提交实例 %s 的停止流水线 %s", id, pipeline.ID)
+	return s.recordPipeline(ctx, id, stopPlatformTemplate, pipeline.ID, models.GameInstanceStateStopping)
+}
+
+// HandlePipelineState 根据启动和停止流水线的状态更新实例状态
+// 只处理实例当前阶段对应模板的流水线，实例记录了其他流水线时忽略
+func (s *GameInstanceService) HandlePipelineState(ctx context.Context, pipeline *models.GamePipeline, from models.PipelineState) {
+	if pipeline.InstanceID == "" || pipeline.Dispatch == nil || pipeline.Status == nil {
+		return
+	}
+	template := pipeline.Dispatch.Template
+	if template != startPlatformTemplate && template != stopPlatformTemplate {
+		return
+	}
+
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	instance, err := s.GameInstanceStore.Get(ctx, pipeline.InstanceID)
+	if err != nil || instance.ID == "" {
+		s.logger.Warn("流水线 %s 所属的实例 %s 不存在", pipeline.ID, pipeline.InstanceID)
+		return
+	}
+	if instance.PipelineID != "" && instance.PipelineID != pipeline.ID {
+		s.logger.Debug("忽略实例 %s 的过期流水线 %s", instance.ID, pipeline.ID)
+		return
+	}
+
+	var target models.GameInstanceState
+	switch template {
+	case startPlatformTemplate:
+		target = startPipelineTarget(instance.Status, pipeline.Status.State)
+	case stopPlatformTemplate:
+		target = stopPipelineTarget(instance.Status, pipeline.Status.State)
+	}
+	if target == "" || target == instance.Status {
+		return
+	}
+
+	instance.PipelineID = pipeline.ID
+	if err := s.transition(ctx, &instance, target, pipeline.Status.ErrorMessage); err != nil {
+		s.logger.Warn("根据流水线 %s 更新实例 %s 状态失败: %v", pipeline.ID, instance.ID, err)
+	}
+}
+
+// startPipelineTarget 启动流水线状态对应的实例状态，返回空表示不更新
+func startPipelineTarget(current models.GameInstanceState, state models.PipelineState) models.GameInstanceState {
+	switch current {
+	case models.GameInstanceStatePreparing, models.GameInstanceStateStarting, models.GameInstanceStateLost:
+	default:
+		return ""
+	}
+	switch state {
+	case models.PipelineStateRunning:
+		if current != models.GameInstanceStateStarting {
+			return models.GameInstanceStateStarting
+		}
+	case models.PipelineStateCompleted:
+		return models.GameInstanceStateRunning
+	case models.PipelineStateFailed, models.PipelineStateCanceled:
+		return models.GameInstanceStateFailed
+	case models.PipelineStateLost:
+		return models.GameInstanceStateLost
+	}
+	return ""
+}
+
+// stopPipelineTarget 停止流水线状态对应的实例状态，返回空表示不更新
+func stopPipelineTarget(current models.GameInstanceState, state models.PipelineState) models.GameInstanceState {
+	if current != models.GameInstanceStateStopping {
+		return ""
+	}
+	switch state {
+	case models.PipelineStateCompleted:
+		return models.GameInstanceStateStopped
+	case models.PipelineStateFailed, models.PipelineStateCanceled:
+		return models.GameInstanceStateFailed
+	}
+	return ""
+}
+
+// transition 校验并保存实例状态转换，调用方需持有 lifecycleMu
+// 进入准备中、调度中和停止中状态时清除上一次的流水线记录
+func (s *GameInstanceService) transition(ctx context.Context, instance *models.GameInstance, target models.GameInstanceState, message string) error {
+	from := instance.Status
+	if !from.CanTransitionTo(target) {
+		return fmt.Errorf("%w: %s -> %s", ErrInstanceStateTransition, from, target)
+	}
+
+	now := time.Now()
+	instance.Status = target
+	instance.Message = message
+	instance.UpdatedAt = now
+	switch target {
+	case models.GameInstanceStateScheduling, models.GameInstanceStatePreparing, models.GameInstanceStateStopping:
+		instance.PipelineID = ""
+	case models.GameInstanceStateRunning:
+		instance.StartedAt = now
+	case models.GameInstanceStateStopped, models.GameInstanceStateFailed:
+		instance.StoppedAt = now
+		instance.Idle = nil
+		if target == models.GameInstanceStateFailed && instance.StopReason == "" {
+			instance.StopReason = models.StopReasonFailed
+		}
+		s.releasePort(ctx, instance)
+	}
+	if err := s.GameInstanceStore.Update(ctx, *instance); err != nil {
+		s.logger.Error("更新实例状态失败: %v", err)
+		return fmt.Errorf("更新实例状态失败: %w", err)
+	}
+	s.logger.Info("实例 %s 状态变更: %s -> %s", instance.ID, from, target)
+	s.notifyStateChange(ctx, *instance, from)
+	return nil
+}
+
+// failLifecycle 实例仍处于 expected 状态时标记为失败
+func (s *GameInstanceService) failLifecycle(ctx context.Context, id string, expected models.GameInstanceState, cause error) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	instance, err := s.GameInstanceStore.Get(ctx, id)
+	if err != nil || instance.Status != expected {
+		return
+	}
+	if err := s.transition(ctx, &instance, models.GameInstanceStateFailed, cause.Error()); err != nil {
+		s.logger.Error("标记实例 %s 失败状态失败: %v", id, err)
+	}
+}
+
+// recordPipeline 记录实例当前的生命周期流水线并返回最新的实例
+// 流水线状态先于记录到达时已由 HandlePipelineState 记录
+// 实例已离开 expected 状态或记录了其他流水线时，template 流水线已经过期，取消流水线并返回错误
+func (s *GameInstanceService) recordPipeline(ctx context.Context, id string, template string, pipelineID string, expected models.GameInstanceState) (models.GameInstance, error) {
+	instance, stale, err := s.storePipeline(ctx, id, pipelineID, expected)
+	if err != nil || !stale {
+		return instance, err
+	}
+	s.logger.Warn("实例 %s 已变为 %s，取消过期的%s流水线 %s", id, instance.Status, template, pipelineID)
+	s.cancelPipeline(ctx, id, pipelineID)
+	return instance, fmt.Errorf("%w: 实例已变为 %s，%s 流水线已取消", ErrInstanceStateTransition, instance.Status, template)
+}
+
+// storePipeline 实例仍处于 expected 状态且未记录其他流水线时记录流水线，否则返回 true 表示流水线已过期
+func (s *GameInstanceService) storePipeline(ctx context.Context, id string, pipelineID string, expected models.GameInstanceState) (models.GameInstance, bool, error) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	instance, err := s.GameInstanceStore.Get(ctx, id)
+	if err != nil {
+		return models.GameInstance{}, false, fmt.Errorf("获取实例失败: %w", err)
+	}
+	if instance.PipelineID == pipelineID {
+		return instance, false, nil
+	}
+	if instance.PipelineID != "" || instance.Status != expected {
+		return instance, true, nil
+	}
+	instance.PipelineID = pipelineID
+	instance.UpdatedAt = time.Now()
+	if err := s.GameInstanceStore.Update(ctx, instance); err != nil {
+		return instance, false, fmt.Errorf("更新实例失败: %w", err)
+	}
+	return instance, false, nil
+}
+
+// cancelPipeline 取消实例尚未结束的流水线，不能在持有 lifecycleMu 时调用
+func (s *GameInstanceService) cancelPipeline(ctx context.Context, id string, pipelineID string) {
+	pipeline, err := s.pipelines.Get(ctx, pipelineID)
+	if err != nil || pipeline == nil || pipelineState(pipeline).IsTerminal() {
+		return
+	}
+	if err := s.pipelines.Cancel(ctx, pipelineID); err != nil {
+		s.logger.Warn("取消实例 %s 的流水线 %s 失败: %v", id, pipelineID, err)
+	}
+}
+
+// checkState 确认实例仍处于 expected 状态
+func (s *GameInstanceService) checkState(ctx context.Context, id string, expected models.GameInstanceState) error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	instance, err := s.GameInstanceStore.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("获取实例失败: %w", err)
+	}
+	if instance.Status != expected {
+		return fmt.Errorf("%w: 实例已变为 %s", ErrInstanceStateTransition, instance.Status)
+	}
+	return nil
+}
+
+// assignPort 在实例的节点上分配端口并记录到实例，未设置端口分配器时不分配
+func (s *GameInstanceService) assignPort(ctx context.Context, instance *models.GameInstance) error {
+	if s.ports == nil {
+		return nil
+	}
+	port, err := s.ports.Allocate(ctx, instance.NodeID, instance.ID)
+	if err != nil {
+		return err
+	}
+
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	current, err := s.GameInstanceStore.Get(ctx, instance.ID)
+	if err != nil {
+		return fmt.Errorf("获取实例失败: %w", err)
+	}
+	// 分配期间实例已被停止或删除，释放刚分配的端口
+	if current.Status != models.GameInstanceStatePreparing {
+		s.releasePort(ctx, &current)
+		return fmt.Errorf("%w: 实例已变为 %s", ErrInstanceStateTransition, current.Status)
+	}
+	current.Port = port
+	current.UpdatedAt = time.Now()
+	if err := s.GameInstanceStore.Update(ctx, current); err != nil {
+		return fmt.Errorf("更新实例失败: %w", err)
+	}
+	*instance = current
+	return nil
+}
+
+// prepareSave 确定实例本次启动要恢复的存档并记录到实例，未设置存档同步服务时不恢复
+func (s *GameInstanceService) prepareSave(ctx context.Context, instance *models.GameInstance) error {
+	if s.saves == nil {
+		return nil
+	}
+	state, err := s.saves.Prepare(ctx, *instance)
+	if err != nil {
+		return err
+	}
+	if state == nil && instance.Save == nil {
+		return nil
+	}
+
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	current, err := s.GameInstanceStore.Get(ctx, instance.ID)
+	if err != nil {
+		return fmt.Errorf("获取实例失败: %w", err)
+	}
+	if current.Status != models.GameInstanceStatePreparing {
+		return fmt.Errorf("%w: 实例已变为 %s", ErrInstanceStateTransition, current.Status)
+	}
+	current.Save = state
+	current.UpdatedAt = time.Now()
+	if err := s.GameInstanceStore.Update(ctx, current); err != nil {
+		return fmt.Errorf("更新实例失败: %w", err)
+	}
+	*instance = current
+	return nil
+}
+
+// updateSave 修改实例的存档同步状态，update 返回 false 时不保存
+func (s *GameInstanceService) updateSave(ctx context.Context, id string, update func(state *models.SaveState) bool) error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	instance, err := s.GameInstanceStore.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("获取实例失败: %w", err)
+	}
+	if instance.Save == nil || !update(instance.Save) {
+		return nil
+	}
+	instance.UpdatedAt = time.Now()
+	if err := s.GameInstanceStore.Update(ctx, instance); err != nil {
+		return fmt.Errorf("更新实例失败: %w", err)
+	}
+	return nil
+}
+
+// updateIdle 记录运行中实例的空闲状态，idle 为 nil 时清除，实例已不在运行中时不修改
+func (s *GameInstanceService) updateIdle(ctx context.Context, id string, idle *models.IdleState) error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	instance, err := s.GameInstanceStore.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("获取实例失败: %w", err)
+	}
+	if instance.Status != models.GameInstanceStateRunning {
+		return nil
+	}
+	instance.Idle = idle
+	instance.UpdatedAt = time.Now()
+	if err := s.GameInstanceStore.Update(ctx, instance); err != nil {
+		return fmt.Errorf("更新实例失败: %w", err)
+	}
+	return nil
+}
+
+// releasePort 释放实例的端口预留，未设置端口分配器时保留实例的端口
+func (s *GameInstanceService) releasePort(ctx context.Context, instance *models.GameInstance) {
+	if s.ports == nil {
+		return
+	}
+	if _, err := s.ports.Release(ctx, instance.ID); err != nil {
+		s.logger.Error("释放实例 %s 的端口失败: %v", instance.ID, err)
+		return
+	}
+	instance.Port = 0
+}
+
+// scheduleInstance 为未分配节点的实例选择节点并预留资源，实例转换为准备中
+func (s *GameInstanceService) scheduleInstance(ctx context.Context, instance *models.GameInstance) error {
+	if s.scheduler == nil {
+		return ErrSchedulerDisabled
+	}
+	_, err := s.scheduler.Place(ctx, ScheduleParams{
+		CardID:     instance.CardID,
+		PlatformID: instance.PlatformID,
+	}, func(decision *models.SchedulingDecision) error {
+		s.lifecycleMu.Lock()
+		defer s.lifecycleMu.Unlock()
+
+		current, err := s.GameInstanceStore.Get(ctx, instance.ID)
+		if err != nil {
+			return fmt.Errorf("获取实例失败: %w", err)
+		}
+		if current.Status != models.GameInstanceStateScheduling {
+			return fmt.Errorf("%w: %s -> %s", ErrInstanceStateTransition, current.Status, models.GameInstanceStatePreparing)
+		}
+		reservation := decision.Requirements.Reservation()
+		current.NodeID = decision.NodeID
+		current.Reservation = &reservation
+		if err := s.transition(ctx, &current, models.GameInstanceStatePreparing, ""); err != nil {
+			return err
+		}
+		*instance = current
+		return nil
+	})
+	return err
+}
+
+// startArgs 根据实例、平台和卡片生成启动流水线的参数，每次启动签发新的 TURN 凭证
+// 存档参数来自启动前确定的存档同步状态
+func (s *GameInstanceService) startArgs(ctx context.Context, instance models.GameInstance) (map[string]string, error) {
+	if s.platforms == nil || s.cards == nil {
+		return nil, ErrLifecycleDisabled
+	}
+	platform, err := s.platforms.Get(ctx, instance.PlatformID)
+	if err != nil {
+		return nil, fmt.Errorf("获取平台失败: %w", err)
+	}
+	if platform == nil {
+		return nil, fmt.Errorf("平台不存在: %s", instance.PlatformID)
+	}
+	if platform.Image == "" {
+		return nil, fmt.Errorf("平台 %s 未配置镜像", platform.ID)
+	}
+	card, err := s.cards.Get(ctx, instance.CardID)
+	if err != nil {
+		return nil, fmt.Errorf("获取卡片失败: %w", err)
+	}
+	if card == nil {
+		return nil, fmt.Errorf("卡片不存在: %s", instance.CardID)
+	}
+
+	port := instance.Port
+	if port == 0 {
+		port = defaultInstancePort
+	}
+	hostname := card.SlugName
+	if hostname == "" {
+		hostname = instance.ID
+	}
+	turnUsername, turnPassword := s.turnCredentials(instance.ID)
+	args := map[string]string{
+		"PLATFORM":      platform.ID,
+		"INSTANCE":      instance.ID,
+		"IMAGE":         platform.Image,
+		"PORT":          strconv.Itoa(port),
+		"HOSTNAME":      sanitizeHostname(hostname),
+		"TURN_USERNAME": turnUsername,
+		"TURN_PASSWORD": turnPassword,
+	}
+	for name, value := range s.saveArgs(instance) {
+		args[name] = value
+	}
+	return args, nil
+}
+
+// saveArgs 启动流水线恢复存档的参数，未设置存档同步服务时参数为空
+func (s *GameInstanceService) saveArgs(instance models.GameInstance) map[string]string {
+	if s.saves == nil {
+		return emptySaveArgs()
+	}
+	return s.saves.RestoreArgs(instance.Save)
+}
+
+// turnCredentials 获取实例本次启动使用的 TURN 用户名和密码
+func (s *GameInstanceService) turnCredentials(instanceID string) (string, string) {
+	if s.turn == nil {
+		return s.pipelines.Env("BEAGLE_WIND_TURN_USERNAME"), s.pipelines.Env("BEAGLE_WIND_TURN_PASSWORD")
+	}
+	credentials := s.turn.Issue(instanceID, time.Now())
+	s.logger.Info("为实例 %s 签发 TURN 凭证，有效期至 %s", instanceID, credentials.ExpiresAt.Format(time.RFC3339))
+	return credentials.Username, credentials.Password
+}
+
+// sanitizeHostname 将名称转换为合法的主机名：小写字母、数字和连字符，不超过 63 个字符
+func sanitizeHostname(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	hostname := b.String()
+	if len(hostname) > maxHostnameLength {
+		hostname = hostname[:maxHostnameLength]
+	}
+	hostname = strings.Trim(hostname, "-")
+	if hostname == "" {
+		return "instance"
+	}
+	return hostname
+}
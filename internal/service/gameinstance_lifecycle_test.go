@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// fakeDispatcher 记录下发和取消的流水线，节点始终在线
+type fakeDispatcher struct {
+	mu         sync.Mutex
+	dispatched []*models.GamePipeline
+	canceled   []string
+	// onDispatch 下发流水线时调用，用于模拟下发期间到达的其他请求
+	onDispatch func(pipeline *models.GamePipeline)
+}
+
+func (d *fakeDispatcher) IsConnected(nodeID string) bool { return true }
+
+func (d *fakeDispatcher) Dispatch(ctx context.Context, nodeID string, pipeline *models.GamePipeline) error {
+	d.mu.Lock()
+	d.dispatched = append(d.dispatched, pipeline)
+	onDispatch := d.onDispatch
+	d.mu.Unlock()
+	if onDispatch != nil {
+		onDispatch(pipeline)
+	}
+	return nil
+}
+
+func (d *fakeDispatcher) Cancel(ctx context.Context, nodeID string, pipelineID string, reason string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.canceled = append(d.canceled, pipelineID)
+	return nil
+}
+
+func TestGameInstanceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// 存储延迟落盘，预先创建数据文件
+	for _, name := range []string{"cards.yaml", "platforms.yaml", "instances.yaml", "pipelines.yaml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[]\n"), 0644))
+	}
+	cardStore, err := store.NewGameCardStore(ctx, filepath.Join(dir, "cards.yaml"))
+	require.NoError(t, err)
+	platformStore, err := store.NewGamePlatformStore(ctx, filepath.Join(dir, "platforms.yaml"))
+	require.NoError(t, err)
+	instanceStore, err := store.NewGameInstanceStore(ctx, filepath.Join(dir, "instances.yaml"), utils.New("LifecycleTest"))
+	require.NoError(t, err)
+
+	cards := NewGameCardService(cardStore)
+	platforms := NewGamePlatformService(platformStore)
+	instances := NewGameInstanceService(instanceStore)
+	pipelines := NewGamePipelineService(store.NewYAMLGamePipelineStore(ctx, filepath.Join(dir, "pipelines.yaml")))
+	dispatcher := &fakeDispatcher{}
+	pipelines.SetDispatcher(dispatcher)
+	pipelines.SetTemplateSource("../../config/pipeline", nil)
+	instances.SetLifecycle(pipelines, platforms, cards)
+	pipelines.OnStateChange(instances.HandlePipelineState)
+	ports, err := NewPortAllocator(store.NewYAMLPortReservationStore(ctx, filepath.Join(dir, "ports.yaml")), 30001, 30010)
+	require.NoError(t, err)
+	instances.SetPortAllocator(ports)
+	turn, err := NewTURNCredentialIssuer("turn-secret", time.Hour)
+	require.NoError(t, err)
+	instances.SetTURNCredentials(turn)
+	var transitions []models.GameInstanceState
+	instances.OnStateChange(func(ctx context.Context, instance models.GameInstance, from models.GameInstanceState) {
+		transitions = append(transitions, instance.Status)
+	})
+
+	_, err = platforms.Create(ctx, models.GamePlatform{ID: "steam", Name: "Steam", Image: "registry.example.com/steam:1.0"})
+	require.NoError(t, err)
+	_, err = cards.Create(ctx, models.GameCard{ID: "card-1", Name: "Card", PlatformID: "steam", SlugName: "Hollow_Knight"})
+	require.NoError(t, err)
+	id, err := instances.Create(ctx, CreateInstanceParams{NodeID: "node-1", PlatformID: "steam", CardID: "card-1"})
+	require.NoError(t, err)
+
+	status := func() models.GameInstanceState {
+		instance, err := instances.Get(ctx, id)
+		require.NoError(t, err)
+		return instance.Status
+	}
+	report := func(pipelineID string, sequence int64, state models.PipelineState) {
+		_, err := pipelines.ApplyStatusReport(ctx, pipelineID, "node-1", sequence, &models.PipelineStatus{State: state, ErrorMessage: "步骤失败"})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, models.GameInstanceStatePending, status())
+
+	_, err = instances.Stop(ctx, id)
+	assert.ErrorIs(t, err, ErrInstanceNotRunning)
+
+	// 启动：分配端口并提交 start-platform 流水线，参数来自实例、平台和卡片
+	instance, err := instances.Start(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameInstanceStatePreparing, instance.Status)
+	assert.Equal(t, 30001, instance.Port)
+	require.Len(t, dispatcher.dispatched, 1)
+	start := dispatcher.dispatched[0]
+	assert.Equal(t, instance.PipelineID, start.ID)
+	assert.Equal(t, id, start.InstanceID)
+	// TURN 密码只在下发给节点时替换到步骤中，保存的流水线只有引用
+	assert.Equal(t, []string{"TURN_PASSWORD"}, start.Dispatch.SecretArgs)
+	stored, err := pipelines.Get(ctx, start.ID)
+	require.NoError(t, err)
+	storedData, err := json.Marshal(stored)
+	require.NoError(t, err)
+	assert.Contains(t, string(storedData), `${{ secrets.TURN_PASSWORD }}`)
+	startData, err := json.Marshal(start)
+	require.NoError(t, err)
+	assert.NotContains(t, string(startData), "secrets.TURN_PASSWORD")
+	password := stored.Dispatch.Secrets["TURN_PASSWORD"]
+	assert.NotEmpty(t, password)
+	assert.NotContains(t, string(storedData), password)
+	assert.Contains(t, string(startData), password)
+	args := start.Dispatch.Args
+	assert.True(t, strings.HasSuffix(args["TURN_USERNAME"], ":"+id))
+	delete(args, "TURN_USERNAME")
+	// 未启用存档同步时存档参数为空
+	assert.Equal(t, map[string]string{
+		"PLATFORM":           "steam",
+		"INSTANCE":           id,
+		"IMAGE":              "registry.example.com/steam:1.0",
+		"PORT":               "30001",
+		"HOSTNAME":           "hollow-knight",
+		"SAVE_S3_URL":        "",
+		"SAVE_S3_ACCESS_KEY": "",
+		"SAVE_S3_SECRET_KEY": "",
+		"SAVE_S3_BUCKET":     "",
+		"SAVE_PATHS":         "",
+		"SAVE_OBJECT":        "",
+		"SAVE_CHECKSUM":      "",
+	}, args)
+
+	_, err = instances.Start(ctx, id)
+	assert.ErrorIs(t, err, ErrInstanceStateTransition)
+
+	report(start.ID, 1, models.PipelineStateRunning)
+	assert.Equal(t, models.GameInstanceStateStarting, status())
+	report(start.ID, 2, models.PipelineStateCompleted)
+	assert.Equal(t, models.GameInstanceStateRunning, status())
+
+	// 停止：提交 stop-platform 流水线，完成后实例停止
+	instance, err = instances.Stop(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameInstanceStateStopping, instance.Status)
+	require.Len(t, dispatcher.dispatched, 2)
+	stop := dispatcher.dispatched[1]
+	assert.Equal(t, stopPlatformTemplate, stop.Dispatch.Template)
+	assert.Equal(t, instance.PipelineID, stop.ID)
+	report(stop.ID, 1, models.PipelineStateCompleted)
+	instance, err = instances.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameInstanceStateStopped, instance.Status)
+	assert.Zero(t, instance.Port)
+	assert.Equal(t, []models.GameInstanceState{
+		models.GameInstanceStatePreparing,
+		models.GameInstanceStateStarting,
+		models.GameInstanceStateRunning,
+		models.GameInstanceStateStopping,
+		models.GameInstanceStateStopped,
+	}, transitions)
+	reservations, err := ports.Reservations(ctx, "node-1")
+	require.NoError(t, err)
+	assert.Empty(t, reservations)
+
+	// 再次启动后流水线失败，随后停止时取消的启动流水线不影响实例
+	_, err = instances.Start(ctx, id)
+	require.NoError(t, err)
+	restart := dispatcher.dispatched[2]
+	report(restart.ID, 1, models.PipelineStateFailed)
+	instance, err = instances.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameInstanceStateFailed, instance.Status)
+	assert.Equal(t, "步骤失败", instance.Message)
+
+	_, err = instances.Start(ctx, id)
+	require.NoError(t, err)
+	running := dispatcher.dispatched[3]
+	report(running.ID, 1, models.PipelineStateRunning)
+	// 未结束的实例需先停止才能删除
+	assert.ErrorIs(t, instances.Delete(ctx, id), ErrInstanceStateTransition)
+	_, err = instances.Stop(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{running.ID}, dispatcher.canceled)
+	assert.Equal(t, models.GameInstanceStateStopping, status())
+	report(dispatcher.dispatched[4].ID, 1, models.PipelineStateCompleted)
+	assert.Equal(t, models.GameInstanceStateStopped, status())
+
+	// 提交启动流水线期间实例被停止：启动流水线被取消，实例随停止流水线停止
+	dispatcher.onDispatch = func(pipeline *models.GamePipeline) {
+		if pipeline.Dispatch.Template == startPlatformTemplate {
+			_, err := instances.Stop(ctx, id)
+			require.NoError(t, err)
+		}
+	}
+	_, err = instances.Start(ctx, id)
+	assert.ErrorIs(t, err, ErrInstanceStateTransition)
+	dispatcher.onDispatch = nil
+	require.Len(t, dispatcher.dispatched, 7)
+	raced, racedStop := dispatcher.dispatched[5], dispatcher.dispatched[6]
+	assert.Equal(t, stopPlatformTemplate, racedStop.Dispatch.Template)
+	assert.Equal(t, []string{running.ID, raced.ID}, dispatcher.canceled)
+	instance, err = instances.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, racedStop.ID, instance.PipelineID)
+	report(racedStop.ID, 1, models.PipelineStateCompleted)
+	assert.Equal(t, models.GameInstanceStateStopped, status())
+	reservations, err = ports.Reservations(ctx, "node-1")
+	require.NoError(t, err)
+	assert.Empty(t, reservations)
+	require.NoError(t, instances.Delete(ctx, id))
+}
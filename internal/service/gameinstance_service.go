@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/open-beagle/beagle-wind-game/internal/models"
@@ -18,12 +19,30 @@ var (
 	ErrInstanceNotRunning     = fmt.Errorf("实例未在运行中")
 	ErrNodeNotFound           = fmt.Errorf("节点不存在")
 	ErrNodeNotReady           = fmt.Errorf("节点未就绪")
+	ErrInvalidUserID          = fmt.Errorf("无效的用户ID")
 )
 
 // GameInstanceService 游戏实例服务
 type GameInstanceService struct {
 	GameInstanceStore store.GameInstanceStore
 	logger            utils.Logger
+
+	// 实例调度器，未设置时创建实例必须指定节点
+	scheduler *InstanceScheduler
+
+	// 实例生命周期依赖，由 SetLifecycle / SetPortAllocator / SetTURNCredentials / SetSaveService 注入
+	pipelines *GamePipelineService
+	platforms *GamePlatformService
+	cards     *GameCardService
+	ports     *PortAllocator
+	turn      *TURNCredentialIssuer
+	saves     *SaveService
+	// lifecycleMu 保证实例状态的检查和更新是原子的，持有时不调用流水线服务
+	lifecycleMu sync.Mutex
+
+	// stateHandlers 实例状态变更处理函数，由 OnStateChange 注册
+	handlersMu    sync.Mutex
+	stateHandlers []InstanceStateChangeHandler
 }
 
 // NewGameInstanceService 创建游戏实例服务
@@ -35,12 +54,17 @@ func NewGameInstanceService(GameInstanceStore store.GameInstanceStore) *GameInst
 	}
 }
 
+// SetScheduler 设置实例调度器
+func (s *GameInstanceService) SetScheduler(scheduler *InstanceScheduler) {
+	s.scheduler = scheduler
+}
+
 // GameInstanceListParams 实例列表查询参数
 type GameInstanceListParams struct {
 	Page       int    `form:"page" binding:"omitempty,min=1"`
 	PageSize   int    `form:"size" binding:"omitempty,min=1,max=100"`
 	Keyword    string `form:"keyword" binding:"omitempty"`
-	Status     string `form:"status" binding:"omitempty,oneof=running stopped paused error"`
+	Status     string `form:"status" binding:"omitempty,oneof=pending scheduling preparing starting running stopping stopped failed lost"`
 	NodeID     string `form:"node_id" binding:"omitempty"`
 	CardID     string `form:"card_id" binding:"omitempty"`
 	PlatformID string `form:"platform_id" binding:"omitempty"`
@@ -100,16 +124,69 @@ func (s *GameInstanceService) Get(ctx context.Context, id string) (models.GameIn
 
 // CreateInstanceParams 创建实例参数
 type CreateInstanceParams struct {
-	NodeID     string `json:"node_id" binding:"required"`
+	NodeID     string `json:"node_id"` // 为空时由调度器选择节点
 	PlatformID string `json:"platform_id" binding:"required"`
 	CardID     string `json:"card_id" binding:"required"`
 	Config     string `json:"config,omitempty"` // 自定义配置
+	Port       int    `json:"port,omitempty"`   // 未设置端口分配器时游戏容器映射到节点上的端口，为空时使用 8080
+
+	// 调度参数，只在未指定 node_id 时使用
+	Policy   models.SchedulingPolicy `json:"policy,omitempty"`   // 调度策略
+	Selector map[string]string       `json:"selector,omitempty"` // 节点标签选择器
+	Location string                  `json:"location,omitempty"` // 节点位置
+
+	// UserID 使用实例的用户，存档按用户和卡片同步，为空时不同步存档
+	UserID string `json:"user_id,omitempty"`
 }
 
 // Create 创建游戏实例
+// 未指定节点时由调度器选择节点，设置了调度器时实例按资源要求在节点上预留资源
 func (s *GameInstanceService) Create(ctx context.Context, params CreateInstanceParams) (string, error) {
 	s.logger.Debug("创建游戏实例: 节点=%s, 平台=%s, 卡片=%s", params.NodeID, params.PlatformID, params.CardID)
+	if params.UserID != "" {
+		if err := models.ValidateSaveName(params.UserID); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidUserID, err)
+		}
+	}
+
+	if s.scheduler == nil {
+		if params.NodeID == "" {
+			return "", ErrSchedulerDisabled
+		}
+		return s.create(ctx, params, nil)
+	}
+
+	var id string
+	_, err := s.scheduler.Place(ctx, ScheduleParams{
+		CardID:     params.CardID,
+		PlatformID: params.PlatformID,
+		NodeID:     params.NodeID,
+		Policy:     params.Policy,
+		Selector:   params.Selector,
+		Location:   params.Location,
+	}, func(decision *models.SchedulingDecision) error {
+		params.NodeID = decision.NodeID
+		reservation := decision.Requirements.Reservation()
+		var err error
+		id, err = s.create(ctx, params, &reservation)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Schedule 预览实例的调度结果，不创建实例也不预留资源
+func (s *GameInstanceService) Schedule(ctx context.Context, params ScheduleParams) (*models.SchedulingDecision, error) {
+	if s.scheduler == nil {
+		return nil, ErrSchedulerDisabled
+	}
+	return s.scheduler.Schedule(ctx, params)
+}
 
+// create 在指定节点上创建游戏实例
+func (s *GameInstanceService) create(ctx context.Context, params CreateInstanceParams, reservation *models.ResourceReservation) (string, error) {
 	// 创建实例
 	now := time.Now()
 	instance := models.GameInstance{
@@ -117,13 +194,15 @@ func (s *GameInstanceService) Create(ctx context.Context, params CreateInstanceP
 		NodeID:     params.NodeID,
 		PlatformID: params.PlatformID,
 		CardID:     params.CardID,
-		Status:     "starting", // 初始状态为starting
-		Resources:  "",         // 待填充
+		Status:     models.GameInstanceStatePending, // 通过 Start 提交启动流水线
+		Resources:  "",                              // 待填充
 		Config:     params.Config,
 		CreatedAt:  now,
 		UpdatedAt:  now,
-		StartedAt:  now,
 	}
+	instance.Reservation = reservation
+	instance.Port = params.Port
+	instance.UserID = params.UserID
 
 	// 检查实例是否已存在
 	existingInstance, err := s.GameInstanceStore.Get(ctx, instance.ID)
@@ -147,9 +226,8 @@ func (s *GameInstanceService) Create(ctx context.Context, params CreateInstanceP
 	return instance.ID, nil
 }
 
-// UpdateInstanceParams 更新实例参数
+// UpdateInstanceParams 更新实例参数，实例状态由启动和停止流水线维护，不能直接修改
 type UpdateInstanceParams struct {
-	Status      string `json:"status,omitempty"`
 	Resources   string `json:"resources,omitempty"`
 	Performance string `json:"performance,omitempty"`
 	SaveData    string `json:"save_data,omitempty"`
@@ -178,6 +256,23 @@ func (s *GameInstanceService) Update(ctx context.Context, instance models.GameIn
 
 	// 保留创建时间
 	instance.CreatedAt = existingInstance.CreatedAt
+	// 保留调度时预留的资源
+	if instance.Reservation == nil {
+		instance.Reservation = existingInstance.Reservation
+	}
+	// 保留生命周期状态
+	instance.Status = existingInstance.Status
+	instance.PipelineID = existingInstance.PipelineID
+	instance.Message = existingInstance.Message
+	if instance.Port == 0 {
+		instance.Port = existingInstance.Port
+	}
+	// 保留实例用户和存档同步状态
+	instance.UserID = existingInstance.UserID
+	instance.Save = existingInstance.Save
+	// 保留停止原因和空闲状态
+	instance.StopReason = existingInstance.StopReason
+	instance.Idle = existingInstance.Idle
 	// 更新更新时间
 	instance.UpdatedAt = time.Now()
 	// 确保ID一致
@@ -198,10 +293,13 @@ func (s *GameInstanceService) Update(ctx context.Context, instance models.GameIn
 	return nil
 }
 
-// Delete 删除游戏实例
+// Delete 删除游戏实例，只能删除未启动、已停止或失败的实例
 func (s *GameInstanceService) Delete(ctx context.Context, id string) error {
 	s.logger.Debug("删除游戏实例: %s", id)
 
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
 	// 检查实例是否存在
 	instance, err := s.GameInstanceStore.Get(ctx, id)
 	if err != nil {
@@ -222,6 +320,14 @@ func (s *GameInstanceService) Delete(ctx context.Context, id string) error {
 		return fmt.Errorf("实例不存在: %s", id)
 	}
 
+	// 未结束的实例在节点上可能仍有容器和流水线，需先停止
+	if instance.Status != models.GameInstanceStatePending && instance.Status.ReservesResources() {
+		return fmt.Errorf("%w: 实例处于 %s 状态，需先停止", ErrInstanceStateTransition, instance.Status)
+	}
+
+	// 释放实例的端口预留
+	s.releasePort(ctx, &instance)
+
 	// 删除实例
 	err = s.GameInstanceStore.Delete(ctx, id)
 	if err != nil {
@@ -237,97 +343,86 @@ func (s *GameInstanceService) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-// Start 启动游戏实例
-func (s *GameInstanceService) Start(ctx context.Context, id string) error {
-	s.logger.Debug("启动游戏实例: %s", id)
+// MarkNodeLost 将节点上启动中和运行中的实例标记为失联，返回标记的数量
+func (s *GameInstanceService) MarkNodeLost(ctx context.Context, nodeID string) (int, error) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
 
-	// 获取实例
-	instance, err := s.GameInstanceStore.Get(ctx, id)
+	instances, err := s.GameInstanceStore.List(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "目标是一个目录") {
-			s.logger.Error("存储层错误: 目标是一个目录")
-			return fmt.Errorf("存储层错误")
-		}
-		s.logger.Error("获取实例失败: %v", err)
-		return err
+		return 0, fmt.Errorf("获取实例列表失败: %w", err)
 	}
 
-	if instance.ID == "" {
-		s.logger.Error("实例不存在: %s", id)
-		return fmt.Errorf("实例不存在: %s", id)
+	count := 0
+	for _, instance := range instances {
+		if instance.NodeID != nodeID || !instance.Status.CanTransitionTo(models.GameInstanceStateLost) {
+			continue
+		}
+		if err := s.transition(ctx, &instance, models.GameInstanceStateLost, "运行节点失联"); err != nil {
+			return count, err
+		}
+		count++
 	}
-
-	// 检查实例状态
-	if instance.Status == "running" {
-		s.logger.Error("实例已经在运行中: %s", id)
-		return ErrInstanceAlreadyRunning
+	if count > 0 {
+		s.logger.Warn("节点 %s 失联，%d 个实例标记为失联", nodeID, count)
 	}
+	return count, nil
+}
 
-	// 更新实例状态
-	instance.Status = "running"
-	instance.StartedAt = time.Now()
-	instance.UpdatedAt = time.Now()
+// RecoverLost 节点恢复后根据容器清单确定失联实例的状态
+// 游戏容器仍在运行时恢复为运行中，否则标记为失败，实例已不处于失联状态时不处理
+func (s *GameInstanceService) RecoverLost(ctx context.Context, id string, containerRunning bool) error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
 
-	// 保存更新
-	err = s.GameInstanceStore.Update(ctx, instance)
+	instance, err := s.GameInstanceStore.Get(ctx, id)
 	if err != nil {
-		if strings.Contains(err.Error(), "目标是一个目录") {
-			s.logger.Error("存储层错误: 目标是一个目录")
-			return fmt.Errorf("存储层错误")
+		return fmt.Errorf("获取实例失败: %w", err)
+	}
+	if instance.Status != models.GameInstanceStateLost {
+		return nil
+	}
+
+	if containerRunning {
+		if err := s.transition(ctx, &instance, models.GameInstanceStateRunning, "运行节点已恢复"); err != nil {
+			return err
 		}
-		s.logger.Error("更新实例状态失败: %v", err)
+		s.logger.Info("实例 %s 的运行节点已恢复，游戏容器仍在运行", id)
+		return nil
+	}
+	if err := s.transition(ctx, &instance, models.GameInstanceStateFailed, "运行节点恢复后游戏容器未运行"); err != nil {
 		return err
 	}
-
-	s.logger.Info("成功启动游戏实例: %s", id)
+	s.logger.Warn("实例 %s 的运行节点已恢复，但游戏容器未运行，标记为失败", id)
 	return nil
 }
 
-// Stop 停止游戏实例
-func (s *GameInstanceService) Stop(ctx context.Context, id string) error {
-	s.logger.Debug("停止游戏实例: %s", id)
+// MarkContainerMissing 将节点上已没有运行中容器的实例标记为失败
+func (s *GameInstanceService) MarkContainerMissing(ctx context.Context, id string) error {
+	return s.failRunning(ctx, id, "游戏容器已不存在")
+}
+
+// MarkContainerExited 将游戏容器已退出的运行中实例标记为失败
+func (s *GameInstanceService) MarkContainerExited(ctx context.Context, id string) error {
+	return s.failRunning(ctx, id, "游戏容器已退出")
+}
+
+// failRunning 将运行中的实例标记为失败，实例已不在运行中时不处理
+func (s *GameInstanceService) failRunning(ctx context.Context, id string, message string) error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
 
-	// 获取实例
 	instance, err := s.GameInstanceStore.Get(ctx, id)
 	if err != nil {
-		if strings.Contains(err.Error(), "目标是一个目录") {
-			s.logger.Error("存储层错误: 目标是一个目录")
-			return fmt.Errorf("存储层错误")
-		}
-		if strings.Contains(err.Error(), "实例不存在") {
-			s.logger.Error("实例不存在: %s", id)
-			return fmt.Errorf("实例不存在: %s", id)
-		}
-		s.logger.Error("获取实例失败: %v", err)
-		return err
-	}
-
-	if instance.ID == "" {
-		s.logger.Error("实例不存在: %s", id)
-		return fmt.Errorf("实例不存在: %s", id)
+		return fmt.Errorf("获取实例失败: %w", err)
 	}
-
-	// 检查实例状态
-	if instance.Status != "running" {
-		s.logger.Error("实例未在运行中: %s", id)
-		return ErrInstanceNotRunning
+	if instance.Status != models.GameInstanceStateRunning {
+		return nil
 	}
 
-	// 更新实例状态
-	instance.Status = "stopped"
-	instance.UpdatedAt = time.Now()
-
-	// 保存更新
-	err = s.GameInstanceStore.Update(ctx, instance)
-	if err != nil {
-		if strings.Contains(err.Error(), "目标是一个目录") {
-			s.logger.Error("存储层错误: 目标是一个目录")
-			return fmt.Errorf("存储层错误")
-		}
-		s.logger.Error("更新实例状态失败: %v", err)
+	if err := s.transition(ctx, &instance, models.GameInstanceStateFailed, message); err != nil {
 		return err
 	}
-
-	s.logger.Info("成功停止游戏实例: %s", id)
+	s.logger.Warn("实例 %s %s，标记为失败", id, message)
 	return nil
 }
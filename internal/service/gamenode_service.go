@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/open-beagle/beagle-wind-game/internal/models"
@@ -11,10 +14,62 @@ import (
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
+// 节点准入错误
+var (
+	// ErrNodePendingApproval 节点等待管理员审批
+	ErrNodePendingApproval = errors.New("节点等待管理员审批")
+	// ErrNodeRejected 节点已被管理员拒绝
+	ErrNodeRejected = errors.New("节点已被拒绝")
+	// ErrNodeNotApproved 节点尚未获批，不能调用除注册外的接口
+	ErrNodeNotApproved = errors.New("节点未获批准")
+	// ErrInvalidCredential 节点凭证缺失或不匹配
+	ErrInvalidCredential = errors.New("无效的节点凭证")
+)
+
+// 节点维护状态错误
+var (
+	// ErrInvalidNodeState 无效的节点维护状态
+	ErrInvalidNodeState = errors.New("无效的节点维护状态")
+	// ErrNodeStateTransition 不允许的维护状态转换
+	ErrNodeStateTransition = errors.New("不允许的维护状态转换")
+)
+
+// NodeStateChangeHandler 节点维护状态变更后执行的处理函数
+type NodeStateChangeHandler func(ctx context.Context, nodeID string, from, to models.GameNodeStaticState)
+
+// NodeContainersHandler 节点容器清单更新后执行的处理函数
+type NodeContainersHandler func(ctx context.Context, nodeID string, containers []models.ContainerInfo)
+
 // GameNodeService 游戏节点服务
 type GameNodeService struct {
-	store  store.GameNodeStore
-	logger utils.Logger
+	store      store.GameNodeStore
+	joinTokens *JoinTokenService
+	logger     utils.Logger
+	// metricsMu 保证指标序号的检查和更新是原子的
+	metricsMu sync.Mutex
+	// connections 节点连接信息，由 SetConnectionProvider 注入
+	connections NodeConnectionProvider
+
+	// stateMu 保护 stateHandlers 和 nodeStateMu
+	stateMu       sync.Mutex
+	stateHandlers []NodeStateChangeHandler
+	// nodeStateMu 节点维护状态锁，保证同一节点维护状态的检查、更新和通知按顺序执行，不同节点互不阻塞
+	nodeStateMu map[string]*sync.Mutex
+
+	// containersMu 保护 containersHandlers
+	containersMu       sync.Mutex
+	containersHandlers []NodeContainersHandler
+
+	// access 访问令牌服务，由 SetAccessService 注入
+	access *AccessService
+}
+
+// NodeAddressLabel 指定节点访问地址的标签，未设置时使用节点连接的来源地址
+const NodeAddressLabel = "address"
+
+// NodeConnectionProvider 提供节点的连接信息，由 gRPC 服务端实现
+type NodeConnectionProvider interface {
+	NodeConnection(nodeID string) (models.NodeConnection, bool)
 }
 
 // NewGameNodeService 创建游戏节点服务
@@ -26,6 +81,29 @@ func NewGameNodeService(store store.GameNodeStore) *GameNodeService {
 	}
 }
 
+// SetJoinTokenService 设置加入令牌服务，未设置时节点只能由管理员审批加入
+func (s *GameNodeService) SetJoinTokenService(joinTokens *JoinTokenService) {
+	s.joinTokens = joinTokens
+}
+
+// SetConnectionProvider 设置节点连接信息来源
+func (s *GameNodeService) SetConnectionProvider(connections NodeConnectionProvider) {
+	s.connections = connections
+}
+
+// GetConnection 获取节点的连接信息，节点自服务端启动后未连接时返回离线状态
+func (s *GameNodeService) GetConnection(ctx context.Context, id string) (models.NodeConnection, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return models.NodeConnection{}, err
+	}
+	if s.connections != nil {
+		if conn, ok := s.connections.NodeConnection(id); ok {
+			return conn, nil
+		}
+	}
+	return models.NodeConnection{NodeID: id}, nil
+}
+
 // GameNodeListParams 节点列表查询参数
 type GameNodeListParams struct {
 	Page      int    `form:"page" binding:"omitempty,min=1"`
@@ -34,6 +112,7 @@ type GameNodeListParams struct {
 	Status    string `form:"status" binding:"omitempty,oneof=online offline maintenance"`
 	Type      string `form:"type" binding:"omitempty,oneof=physical virtual"`
 	Region    string `form:"region" binding:"omitempty"`
+	Approval  string `form:"approval" binding:"omitempty,oneof=pending approved rejected"`
 	SortBy    string `form:"sort_by" binding:"omitempty,oneof=created_at updated_at status"`
 	SortOrder string `form:"sort_order" binding:"omitempty,oneof=asc desc"`
 }
@@ -47,6 +126,7 @@ type GameNodeListResult struct {
 // NodeAccessResult 节点访问链接结果
 type NodeAccessResult struct {
 	Link      string    `json:"link"`
+	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expiresAt"`
 }
 
@@ -76,6 +156,11 @@ func (s *GameNodeService) List(ctx context.Context, params GameNodeListParams) (
 			continue
 		}
 
+		// 审批状态过滤，未设置审批状态的节点视为等待审批
+		if params.Approval != "" && string(effectiveApproval(node.Approval)) != params.Approval {
+			continue
+		}
+
 		// 如果满足所有条件，添加到结果中
 		filteredNodes = append(filteredNodes, node)
 	}
@@ -112,6 +197,16 @@ func (s *GameNodeService) List(ctx context.Context, params GameNodeListParams) (
 	}, nil
 }
 
+// ListAll 获取全部游戏节点，不分页
+func (s *GameNodeService) ListAll(ctx context.Context) ([]models.GameNode, error) {
+	nodes, err := s.store.List(ctx)
+	if err != nil {
+		s.logger.Error("获取节点列表失败: %v", err)
+		return nil, fmt.Errorf("存储层错误: %w", err)
+	}
+	return nodes, nil
+}
+
 // Get 获取节点信息
 func (s *GameNodeService) Get(ctx context.Context, id string) (models.GameNode, error) {
 	s.logger.Debug("获取游戏节点信息: %s", id)
@@ -188,8 +283,9 @@ func (s *GameNodeService) Update(ctx context.Context, node models.GameNode) erro
 		return fmt.Errorf("节点不存在: %s", node.ID)
 	}
 
-	// 保留创建时间
+	// 保留创建时间，维护状态只能通过 UpdateState 变更
 	node.CreatedAt = existingNode.CreatedAt
+	node.State = existingNode.State
 	// 更新更新时间
 	node.UpdatedAt = time.Now()
 
@@ -203,6 +299,70 @@ func (s *GameNodeService) Update(ctx context.Context, node models.GameNode) erro
 	return nil
 }
 
+// OnStateChange 注册维护状态变更后执行的处理函数，按注册顺序在节点的状态锁内执行，处理函数中不能调用 UpdateState
+func (s *GameNodeService) OnStateChange(handler NodeStateChangeHandler) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.stateHandlers = append(s.stateHandlers, handler)
+}
+
+// UpdateState 变更节点维护状态
+// 禁用状态不允许直接转换为维护状态，状态未变化时不执行处理函数
+// 处理函数在节点的状态锁内执行，保证同一节点连续的状态变更按发生顺序通知，处理函数不能再变更节点维护状态
+// 处理函数中的网络请求只阻塞同一节点的状态变更，不影响其他节点
+func (s *GameNodeService) UpdateState(ctx context.Context, id string, state models.GameNodeStaticState) (models.GameNode, error) {
+	s.logger.Debug("变更节点维护状态: id=%s, state=%s", id, state)
+	if !state.IsValid() {
+		return models.GameNode{}, fmt.Errorf("%w: %s", ErrInvalidNodeState, state)
+	}
+
+	mu := s.nodeStateLock(id)
+	mu.Lock()
+	defer mu.Unlock()
+	node, err := s.Get(ctx, id)
+	if err != nil {
+		return models.GameNode{}, err
+	}
+	from := node.State.Effective()
+	if from == state {
+		return node, nil
+	}
+	if !from.CanTransitionTo(state) {
+		return models.GameNode{}, fmt.Errorf("%w: %s -> %s", ErrNodeStateTransition, from, state)
+	}
+
+	node.State = state
+	node.UpdatedAt = time.Now()
+	if err := s.store.Update(ctx, node); err != nil {
+		s.logger.Error("更新节点维护状态失败: %v", err)
+		return models.GameNode{}, fmt.Errorf("存储层错误: %w", err)
+	}
+
+	s.logger.Info("节点 %s 维护状态变更: %s -> %s", id, from, state)
+	s.stateMu.Lock()
+	handlers := append([]NodeStateChangeHandler(nil), s.stateHandlers...)
+	s.stateMu.Unlock()
+	for _, handler := range handlers {
+		handler(ctx, id, from, state)
+	}
+	return node, nil
+}
+
+// nodeStateLock 获取节点的维护状态锁，不存在时创建
+func (s *GameNodeService) nodeStateLock(id string) *sync.Mutex {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.nodeStateMu == nil {
+		s.nodeStateMu = make(map[string]*sync.Mutex)
+	}
+	mu, ok := s.nodeStateMu[id]
+	if !ok {
+		mu = &sync.Mutex{}
+		s.nodeStateMu[id] = mu
+	}
+	return mu
+}
+
 // Delete 删除游戏节点
 func (s *GameNodeService) Delete(ctx context.Context, id string, force bool) error {
 	s.logger.Debug("删除游戏节点: %s, force=%v", id, force)
@@ -288,6 +448,80 @@ func (s *GameNodeService) UpdateStatusMetrics(ctx context.Context, id string, me
 	return nil
 }
 
+// ApplyMetricsReport 应用节点上报的指标，返回是否已应用
+// 序号不大于已应用序号的上报被忽略，Agent 重连后重放积压的指标时不会覆盖更新的指标
+func (s *GameNodeService) ApplyMetricsReport(ctx context.Context, id string, sequence int64, metrics models.MetricsInfo) (bool, error) {
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+
+	node, err := s.store.Get(ctx, id)
+	if err != nil {
+		s.logger.Error("获取节点信息失败: %v", err)
+		return false, fmt.Errorf("存储层错误: %w", err)
+	}
+	if node.ID == "" {
+		s.logger.Error("节点不存在: %s", id)
+		return false, fmt.Errorf("节点不存在: %s", id)
+	}
+	if sequence > 0 && sequence <= node.Status.MetricsSequence {
+		s.logger.Debug("忽略过期的节点指标上报: %s, 序号: %d, 已应用序号: %d", id, sequence, node.Status.MetricsSequence)
+		return false, nil
+	}
+
+	node.Status.Metrics = metrics
+	node.Status.UpdatedAt = time.Now()
+	if sequence > 0 {
+		node.Status.MetricsSequence = sequence
+	}
+	if err := s.store.Update(ctx, node); err != nil {
+		s.logger.Error("更新节点指标失败: %v", err)
+		return false, fmt.Errorf("存储层错误: %w", err)
+	}
+	return true, nil
+}
+
+// UpdateStatusContainers 更新节点容器清单
+func (s *GameNodeService) UpdateStatusContainers(ctx context.Context, id string, containers []models.ContainerInfo) error {
+	s.logger.Debug("更新游戏节点容器清单: %s", id)
+
+	node, err := s.store.Get(ctx, id)
+	if err != nil {
+		s.logger.Error("获取节点信息失败: %v", err)
+		return fmt.Errorf("存储层错误: %w", err)
+	}
+	if node.ID == "" {
+		s.logger.Error("节点不存在: %s", id)
+		return fmt.Errorf("节点不存在: %s", id)
+	}
+
+	node.Status.Containers = containers
+	node.Status.UpdatedAt = time.Now()
+	node.Status.ContainersUpdatedAt = node.Status.UpdatedAt
+
+	err = s.store.Update(ctx, node)
+	if err != nil {
+		s.logger.Error("更新节点容器清单失败: %v", err)
+		return fmt.Errorf("存储层错误: %w", err)
+	}
+
+	s.logger.Debug("成功更新游戏节点容器清单: %s, 容器数: %d", id, len(containers))
+
+	s.containersMu.Lock()
+	handlers := append([]NodeContainersHandler{}, s.containersHandlers...)
+	s.containersMu.Unlock()
+	for _, handler := range handlers {
+		handler(ctx, id, containers)
+	}
+	return nil
+}
+
+// OnContainersUpdate 注册容器清单更新后执行的处理函数，按注册顺序在上报请求中同步执行
+func (s *GameNodeService) OnContainersUpdate(handler NodeContainersHandler) {
+	s.containersMu.Lock()
+	defer s.containersMu.Unlock()
+	s.containersHandlers = append(s.containersHandlers, handler)
+}
+
 // UpdateHardwareAndSystem 更新节点硬件和系统信息
 func (s *GameNodeService) UpdateHardwareAndSystem(ctx context.Context, id string, hardware models.HardwareInfo, system models.SystemInfo) error {
 	s.logger.Debug("更新游戏节点硬件和系统信息: %s", id)
@@ -350,50 +584,194 @@ func (s *GameNodeService) UpdateStatusOnlineStatus(ctx context.Context, id strin
 	return nil
 }
 
-// GetAccess 获取节点访问链接
-func (s *GameNodeService) GetAccess(ctx context.Context, id string) (NodeAccessResult, error) {
-	s.logger.Debug("获取游戏节点访问链接: %s", id)
+// SetAccessService 设置访问令牌服务，未设置时不能获取节点访问链接
+func (s *GameNodeService) SetAccessService(access *AccessService) {
+	s.access = access
+}
 
-	node, err := s.store.Get(ctx, id)
+// Address 获取服务端和用户访问节点使用的地址
+// 依次使用节点的 address 标签、节点连接的来源地址和节点上报的第一个网卡地址
+func (s *GameNodeService) Address(ctx context.Context, id string) (string, error) {
+	node, err := s.Get(ctx, id)
 	if err != nil {
-		s.logger.Error("获取节点信息失败: %v", err)
-		return NodeAccessResult{}, fmt.Errorf("存储层错误: %w", err)
+		return "", err
 	}
-	if node.ID == "" {
-		s.logger.Error("节点不存在: %s", id)
-		return NodeAccessResult{}, fmt.Errorf("节点不存在: %s", id)
+	if address := node.Labels[NodeAddressLabel]; address != "" {
+		return address, nil
+	}
+	if s.connections != nil {
+		if conn, ok := s.connections.NodeConnection(id); ok && conn.RemoteAddr != "" {
+			if host, _, err := net.SplitHostPort(conn.RemoteAddr); err == nil && host != "" {
+				return host, nil
+			}
+		}
+	}
+	for _, network := range node.Status.Hardware.Networks {
+		if network.IpAddress != "" {
+			return network.IpAddress, nil
+		}
 	}
+	return "", fmt.Errorf("%w: 无法确定节点 %s 的地址", ErrAccessUnavailable, id)
+}
 
-	// TODO: 实现访问链接生成逻辑
-	result := NodeAccessResult{
-		Link:      fmt.Sprintf("https://node-%s.example.com", id),
-		ExpiresAt: time.Now().Add(24 * time.Hour),
+// GetAccess 获取用户的节点访问链接
+func (s *GameNodeService) GetAccess(ctx context.Context, id string, userID string) (NodeAccessResult, error) {
+	s.logger.Debug("获取游戏节点访问链接: %s, 用户: %s", id, userID)
+	if s.access == nil {
+		return NodeAccessResult{}, ErrAccessDisabled
 	}
 
-	s.logger.Debug("成功获取游戏节点访问链接: id=%s, link=%s, expires=%v", id, result.Link, result.ExpiresAt)
+	access, err := s.access.IssueNode(ctx, id, userID)
+	if err != nil {
+		s.logger.Error("签发节点访问链接失败: %v", err)
+		return NodeAccessResult{}, err
+	}
+
+	result := NodeAccessResult{Link: access.Link, Token: access.Token, ExpiresAt: access.ExpiresAt}
+	s.logger.Debug("成功获取游戏节点访问链接: id=%s, expires=%v", id, result.ExpiresAt)
 	return result, nil
 }
 
-// RefreshAccess 刷新节点访问链接
-func (s *GameNodeService) RefreshAccess(ctx context.Context, id string) (NodeAccessResult, error) {
-	s.logger.Debug("刷新游戏节点访问链接: %s", id)
+// RefreshAccess 刷新用户的节点访问链接，之前获取的链接失效
+func (s *GameNodeService) RefreshAccess(ctx context.Context, id string, userID string) (NodeAccessResult, error) {
+	s.logger.Debug("刷新游戏节点访问链接: %s, 用户: %s", id, userID)
+	if s.access == nil {
+		return NodeAccessResult{}, ErrAccessDisabled
+	}
+
+	access, err := s.access.RefreshNode(ctx, id, userID)
+	if err != nil {
+		s.logger.Error("刷新节点访问链接失败: %v", err)
+		return NodeAccessResult{}, err
+	}
+
+	result := NodeAccessResult{Link: access.Link, Token: access.Token, ExpiresAt: access.ExpiresAt}
+	s.logger.Debug("成功刷新游戏节点访问链接: id=%s, expires=%v", id, result.ExpiresAt)
+	return result, nil
+}
+
+// effectiveApproval 未设置审批状态的节点视为等待审批
+func effectiveApproval(approval models.NodeApproval) models.NodeApproval {
+	if approval == "" {
+		return models.NodeApprovalPending
+	}
+	return approval
+}
+
+// Admit 节点注册时的准入检查，会修改 node 的审批状态和凭证摘要，由调用方负责保存
+// 准入顺序：
+//  1. 已拒绝的节点直接拒绝
+//  2. 携带凭证且与节点凭证一致时通过
+//  3. 尚未持有凭证的节点携带有效的加入令牌时批准节点并签发新凭证
+//  4. 管理员已批准但尚未签发凭证的节点签发新凭证
+//  5. 其余节点进入等待审批状态
+//
+// 签发新凭证时返回凭证明文，否则返回空字符串
+func (s *GameNodeService) Admit(ctx context.Context, node *models.GameNode, joinToken, credential string) (string, error) {
+	approval := effectiveApproval(node.Approval)
+	if approval == models.NodeApprovalRejected {
+		return "", ErrNodeRejected
+	}
+
+	if credential != "" {
+		if approval == models.NodeApprovalApproved && secretMatches(node.CredentialHash, credential) {
+			return "", nil
+		}
+		// 凭证无效时，尚未持有凭证的节点（如凭证已被重置）允许使用加入令牌重新加入
+		if joinToken == "" {
+			s.logger.Warn("节点 %s 的凭证无效", node.ID)
+			return "", ErrInvalidCredential
+		}
+	}
+
+	if joinToken != "" {
+		if s.joinTokens == nil {
+			return "", ErrInvalidJoinToken
+		}
+		// 加入令牌可能多次使用并由多个节点共用，不能用来替换已签发的凭证，否则持有令牌即可冒用在线节点；
+		// 节点凭证丢失时由管理员拒绝后重新批准节点以重置凭证
+		if approval == models.NodeApprovalApproved && node.CredentialHash != "" {
+			s.logger.Warn("节点 %s 已持有凭证，拒绝使用加入令牌重新签发凭证", node.ID)
+			return "", ErrInvalidCredential
+		}
+		if err := s.joinTokens.Consume(ctx, joinToken, node.ID); err != nil {
+			return "", err
+		}
+		return s.issueCredential(node)
+	}
+
+	if approval == models.NodeApprovalApproved {
+		if node.CredentialHash != "" {
+			// 节点已持有凭证，未携带凭证的注册请求可能来自冒用节点ID的进程
+			s.logger.Warn("节点 %s 已签发凭证，但注册请求未携带凭证", node.ID)
+			return "", ErrInvalidCredential
+		}
+		return s.issueCredential(node)
+	}
+
+	node.Approval = models.NodeApprovalPending
+	return "", ErrNodePendingApproval
+}
+
+// issueCredential 批准节点并签发新凭证
+func (s *GameNodeService) issueCredential(node *models.GameNode) (string, error) {
+	credential, err := generateSecret(32)
+	if err != nil {
+		return "", err
+	}
+	node.Approval = models.NodeApprovalApproved
+	node.CredentialHash = hashSecret(credential)
+	s.logger.Info("为节点 %s 签发凭证", node.ID)
+	return credential, nil
+}
+
+// VerifyCredential 校验节点凭证，节点必须已获批准
+func (s *GameNodeService) VerifyCredential(ctx context.Context, id string, credential string) error {
+	node, err := s.store.Get(ctx, id)
+	if err != nil || node.ID == "" {
+		return ErrInvalidCredential
+	}
+	if effectiveApproval(node.Approval) != models.NodeApprovalApproved {
+		return ErrNodeNotApproved
+	}
+	if !secretMatches(node.CredentialHash, credential) {
+		return ErrInvalidCredential
+	}
+	return nil
+}
+
+// Approve 批准节点加入，节点在下一次注册时获得凭证
+func (s *GameNodeService) Approve(ctx context.Context, id string) error {
+	return s.setApproval(ctx, id, models.NodeApprovalApproved)
+}
+
+// Reject 拒绝节点加入，并作废节点已有的凭证
+func (s *GameNodeService) Reject(ctx context.Context, id string) error {
+	return s.setApproval(ctx, id, models.NodeApprovalRejected)
+}
 
+// setApproval 更新节点审批状态
+func (s *GameNodeService) setApproval(ctx context.Context, id string, approval models.NodeApproval) error {
 	node, err := s.store.Get(ctx, id)
 	if err != nil {
 		s.logger.Error("获取节点信息失败: %v", err)
-		return NodeAccessResult{}, fmt.Errorf("存储层错误: %w", err)
+		return fmt.Errorf("节点不存在: %s", id)
 	}
-	if node.ID == "" {
-		s.logger.Error("节点不存在: %s", id)
-		return NodeAccessResult{}, fmt.Errorf("节点不存在: %s", id)
+
+	if node.Approval == approval {
+		return nil
+	}
+	node.Approval = approval
+	if approval == models.NodeApprovalRejected {
+		node.CredentialHash = ""
 	}
+	node.UpdatedAt = time.Now()
 
-	// TODO: 实现访问链接刷新逻辑
-	result := NodeAccessResult{
-		Link:      fmt.Sprintf("https://node-%s.example.com", id),
-		ExpiresAt: time.Now().Add(24 * time.Hour),
+	if err := s.store.Update(ctx, node); err != nil {
+		s.logger.Error("更新节点审批状态失败: %v", err)
+		return fmt.Errorf("存储层错误: %w", err)
 	}
 
-	s.logger.Debug("成功刷新游戏节点访问链接: id=%s, link=%s, expires=%v", id, result.Link, result.ExpiresAt)
-	return result, nil
+	s.logger.Info("节点 %s 审批状态更新为 %s", id, approval)
+	return nil
 }
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+)
+
+func TestGameNodeUpdateState(t *testing.T) {
+	ctx := context.Background()
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(t.TempDir(), "nodes.yaml"))
+	require.NoError(t, err)
+	svc := NewGameNodeService(nodeStore)
+	require.NoError(t, svc.Create(ctx, models.GameNode{ID: "node-1"}))
+
+	var changes []models.GameNodeStaticState
+	svc.OnStateChange(func(ctx context.Context, nodeID string, from, to models.GameNodeStaticState) {
+		changes = append(changes, to)
+	})
+
+	_, err = svc.UpdateState(ctx, "node-1", models.GameNodeStaticStateMaintenance)
+	require.NoError(t, err)
+	node, err := svc.UpdateState(ctx, "node-1", models.GameNodeStaticStateDisabled)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameNodeStaticStateDisabled, node.State)
+
+	// 禁用状态不允许直接转换为维护状态
+	_, err = svc.UpdateState(ctx, "node-1", models.GameNodeStaticStateMaintenance)
+	assert.ErrorIs(t, err, ErrNodeStateTransition)
+	_, err = svc.UpdateState(ctx, "node-1", "unknown")
+	assert.ErrorIs(t, err, ErrInvalidNodeState)
+
+	// 状态未变化时不执行处理函数
+	_, err = svc.UpdateState(ctx, "node-1", models.GameNodeStaticStateDisabled)
+	require.NoError(t, err)
+	_, err = svc.UpdateState(ctx, "node-1", models.GameNodeStaticStateNormal)
+	require.NoError(t, err)
+	assert.Equal(t, []models.GameNodeStaticState{
+		models.GameNodeStaticStateMaintenance,
+		models.GameNodeStaticStateDisabled,
+		models.GameNodeStaticStateNormal,
+	}, changes)
+
+	// 更新节点信息不会修改维护状态
+	node.Alias = "renamed"
+	require.NoError(t, svc.Update(ctx, node))
+	node, err = svc.Get(ctx, "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", node.Alias)
+	assert.Equal(t, models.GameNodeStaticStateNormal, node.State)
+}
+
+func TestGameNodeUpdateStateOrder(t *testing.T) {
+	ctx := context.Background()
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(t.TempDir(), "nodes.yaml"))
+	require.NoError(t, err)
+	svc := NewGameNodeService(nodeStore)
+	require.NoError(t, svc.Create(ctx, models.GameNode{ID: "node-1"}))
+
+	var changes [][2]models.GameNodeStaticState
+	svc.OnStateChange(func(ctx context.Context, nodeID string, from, to models.GameNodeStaticState) {
+		changes = append(changes, [2]models.GameNodeStaticState{from, to})
+	})
+
+	// 并发变更状态时，处理函数按变更发生的顺序执行
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		state := models.GameNodeStaticStateMaintenance
+		if i%2 == 1 {
+			state = models.GameNodeStaticStateNormal
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.UpdateState(ctx, "node-1", state)
+		}()
+	}
+	wg.Wait()
+
+	current := models.GameNodeStaticStateNormal
+	for _, change := range changes {
+		assert.Equal(t, current, change[0])
+		current = change[1]
+	}
+	node, err := svc.Get(ctx, "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, current, node.State.Effective())
+
+	// 处理函数阻塞时只阻塞同一节点的状态变更，其他节点不受影响
+	require.NoError(t, svc.Create(ctx, models.GameNode{ID: "node-2"}))
+	started, release := make(chan struct{}), make(chan struct{})
+	svc.OnStateChange(func(ctx context.Context, nodeID string, from, to models.GameNodeStaticState) {
+		if nodeID == "node-1" {
+			close(started)
+			<-release
+		}
+	})
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		_, _ = svc.UpdateState(ctx, "node-1", models.GameNodeStaticStateDisabled)
+	}()
+	<-started
+	node, err = svc.UpdateState(ctx, "node-2", models.GameNodeStaticStateMaintenance)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameNodeStaticStateMaintenance, node.State)
+	close(release)
+	<-blocked
+}
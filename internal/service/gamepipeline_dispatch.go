@@ -0,0 +1,481 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	pl "github.com/open-beagle/beagle-wind-game/internal/pipeline"
+)
+
+// 流水线下发相关错误
+var (
+	ErrNodeNotConnected  = errors.New("节点未连接")
+	ErrNoMatchingNode    = errors.New("没有符合条件的节点")
+	ErrDispatchDisabled  = errors.New("流水线下发未启用")
+	ErrInvalidDispatchTo = errors.New("必须指定 node_id 或 selector")
+	ErrNodeUnavailable   = errors.New("节点处于维护或禁用状态，不接收新的流水线")
+	ErrSecretsLost       = errors.New("流水线的敏感参数已丢失，需重新提交")
+)
+
+// secretArgSuffixes 敏感参数名称的后缀，例如 TURN_PASSWORD、SAVE_S3_SECRET_KEY
+var secretArgSuffixes = []string{"PASSWORD", "SECRET", "SECRET_KEY", "TOKEN"}
+
+// PipelineDispatcher 流水线下发接口，由 gRPC Pipeline 服务端实现
+type PipelineDispatcher interface {
+	// IsConnected 节点是否已建立 PipelineStream 会话
+	IsConnected(nodeID string) bool
+	// Dispatch 通过节点会话下发流水线，节点未连接时返回 ErrNodeNotConnected
+	Dispatch(ctx context.Context, nodeID string, pipeline *models.GamePipeline) error
+	// Cancel 通过节点会话通知节点取消流水线
+	Cancel(ctx context.Context, nodeID string, pipelineID string, reason string) error
+}
+
+// SubmitPipelineParams 提交流水线参数
+type SubmitPipelineParams struct {
+	Template   string            `json:"template" binding:"required"` // 模板名称，对应模板目录下的 <template>.yaml
+	Args       map[string]string `json:"args"`                        // 模板参数
+	NodeID     string            `json:"node_id"`                     // 目标节点
+	Selector   map[string]string `json:"selector"`                    // 节点标签选择器，未指定 node_id 时使用
+	InstanceID string            `json:"instance_id"`                 // 所属游戏实例
+	Queue      bool              `json:"queue"`                       // 节点未连接时是否排队等待
+}
+
+// SetDispatcher 设置流水线下发器
+func (s *GamePipelineService) SetDispatcher(dispatcher PipelineDispatcher) {
+	s.dispatcher = dispatcher
+}
+
+// SetTemplateSource 设置流水线模板目录及渲染使用的环境变量
+func (s *GamePipelineService) SetTemplateSource(dir string, envs map[string]string) {
+	s.templateDir = dir
+	s.envs = envs
+}
+
+// Env 获取渲染模板时使用的环境变量，未配置时返回空字符串
+func (s *GamePipelineService) Env(name string) string {
+	return s.envs[name]
+}
+
+// SetNodeService 设置节点服务，用于校验目标节点和按选择器选择节点
+func (s *GamePipelineService) SetNodeService(nodes *GameNodeService) {
+	s.nodes = nodes
+}
+
+// Submit 从模板创建流水线并下发到节点
+func (s *GamePipelineService) Submit(ctx context.Context, params SubmitPipelineParams) (*models.GamePipeline, error) {
+	s.logger.Debug("提交流水线: 模板: %s, 节点: %s", params.Template, params.NodeID)
+	if s.dispatcher == nil {
+		return nil, ErrDispatchDisabled
+	}
+
+	// 1. 渲染模板
+	data, err := pl.LoadTemplate(s.templateDir, params.Template)
+	if err != nil {
+		return nil, err
+	}
+	// 敏感参数渲染为引用，参数值只在下发时替换，不写入数据文件和 API 响应
+	args, secrets := splitSecretArgs(params.Args)
+	renderArgs := make(map[string]string, len(params.Args))
+	for name, value := range args {
+		renderArgs[name] = value
+	}
+	secretArgs := make([]string, 0, len(secrets))
+	for name := range secrets {
+		renderArgs[name] = pl.SecretRef(name)
+		secretArgs = append(secretArgs, name)
+	}
+	sort.Strings(secretArgs)
+	pipeline, err := pl.RenderTemplate(data, renderArgs, s.envs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 选择节点
+	nodeID, err := s.selectNode(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	connected := s.dispatcher.IsConnected(nodeID)
+	if !connected && !params.Queue {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotConnected, nodeID)
+	}
+
+	// 3. 创建流水线
+	now := time.Now()
+	pipeline.ID = fmt.Sprintf("%s-%s", params.Template, strconv.FormatInt(now.UnixNano(), 36))
+	pipeline.InstanceID = params.InstanceID
+	pipeline.Status = &models.PipelineStatus{
+		NodeID:     nodeID,
+		State:      models.PipelineStatePending,
+		TotalSteps: int32(len(pipeline.Steps)),
+		Steps:      make([]models.StepStatus, len(pipeline.Steps)),
+		UpdatedAt:  &now,
+	}
+	pipeline.Dispatch = &models.PipelineDispatch{
+		State:      models.PipelineDispatchQueued,
+		Template:   params.Template,
+		Args:       args,
+		SecretArgs: secretArgs,
+		Secrets:    secrets,
+		QueuedAt:   &now,
+	}
+	if err := s.Create(ctx, pipeline); err != nil {
+		return nil, err
+	}
+
+	// 4. 下发，节点未连接时保持排队，待节点建立会话后由 DispatchQueued 下发
+	if !connected {
+		s.logger.Info("节点 %s 未连接，流水线 %s 已进入队列", nodeID, pipeline.ID)
+		return pipeline, nil
+	}
+	if err := s.dispatch(ctx, pipeline); err != nil {
+		if params.Queue && errors.Is(err, ErrNodeNotConnected) {
+			return pipeline, nil
+		}
+		s.failDispatch(ctx, pipeline.ID, err)
+		return nil, err
+	}
+
+	return pipeline, nil
+}
+
+// DispatchQueued 下发节点上排队或尚未确认的流水线，在节点建立会话后调用
+// 节点处于维护或禁用状态时排队的流水线保持排队，恢复正常状态后再下发
+func (s *GamePipelineService) DispatchQueued(ctx context.Context, nodeID string) error {
+	if s.nodes != nil {
+		node, err := s.nodes.Get(ctx, nodeID)
+		if err != nil {
+			return err
+		}
+		if !node.State.AcceptsPipelines() {
+			s.logger.Info("节点 %s 处于 %s 状态，暂不下发排队的流水线", nodeID, node.State)
+			return nil
+		}
+	}
+
+	pipelines, err := s.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("获取流水线列表失败: %w", err)
+	}
+
+	pending := make([]*models.GamePipeline, 0)
+	for _, p := range pipelines {
+		if p.Status == nil || p.Dispatch == nil || p.Status.NodeID != nodeID || p.Status.State.IsTerminal() {
+			continue
+		}
+		if p.Dispatch.State == models.PipelineDispatchQueued || p.Dispatch.State == models.PipelineDispatchSent {
+			pending = append(pending, p)
+		}
+	}
+
+	// 按进入队列的先后顺序下发
+	sort.Slice(pending, func(i, j int) bool {
+		return queuedAt(pending[i]).Before(queuedAt(pending[j]))
+	})
+
+	for _, p := range pending {
+		if err := s.dispatch(ctx, p); err != nil {
+			// 服务端重启后敏感参数已丢失的流水线无法下发，直接失败，不影响后续流水线
+			if errors.Is(err, ErrSecretsLost) {
+				s.failDispatch(ctx, p.ID, err)
+				continue
+			}
+			s.logger.Warn("下发排队流水线 %s 到节点 %s 失败: %v", p.ID, nodeID, err)
+			return err
+		}
+		s.logger.Info("已下发排队流水线 %s 到节点 %s", p.ID, nodeID)
+	}
+	return nil
+}
+
+// AckDispatch 记录节点对下发流水线的确认，节点拒绝时流水线直接失败
+func (s *GamePipelineService) AckDispatch(ctx context.Context, id string, nodeID string, accepted bool, message string) error {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+
+	pipeline, err := s.getOwnedPipeline(ctx, id, nodeID)
+	if err != nil {
+		return err
+	}
+	if pipeline.Dispatch == nil {
+		pipeline.Dispatch = &models.PipelineDispatch{}
+	}
+
+	from := pipelineState(pipeline)
+	now := time.Now()
+	pipeline.Dispatch.AckedAt = &now
+	pipeline.Dispatch.Message = message
+	// 节点确认后不会再重新下发，敏感参数不再需要保留
+	pipeline.Dispatch.Secrets = nil
+	if accepted {
+		pipeline.Dispatch.State = models.PipelineDispatchAcked
+	} else {
+		pipeline.Dispatch.State = models.PipelineDispatchRejected
+		if !pipeline.Status.State.IsTerminal() {
+			pipeline.Status.State = models.PipelineStateFailed
+			pipeline.Status.ErrorMessage = fmt.Sprintf("节点拒绝执行: %s", message)
+			pipeline.Status.EndTime = &now
+			pipeline.Status.UpdatedAt = &now
+		}
+	}
+
+	if err := s.store.Update(ctx, pipeline); err != nil {
+		return fmt.Errorf("更新流水线下发状态失败: %w", err)
+	}
+	s.logger.Info("节点 %s 确认流水线 %s: accepted=%v %s", nodeID, id, accepted, message)
+	s.notifyStateChange(ctx, pipeline, from)
+	return nil
+}
+
+// MarkNodeLost 将节点上已下发且未结束的流水线标记为失联，返回标记的数量
+// 失联不是终态，节点恢复后重新上报的状态会覆盖失联状态
+func (s *GamePipelineService) MarkNodeLost(ctx context.Context, nodeID string) (int, error) {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+
+	pipelines, err := s.store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取流水线列表失败: %w", err)
+	}
+
+	now := time.Now()
+	count := 0
+	for _, pipeline := range pipelines {
+		if pipeline.Status == nil || pipeline.Status.NodeID != nodeID || !isDelivered(pipeline) {
+			continue
+		}
+		if pipeline.Status.State != models.PipelineStateRunning && pipeline.Status.State != models.PipelineStatePending {
+			continue
+		}
+		from := pipeline.Status.State
+		pipeline.Status.State = models.PipelineStateLost
+		pipeline.Status.ErrorMessage = "执行节点失联"
+		pipeline.Status.UpdatedAt = &now
+		if err := s.store.Update(ctx, pipeline); err != nil {
+			return count, fmt.Errorf("更新流水线状态失败: %w", err)
+		}
+		s.notifyStateChange(ctx, pipeline, from)
+		count++
+	}
+	if count > 0 {
+		s.logger.Warn("节点 %s 失联，%d 个流水线标记为失联", nodeID, count)
+	}
+	return count, nil
+}
+
+// CancelNodePipelines 取消节点上所有未结束的流水线，包括排队中的流水线，返回取消的数量
+func (s *GamePipelineService) CancelNodePipelines(ctx context.Context, nodeID string, reason string) (int, error) {
+	pipelines, err := s.store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取流水线列表失败: %w", err)
+	}
+
+	count := 0
+	for _, pipeline := range pipelines {
+		if pipeline.Status == nil || pipeline.Status.NodeID != nodeID || pipeline.Status.State.IsTerminal() {
+			continue
+		}
+		if err := s.Cancel(ctx, pipeline.ID); err != nil {
+			return count, fmt.Errorf("取消流水线 %s 失败: %w", pipeline.ID, err)
+		}
+		count++
+	}
+	if count > 0 {
+		s.logger.Warn("%s，已取消节点 %s 上的 %d 个流水线", reason, nodeID, count)
+	}
+	return count, nil
+}
+
+// isDelivered 流水线是否已发送到节点
+func isDelivered(pipeline *models.GamePipeline) bool {
+	if pipeline.Dispatch == nil {
+		return pipeline.Status.State == models.PipelineStateRunning
+	}
+	return pipeline.Dispatch.State == models.PipelineDispatchSent || pipeline.Dispatch.State == models.PipelineDispatchAcked
+}
+
+// dispatch 通过下发器发送流水线并记录下发状态
+func (s *GamePipelineService) dispatch(ctx context.Context, pipeline *models.GamePipeline) error {
+	nodeID := pipeline.Status.NodeID
+	payload, err := withSecrets(pipeline)
+	if err != nil {
+		return err
+	}
+	err = s.dispatcher.Dispatch(ctx, nodeID, payload)
+
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+
+	now := time.Now()
+	pipeline.Dispatch.Attempts++
+	if err != nil {
+		pipeline.Dispatch.Message = err.Error()
+		if errors.Is(err, ErrNodeNotConnected) {
+			pipeline.Dispatch.State = models.PipelineDispatchQueued
+		}
+	} else {
+		pipeline.Dispatch.State = models.PipelineDispatchSent
+		pipeline.Dispatch.SentAt = &now
+		pipeline.Dispatch.Message = ""
+	}
+	if updateErr := s.store.Update(ctx, pipeline); updateErr != nil {
+		s.logger.Error("更新流水线下发状态失败: %v", updateErr)
+	}
+	return err
+}
+
+// failDispatch 下发失败且不排队时将流水线标记为失败
+func (s *GamePipelineService) failDispatch(ctx context.Context, id string, cause error) {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+
+	pipeline, err := s.store.Get(ctx, id)
+	if err != nil || pipeline == nil {
+		return
+	}
+	from := pipelineState(pipeline)
+	now := time.Now()
+	pipeline.Status.State = models.PipelineStateFailed
+	pipeline.Status.ErrorMessage = fmt.Sprintf("下发失败: %v", cause)
+	pipeline.Status.EndTime = &now
+	pipeline.Status.UpdatedAt = &now
+	if err := s.store.Update(ctx, pipeline); err != nil {
+		s.logger.Error("更新流水线状态失败: %v", err)
+		return
+	}
+	s.notifyStateChange(ctx, pipeline, from)
+}
+
+// selectNode 确定流水线的目标节点
+// 指定 node_id 时直接使用；否则在标签匹配选择器的节点中优先选择已连接、运行中流水线最少的节点
+func (s *GamePipelineService) selectNode(ctx context.Context, params SubmitPipelineParams) (string, error) {
+	if params.NodeID != "" {
+		if s.nodes != nil {
+			node, err := s.nodes.Get(ctx, params.NodeID)
+			if err != nil {
+				return "", err
+			}
+			if !node.State.AcceptsPipelines() {
+				return "", fmt.Errorf("%w: %s(%s)", ErrNodeUnavailable, params.NodeID, node.State)
+			}
+		}
+		return params.NodeID, nil
+	}
+	if len(params.Selector) == 0 {
+		return "", ErrInvalidDispatchTo
+	}
+	if s.nodes == nil {
+		return "", ErrNoMatchingNode
+	}
+
+	nodes, err := s.nodes.ListAll(ctx)
+	if err != nil {
+		return "", err
+	}
+	active, err := s.activePipelineCount(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	candidates := make([]string, 0)
+	for _, node := range nodes {
+		if node.State.AcceptsPipelines() && matchLabels(node.Labels, params.Selector) {
+			candidates = append(candidates, node.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w: %v", ErrNoMatchingNode, params.Selector)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := s.dispatcher.IsConnected(candidates[i]), s.dispatcher.IsConnected(candidates[j])
+		if ci != cj {
+			return ci
+		}
+		if active[candidates[i]] != active[candidates[j]] {
+			return active[candidates[i]] < active[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	return candidates[0], nil
+}
+
+// activePipelineCount 统计每个节点上未结束的流水线数量
+func (s *GamePipelineService) activePipelineCount(ctx context.Context) (map[string]int, error) {
+	pipelines, err := s.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取流水线列表失败: %w", err)
+	}
+	counts := make(map[string]int)
+	for _, p := range pipelines {
+		if p.Status != nil && p.Status.NodeID != "" && !p.Status.State.IsTerminal() {
+			counts[p.Status.NodeID]++
+		}
+	}
+	return counts, nil
+}
+
+// matchLabels 判断标签是否满足选择器
+func matchLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// queuedAt 获取流水线进入队列的时间
+func queuedAt(p *models.GamePipeline) time.Time {
+	if p.Dispatch != nil && p.Dispatch.QueuedAt != nil {
+		return *p.Dispatch.QueuedAt
+	}
+	return time.Time{}
+}
+
+// splitSecretArgs 拆分普通参数和敏感参数，值为空的敏感参数按普通参数处理
+func splitSecretArgs(params map[string]string) (map[string]string, map[string]string) {
+	args := make(map[string]string, len(params))
+	var secrets map[string]string
+	for name, value := range params {
+		if value == "" || !isSecretArg(name) {
+			args[name] = value
+			continue
+		}
+		if secrets == nil {
+			secrets = make(map[string]string)
+		}
+		secrets[name] = value
+	}
+	return args, secrets
+}
+
+// isSecretArg 判断参数是否为敏感参数
+func isSecretArg(name string) bool {
+	for _, suffix := range secretArgSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// withSecrets 下发给节点的流水线，敏感参数的引用替换为内存中的参数值
+// 服务端重启后敏感参数已丢失，返回 ErrSecretsLost
+func withSecrets(pipeline *models.GamePipeline) (*models.GamePipeline, error) {
+	if pipeline.Dispatch == nil || len(pipeline.Dispatch.SecretArgs) == 0 {
+		return pipeline, nil
+	}
+	for _, name := range pipeline.Dispatch.SecretArgs {
+		if _, ok := pipeline.Dispatch.Secrets[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrSecretsLost, name)
+		}
+	}
+	return pl.RenderSecrets(pipeline, pipeline.Dispatch.Secrets)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+)
+
+// maxTailBytes 按行读取日志末尾时最多读取的字节数
+const maxTailBytes = 1 << 20
+
+// 步骤日志相关错误
+var (
+	ErrStepNotFound     = errors.New("流水线步骤不存在")
+	ErrLogStoreDisabled = errors.New("步骤日志存储未启用")
+)
+
+// SetLogStore 设置步骤日志存储
+func (s *GamePipelineService) SetLogStore(logs *store.StepLogStore) {
+	s.logs = logs
+}
+
+// AppendStepLog 追加节点上传的步骤日志，流水线必须属于上传节点，返回已保存的日志字节数
+func (s *GamePipelineService) AppendStepLog(ctx context.Context, nodeID, pipelineID, stepID string, offset int64, data []byte, eof bool) (int64, error) {
+	if s.logs == nil {
+		return 0, ErrLogStoreDisabled
+	}
+	pipeline, err := s.getOwnedPipeline(ctx, pipelineID, nodeID)
+	if err != nil {
+		return 0, err
+	}
+	if findStepStatus(pipeline, stepID) == nil {
+		return 0, fmt.Errorf("%w: %s", ErrStepNotFound, stepID)
+	}
+	return s.logs.Append(pipelineID, stepID, offset, data, eof)
+}
+
+// StatStepLog 获取步骤日志概况，并返回步骤是否已结束
+func (s *GamePipelineService) StatStepLog(ctx context.Context, pipelineID, stepID string) (store.StepLogInfo, bool, error) {
+	if s.logs == nil {
+		return store.StepLogInfo{}, false, ErrLogStoreDisabled
+	}
+	pipeline, err := s.store.Get(ctx, pipelineID)
+	if err != nil || pipeline == nil || pipeline.Status == nil {
+		return store.StepLogInfo{}, false, fmt.Errorf("%w: %s", ErrPipelineNotFound, pipelineID)
+	}
+	step := findStepStatus(pipeline, stepID)
+	if step == nil {
+		return store.StepLogInfo{}, false, fmt.Errorf("%w: %s", ErrStepNotFound, stepID)
+	}
+
+	info, err := s.logs.Stat(pipelineID, stepID)
+	if err != nil {
+		return store.StepLogInfo{}, false, err
+	}
+	finished := pipeline.Status.State.IsTerminal() ||
+		step.State == models.StepStateCompleted || step.State == models.StepStateFailed || step.State == models.StepStateSkipped
+	return info, finished, nil
+}
+
+// ReadStepLog 读取步骤日志 [start, end) 范围内的内容
+func (s *GamePipelineService) ReadStepLog(ctx context.Context, pipelineID, stepID string, start, end int64) ([]byte, error) {
+	if s.logs == nil {
+		return nil, ErrLogStoreDisabled
+	}
+	return s.logs.Read(pipelineID, stepID, start, end)
+}
+
+// TailStepLog 读取步骤日志最后 lines 行，返回日志及其起始偏移
+func (s *GamePipelineService) TailStepLog(ctx context.Context, pipelineID, stepID string, lines int) ([]byte, int64, error) {
+	if s.logs == nil {
+		return nil, 0, ErrLogStoreDisabled
+	}
+	return s.logs.Tail(pipelineID, stepID, lines, maxTailBytes)
+}
+
+// findStepStatus 按步骤ID查找步骤状态
+func findStepStatus(pipeline *models.GamePipeline, stepID string) *models.StepStatus {
+	for i := range pipeline.Status.Steps {
+		if pipeline.Status.Steps[i].ID == stepID {
+			return &pipeline.Status.Steps[i]
+		}
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/open-beagle/beagle-wind-game/internal/models"
@@ -22,10 +24,37 @@ type PipelineStore interface {
 	Cleanup() error
 }
 
+// 流水线状态上报相关错误
+var (
+	ErrPipelineNotFound    = errors.New("流水线不存在")
+	ErrPipelineNotOwned    = errors.New("流水线不属于上报节点")
+	ErrStepStateTransition = errors.New("不允许的步骤状态转换")
+)
+
+// PipelineStateChangeHandler 流水线状态变更后执行的处理函数
+// 处理函数同步执行，可能持有状态上报锁，不能再调用更新流水线状态的方法
+type PipelineStateChangeHandler func(ctx context.Context, pipeline *models.GamePipeline, from models.PipelineState)
+
 // GamePipelineService 游戏节点流水线服务
 type GamePipelineService struct {
 	store  store.GamePipelineStore
 	logger utils.Logger
+
+	// reportMu 串行化节点上报与下发状态的更新，保证检查与写入的原子性
+	reportMu sync.Mutex
+
+	// 流水线下发依赖，由 SetDispatcher / SetTemplateSource / SetNodeService 注入
+	dispatcher  PipelineDispatcher
+	nodes       *GameNodeService
+	templateDir string
+	envs        map[string]string
+
+	// logs 步骤日志存储，由 SetLogStore 注入
+	logs *store.StepLogStore
+
+	// stateHandlers 流水线状态变更处理函数，由 OnStateChange 注册
+	handlersMu    sync.Mutex
+	stateHandlers []PipelineStateChangeHandler
 }
 
 // NewGamePipelineService 创建新的游戏节点流水线服务
@@ -37,6 +66,36 @@ func NewGamePipelineService(store store.GamePipelineStore) *GamePipelineService
 	}
 }
 
+// OnStateChange 注册流水线状态变更后执行的处理函数，按注册顺序执行
+func (s *GamePipelineService) OnStateChange(handler PipelineStateChangeHandler) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.stateHandlers = append(s.stateHandlers, handler)
+}
+
+// notifyStateChange 流水线状态与 from 不同时执行状态变更处理函数
+func (s *GamePipelineService) notifyStateChange(ctx context.Context, pipeline *models.GamePipeline, from models.PipelineState) {
+	to := pipelineState(pipeline)
+	if to == from {
+		return
+	}
+	s.handlersMu.Lock()
+	handlers := append([]PipelineStateChangeHandler{}, s.stateHandlers...)
+	s.handlersMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(ctx, pipeline, from)
+	}
+}
+
+// pipelineState 获取流水线当前状态，状态未初始化时返回空
+func pipelineState(pipeline *models.GamePipeline) models.PipelineState {
+	if pipeline == nil || pipeline.Status == nil {
+		return ""
+	}
+	return pipeline.Status.State
+}
+
 // List 获取流水线列表
 func (s *GamePipelineService) List(ctx context.Context) ([]*models.GamePipeline, error) {
 	s.logger.Debug("获取流水线列表")
@@ -89,6 +148,17 @@ func (s *GamePipelineService) Create(ctx context.Context, pipeline *models.GameP
 		}
 	}
 
+	// 步骤ID与 Agent 上报保持一致，使用步骤名称
+	for i := range pipeline.Status.Steps {
+		if i < len(pipeline.Steps) && pipeline.Status.Steps[i].ID == "" {
+			pipeline.Status.Steps[i].ID = pipeline.Steps[i].Name
+			pipeline.Status.Steps[i].Name = pipeline.Steps[i].Name
+		}
+		if pipeline.Status.Steps[i].State == "" {
+			pipeline.Status.Steps[i].State = models.StepStatePending
+		}
+	}
+
 	// 3. 保存到存储
 	if err := s.store.Add(ctx, pipeline); err != nil {
 		s.logger.Error("创建流水线失败: %v", err)
@@ -140,6 +210,7 @@ func (s *GamePipelineService) UpdateStatus(ctx context.Context, id string, statu
 	}
 
 	// 更新状态
+	from := pipelineState(pipeline)
 	pipeline.Status = status
 	err = s.store.Update(ctx, pipeline)
 	if err != nil {
@@ -147,6 +218,7 @@ func (s *GamePipelineService) UpdateStatus(ctx context.Context, id string, statu
 		return fmt.Errorf("更新流水线状态失败: %w", err)
 	}
 	s.logger.Info("成功更新流水线状态: %s, 状态: %s", id, status.State)
+	s.notifyStateChange(ctx, pipeline, from)
 	return nil
 }
 
@@ -165,6 +237,24 @@ func (s *GamePipelineService) UpdateStepStatus(ctx context.Context, pipelineID s
 		return fmt.Errorf("流水线不存在: %s", pipelineID)
 	}
 
+	from := pipelineState(pipeline)
+	if err := s.applyStepStatus(pipeline, stepID, status); err != nil {
+		return err
+	}
+
+	// 保存更新
+	err = s.store.Update(ctx, pipeline)
+	if err != nil {
+		s.logger.Error("更新流水线步骤状态失败: %v", err)
+		return fmt.Errorf("更新流水线步骤状态失败: %w", err)
+	}
+	s.logger.Info("成功更新流水线步骤状态: 流水线ID: %s, 步骤ID: %s, 状态: %s", pipelineID, stepID, status.State)
+	s.notifyStateChange(ctx, pipeline, from)
+	return nil
+}
+
+// applyStepStatus 校验状态转换并将步骤状态写入流水线
+func (s *GamePipelineService) applyStepStatus(pipeline *models.GamePipeline, stepID string, status *models.StepStatus) error {
 	// 验证状态转换是否合法
 	if err := s.validateStepStateTransition(pipeline, stepID, status.State); err != nil {
 		return err
@@ -173,18 +263,34 @@ func (s *GamePipelineService) UpdateStepStatus(ctx context.Context, pipelineID s
 	// 更新步骤状态
 	stepFound := false
 	for i := range pipeline.Status.Steps {
-		if pipeline.Status.Steps[i].ID == stepID {
-			// 只更新状态字段，保留其他信息
-			pipeline.Status.Steps[i].State = status.State
-			pipeline.Status.Steps[i].Error = status.Error
-			stepFound = true
-			break
+		step := &pipeline.Status.Steps[i]
+		if step.ID != stepID {
+			continue
+		}
+		// 只更新上报的字段，保留其他信息
+		step.State = status.State
+		step.Error = status.Error
+		if status.ContainerID != "" {
+			step.ContainerID = status.ContainerID
+		}
+		if status.StartTime != nil {
+			step.StartTime = status.StartTime
 		}
+		if status.EndTime != nil {
+			step.EndTime = status.EndTime
+		}
+		if status.Progress > 0 {
+			step.Progress = status.Progress
+		}
+		now := time.Now()
+		step.UpdatedAt = &now
+		stepFound = true
+		break
 	}
 
 	if !stepFound {
-		s.logger.Error("流水线步骤不存在: 流水线ID: %s, 步骤ID: %s", pipelineID, stepID)
-		return fmt.Errorf("流水线步骤不存在: 流水线ID: %s, 步骤ID: %s", pipelineID, stepID)
+		s.logger.Error("流水线步骤不存在: 流水线ID: %s, 步骤ID: %s", pipeline.ID, stepID)
+		return fmt.Errorf("%w: 流水线ID: %s, 步骤ID: %s", ErrStepNotFound, pipeline.ID, stepID)
 	}
 
 	// 更新流水线进度
@@ -192,15 +298,116 @@ func (s *GamePipelineService) UpdateStepStatus(ctx context.Context, pipelineID s
 
 	// 检查是否需要更新流水线状态
 	s.updatePipelineState(pipeline)
+	return nil
+}
 
-	// 保存更新
-	err = s.store.Update(ctx, pipeline)
+// ApplyStatusReport 应用节点上报的流水线状态
+// 序号不大于已应用序号的上报视为重复或过期，直接忽略并返回 false
+func (s *GamePipelineService) ApplyStatusReport(ctx context.Context, id string, nodeID string, sequence int64, report *models.PipelineStatus) (bool, error) {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+
+	pipeline, err := s.getOwnedPipeline(ctx, id, nodeID)
 	if err != nil {
+		return false, err
+	}
+	if s.isStaleReport(pipeline, sequence) {
+		s.logger.Debug("忽略过期的流水线状态上报: %s, 序号: %d, 已应用序号: %d", id, sequence, pipeline.Status.Sequence)
+		return false, nil
+	}
+	// 终态不再改变，例如服务端已取消的流水线节点随后上报失败
+	if pipeline.Status.State.IsTerminal() && report.State != pipeline.Status.State {
+		s.logger.Warn("流水线 %s 已处于终态 %s，忽略上报状态 %s", id, pipeline.Status.State, report.State)
+		return false, nil
+	}
+
+	// 合并上报字段，保留服务端维护的步骤状态
+	status := *pipeline.Status
+	status.State = report.State
+	status.CurrentStep = report.CurrentStep
+	if report.TotalSteps > 0 {
+		status.TotalSteps = report.TotalSteps
+	}
+	status.ErrorMessage = report.ErrorMessage
+	if report.StartTime != nil {
+		status.StartTime = report.StartTime
+	}
+	if report.EndTime != nil {
+		status.EndTime = report.EndTime
+	}
+	now := time.Now()
+	status.UpdatedAt = &now
+	if sequence > status.Sequence {
+		status.Sequence = sequence
+	}
+
+	if err := s.UpdateStatus(ctx, id, &status); err != nil {
+		if errors.Is(err, store.ErrPipelineNotFound) {
+			return false, fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ApplyStepReport 应用节点上报的步骤状态，序号规则与 ApplyStatusReport 相同
+func (s *GamePipelineService) ApplyStepReport(ctx context.Context, id string, nodeID string, stepID string, sequence int64, report *models.StepStatus) (bool, error) {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+
+	pipeline, err := s.getOwnedPipeline(ctx, id, nodeID)
+	if err != nil {
+		return false, err
+	}
+	if s.isStaleReport(pipeline, sequence) {
+		s.logger.Debug("忽略过期的步骤状态上报: %s/%s, 序号: %d, 已应用序号: %d", id, stepID, sequence, pipeline.Status.Sequence)
+		return false, nil
+	}
+
+	from := pipelineState(pipeline)
+	if err := s.applyStepStatus(pipeline, stepID, report); err != nil {
+		return false, err
+	}
+	if sequence > pipeline.Status.Sequence {
+		pipeline.Status.Sequence = sequence
+	}
+
+	if err := s.store.Update(ctx, pipeline); err != nil {
 		s.logger.Error("更新流水线步骤状态失败: %v", err)
-		return fmt.Errorf("更新流水线步骤状态失败: %w", err)
+		return false, reportStoreError(id, "更新流水线步骤状态失败", err)
 	}
-	s.logger.Info("成功更新流水线步骤状态: 流水线ID: %s, 步骤ID: %s, 状态: %s", pipelineID, stepID, status.State)
-	return nil
+	s.logger.Info("成功更新流水线步骤状态: 流水线ID: %s, 步骤ID: %s, 状态: %s", id, stepID, report.State)
+	s.notifyStateChange(ctx, pipeline, from)
+	return true, nil
+}
+
+// getOwnedPipeline 获取流水线并校验其归属节点
+func (s *GamePipelineService) getOwnedPipeline(ctx context.Context, id string, nodeID string) (*models.GamePipeline, error) {
+	pipeline, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, reportStoreError(id, "获取流水线失败", err)
+	}
+	if pipeline == nil || pipeline.Status == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	if pipeline.Status.NodeID == "" || pipeline.Status.NodeID != nodeID {
+		s.logger.Warn("节点 %s 上报了不属于它的流水线 %s（归属节点: %s）", nodeID, id, pipeline.Status.NodeID)
+		return nil, fmt.Errorf("%w: %s", ErrPipelineNotOwned, id)
+	}
+	return pipeline, nil
+}
+
+// reportStoreError 包装处理上报时的存储层错误，流水线已被删除时返回 ErrPipelineNotFound
+func reportStoreError(id string, message string, err error) error {
+	if errors.Is(err, store.ErrPipelineNotFound) {
+		return fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// isStaleReport 判断上报序号是否已被应用过，序号为 0 表示上报方不支持序号
+func (s *GamePipelineService) isStaleReport(pipeline *models.GamePipeline, sequence int64) bool {
+	return sequence > 0 && sequence <= pipeline.Status.Sequence
 }
 
 // validateStepStateTransition 验证步骤状态转换是否合法
@@ -214,24 +421,29 @@ func (s *GamePipelineService) validateStepStateTransition(pipeline *models.GameP
 		}
 	}
 	if currentStep == nil {
-		return fmt.Errorf("步骤不存在: %s", stepID)
+		return fmt.Errorf("%w: %s", ErrStepNotFound, stepID)
+	}
+
+	// 相同状态的重复上报不做校验
+	if currentStep.State == newState {
+		return nil
 	}
 
 	// 状态转换规则
 	switch currentStep.State {
 	case models.StepStatePending:
-		// 只能转换为运行中
-		if newState != models.StepStateRunning {
-			return fmt.Errorf("无效的状态转换: 从 %s 到 %s", currentStep.State, newState)
+		// 执行很快的步骤可能直接进入终态，但不能回到等待中
+		if newState == models.StepStatePending {
+			return fmt.Errorf("%w: 从 %s 到 %s", ErrStepStateTransition, currentStep.State, newState)
 		}
 	case models.StepStateRunning:
 		// 可以转换为完成、失败或跳过
 		if newState != models.StepStateCompleted && newState != models.StepStateFailed && newState != models.StepStateSkipped {
-			return fmt.Errorf("无效的状态转换: 从 %s 到 %s", currentStep.State, newState)
+			return fmt.Errorf("%w: 从 %s 到 %s", ErrStepStateTransition, currentStep.State, newState)
 		}
 	case models.StepStateCompleted, models.StepStateFailed, models.StepStateSkipped:
 		// 终态不能改变
-		return fmt.Errorf("步骤已处于终态 %s，不能改变状态", currentStep.State)
+		return fmt.Errorf("%w: 步骤已处于终态 %s", ErrStepStateTransition, currentStep.State)
 	}
 
 	return nil
@@ -257,18 +469,21 @@ func (s *GamePipelineService) updatePipelineState(pipeline *models.GamePipeline)
 	hasSkippedStep := false
 
 	for _, step := range pipeline.Status.Steps {
-		if step.State == models.StepStateRunning {
+		switch step.State {
+		case models.StepStateRunning, models.StepStatePending:
 			allStepsCompleted = false
-			break
-		}
-		if step.State == models.StepStateFailed {
+		case models.StepStateFailed:
 			hasFailedStep = true
-		}
-		if step.State == models.StepStateSkipped {
+		case models.StepStateSkipped:
 			hasSkippedStep = true
 		}
 	}
 
+	// 步骤失败后后续步骤不再执行，流水线直接失败
+	if hasFailedStep {
+		allStepsCompleted = true
+	}
+
 	if allStepsCompleted {
 		if hasFailedStep {
 			pipeline.Status.State = models.PipelineStateFailed
@@ -282,37 +497,28 @@ func (s *GamePipelineService) updatePipelineState(pipeline *models.GamePipeline)
 	}
 }
 
-// SaveStepLogs 保存步骤日志
+// SaveStepLogs 保存步骤日志，日志写入步骤日志文件而不是流水线数据
 func (s *GamePipelineService) SaveStepLogs(ctx context.Context, pipelineID string, stepID string, logs []byte) error {
 	s.logger.Debug("保存流水线步骤日志: 流水线ID: %s, 步骤ID: %s, 日志大小: %d字节", pipelineID, stepID, len(logs))
-	// 获取流水线
-	pipeline, err := s.store.Get(ctx, pipelineID)
-	if err != nil {
-		s.logger.Error("获取流水线失败: %v", err)
-		return fmt.Errorf("获取流水线失败: %w", err)
-	}
-	if pipeline == nil {
-		s.logger.Error("流水线不存在: %s", pipelineID)
-		return fmt.Errorf("流水线不存在: %s", pipelineID)
+	if s.logs == nil {
+		return ErrLogStoreDisabled
 	}
 
-	// 更新步骤日志
-	stepFound := false
-	for i := range pipeline.Status.Steps {
-		if pipeline.Status.Steps[i].ID == stepID {
-			pipeline.Status.Steps[i].Logs = logs
-			stepFound = true
-			break
-		}
+	pipeline, err := s.store.Get(ctx, pipelineID)
+	if err != nil || pipeline == nil || pipeline.Status == nil {
+		s.logger.Error("流水线不存在: %s", pipelineID)
+		return fmt.Errorf("%w: %s", ErrPipelineNotFound, pipelineID)
 	}
-
-	if !stepFound {
+	if findStepStatus(pipeline, stepID) == nil {
 		s.logger.Error("流水线步骤不存在: 流水线ID: %s, 步骤ID: %s", pipelineID, stepID)
-		return fmt.Errorf("流水线步骤不存在: 流水线ID: %s, 步骤ID: %s", pipelineID, stepID)
+		return fmt.Errorf("%w: %s", ErrStepNotFound, stepID)
 	}
 
-	err = s.store.Update(ctx, pipeline)
+	info, err := s.logs.Stat(pipelineID, stepID)
 	if err != nil {
+		return err
+	}
+	if _, err := s.logs.Append(pipelineID, stepID, info.End, logs, false); err != nil {
 		s.logger.Error("保存流水线步骤日志失败: %v", err)
 		return fmt.Errorf("保存流水线步骤日志失败: %w", err)
 	}
@@ -344,7 +550,17 @@ func (s *GamePipelineService) Cancel(ctx context.Context, id string) error {
 		UpdatedAt:    &now,
 	}
 
-	return s.UpdateStatus(ctx, id, status)
+	if err := s.UpdateStatus(ctx, id, status); err != nil {
+		return err
+	}
+
+	// 通知节点停止执行，节点未连接时只记录服务端状态
+	if s.dispatcher != nil && status.NodeID != "" {
+		if err := s.dispatcher.Cancel(ctx, status.NodeID, id, status.ErrorMessage); err != nil {
+			s.logger.Warn("通知节点 %s 取消流水线 %s 失败: %v", status.NodeID, id, err)
+		}
+	}
+	return nil
 }
 
 // Delete 删除流水线
@@ -355,6 +571,11 @@ func (s *GamePipelineService) Delete(ctx context.Context, id string) error {
 		s.logger.Error("删除流水线失败: %v", err)
 		return fmt.Errorf("删除流水线失败: %w", err)
 	}
+	if s.logs != nil {
+		if err := s.logs.DeletePipeline(id); err != nil {
+			s.logger.Warn("删除流水线 %s 的步骤日志失败: %v", id, err)
+		}
+	}
 	s.logger.Info("成功删除流水线: %s", id)
 	return nil
 }
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+)
+
+func TestPipelineReports(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// 存储延迟落盘，预先创建数据文件
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pipelines.yaml"), []byte("[]\n"), 0644))
+	pipelines := NewGamePipelineService(store.NewYAMLGamePipelineStore(ctx, filepath.Join(dir, "pipelines.yaml")))
+	pipelines.SetDispatcher(&fakeDispatcher{})
+	pipelines.SetTemplateSource("../../config/pipeline", nil)
+	var states []models.PipelineState
+	pipelines.OnStateChange(func(ctx context.Context, pipeline *models.GamePipeline, from models.PipelineState) {
+		states = append(states, pipeline.Status.State)
+	})
+
+	pipeline, err := pipelines.Submit(ctx, SubmitPipelineParams{Template: stopPlatformTemplate, Args: map[string]string{"INSTANCE": "inst-1"}, NodeID: "node-1"})
+	require.NoError(t, err)
+	get := func() *models.GamePipeline {
+		current, err := pipelines.Get(ctx, pipeline.ID)
+		require.NoError(t, err)
+		return current
+	}
+
+	// 不属于上报节点的流水线拒绝上报，状态不变
+	_, err = pipelines.ApplyStatusReport(ctx, pipeline.ID, "node-2", 1, &models.PipelineStatus{State: models.PipelineStateRunning})
+	assert.ErrorIs(t, err, ErrPipelineNotOwned)
+	_, err = pipelines.ApplyStepReport(ctx, pipeline.ID, "node-2", "teardown", 1, &models.StepStatus{State: models.StepStateRunning})
+	assert.ErrorIs(t, err, ErrPipelineNotOwned)
+	assert.Equal(t, models.PipelineStatePending, get().Status.State)
+
+	applied, err := pipelines.ApplyStatusReport(ctx, pipeline.ID, "node-1", 1, &models.PipelineStatus{State: models.PipelineStateRunning})
+	require.NoError(t, err)
+	assert.True(t, applied)
+	applied, err = pipelines.ApplyStepReport(ctx, pipeline.ID, "node-1", "teardown", 2, &models.StepStatus{State: models.StepStateRunning})
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	// 重复和过期序号的上报直接丢弃
+	applied, err = pipelines.ApplyStepReport(ctx, pipeline.ID, "node-1", "teardown", 2, &models.StepStatus{State: models.StepStateCompleted})
+	require.NoError(t, err)
+	assert.False(t, applied)
+	applied, err = pipelines.ApplyStatusReport(ctx, pipeline.ID, "node-1", 1, &models.PipelineStatus{State: models.PipelineStateFailed})
+	require.NoError(t, err)
+	assert.False(t, applied)
+	current := get()
+	assert.Equal(t, models.PipelineStateRunning, current.Status.State)
+	assert.Equal(t, models.StepStateRunning, current.Status.Steps[0].State)
+	assert.Equal(t, int64(2), current.Status.Sequence)
+
+	// 序号为 0 的上报不做去重
+	applied, err = pipelines.ApplyStepReport(ctx, pipeline.ID, "node-1", "teardown", 0, &models.StepStatus{State: models.StepStateCompleted})
+	require.NoError(t, err)
+	assert.True(t, applied)
+	assert.Equal(t, models.PipelineStateCompleted, get().Status.State)
+	assert.Equal(t, []models.PipelineState{models.PipelineStateRunning, models.PipelineStateCompleted}, states)
+
+	// 不允许的步骤状态转换和不存在的步骤被拒绝
+	_, err = pipelines.ApplyStepReport(ctx, pipeline.ID, "node-1", "teardown", 3, &models.StepStatus{State: models.StepStateRunning})
+	assert.ErrorIs(t, err, ErrStepStateTransition)
+	_, err = pipelines.ApplyStepReport(ctx, pipeline.ID, "node-1", "missing", 4, &models.StepStatus{State: models.StepStateRunning})
+	assert.ErrorIs(t, err, ErrStepNotFound)
+
+	// 已删除的流水线返回不存在
+	require.NoError(t, pipelines.Delete(ctx, pipeline.ID))
+	_, err = pipelines.ApplyStatusReport(ctx, pipeline.ID, "node-1", 5, &models.PipelineStatus{State: models.PipelineStateCompleted})
+	assert.ErrorIs(t, err, ErrPipelineNotFound)
+	_, err = pipelines.ApplyStepReport(ctx, pipeline.ID, "node-1", "teardown", 5, &models.StepStatus{State: models.StepStateCompleted})
+	assert.ErrorIs(t, err, ErrPipelineNotFound)
+}
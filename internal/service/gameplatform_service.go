@@ -14,6 +14,9 @@ import (
 type GamePlatformService struct {
 	platformStore store.GamePlatformStore
 	logger        utils.Logger
+
+	// access 访问令牌服务，由 SetAccessService 注入
+	access *AccessService
 }
 
 // NewGamePlatformService 创建游戏平台服务
@@ -122,38 +125,32 @@ func (s *GamePlatformService) Get(ctx context.Context, id string) (*models.GameP
 // GamePlatformAccessResult 平台远程访问结果
 type GamePlatformAccessResult struct {
 	Link      string    `json:"link"`
+	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// GetAccess 获取平台远程访问链接
-func (s *GamePlatformService) GetAccess(ctx context.Context, id string) (GamePlatformAccessResult, error) {
-	s.logger.Debug("获取游戏平台访问链接: %s", id)
-
-	// 检查平台是否存在
-	platform, err := s.platformStore.Get(ctx, id)
-	if err != nil {
-		s.logger.Error("获取平台详情失败: %v", err)
-		return GamePlatformAccessResult{}, fmt.Errorf("存储层错误: %w", err)
-	}
-	if platform.ID == "" {
-		s.logger.Error("平台不存在: %s", id)
-		return GamePlatformAccessResult{}, fmt.Errorf("平台不存在: %s", id)
-	}
+// SetAccessService 设置访问令牌服务，未设置时不能获取平台访问链接
+func (s *GamePlatformService) SetAccessService(access *AccessService) {
+	s.access = access
+}
 
-	// 生成访问链接
-	expiresAt := time.Now().Add(24 * time.Hour)
-	link := "https://vnc.example.com/platform/" + id
+// GetAccess 获取平台远程访问链接，链接指向该平台最近启动的运行中实例
+func (s *GamePlatformService) GetAccess(ctx context.Context, id string, userID string) (GamePlatformAccessResult, error) {
+	s.logger.Debug("获取游戏平台访问链接: %s, 用户: %s", id, userID)
+	return s.platformAccess(ctx, id, userID, false)
+}
 
-	s.logger.Debug("成功生成平台访问链接: id=%s, link=%s, expires=%v", id, link, expiresAt)
-	return GamePlatformAccessResult{
-		Link:      link,
-		ExpiresAt: expiresAt,
-	}, nil
+// RefreshAccess 刷新平台远程访问链接，之前获取的链接失效
+func (s *GamePlatformService) RefreshAccess(ctx context.Context, id string, userID string) (GamePlatformAccessResult, error) {
+	s.logger.Debug("刷新游戏平台访问链接: %s, 用户: %s", id, userID)
+	return s.platformAccess(ctx, id, userID, true)
 }
 
-// RefreshAccess 刷新平台远程访问链接
-func (s *GamePlatformService) RefreshAccess(ctx context.Context, id string) (GamePlatformAccessResult, error) {
-	s.logger.Debug("刷新游戏平台访问链接: %s", id)
+// platformAccess 为平台最近启动的运行中实例签发访问链接
+func (s *GamePlatformService) platformAccess(ctx context.Context, id string, userID string, refresh bool) (GamePlatformAccessResult, error) {
+	if s.access == nil {
+		return GamePlatformAccessResult{}, ErrAccessDisabled
+	}
 
 	// 检查平台是否存在
 	platform, err := s.platformStore.Get(ctx, id)
@@ -166,14 +163,26 @@ func (s *GamePlatformService) RefreshAccess(ctx context.Context, id string) (Gam
 		return GamePlatformAccessResult{}, fmt.Errorf("平台不存在: %s", id)
 	}
 
-	// 刷新访问链接
-	expiresAt := time.Now().Add(24 * time.Hour)
-	link := "https://vnc.example.com/platform/" + id + "?refresh=" + time.Now().String()
+	instanceID, err := s.access.latestRunningInstance(ctx, id)
+	if err != nil {
+		return GamePlatformAccessResult{}, err
+	}
+	var access AccessResult
+	if refresh {
+		access, err = s.access.RefreshInstance(ctx, instanceID, userID)
+	} else {
+		access, err = s.access.IssueInstance(ctx, instanceID, userID)
+	}
+	if err != nil {
+		s.logger.Error("签发平台访问链接失败: %v", err)
+		return GamePlatformAccessResult{}, err
+	}
 
-	s.logger.Debug("成功刷新平台访问链接: id=%s, link=%s, expires=%v", id, link, expiresAt)
+	s.logger.Debug("成功生成平台访问链接: id=%s, instance=%s, expires=%v", id, instanceID, access.ExpiresAt)
 	return GamePlatformAccessResult{
-		Link:      link,
-		ExpiresAt: expiresAt,
+		Link:      access.Link,
+		Token:     access.Token,
+		ExpiresAt: access.ExpiresAt,
 	}, nil
 }
 
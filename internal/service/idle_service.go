@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// defaultIdleInterval 默认的空闲检测周期
+	defaultIdleInterval = time.Minute
+	// defaultIdleTimeout 默认的空闲超时时间
+	defaultIdleTimeout = 30 * time.Minute
+	// defaultIdleWarning 默认在自动停止前多久发出空闲警告
+	defaultIdleWarning = 5 * time.Minute
+	// defaultIdleCPUThreshold 默认的 CPU 使用率阈值，单核满载为 100
+	defaultIdleCPUThreshold = 10
+	// defaultIdleGPUThreshold 默认的 GPU 使用率阈值
+	defaultIdleGPUThreshold = 10
+)
+
+// IdleOptions 空闲检测配置
+type IdleOptions struct {
+	// Interval 检测周期
+	Interval time.Duration
+	// Timeout 实例持续空闲超过该时间后自动停止
+	Timeout time.Duration
+	// Warning 自动停止前多久在实例上记录空闲警告
+	Warning time.Duration
+	// CPUThreshold 容器 CPU 使用率达到该值时视为活动
+	CPUThreshold float64
+	// GPUThreshold 容器 GPU 使用率达到该值时视为活动
+	GPUThreshold float64
+	// IdleWithoutConnection 没有串流连接时忽略输入和 CPU、GPU 使用率，直接视为空闲
+	IdleWithoutConnection bool
+}
+
+// IdleService 空闲检测服务
+// 根据节点上报的容器活动采样判断运行中的实例是否空闲，空闲超时后按正常流程停止实例
+type IdleService struct {
+	nodes     *GameNodeService
+	instances *GameInstanceService
+	opts      IdleOptions
+	logger    utils.Logger
+	// now 检测使用的当前时间
+	now func() time.Time
+
+	mu sync.Mutex
+	// lastActive 运行中实例最近一次检测到活动的时间
+	lastActive map[string]time.Time
+}
+
+// NewIdleService 创建空闲检测服务，未设置的配置项使用默认值
+func NewIdleService(nodes *GameNodeService, instances *GameInstanceService, opts IdleOptions) *IdleService {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultIdleInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultIdleTimeout
+	}
+	if opts.Warning <= 0 {
+		opts.Warning = defaultIdleWarning
+	}
+	if opts.Warning > opts.Timeout {
+		opts.Warning = opts.Timeout
+	}
+	if opts.CPUThreshold <= 0 {
+		opts.CPUThreshold = defaultIdleCPUThreshold
+	}
+	if opts.GPUThreshold <= 0 {
+		opts.GPUThreshold = defaultIdleGPUThreshold
+	}
+	return &IdleService{
+		nodes:      nodes,
+		instances:  instances,
+		opts:       opts,
+		logger:     utils.New("IdleService"),
+		now:        time.Now,
+		lastActive: make(map[string]time.Time),
+	}
+}
+
+// Run 定期检测空闲实例，直到 ctx 取消
+func (s *IdleService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("空闲检测启动，周期: %v，超时: %v，警告: %v", s.opts.Interval, s.opts.Timeout, s.opts.Warning)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Check(ctx); err != nil {
+				s.logger.Error("检测空闲实例失败: %v", err)
+			}
+		}
+	}
+}
+
+// Check 检测所有运行中的实例，记录或清除空闲警告，停止空闲超时的实例
+// 运行节点离线或没有活动采样的实例不参与检测
+func (s *IdleService) Check(ctx context.Context) error {
+	nodes, err := s.nodes.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	instances, err := s.instances.List(ctx)
+	if err != nil {
+		return fmt.Errorf("获取实例列表失败: %w", err)
+	}
+
+	activities := make(map[string]*models.ContainerActivity)
+	for _, node := range nodes {
+		if !node.Status.Online {
+			continue
+		}
+		for _, c := range node.Status.Containers {
+			if c.InstanceID != "" && c.State == "running" && c.Activity != nil {
+				activities[c.InstanceID] = c.Activity
+			}
+		}
+	}
+
+	now := s.now()
+	var expired []string
+	s.mu.Lock()
+	running := make(map[string]bool)
+	for i := range instances {
+		instance := &instances[i]
+		if instance.Status != models.GameInstanceStateRunning {
+			continue
+		}
+		running[instance.ID] = true
+
+		// 实例重新启动后从启动时间开始计算
+		last := s.lastActive[instance.ID]
+		if last.Before(instance.StartedAt) {
+			last = instance.StartedAt
+		}
+		activity, ok := activities[instance.ID]
+		if !ok {
+			s.lastActive[instance.ID] = last
+			continue
+		}
+		if active := s.activeAt(activity); active.After(last) {
+			last = active
+		}
+		s.lastActive[instance.ID] = last
+
+		idle := now.Sub(last)
+		switch {
+		case idle >= s.opts.Timeout:
+			expired = append(expired, instance.ID)
+		case idle >= s.opts.Timeout-s.opts.Warning:
+			if instance.Idle != nil && instance.Idle.Since.Equal(last) {
+				continue
+			}
+			state := &models.IdleState{Since: last, WarnedAt: now, ShutdownAt: last.Add(s.opts.Timeout)}
+			if instance.Idle != nil {
+				state.WarnedAt = instance.Idle.WarnedAt
+			}
+			s.logger.Warn("实例 %s 已空闲 %v，将于 %s 自动停止", instance.ID, idle.Round(time.Second), state.ShutdownAt.Format(time.RFC3339))
+			if err := s.instances.updateIdle(ctx, instance.ID, state); err != nil {
+				s.logger.Error("记录实例 %s 的空闲警告失败: %v", instance.ID, err)
+			}
+		case instance.Idle != nil:
+			s.logger.Info("实例 %s 恢复活动，清除空闲警告", instance.ID)
+			if err := s.instances.updateIdle(ctx, instance.ID, nil); err != nil {
+				s.logger.Error("清除实例 %s 的空闲警告失败: %v", instance.ID, err)
+			}
+		}
+	}
+	for id := range s.lastActive {
+		if !running[id] {
+			delete(s.lastActive, id)
+		}
+	}
+	s.mu.Unlock()
+
+	// 停止流程会等待生命周期锁并提交流水线，在检测锁之外执行
+	for _, id := range expired {
+		s.logger.Info("实例 %s 空闲超过 %v，自动停止", id, s.opts.Timeout)
+		if _, err := s.instances.StopWithReason(ctx, id, models.StopReasonIdle); err != nil {
+			s.logger.Error("自动停止空闲实例 %s 失败: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// activeAt 根据一次活动采样返回检测到活动的最近时间，采样中没有活动时返回零值
+func (s *IdleService) activeAt(a *models.ContainerActivity) time.Time {
+	if s.opts.IdleWithoutConnection && a.Connections == 0 {
+		return time.Time{}
+	}
+	var active time.Time
+	if a.InputIdle >= 0 {
+		active = a.SampledAt.Add(-a.InputIdle)
+	} else if a.Connections > 0 {
+		// 节点无法监听输入设备时，有串流连接即视为活动
+		active = a.SampledAt
+	}
+	if (a.CPUPercent >= 0 && a.CPUPercent >= s.opts.CPUThreshold) || (a.GPUPercent >= 0 && a.GPUPercent >= s.opts.GPUThreshold) {
+		active = a.SampledAt
+	}
+	return active
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestIdleCheck(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// 存储延迟落盘，预先创建数据文件
+	for _, name := range []string{"instances.yaml", "pipelines.yaml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[]\n"), 0644))
+	}
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(dir, "nodes.yaml"))
+	require.NoError(t, err)
+	instanceStore, err := store.NewGameInstanceStore(ctx, filepath.Join(dir, "instances.yaml"), utils.New("IdleTest"))
+	require.NoError(t, err)
+
+	nodes := NewGameNodeService(nodeStore)
+	instances := NewGameInstanceService(instanceStore)
+	pipelines := NewGamePipelineService(store.NewYAMLGamePipelineStore(ctx, filepath.Join(dir, "pipelines.yaml")))
+	dispatcher := &fakeDispatcher{}
+	pipelines.SetDispatcher(dispatcher)
+	pipelines.SetTemplateSource("../../config/pipeline", nil)
+	instances.SetLifecycle(pipelines, nil, nil)
+
+	idle := NewIdleService(nodes, instances, IdleOptions{Timeout: 30 * time.Minute, Warning: 5 * time.Minute, IdleWithoutConnection: true})
+	now := time.Now()
+	idle.now = func() time.Time { return now }
+	started := now.Add(-2 * time.Hour)
+
+	sample := func(connections int, cpu float64, inputIdle time.Duration) *models.ContainerActivity {
+		return &models.ContainerActivity{SampledAt: now, Connections: connections, CPUPercent: cpu, GPUPercent: -1, InputIdle: inputIdle}
+	}
+	require.NoError(t, nodes.Create(ctx, models.GameNode{ID: "node-1"}))
+	require.NoError(t, nodes.UpdateStatusOnlineStatus(ctx, "node-1", true))
+	report := func(activities map[string]*models.ContainerActivity) {
+		containers := make([]models.ContainerInfo, 0, len(activities))
+		for id, activity := range activities {
+			containers = append(containers, models.ContainerInfo{ID: "c-" + id, State: "running", InstanceID: id, Activity: activity})
+		}
+		require.NoError(t, nodes.UpdateStatusContainers(ctx, "node-1", containers))
+	}
+	for _, id := range []string{"inst-busy", "inst-warn", "inst-idle", "inst-unwatched", "inst-unsampled"} {
+		require.NoError(t, instanceStore.Add(ctx, models.GameInstance{ID: id, NodeID: "node-1", Status: models.GameInstanceStateRunning, StartedAt: started}))
+	}
+	report(map[string]*models.ContainerActivity{
+		// CPU 使用率达到阈值
+		"inst-busy": sample(1, 50, time.Hour),
+		// 最近一次输入在 27 分钟前，进入警告期
+		"inst-warn": sample(1, 1, 27*time.Minute),
+		// 最近一次输入在 40 分钟前
+		"inst-idle": sample(1, 1, 40*time.Minute),
+		// 没有串流连接时忽略 CPU 使用率
+		"inst-unwatched": sample(0, 80, 0),
+	})
+
+	require.NoError(t, idle.Check(ctx))
+	get := func(id string) models.GameInstance {
+		instance, err := instances.Get(ctx, id)
+		require.NoError(t, err)
+		return instance
+	}
+
+	busy := get("inst-busy")
+	assert.Equal(t, models.GameInstanceStateRunning, busy.Status)
+	assert.Nil(t, busy.Idle)
+
+	warn := get("inst-warn")
+	assert.Equal(t, models.GameInstanceStateRunning, warn.Status)
+	require.NotNil(t, warn.Idle)
+	assert.True(t, warn.Idle.Since.Equal(now.Add(-27*time.Minute)))
+	assert.True(t, warn.Idle.ShutdownAt.Equal(now.Add(3*time.Minute)))
+
+	for _, id := range []string{"inst-idle", "inst-unwatched"} {
+		instance := get(id)
+		assert.Equal(t, models.GameInstanceStateStopping, instance.Status, id)
+		assert.Equal(t, models.StopReasonIdle, instance.StopReason, id)
+		assert.NotEmpty(t, instance.PipelineID, id)
+	}
+	require.Len(t, dispatcher.dispatched, 2)
+	assert.Equal(t, stopPlatformTemplate, dispatcher.dispatched[0].Dispatch.Template)
+
+	// 没有活动采样的实例不参与检测
+	unsampled := get("inst-unsampled")
+	assert.Equal(t, models.GameInstanceStateRunning, unsampled.Status)
+	assert.Nil(t, unsampled.Idle)
+
+	// 恢复输入后清除空闲警告
+	now = now.Add(time.Minute)
+	report(map[string]*models.ContainerActivity{"inst-warn": sample(1, 1, 0)})
+	require.NoError(t, idle.Check(ctx))
+	assert.Nil(t, get("inst-warn").Idle)
+	assert.Equal(t, models.GameInstanceStateRunning, get("inst-warn").Status)
+}
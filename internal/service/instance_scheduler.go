@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// 实例调度相关错误
+var (
+	ErrNoSchedulableNode  = errors.New("没有满足资源要求的节点")
+	ErrInvalidPolicy      = errors.New("不支持的调度策略")
+	ErrSchedulerDisabled  = errors.New("实例调度未启用，必须指定 node_id")
+	ErrMissingPlacementID = errors.New("必须指定 card_id 和 platform_id")
+)
+
+// gpuVendorAliases GPU 厂商在型号名称中可能出现的名称
+var gpuVendorAliases = map[string][]string{
+	"nvidia": {"nvidia", "geforce", "quadro", "tesla"},
+	"amd":    {"amd", "radeon"},
+	"intel":  {"intel", "arc"},
+}
+
+// SchedulingError 没有节点满足资源要求，Rejections 说明每个节点被排除的原因
+type SchedulingError struct {
+	Requirements models.ResourceRequirements
+	Rejections   []models.NodeRejection
+}
+
+// Error 实现 error 接口
+func (e *SchedulingError) Error() string {
+	if len(e.Rejections) == 0 {
+		return fmt.Sprintf("%s: 没有可用的节点", ErrNoSchedulableNode)
+	}
+	reasons := make([]string, 0, len(e.Rejections))
+	for _, r := range e.Rejections {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", r.NodeID, r.Reason))
+	}
+	return fmt.Sprintf("%s: %s", ErrNoSchedulableNode, strings.Join(reasons, "; "))
+}
+
+// Unwrap 支持 errors.Is(err, ErrNoSchedulableNode)
+func (e *SchedulingError) Unwrap() error {
+	return ErrNoSchedulableNode
+}
+
+// ScheduleParams 实例调度参数
+type ScheduleParams struct {
+	CardID     string                  `json:"card_id" binding:"required"`     // 游戏卡片
+	PlatformID string                  `json:"platform_id" binding:"required"` // 游戏平台
+	NodeID     string                  `json:"node_id"`                        // 指定节点，不检查资源，只记录预留
+	Policy     models.SchedulingPolicy `json:"policy"`                         // 调度策略，为空时使用默认策略
+	Selector   map[string]string       `json:"selector"`                       // 节点标签选择器，与资源要求中的标签合并
+	Location   string                  `json:"location"`                       // 节点位置，覆盖资源要求中的位置
+}
+
+// InstanceScheduler 实例调度器
+// 在在线且处于正常状态的节点中筛选满足平台和游戏卡片资源要求的节点，按调度策略评分后选择一个节点。
+// 节点可用资源取监控指标中的空闲资源与未被实例预留的资源中的较小值，
+// Place 在持有锁期间完成选择和创建实例，保证并发创建时不会重复占用同一份资源
+type InstanceScheduler struct {
+	mu        sync.Mutex
+	nodes     *GameNodeService
+	cards     *GameCardService
+	platforms *GamePlatformService
+	instances *GameInstanceService
+	policy    models.SchedulingPolicy
+	logger    utils.Logger
+}
+
+// NewInstanceScheduler 创建实例调度器，policy 为空时使用 least_loaded
+func NewInstanceScheduler(nodes *GameNodeService, cards *GameCardService, platforms *GamePlatformService, instances *GameInstanceService, policy models.SchedulingPolicy) (*InstanceScheduler, error) {
+	if policy == "" {
+		policy = models.SchedulingPolicyLeastLoaded
+	}
+	if !policy.IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, policy)
+	}
+	return &InstanceScheduler{
+		nodes:     nodes,
+		cards:     cards,
+		platforms: platforms,
+		instances: instances,
+		policy:    policy,
+		logger:    utils.New("InstanceScheduler"),
+	}, nil
+}
+
+// Requirements 合并平台和游戏卡片声明的资源要求，游戏卡片的要求优先
+func (s *InstanceScheduler) Requirements(ctx context.Context, cardID, platformID string) (models.ResourceRequirements, error) {
+	var req models.ResourceRequirements
+	platform, err := s.platforms.Get(ctx, platformID)
+	if err != nil {
+		return req, fmt.Errorf("获取游戏平台失败: %w", err)
+	}
+	card, err := s.cards.Get(ctx, cardID)
+	if err != nil {
+		return req, fmt.Errorf("获取游戏卡片失败: %w", err)
+	}
+	return req.Merge(platform.Requirements).Merge(card.Requirements), nil
+}
+
+// Schedule 计算调度结果但不预留资源，没有节点满足要求时返回 *SchedulingError
+func (s *InstanceScheduler) Schedule(ctx context.Context, params ScheduleParams) (*models.SchedulingDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.schedule(ctx, params)
+}
+
+// Place 选择节点后调用 commit 创建实例，commit 返回前其他调度请求等待，从而原子地预留资源
+func (s *InstanceScheduler) Place(ctx context.Context, params ScheduleParams, commit func(decision *models.SchedulingDecision) error) (*models.SchedulingDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	decision, err := s.schedule(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := commit(decision); err != nil {
+		return nil, err
+	}
+	s.logger.Info("实例调度到节点 %s, 策略: %s, 游戏卡片: %s", decision.NodeID, decision.Policy, params.CardID)
+	return decision, nil
+}
+
+// schedule 计算调度结果，调用方持有 s.mu
+func (s *InstanceScheduler) schedule(ctx context.Context, params ScheduleParams) (*models.SchedulingDecision, error) {
+	if params.CardID == "" || params.PlatformID == "" {
+		return nil, ErrMissingPlacementID
+	}
+	policy := params.Policy
+	if policy == "" {
+		policy = s.policy
+	}
+	if !policy.IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, policy)
+	}
+
+	req, err := s.Requirements(ctx, params.CardID, params.PlatformID)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Merge(&models.ResourceRequirements{Labels: params.Selector, Location: params.Location})
+
+	decision := &models.SchedulingDecision{
+		Policy:       policy,
+		Requirements: req,
+		Candidates:   []models.NodeScore{},
+		Rejections:   []models.NodeRejection{},
+	}
+
+	// 指定节点时不检查资源
+	if params.NodeID != "" {
+		decision.NodeID = params.NodeID
+		return decision, nil
+	}
+
+	nodes, err := s.nodes.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	reserved, counts, err := s.reservedResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		score, reason := evaluateNode(node, req, reserved[node.ID], counts[node.ID], policy)
+		if reason != "" {
+			decision.Rejections = append(decision.Rejections, models.NodeRejection{NodeID: node.ID, Reason: reason})
+			continue
+		}
+		decision.Candidates = append(decision.Candidates, score)
+	}
+	if len(decision.Candidates) == 0 {
+		return decision, &SchedulingError{Requirements: req, Rejections: decision.Rejections}
+	}
+
+	sort.SliceStable(decision.Candidates, func(i, j int) bool {
+		ci, cj := decision.Candidates[i], decision.Candidates[j]
+		if ci.Score != cj.Score {
+			return ci.Score > cj.Score
+		}
+		return ci.NodeID < cj.NodeID
+	})
+	decision.NodeID = decision.Candidates[0].NodeID
+	return decision, nil
+}
+
+// reservedResources 统计每个节点上占用资源的实例预留的资源和实例数
+func (s *InstanceScheduler) reservedResources(ctx context.Context) (map[string]models.ResourceReservation, map[string]int, error) {
+	instances, err := s.instances.List(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取实例列表失败: %w", err)
+	}
+	reserved := make(map[string]models.ResourceReservation)
+	counts := make(map[string]int)
+	for _, instance := range instances {
+		if !instance.Status.ReservesResources() {
+			continue
+		}
+		counts[instance.NodeID]++
+		if instance.Reservation != nil {
+			reserved[instance.NodeID] = reserved[instance.NodeID].Add(*instance.Reservation)
+		}
+	}
+	return reserved, counts, nil
+}
+
+// evaluateNode 检查节点是否满足资源要求并按调度策略评分，不满足时返回原因
+func evaluateNode(node models.GameNode, req models.ResourceRequirements, reserved models.ResourceReservation, instances int, policy models.SchedulingPolicy) (models.NodeScore, string) {
+	score := models.NodeScore{NodeID: node.ID, Instances: instances}
+	if !node.Status.Online {
+		return score, "节点离线"
+	}
+	if !node.State.AcceptsPipelines() {
+		return score, fmt.Sprintf("节点处于 %s 状态", node.State)
+	}
+	if req.Location != "" && node.Location != req.Location {
+		return score, fmt.Sprintf("节点位置为 %q，要求 %q", node.Location, req.Location)
+	}
+	for k, v := range req.Labels {
+		if node.Labels[k] != v {
+			return score, fmt.Sprintf("节点标签 %s=%q，要求 %q", k, node.Labels[k], v)
+		}
+	}
+
+	metrics := node.Status.Metrics
+	// 每一项资源放置后剩余的比例，用于评分
+	var remaining []float64
+
+	// CPU：总线程数按使用率扣除已使用部分
+	var cpuTotal, cpuIdle float64
+	for _, cpu := range metrics.CPUs {
+		threads := float64(cpu.Threads)
+		if threads == 0 {
+			threads = float64(cpu.Cores)
+		}
+		cpuTotal += threads
+		cpuIdle += threads * (1 - cpu.Usage/100)
+	}
+	score.FreeCPUCores = math.Max(0, math.Min(cpuTotal-reserved.CPUCores, cpuIdle))
+	if req.CPUCores > 0 {
+		if cpuTotal == 0 {
+			return score, "节点未上报 CPU 指标"
+		}
+		if score.FreeCPUCores < req.CPUCores {
+			return score, fmt.Sprintf("可用 CPU 不足，需要 %.1f，可用 %.1f", req.CPUCores, score.FreeCPUCores)
+		}
+	}
+	if cpuTotal > 0 {
+		remaining = append(remaining, (score.FreeCPUCores-req.CPUCores)/cpuTotal)
+	}
+
+	// 内存：监控指标单位为字节，资源要求单位为 MB
+	memTotal := metrics.Memory.Total >> 20
+	score.FreeMemory = max(0, min(memTotal-reserved.Memory, metrics.Memory.Available>>20))
+	if req.Memory > 0 {
+		if memTotal == 0 {
+			return score, "节点未上报内存指标"
+		}
+		if score.FreeMemory < req.Memory {
+			return score, fmt.Sprintf("可用内存不足，需要 %dMB，可用 %dMB", req.Memory, score.FreeMemory)
+		}
+	}
+	if memTotal > 0 {
+		remaining = append(remaining, float64(score.FreeMemory-req.Memory)/float64(memTotal))
+	}
+
+	// GPU：只统计厂商匹配的 GPU，显存单位为 MB
+	if req.GPUVendor != "" || req.GPUMemory > 0 {
+		var gpuTotal, gpuFree, largest int64
+		for _, gpu := range metrics.GPUs {
+			if !matchGPUVendor(gpu.Model, req.GPUVendor) {
+				continue
+			}
+			gpuTotal += gpu.MemoryTotal
+			gpuFree += gpu.MemoryFree
+			largest = max(largest, gpu.MemoryTotal)
+		}
+		if gpuTotal == 0 {
+			if req.GPUVendor != "" {
+				return score, fmt.Sprintf("节点没有 %s GPU", req.GPUVendor)
+			}
+			return score, "节点没有 GPU"
+		}
+		score.FreeGPUMemory = max(0, min(gpuTotal-reserved.GPUMemory, gpuFree))
+		if largest < req.GPUMemory {
+			return score, fmt.Sprintf("单块 GPU 显存不足，需要 %dMB，最大 %dMB", req.GPUMemory, largest)
+		}
+		if score.FreeGPUMemory < req.GPUMemory {
+			return score, fmt.Sprintf("可用显存不足，需要 %dMB，可用 %dMB", req.GPUMemory, score.FreeGPUMemory)
+		}
+		remaining = append(remaining, float64(score.FreeGPUMemory-req.GPUMemory)/float64(gpuTotal))
+	}
+
+	free := 0.0
+	if len(remaining) > 0 {
+		for _, r := range remaining {
+			free += r
+		}
+		free /= float64(len(remaining))
+	}
+	switch policy {
+	case models.SchedulingPolicyBinPacking:
+		score.Score = 1 - free
+	case models.SchedulingPolicySpread:
+		// 实例数相同时选择剩余资源比例高的节点
+		score.Score = float64(-instances) + free/2
+	default:
+		score.Score = free
+	}
+	return score, ""
+}
+
+// matchGPUVendor 判断 GPU 型号是否属于指定厂商，vendor 为空时匹配所有 GPU
+func matchGPUVendor(model, vendor string) bool {
+	if vendor == "" {
+		return true
+	}
+	model = strings.ToLower(model)
+	vendor = strings.ToLower(vendor)
+	aliases, ok := gpuVendorAliases[vendor]
+	if !ok {
+		aliases = []string{vendor}
+	}
+	for _, alias := range aliases {
+		if strings.Contains(model, alias) {
+			return true
+		}
+	}
+	return false
+}
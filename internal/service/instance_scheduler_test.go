@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// nodeMetrics 构造节点监控指标，内存单位为 MB
+func nodeMetrics(threads int32, cpuUsage float64, memory, memoryAvailable int64, gpus ...models.GPUMetrics) models.MetricsInfo {
+	return models.MetricsInfo{
+		CPUs:   []models.CPUMetrics{{Threads: threads, Usage: cpuUsage}},
+		Memory: models.MemoryMetrics{Total: memory << 20, Available: memoryAvailable << 20},
+		GPUs:   gpus,
+	}
+}
+
+func TestInstanceScheduler(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(dir, "nodes.yaml"))
+	require.NoError(t, err)
+	// 存储延迟落盘，预先创建数据文件
+	for _, name := range []string{"cards.yaml", "platforms.yaml", "instances.yaml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[]\n"), 0644))
+	}
+	cardStore, err := store.NewGameCardStore(ctx, filepath.Join(dir, "cards.yaml"))
+	require.NoError(t, err)
+	platformStore, err := store.NewGamePlatformStore(ctx, filepath.Join(dir, "platforms.yaml"))
+	require.NoError(t, err)
+	instanceStore, err := store.NewGameInstanceStore(ctx, filepath.Join(dir, "instances.yaml"), utils.New("SchedulerTest"))
+	require.NoError(t, err)
+
+	nodes := NewGameNodeService(nodeStore)
+	cards := NewGameCardService(cardStore)
+	platforms := NewGamePlatformService(platformStore)
+	instances := NewGameInstanceService(instanceStore)
+	scheduler, err := NewInstanceScheduler(nodes, cards, platforms, instances, "")
+	require.NoError(t, err)
+	instances.SetScheduler(scheduler)
+
+	_, err = platforms.Create(ctx, models.GamePlatform{
+		ID:           "steam",
+		Requirements: &models.ResourceRequirements{GPUVendor: "nvidia", Memory: 4096, CPUCores: 2},
+	})
+	require.NoError(t, err)
+	for _, id := range []string{"card-a", "card-b", "card-c"} {
+		_, err = cards.Create(ctx, models.GameCard{
+			ID:           id,
+			PlatformID:   "steam",
+			Requirements: &models.ResourceRequirements{GPUMemory: 10000, CPUCores: 4},
+		})
+		require.NoError(t, err)
+	}
+
+	gpu := func(model string, total, free int64) models.GPUMetrics {
+		return models.GPUMetrics{Model: model, MemoryTotal: total, MemoryFree: free}
+	}
+	for _, n := range []struct {
+		id      string
+		online  bool
+		metrics models.MetricsInfo
+	}{
+		{"big", true, nodeMetrics(32, 10, 65536, 60000, gpu("NVIDIA GeForce RTX 4090", 24576, 24000))},
+		{"small", true, nodeMetrics(16, 10, 32768, 30000, gpu("NVIDIA GeForce RTX 3060", 8192, 8000))},
+		{"radeon", true, nodeMetrics(16, 10, 32768, 30000, gpu("AMD Radeon RX 7900 XTX", 24576, 24000))},
+		{"offline", false, nodeMetrics(32, 10, 65536, 60000, gpu("NVIDIA GeForce RTX 4090", 24576, 24000))},
+		{"busy", true, nodeMetrics(8, 90, 65536, 60000, gpu("NVIDIA GeForce RTX 4090", 24576, 24000))},
+	} {
+		require.NoError(t, nodes.Create(ctx, models.GameNode{ID: n.id}))
+		require.NoError(t, nodes.UpdateStatusMetrics(ctx, n.id, n.metrics))
+		require.NoError(t, nodes.UpdateStatusOnlineStatus(ctx, n.id, n.online))
+	}
+
+	// 预览调度结果，不满足要求的节点给出原因
+	decision, err := instances.Schedule(ctx, ScheduleParams{CardID: "card-a", PlatformID: "steam"})
+	require.NoError(t, err)
+	assert.Equal(t, "big", decision.NodeID)
+	assert.Equal(t, models.SchedulingPolicyLeastLoaded, decision.Policy)
+	assert.Equal(t, models.ResourceRequirements{GPUVendor: "nvidia", GPUMemory: 10000, CPUCores: 4, Memory: 4096}, decision.Requirements)
+	reasons := make(map[string]string)
+	for _, r := range decision.Rejections {
+		reasons[r.NodeID] = r.Reason
+	}
+	assert.Contains(t, reasons["small"], "单块 GPU 显存不足")
+	assert.Contains(t, reasons["radeon"], "没有 nvidia GPU")
+	assert.Contains(t, reasons["offline"], "离线")
+	assert.Contains(t, reasons["busy"], "可用 CPU 不足")
+
+	// 创建的实例预留资源，显存用完后调度失败
+	for _, card := range []string{"card-a", "card-b"} {
+		id, err := instances.Create(ctx, CreateInstanceParams{CardID: card, PlatformID: "steam"})
+		require.NoError(t, err)
+		instance, err := instances.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "big", instance.NodeID)
+		require.NotNil(t, instance.Reservation)
+		assert.Equal(t, int64(10000), instance.Reservation.GPUMemory)
+	}
+	_, err = instances.Create(ctx, CreateInstanceParams{CardID: "card-c", PlatformID: "steam"})
+	var schedErr *SchedulingError
+	require.True(t, errors.As(err, &schedErr))
+	assert.True(t, errors.Is(err, ErrNoSchedulableNode))
+	assert.Contains(t, err.Error(), "big: 可用显存不足")
+
+	// 维护状态的节点不参与调度
+	_, err = nodes.UpdateState(ctx, "big", models.GameNodeStaticStateMaintenance)
+	require.NoError(t, err)
+	_, err = instances.Schedule(ctx, ScheduleParams{CardID: "card-c", PlatformID: "steam", Policy: models.SchedulingPolicySpread})
+	assert.ErrorContains(t, err, "big: 节点处于 maintenance 状态")
+
+	_, err = instances.Schedule(ctx, ScheduleParams{CardID: "card-c", PlatformID: "steam", Policy: "random"})
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+}
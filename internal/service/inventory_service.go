@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+const (
+	// defaultInventoryInterval 默认的容器清单核对周期
+	defaultInventoryInterval = 60 * time.Second
+	// defaultInventoryGrace 默认的核对宽限期
+	defaultInventoryGrace = 5 * time.Minute
+)
+
+// ContainerRemover 删除节点上托管容器的接口，由 gRPC Pipeline 服务端实现
+type ContainerRemover interface {
+	// RemoveContainer 通过节点会话下发删除容器命令，节点未连接时返回 ErrNodeNotConnected
+	RemoveContainer(ctx context.Context, nodeID string, containerID string, reason string) error
+}
+
+// InventoryOptions 容器清单核对配置
+type InventoryOptions struct {
+	// Interval 核对周期
+	Interval time.Duration
+	// Grace 实例启动或容器创建后经过该时间才参与核对，避免把正在创建的容器误判为不一致
+	Grace time.Duration
+	// AutoCleanup 是否自动清理：缺少容器的实例标记为 failed，孤立容器通知节点删除
+	AutoCleanup bool
+}
+
+// InventoryService 容器清单核对服务
+// 将节点上报的托管容器清单与实例记录比对，记录运行中却没有容器的实例和没有对应实例的容器
+type InventoryService struct {
+	nodes     *GameNodeService
+	instances *GameInstanceService
+	pipelines *GamePipelineService
+	remover   ContainerRemover
+	ports     *PortAllocator
+	opts      InventoryOptions
+	logger    utils.Logger
+
+	mu      sync.RWMutex
+	reports map[string]models.InventoryReport
+}
+
+// NewInventoryService 创建容器清单核对服务，未设置的配置项使用默认值
+func NewInventoryService(nodes *GameNodeService, instances *GameInstanceService, pipelines *GamePipelineService, opts InventoryOptions) *InventoryService {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInventoryInterval
+	}
+	if opts.Grace <= 0 {
+		opts.Grace = defaultInventoryGrace
+	}
+	return &InventoryService{
+		nodes:     nodes,
+		instances: instances,
+		pipelines: pipelines,
+		opts:      opts,
+		logger:    utils.New("InventoryService"),
+		reports:   make(map[string]models.InventoryReport),
+	}
+}
+
+// SetRemover 设置容器删除器，未设置时不清理孤立容器
+func (s *InventoryService) SetRemover(remover ContainerRemover) {
+	s.remover = remover
+}
+
+// SetPortAllocator 设置端口分配器，核对容器清单时同时核对节点的端口预留
+func (s *InventoryService) SetPortAllocator(ports *PortAllocator) {
+	s.ports = ports
+}
+
+// Run 定期核对容器清单，直到 ctx 取消
+func (s *InventoryService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("容器清单核对启动，周期: %v，自动清理: %v", s.opts.Interval, s.opts.AutoCleanup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Reconcile(ctx); err != nil {
+				s.logger.Error("核对容器清单失败: %v", err)
+			}
+		}
+	}
+}
+
+// Reconcile 核对所有在线且已上报容器清单的节点，返回本次的核对结果
+func (s *InventoryService) Reconcile(ctx context.Context) ([]models.InventoryReport, error) {
+	nodes, err := s.nodes.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	instances, err := s.instances.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取实例列表失败: %w", err)
+	}
+	pipelines, err := s.pipelines.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取流水线列表失败: %w", err)
+	}
+
+	// 容器归属：实例ID，或未结束的流水线（未关联实例的流水线以流水线ID作为容器的实例标签）
+	owners := make(map[string]bool, len(instances)+len(pipelines))
+	for _, instance := range instances {
+		owners[instance.ID] = true
+	}
+	active := make(map[string]bool)
+	for _, pipeline := range pipelines {
+		if pipeline.Status == nil || !pipeline.Status.State.IsTerminal() {
+			active[pipeline.ID] = true
+			owners[pipeline.ResourceScope()] = true
+		}
+	}
+
+	now := time.Now()
+	reports := make([]models.InventoryReport, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.Status.Online || node.Status.ContainersUpdatedAt.IsZero() {
+			continue
+		}
+		report := s.reconcileNode(ctx, node, instances, owners, active, now)
+		reports = append(reports, report)
+	}
+
+	s.mu.Lock()
+	for _, report := range reports {
+		s.reports[report.NodeID] = report
+	}
+	s.mu.Unlock()
+	return reports, nil
+}
+
+// reconcileNode 核对一个节点的容器清单
+func (s *InventoryService) reconcileNode(ctx context.Context, node models.GameNode, instances []models.GameInstance, owners, active map[string]bool, now time.Time) models.InventoryReport {
+	reportedAt := node.Status.ContainersUpdatedAt
+	report := models.InventoryReport{
+		NodeID:     node.ID,
+		CheckedAt:  now,
+		ReportedAt: reportedAt,
+		Containers: len(node.Status.Containers),
+		Issues:     make([]models.InventoryIssue, 0),
+	}
+
+	running := runningContainers(node.Status.Containers)
+
+	// 运行中的实例没有运行中的容器，只核对在容器清单上报前已启动超过宽限期的实例
+	for _, instance := range instances {
+		if instance.NodeID != node.ID {
+			continue
+		}
+		if instance.Status == models.GameInstanceStateLost {
+			report.Instances++
+			// 只使用实例失联后上报的容器清单
+			if !reportedAt.After(instance.UpdatedAt) {
+				continue
+			}
+			if issue, ok := s.recoverLost(ctx, instance, running[instance.ID]); ok {
+				report.Issues = append(report.Issues, issue)
+			}
+			continue
+		}
+		if instance.Status != models.GameInstanceStateRunning {
+			continue
+		}
+		report.Instances++
+		if running[instance.ID] || reportedAt.Sub(instance.StartedAt) < s.opts.Grace {
+			continue
+		}
+		issue := models.InventoryIssue{
+			Type:       models.InventoryIssueMissingContainer,
+			NodeID:     node.ID,
+			InstanceID: instance.ID,
+			Message:    "实例处于运行状态，但节点上没有运行中的容器",
+		}
+		if s.opts.AutoCleanup {
+			if err := s.instances.MarkContainerMissing(ctx, instance.ID); err != nil {
+				s.logger.Error("标记实例 %s 失败: %v", instance.ID, err)
+			} else {
+				issue.CleanedUp = true
+			}
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	// 托管容器没有对应的实例或未结束的流水线，只核对创建超过宽限期的容器
+	for _, c := range node.Status.Containers {
+		if owners[c.InstanceID] || active[c.PipelineID] || reportedAt.Sub(c.CreatedAt) < s.opts.Grace {
+			continue
+		}
+		issue := models.InventoryIssue{
+			Type:        models.InventoryIssueOrphanContainer,
+			NodeID:      node.ID,
+			InstanceID:  c.InstanceID,
+			ContainerID: c.ID,
+			Container:   c.Name,
+			PipelineID:  c.PipelineID,
+			Message:     "托管容器没有对应的实例或未结束的流水线",
+		}
+		if s.opts.AutoCleanup && s.remover != nil {
+			if err := s.remover.RemoveContainer(ctx, node.ID, c.ID, "没有对应的实例"); err != nil {
+				s.logger.Warn("通知节点 %s 删除孤立容器 %s 失败: %v", node.ID, c.Name, err)
+			} else {
+				issue.CleanedUp = true
+			}
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	// 使用容器的端口映射核对端口预留，宽限期内创建的预留不释放
+	if s.ports != nil {
+		issues, err := s.ports.Reconcile(ctx, node.ID, node.Status.Containers, instances, reportedAt.Add(-s.opts.Grace))
+		if err != nil {
+			s.logger.Error("核对节点 %s 的端口预留失败: %v", node.ID, err)
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+
+	if len(report.Issues) > 0 {
+		s.logger.Warn("节点 %s 的容器清单与实例记录不一致，共 %d 项", node.ID, len(report.Issues))
+	}
+	return report
+}
+
+// HandleContainers 节点上报容器清单后立即处理节点上的实例，不等待下一次核对，也不受自动清理配置影响
+// 节点重新连接后首先上报容器清单，失联实例由此恢复为运行中或标记为失败；
+// 游戏容器退出时节点在 2 秒内补充上报，运行中的实例由此标记为失败，存档随之上传
+func (s *InventoryService) HandleContainers(ctx context.Context, nodeID string, containers []models.ContainerInfo) {
+	instances, err := s.instances.List(ctx)
+	if err != nil {
+		s.logger.Error("获取实例列表失败: %v", err)
+		return
+	}
+	running := runningContainers(containers)
+	exited := make(map[string]bool)
+	for _, c := range containers {
+		if c.State == "exited" || c.State == "dead" {
+			exited[c.InstanceID] = true
+		}
+	}
+	for _, instance := range instances {
+		if instance.NodeID != nodeID {
+			continue
+		}
+		switch instance.Status {
+		case models.GameInstanceStateLost:
+			s.recoverLost(ctx, instance, running[instance.ID])
+		case models.GameInstanceStateRunning:
+			// 步骤容器执行完成后即删除，实例只剩已退出的容器说明游戏容器已崩溃
+			if exited[instance.ID] && !running[instance.ID] {
+				if err := s.instances.MarkContainerExited(ctx, instance.ID); err != nil {
+					s.logger.Error("标记实例 %s 失败: %v", instance.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// recoverLost 根据容器清单确定失联实例的状态，实例被标记为失败时返回对应的问题
+// 生命周期流水线尚未结束的实例由流水线上报继续更新状态，容器运行中时直接恢复
+func (s *InventoryService) recoverLost(ctx context.Context, instance models.GameInstance, containerRunning bool) (models.InventoryIssue, bool) {
+	if !containerRunning && instance.PipelineID != "" {
+		if pipeline, err := s.pipelines.Get(ctx, instance.PipelineID); err == nil && pipeline != nil && !pipelineState(pipeline).IsTerminal() {
+			return models.InventoryIssue{}, false
+		}
+	}
+	if err := s.instances.RecoverLost(ctx, instance.ID, containerRunning); err != nil {
+		s.logger.Error("恢复失联实例 %s 失败: %v", instance.ID, err)
+		return models.InventoryIssue{}, false
+	}
+	if containerRunning {
+		return models.InventoryIssue{}, false
+	}
+	return models.InventoryIssue{
+		Type:       models.InventoryIssueMissingContainer,
+		NodeID:     instance.NodeID,
+		InstanceID: instance.ID,
+		Message:    "实例在节点失联前运行，节点恢复后没有运行中的容器",
+		CleanedUp:  true,
+	}, true
+}
+
+// runningContainers 容器清单中有运行中容器的实例
+func runningContainers(containers []models.ContainerInfo) map[string]bool {
+	running := make(map[string]bool)
+	for _, c := range containers {
+		if c.State == "running" {
+			running[c.InstanceID] = true
+		}
+	}
+	return running
+}
+
+// Reports 获取所有节点最近一次的核对结果，按节点ID排序
+func (s *InventoryService) Reports() []models.InventoryReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reports := make([]models.InventoryReport, 0, len(s.reports))
+	for _, report := range s.reports {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].NodeID < reports[j].NodeID
+	})
+	return reports
+}
+
+// Report 获取节点最近一次的核对结果
+func (s *InventoryService) Report(nodeID string) (models.InventoryReport, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	report, ok := s.reports[nodeID]
+	return report, ok
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+type fakeContainerRemover struct {
+	removed []string
+}
+
+func (r *fakeContainerRemover) RemoveContainer(ctx context.Context, nodeID string, containerID string, reason string) error {
+	r.removed = append(r.removed, containerID)
+	return nil
+}
+
+func TestInventoryReconcile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(dir, "nodes.yaml"))
+	require.NoError(t, err)
+	// 实例存储延迟落盘，预先创建数据文件
+	instanceFile := filepath.Join(dir, "instances.yaml")
+	require.NoError(t, os.WriteFile(instanceFile, []byte("[]\n"), 0644))
+	instanceStore, err := store.NewGameInstanceStore(ctx, instanceFile, utils.New("InventoryTest"))
+	require.NoError(t, err)
+	pipelineStore := store.NewYAMLGamePipelineStore(ctx, filepath.Join(dir, "pipelines.yaml"))
+	t.Cleanup(pipelineStore.Close)
+
+	nodes := NewGameNodeService(nodeStore)
+	instances := NewGameInstanceService(instanceStore)
+	pipelines := NewGamePipelineService(pipelineStore)
+	inventory := NewInventoryService(nodes, instances, pipelines, InventoryOptions{Grace: time.Minute, AutoCleanup: true})
+	remover := &fakeContainerRemover{}
+	inventory.SetRemover(remover)
+
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	require.NoError(t, nodes.Create(ctx, models.GameNode{ID: "node-1"}))
+	require.NoError(t, nodes.UpdateStatusOnlineStatus(ctx, "node-1", true))
+	require.NoError(t, nodes.UpdateStatusContainers(ctx, "node-1", []models.ContainerInfo{
+		{ID: "c-healthy", State: "running", InstanceID: "inst-healthy", CreatedAt: old},
+		{ID: "c-orphan", State: "running", InstanceID: "inst-gone", CreatedAt: old},
+		{ID: "c-new", State: "running", InstanceID: "inst-creating", CreatedAt: now},
+	}))
+	for _, instance := range []models.GameInstance{
+		{ID: "inst-healthy", NodeID: "node-1", Status: models.GameInstanceStateRunning, StartedAt: old},
+		{ID: "inst-missing", NodeID: "node-1", Status: models.GameInstanceStateRunning, StartedAt: old},
+		{ID: "inst-starting", NodeID: "node-1", Status: models.GameInstanceStateRunning, StartedAt: now},
+	} {
+		require.NoError(t, instanceStore.Add(ctx, instance))
+	}
+
+	reports, err := inventory.Reconcile(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	report := reports[0]
+	assert.Equal(t, 3, report.Containers)
+	assert.Equal(t, 3, report.Instances)
+
+	// 宽限期内的实例和容器不参与核对
+	require.Len(t, report.Issues, 2)
+	assert.Equal(t, models.InventoryIssueMissingContainer, report.Issues[0].Type)
+	assert.Equal(t, "inst-missing", report.Issues[0].InstanceID)
+	assert.True(t, report.Issues[0].CleanedUp)
+	assert.Equal(t, models.InventoryIssueOrphanContainer, report.Issues[1].Type)
+	assert.Equal(t, "c-orphan", report.Issues[1].ContainerID)
+	assert.Equal(t, []string{"c-orphan"}, remover.removed)
+
+	instance, err := instances.Get(ctx, "inst-missing")
+	require.NoError(t, err)
+	assert.Equal(t, models.GameInstanceStateFailed, instance.Status)
+
+	saved, ok := inventory.Report("node-1")
+	require.True(t, ok)
+	assert.Equal(t, report.CheckedAt, saved.CheckedAt)
+}
+
+func TestInventoryRecoverLost(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(dir, "nodes.yaml"))
+	require.NoError(t, err)
+	// 实例存储延迟落盘，预先创建数据文件
+	instanceFile := filepath.Join(dir, "instances.yaml")
+	require.NoError(t, os.WriteFile(instanceFile, []byte("[]\n"), 0644))
+	instanceStore, err := store.NewGameInstanceStore(ctx, instanceFile, utils.New("InventoryTest"))
+	require.NoError(t, err)
+	pipelineStore := store.NewYAMLGamePipelineStore(ctx, filepath.Join(dir, "pipelines.yaml"))
+	t.Cleanup(pipelineStore.Close)
+
+	nodes := NewGameNodeService(nodeStore)
+	instances := NewGameInstanceService(instanceStore)
+	pipelines := NewGamePipelineService(pipelineStore)
+	inventory := NewInventoryService(nodes, instances, pipelines, InventoryOptions{Grace: time.Minute})
+	nodes.OnContainersUpdate(inventory.HandleContainers)
+	var transitions []string
+	instances.OnStateChange(func(ctx context.Context, instance models.GameInstance, from models.GameInstanceState) {
+		transitions = append(transitions, instance.ID+":"+string(from)+"->"+string(instance.Status))
+	})
+
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, nodes.Create(ctx, models.GameNode{ID: "node-1"}))
+	require.NoError(t, nodes.UpdateStatusOnlineStatus(ctx, "node-1", true))
+	// 启动流水线尚未结束的实例由流水线上报继续更新
+	require.NoError(t, pipelineStore.Add(ctx, &models.GamePipeline{ID: "pipe-start", InstanceID: "inst-starting",
+		Status: &models.PipelineStatus{State: models.PipelineStateRunning}}))
+	for _, instance := range []models.GameInstance{
+		{ID: "inst-alive", NodeID: "node-1", Status: models.GameInstanceStateRunning, StartedAt: old},
+		{ID: "inst-crashed", NodeID: "node-1", Status: models.GameInstanceStateRunning, StartedAt: old},
+		{ID: "inst-starting", NodeID: "node-1", Status: models.GameInstanceStateStarting, PipelineID: "pipe-start"},
+		{ID: "inst-other", NodeID: "node-2", Status: models.GameInstanceStateRunning, StartedAt: old},
+	} {
+		require.NoError(t, instanceStore.Add(ctx, instance))
+	}
+
+	// 节点离线
+	count, err := instances.MarkNodeLost(ctx, "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	require.NoError(t, nodes.UpdateStatusOnlineStatus(ctx, "node-1", false))
+
+	// 节点恢复后上报容器清单
+	require.NoError(t, nodes.UpdateStatusOnlineStatus(ctx, "node-1", true))
+	require.NoError(t, nodes.UpdateStatusContainers(ctx, "node-1", []models.ContainerInfo{
+		{ID: "c-alive", State: "running", InstanceID: "inst-alive", CreatedAt: old},
+		{ID: "c-crashed", State: "exited", InstanceID: "inst-crashed", CreatedAt: old},
+	}))
+
+	get := func(id string) models.GameInstance {
+		instance, err := instances.Get(ctx, id)
+		require.NoError(t, err)
+		return instance
+	}
+	assert.Equal(t, models.GameInstanceStateRunning, get("inst-alive").Status)
+	crashed := get("inst-crashed")
+	assert.Equal(t, models.GameInstanceStateFailed, crashed.Status)
+	assert.Equal(t, models.StopReasonFailed, crashed.StopReason)
+	assert.Equal(t, models.GameInstanceStateLost, get("inst-starting").Status)
+	assert.Equal(t, models.GameInstanceStateRunning, get("inst-other").Status)
+	assert.Equal(t, []string{
+		"inst-alive:running->lost",
+		"inst-crashed:running->lost",
+		"inst-starting:starting->lost",
+		"inst-alive:lost->running",
+		"inst-crashed:lost->failed",
+	}, transitions)
+
+	// 启动流水线结束后仍处于失联状态的实例由容器清单核对处理
+	pipeline, err := pipelineStore.Get(ctx, "pipe-start")
+	require.NoError(t, err)
+	pipeline.Status.State = models.PipelineStateFailed
+	require.NoError(t, pipelineStore.Update(ctx, pipeline))
+	reports, err := inventory.Reconcile(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Len(t, reports[0].Issues, 1)
+	assert.Equal(t, "inst-starting", reports[0].Issues[0].InstanceID)
+	assert.Equal(t, models.GameInstanceStateFailed, get("inst-starting").Status)
+
+	// 失联的实例可以重新启动
+	assert.True(t, models.GameInstanceStateLost.CanTransitionTo(models.GameInstanceStatePreparing))
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// defaultJoinTokenTTL 未指定有效期时加入令牌的有效期
+const defaultJoinTokenTTL = 24 * time.Hour
+
+// ErrInvalidJoinToken 加入令牌不存在、已过期、已吊销或已用完
+var ErrInvalidJoinToken = errors.New("无效的加入令牌")
+
+// JoinTokenService 节点加入令牌服务
+type JoinTokenService struct {
+	store  store.JoinTokenStore
+	logger utils.Logger
+	// mu 保证令牌使用次数的检查和累加是原子的
+	mu sync.Mutex
+}
+
+// NewJoinTokenService 创建节点加入令牌服务
+func NewJoinTokenService(store store.JoinTokenStore) *JoinTokenService {
+	return &JoinTokenService{
+		store:  store,
+		logger: utils.New("JoinTokenService"),
+	}
+}
+
+// CreateJoinTokenParams 创建加入令牌参数
+type CreateJoinTokenParams struct {
+	Description string `json:"description"`
+	// TTL 有效期，如 "24h"，为空时使用默认有效期
+	TTL string `json:"ttl"`
+	// MaxUses 最大使用次数，0 表示不限
+	MaxUses int `json:"max_uses" binding:"omitempty,min=0"`
+}
+
+// CreateJoinTokenResult 创建加入令牌结果，Token 为令牌明文，只返回这一次
+type CreateJoinTokenResult struct {
+	Token     string            `json:"token"`
+	JoinToken *models.JoinToken `json:"join_token"`
+}
+
+// Create 创建加入令牌
+func (s *JoinTokenService) Create(ctx context.Context, params CreateJoinTokenParams) (*CreateJoinTokenResult, error) {
+	ttl := defaultJoinTokenTTL
+	if params.TTL != "" {
+		d, err := time.ParseDuration(params.TTL)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("无效的有效期: %s", params.TTL)
+		}
+		ttl = d
+	}
+	if params.MaxUses < 0 {
+		return nil, fmt.Errorf("最大使用次数不能为负数")
+	}
+
+	id, err := generateSecret(6)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	token := &models.JoinToken{
+		ID:          id,
+		SecretHash:  hashSecret(secret),
+		Description: params.Description,
+		ExpiresAt:   now.Add(ttl),
+		MaxUses:     params.MaxUses,
+		CreatedAt:   now,
+	}
+	if err := s.store.Add(ctx, token); err != nil {
+		s.logger.Error("保存加入令牌失败: %v", err)
+		return nil, fmt.Errorf("存储层错误: %w", err)
+	}
+
+	s.logger.Info("创建加入令牌: id=%s, 过期时间=%s, 最大使用次数=%d", id, token.ExpiresAt.Format(time.RFC3339), token.MaxUses)
+	return &CreateJoinTokenResult{
+		Token:     id + "." + secret,
+		JoinToken: token,
+	}, nil
+}
+
+// List 获取所有加入令牌
+func (s *JoinTokenService) List(ctx context.Context) ([]*models.JoinToken, error) {
+	tokens, err := s.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("存储层错误: %w", err)
+	}
+	return tokens, nil
+}
+
+// Revoke 吊销加入令牌，已使用该令牌加入的节点不受影响
+func (s *JoinTokenService) Revoke(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.store.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("加入令牌不存在: %s", id)
+	}
+	token.Revoked = true
+	if err := s.store.Update(ctx, token); err != nil {
+		return fmt.Errorf("存储层错误: %w", err)
+	}
+
+	s.logger.Info("吊销加入令牌: %s", id)
+	return nil
+}
+
+// Consume 校验加入令牌并记录一次使用
+func (s *JoinTokenService) Consume(ctx context.Context, raw string, nodeID string) error {
+	id, secret, ok := strings.Cut(raw, ".")
+	if !ok || id == "" || secret == "" {
+		return ErrInvalidJoinToken
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.store.Get(ctx, id)
+	if err != nil || !secretMatches(token.SecretHash, secret) {
+		s.logger.Warn("节点 %s 使用了无效的加入令牌: %s", nodeID, id)
+		return ErrInvalidJoinToken
+	}
+	if !token.Usable(time.Now()) {
+		s.logger.Warn("节点 %s 使用了不可用的加入令牌: %s", nodeID, id)
+		return ErrInvalidJoinToken
+	}
+
+	token.Uses++
+	token.UsedBy = append(token.UsedBy, nodeID)
+	if err := s.store.Update(ctx, token); err != nil {
+		return fmt.Errorf("存储层错误: %w", err)
+	}
+
+	s.logger.Info("节点 %s 使用加入令牌 %s 加入集群", nodeID, id)
+	return nil
+}
+
+// generateSecret 生成 n 字节的随机值，返回十六进制字符串
+func generateSecret(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashSecret 计算密钥的 SHA-256 摘要
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// secretMatches 以常量时间比较密钥与摘要
+func secretMatches(hash, secret string) bool {
+	if hash == "" || secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashSecret(secret))) == 1
+}
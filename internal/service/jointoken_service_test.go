@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+)
+
+func newTestJoinTokenService(t *testing.T) *JoinTokenService {
+	t.Helper()
+	tokenStore := store.NewYAMLJoinTokenStore(context.Background(), filepath.Join(t.TempDir(), "jointokens.yaml"))
+	t.Cleanup(tokenStore.Close)
+	return NewJoinTokenService(tokenStore)
+}
+
+func TestJoinTokenConsume(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestJoinTokenService(t)
+
+	result, err := svc.Create(ctx, CreateJoinTokenParams{MaxUses: 1})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, svc.Consume(ctx, result.JoinToken.ID+".wrong", "node-1"), ErrInvalidJoinToken)
+	assert.NoError(t, svc.Consume(ctx, result.Token, "node-1"))
+	// 超过最大使用次数
+	assert.ErrorIs(t, svc.Consume(ctx, result.Token, "node-2"), ErrInvalidJoinToken)
+
+	revoked, err := svc.Create(ctx, CreateJoinTokenParams{})
+	require.NoError(t, err)
+	require.NoError(t, svc.Revoke(ctx, revoked.JoinToken.ID))
+	assert.ErrorIs(t, svc.Consume(ctx, revoked.Token, "node-3"), ErrInvalidJoinToken)
+
+	_, err = svc.Create(ctx, CreateJoinTokenParams{TTL: "-1h"})
+	assert.Error(t, err)
+}
+
+func TestGameNodeAdmit(t *testing.T) {
+	ctx := context.Background()
+	joinTokens := newTestJoinTokenService(t)
+	nodes := &GameNodeService{joinTokens: joinTokens, logger: joinTokens.logger}
+
+	// 无令牌、无凭证的新节点等待审批
+	node := &models.GameNode{ID: "node-1"}
+	_, err := nodes.Admit(ctx, node, "", "")
+	assert.ErrorIs(t, err, ErrNodePendingApproval)
+	assert.Equal(t, models.NodeApprovalPending, node.Approval)
+
+	// 有效令牌直接批准并签发凭证
+	token, err := joinTokens.Create(ctx, CreateJoinTokenParams{})
+	require.NoError(t, err)
+	credential, err := nodes.Admit(ctx, node, token.Token, "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, credential)
+	assert.Equal(t, models.NodeApprovalApproved, node.Approval)
+
+	// 持有凭证后注册不再签发新凭证
+	reissued, err := nodes.Admit(ctx, node, "", credential)
+	require.NoError(t, err)
+	assert.Empty(t, reissued)
+
+	// 冒用节点ID
+	_, err = nodes.Admit(ctx, node, "", "")
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+	_, err = nodes.Admit(ctx, node, "", "forged")
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+	// 加入令牌不能替换已签发的凭证
+	hash := node.CredentialHash
+	_, err = nodes.Admit(ctx, node, token.Token, "")
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+	_, err = nodes.Admit(ctx, node, token.Token, "forged")
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+	assert.Equal(t, hash, node.CredentialHash)
+
+	// 管理员拒绝后重新批准时凭证被清除，节点可以使用加入令牌重新加入
+	node.CredentialHash = ""
+	reissued, err = nodes.Admit(ctx, node, token.Token, "forged")
+	require.NoError(t, err)
+	assert.NotEmpty(t, reissued)
+
+	// 管理员批准但尚未签发凭证的节点
+	approved := &models.GameNode{ID: "node-2", Approval: models.NodeApprovalApproved}
+	credential, err = nodes.Admit(ctx, approved, "", "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, credential)
+
+	rejected := &models.GameNode{ID: "node-3", Approval: models.NodeApprovalRejected}
+	_, err = nodes.Admit(ctx, rejected, token.Token, "")
+	assert.ErrorIs(t, err, ErrNodeRejected)
+}
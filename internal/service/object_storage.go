@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound 对象存储中不存在指定对象
+var ErrObjectNotFound = errors.New("对象不存在")
+
+const (
+	// s3Service SigV4 签名使用的服务名称
+	s3Service = "s3"
+	// s3EmptyPayloadHash 空请求体的 SHA-256
+	s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// s3MetadataPrefix 用户元数据的响应头前缀
+	s3MetadataPrefix = "X-Amz-Meta-"
+	// s3RequestTimeout 单次请求的超时时间
+	s3RequestTimeout = 30 * time.Second
+)
+
+// ObjectInfo 对象的元信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	// Metadata 用户元数据，键为小写，例如上传时 --attr "sha256=..." 对应 sha256
+	Metadata map[string]string
+}
+
+// ObjectStorage 存档同步使用的对象存储操作，存档内容由节点上的流水线直接上传和下载
+type ObjectStorage interface {
+	// Stat 获取对象的元信息，对象不存在时返回 ErrObjectNotFound
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Copy 在存储桶内复制对象，保留源对象的元数据
+	Copy(ctx context.Context, src, dst string) error
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// S3Options S3 兼容存储的连接配置
+type S3Options struct {
+	// Endpoint 服务地址，例如 http://minio:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3Storage 使用 SigV4 签名访问 S3 兼容存储，按路径风格寻址存储桶
+type S3Storage struct {
+	endpoint *url.URL
+	opts     S3Options
+	client   *http.Client
+	// now 签名使用的当前时间
+	now func() time.Time
+}
+
+// NewS3Storage 创建 S3 兼容存储客户端
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, fmt.Errorf("对象存储地址和存储桶不能为空")
+	}
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, fmt.Errorf("对象存储访问密钥不能为空")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(opts.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的对象存储地址: %s", opts.Endpoint)
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	return &S3Storage{
+		endpoint: endpoint,
+		opts:     opts,
+		client:   &http.Client{Timeout: s3RequestTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// Stat 获取对象的元信息
+func (s *S3Storage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ObjectInfo{}, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ObjectInfo{}, fmt.Errorf("获取对象 %s 失败: %s", key, resp.Status)
+	}
+
+	info := ObjectInfo{
+		Key:      key,
+		Size:     resp.ContentLength,
+		ETag:     strings.Trim(resp.Header.Get("ETag"), `"`),
+		Metadata: make(map[string]string),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified
+	}
+	for name, values := range resp.Header {
+		if strings.HasPrefix(name, s3MetadataPrefix) && len(values) > 0 {
+			info.Metadata[strings.ToLower(strings.TrimPrefix(name, s3MetadataPrefix))] = values[0]
+		}
+	}
+	return info, nil
+}
+
+// Copy 在存储桶内复制对象
+func (s *S3Storage) Copy(ctx context.Context, src, dst string) error {
+	resp, err := s.do(ctx, http.MethodPut, dst, map[string]string{
+		"x-amz-copy-source":        s.objectPath(src),
+		"x-amz-metadata-directive": "COPY",
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 复制失败时服务端可能返回 200 和错误内容
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, src)
+	}
+	if resp.StatusCode != http.StatusOK || strings.Contains(string(body), "<Error>") {
+		return fmt.Errorf("复制对象 %s 到 %s 失败: %s %s", src, dst, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Delete 删除对象
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("删除对象 %s 失败: %s", key, resp.Status)
+	}
+	return nil
+}
+
+// do 签名并发送不带请求体的请求
+func (s *S3Storage) do(ctx context.Context, method, key string, headers map[string]string) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + s.opts.Bucket + "/" + key
+	u.RawPath = s.endpoint.EscapedPath() + s.objectPath(key)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建对象存储请求失败: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	s.sign(req, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求对象存储失败: %w", err)
+	}
+	return resp, nil
+}
+
+// objectPath 对象在存储桶中的编码路径，格式为 /bucket/key
+func (s *S3Storage) objectPath(key string) string {
+	return "/" + s3Escape(s.opts.Bucket) + "/" + s3Escape(key)
+}
+
+// sign 按 AWS Signature Version 4 为请求添加 Authorization 请求头
+func (s *S3Storage) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	if req.Header.Get("x-amz-content-sha256") == "" {
+		req.Header.Set("x-amz-content-sha256", s3EmptyPayloadHash)
+	}
+
+	// 签名 Host 和所有 x-amz-* 请求头
+	signed := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			signed[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signed[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+	scope := date + "/" + s.opts.Region + "/" + s3Service + "/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape 按 SigV4 的规则编码对象路径，保留 / 和非保留字符
+func s3Escape(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// 端口分配相关错误
+var (
+	ErrInvalidPortRange = errors.New("无效的端口范围")
+	ErrNoFreePort       = errors.New("节点没有可用的端口")
+)
+
+// PortAllocator 按节点为实例分配主机端口
+// 端口预留持久化保存，实例停止、失败或删除时释放；
+// 节点上报的容器端口映射用于核对预留，被其他容器占用的端口在占用期间不参与分配
+type PortAllocator struct {
+	store  store.PortReservationStore
+	start  int
+	end    int
+	logger utils.Logger
+
+	// mu 保证端口的选择和预留是原子的
+	mu sync.Mutex
+	// occupied 节点上被没有预留的容器占用的端口，每次核对节点时重建
+	occupied map[string]map[int]string
+}
+
+// NewPortAllocator 创建端口分配器，端口范围为 [start, end]
+func NewPortAllocator(store store.PortReservationStore, start, end int) (*PortAllocator, error) {
+	if start <= 0 || end > 65535 || start > end {
+		return nil, fmt.Errorf("%w: %d-%d", ErrInvalidPortRange, start, end)
+	}
+	return &PortAllocator{
+		store:    store,
+		start:    start,
+		end:      end,
+		logger:   utils.New("PortAllocator"),
+		occupied: make(map[string]map[int]string),
+	}, nil
+}
+
+// Allocate 在节点上为实例分配端口
+// 实例在该节点上已有预留时直接返回，在其他节点上的预留被释放
+func (a *PortAllocator) Allocate(ctx context.Context, nodeID, instanceID string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	reservations, err := a.store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取端口预留失败: %w", err)
+	}
+
+	used := make(map[int]bool)
+	for port := range a.occupied[nodeID] {
+		used[port] = true
+	}
+	for _, reservation := range reservations {
+		if reservation.InstanceID == instanceID {
+			if reservation.NodeID == nodeID {
+				return reservation.Port, nil
+			}
+			if err := a.store.Delete(ctx, reservation.NodeID, reservation.Port); err != nil {
+				return 0, fmt.Errorf("释放端口预留失败: %w", err)
+			}
+			a.logger.Info("实例 %s 已转移到节点 %s，释放节点 %s 的端口 %d", instanceID, nodeID, reservation.NodeID, reservation.Port)
+			continue
+		}
+		if reservation.NodeID == nodeID {
+			used[reservation.Port] = true
+		}
+	}
+
+	for port := a.start; port <= a.end; port++ {
+		if used[port] {
+			continue
+		}
+		reservation := models.PortReservation{
+			NodeID:     nodeID,
+			Port:       port,
+			InstanceID: instanceID,
+			CreatedAt:  time.Now(),
+		}
+		if err := a.store.Add(ctx, reservation); err != nil {
+			return 0, fmt.Errorf("保存端口预留失败: %w", err)
+		}
+		a.logger.Info("为实例 %s 分配节点 %s 的端口 %d", instanceID, nodeID, port)
+		return port, nil
+	}
+	return 0, fmt.Errorf("%w: %s(%d-%d)", ErrNoFreePort, nodeID, a.start, a.end)
+}
+
+// Release 释放实例在所有节点上的端口预留，返回释放的数量
+func (a *PortAllocator) Release(ctx context.Context, instanceID string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	reservations, err := a.store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取端口预留失败: %w", err)
+	}
+	count := 0
+	for _, reservation := range reservations {
+		if reservation.InstanceID != instanceID {
+			continue
+		}
+		if err := a.store.Delete(ctx, reservation.NodeID, reservation.Port); err != nil {
+			return count, fmt.Errorf("释放端口预留失败: %w", err)
+		}
+		a.logger.Info("释放实例 %s 在节点 %s 的端口 %d", instanceID, reservation.NodeID, reservation.Port)
+		count++
+	}
+	return count, nil
+}
+
+// Reservations 获取节点上的端口预留，nodeID 为空时返回所有节点的预留
+func (a *PortAllocator) Reservations(ctx context.Context, nodeID string) ([]models.PortReservation, error) {
+	reservations, err := a.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取端口预留失败: %w", err)
+	}
+	if nodeID == "" {
+		return reservations, nil
+	}
+	result := make([]models.PortReservation, 0)
+	for _, reservation := range reservations {
+		if reservation.NodeID == nodeID {
+			result = append(result, reservation)
+		}
+	}
+	return result, nil
+}
+
+// Reconcile 使用节点上报的容器端口映射核对节点的端口预留，返回发现的端口冲突
+//   - 运行中的实例容器占用了端口但实例没有预留时，为实例补充预留（例如预留数据丢失）
+//   - 其他容器占用的端口记为已占用，占用期间不参与分配
+//   - 实例已删除、已停止或已转移到其他节点，且端口不再被占用的预留被释放；
+//     cutoff 之后创建的预留不释放，避免与核对期间的分配竞争
+func (a *PortAllocator) Reconcile(ctx context.Context, nodeID string, containers []models.ContainerInfo, instances []models.GameInstance, cutoff time.Time) ([]models.InventoryIssue, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	reservations, err := a.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取端口预留失败: %w", err)
+	}
+	byPort := make(map[int]models.PortReservation)
+	reserved := make(map[string]bool)
+	for _, reservation := range reservations {
+		if reservation.NodeID == nodeID {
+			byPort[reservation.Port] = reservation
+			reserved[reservation.InstanceID] = true
+		}
+	}
+	active := make(map[string]bool)
+	for _, instance := range instances {
+		if instance.NodeID == nodeID && instance.Status.ReservesResources() {
+			active[instance.ID] = true
+		}
+	}
+
+	bound := make(map[int]models.ContainerInfo)
+	for _, c := range containers {
+		if c.State != "running" {
+			continue
+		}
+		for _, p := range c.Ports {
+			if p.PublicPort > 0 {
+				bound[int(p.PublicPort)] = c
+			}
+		}
+	}
+	// 按端口排序，保证结果稳定
+	ports := make([]int, 0, len(bound))
+	for port := range bound {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	issues := make([]models.InventoryIssue, 0)
+	occupied := make(map[int]string)
+	for _, port := range ports {
+		c := bound[port]
+		reservation, ok := byPort[port]
+		switch {
+		case ok && reservation.InstanceID == c.InstanceID:
+		case ok:
+			issues = append(issues, models.InventoryIssue{
+				Type:        models.InventoryIssuePortConflict,
+				NodeID:      nodeID,
+				InstanceID:  reservation.InstanceID,
+				ContainerID: c.ID,
+				Container:   c.Name,
+				PipelineID:  c.PipelineID,
+				Message:     fmt.Sprintf("端口 %d 预留给实例 %s，但被实例 %s 的容器占用", port, reservation.InstanceID, c.InstanceID),
+			})
+		case active[c.InstanceID] && !reserved[c.InstanceID] && port >= a.start && port <= a.end:
+			reservation = models.PortReservation{NodeID: nodeID, Port: port, InstanceID: c.InstanceID, CreatedAt: time.Now()}
+			if err := a.store.Add(ctx, reservation); err != nil {
+				return issues, fmt.Errorf("保存端口预留失败: %w", err)
+			}
+			byPort[port] = reservation
+			reserved[c.InstanceID] = true
+			a.logger.Warn("节点 %s 的端口 %d 被实例 %s 的容器占用但没有预留，已补充预留", nodeID, port, c.InstanceID)
+		default:
+			occupied[port] = c.Name
+		}
+	}
+	a.occupied[nodeID] = occupied
+
+	for port, reservation := range byPort {
+		if active[reservation.InstanceID] || reservation.CreatedAt.After(cutoff) {
+			continue
+		}
+		if c, ok := bound[port]; ok && c.InstanceID == reservation.InstanceID {
+			continue
+		}
+		if err := a.store.Delete(ctx, nodeID, port); err != nil {
+			return issues, fmt.Errorf("释放端口预留失败: %w", err)
+		}
+		a.logger.Info("实例 %s 不再使用节点 %s 的端口 %d，释放预留", reservation.InstanceID, nodeID, port)
+	}
+	return issues, nil
+}
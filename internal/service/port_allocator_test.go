@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+)
+
+func TestPortAllocator(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ports.yaml")
+	portStore := store.NewYAMLPortReservationStore(ctx, path)
+	ports, err := NewPortAllocator(portStore, 30000, 30002)
+	require.NoError(t, err)
+
+	_, err = NewPortAllocator(portStore, 30002, 30000)
+	assert.ErrorIs(t, err, ErrInvalidPortRange)
+
+	// 每个节点独立分配，同一实例重复分配返回同一端口
+	port, err := ports.Allocate(ctx, "node-1", "inst-a")
+	require.NoError(t, err)
+	assert.Equal(t, 30000, port)
+	port, err = ports.Allocate(ctx, "node-1", "inst-b")
+	require.NoError(t, err)
+	assert.Equal(t, 30001, port)
+	port, err = ports.Allocate(ctx, "node-1", "inst-a")
+	require.NoError(t, err)
+	assert.Equal(t, 30000, port)
+	port, err = ports.Allocate(ctx, "node-2", "inst-c")
+	require.NoError(t, err)
+	assert.Equal(t, 30000, port)
+
+	// 节点上报的其他容器占用的端口不参与分配
+	issues, err := ports.Reconcile(ctx, "node-1", []models.ContainerInfo{
+		{ID: "c-1", Name: "other", State: "running", InstanceID: "unknown", Ports: []models.ContainerPort{{PrivatePort: 8080, PublicPort: 30002}}},
+	}, nil, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+	_, err = ports.Allocate(ctx, "node-1", "inst-d")
+	assert.ErrorIs(t, err, ErrNoFreePort)
+
+	// 实例转移到其他节点时释放原节点的预留
+	port, err = ports.Allocate(ctx, "node-2", "inst-b")
+	require.NoError(t, err)
+	assert.Equal(t, 30001, port)
+	reservations, err := ports.Reservations(ctx, "node-1")
+	require.NoError(t, err)
+	require.Len(t, reservations, 1)
+	assert.Equal(t, "inst-a", reservations[0].InstanceID)
+
+	count, err := ports.Release(ctx, "inst-a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// 预留在服务端重启后保留
+	portStore.Close()
+	reloaded, err := NewPortAllocator(store.NewYAMLPortReservationStore(ctx, path), 30000, 30002)
+	require.NoError(t, err)
+	reservations, err = reloaded.Reservations(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []models.PortReservation{
+		{NodeID: "node-2", Port: 30000, InstanceID: "inst-c", CreatedAt: reservations[0].CreatedAt},
+		{NodeID: "node-2", Port: 30001, InstanceID: "inst-b", CreatedAt: reservations[1].CreatedAt},
+	}, reservations)
+}
+
+func TestPortAllocatorReconcile(t *testing.T) {
+	ctx := context.Background()
+	ports, err := NewPortAllocator(store.NewYAMLPortReservationStore(ctx, filepath.Join(t.TempDir(), "ports.yaml")), 30000, 30009)
+	require.NoError(t, err)
+
+	for _, id := range []string{"running", "stopped", "conflict"} {
+		_, err := ports.Allocate(ctx, "node-1", id)
+		require.NoError(t, err)
+	}
+	instances := []models.GameInstance{
+		{ID: "running", NodeID: "node-1", Status: models.GameInstanceStateRunning},
+		{ID: "stopped", NodeID: "node-1", Status: models.GameInstanceStateStopped},
+		{ID: "conflict", NodeID: "node-1", Status: models.GameInstanceStateRunning},
+		{ID: "adopted", NodeID: "node-1", Status: models.GameInstanceStateRunning},
+	}
+	container := func(instanceID string, port uint16) models.ContainerInfo {
+		return models.ContainerInfo{
+			ID:         "c-" + instanceID,
+			Name:       instanceID,
+			State:      "running",
+			InstanceID: instanceID,
+			Ports:      []models.ContainerPort{{PrivatePort: 8080, PublicPort: port, Protocol: "tcp"}},
+		}
+	}
+	containers := []models.ContainerInfo{
+		container("running", 30000),
+		container("other", 30002),   // 占用预留给 conflict 的端口
+		container("adopted", 30005), // 实例没有预留
+	}
+
+	// cutoff 之前创建的预留才会被释放
+	issues, err := ports.Reconcile(ctx, "node-1", containers, instances, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, models.InventoryIssuePortConflict, issues[0].Type)
+	assert.Equal(t, "conflict", issues[0].InstanceID)
+	assert.Equal(t, "c-other", issues[0].ContainerID)
+	reservations, err := ports.Reservations(ctx, "node-1")
+	require.NoError(t, err)
+	assert.Len(t, reservations, 4)
+
+	_, err = ports.Reconcile(ctx, "node-1", containers, instances, time.Now())
+	require.NoError(t, err)
+	reservations, err = ports.Reservations(ctx, "node-1")
+	require.NoError(t, err)
+	owners := make(map[int]string)
+	for _, reservation := range reservations {
+		owners[reservation.Port] = reservation.InstanceID
+	}
+	assert.Equal(t, map[int]string{30000: "running", 30002: "conflict", 30005: "adopted"}, owners)
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// ErrSaveUploading 上一次游戏的存档仍在上传
+var ErrSaveUploading = errors.New("存档正在上传")
+
+const (
+	// saveUploadTemplate 上传实例存档的流水线模板
+	saveUploadTemplate = "save-upload"
+	// saveChecksumMetadata 存档对象上记录 SHA-256 的用户元数据，由上传流水线写入
+	saveChecksumMetadata = "sha256"
+	// saveCommitTimeout 上传完成后提交存档的超时时间
+	saveCommitTimeout = 2 * time.Minute
+)
+
+// SaveOptions 存档同步配置
+type SaveOptions struct {
+	// Prefix 存档在存储桶中的路径前缀，存档位于 <prefix>/<用户>/<卡片>/ 下
+	Prefix string
+	// S3 节点上的流水线上传和下载存档使用的连接配置
+	S3 S3Options
+}
+
+// SaveService 在实例和对象存储之间同步游戏存档
+// 启动前确定用户的最新存档由启动流水线恢复，实例停止或崩溃后提交上传流水线，
+// 上传完成后按校验和检测冲突：存档未变化时丢弃，最新存档在游戏期间被其他实例更新时
+// 本次存档另存为冲突副本，否则替换为用户的最新存档
+type SaveService struct {
+	storage   ObjectStorage
+	instances *GameInstanceService
+	pipelines *GamePipelineService
+	cards     *GameCardService
+	opts      SaveOptions
+	logger    utils.Logger
+}
+
+// NewSaveService 创建存档同步服务
+func NewSaveService(storage ObjectStorage, instances *GameInstanceService, pipelines *GamePipelineService, cards *GameCardService, opts SaveOptions) *SaveService {
+	opts.Prefix = strings.Trim(opts.Prefix, "/")
+	if opts.Prefix == "" {
+		opts.Prefix = "saves"
+	}
+	return &SaveService{
+		storage:   storage,
+		instances: instances,
+		pipelines: pipelines,
+		cards:     cards,
+		opts:      opts,
+		logger:    utils.New("SaveService"),
+	}
+}
+
+// Prepare 确定实例本次启动要恢复的存档，实例没有用户或卡片没有存档路径时返回 nil
+// 上一次上传失败时节点上的存档比对象存储中的新，本次不恢复，冲突检测仍以上一次恢复的存档为准
+func (s *SaveService) Prepare(ctx context.Context, instance models.GameInstance) (*models.SaveState, error) {
+	if instance.UserID == "" {
+		return nil, nil
+	}
+	card, err := s.cards.Get(ctx, instance.CardID)
+	if err != nil {
+		return nil, fmt.Errorf("获取卡片失败: %w", err)
+	}
+	if len(card.SavePaths) == 0 {
+		return nil, nil
+	}
+	previous := instance.Save
+	if previous != nil && previous.Status == models.SaveSyncUploading {
+		return nil, fmt.Errorf("%w: %s", ErrSaveUploading, previous.Upload)
+	}
+	for _, name := range []string{instance.UserID, card.ID, instance.ID} {
+		if err := models.ValidateSaveName(name); err != nil {
+			return nil, fmt.Errorf("无法同步存档: %w", err)
+		}
+	}
+
+	state := &models.SaveState{
+		Status:     models.SaveSyncPrepared,
+		Paths:      card.SavePaths,
+		Object:     s.latestKey(instance.UserID, card.ID),
+		PreparedAt: time.Now(),
+	}
+	if previous != nil && previous.Status == models.SaveSyncFailed && previous.Object == state.Object {
+		state.BaseChecksum = previous.BaseChecksum
+		state.BaseETag = previous.BaseETag
+		state.Message = "上一次存档未上传，保留节点上的存档"
+		s.logger.Warn("实例 %s 上一次存档未上传，本次启动不恢复存档", instance.ID)
+		return state, nil
+	}
+
+	info, err := s.storage.Stat(ctx, state.Object)
+	switch {
+	case errors.Is(err, ErrObjectNotFound):
+		s.logger.Info("用户 %s 没有卡片 %s 的存档", instance.UserID, card.ID)
+	case err != nil:
+		return nil, fmt.Errorf("获取用户存档失败: %w", err)
+	default:
+		state.Restore = true
+		state.BaseChecksum = info.Metadata[saveChecksumMetadata]
+		state.BaseETag = info.ETag
+		s.logger.Info("实例 %s 启动时恢复存档 %s", instance.ID, state.Object)
+	}
+	return state, nil
+}
+
+// RestoreArgs 启动流水线恢复存档使用的参数，state 为 nil 时不恢复存档
+func (s *SaveService) RestoreArgs(state *models.SaveState) map[string]string {
+	args := emptySaveArgs()
+	for name, value := range s.connectionArgs() {
+		args[name] = value
+	}
+	if state != nil {
+		args["SAVE_PATHS"] = strings.Join(state.Paths, " ")
+		if state.Restore {
+			args["SAVE_OBJECT"] = state.Object
+			args["SAVE_CHECKSUM"] = state.BaseChecksum
+		}
+	}
+	return args
+}
+
+// HandleInstanceState 实例停止或运行中崩溃后上传存档
+// 实例未运行就停止或失败时节点上的存档没有变化，不上传
+func (s *SaveService) HandleInstanceState(ctx context.Context, instance models.GameInstance, from models.GameInstanceState) {
+	if instance.Save == nil || instance.Save.Status != models.SaveSyncPrepared {
+		return
+	}
+	switch instance.Status {
+	case models.GameInstanceStateStopped, models.GameInstanceStateFailed:
+	default:
+		return
+	}
+	// 状态变更处理函数在持有实例锁时执行，上传在后台提交
+	go s.finish(context.Background(), instance)
+}
+
+// HandlePipelineState 上传流水线结束后提交存档
+func (s *SaveService) HandlePipelineState(ctx context.Context, pipeline *models.GamePipeline, from models.PipelineState) {
+	if pipeline.InstanceID == "" || pipeline.Dispatch == nil || pipeline.Dispatch.Template != saveUploadTemplate {
+		return
+	}
+	if !pipelineState(pipeline).IsTerminal() {
+		return
+	}
+	// 提交需要访问对象存储，不阻塞流水线状态上报
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), saveCommitTimeout)
+		defer cancel()
+		s.commit(ctx, pipeline)
+	}()
+}
+
+// finish 实例结束后提交上传流水线，实例没有运行过时只更新存档状态
+func (s *SaveService) finish(ctx context.Context, instance models.GameInstance) {
+	state := instance.Save
+	if instance.StartedAt.Before(state.PreparedAt) {
+		s.update(ctx, instance.ID, state.PreparedAt, func(state *models.SaveState) {
+			state.Status = models.SaveSyncUnchanged
+			state.Message = "实例未运行，未上传存档"
+		})
+		return
+	}
+
+	upload := path.Join(path.Dir(state.Object), "uploads", instance.ID+"-"+strconv.FormatInt(time.Now().Unix(), 10)+".tar.gz")
+	s.update(ctx, instance.ID, state.PreparedAt, func(state *models.SaveState) {
+		state.Status = models.SaveSyncUploading
+		state.Upload = upload
+		state.UploadPipelineID = ""
+		state.Message = ""
+	})
+
+	args := s.connectionArgs()
+	args["INSTANCE"] = instance.ID
+	args["SAVE_PATHS"] = strings.Join(state.Paths, " ")
+	args["SAVE_UPLOAD"] = upload
+	pipeline, err := s.pipelines.Submit(ctx, SubmitPipelineParams{
+		Template:   saveUploadTemplate,
+		Args:       args,
+		NodeID:     instance.NodeID,
+		InstanceID: instance.ID,
+		Queue:      true,
+	})
+	if err != nil {
+		s.logger.Error("提交实例 %s 的存档上传流水线失败: %v", instance.ID, err)
+		s.update(ctx, instance.ID, state.PreparedAt, func(state *models.SaveState) {
+			state.Status = models.SaveSyncFailed
+			state.Message = fmt.Sprintf("提交存档上传流水线失败: %v", err)
+		})
+		return
+	}
+
+	s.logger.Info("已提交实例 %s 的存档上传流水线 %s", instance.ID, pipeline.ID)
+	s.update(ctx, instance.ID, state.PreparedAt, func(state *models.SaveState) {
+		if state.Upload == upload && state.UploadPipelineID == "" {
+			state.UploadPipelineID = pipeline.ID
+		}
+	})
+}
+
+// commit 根据上传结果更新用户的最新存档
+func (s *SaveService) commit(ctx context.Context, pipeline *models.GamePipeline) {
+	upload := pipeline.Dispatch.Args["SAVE_UPLOAD"]
+	instance, err := s.instances.Get(ctx, pipeline.InstanceID)
+	if err != nil {
+		s.logger.Warn("存档上传流水线 %s 所属的实例不存在: %v", pipeline.ID, err)
+		return
+	}
+	state := instance.Save
+	if state == nil || state.Status != models.SaveSyncUploading || state.Upload != upload {
+		s.logger.Debug("忽略实例 %s 的过期存档上传流水线 %s", instance.ID, pipeline.ID)
+		return
+	}
+
+	result := s.resolveUpload(ctx, pipeline, state)
+	s.update(ctx, instance.ID, state.PreparedAt, func(state *models.SaveState) {
+		if state.Upload != upload {
+			return
+		}
+		state.Status = result.Status
+		state.Checksum = result.Checksum
+		state.UploadedAt = result.UploadedAt
+		state.Conflict = result.Conflict
+		state.Message = result.Message
+		if state.UploadPipelineID == "" {
+			state.UploadPipelineID = pipeline.ID
+		}
+	})
+}
+
+// resolveUpload 检查上传的存档并与用户的最新存档比较，返回同步结果
+func (s *SaveService) resolveUpload(ctx context.Context, pipeline *models.GamePipeline, state *models.SaveState) models.SaveState {
+	if pipelineState(pipeline) != models.PipelineStateCompleted {
+		message := "存档上传流水线未完成"
+		if pipeline.Status.ErrorMessage != "" {
+			message += ": " + pipeline.Status.ErrorMessage
+		}
+		return models.SaveState{Status: models.SaveSyncFailed, Message: message}
+	}
+
+	uploaded, err := s.storage.Stat(ctx, state.Upload)
+	if errors.Is(err, ErrObjectNotFound) {
+		// 存档目录中没有文件时流水线不上传
+		return models.SaveState{Status: models.SaveSyncEmpty, Message: "存档目录中没有文件"}
+	}
+	if err != nil {
+		return models.SaveState{Status: models.SaveSyncFailed, Message: err.Error()}
+	}
+	checksum := uploaded.Metadata[saveChecksumMetadata]
+	if checksum != "" && checksum == state.BaseChecksum {
+		s.remove(ctx, state.Upload)
+		s.logger.Info("实例 %s 的存档没有变化", pipeline.InstanceID)
+		return models.SaveState{Status: models.SaveSyncUnchanged, Checksum: checksum}
+	}
+
+	latest, err := s.storage.Stat(ctx, state.Object)
+	switch {
+	case errors.Is(err, ErrObjectNotFound):
+		if state.BaseETag != "" {
+			s.logger.Warn("用户的最新存档 %s 在游戏期间被删除", state.Object)
+		}
+	case err != nil:
+		return models.SaveState{Status: models.SaveSyncFailed, Checksum: checksum, Message: err.Error()}
+	case !sameSave(latest, state):
+		conflict := path.Join(path.Dir(state.Object), "conflicts", path.Base(state.Upload))
+		if err := s.storage.Copy(ctx, state.Upload, conflict); err != nil {
+			return models.SaveState{Status: models.SaveSyncFailed, Checksum: checksum, Message: err.Error()}
+		}
+		s.remove(ctx, state.Upload)
+		s.logger.Warn("实例 %s 的存档与最新存档冲突，已另存为 %s", pipeline.InstanceID, conflict)
+		return models.SaveState{
+			Status:     models.SaveSyncConflict,
+			Checksum:   checksum,
+			UploadedAt: time.Now(),
+			Conflict:   conflict,
+			Message:    "游戏期间最新存档已被其他实例更新，本次存档另存为冲突副本",
+		}
+	}
+
+	if err := s.storage.Copy(ctx, state.Upload, state.Object); err != nil {
+		return models.SaveState{Status: models.SaveSyncFailed, Checksum: checksum, Message: err.Error()}
+	}
+	s.remove(ctx, state.Upload)
+	s.logger.Info("实例 %s 的存档已更新为最新存档 %s", pipeline.InstanceID, state.Object)
+	return models.SaveState{Status: models.SaveSyncSynced, Checksum: checksum, UploadedAt: time.Now()}
+}
+
+// update 修改实例的存档状态，实例已重新启动时不修改
+func (s *SaveService) update(ctx context.Context, instanceID string, preparedAt time.Time, update func(state *models.SaveState)) {
+	err := s.instances.updateSave(ctx, instanceID, func(state *models.SaveState) bool {
+		if !state.PreparedAt.Equal(preparedAt) {
+			return false
+		}
+		update(state)
+		return true
+	})
+	if err != nil {
+		s.logger.Error("更新实例 %s 的存档状态失败: %v", instanceID, err)
+	}
+}
+
+// remove 删除暂存的上传对象，失败时只记录日志
+func (s *SaveService) remove(ctx context.Context, key string) {
+	if err := s.storage.Delete(ctx, key); err != nil {
+		s.logger.Warn("删除暂存存档 %s 失败: %v", key, err)
+	}
+}
+
+// latestKey 用户在卡片上的最新存档
+func (s *SaveService) latestKey(userID, cardID string) string {
+	return path.Join(s.opts.Prefix, userID, cardID, "latest.tar.gz")
+}
+
+// connectionArgs 流水线访问对象存储的参数
+func (s *SaveService) connectionArgs() map[string]string {
+	return map[string]string{
+		"SAVE_S3_URL":        s.opts.S3.Endpoint,
+		"SAVE_S3_ACCESS_KEY": s.opts.S3.AccessKey,
+		"SAVE_S3_SECRET_KEY": s.opts.S3.SecretKey,
+		"SAVE_S3_BUCKET":     s.opts.S3.Bucket,
+	}
+}
+
+// emptySaveArgs 启动流水线中存档相关的参数，参数为空时不恢复存档
+func emptySaveArgs() map[string]string {
+	return map[string]string{
+		"SAVE_S3_URL":        "",
+		"SAVE_S3_ACCESS_KEY": "",
+		"SAVE_S3_SECRET_KEY": "",
+		"SAVE_S3_BUCKET":     "",
+		"SAVE_PATHS":         "",
+		"SAVE_OBJECT":        "",
+		"SAVE_CHECKSUM":      "",
+	}
+}
+
+// sameSave 判断最新存档是否仍是启动时恢复的存档，优先比较校验和
+func sameSave(latest ObjectInfo, state *models.SaveState) bool {
+	if state.BaseETag == "" {
+		return false
+	}
+	if checksum := latest.Metadata[saveChecksumMetadata]; checksum != "" && state.BaseChecksum != "" {
+		return checksum == state.BaseChecksum
+	}
+	return latest.ETag == state.BaseETag
+}
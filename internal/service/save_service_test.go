@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// fakeObjectStorage 内存中的对象存储，只保存对象的元信息
+type fakeObjectStorage struct {
+	mu      sync.Mutex
+	objects map[string]ObjectInfo
+}
+
+func (f *fakeObjectStorage) put(key, checksum string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = ObjectInfo{Key: key, ETag: "etag-" + checksum, Metadata: map[string]string{saveChecksumMetadata: checksum}}
+}
+
+func (f *fakeObjectStorage) checksum(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[key].Metadata[saveChecksumMetadata]
+}
+
+func (f *fakeObjectStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, ok := f.objects[key]
+	if !ok {
+		return ObjectInfo{}, ErrObjectNotFound
+	}
+	return info, nil
+}
+
+func (f *fakeObjectStorage) Copy(ctx context.Context, src, dst string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, ok := f.objects[src]
+	if !ok {
+		return ErrObjectNotFound
+	}
+	info.Key = dst
+	f.objects[dst] = info
+	return nil
+}
+
+func (f *fakeObjectStorage) Delete(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, key)
+	return nil
+}
+
+func TestSaveService(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// 存储延迟落盘，预先创建数据文件
+	for _, name := range []string{"cards.yaml", "platforms.yaml", "instances.yaml", "pipelines.yaml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[]\n"), 0644))
+	}
+	cardStore, err := store.NewGameCardStore(ctx, filepath.Join(dir, "cards.yaml"))
+	require.NoError(t, err)
+	platformStore, err := store.NewGamePlatformStore(ctx, filepath.Join(dir, "platforms.yaml"))
+	require.NoError(t, err)
+	instanceStore, err := store.NewGameInstanceStore(ctx, filepath.Join(dir, "instances.yaml"), utils.New("SaveTest"))
+	require.NoError(t, err)
+
+	cards := NewGameCardService(cardStore)
+	platforms := NewGamePlatformService(platformStore)
+	instances := NewGameInstanceService(instanceStore)
+	pipelines := NewGamePipelineService(store.NewYAMLGamePipelineStore(ctx, filepath.Join(dir, "pipelines.yaml")))
+	dispatcher := &fakeDispatcher{}
+	pipelines.SetDispatcher(dispatcher)
+	pipelines.SetTemplateSource("../../config/pipeline", nil)
+	instances.SetLifecycle(pipelines, platforms, cards)
+	pipelines.OnStateChange(instances.HandlePipelineState)
+
+	storage := &fakeObjectStorage{objects: make(map[string]ObjectInfo)}
+	saves := NewSaveService(storage, instances, pipelines, cards, SaveOptions{
+		S3: S3Options{Endpoint: "http://minio:9000", Bucket: "wind", AccessKey: "access", SecretKey: "secret"},
+	})
+	instances.SetSaveService(saves)
+	instances.OnStateChange(saves.HandleInstanceState)
+	pipelines.OnStateChange(saves.HandlePipelineState)
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(dir, "nodes.yaml"))
+	require.NoError(t, err)
+	nodes := NewGameNodeService(nodeStore)
+	require.NoError(t, nodes.Create(ctx, models.GameNode{ID: "node-1"}))
+	// 未启用自动清理时也按容器清单检测崩溃的游戏容器
+	inventory := NewInventoryService(nodes, instances, pipelines, InventoryOptions{})
+	nodes.OnContainersUpdate(inventory.HandleContainers)
+
+	_, err = platforms.Create(ctx, models.GamePlatform{ID: "steam", Name: "Steam", Image: "registry.example.com/steam:1.0"})
+	require.NoError(t, err)
+	_, err = cards.Create(ctx, models.GameCard{ID: "card-1", PlatformID: "steam", SavePaths: []string{"../etc"}})
+	assert.Error(t, err)
+	_, err = cards.Create(ctx, models.GameCard{ID: "card-1", PlatformID: "steam", SavePaths: []string{".local/share/game", "Documents/saves"}})
+	require.NoError(t, err)
+	_, err = instances.Create(ctx, CreateInstanceParams{NodeID: "node-1", PlatformID: "steam", CardID: "card-1", UserID: "alice/bob"})
+	assert.ErrorIs(t, err, ErrInvalidUserID)
+	id, err := instances.Create(ctx, CreateInstanceParams{NodeID: "node-1", PlatformID: "steam", CardID: "card-1", UserID: "alice"})
+	require.NoError(t, err)
+
+	latest := "saves/alice/card-1/latest.tar.gz"
+	storage.put(latest, "base")
+	sequence := int64(0)
+	report := func(pipelineID string, state models.PipelineState) {
+		sequence++
+		_, err := pipelines.ApplyStatusReport(ctx, pipelineID, "node-1", sequence, &models.PipelineStatus{State: state})
+		require.NoError(t, err)
+	}
+	saveState := func() *models.SaveState {
+		instance, err := instances.Get(ctx, id)
+		require.NoError(t, err)
+		return instance.Save
+	}
+	dispatched := func() []*models.GamePipeline {
+		dispatcher.mu.Lock()
+		defer dispatcher.mu.Unlock()
+		return append([]*models.GamePipeline{}, dispatcher.dispatched...)
+	}
+	// play 启动并停止实例，返回提交的存档上传流水线
+	play := func(during func()) *models.GamePipeline {
+		instance, err := instances.Start(ctx, id)
+		require.NoError(t, err)
+		report(instance.PipelineID, models.PipelineStateCompleted)
+		if during != nil {
+			during()
+		}
+		instance, err = instances.Stop(ctx, id)
+		require.NoError(t, err)
+		count := len(dispatched())
+		report(instance.PipelineID, models.PipelineStateCompleted)
+		require.Eventually(t, func() bool { return len(dispatched()) == count+1 }, time.Second, 10*time.Millisecond)
+		upload := dispatched()[count]
+		assert.Equal(t, saveUploadTemplate, upload.Dispatch.Template)
+		assert.Equal(t, ".local/share/game Documents/saves", upload.Dispatch.Args["SAVE_PATHS"])
+		return upload
+	}
+
+	// 启动流水线恢复用户的最新存档
+	instance, err := instances.Start(ctx, id)
+	require.NoError(t, err)
+	args := dispatched()[0].Dispatch.Args
+	assert.Equal(t, latest, args["SAVE_OBJECT"])
+	assert.Equal(t, "base", args["SAVE_CHECKSUM"])
+	assert.Equal(t, "wind", args["SAVE_S3_BUCKET"])
+	assert.Equal(t, models.SaveSyncPrepared, instance.Save.Status)
+
+	// 实例未运行就停止时不上传存档
+	instance, err = instances.Stop(ctx, id)
+	require.NoError(t, err)
+	report(instance.PipelineID, models.PipelineStateCompleted)
+	require.Eventually(t, func() bool { return saveState().Status == models.SaveSyncUnchanged }, time.Second, 10*time.Millisecond)
+	assert.Len(t, dispatched(), 2)
+
+	// 存档有变化且最新存档未被更新时替换为最新存档
+	upload := play(nil)
+	key := upload.Dispatch.Args["SAVE_UPLOAD"]
+	assert.Equal(t, models.SaveSyncUploading, saveState().Status)
+	_, err = instances.Start(ctx, id)
+	assert.ErrorIs(t, err, ErrSaveUploading)
+	storage.put(key, "progress")
+	report(upload.ID, models.PipelineStateCompleted)
+	require.Eventually(t, func() bool { return saveState().Status == models.SaveSyncSynced }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "progress", storage.checksum(latest))
+	assert.Equal(t, upload.ID, saveState().UploadPipelineID)
+	_, err = storage.Stat(ctx, key)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	// 存档没有变化时丢弃上传的存档
+	upload = play(nil)
+	storage.put(upload.Dispatch.Args["SAVE_UPLOAD"], "progress")
+	report(upload.ID, models.PipelineStateCompleted)
+	require.Eventually(t, func() bool { return saveState().Status == models.SaveSyncUnchanged }, time.Second, 10*time.Millisecond)
+
+	// 游戏期间最新存档被其他实例更新时本次存档另存为冲突副本
+	upload = play(func() { storage.put(latest, "elsewhere") })
+	storage.put(upload.Dispatch.Args["SAVE_UPLOAD"], "mine")
+	report(upload.ID, models.PipelineStateCompleted)
+	require.Eventually(t, func() bool { return saveState().Status == models.SaveSyncConflict }, time.Second, 10*time.Millisecond)
+	state := saveState()
+	assert.Equal(t, "elsewhere", storage.checksum(latest))
+	assert.Equal(t, "mine", storage.checksum(state.Conflict))
+	assert.Contains(t, state.Conflict, "saves/alice/card-1/conflicts/")
+
+	// 上传失败后再次启动不恢复存档，冲突检测仍以上一次恢复的存档为准
+	upload = play(nil)
+	report(upload.ID, models.PipelineStateFailed)
+	require.Eventually(t, func() bool { return saveState().Status == models.SaveSyncFailed }, time.Second, 10*time.Millisecond)
+	count := len(dispatched())
+	instance, err = instances.Start(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, dispatched()[count].Dispatch.Args["SAVE_OBJECT"])
+	assert.Equal(t, "elsewhere", saveState().BaseChecksum)
+
+	// crash 等待实例失败后提交的存档上传流水线并完成上传
+	crash := func(checksum string, during func()) {
+		count := len(dispatched())
+		during()
+		require.Eventually(t, func() bool { return len(dispatched()) == count+1 }, time.Second, 10*time.Millisecond)
+		upload := dispatched()[count]
+		assert.Equal(t, saveUploadTemplate, upload.Dispatch.Template)
+		storage.put(upload.Dispatch.Args["SAVE_UPLOAD"], checksum)
+		report(upload.ID, models.PipelineStateCompleted)
+		require.Eventually(t, func() bool { return saveState().Status == models.SaveSyncSynced }, time.Second, 10*time.Millisecond)
+	}
+
+	// 游戏容器在运行中退出时实例标记为失败并上传存档
+	report(instance.PipelineID, models.PipelineStateCompleted)
+	crash("crashed", func() {
+		require.NoError(t, nodes.UpdateStatusContainers(ctx, "node-1", []models.ContainerInfo{
+			{ID: "c-game", State: "exited", InstanceID: id},
+		}))
+		failed, err := instances.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, models.GameInstanceStateFailed, failed.Status)
+		assert.Equal(t, "游戏容器已退出", failed.Message)
+	})
+
+	// 节点失联恢复后游戏容器未运行时实例标记为失败并上传存档
+	instance, err = instances.Start(ctx, id)
+	require.NoError(t, err)
+	report(instance.PipelineID, models.PipelineStateCompleted)
+	_, err = instances.MarkNodeLost(ctx, "node-1")
+	require.NoError(t, err)
+	crash("recovered", func() {
+		require.NoError(t, nodes.UpdateStatusContainers(ctx, "node-1", nil))
+	})
+	assert.Equal(t, "recovered", storage.checksum(latest))
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TURNCredentials TURN 服务的临时凭证
+type TURNCredentials struct {
+	Username  string
+	Password  string
+	ExpiresAt time.Time
+}
+
+// TURNCredentialIssuer 按 coturn REST API 的共享密钥方案签发 TURN 临时凭证
+// 用户名为 "过期时间戳:实例ID"，密码为 base64(HMAC-SHA1(共享密钥, 用户名))，
+// coturn 使用相同的 static-auth-secret 校验，无需保存凭证
+type TURNCredentialIssuer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewTURNCredentialIssuer 创建 TURN 凭证签发器，secret 需与 coturn 的 static-auth-secret 一致
+func NewTURNCredentialIssuer(secret string, ttl time.Duration) (*TURNCredentialIssuer, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("TURN 共享密钥不能为空")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("无效的 TURN 凭证有效期: %v", ttl)
+	}
+	return &TURNCredentialIssuer{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}, nil
+}
+
+// Issue 为实例签发 TURN 凭证，有效期从 now 开始计算
+func (i *TURNCredentialIssuer) Issue(instanceID string, now time.Time) TURNCredentials {
+	expiresAt := now.Add(i.ttl).Truncate(time.Second)
+	username := strconv.FormatInt(expiresAt.Unix(), 10) + ":" + instanceID
+	mac := hmac.New(sha1.New, i.secret)
+	mac.Write([]byte(username))
+	return TURNCredentials{
+		Username:  username,
+		Password:  base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+		ExpiresAt: expiresAt,
+	}
+}
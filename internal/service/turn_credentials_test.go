@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTURNCredentialIssuer(t *testing.T) {
+	_, err := NewTURNCredentialIssuer("", time.Hour)
+	assert.Error(t, err)
+
+	issuer, err := NewTURNCredentialIssuer("north-secret", time.Hour)
+	require.NoError(t, err)
+
+	// 用户名为 "过期时间戳:实例ID"，密码为 base64(HMAC-SHA1(密钥, 用户名))
+	credentials := issuer.Issue("inst-1", time.Unix(1000, 0))
+	assert.Equal(t, "4600:inst-1", credentials.Username)
+	assert.Equal(t, "z/e4QZEvA7e6D5FkB29+uYGT0lQ=", credentials.Password)
+	assert.Equal(t, time.Unix(4600, 0), credentials.ExpiresAt)
+
+	// 再次启动时签发新的凭证
+	rotated := issuer.Issue("inst-1", time.Unix(2000, 0))
+	assert.Equal(t, "5600:inst-1", rotated.Username)
+	assert.NotEqual(t, credentials.Password, rotated.Password)
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// UsageService 实例用量统计服务
+// 实例进入运行中时开始一次会话，停止或失败时结束会话，会话记录与实例分开保存
+type UsageService struct {
+	store  store.UsageStore
+	nodes  *GameNodeService
+	logger utils.Logger
+	// now 计算进行中会话时长使用的当前时间
+	now func() time.Time
+
+	// mu 保证同一实例同时只有一个进行中的会话
+	mu sync.Mutex
+}
+
+// NewUsageService 创建实例用量统计服务，nodes 用于记录运行节点的 GPU 型号
+func NewUsageService(store store.UsageStore, nodes *GameNodeService) *UsageService {
+	return &UsageService{
+		store:  store,
+		nodes:  nodes,
+		logger: utils.New("UsageService"),
+		now:    time.Now,
+	}
+}
+
+// HandleInstanceState 根据实例状态开始或结束会话
+// 节点失联后恢复的实例继续原来的会话，不开始新的会话
+func (s *UsageService) HandleInstanceState(ctx context.Context, instance models.GameInstance, from models.GameInstanceState) {
+	switch instance.Status {
+	case models.GameInstanceStateRunning:
+		if err := s.open(ctx, instance); err != nil {
+			s.logger.Error("记录实例 %s 的会话开始失败: %v", instance.ID, err)
+		}
+	case models.GameInstanceStateStopped, models.GameInstanceStateFailed:
+		if err := s.close(ctx, instance); err != nil {
+			s.logger.Error("记录实例 %s 的会话结束失败: %v", instance.ID, err)
+		}
+	}
+}
+
+// open 为实例开始一次会话，实例已有进行中的会话时不处理
+func (s *UsageService) open(ctx context.Context, instance models.GameInstance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok, err := s.openSession(ctx, instance.ID); err != nil || ok {
+		return err
+	}
+	id, err := generateSecret(8)
+	if err != nil {
+		return err
+	}
+	startedAt := instance.StartedAt
+	if startedAt.IsZero() {
+		startedAt = s.now()
+	}
+	session := models.UsageSession{
+		ID:         id,
+		InstanceID: instance.ID,
+		UserID:     instance.UserID,
+		CardID:     instance.CardID,
+		PlatformID: instance.PlatformID,
+		NodeID:     instance.NodeID,
+		GPUModel:   s.gpuModel(ctx, instance.NodeID),
+		StartedAt:  startedAt,
+	}
+	if err := s.store.Add(ctx, session); err != nil {
+		return fmt.Errorf("存储层错误: %w", err)
+	}
+	s.logger.Info("实例 %s 开始会话 %s", instance.ID, id)
+	return nil
+}
+
+// close 结束实例进行中的会话，实例没有进行中的会话时不处理
+func (s *UsageService) close(ctx context.Context, instance models.GameInstance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok, err := s.openSession(ctx, instance.ID)
+	if err != nil || !ok {
+		return err
+	}
+	stoppedAt := instance.StoppedAt
+	if stoppedAt.IsZero() {
+		stoppedAt = s.now()
+	}
+	if stoppedAt.Before(session.StartedAt) {
+		stoppedAt = session.StartedAt
+	}
+	session.StoppedAt = &stoppedAt
+	session.Duration = int64(stoppedAt.Sub(session.StartedAt) / time.Second)
+	session.StopReason = instance.StopReason
+	if err := s.store.Update(ctx, session); err != nil {
+		return fmt.Errorf("存储层错误: %w", err)
+	}
+	s.logger.Info("实例 %s 结束会话 %s，时长: %ds，原因: %s", instance.ID, session.ID, session.Duration, session.StopReason)
+	return nil
+}
+
+// openSession 查找实例进行中的会话，调用方需持有 mu
+func (s *UsageService) openSession(ctx context.Context, instanceID string) (models.UsageSession, bool, error) {
+	sessions, err := s.store.List(ctx)
+	if err != nil {
+		return models.UsageSession{}, false, fmt.Errorf("存储层错误: %w", err)
+	}
+	for _, session := range sessions {
+		if session.InstanceID == instanceID && session.StoppedAt == nil {
+			return session, true, nil
+		}
+	}
+	return models.UsageSession{}, false, nil
+}
+
+// gpuModel 节点的 GPU 型号，优先使用硬件信息，没有时使用 GPU 指标中的型号
+func (s *UsageService) gpuModel(ctx context.Context, nodeID string) string {
+	if s.nodes == nil || nodeID == "" {
+		return ""
+	}
+	node, err := s.nodes.Get(ctx, nodeID)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	seen := make(map[string]bool)
+	add := func(model string) {
+		if model != "" && !seen[model] {
+			seen[model] = true
+			names = append(names, model)
+		}
+	}
+	for _, gpu := range node.Status.Hardware.GPUs {
+		add(gpu.Model)
+	}
+	if len(names) == 0 {
+		for _, gpu := range node.Status.Metrics.GPUs {
+			add(gpu.Model)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// List 获取与查询时间范围重叠且满足过滤条件的会话，按开始时间排序
+// 进行中会话的时长计算到当前时间
+func (s *UsageService) List(ctx context.Context, query models.UsageQuery) ([]models.UsageSession, error) {
+	sessions, err := s.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("存储层错误: %w", err)
+	}
+	now := s.now()
+	result := make([]models.UsageSession, 0, len(sessions))
+	for _, session := range sessions {
+		if !matchUsageQuery(session, query) {
+			continue
+		}
+		end := sessionEnd(session, now)
+		if _, _, ok := clipUsage(session.StartedAt, end, query); !ok {
+			continue
+		}
+		if session.StoppedAt == nil {
+			session.Duration = int64(end.Sub(session.StartedAt) / time.Second)
+		}
+		result = append(result, session)
+	}
+	return result, nil
+}
+
+// Summarize 按维度汇总查询时间范围内的使用时长，会话只计算与时间范围重叠的部分
+// 按天汇总时使用 loc 划分自然日，loc 为空时使用服务端时区
+func (s *UsageService) Summarize(ctx context.Context, query models.UsageQuery, groupBy models.UsageGroupBy, loc *time.Location) ([]models.UsageSummary, error) {
+	if !groupBy.IsValid() {
+		return nil, fmt.Errorf("无效的汇总维度: %s", groupBy)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	sessions, err := s.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	summaries := make(map[string]*models.UsageSummary)
+	add := func(key string, duration time.Duration) {
+		summary, ok := summaries[key]
+		if !ok {
+			summary = &models.UsageSummary{Key: key}
+			summaries[key] = summary
+		}
+		summary.Sessions++
+		summary.Duration += int64(duration / time.Second)
+	}
+	for _, session := range sessions {
+		start, end, _ := clipUsage(session.StartedAt, sessionEnd(session, now), query)
+		switch groupBy {
+		case models.UsageGroupByUser:
+			add(session.UserID, end.Sub(start))
+		case models.UsageGroupByCard:
+			add(session.CardID, end.Sub(start))
+		case models.UsageGroupByNode:
+			add(session.NodeID, end.Sub(start))
+		case models.UsageGroupByDay:
+			// 跨天的会话按自然日拆分
+			for day := start.In(loc); ; {
+				next := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+				segmentEnd := end
+				if next.Before(end) {
+					segmentEnd = next
+				}
+				add(day.Format("2006-01-02"), segmentEnd.Sub(day))
+				if !next.Before(end) {
+					break
+				}
+				day = next
+			}
+		}
+	}
+
+	result := make([]models.UsageSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if groupBy != models.UsageGroupByDay && result[i].Duration != result[j].Duration {
+			return result[i].Duration > result[j].Duration
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// matchUsageQuery 判断会话是否满足查询的过滤条件
+func matchUsageQuery(session models.UsageSession, query models.UsageQuery) bool {
+	return (query.UserID == "" || session.UserID == query.UserID) &&
+		(query.CardID == "" || session.CardID == query.CardID) &&
+		(query.NodeID == "" || session.NodeID == query.NodeID)
+}
+
+// sessionEnd 会话的结束时间，进行中的会话为 now
+func sessionEnd(session models.UsageSession, now time.Time) time.Time {
+	if session.StoppedAt != nil {
+		return *session.StoppedAt
+	}
+	if now.Before(session.StartedAt) {
+		return session.StartedAt
+	}
+	return now
+}
+
+// clipUsage 将会话时间截取到查询时间范围内，会话与时间范围不重叠时返回 false
+func clipUsage(start, end time.Time, query models.UsageQuery) (time.Time, time.Time, bool) {
+	if !query.To.IsZero() && !start.Before(query.To) {
+		return start, end, false
+	}
+	if !query.From.IsZero() && end.Before(query.From) {
+		return start, end, false
+	}
+	if !query.From.IsZero() && start.Before(query.From) {
+		start = query.From
+	}
+	if !query.To.IsZero() && end.After(query.To) {
+		end = query.To
+	}
+	return start, end, true
+}
+
+// csvCell 转义 CSV 中的文本单元格，以 =、+、-、@ 等开头的值前加单引号，避免在表格软件中被当作公式执行
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// WriteUsageSessionsCSV 将会话写为 CSV，时间使用 RFC 3339 格式，时长单位为秒，文本单元格按 csvCell 转义
+func WriteUsageSessionsCSV(w io.Writer, sessions []models.UsageSession) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "instance_id", "user_id", "card_id", "platform_id", "node_id", "gpu_model", "started_at", "stopped_at", "duration_seconds", "stop_reason"}); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		stoppedAt := ""
+		if session.StoppedAt != nil {
+			stoppedAt = session.StoppedAt.Format(time.RFC3339)
+		}
+		if err := writer.Write([]string{
+			csvCell(session.ID),
+			csvCell(session.InstanceID),
+			csvCell(session.UserID),
+			csvCell(session.CardID),
+			csvCell(session.PlatformID),
+			csvCell(session.NodeID),
+			csvCell(session.GPUModel),
+			session.StartedAt.Format(time.RFC3339),
+			stoppedAt,
+			strconv.FormatInt(session.Duration, 10),
+			string(session.StopReason),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteUsageSummaryCSV 将汇总结果写为 CSV，第一列的列名为汇总维度，汇总键按 csvCell 转义
+func WriteUsageSummaryCSV(w io.Writer, groupBy models.UsageGroupBy, summaries []models.UsageSummary) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{string(groupBy), "sessions", "duration_seconds"}); err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		if err := writer.Write([]string{
+			csvCell(summary.Key),
+			strconv.Itoa(summary.Sessions),
+			strconv.FormatInt(summary.Duration, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/store"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+func TestUsageSessions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// 会话存储延迟落盘，预先创建数据文件
+	usageFile := filepath.Join(dir, "usage.yaml")
+	require.NoError(t, os.WriteFile(usageFile, []byte("[]\n"), 0644))
+	usageStore := store.NewYAMLUsageStore(ctx, usageFile)
+	t.Cleanup(usageStore.Close)
+	nodeStore, err := store.NewGameNodeStore(ctx, filepath.Join(dir, "nodes.yaml"))
+	require.NoError(t, err)
+
+	nodes := NewGameNodeService(nodeStore)
+	require.NoError(t, nodes.Create(ctx, models.GameNode{ID: "node-1"}))
+	require.NoError(t, nodes.UpdateHardwareAndSystem(ctx, "node-1", models.HardwareInfo{
+		GPUs: []models.GPUDevice{{Model: "NVIDIA RTX 4090"}, {Model: "NVIDIA RTX 4090"}},
+	}, models.SystemInfo{}))
+	usage := NewUsageService(usageStore, nodes)
+	now := time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC)
+	usage.now = func() time.Time { return now }
+
+	// 会话 A 跨越 UTC+8 的零点，节点失联恢复后继续原来的会话
+	a := models.GameInstance{ID: "inst-a", UserID: "u1", CardID: "card-1", PlatformID: "steam", NodeID: "node-1",
+		Status: models.GameInstanceStateRunning, StartedAt: time.Date(2026, 10, 17, 15, 0, 0, 0, time.UTC)}
+	usage.HandleInstanceState(ctx, a, models.GameInstanceStateStarting)
+	a.StartedAt = a.StartedAt.Add(30 * time.Minute)
+	usage.HandleInstanceState(ctx, a, models.GameInstanceStateLost)
+	a.Status = models.GameInstanceStateStopped
+	a.StoppedAt = time.Date(2026, 10, 17, 17, 0, 0, 0, time.UTC)
+	a.StopReason = models.StopReasonUser
+	usage.HandleInstanceState(ctx, a, models.GameInstanceStateStopping)
+
+	// 会话 B 进行中
+	b := models.GameInstance{ID: "inst-b", UserID: "u2", CardID: "card-1", PlatformID: "steam", NodeID: "node-1",
+		Status: models.GameInstanceStateRunning, StartedAt: time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC)}
+	usage.HandleInstanceState(ctx, b, models.GameInstanceStatePreparing)
+
+	sessions, err := usage.List(ctx, models.UsageQuery{})
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "inst-a", sessions[0].InstanceID)
+	assert.Equal(t, "NVIDIA RTX 4090", sessions[0].GPUModel)
+	assert.True(t, sessions[0].StartedAt.Equal(time.Date(2026, 10, 17, 15, 0, 0, 0, time.UTC)))
+	require.NotNil(t, sessions[0].StoppedAt)
+	assert.Equal(t, int64(7200), sessions[0].Duration)
+	assert.Equal(t, models.StopReasonUser, sessions[0].StopReason)
+	assert.Equal(t, "inst-b", sessions[1].InstanceID)
+	assert.Nil(t, sessions[1].StoppedAt)
+	assert.Equal(t, int64(3600), sessions[1].Duration)
+
+	byUser, err := usage.Summarize(ctx, models.UsageQuery{}, models.UsageGroupByUser, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UsageSummary{
+		{Key: "u1", Sessions: 1, Duration: 7200},
+		{Key: "u2", Sessions: 1, Duration: 3600},
+	}, byUser)
+
+	// 跨天的会话按自然日拆分
+	byDay, err := usage.Summarize(ctx, models.UsageQuery{}, models.UsageGroupByDay, time.FixedZone("UTC+8", 8*3600))
+	require.NoError(t, err)
+	assert.Equal(t, []models.UsageSummary{
+		{Key: "2026-10-17", Sessions: 1, Duration: 3600},
+		{Key: "2026-10-18", Sessions: 2, Duration: 7200},
+	}, byDay)
+
+	// 只计算与时间范围重叠的部分
+	byCard, err := usage.Summarize(ctx, models.UsageQuery{From: time.Date(2026, 10, 17, 16, 30, 0, 0, time.UTC)}, models.UsageGroupByCard, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UsageSummary{{Key: "card-1", Sessions: 2, Duration: 5400}}, byCard)
+
+	filtered, err := usage.List(ctx, models.UsageQuery{UserID: "u2"})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "inst-b", filtered[0].InstanceID)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteUsageSessionsCSV(&buf, sessions))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "id,instance_id,user_id,"))
+	assert.Contains(t, lines[1], ",inst-a,u1,card-1,steam,node-1,NVIDIA RTX 4090,2026-10-17T15:00:00Z,2026-10-17T17:00:00Z,7200,user")
+	assert.Contains(t, lines[2], ",inst-b,u2,card-1,steam,node-1,NVIDIA RTX 4090,2026-10-18T01:00:00Z,,3600,")
+
+	// 可能被表格软件当作公式的文本单元格前加单引号
+	sessions[0].UserID = "=1+1"
+	sessions[0].CardID = "+card"
+	sessions[0].GPUModel = "@SUM(A1)"
+	buf.Reset()
+	require.NoError(t, WriteUsageSessionsCSV(&buf, sessions[:1]))
+	assert.Contains(t, buf.String(), ",inst-a,'=1+1,'+card,steam,node-1,'@SUM(A1),")
+	buf.Reset()
+	require.NoError(t, WriteUsageSummaryCSV(&buf, models.UsageGroupByUser, []models.UsageSummary{{Key: "-2", Sessions: 1, Duration: 60}}))
+	assert.Equal(t, "user,sessions,duration_seconds\n'-2,1,60\n", buf.String())
+}
+
+func TestUsageSessionsClosedWithInstance(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// 存储延迟落盘，预先创建数据文件
+	for _, name := range []string{"usage.yaml", "instances.yaml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[]\n"), 0644))
+	}
+	usageStore := store.NewYAMLUsageStore(ctx, filepath.Join(dir, "usage.yaml"))
+	t.Cleanup(usageStore.Close)
+	instanceStore, err := store.NewGameInstanceStore(ctx, filepath.Join(dir, "instances.yaml"), utils.New("UsageTest"))
+	require.NoError(t, err)
+
+	instances := NewGameInstanceService(instanceStore)
+	usage := NewUsageService(usageStore, nil)
+	instances.OnStateChange(usage.HandleInstanceState)
+
+	started := time.Now().Add(-time.Hour)
+	instance := models.GameInstance{ID: "inst-lost", UserID: "u1", NodeID: "node-1", Status: models.GameInstanceStateRunning, StartedAt: started}
+	require.NoError(t, instanceStore.Add(ctx, instance))
+	usage.HandleInstanceState(ctx, instance, models.GameInstanceStateStarting)
+
+	// 节点恢复后游戏容器未运行，失联的实例标记为失败时结束会话
+	_, err = instances.MarkNodeLost(ctx, "node-1")
+	require.NoError(t, err)
+	require.NoError(t, instances.RecoverLost(ctx, "inst-lost", false))
+
+	sessions, err := usage.List(ctx, models.UsageQuery{})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.NotNil(t, sessions[0].StoppedAt)
+	assert.GreaterOrEqual(t, sessions[0].Duration, int64(3600))
+	assert.Equal(t, models.StopReasonFailed, sessions[0].StopReason)
+}
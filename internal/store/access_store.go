@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// AccessGrantStore 访问令牌代数存储接口
+type AccessGrantStore interface {
+	// Get 获取用户在作用范围上的令牌代数，不存在时返回代数为 0 的记录
+	Get(ctx context.Context, scope models.AccessScope, target, userID string) (models.AccessGrant, error)
+	// Put 保存令牌代数
+	Put(ctx context.Context, grant models.AccessGrant) error
+	// Close 关闭存储
+	Close()
+}
+
+// YAMLAccessGrantStore 基于YAML文件的访问令牌代数存储实现
+type YAMLAccessGrantStore struct {
+	filepath  string
+	grants    map[string]models.AccessGrant
+	mu        sync.RWMutex
+	logger    utils.Logger
+	yamlSaver *utils.YAMLSaver
+}
+
+// NewYAMLAccessGrantStore 创建新的YAML访问令牌代数存储
+func NewYAMLAccessGrantStore(ctx context.Context, filepath string) *YAMLAccessGrantStore {
+	logger := utils.New("AccessGrantStore")
+
+	store := &YAMLAccessGrantStore{
+		filepath: filepath,
+		grants:   make(map[string]models.AccessGrant),
+		logger:   logger,
+	}
+
+	// 创建YAML保存器，使用1秒的延迟保存，按键排序写入
+	store.yamlSaver = utils.NewYAMLSaver(
+		filepath,
+		func() interface{} {
+			store.mu.RLock()
+			defer store.mu.RUnlock()
+			keys := make([]string, 0, len(store.grants))
+			for key := range store.grants {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			grants := make([]models.AccessGrant, 0, len(keys))
+			for _, key := range keys {
+				grants = append(grants, store.grants[key])
+			}
+			return grants
+		},
+		logger,
+		utils.WithDelay(time.Second),
+	)
+
+	logger.Info("初始化访问令牌存储，数据文件: %s", filepath)
+	if err := store.load(ctx); err != nil {
+		logger.Error("加载访问令牌数据失败: %v", err)
+	}
+
+	logger.Info("成功加载访问令牌数据，共%d条记录", len(store.grants))
+	return store
+}
+
+// grantKey 令牌代数的唯一键
+func grantKey(scope models.AccessScope, target, userID string) string {
+	return fmt.Sprintf("%s/%s/%s", scope, target, userID)
+}
+
+// Get 获取用户在作用范围上的令牌代数
+func (s *YAMLAccessGrantStore) Get(ctx context.Context, scope models.AccessScope, target, userID string) (models.AccessGrant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if grant, exists := s.grants[grantKey(scope, target, userID)]; exists {
+		return grant, nil
+	}
+	return models.AccessGrant{Scope: scope, Target: target, UserID: userID}, nil
+}
+
+// Put 保存令牌代数
+func (s *YAMLAccessGrantStore) Put(ctx context.Context, grant models.AccessGrant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := grantKey(grant.Scope, grant.Target, grant.UserID)
+	s.grants[key] = grant
+	s.logger.Debug("保存访问令牌代数: %s, 代数: %d", key, grant.Generation)
+	return s.save(ctx)
+}
+
+// save 使用延迟保存器保存到文件
+func (s *YAMLAccessGrantStore) save(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.yamlSaver.Save(ctx)
+}
+
+// load 从文件加载所有令牌代数
+func (s *YAMLAccessGrantStore) load(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.filepath); os.IsNotExist(err) {
+		s.logger.Info("数据文件不存在，使用空数据: %s", s.filepath)
+		return nil
+	}
+
+	data, err := os.ReadFile(s.filepath)
+	if err != nil {
+		return fmt.Errorf("读取数据文件失败: %w", err)
+	}
+
+	var grants []models.AccessGrant
+	if err := yaml.Unmarshal(data, &grants); err != nil {
+		return fmt.Errorf("解析访问令牌数据失败: %w", err)
+	}
+	for _, grant := range grants {
+		s.grants[grantKey(grant.Scope, grant.Target, grant.UserID)] = grant
+	}
+	return nil
+}
+
+// Close 关闭存储，确保所有待处理的保存操作完成
+func (s *YAMLAccessGrantStore) Close() {
+	s.logger.Info("关闭AccessGrantStore，确保数据保存...")
+	if s.yamlSaver != nil {
+		// 延迟保存器关闭时会丢弃未执行的保存，先立即写入一次，避免已吊销的令牌在重启后恢复有效
+		if err := s.yamlSaver.SaveNow(context.Background()); err != nil {
+			s.logger.Error("保存访问令牌数据失败: %v", err)
+		}
+		s.yamlSaver.Close()
+	}
+}
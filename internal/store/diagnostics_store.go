@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// DiagnosticsStore 节点诊断包记录存储接口
+// 只保存诊断包的元数据，诊断包文件由服务层管理
+type DiagnosticsStore interface {
+	// Get 获取指定ID的诊断包记录
+	Get(ctx context.Context, id string) (*models.DiagnosticsBundle, error)
+	// List 获取所有诊断包记录
+	List(ctx context.Context) ([]*models.DiagnosticsBundle, error)
+	// Add 添加新的诊断包记录
+	Add(ctx context.Context, bundle *models.DiagnosticsBundle) error
+	// Update 更新诊断包记录
+	Update(ctx context.Context, bundle *models.DiagnosticsBundle) error
+	// Delete 删除诊断包记录
+	Delete(ctx context.Context, id string) error
+	// Load 从文件加载所有诊断包记录
+	Load(ctx context.Context) error
+	// Close 关闭存储
+	Close()
+}
+
+// YAMLDiagnosticsStore 基于YAML文件的诊断包记录存储实现
+type YAMLDiagnosticsStore struct {
+	filepath  string
+	bundles   map[string]*models.DiagnosticsBundle
+	mu        sync.RWMutex
+	logger    utils.Logger
+	yamlSaver *utils.YAMLSaver
+}
+
+// NewYAMLDiagnosticsStore 创建新的YAML诊断包记录存储
+func NewYAMLDiagnosticsStore(ctx context.Context, filepath string) *YAMLDiagnosticsStore {
+	logger := utils.New("DiagnosticsStore")
+
+	store := &YAMLDiagnosticsStore{
+		filepath: filepath,
+		bundles:  make(map[string]*models.DiagnosticsBundle),
+		logger:   logger,
+	}
+
+	// 创建YAML保存器，使用1秒的延迟保存
+	store.yamlSaver = utils.NewYAMLSaver(
+		filepath,
+		func() interface{} {
+			store.mu.RLock()
+			defer store.mu.RUnlock()
+			return store.bundles
+		},
+		logger,
+		utils.WithDelay(time.Second),
+	)
+
+	logger.Info("初始化诊断包存储，数据文件: %s", filepath)
+	if err := store.Load(ctx); err != nil {
+		logger.Error("加载诊断包数据失败: %v", err)
+	}
+
+	logger.Info("成功加载诊断包数据，共%d条记录", len(store.bundles))
+	return store
+}
+
+// Get 获取指定ID的诊断包记录
+func (s *YAMLDiagnosticsStore) Get(ctx context.Context, id string) (*models.DiagnosticsBundle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bundle, exists := s.bundles[id]
+	if !exists {
+		return nil, fmt.Errorf("diagnostics bundle not found: %s", id)
+	}
+
+	// 返回副本，避免调用方修改存储中的数据
+	copied := *bundle
+	return &copied, nil
+}
+
+// List 获取所有诊断包记录
+func (s *YAMLDiagnosticsStore) List(ctx context.Context) ([]*models.DiagnosticsBundle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bundles := make([]*models.DiagnosticsBundle, 0, len(s.bundles))
+	for _, bundle := range s.bundles {
+		copied := *bundle
+		bundles = append(bundles, &copied)
+	}
+	return bundles, nil
+}
+
+// Add 添加新的诊断包记录
+func (s *YAMLDiagnosticsStore) Add(ctx context.Context, bundle *models.DiagnosticsBundle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.bundles[bundle.ID]; exists {
+		return fmt.Errorf("diagnostics bundle already exists: %s", bundle.ID)
+	}
+
+	s.bundles[bundle.ID] = bundle
+	s.logger.Info("添加诊断包记录: %s (节点 %s)", bundle.ID, bundle.NodeID)
+	return s.save(ctx)
+}
+
+// Update 更新诊断包记录
+func (s *YAMLDiagnosticsStore) Update(ctx context.Context, bundle *models.DiagnosticsBundle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.bundles[bundle.ID]; !exists {
+		return fmt.Errorf("diagnostics bundle not found: %s", bundle.ID)
+	}
+
+	s.bundles[bundle.ID] = bundle
+	s.logger.Debug("更新诊断包记录: %s", bundle.ID)
+	return s.save(ctx)
+}
+
+// Delete 删除诊断包记录
+func (s *YAMLDiagnosticsStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.bundles[id]; !exists {
+		return fmt.Errorf("diagnostics bundle not found: %s", id)
+	}
+
+	delete(s.bundles, id)
+	s.logger.Info("删除诊断包记录: %s", id)
+	return s.save(ctx)
+}
+
+// save 使用延迟保存器保存到文件
+func (s *YAMLDiagnosticsStore) save(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.yamlSaver.Save(ctx)
+}
+
+// Load 从文件加载所有诊断包记录
+func (s *YAMLDiagnosticsStore) Load(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.filepath); os.IsNotExist(err) {
+		s.logger.Info("数据文件不存在，使用空数据: %s", s.filepath)
+		return nil
+	}
+
+	data, err := os.ReadFile(s.filepath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &s.bundles); err != nil {
+		return fmt.Errorf("failed to unmarshal diagnostics bundles: %w", err)
+	}
+	if s.bundles == nil {
+		s.bundles = make(map[string]*models.DiagnosticsBundle)
+	}
+	return nil
+}
+
+// Close 关闭存储，确保所有待处理的保存操作完成
+func (s *YAMLDiagnosticsStore) Close() {
+	s.logger.Info("关闭DiagnosticsStore，确保数据保存...")
+	if s.yamlSaver != nil {
+		s.yamlSaver.Close()
+	}
+}
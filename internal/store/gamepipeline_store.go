@@ -2,16 +2,21 @@ package store
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/open-beagle/beagle-wind-game/internal/models"
 	"github.com/open-beagle/beagle-wind-game/internal/utils"
 )
 
+// ErrPipelineNotFound 流水线不存在
+var ErrPipelineNotFound = errors.New("pipeline not found")
+
 // GamePipelineStore 游戏节点流水线存储接口
 type GamePipelineStore interface {
 	// Get 获取指定ID的流水线
@@ -84,7 +89,7 @@ func (s *YAMLGamePipelineStore) Get(ctx context.Context, id string) (*models.Gam
 	pipeline, exists := s.pipelines[id]
 	if !exists {
 		s.logger.Error("流水线不存在: %s", id)
-		return nil, fmt.Errorf("pipeline not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
 	}
 
 	s.logger.Debug("获取流水线: %s", id)
@@ -137,7 +142,7 @@ func (s *YAMLGamePipelineStore) Update(ctx context.Context, pipeline *models.Gam
 
 	if _, exists := s.pipelines[pipeline.ID]; !exists {
 		s.logger.Error("流水线不存在: %s", pipeline.ID)
-		return fmt.Errorf("pipeline not found: %s", pipeline.ID)
+		return fmt.Errorf("%w: %s", ErrPipelineNotFound, pipeline.ID)
 	}
 
 	s.pipelines[pipeline.ID] = pipeline
@@ -152,7 +157,7 @@ func (s *YAMLGamePipelineStore) Delete(ctx context.Context, id string) error {
 
 	if _, exists := s.pipelines[id]; !exists {
 		s.logger.Error("流水线不存在: %s", id)
-		return fmt.Errorf("pipeline not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
 	}
 
 	delete(s.pipelines, id)
@@ -194,11 +199,14 @@ func (s *YAMLGamePipelineStore) Load(ctx context.Context) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// 解析JSON
-	if err := json.Unmarshal(data, &s.pipelines); err != nil {
+	// 解析YAML，与 YAMLSaver 写入的格式保持一致
+	if err := yaml.Unmarshal(data, &s.pipelines); err != nil {
 		s.logger.Error("解析流水线数据失败: %v", err)
 		return fmt.Errorf("failed to unmarshal pipelines: %w", err)
 	}
+	if s.pipelines == nil {
+		s.pipelines = make(map[string]*models.GamePipeline)
+	}
 
 	s.logger.Debug("成功从文件加载%d个流水线", len(s.pipelines))
 	return nil
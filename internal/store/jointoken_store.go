@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// JoinTokenStore 节点加入令牌存储接口
+type JoinTokenStore interface {
+	// Get 获取指定ID的令牌
+	Get(ctx context.Context, id string) (*models.JoinToken, error)
+	// List 获取所有令牌
+	List(ctx context.Context) ([]*models.JoinToken, error)
+	// Add 添加新的令牌
+	Add(ctx context.Context, token *models.JoinToken) error
+	// Update 更新令牌
+	Update(ctx context.Context, token *models.JoinToken) error
+	// Load 从文件加载所有令牌
+	Load(ctx context.Context) error
+	// Close 关闭存储
+	Close()
+}
+
+// YAMLJoinTokenStore 基于YAML文件的加入令牌存储实现
+type YAMLJoinTokenStore struct {
+	filepath  string
+	tokens    map[string]*models.JoinToken
+	mu        sync.RWMutex
+	logger    utils.Logger
+	yamlSaver *utils.YAMLSaver
+}
+
+// NewYAMLJoinTokenStore 创建新的YAML加入令牌存储
+func NewYAMLJoinTokenStore(ctx context.Context, filepath string) *YAMLJoinTokenStore {
+	logger := utils.New("JoinTokenStore")
+
+	store := &YAMLJoinTokenStore{
+		filepath: filepath,
+		tokens:   make(map[string]*models.JoinToken),
+		logger:   logger,
+	}
+
+	// 创建YAML保存器，使用1秒的延迟保存
+	store.yamlSaver = utils.NewYAMLSaver(
+		filepath,
+		func() interface{} {
+			store.mu.RLock()
+			defer store.mu.RUnlock()
+			return store.tokens
+		},
+		logger,
+		utils.WithDelay(time.Second),
+	)
+
+	logger.Info("初始化加入令牌存储，数据文件: %s", filepath)
+	if err := store.Load(ctx); err != nil {
+		logger.Error("加载加入令牌数据失败: %v", err)
+	}
+
+	logger.Info("成功加载加入令牌数据，共%d个令牌", len(store.tokens))
+	return store
+}
+
+// Get 获取指定ID的令牌
+func (s *YAMLJoinTokenStore) Get(ctx context.Context, id string) (*models.JoinToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, exists := s.tokens[id]
+	if !exists {
+		return nil, fmt.Errorf("join token not found: %s", id)
+	}
+
+	// 返回副本，避免调用方修改存储中的数据
+	copied := *token
+	copied.UsedBy = append([]string(nil), token.UsedBy...)
+	return &copied, nil
+}
+
+// List 获取所有令牌
+func (s *YAMLJoinTokenStore) List(ctx context.Context) ([]*models.JoinToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]*models.JoinToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		copied := *token
+		copied.UsedBy = append([]string(nil), token.UsedBy...)
+		tokens = append(tokens, &copied)
+	}
+	return tokens, nil
+}
+
+// Add 添加新的令牌
+func (s *YAMLJoinTokenStore) Add(ctx context.Context, token *models.JoinToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tokens[token.ID]; exists {
+		return fmt.Errorf("join token already exists: %s", token.ID)
+	}
+
+	s.tokens[token.ID] = token
+	s.logger.Info("添加加入令牌: %s", token.ID)
+	return s.save(ctx)
+}
+
+// Update 更新令牌
+func (s *YAMLJoinTokenStore) Update(ctx context.Context, token *models.JoinToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tokens[token.ID]; !exists {
+		return fmt.Errorf("join token not found: %s", token.ID)
+	}
+
+	s.tokens[token.ID] = token
+	s.logger.Debug("更新加入令牌: %s", token.ID)
+	return s.save(ctx)
+}
+
+// save 使用延迟保存器保存到文件
+func (s *YAMLJoinTokenStore) save(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.yamlSaver.Save(ctx)
+}
+
+// Load 从文件加载所有令牌
+func (s *YAMLJoinTokenStore) Load(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.filepath); os.IsNotExist(err) {
+		s.logger.Info("数据文件不存在，使用空数据: %s", s.filepath)
+		return nil
+	}
+
+	data, err := os.ReadFile(s.filepath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &s.tokens); err != nil {
+		return fmt.Errorf("failed to unmarshal join tokens: %w", err)
+	}
+	if s.tokens == nil {
+		s.tokens = make(map[string]*models.JoinToken)
+	}
+	return nil
+}
+
+// Close 关闭存储，确保所有待处理的保存操作完成
+func (s *YAMLJoinTokenStore) Close() {
+	s.logger.Info("关闭JoinTokenStore，确保数据保存...")
+	if s.yamlSaver != nil {
+		s.yamlSaver.Close()
+	}
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// PortReservationStore 节点端口预留存储接口
+type PortReservationStore interface {
+	// List 获取所有端口预留
+	List(ctx context.Context) ([]models.PortReservation, error)
+	// Add 添加端口预留，节点上的端口已被预留时返回错误
+	Add(ctx context.Context, reservation models.PortReservation) error
+	// Delete 删除节点上的端口预留，预留不存在时不返回错误
+	Delete(ctx context.Context, nodeID string, port int) error
+	// Close 关闭存储
+	Close()
+}
+
+// YAMLPortReservationStore 基于YAML文件的端口预留存储实现
+type YAMLPortReservationStore struct {
+	filepath     string
+	reservations map[string]models.PortReservation
+	mu           sync.RWMutex
+	logger       utils.Logger
+	yamlSaver    *utils.YAMLSaver
+}
+
+// NewYAMLPortReservationStore 创建新的YAML端口预留存储
+func NewYAMLPortReservationStore(ctx context.Context, filepath string) *YAMLPortReservationStore {
+	logger := utils.New("PortReservationStore")
+
+	store := &YAMLPortReservationStore{
+		filepath:     filepath,
+		reservations: make(map[string]models.PortReservation),
+		logger:       logger,
+	}
+
+	// 创建YAML保存器，使用1秒的延迟保存，按节点和端口排序写入
+	store.yamlSaver = utils.NewYAMLSaver(
+		filepath,
+		func() interface{} {
+			store.mu.RLock()
+			defer store.mu.RUnlock()
+			return store.sorted()
+		},
+		logger,
+		utils.WithDelay(time.Second),
+	)
+
+	logger.Info("初始化端口预留存储，数据文件: %s", filepath)
+	if err := store.load(ctx); err != nil {
+		logger.Error("加载端口预留数据失败: %v", err)
+	}
+
+	logger.Info("成功加载端口预留数据，共%d个端口", len(store.reservations))
+	return store
+}
+
+// reservationKey 端口预留的唯一键
+func reservationKey(nodeID string, port int) string {
+	return fmt.Sprintf("%s/%d", nodeID, port)
+}
+
+// sorted 按节点和端口排序的端口预留，调用方需持有读锁
+func (s *YAMLPortReservationStore) sorted() []models.PortReservation {
+	reservations := make([]models.PortReservation, 0, len(s.reservations))
+	for _, reservation := range s.reservations {
+		reservations = append(reservations, reservation)
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		if reservations[i].NodeID != reservations[j].NodeID {
+			return reservations[i].NodeID < reservations[j].NodeID
+		}
+		return reservations[i].Port < reservations[j].Port
+	})
+	return reservations
+}
+
+// List 获取所有端口预留
+func (s *YAMLPortReservationStore) List(ctx context.Context) ([]models.PortReservation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted(), nil
+}
+
+// Add 添加端口预留
+func (s *YAMLPortReservationStore) Add(ctx context.Context, reservation models.PortReservation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := reservationKey(reservation.NodeID, reservation.Port)
+	if existing, exists := s.reservations[key]; exists {
+		return fmt.Errorf("端口已被预留: %s, 实例: %s", key, existing.InstanceID)
+	}
+	s.reservations[key] = reservation
+	s.logger.Debug("添加端口预留: %s, 实例: %s", key, reservation.InstanceID)
+	return s.save(ctx)
+}
+
+// Delete 删除端口预留
+func (s *YAMLPortReservationStore) Delete(ctx context.Context, nodeID string, port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := reservationKey(nodeID, port)
+	if _, exists := s.reservations[key]; !exists {
+		return nil
+	}
+	delete(s.reservations, key)
+	s.logger.Debug("删除端口预留: %s", key)
+	return s.save(ctx)
+}
+
+// save 使用延迟保存器保存到文件
+func (s *YAMLPortReservationStore) save(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.yamlSaver.Save(ctx)
+}
+
+// load 从文件加载所有端口预留
+func (s *YAMLPortReservationStore) load(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.filepath); os.IsNotExist(err) {
+		s.logger.Info("数据文件不存在，使用空数据: %s", s.filepath)
+		return nil
+	}
+
+	data, err := os.ReadFile(s.filepath)
+	if err != nil {
+		return fmt.Errorf("读取数据文件失败: %w", err)
+	}
+
+	var reservations []models.PortReservation
+	if err := yaml.Unmarshal(data, &reservations); err != nil {
+		return fmt.Errorf("解析端口预留数据失败: %w", err)
+	}
+	for _, reservation := range reservations {
+		s.reservations[reservationKey(reservation.NodeID, reservation.Port)] = reservation
+	}
+	return nil
+}
+
+// Close 关闭存储，确保所有待处理的保存操作完成
+func (s *YAMLPortReservationStore) Close() {
+	s.logger.Info("关闭PortReservationStore，确保数据保存...")
+	if s.yamlSaver != nil {
+		// 延迟保存器关闭时会丢弃未执行的保存，先立即写入一次
+		if err := s.yamlSaver.SaveNow(context.Background()); err != nil {
+			s.logger.Error("保存端口预留数据失败: %v", err)
+		}
+		s.yamlSaver.Close()
+	}
+}
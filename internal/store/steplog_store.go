@@ -0,0 +1,418 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// ErrLogRangeNotSatisfiable 请求的日志范围已被轮转清理或超出已写入的范围
+var ErrLogRangeNotSatisfiable = errors.New("日志范围不可用")
+
+// stepLogGapChunk 补齐缺失日志时每次写入的最大字节数
+const stepLogGapChunk = 64 << 10
+
+// StepLogInfo 步骤日志概况
+// 偏移均为步骤日志的绝对偏移，[Start, End) 为当前保留的日志范围，Start 之前的日志已被轮转清理
+type StepLogInfo struct {
+	Start    int64 `json:"start"`
+	End      int64 `json:"end"`
+	Complete bool  `json:"complete"`
+}
+
+// stepLogMeta 步骤日志元数据，与日志文件保存在同一目录
+type stepLogMeta struct {
+	Written  int64 `yaml:"written"`
+	Complete bool  `yaml:"complete"`
+}
+
+// StepLogStore 基于文件的步骤日志存储
+// 每个步骤的日志保存在 <dir>/<流水线ID>/<步骤ID>.log，超过 maxFileSize 后轮转为 .log.1、.log.2 ...，
+// 最多保留 maxFiles 个文件
+type StepLogStore struct {
+	dir         string
+	maxFileSize int64
+	maxFiles    int
+	mu          sync.Mutex
+	logger      utils.Logger
+}
+
+// NewStepLogStore 创建步骤日志存储
+func NewStepLogStore(dir string, maxFileSize int64, maxFiles int) *StepLogStore {
+	if maxFiles < 1 {
+		maxFiles = 1
+	}
+	return &StepLogStore{
+		dir:         dir,
+		maxFileSize: maxFileSize,
+		maxFiles:    maxFiles,
+		logger:      utils.New("StepLogStore"),
+	}
+}
+
+// Append 追加步骤日志，返回追加后已写入的字节数
+// offset 为数据在步骤日志中的起始偏移：已写入的部分会被跳过，中间缺失的部分用占位内容补齐，保证偏移与文件内容一致
+func (s *StepLogStore) Append(pipelineID, stepID string, offset int64, data []byte, eof bool) (int64, error) {
+	base, err := s.basePath(pipelineID, stepID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, err := s.loadMeta(base)
+	if err != nil {
+		return 0, err
+	}
+
+	// 跳过已写入的数据，用于断线重传
+	if offset < meta.Written {
+		skip := meta.Written - offset
+		if skip >= int64(len(data)) {
+			data = nil
+		} else {
+			data = data[skip:]
+		}
+		offset = meta.Written
+	}
+
+	if offset > meta.Written || len(data) > 0 {
+		if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+			return 0, fmt.Errorf("创建日志目录失败: %w", err)
+		}
+	}
+	if offset > meta.Written {
+		gap := offset - meta.Written
+		s.logger.Warn("步骤日志 %s/%s 缺失 %d 字节，使用占位内容补齐", pipelineID, stepID, gap)
+		if err := s.fillGap(base, gap); err != nil {
+			return 0, err
+		}
+	}
+	if len(data) > 0 {
+		if err := s.write(base, data); err != nil {
+			return 0, err
+		}
+	}
+
+	meta.Written = offset + int64(len(data))
+	if eof {
+		meta.Complete = true
+	}
+	if err := s.saveMeta(base, meta); err != nil {
+		return 0, err
+	}
+	return meta.Written, nil
+}
+
+// fillGap 写入 gap 字节的占位内容：空格填充，末尾为缺失说明
+// 缺失部分超过保留上限时先删除现有日志文件，只写入保留上限内的占位内容，已保留的范围仍由已写入字节数推算
+func (s *StepLogStore) fillGap(base string, gap int64) error {
+	if limit := s.maxFileSize * int64(s.maxFiles); s.maxFileSize > 0 && gap > limit {
+		for _, f := range s.files(base) {
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("删除旧日志文件失败: %w", err)
+			}
+		}
+		gap = limit
+	}
+
+	marker := []byte(fmt.Sprintf("\n[缺失 %d 字节日志]\n", gap))
+	if int64(len(marker)) > gap {
+		marker = nil
+	}
+	padding := gap - int64(len(marker))
+	chunk := bytes.Repeat([]byte{' '}, int(min(padding, stepLogGapChunk)))
+	for padding > 0 {
+		n := min(padding, int64(len(chunk)))
+		if err := s.write(base, chunk[:n]); err != nil {
+			return err
+		}
+		padding -= n
+	}
+	if len(marker) > 0 {
+		return s.write(base, marker)
+	}
+	return nil
+}
+
+// write 写入当前日志文件，超过大小限制时先轮转
+func (s *StepLogStore) write(base string, data []byte) error {
+	current := base + ".log"
+	for len(data) > 0 {
+		size := fileSize(current)
+		if s.maxFileSize > 0 && size >= s.maxFileSize {
+			if err := s.rotate(base); err != nil {
+				return err
+			}
+			size = 0
+		}
+
+		n := int64(len(data))
+		if s.maxFileSize > 0 && size+n > s.maxFileSize {
+			n = s.maxFileSize - size
+		}
+
+		f, err := os.OpenFile(current, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("打开日志文件失败: %w", err)
+		}
+		_, err = f.Write(data[:n])
+		closeErr := f.Close()
+		if err != nil {
+			return fmt.Errorf("写入日志文件失败: %w", err)
+		}
+		if closeErr != nil {
+			return fmt.Errorf("写入日志文件失败: %w", closeErr)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// rotate 轮转日志文件，超出保留数量的最旧文件被删除
+func (s *StepLogStore) rotate(base string) error {
+	oldest := rotatedPath(base, s.maxFiles-1)
+	if s.maxFiles == 1 {
+		oldest = base + ".log"
+	}
+	if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除旧日志文件失败: %w", err)
+	}
+	for i := s.maxFiles - 2; i >= 1; i-- {
+		if err := os.Rename(rotatedPath(base, i), rotatedPath(base, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("轮转日志文件失败: %w", err)
+		}
+	}
+	if s.maxFiles > 1 {
+		if err := os.Rename(base+".log", rotatedPath(base, 1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("轮转日志文件失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stat 获取步骤日志概况，日志不存在时返回空范围
+func (s *StepLogStore) Stat(pipelineID, stepID string) (StepLogInfo, error) {
+	base, err := s.basePath(pipelineID, stepID)
+	if err != nil {
+		return StepLogInfo{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statLocked(base)
+}
+
+// statLocked 计算当前保留的日志范围，调用方需持有锁
+func (s *StepLogStore) statLocked(base string) (StepLogInfo, error) {
+	meta, err := s.loadMeta(base)
+	if err != nil {
+		return StepLogInfo{}, err
+	}
+	var retained int64
+	for _, f := range s.files(base) {
+		retained += f.size
+	}
+	start := meta.Written - retained
+	if start < 0 {
+		start = 0
+	}
+	return StepLogInfo{Start: start, End: meta.Written, Complete: meta.Complete}, nil
+}
+
+// Read 读取 [start, end) 范围内的日志，范围必须位于当前保留的日志范围内
+func (s *StepLogStore) Read(pipelineID, stepID string, start, end int64) ([]byte, error) {
+	base, err := s.basePath(pipelineID, stepID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readLocked(base, start, end)
+}
+
+// readLocked 读取日志范围，调用方需持有锁
+func (s *StepLogStore) readLocked(base string, start, end int64) ([]byte, error) {
+	info, err := s.statLocked(base)
+	if err != nil {
+		return nil, err
+	}
+	if start < info.Start || start > end || end > info.End {
+		return nil, ErrLogRangeNotSatisfiable
+	}
+
+	buf := make([]byte, 0, end-start)
+	pos := info.Start
+	for _, f := range s.files(base) {
+		fileStart, fileEnd := pos, pos+f.size
+		pos = fileEnd
+		if fileEnd <= start || fileStart >= end {
+			continue
+		}
+		from := max(start, fileStart) - fileStart
+		to := min(end, fileEnd) - fileStart
+
+		fh, err := os.Open(f.path)
+		if err != nil {
+			return nil, fmt.Errorf("打开日志文件失败: %w", err)
+		}
+		chunk := make([]byte, to-from)
+		_, err = fh.ReadAt(chunk, from)
+		fh.Close()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("读取日志文件失败: %w", err)
+		}
+		buf = append(buf, chunk...)
+	}
+	return buf, nil
+}
+
+// Tail 读取最后 lines 行日志（最多读取 maxBytes 字节），返回日志及其起始偏移
+func (s *StepLogStore) Tail(pipelineID, stepID string, lines int, maxBytes int64) ([]byte, int64, error) {
+	base, err := s.basePath(pipelineID, stepID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := s.statLocked(base)
+	if err != nil {
+		return nil, 0, err
+	}
+	start := max(info.End-maxBytes, info.Start)
+	data, err := s.readLocked(base, start, info.End)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 从末尾向前查找换行符，末尾的换行符不计入行数
+	cut := len(data)
+	if cut > 0 && data[cut-1] == '\n' {
+		cut--
+	}
+	for i := 0; i < lines; i++ {
+		idx := bytes.LastIndexByte(data[:cut], '\n')
+		if idx < 0 {
+			return data, start, nil
+		}
+		cut = idx
+	}
+	return data[cut+1:], start + int64(cut+1), nil
+}
+
+// DeletePipeline 删除流水线的所有步骤日志
+func (s *StepLogStore) DeletePipeline(pipelineID string) error {
+	if err := validateLogName(pipelineID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.RemoveAll(filepath.Join(s.dir, pipelineID)); err != nil {
+		return fmt.Errorf("删除流水线日志失败: %w", err)
+	}
+	return nil
+}
+
+// logFile 日志文件及其大小
+type logFile struct {
+	path string
+	size int64
+}
+
+// files 按从旧到新的顺序返回步骤的日志文件
+func (s *StepLogStore) files(base string) []logFile {
+	var result []logFile
+	for i := s.maxFiles - 1; i >= 1; i-- {
+		path := rotatedPath(base, i)
+		if info, err := os.Stat(path); err == nil {
+			result = append(result, logFile{path: path, size: info.Size()})
+		}
+	}
+	if info, err := os.Stat(base + ".log"); err == nil {
+		result = append(result, logFile{path: base + ".log", size: info.Size()})
+	}
+	return result
+}
+
+// basePath 步骤日志文件的路径前缀
+func (s *StepLogStore) basePath(pipelineID, stepID string) (string, error) {
+	if err := validateLogName(pipelineID); err != nil {
+		return "", err
+	}
+	if err := validateLogName(stepID); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, pipelineID, stepID), nil
+}
+
+// loadMeta 读取步骤日志元数据，不存在时返回空元数据
+func (s *StepLogStore) loadMeta(base string) (*stepLogMeta, error) {
+	meta := &stepLogMeta{}
+	data, err := os.ReadFile(base + ".meta.yaml")
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取日志元数据失败: %w", err)
+	}
+	if err := yaml.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("解析日志元数据失败: %w", err)
+	}
+	return meta, nil
+}
+
+// saveMeta 保存步骤日志元数据
+func (s *StepLogStore) saveMeta(base string, meta *stepLogMeta) error {
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("序列化日志元数据失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return fmt.Errorf("创建日志目录失败: %w", err)
+	}
+	tmp := base + ".meta.yaml.tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("保存日志元数据失败: %w", err)
+	}
+	if err := os.Rename(tmp, base+".meta.yaml"); err != nil {
+		return fmt.Errorf("保存日志元数据失败: %w", err)
+	}
+	return nil
+}
+
+// validateLogName 流水线ID和步骤ID用作文件名，不能包含路径分隔符
+func validateLogName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("无效的日志名称: %q", name)
+	}
+	return nil
+}
+
+// rotatedPath 第 n 个轮转日志文件的路径
+func rotatedPath(base string, n int) string {
+	return fmt.Sprintf("%s.log.%d", base, n)
+}
+
+// fileSize 获取文件大小，文件不存在时返回 0
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepLogStoreRotation(t *testing.T) {
+	s := NewStepLogStore(t.TempDir(), 10, 2)
+
+	written, err := s.Append("p1", "build", 0, []byte("line1\nline2\nline3\n"), false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(18), written)
+
+	// 两个 10 字节的文件只保留最后 18 字节中的 10+8 字节
+	info, err := s.Stat("p1", "build")
+	require.NoError(t, err)
+	assert.Equal(t, StepLogInfo{Start: 0, End: 18}, info)
+
+	written, err = s.Append("p1", "build", 18, []byte("line4\n"), true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(24), written)
+
+	info, err = s.Stat("p1", "build")
+	require.NoError(t, err)
+	assert.Equal(t, StepLogInfo{Start: 10, End: 24, Complete: true}, info)
+
+	data, err := s.Read("p1", "build", 12, 24)
+	require.NoError(t, err)
+	assert.Equal(t, "line3\nline4\n", string(data))
+
+	_, err = s.Read("p1", "build", 0, 24)
+	assert.ErrorIs(t, err, ErrLogRangeNotSatisfiable)
+
+	tail, start, err := s.Tail("p1", "build", 1, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "line4\n", string(tail))
+	assert.Equal(t, int64(18), start)
+}
+
+func TestStepLogStoreDuplicateChunk(t *testing.T) {
+	s := NewStepLogStore(t.TempDir(), 1024, 3)
+
+	_, err := s.Append("p1", "run", 0, []byte("hello "), false)
+	require.NoError(t, err)
+	// 重传的块与已写入部分重叠
+	written, err := s.Append("p1", "run", 3, []byte("lo world"), false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), written)
+
+	data, err := s.Read("p1", "run", 0, written)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	_, err = s.Append("../p1", "run", 0, []byte("x"), false)
+	assert.Error(t, err)
+}
+
+func TestStepLogStoreGap(t *testing.T) {
+	s := NewStepLogStore(t.TempDir(), 1024, 3)
+
+	_, err := s.Append("p1", "run", 0, []byte("hello\n"), false)
+	require.NoError(t, err)
+	// 中间的块上传失败被丢弃，后续的块从更大的偏移开始
+	written, err := s.Append("p1", "run", 40, []byte("world\n"), false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(46), written)
+
+	// 缺失部分以占位内容补齐，偏移与文件内容保持一致
+	data, err := s.Read("p1", "run", 0, written)
+	require.NoError(t, err)
+	require.Len(t, data, 46)
+	assert.Equal(t, "hello\n", string(data[:6]))
+	assert.Contains(t, string(data[6:40]), "[缺失 34 字节日志]")
+	assert.Equal(t, "world\n", string(data[40:]))
+
+	data, err = s.Read("p1", "run", 40, written)
+	require.NoError(t, err)
+	assert.Equal(t, "world\n", string(data))
+}
+
+func TestStepLogStoreGapBeyondRetention(t *testing.T) {
+	s := NewStepLogStore(t.TempDir(), 10, 2)
+
+	_, err := s.Append("p1", "run", 0, []byte("hello\n"), false)
+	require.NoError(t, err)
+	written, err := s.Append("p1", "run", 1000, []byte("end\n"), false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1004), written)
+
+	info, err := s.Stat("p1", "run")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1004), info.End)
+	data, err := s.Read("p1", "run", 1000, 1004)
+	require.NoError(t, err)
+	assert.Equal(t, "end\n", string(data))
+}
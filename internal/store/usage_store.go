@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/open-beagle/beagle-wind-game/internal/models"
+	"github.com/open-beagle/beagle-wind-game/internal/utils"
+)
+
+// UsageStore 实例运行会话存储接口，与实例记录分开保存，实例删除后会话记录保留
+type UsageStore interface {
+	// List 获取所有会话记录，按开始时间排序
+	List(ctx context.Context) ([]models.UsageSession, error)
+	// Add 添加新的会话记录
+	Add(ctx context.Context, session models.UsageSession) error
+	// Update 更新会话记录
+	Update(ctx context.Context, session models.UsageSession) error
+	// Close 关闭存储
+	Close()
+}
+
+// YAMLUsageStore 基于YAML文件的会话记录存储实现
+type YAMLUsageStore struct {
+	filepath  string
+	sessions  map[string]models.UsageSession
+	mu        sync.RWMutex
+	logger    utils.Logger
+	yamlSaver *utils.YAMLSaver
+}
+
+// NewYAMLUsageStore 创建新的YAML会话记录存储
+func NewYAMLUsageStore(ctx context.Context, filepath string) *YAMLUsageStore {
+	logger := utils.New("UsageStore")
+
+	store := &YAMLUsageStore{
+		filepath: filepath,
+		sessions: make(map[string]models.UsageSession),
+		logger:   logger,
+	}
+
+	// 创建YAML保存器，使用1秒的延迟保存，按开始时间排序写入
+	store.yamlSaver = utils.NewYAMLSaver(
+		filepath,
+		func() interface{} {
+			store.mu.RLock()
+			defer store.mu.RUnlock()
+			return store.sorted()
+		},
+		logger,
+		utils.WithDelay(time.Second),
+	)
+
+	logger.Info("初始化会话记录存储，数据文件: %s", filepath)
+	if err := store.load(ctx); err != nil {
+		logger.Error("加载会话记录失败: %v", err)
+	}
+
+	logger.Info("成功加载会话记录，共%d条记录", len(store.sessions))
+	return store
+}
+
+// List 获取所有会话记录
+func (s *YAMLUsageStore) List(ctx context.Context) ([]models.UsageSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted(), nil
+}
+
+// Add 添加新的会话记录
+func (s *YAMLUsageStore) Add(ctx context.Context, session models.UsageSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sessions[session.ID]; exists {
+		return fmt.Errorf("会话记录已存在: %s", session.ID)
+	}
+	s.sessions[session.ID] = session
+	s.logger.Debug("添加会话记录: %s (实例 %s)", session.ID, session.InstanceID)
+	return s.save(ctx)
+}
+
+// Update 更新会话记录
+func (s *YAMLUsageStore) Update(ctx context.Context, session models.UsageSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sessions[session.ID]; !exists {
+		return fmt.Errorf("会话记录不存在: %s", session.ID)
+	}
+	s.sessions[session.ID] = session
+	s.logger.Debug("更新会话记录: %s", session.ID)
+	return s.save(ctx)
+}
+
+// sorted 按开始时间排序的会话记录，调用方需持有锁
+func (s *YAMLUsageStore) sorted() []models.UsageSession {
+	sessions := make([]models.UsageSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].StartedAt.Equal(sessions[j].StartedAt) {
+			return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+// save 使用延迟保存器保存到文件
+func (s *YAMLUsageStore) save(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.yamlSaver.Save(ctx)
+}
+
+// load 从文件加载所有会话记录
+func (s *YAMLUsageStore) load(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.filepath); os.IsNotExist(err) {
+		s.logger.Info("数据文件不存在，使用空数据: %s", s.filepath)
+		return nil
+	}
+
+	data, err := os.ReadFile(s.filepath)
+	if err != nil {
+		return fmt.Errorf("读取数据文件失败: %w", err)
+	}
+
+	var sessions []models.UsageSession
+	if err := yaml.Unmarshal(data, &sessions); err != nil {
+		return fmt.Errorf("解析会话记录失败: %w", err)
+	}
+	for _, session := range sessions {
+		s.sessions[session.ID] = session
+	}
+	return nil
+}
+
+// Close 关闭存储，确保所有待处理的保存操作完成
+func (s *YAMLUsageStore) Close() {
+	s.logger.Info("关闭UsageStore，确保数据保存...")
+	if s.yamlSaver != nil {
+		// 延迟保存器关闭时会丢弃未执行的保存，先立即写入一次，避免丢失刚结束的会话
+		if err := s.yamlSaver.SaveNow(context.Background()); err != nil {
+			s.logger.Error("保存会话记录失败: %v", err)
+		}
+		s.yamlSaver.Close()
+	}
+}
@@ -194,6 +194,9 @@ func newZapLogger(config LoggerConfig) (*ZapLogger, error) {
 		cores = append(cores, fileCore)
 	}
 
+	// 最近日志保留在内存中，用于收集诊断信息
+	cores = append(cores, zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), recentLogs, atomicLevel))
+
 	// 创建核心
 	core := zapcore.NewTee(cores...)
 
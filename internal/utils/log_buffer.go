@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bytes"
+	"sync"
+)
+
+// recentLogSize 内存中保留的最近日志字节数
+const recentLogSize = 1 << 20
+
+// recentLogs 所有日志器共用的最近日志缓冲区
+var recentLogs = NewLogBuffer(recentLogSize)
+
+// LogBuffer 保留最近写入日志的内存缓冲区，用于收集诊断信息
+// 超出容量后按整行丢弃最早的日志
+type LogBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+}
+
+// NewLogBuffer 创建最多保留 size 字节的日志缓冲区
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{size: size}
+}
+
+// Write 实现 io.Writer，写入一条或多条日志
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	// 超过两倍容量时才整理，避免每次写入都移动数据
+	if len(b.buf) > 2*b.size {
+		b.buf = append(b.buf[:0], b.tail()...)
+	}
+	return len(p), nil
+}
+
+// Sync 实现 zapcore.WriteSyncer
+func (b *LogBuffer) Sync() error {
+	return nil
+}
+
+// Bytes 获取缓冲区中的日志副本
+func (b *LogBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.tail()...)
+}
+
+// tail 最后 size 字节中从第一个完整行开始的部分，调用方需持有锁
+func (b *LogBuffer) tail() []byte {
+	if len(b.buf) <= b.size {
+		return b.buf
+	}
+	data := b.buf[len(b.buf)-b.size:]
+	if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+		data = data[idx+1:]
+	}
+	return data
+}
+
+// RecentLogs 获取本进程最近输出的日志
+func RecentLogs() []byte {
+	return recentLogs.Bytes()
+}
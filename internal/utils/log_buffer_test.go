@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogBuffer_KeepsRecentLines(t *testing.T) {
+	buf := NewLogBuffer(64)
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(buf, "line %03d\n", i)
+	}
+
+	data := string(buf.Bytes())
+	assert.LessOrEqual(t, len(data), 64)
+	assert.True(t, strings.HasPrefix(data, "line "), "应从完整的行开始: %q", data)
+	assert.True(t, strings.HasSuffix(data, "line 099\n"))
+}
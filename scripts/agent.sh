@@ -84,8 +84,8 @@ echo "========================================"
 
 # 在前台运行 agent，同时输出到控制台和日志文件
 ./bin/agent \
-  --node-id="$NODE_ID" \
-  --server-addr="$SERVER_ADDR" \
+  --id="$NODE_ID" \
+  --server="$SERVER_ADDR" \
   --log-level="$LOG_LEVEL" \
   --log-file="logs/agent.log" \
   --log-both=false